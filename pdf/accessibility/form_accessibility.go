@@ -0,0 +1,698 @@
+/*
+ * Make a form (AcroForm) easier to use with a screen reader and the keyboard: give every field a tooltip, and put the
+ * fields in a logical tab order, top to bottom and left to right through the pages.
+ *
+ * The tooltip of a field (its alternate name, TU) is shown when the mouse is over the field, and is what a screen
+ * reader says for the field, instead of the field name.  The tooltips are read from a file with a line per field,
+ * "full.field.name=Tooltip" (lines starting with # are comments).  Fields that are not in the file keep their
+ * tooltip, and fields without one get a tooltip made from the field name, e.g. "Date of birth" for "date_of_birth" or
+ * "dateOfBirth", with "(required)" added for required fields.  These generated tooltips are marked in the output, to
+ * be checked.  Radio buttons have a tooltip for the group.
+ *
+ * The tab order of a page is set by its Tabs entry: R (row order) or C (column order), or else the order of the
+ * annotations in its Annots array.  Both are set, so that viewers that ignore Tabs follow the same order: the widgets
+ * in the Annots array are sorted in rows, top to bottom and left to right (or in columns, left to right and top to
+ * bottom, with -order column).  Other annotations, such as links, keep their place.  Viewers move from the last field
+ * of a page to the first field of the next page, so the tab order continues through the pages.  The fields of the
+ * form (the Fields array and the kids of the parent fields) are sorted in the same order, as this is the order in
+ * which assistive technology and form tools list them; a field with widgets on several pages is placed at its first
+ * widget.
+ *
+ * The changes are appended to the input as an incremental update, written by writeUpdate in update.go (shared with
+ * tagged_pdf.go).  The Tabs entry requires PDF 1.5, the version is raised if needed.  For a fully accessible form,
+ * the document should also be tagged with Form structure elements (see tagged_pdf.go), which this example does not
+ * do.
+ *
+ * Run as: go run form_accessibility.go update.go [-tooltips tooltips.txt] [-order row|column] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run form_accessibility.go update.go [-tooltips tooltips.txt] [-order row|column] " +
+	"input.pdf output.pdf\n"
+
+// Field flags (Ff).
+const fieldFlagRequired = 1 << 1
+
+// Annotation flags.
+const annotFlagHidden = 2
+
+// formField is a terminal field of the form with its widgets.
+type formField struct {
+	Name    string
+	Dict    *pdfcore.PdfObjectDictionary
+	Owner   int64 // Number of the object containing the field dictionary.
+	FT      string
+	Ff      int64
+	Widgets []int64 // Numbers of the widget annotation objects.
+}
+
+// widget is a widget annotation on a page, with its position in the tab order.
+type widget struct {
+	num  int64
+	page int
+	rect pdf.PdfRectangle
+	tab  int
+}
+
+// document gives access to the objects of the input and collects the changed objects.
+type document struct {
+	reader  *pdf.PdfReader
+	objects map[int64]*updateObject
+}
+
+func main() {
+	tooltipsPath := ""
+	order := ""
+	flag.StringVar(&tooltipsPath, "tooltips", "", "File with a line name=tooltip per field")
+	flag.StringVar(&order, "order", "row", "Tab order: row (left to right, then down) or column (down, then right)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 || (order != "row" && order != "column") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	tooltips := map[string]string{}
+	if tooltipsPath != "" {
+		var err error
+		tooltips, err = readTooltips(tooltipsPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	err := makeFormAccessible(inputPath, outputPath, tooltips, order == "column")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Reads the tooltips file: lines name=tooltip, ignoring empty lines and comments.
+func readTooltips(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tooltips := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("%s line %d: expected name=tooltip", path, lineNum)
+		}
+		tooltips[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return tooltips, scanner.Err()
+}
+
+func makeFormAccessible(inputPath, outputPath string, tooltips map[string]string, columns bool) error {
+	data, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		return errors.New("Encrypted files are not supported")
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	doc := &document{reader: pdfReader, objects: map[int64]*updateObject{}}
+
+	catalog, catalogNum := doc.resolveDict(trailer.Get("Root"))
+	if catalog == nil {
+		return errors.New("Missing catalog")
+	}
+	form, formNum := doc.resolveDict(catalog.Get("AcroForm"))
+	if formNum == 0 {
+		formNum = catalogNum
+	}
+	if form == nil || form.Get("Fields") == nil {
+		return fmt.Errorf("No form in %s", inputPath)
+	}
+
+	// The widgets of each page in tab order.  The Annots array of the page gets the widgets in this order, in the
+	// places of the widgets, and the page the Tabs entry.
+	widgets := map[int64]*widget{}
+	pages, _ := doc.resolveDict(catalog.Get("Pages"))
+	if pages == nil {
+		return errors.New("Missing page tree")
+	}
+	pageNum := 0
+	tab := 0
+	err = doc.walkPages(pages, 0, func(page *pdfcore.PdfObjectDictionary, num int64) error {
+		pageNum++
+		annots, _ := doc.resolveArray(page.Get("Annots"))
+		if annots == nil {
+			return nil
+		}
+		slots := []int{}
+		pageWidgets := []*widget{}
+		for i, obj := range *annots {
+			dict, annotNum := doc.resolveDict(obj)
+			if dict == nil || annotNum == 0 {
+				continue
+			}
+			if subtype, ok := dict.Get("Subtype").(*pdfcore.PdfObjectName); !ok || *subtype != "Widget" {
+				continue
+			}
+			rect, ok := annotationRect(dict)
+			if !ok {
+				continue
+			}
+			w := &widget{num: annotNum, page: pageNum, rect: rect, tab: math.MaxInt32}
+			widgets[annotNum] = w
+			if flags, ok := dict.Get("F").(*pdfcore.PdfObjectInteger); ok && *flags&annotFlagHidden != 0 {
+				// Hidden widgets are not in the tab order.
+				continue
+			}
+			slots = append(slots, i)
+			pageWidgets = append(pageWidgets, w)
+		}
+		if len(pageWidgets) == 0 {
+			return nil
+		}
+
+		sorted := sortWidgets(pageWidgets, columns)
+		newAnnots := append(pdfcore.PdfObjectArray{}, *annots...)
+		for i, w := range sorted {
+			newAnnots[slots[i]] = &pdfcore.PdfObjectReference{ObjectNumber: w.num}
+			w.tab = tab
+			tab++
+		}
+		page.Set("Annots", &newAnnots)
+		if columns {
+			page.Set("Tabs", pdfcore.MakeName("C"))
+		} else {
+			page.Set("Tabs", pdfcore.MakeName("R"))
+		}
+		doc.changed(num, page)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The fields in tab order.
+	fieldArray, _ := doc.resolveArray(form.Get("Fields"))
+	if fieldArray == nil {
+		return errors.New("Invalid Fields array")
+	}
+	form.Set("Fields", doc.sortFields(*fieldArray, widgets, 0))
+	doc.changed(formNum, form)
+
+	fields := map[string]*formField{}
+	for _, obj := range *fieldArray {
+		doc.collectFields(obj, "", nil, 0, "", 0, fields, 0)
+	}
+	list := []*formField{}
+	for _, field := range fields {
+		list = append(list, field)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		ti, tj := firstTab(list[i], widgets), firstTab(list[j], widgets)
+		if ti != tj {
+			return ti < tj
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	// The tooltips.
+	generated := 0
+	for _, field := range list {
+		tooltip, fromFile := tooltips[field.Name]
+		delete(tooltips, field.Name)
+		note := ""
+		kept := false
+		if !fromFile {
+			if tu, ok := pdfcore.TraceToDirectObject(field.Dict.Get("TU")).(*pdfcore.PdfObjectString); ok &&
+				strings.TrimSpace(string(*tu)) != "" {
+				tooltip = decodePdfString(string(*tu))
+				kept = true
+			} else {
+				tooltip = tooltipFromName(field.Name)
+				if field.Ff&fieldFlagRequired != 0 {
+					tooltip += " (required)"
+				}
+				note = " (generated, please check)"
+				generated++
+			}
+		}
+		if !kept {
+			field.Dict.Set("TU", pdfcore.MakeString(encodePdfString(tooltip)))
+			doc.changed(field.Owner, field.Dict)
+		}
+
+		page := "no widget"
+		if tab := firstTab(field, widgets); tab != math.MaxInt32 {
+			for _, num := range field.Widgets {
+				if w, ok := widgets[num]; ok && w.tab == tab {
+					page = fmt.Sprintf("page %d", w.page)
+				}
+			}
+		}
+		fmt.Printf("%-30s %-4s %-10s %q%s\n", field.Name, field.FT, page, tooltip, note)
+	}
+	for name := range tooltips {
+		fmt.Printf("Not in the form: %s\n", name)
+	}
+	fmt.Printf("%d fields in tab order, %d generated tooltips\n", len(list), generated)
+
+	// Tabs was introduced in PDF 1.5.
+	if pdfVersion(data, catalog) < "1.5" {
+		catalog.Set("Version", pdfcore.MakeName("1.5"))
+		doc.changed(catalogNum, catalog)
+	}
+
+	var objects []updateObject
+	for _, obj := range doc.objects {
+		objects = append(objects, *obj)
+	}
+	update, err := writeUpdate(data, objects, trailer, int64(*size))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outputPath, append(data, update...), 0644)
+}
+
+// Returns the widgets in tab order.  In rows: the widgets are grouped into rows of widgets overlapping vertically by
+// at least half the height of the smaller one, the rows from top to bottom, and the widgets of a row from left to
+// right.  In columns, the same with x and y swapped.
+func sortWidgets(widgets []*widget, columns bool) []*widget {
+	// The extent of a widget across the rows (or columns), and its position along them.
+	extent := func(w *widget) (float64, float64, float64) {
+		if columns {
+			return -w.rect.Urx, -w.rect.Llx, w.rect.Ury
+		}
+		return w.rect.Lly, w.rect.Ury, -w.rect.Llx
+	}
+
+	sorted := append([]*widget{}, widgets...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		_, hi, _ := extent(sorted[i])
+		_, hj, _ := extent(sorted[j])
+		return hi > hj
+	})
+
+	type band struct {
+		lo, hi  float64
+		widgets []*widget
+	}
+	bands := []*band{}
+	for _, w := range sorted {
+		lo, hi, _ := extent(w)
+		var in *band
+		for i := len(bands) - 1; i >= 0 && in == nil; i-- {
+			b := bands[i]
+			overlap := math.Min(b.hi, hi) - math.Max(b.lo, lo)
+			if overlap > 0.5*math.Min(b.hi-b.lo, hi-lo) {
+				in = b
+			}
+		}
+		if in == nil {
+			in = &band{lo: lo, hi: hi}
+			bands = append(bands, in)
+		}
+		in.widgets = append(in.widgets, w)
+		in.lo, in.hi = math.Min(in.lo, lo), math.Max(in.hi, hi)
+	}
+
+	result := []*widget{}
+	for _, b := range bands {
+		ws := b.widgets
+		sort.SliceStable(ws, func(i, j int) bool {
+			_, _, pi := extent(ws[i])
+			_, _, pj := extent(ws[j])
+			return pi > pj
+		})
+		result = append(result, ws...)
+	}
+	return result
+}
+
+// Returns the fields sorted by their first widget in the tab order, with the kids of the parent fields sorted the
+// same way.  Fields without widgets are placed last.
+func (doc *document) sortFields(fields pdfcore.PdfObjectArray, widgets map[int64]*widget,
+	depth int) *pdfcore.PdfObjectArray {
+	tabs := make([]int, len(fields))
+	for i, obj := range fields {
+		tabs[i] = doc.firstWidgetTab(obj, widgets, 0)
+
+		// The kids of a parent field, which are named fields.
+		dict, num := doc.resolveDict(obj)
+		if dict == nil || depth > 32 {
+			continue
+		}
+		kids, _ := doc.resolveArray(dict.Get("Kids"))
+		if kids == nil || len(*kids) == 0 {
+			continue
+		}
+		kid, _ := doc.resolveDict((*kids)[0])
+		if kid == nil || kid.Get("T") == nil {
+			continue
+		}
+		dict.Set("Kids", doc.sortFields(*kids, widgets, depth+1))
+		doc.changed(num, dict)
+	}
+
+	indices := make([]int, len(fields))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool { return tabs[indices[i]] < tabs[indices[j]] })
+	sorted := pdfcore.PdfObjectArray{}
+	for _, i := range indices {
+		sorted = append(sorted, fields[i])
+	}
+	return &sorted
+}
+
+// Returns the first position in the tab order of the widgets of the field and its kids.
+func (doc *document) firstWidgetTab(obj pdfcore.PdfObject, widgets map[int64]*widget, depth int) int {
+	dict, num := doc.resolveDict(obj)
+	if dict == nil || depth > 32 {
+		return math.MaxInt32
+	}
+	first := math.MaxInt32
+	if w, ok := widgets[num]; ok {
+		first = w.tab
+	}
+	if kids, _ := doc.resolveArray(dict.Get("Kids")); kids != nil {
+		for _, kid := range *kids {
+			if tab := doc.firstWidgetTab(kid, widgets, depth+1); tab < first {
+				first = tab
+			}
+		}
+	}
+	return first
+}
+
+// Returns the first position in the tab order of the widgets of the field.
+func firstTab(field *formField, widgets map[int64]*widget) int {
+	first := math.MaxInt32
+	for _, num := range field.Widgets {
+		if w, ok := widgets[num]; ok && w.tab < first {
+			first = w.tab
+		}
+	}
+	return first
+}
+
+// Returns a tooltip made from the partial name of a field: the words of the name, separated by underscores,
+// hyphens, spaces or case changes, with the first letter in upper case.
+func tooltipFromName(name string) string {
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	words := []string{}
+	word := []rune{}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || unicode.IsSpace(r):
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		case unicode.IsUpper(r) && len(word) > 0 && (unicode.IsLower(word[len(word)-1]) ||
+			i+1 < len(runes) && unicode.IsLower(runes[i+1])):
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	for i, w := range words {
+		// Keep acronyms such as ZIP in upper case.
+		if strings.ToUpper(w) != w {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	text := strings.Join(words, " ")
+	if text == "" {
+		return name
+	}
+	r := []rune(text)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// Adds the terminal fields of the field tree to fields.  Kids without a name are widgets, and terminal fields without
+// kids are their own widget.
+func (doc *document) collectFields(obj pdfcore.PdfObject, parentName string, named *formField, owner int64,
+	ft string, ff int64, fields map[string]*formField, depth int) {
+	dict, num := doc.resolveDict(obj)
+	if dict == nil || depth > 32 {
+		return
+	}
+	if num == 0 {
+		num = owner
+	}
+
+	if t, ok := pdfcore.TraceToDirectObject(dict.Get("FT")).(*pdfcore.PdfObjectName); ok {
+		ft = string(*t)
+	}
+	if f, ok := pdfcore.TraceToDirectObject(dict.Get("Ff")).(*pdfcore.PdfObjectInteger); ok {
+		ff = int64(*f)
+	}
+
+	name := parentName
+	if t, ok := pdfcore.TraceToDirectObject(dict.Get("T")).(*pdfcore.PdfObjectString); ok {
+		name = joinName(parentName, decodePdfString(string(*t)))
+		named = &formField{Name: name, Dict: dict, Owner: num}
+	}
+	if named == nil {
+		return
+	}
+
+	kids, _ := doc.resolveArray(dict.Get("Kids"))
+	if kids == nil {
+		field, ok := fields[name]
+		if !ok {
+			field = named
+			field.FT, field.Ff = ft, ff
+			fields[name] = field
+		}
+		if subtype, ok := dict.Get("Subtype").(*pdfcore.PdfObjectName); ok && *subtype == "Widget" {
+			field.Widgets = append(field.Widgets, num)
+		}
+		return
+	}
+	for _, kid := range *kids {
+		doc.collectFields(kid, name, named, num, ft, ff, fields, depth+1)
+	}
+}
+
+// Returns the full name of a field from the full name of the parent and the partial name.
+func joinName(parentName, name string) string {
+	if parentName == "" {
+		return name
+	}
+	if name == "" {
+		return parentName
+	}
+	return parentName + "." + name
+}
+
+// Returns the rectangle of an annotation.
+func annotationRect(dict *pdfcore.PdfObjectDictionary) (pdf.PdfRectangle, bool) {
+	arr, ok := pdfcore.TraceToDirectObject(dict.Get("Rect")).(*pdfcore.PdfObjectArray)
+	if !ok || len(*arr) != 4 {
+		return pdf.PdfRectangle{}, false
+	}
+	rect, err := pdf.NewPdfRectangle(*arr)
+	if err != nil {
+		return pdf.PdfRectangle{}, false
+	}
+	return *rect, true
+}
+
+// Calls fn for the pages of the page tree in order.
+func (doc *document) walkPages(node *pdfcore.PdfObjectDictionary, depth int,
+	fn func(page *pdfcore.PdfObjectDictionary, num int64) error) error {
+	kids, _ := doc.resolveArray(node.Get("Kids"))
+	if kids == nil || depth > 32 {
+		return nil
+	}
+	for _, kid := range *kids {
+		dict, num := doc.resolveDict(kid)
+		if dict == nil {
+			continue
+		}
+		if t, ok := dict.Get("Type").(*pdfcore.PdfObjectName); ok && *t == "Pages" {
+			err := doc.walkPages(dict, depth+1, fn)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if num == 0 {
+			return errors.New("Page not an indirect object")
+		}
+		err := fn(dict, num)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the PDF version of the file: the version of the header, or of the catalog if later.
+func pdfVersion(data []byte, catalog *pdfcore.PdfObjectDictionary) string {
+	version := "1.3"
+	if bytes.HasPrefix(data, []byte("%PDF-")) && len(data) >= 8 {
+		version = string(data[5:8])
+	}
+	if v, ok := pdfcore.TraceToDirectObject(catalog.Get("Version")).(*pdfcore.PdfObjectName); ok &&
+		string(*v) > version {
+		version = string(*v)
+	}
+	return version
+}
+
+// Returns the dictionary of an object, resolving references, and the number of the object if it is an indirect
+// object (0 for a direct object).  Changed objects are returned as changed.
+func (doc *document) resolveDict(obj pdfcore.PdfObject) (*pdfcore.PdfObjectDictionary, int64) {
+	obj, num := doc.resolve(obj)
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectDictionary:
+		return t, num
+	case *pdfcore.PdfObjectStream:
+		return t.PdfObjectDictionary, num
+	}
+	return nil, 0
+}
+
+// Returns the array of an object, resolving references, and the number of the object if it is an indirect object.
+func (doc *document) resolveArray(obj pdfcore.PdfObject) (*pdfcore.PdfObjectArray, int64) {
+	obj, num := doc.resolve(obj)
+	arr, ok := obj.(*pdfcore.PdfObjectArray)
+	if !ok {
+		return nil, 0
+	}
+	return arr, num
+}
+
+// Returns the direct object of an object, resolving references, and the number of the object.
+func (doc *document) resolve(obj pdfcore.PdfObject) (pdfcore.PdfObject, int64) {
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		if u, ok := doc.objects[ref.ObjectNumber]; ok {
+			return u.obj, ref.ObjectNumber
+		}
+		resolved, err := doc.reader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, 0
+		}
+		obj = resolved
+	}
+	switch t := obj.(type) {
+	case *pdfcore.PdfIndirectObject:
+		return t.PdfObject, t.ObjectNumber
+	case *pdfcore.PdfObjectStream:
+		return t, t.ObjectNumber
+	}
+	return obj, 0
+}
+
+// Records that the dictionary of the object with the number was changed.
+func (doc *document) changed(num int64, dict *pdfcore.PdfObjectDictionary) {
+	if num == 0 {
+		return
+	}
+	if _, ok := doc.objects[num]; !ok {
+		doc.objects[num] = &updateObject{number: num, obj: dict}
+	}
+}
+
+// Decodes a PDF text string: UTF-16BE with a byte order mark, or PDFDocEncoding (read as Latin-1).
+func decodePdfString(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := []uint16{}
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// Encodes a text string: as is if ASCII, otherwise as UTF-16BE with a byte order mark.
+func encodePdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 128 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\xfe\xff")
+	binary.Write(&buf, binary.BigEndian, utf16.Encode([]rune(s)))
+	return buf.String()
+}
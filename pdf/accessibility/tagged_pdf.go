@@ -0,0 +1,499 @@
+/*
+ * Create a tagged PDF file: a document with a logical structure (structure tree) for accessibility, so that screen
+ * readers and other assistive technology can read it in a sensible order and know what each part is.
+ *
+ * The page content is marked in the content stream as marked content sequences with a marked content identifier
+ * (MCID), e.g. "/P <</MCID 3>> BDC ... EMC".  The structure tree has an element for each heading (H1, H2), paragraph
+ * (P), table (Table with TR rows of TH header cells and TD data cells) and figure (Figure), which refers to its
+ * marked content by the MCID and the page.  The header cells have a Scope attribute telling that they are the
+ * headers of their column, and the figure has alternate text (Alt), read instead of the image.  Content that is not
+ * part of the text, such as the table rules and the page number, is marked as artifacts and is skipped.
+ *
+ * The reading order is the order of the elements in the structure tree, independent of the positions on the page:
+ * the figure next to the last paragraph is read after it.  The content is written in the same order, so that tools
+ * reading the content stream (text extraction, reflow) find the same order, and the page tab order follows the
+ * structure.  The parent tree maps each MCID back to its element.
+ *
+ * The catalog has MarkInfo (Marked true), the StructTreeRoot and the language of the document, and the viewer shows
+ * the document title rather than the file name.  These are added in an incremental update, as the model does not give
+ * access to the catalog; the update is written by writeUpdate in update.go, shared with form_accessibility.go.  The
+ * fonts are the standard Helvetica fonts, which are not embedded: use embedded fonts for PDF/UA conformance.
+ *
+ * Run as: go run tagged_pdf.go update.go output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	goimage "image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// Page layout.
+const (
+	pageWidth  = 612.0
+	pageHeight = 792.0
+	margin     = 72.0
+)
+
+const title = "Annual report 2017"
+
+// structElem is an element of the structure tree with its marked content (MCIDs on the page) or its kids.
+type structElem struct {
+	role  string
+	alt   string // Alternate text of a figure.
+	scope string // Scope of a table header cell: Column or Row.
+	mcids []int
+	kids  []*structElem
+	num   int64 // Object number in the output.
+}
+
+// Adds a kid element with the role and returns it.
+func (e *structElem) add(role string) *structElem {
+	kid := &structElem{role: role}
+	e.kids = append(e.kids, kid)
+	return kid
+}
+
+// pageBuilder writes the content stream of a page, with the marked content of the structure elements.
+type pageBuilder struct {
+	content bytes.Buffer
+	// The element of each MCID, the MCID being the index.
+	parents []*structElem
+	// The top of the next block, from the bottom of the page.
+	y float64
+}
+
+// The sales per region: region, 2016, 2017.
+var sales = [][]string{
+	{"Region", "2016", "2017"},
+	{"North", "1,250", "1,410"},
+	{"South", "980", "1,120"},
+	{"East", "1,430", "1,390"},
+	{"West", "760", "1,050"},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run tagged_pdf.go update.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	err := createTaggedPdf(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createTaggedPdf(outputPath string) error {
+	doc := &structElem{role: "Document"}
+	b := &pageBuilder{y: pageHeight - margin}
+	width := pageWidth - 2*margin
+
+	b.heading(doc.add("H1"), title, 20)
+	b.paragraph(doc.add("P"), "This report summarizes the sales of the year 2017. The sales grew in all regions "+
+		"but the East, with the largest growth in the West, where the new store opened in March.", margin, width)
+
+	b.heading(doc.add("H2"), "Sales by region", 14)
+	b.paragraph(doc.add("P"), "The table lists the sales per region in thousands of euros.", margin, width)
+	b.table(doc.add("Table"), sales)
+
+	b.heading(doc.add("H2"), "Growth", 14)
+	// The paragraph with the figure next to it, on the right.
+	top := b.y
+	figureWidth := 160.0
+	b.paragraph(doc.add("P"), "The growth of the sales from 2016 to 2017 per region is shown in the chart. The "+
+		"West grew by 38 percent, the North and the South by 13 and 14 percent, while the sales in the East went "+
+		"down by 3 percent.", margin, width-figureWidth-24)
+	figure := doc.add("Figure")
+	figure.alt = "Bar chart of the sales per region in 2016 and 2017: North 1,250 and 1,410, South 980 and 1,120, " +
+		"East 1,430 and 1,390, West 760 and 1,050 thousand euros."
+	b.figure(figure, "Im1", pageWidth-margin-figureWidth, top, figureWidth, figureWidth*0.75)
+
+	// The page number is an artifact.
+	b.content.WriteString("/Artifact <</Type /Pagination /Subtype /Footer>> BDC\n")
+	b.text("Page 1", "F1", 9, pageWidth/2-textWidth("Page 1", fonts.NewFontHelvetica(), 9)/2, margin/2)
+	b.content.WriteString("EMC\n")
+
+	chart, err := makeChart(sales[1:])
+	if err != nil {
+		return err
+	}
+
+	resources := pdf.NewPdfPageResources()
+	resources.SetFontByName("F1", makeFont("Helvetica"))
+	resources.SetFontByName("F2", makeFont("Helvetica-Bold"))
+	err = resources.SetXObjectImageByName("Im1", chart)
+	if err != nil {
+		return err
+	}
+
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: pageWidth, Ury: pageHeight}
+	page.Resources = resources
+	// The key of the page in the parent tree.
+	page.StructParents = pdfcore.MakeInteger(0)
+	// Tab order in the order of the structure.
+	page.Tabs = pdfcore.MakeName("S")
+	err = page.SetContentStreams([]string{b.content.String()}, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	// The structure tree and MarkInfo were introduced in PDF 1.3 and 1.4, the Tabs entry in PDF 1.5.
+	pdfWriter.SetVersion(1, 5)
+	err = pdfWriter.AddPage(page)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	err = pdfWriter.Write(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	return addStructureTree(outputPath, doc, b.parents)
+}
+
+// Returns a standard font dictionary with WinAnsiEncoding.
+func makeFont(baseFont string) *pdfcore.PdfIndirectObject {
+	font := pdfcore.MakeDict()
+	font.Set("Type", pdfcore.MakeName("Font"))
+	font.Set("Subtype", pdfcore.MakeName("Type1"))
+	font.Set("BaseFont", pdfcore.MakeName(baseFont))
+	font.Set("Encoding", pdfcore.MakeName("WinAnsiEncoding"))
+	return pdfcore.MakeIndirectObject(font)
+}
+
+// Starts the marked content of the element with a new MCID.
+func (b *pageBuilder) begin(e *structElem) {
+	mcid := len(b.parents)
+	b.parents = append(b.parents, e)
+	e.mcids = append(e.mcids, mcid)
+	b.content.WriteString(fmt.Sprintf("/%s <</MCID %d>> BDC\n", e.role, mcid))
+}
+
+// Ends the marked content.
+func (b *pageBuilder) end() {
+	b.content.WriteString("EMC\n")
+}
+
+// Writes the text with the font at the position of the baseline.
+func (b *pageBuilder) text(text, font string, size, x, y float64) {
+	b.content.WriteString(fmt.Sprintf("BT /%s %g Tf %.2f %.2f Td %s Tj ET\n", font, size, x, y,
+		pdfcore.MakeString(text).DefaultWriteString()))
+}
+
+// Adds a heading in bold.
+func (b *pageBuilder) heading(e *structElem, text string, size float64) {
+	b.y -= size * 1.5
+	b.begin(e)
+	b.text(text, "F2", size, margin, b.y)
+	b.end()
+	b.y -= size * 0.5
+}
+
+// Adds a paragraph wrapped to the width.
+func (b *pageBuilder) paragraph(e *structElem, text string, x, width float64) {
+	size := 11.0
+	b.begin(e)
+	for _, line := range wrapText(text, fonts.NewFontHelvetica(), size, width) {
+		b.y -= size * 1.4
+		b.text(line, "F1", size, x, b.y)
+	}
+	b.end()
+	b.y -= size
+}
+
+// Adds a table with a header row.  Each cell is a TH or TD element in a TR row, the rules are artifacts.
+func (b *pageBuilder) table(table *structElem, rows [][]string) {
+	size := 11.0
+	rowHeight := 20.0
+	colWidths := []float64{140, 90, 90}
+	tableWidth := 0.0
+	for _, w := range colWidths {
+		tableWidth += w
+	}
+
+	top := b.y
+	for i, row := range rows {
+		tr := table.add("TR")
+		x := margin
+		for j, cell := range row {
+			var e *structElem
+			font := "F1"
+			if i == 0 {
+				e = tr.add("TH")
+				e.scope = "Column"
+				font = "F2"
+			} else {
+				e = tr.add("TD")
+			}
+			// The numbers are right aligned.
+			cellX := x + 4
+			if j > 0 {
+				fnt := fonts.Font(fonts.NewFontHelvetica())
+				if font == "F2" {
+					fnt = fonts.NewFontHelveticaBold()
+				}
+				cellX = x + colWidths[j] - 4 - textWidth(cell, fnt, size)
+			}
+			b.begin(e)
+			b.text(cell, font, size, cellX, b.y-rowHeight+6)
+			b.end()
+			x += colWidths[j]
+		}
+		b.y -= rowHeight
+	}
+
+	// The rules: above and below the table, and below the header row.
+	b.content.WriteString("/Artifact BMC\n0.5 G 1 w\n")
+	for _, y := range []float64{top, top - rowHeight, b.y} {
+		b.content.WriteString(fmt.Sprintf("%.2f %.2f m %.2f %.2f l S\n", margin, y, margin+tableWidth, y))
+	}
+	b.content.WriteString("EMC\n")
+	b.y -= size
+}
+
+// Adds a figure showing the image with the upper left corner at (x, y).
+func (b *pageBuilder) figure(e *structElem, name string, x, y, width, height float64) {
+	b.begin(e)
+	b.content.WriteString(fmt.Sprintf("q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", width, height, x, y-height, name))
+	b.end()
+}
+
+// Returns the image of a bar chart of the sales, with the bars of 2016 and 2017 side by side for each region.
+func makeChart(rows [][]string) (*pdf.XObjectImage, error) {
+	width, height := 320, 240
+	img := goimage.NewRGBA(goimage.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), goimage.White, goimage.Point{}, draw.Src)
+
+	colors := []color.RGBA{{150, 170, 200, 255}, {40, 90, 160, 255}}
+	barWidth := 24
+	groupWidth := width / len(rows)
+	maxValue := 1500.0
+	for i, row := range rows {
+		for j := 0; j < 2; j++ {
+			value, err := strconv.ParseFloat(strings.Replace(row[j+1], ",", "", -1), 64)
+			if err != nil {
+				return nil, err
+			}
+			barHeight := int(value / maxValue * float64(height-20))
+			x := i*groupWidth + (groupWidth-2*barWidth)/2 + j*barWidth
+			bar := goimage.Rect(x, height-10-barHeight, x+barWidth-2, height-10)
+			draw.Draw(img, bar, goimage.NewUniform(colors[j]), goimage.Point{}, draw.Src)
+		}
+	}
+	axis := goimage.Rect(0, height-10, width, height-9)
+	draw.Draw(img, axis, goimage.NewUniform(color.Gray{Y: 80}), goimage.Point{}, draw.Src)
+
+	pdfImg, err := pdf.ImageHandling.NewImageFromGoImage(img)
+	if err != nil {
+		return nil, err
+	}
+	return pdf.NewXObjectImageFromImage(pdfImg, nil, pdfcore.NewFlateEncoder())
+}
+
+// Adds the structure tree to the tagged PDF file in an incremental update: the structure elements, the parent tree
+// with the element of each MCID, and the catalog entries.
+func addStructureTree(path string, doc *structElem, parents []*structElem) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	rootRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return errors.New("Missing Root in trailer")
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	catalogObj, err := pdfReader.GetIndirectObjectByNumber(int(rootRef.ObjectNumber))
+	if err != nil {
+		return err
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(catalogObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Catalog not a dictionary")
+	}
+	page, err := pdfReader.GetPage(1)
+	if err != nil {
+		return err
+	}
+	pageRef := &pdfcore.PdfObjectReference{ObjectNumber: page.GetPageAsIndirectObject().ObjectNumber}
+
+	// Number the elements, the structure tree root first.
+	nextNum := int64(*size)
+	rootNum := nextNum
+	nextNum++
+	var number func(e *structElem)
+	number = func(e *structElem) {
+		e.num = nextNum
+		nextNum++
+		for _, kid := range e.kids {
+			number(kid)
+		}
+	}
+	number(doc)
+
+	objects := []updateObject{}
+	var addElem func(e *structElem, parentNum int64)
+	addElem = func(e *structElem, parentNum int64) {
+		dict := pdfcore.MakeDict()
+		dict.Set("Type", pdfcore.MakeName("StructElem"))
+		dict.Set("S", pdfcore.MakeName(e.role))
+		dict.Set("P", &pdfcore.PdfObjectReference{ObjectNumber: parentNum})
+		if e.alt != "" {
+			dict.Set("Alt", pdfcore.MakeString(e.alt))
+		}
+		if e.scope != "" {
+			attrs := pdfcore.MakeDict()
+			attrs.Set("O", pdfcore.MakeName("Table"))
+			attrs.Set("Scope", pdfcore.MakeName(e.scope))
+			dict.Set("A", attrs)
+		}
+		switch {
+		case len(e.kids) > 0:
+			kids := pdfcore.PdfObjectArray{}
+			for _, kid := range e.kids {
+				kids = append(kids, &pdfcore.PdfObjectReference{ObjectNumber: kid.num})
+				addElem(kid, e.num)
+			}
+			dict.Set("K", &kids)
+		case len(e.mcids) == 1:
+			dict.Set("Pg", pageRef)
+			dict.Set("K", pdfcore.MakeInteger(int64(e.mcids[0])))
+		case len(e.mcids) > 1:
+			dict.Set("Pg", pageRef)
+			kids := pdfcore.PdfObjectArray{}
+			for _, mcid := range e.mcids {
+				kids = append(kids, pdfcore.MakeInteger(int64(mcid)))
+			}
+			dict.Set("K", &kids)
+		}
+		objects = append(objects, updateObject{number: e.num, obj: dict})
+	}
+	addElem(doc, rootNum)
+
+	// The parent tree: a number tree with, for the StructParents key of the page, the element of each MCID.
+	pageParents := pdfcore.PdfObjectArray{}
+	for _, e := range parents {
+		pageParents = append(pageParents, &pdfcore.PdfObjectReference{ObjectNumber: e.num})
+	}
+	parentTree := pdfcore.MakeDict()
+	parentTree.Set("Nums", pdfcore.MakeArray(pdfcore.MakeInteger(0), &pageParents))
+
+	structTreeRoot := pdfcore.MakeDict()
+	structTreeRoot.Set("Type", pdfcore.MakeName("StructTreeRoot"))
+	structTreeRoot.Set("K", &pdfcore.PdfObjectReference{ObjectNumber: doc.num})
+	structTreeRoot.Set("ParentTree", parentTree)
+	structTreeRoot.Set("ParentTreeNextKey", pdfcore.MakeInteger(1))
+	objects = append(objects, updateObject{number: rootNum, obj: structTreeRoot})
+
+	yes := pdfcore.PdfObjectBool(true)
+	markInfo := pdfcore.MakeDict()
+	markInfo.Set("Marked", &yes)
+	viewerPreferences := pdfcore.MakeDict()
+	viewerPreferences.Set("DisplayDocTitle", &yes)
+	catalog.Set("MarkInfo", markInfo)
+	catalog.Set("StructTreeRoot", &pdfcore.PdfObjectReference{ObjectNumber: rootNum})
+	catalog.Set("Lang", pdfcore.MakeString("en-US"))
+	catalog.Set("ViewerPreferences", viewerPreferences)
+	objects = append(objects, updateObject{number: rootRef.ObjectNumber, obj: catalog})
+
+	// The title of the document, shown by the viewer.
+	if infoRef, ok := trailer.Get("Info").(*pdfcore.PdfObjectReference); ok {
+		infoObj, err := pdfReader.GetIndirectObjectByNumber(int(infoRef.ObjectNumber))
+		if err != nil {
+			return err
+		}
+		if info, ok := pdfcore.TraceToDirectObject(infoObj).(*pdfcore.PdfObjectDictionary); ok {
+			info.Set("Title", pdfcore.MakeString(title))
+			objects = append(objects, updateObject{number: infoRef.ObjectNumber, obj: info})
+		}
+	}
+
+	fmt.Printf("Tagged %d marked content sequences in %d structure elements\n", len(parents), nextNum-rootNum-1)
+
+	update, err := writeUpdate(data, objects, trailer, nextNum)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(update)
+	return err
+}
+
+// Returns the lines of the text wrapped at spaces to the width.
+func wrapText(text string, font fonts.Font, size, width float64) []string {
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && textWidth(line+" "+word, font, size) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Returns the width of the text in the font.
+func textWidth(text string, font fonts.Font, size float64) float64 {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetEnableWrap(false)
+	return p.Width()
+}
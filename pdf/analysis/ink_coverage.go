@@ -0,0 +1,947 @@
+/*
+ * Estimate the CMYK ink coverage of each page of a PDF, for print cost estimation, and print a report per page and
+ * for the whole document.
+ *
+ * The coverage of an ink is the percentage of the page area covered by it at full strength: a page with a black
+ * square of 10% of the page area and a 50% gray square of the same size has a black coverage of 15%.  Toner and ink
+ * yields are usually given for 5% coverage per color (ISO/IEC 19752 and 24711).
+ *
+ * Each page is rasterized at a low resolution (-dpi) into four coverage planes, one per ink, by processing the
+ * content streams in the way of the renderer of pdf/render/render.go.  Each painting operation replaces what is below
+ * it, as on paper with an opaque ink (without overprint).  The colors are converted to CMYK:
+ * - DeviceCMYK colors are taken as they are, gray colors are printed with black only (K = 1 - gray).
+ * - RGB colors, and colors in other color spaces (converted to RGB by the color space), are converted with the
+ *   naive formula K = 1 - max(R, G, B) and C, M, Y from the rest.  A print shop converts with the ICC profile of the
+ *   press and limits the total ink, so the estimate is approximate for RGB content.
+ * - Images are sampled at each pixel of the planes, in the same way, and indexed images through the palette.  Image
+ *   masks are painted with the fill color.
+ * - Text is estimated without the glyph outlines: each glyph is a box of its width (from the font widths, with
+ *   text_layout.go) and the height from the descent to the ascent of the font, covered to textInk by the ink of the
+ *   fill color.  Invisible text, such as the OCR layer of a scanned page, is skipped.
+ * The report also gives the largest total ink (the sum of the four inks) at any point of the page, which a print
+ * shop checks against the ink limit of the press, e.g. 300%.
+ *
+ * Not included are clipping, transparency, overprint, patterns, shadings, inline images and annotations; the number
+ * of patterns, shadings and inline images skipped is reported.  Areas filled with a pattern are counted as no ink.
+ *
+ * Large documents are processed page by page: only one page is parsed and rasterized at a time, each image is
+ * decoded only while it is sampled, and the coverage planes are reused from page to page.
+ *
+ * Run as: go run ink_coverage.go text_layout.go [-dpi 50] input.pdf
+ */
+/*
+ * NOTE: This example depends on golang.org/x/image (vector), BSD licensed.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	goimage "image"
+	"math"
+	"os"
+
+	"golang.org/x/image/vector"
+
+	unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run ink_coverage.go text_layout.go [-dpi 50] input.pdf\n"
+
+// Text estimate: the fraction of the glyph boxes covered by the glyphs.
+const textInk = 0.15
+
+var inkNames = [4]string{"Cyan", "Magenta", "Yellow", "Black"}
+
+// ink is the amount of the C, M, Y and K inks, from 0 to 1.
+type ink [4]float64
+
+// pageCoverage is the ink coverage of a page: the average of each ink over the page, and the largest total ink at any
+// point, from 0 to 1 (or 4 for the total).
+type pageCoverage struct {
+	Average  ink
+	MaxTotal float64
+	Area     float64 // Page area in square points.
+}
+
+func main() {
+	dpi := 0.0
+	flag.Float64Var(&dpi, "dpi", 50, "Resolution of the coverage planes")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if dpi < 10 || dpi > 600 {
+		fmt.Printf("Error: -dpi must be between 10 and 600\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	err := printInkCoverage(inputPath, dpi)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printInkCoverage estimates the coverage of the pages one by one, printing each page as it is done, and then the
+// coverage of the whole document.
+func printInkCoverage(inputPath string, dpi float64) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Encrypted PDF, a password is required")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Ink coverage of %s, estimated at %.0f dpi\n\n", inputPath, dpi)
+	fmt.Printf("%6s %8s %8s %8s %8s %8s %8s\n", "Page", "Cyan", "Magenta", "Yellow", "Black", "Total", "Max")
+
+	e := &estimator{dpi: dpi, fonts: fontCache{}}
+	var total ink
+	totalArea := 0.0
+	maxTotal := 0.0
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+		cov, err := e.pageCoverage(page)
+		if err != nil {
+			return fmt.Errorf("Page %d: %v", i+1, err)
+		}
+		printCoverageRow(fmt.Sprintf("%d", i+1), cov.Average, cov.MaxTotal)
+
+		// The document coverage is the average over the area of all pages.
+		for k := range total {
+			total[k] += cov.Average[k] * cov.Area
+		}
+		totalArea += cov.Area
+		maxTotal = math.Max(maxTotal, cov.MaxTotal)
+	}
+	if totalArea > 0 {
+		for k := range total {
+			total[k] /= totalArea
+		}
+	}
+	printCoverageRow("All", total, maxTotal)
+
+	fmt.Printf("\nAverage coverage per page:")
+	for k, name := range inkNames {
+		fmt.Printf(" %s %.1f%%", name, 100*total[k])
+		if k < len(inkNames)-1 {
+			fmt.Printf(",")
+		}
+	}
+	fmt.Printf("\n")
+	if e.patterns > 0 || e.shadings > 0 || e.inlineImages > 0 {
+		fmt.Printf("Not included: %d pattern colors, %d shadings, %d inline images\n", e.patterns, e.shadings,
+			e.inlineImages)
+	}
+
+	return nil
+}
+
+func printCoverageRow(label string, avg ink, maxTotal float64) {
+	fmt.Printf("%6s", label)
+	for _, v := range avg {
+		fmt.Printf(" %7.1f%%", 100*v)
+	}
+	fmt.Printf(" %7.1f%% %7.0f%%\n", 100*(avg[0]+avg[1]+avg[2]+avg[3]), 100*maxTotal)
+}
+
+func (m matrix) inverse() (matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if math.Abs(det) < 1e-12 {
+		return matrix{}, false
+	}
+	return matrix{
+		m[3] / det,
+		-m[1] / det,
+		-m[2] / det,
+		m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}, true
+}
+
+// Scale factor of the matrix (geometric mean of the axis scales), used for line widths.
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// Path segment in device coordinates.
+type pathSegment struct {
+	op  byte // 'm' move, 'l' line, 'c' cubic curve, 'h' close.
+	pts [3][2]float64
+}
+
+// Graphics state.
+type graphicsState struct {
+	textState // With the CTM, user space to device space.
+
+	fillCS    pdf.PdfColorspace
+	strokeCS  pdf.PdfColorspace
+	fillInk   ink
+	strokeInk ink
+	lineWidth float64
+}
+
+// estimator rasterizes pages into coverage planes.
+type estimator struct {
+	dpi float64
+
+	// The coverage planes of the current page, one per ink, width x height pixels.  The planes are kept for the next
+	// page and grown when needed.
+	planes        [4][]float32
+	width, height int
+
+	// Counts of the content that is not included.
+	patterns, shadings, inlineImages int
+
+	fonts fontCache
+}
+
+// pageCoverage rasterizes the page and returns its ink coverage.
+func (e *estimator) pageCoverage(page *pdf.PdfPage) (pageCoverage, error) {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return pageCoverage{}, err
+	}
+
+	// Device matrix: user space to pixels, origin in the upper left corner.  The rotation of the page does not
+	// change the coverage.
+	s := e.dpi / 72
+	w, h := mbox.Urx-mbox.Llx, mbox.Ury-mbox.Lly
+	device := matrix{s, 0, 0, -s, -mbox.Llx * s, mbox.Ury * s}
+	e.width = int(math.Ceil(w * s))
+	e.height = int(math.Ceil(h * s))
+	if e.width <= 0 || e.height <= 0 {
+		return pageCoverage{}, errors.New("Empty media box")
+	}
+	n := e.width * e.height
+	for k := range e.planes {
+		if cap(e.planes[k]) < n {
+			e.planes[k] = make([]float32, n)
+		}
+		e.planes[k] = e.planes[k][:n]
+		for i := range e.planes[k] {
+			e.planes[k][i] = 0
+		}
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return pageCoverage{}, err
+	}
+
+	gs := graphicsState{
+		textState: newTextState(device),
+		fillCS:    pdf.NewPdfColorspaceDeviceGray(),
+		strokeCS:  pdf.NewPdfColorspaceDeviceGray(),
+		fillInk:   ink{0, 0, 0, 1},
+		strokeInk: ink{0, 0, 0, 1},
+		lineWidth: 1,
+	}
+	err = e.processContents(contents, page.Resources, gs, 0)
+	if err != nil {
+		return pageCoverage{}, err
+	}
+
+	cov := pageCoverage{Area: w * h}
+	for i := 0; i < n; i++ {
+		total := 0.0
+		for k := range e.planes {
+			v := float64(e.planes[k][i])
+			cov.Average[k] += v
+			total += v
+		}
+		cov.MaxTotal = math.Max(cov.MaxTotal, total)
+	}
+	for k := range cov.Average {
+		cov.Average[k] /= float64(n)
+	}
+	return cov, nil
+}
+
+// Processes a content stream with the given resources and initial graphics state.
+func (e *estimator) processContents(contents string, resources *pdf.PdfPageResources, gs graphicsState,
+	depth int) error {
+	cstreamParser := pdfcontent.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return err
+	}
+
+	stack := []graphicsState{}
+	path := []pathSegment{}
+	var cx, cy, sx, sy float64 // Current point and subpath start, in user space.
+
+	for _, op := range *operations {
+		params := make([]float64, len(op.Params))
+		for i, p := range op.Params {
+			params[i], _ = getNumberAsFloat(p)
+		}
+
+		switch op.Operand {
+		// Graphics state.
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(params) == 6 {
+				gs.ctm = matrix{params[0], params[1], params[2], params[3], params[4], params[5]}.mult(gs.ctm)
+			}
+		case "w":
+			if len(params) == 1 {
+				gs.lineWidth = params[0]
+			}
+
+		// Colors.
+		case "g", "rg", "k":
+			if c, ok := deviceInk(params); ok {
+				gs.fillInk = c
+			}
+		case "G", "RG", "K":
+			if c, ok := deviceInk(params); ok {
+				gs.strokeInk = c
+			}
+		case "cs":
+			gs.fillCS = lookupColorspace(op.Params, resources)
+			gs.fillInk = ink{0, 0, 0, 1}
+		case "CS":
+			gs.strokeCS = lookupColorspace(op.Params, resources)
+			gs.strokeInk = ink{0, 0, 0, 1}
+		case "sc", "scn":
+			gs.fillInk = e.colorInk(gs.fillCS, op.Params, gs.fillInk)
+		case "SC", "SCN":
+			gs.strokeInk = e.colorInk(gs.strokeCS, op.Params, gs.strokeInk)
+
+		// Path construction.
+		case "m":
+			if len(params) == 2 {
+				cx, cy, sx, sy = params[0], params[1], params[0], params[1]
+				path = append(path, segment(gs.ctm, 'm', cx, cy))
+			}
+		case "l":
+			if len(params) == 2 {
+				cx, cy = params[0], params[1]
+				path = append(path, segment(gs.ctm, 'l', cx, cy))
+			}
+		case "c", "v", "y":
+			pts := params
+			if op.Operand == "v" && len(params) == 4 {
+				pts = []float64{cx, cy, params[0], params[1], params[2], params[3]}
+			} else if op.Operand == "y" && len(params) == 4 {
+				pts = []float64{params[0], params[1], params[2], params[3], params[2], params[3]}
+			}
+			if len(pts) == 6 {
+				path = append(path, segment(gs.ctm, 'c', pts...))
+				cx, cy = pts[4], pts[5]
+			}
+		case "h":
+			path = append(path, pathSegment{op: 'h'})
+			cx, cy = sx, sy
+		case "re":
+			if len(params) == 4 {
+				x, y, w, h := params[0], params[1], params[2], params[3]
+				path = append(path,
+					segment(gs.ctm, 'm', x, y),
+					segment(gs.ctm, 'l', x+w, y),
+					segment(gs.ctm, 'l', x+w, y+h),
+					segment(gs.ctm, 'l', x, y+h),
+					pathSegment{op: 'h'})
+				cx, cy, sx, sy = x, y, x, y
+			}
+
+		// Path painting.
+		case "f", "F", "f*":
+			e.fillPath(path, gs.fillInk, 1)
+		case "S":
+			e.strokePath(path, gs.strokeInk, gs.lineWidth*gs.ctm.scale())
+		case "s":
+			path = append(path, pathSegment{op: 'h'})
+			e.strokePath(path, gs.strokeInk, gs.lineWidth*gs.ctm.scale())
+		case "B", "B*":
+			e.fillPath(path, gs.fillInk, 1)
+			e.strokePath(path, gs.strokeInk, gs.lineWidth*gs.ctm.scale())
+		case "b", "b*":
+			path = append(path, pathSegment{op: 'h'})
+			e.fillPath(path, gs.fillInk, 1)
+			e.strokePath(path, gs.strokeInk, gs.lineWidth*gs.ctm.scale())
+		case "sh":
+			e.shadings++
+		case "BI":
+			e.inlineImages++
+		}
+		switch op.Operand {
+		case "f", "F", "f*", "S", "s", "B", "B*", "b", "b*", "n":
+			path = path[:0]
+		}
+
+		switch op.Operand {
+		// XObjects.
+		case "Do":
+			if len(op.Params) != 1 || resources == nil {
+				continue
+			}
+			name, ok := op.Params[0].(*pdfcore.PdfObjectName)
+			if !ok {
+				continue
+			}
+			stream, xtype := resources.GetXObjectByName(*name)
+			switch xtype {
+			case pdf.XObjectTypeImage:
+				err := e.drawImage(stream, gs)
+				if err != nil {
+					unicommon.Log.Debug("Image %s not included: %v", *name, err)
+				}
+			case pdf.XObjectTypeForm:
+				if depth >= maxFormDepth {
+					continue
+				}
+				xform, err := pdf.NewXObjectFormFromStream(stream)
+				if err != nil {
+					return err
+				}
+				formContents, err := xform.GetContentStream()
+				if err != nil {
+					return err
+				}
+				formGs := gs
+				if arr, ok := pdfcore.TraceToDirectObject(xform.Matrix).(*pdfcore.PdfObjectArray); ok {
+					vals, err := arr.ToFloat64Array()
+					if err == nil && len(vals) == 6 {
+						formGs.ctm = matrix{vals[0], vals[1], vals[2], vals[3], vals[4], vals[5]}.mult(gs.ctm)
+					}
+				}
+				formResources := xform.Resources
+				if formResources == nil {
+					formResources = resources
+				}
+				err = e.processContents(string(formContents), formResources, formGs, depth+1)
+				if err != nil {
+					return err
+				}
+			}
+
+		// Text.
+		default:
+			gs.apply(op, resources, e.fonts, func(g shownGlyph) {
+				e.showGlyph(g, &gs)
+			})
+		}
+	}
+
+	return nil
+}
+
+// Creates a path segment with the points (x1 y1 x2 y2 ...) transformed to device space.
+func segment(ctm matrix, op byte, coords ...float64) pathSegment {
+	seg := pathSegment{op: op}
+	for i := 0; i+1 < len(coords) && i < 6; i += 2 {
+		seg.pts[i/2][0], seg.pts[i/2][1] = ctm.transform(coords[i], coords[i+1])
+	}
+	return seg
+}
+
+// Returns the device space bounding box of the path.
+func pathBounds(path []pathSegment) goimage.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, seg := range path {
+		n := 1
+		if seg.op == 'c' {
+			n = 3
+		} else if seg.op == 'h' {
+			n = 0
+		}
+		for i := 0; i < n; i++ {
+			minX = math.Min(minX, seg.pts[i][0])
+			minY = math.Min(minY, seg.pts[i][1])
+			maxX = math.Max(maxX, seg.pts[i][0])
+			maxY = math.Max(maxY, seg.pts[i][1])
+		}
+	}
+	if minX > maxX {
+		return goimage.Rectangle{}
+	}
+	return goimage.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+// Fills the path (nonzero winding rule) with the ink, with the opacity from 0 to 1: each pixel covered by the path
+// is set to the ink, in proportion to the area covered times the opacity.
+func (e *estimator) fillPath(path []pathSegment, c ink, opacity float64) {
+	bounds := pathBounds(path).Intersect(goimage.Rect(0, 0, e.width, e.height))
+	if bounds.Empty() {
+		return
+	}
+
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	pt := func(i int, seg pathSegment) (float32, float32) {
+		return float32(seg.pts[i][0] - ox), float32(seg.pts[i][1] - oy)
+	}
+	open := false
+	for _, seg := range path {
+		switch seg.op {
+		case 'm':
+			if open {
+				z.ClosePath()
+			}
+			z.MoveTo(pt(0, seg))
+			open = true
+		case 'l':
+			z.LineTo(pt(0, seg))
+		case 'c':
+			x1, y1 := pt(0, seg)
+			x2, y2 := pt(1, seg)
+			x3, y3 := pt(2, seg)
+			z.CubeTo(x1, y1, x2, y2, x3, y3)
+		case 'h':
+			if open {
+				z.ClosePath()
+				open = false
+			}
+		}
+	}
+	if open {
+		z.ClosePath()
+	}
+
+	mask := goimage.NewAlpha(goimage.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	z.Draw(mask, mask.Bounds(), goimage.Opaque, goimage.Point{})
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			a := float64(mask.Pix[y*mask.Stride+x]) / 255 * opacity
+			if a > 0 {
+				e.paint((bounds.Min.Y+y)*e.width+bounds.Min.X+x, c, a)
+			}
+		}
+	}
+}
+
+// paint sets the pixel at index i of the planes to the ink, mixed with the ink below by the coverage a.
+func (e *estimator) paint(i int, c ink, a float64) {
+	for k := range e.planes {
+		e.planes[k][i] = float32(float64(e.planes[k][i])*(1-a) + c[k]*a)
+	}
+}
+
+// Strokes the path with the ink and line width (in pixels).  Segments are drawn as quadrilaterals with round joins
+// and caps.
+func (e *estimator) strokePath(path []pathSegment, c ink, width float64) {
+	if width < 1 {
+		// Thinnest line that can be printed, about one pixel at the default resolution.
+		width = 1
+	}
+	hw := width / 2
+
+	// Flatten to polylines.
+	polylines := [][][2]float64{}
+	var cur [][2]float64
+	for _, seg := range path {
+		switch seg.op {
+		case 'm':
+			if len(cur) > 0 {
+				polylines = append(polylines, cur)
+			}
+			cur = [][2]float64{seg.pts[0]}
+		case 'l':
+			cur = append(cur, seg.pts[0])
+		case 'c':
+			if len(cur) == 0 {
+				continue
+			}
+			p0 := cur[len(cur)-1]
+			for i := 1; i <= 16; i++ {
+				t := float64(i) / 16
+				mt := 1 - t
+				var p [2]float64
+				for k := 0; k < 2; k++ {
+					p[k] = mt*mt*mt*p0[k] + 3*mt*mt*t*seg.pts[0][k] + 3*mt*t*t*seg.pts[1][k] + t*t*t*seg.pts[2][k]
+				}
+				cur = append(cur, p)
+			}
+		case 'h':
+			if len(cur) > 0 {
+				start := cur[0]
+				cur = append(cur, start)
+				polylines = append(polylines, cur)
+				cur = [][2]float64{start}
+			}
+		}
+	}
+	if len(cur) > 1 {
+		polylines = append(polylines, cur)
+	}
+
+	// Outline path: quadrilaterals for segments and polygons approximating circles at the vertices.  All polygons
+	// are given the same orientation, so overlaps add up rather than cancel out.
+	outline := []pathSegment{}
+	addPolygon := func(pts [][2]float64) {
+		area := 0.0
+		for i := range pts {
+			j := (i + 1) % len(pts)
+			area += pts[i][0]*pts[j][1] - pts[j][0]*pts[i][1]
+		}
+		if area < 0 {
+			for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+				pts[i], pts[j] = pts[j], pts[i]
+			}
+		}
+		for i, p := range pts {
+			op := byte('l')
+			if i == 0 {
+				op = 'm'
+			}
+			outline = append(outline, pathSegment{op: op, pts: [3][2]float64{p}})
+		}
+		outline = append(outline, pathSegment{op: 'h'})
+	}
+
+	for _, pl := range polylines {
+		for i, p := range pl {
+			if hw > 0.75 {
+				circle := [][2]float64{}
+				for k := 0; k < 12; k++ {
+					a := float64(k) * math.Pi / 6
+					circle = append(circle, [2]float64{p[0] + hw*math.Cos(a), p[1] + hw*math.Sin(a)})
+				}
+				addPolygon(circle)
+			}
+			if i == 0 {
+				continue
+			}
+			q := pl[i-1]
+			dx, dy := p[0]-q[0], p[1]-q[1]
+			l := math.Hypot(dx, dy)
+			if l == 0 {
+				continue
+			}
+			nx, ny := -dy/l*hw, dx/l*hw
+			addPolygon([][2]float64{
+				{q[0] + nx, q[1] + ny},
+				{p[0] + nx, p[1] + ny},
+				{p[0] - nx, p[1] - ny},
+				{q[0] - nx, q[1] - ny},
+			})
+		}
+	}
+
+	e.fillPath(outline, c, 1)
+}
+
+// Draws an image XObject on the unit square transformed by the CTM, sampling the image at the center of each pixel.
+func (e *estimator) drawImage(stream *pdfcore.PdfObjectStream, gs graphicsState) error {
+	ximg, err := pdf.NewXObjectImageFromStream(stream)
+	if err != nil {
+		return err
+	}
+
+	var sample func(x, y int) (ink, bool)
+	var w, h int
+	if isMask, ok := pdfcore.TraceToDirectObject(ximg.ImageMask).(*pdfcore.PdfObjectBool); ok && bool(*isMask) {
+		// Stencil mask: paint the fill color where the sample is 0 (or 1 with Decode [1 0]).
+		data, err := pdfcore.DecodeStream(stream)
+		if err != nil {
+			return err
+		}
+		w, h = int(*ximg.Width), int(*ximg.Height)
+		paint := byte(0)
+		if arr, ok := pdfcore.TraceToDirectObject(ximg.Decode).(*pdfcore.PdfObjectArray); ok {
+			if vals, err := arr.ToFloat64Array(); err == nil && len(vals) == 2 && vals[0] == 1 {
+				paint = 1
+			}
+		}
+		rowBytes := (w + 7) / 8
+		sample = func(x, y int) (ink, bool) {
+			idx := y*rowBytes + x/8
+			if idx >= len(data) {
+				return ink{}, false
+			}
+			bit := (data[idx] >> uint(7-x%8)) & 1
+			return gs.fillInk, bit == paint
+		}
+	} else {
+		img, err := ximg.ToImage()
+		if err != nil {
+			return err
+		}
+		w, h = int(img.Width), int(img.Height)
+		pixelInk, err := imageInk(img, ximg.ColorSpace)
+		if err != nil {
+			return err
+		}
+		sample = func(x, y int) (ink, bool) {
+			return pixelInk(y*w + x), true
+		}
+	}
+	if w == 0 || h == 0 {
+		return nil
+	}
+
+	inv, ok := gs.ctm.inverse()
+	if !ok {
+		return nil
+	}
+
+	// Device bounding box of the unit square.
+	square := []pathSegment{
+		segment(gs.ctm, 'm', 0, 0), segment(gs.ctm, 'l', 1, 0),
+		segment(gs.ctm, 'l', 1, 1), segment(gs.ctm, 'l', 0, 1),
+	}
+	bounds := pathBounds(square).Intersect(goimage.Rect(0, 0, e.width, e.height))
+
+	// Map each device pixel back to the image (the top row of the image is at v=1).
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			u, v := inv.transform(float64(px)+0.5, float64(py)+0.5)
+			if u < 0 || u >= 1 || v <= 0 || v > 1 {
+				continue
+			}
+			ix := int(u * float64(w))
+			iy := int((1 - v) * float64(h))
+			if c, paint := sample(ix, iy); paint {
+				e.paint(py*e.width+px, c, 1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// imageInk returns a function that gives the ink of the pixel with the index i (y * width + x) of the image in the
+// color space cs.  CMYK and gray images are taken as they are, indexed images through the palette, and other images
+// are converted to RGB by the color space.
+func imageInk(img *pdf.Image, cs pdf.PdfColorspace) (func(i int) ink, error) {
+	switch cs := cs.(type) {
+	case *pdf.PdfColorspaceDeviceCMYK, *pdf.PdfColorspaceDeviceGray:
+	case *pdf.PdfColorspaceSpecialIndexed:
+		palette, err := inkPalette(cs)
+		if err != nil {
+			return nil, err
+		}
+		samples := img.GetSamples()
+		return func(i int) ink {
+			if i >= len(samples) {
+				return ink{}
+			}
+			index := int(samples[i])
+			if index >= len(palette) {
+				index = len(palette) - 1
+			}
+			return palette[index]
+		}, nil
+	default:
+		rgbImg, err := cs.ImageToRGB(*img)
+		if err != nil {
+			return nil, err
+		}
+		img = &rgbImg
+	}
+
+	samples := img.GetSamples()
+	n := img.ColorComponents
+	maxVal := math.Pow(2, float64(img.BitsPerComponent)) - 1
+	return func(i int) ink {
+		if (i+1)*n > len(samples) {
+			return ink{}
+		}
+		vals := make([]float64, n)
+		for k := range vals {
+			vals[k] = float64(samples[i*n+k]) / maxVal
+		}
+		c, _ := deviceInk(vals)
+		return c
+	}, nil
+}
+
+// inkPalette returns the palette of the indexed color space as inks.  The lookup table has one entry per index up to
+// HiVal, each with a byte per component of the base color space.
+func inkPalette(cs *pdf.PdfColorspaceSpecialIndexed) ([]ink, error) {
+	var lookup []byte
+	switch obj := pdfcore.TraceToDirectObject(cs.Lookup).(type) {
+	case *pdfcore.PdfObjectString:
+		lookup = []byte(*obj)
+	case *pdfcore.PdfObjectStream:
+		decoded, err := pdfcore.DecodeStream(obj)
+		if err != nil {
+			return nil, err
+		}
+		lookup = decoded
+	default:
+		return nil, errors.New("Invalid indexed color space lookup table")
+	}
+
+	n := cs.Base.GetNumComponents()
+	entries := cs.HiVal + 1
+	if len(lookup)/n < entries {
+		entries = len(lookup) / n
+	}
+	if entries == 0 {
+		return nil, errors.New("Empty indexed color space lookup table")
+	}
+
+	palette := make([]ink, entries)
+	for i := range palette {
+		vals := make([]pdfcore.PdfObject, n)
+		for j := range vals {
+			vals[j] = pdfcore.MakeFloat(float64(lookup[i*n+j]) / 255)
+		}
+		c, err := colorspaceInk(cs.Base, vals)
+		if err != nil {
+			return nil, err
+		}
+		palette[i] = c
+	}
+	return palette, nil
+}
+
+// colorInk returns the ink of the color with the components params in the color space cs: no ink for a pattern,
+// which is not included, and the ink c if the color cannot be converted.
+func (e *estimator) colorInk(cs pdf.PdfColorspace, params []pdfcore.PdfObject, c ink) ink {
+	if _, isPattern := cs.(*pdf.PdfColorspaceSpecialPattern); isPattern {
+		e.patterns++
+		return ink{}
+	}
+	converted, err := colorspaceInk(cs, params)
+	if err != nil {
+		unicommon.Log.Debug("Color not converted: %v", err)
+		return c
+	}
+	return converted
+}
+
+// colorspaceInk returns the ink of the color with the components vals in the color space cs.
+func colorspaceInk(cs pdf.PdfColorspace, vals []pdfcore.PdfObject) (ink, error) {
+	color, err := cs.ColorFromPdfObjects(vals)
+	if err != nil {
+		return ink{}, err
+	}
+	switch color := color.(type) {
+	case *pdf.PdfColorDeviceCMYK:
+		return ink{color.C(), color.M(), color.Y(), color.K()}, nil
+	case *pdf.PdfColorDeviceGray:
+		return ink{0, 0, 0, 1 - color.Val()}, nil
+	}
+
+	rgb, err := cs.ColorToRGB(color)
+	if err != nil {
+		return ink{}, err
+	}
+	rgbColor, ok := rgb.(*pdf.PdfColorDeviceRGB)
+	if !ok {
+		return ink{}, fmt.Errorf("Unexpected RGB color type %T", rgb)
+	}
+	c, _ := deviceInk([]float64{rgbColor.R(), rgbColor.G(), rgbColor.B()})
+	return c, nil
+}
+
+// deviceInk converts color components (gray, RGB or CMYK depending on the number) to ink.  Gray is printed with black
+// only, and RGB with the naive conversion to CMYK.
+func deviceInk(vals []float64) (ink, bool) {
+	clamp := func(v float64) float64 {
+		return math.Max(0, math.Min(1, v))
+	}
+	switch len(vals) {
+	case 1:
+		return ink{0, 0, 0, 1 - clamp(vals[0])}, true
+	case 3:
+		r, g, b := clamp(vals[0]), clamp(vals[1]), clamp(vals[2])
+		k := 1 - math.Max(r, math.Max(g, b))
+		if k >= 1 {
+			return ink{0, 0, 0, 1}, true
+		}
+		return ink{(1 - r - k) / (1 - k), (1 - g - k) / (1 - k), (1 - b - k) / (1 - k), k}, true
+	case 4:
+		return ink{clamp(vals[0]), clamp(vals[1]), clamp(vals[2]), clamp(vals[3])}, true
+	}
+	return ink{}, false
+}
+
+// lookupColorspace returns the color space named by the cs or CS operands: a device color space, or one defined in
+// the resources.  Unknown color spaces are taken as DeviceGray.
+func lookupColorspace(params []pdfcore.PdfObject, resources *pdf.PdfPageResources) pdf.PdfColorspace {
+	if len(params) != 1 {
+		return pdf.NewPdfColorspaceDeviceGray()
+	}
+	name, ok := params[0].(*pdfcore.PdfObjectName)
+	if !ok {
+		return pdf.NewPdfColorspaceDeviceGray()
+	}
+	switch *name {
+	case "DeviceGray", "G":
+		return pdf.NewPdfColorspaceDeviceGray()
+	case "DeviceRGB", "RGB":
+		return pdf.NewPdfColorspaceDeviceRGB()
+	case "DeviceCMYK", "CMYK":
+		return pdf.NewPdfColorspaceDeviceCMYK()
+	case "Pattern":
+		return pdf.NewPdfColorspaceSpecialPattern()
+	}
+	if resources != nil && resources.ColorSpace != nil {
+		if cs, has := resources.ColorSpace.Colorspaces[string(*name)]; has {
+			return cs
+		}
+	}
+	unicommon.Log.Debug("Color space %s not found, taken as DeviceGray", *name)
+	return pdf.NewPdfColorspaceDeviceGray()
+}
+
+// Paints the glyph as its box, from the descent to the ascent and of the glyph width, covered to textInk by the
+// fill ink.
+func (e *estimator) showGlyph(g shownGlyph, gs *graphicsState) {
+	// Invisible text (render mode 3, and 7 which only clips) has no ink.
+	if gs.renderMode == 3 || gs.renderMode == 7 {
+		return
+	}
+	box := []pathSegment{
+		segment(g.trm, 'm', 0, g.font.descent), segment(g.trm, 'l', g.width, g.font.descent),
+		segment(g.trm, 'l', g.width, g.font.ascent), segment(g.trm, 'l', 0, g.font.ascent),
+		{op: 'h'},
+	}
+	e.fillPath(box, gs.fillInk, textInk)
+}
@@ -0,0 +1,503 @@
+/*
+ * Export the comments of a PDF file (markup annotations) as a review summary: as JSON, and optionally as a summary
+ * PDF.
+ *
+ * The markup annotations are the comments and markup made by reviewers: sticky notes (Text), text boxes (FreeText),
+ * highlights, underlines, strike-outs, shapes, ink, stamps and the like.  Links, form field widgets and popup windows
+ * are not comments and are left out.  For each comment the type, author, subject, contents, date, review state and
+ * rectangle are exported.  The date is the modification date, or the creation date if there is none.
+ *
+ * The comments are grouped by page and sorted by their position, from top to bottom and left to right.  Replies (the
+ * annotations with an IRT entry, "in reply to") are nested under the comment they reply to, sorted by date, so a
+ * discussion reads as a thread.  Replies that set a review state (e.g. Accepted or Rejected) have the state set.
+ *
+ * The JSON is printed to the console unless -json is given.  With -pdf, the summary is also written as a PDF, with
+ * the replies indented under their comments.  Characters that are not in the WinAnsi encoding are shown as "?" in
+ * the PDF.
+ *
+ * Run as: go run export_comments.go [-json comments.json] [-pdf summary.pdf] input.pdf
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+const usage = "Usage: go run export_comments.go [-json comments.json] [-pdf summary.pdf] input.pdf\n"
+
+// The markup annotation subtypes.
+var markupSubtypes = map[string]bool{
+	"Text": true, "FreeText": true, "Line": true, "Square": true, "Circle": true, "Polygon": true, "PolyLine": true,
+	"Highlight": true, "Underline": true, "Squiggly": true, "StrikeOut": true, "Stamp": true, "Caret": true,
+	"Ink": true, "FileAttachment": true, "Sound": true, "Redact": true,
+}
+
+// Indentation of each reply level in the summary PDF.
+const replyIndent = 18.0
+
+func main() {
+	jsonPath := ""
+	pdfPath := ""
+	flag.StringVar(&jsonPath, "json", "", "JSON output file (default: print to the console)")
+	flag.StringVar(&pdfPath, "pdf", "", "Summary PDF output file")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	err := exportComments(inputPath, jsonPath, pdfPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// comment is a markup annotation with its replies.
+type comment struct {
+	Type     string     `json:"type"`
+	Author   string     `json:"author,omitempty"`
+	Subject  string     `json:"subject,omitempty"`
+	Contents string     `json:"contents,omitempty"`
+	Date     string     `json:"date,omitempty"`  // RFC 3339.
+	State    string     `json:"state,omitempty"` // Review state set by a reply, e.g. "Review: Accepted".
+	Rect     [4]float64 `json:"rect"`            // Llx, Lly, Urx, Ury.
+	Replies  []*comment `json:"replies,omitempty"`
+
+	page  int
+	date  time.Time
+	order int                          // Order of the annotation in the document.
+	obj   *pdfcore.PdfIndirectObject   // The annotation object.
+	irt   pdfcore.PdfObject            // The annotation replied to.
+	dict  *pdfcore.PdfObjectDictionary // The annotation dictionary.
+}
+
+// pageComments are the comments of a page.
+type pageComments struct {
+	Page     int        `json:"page"`
+	Comments []*comment `json:"comments"`
+}
+
+func exportComments(inputPath, jsonPath, pdfPath string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Encrypted PDF, a password is required")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	comments := []*comment{}
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+		for _, annot := range page.Annotations {
+			if c := newComment(annot, pageNum, len(comments)); c != nil {
+				comments = append(comments, c)
+			}
+		}
+	}
+
+	pages, numReplies := buildThreads(comments)
+	fmt.Fprintf(os.Stderr, "Exported %d comments and %d replies on %d pages\n", len(comments)-numReplies,
+		numReplies, len(pages))
+
+	data, err := json.MarshalIndent(pages, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if jsonPath == "" {
+		os.Stdout.Write(data)
+	} else {
+		err := ioutil.WriteFile(jsonPath, data, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "JSON written to %s\n", jsonPath)
+	}
+
+	if pdfPath != "" {
+		err := writeSummary(pages, filepath.Base(inputPath), len(comments)-numReplies, numReplies, pdfPath)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Summary written to %s\n", pdfPath)
+	}
+	return nil
+}
+
+// Returns the comment of a markup annotation, or nil if the annotation is not a markup annotation.
+func newComment(annot *pdf.PdfAnnotation, pageNum, order int) *comment {
+	obj, ok := annot.GetContainingPdfObject().(*pdfcore.PdfIndirectObject)
+	if !ok {
+		return nil
+	}
+	dict, ok := obj.PdfObject.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	subtype := nameValue(dict.Get("Subtype"))
+	if !markupSubtypes[subtype] {
+		return nil
+	}
+
+	c := &comment{
+		Type:     subtype,
+		Author:   stringValue(dict.Get("T")),
+		Subject:  stringValue(dict.Get("Subj")),
+		Contents: strings.Replace(stringValue(dict.Get("Contents")), "\r\n", "\n", -1),
+		page:     pageNum,
+		order:    order,
+		obj:      obj,
+		irt:      dict.Get("IRT"),
+		dict:     dict,
+	}
+
+	date := stringValue(dict.Get("M"))
+	if date == "" {
+		date = stringValue(dict.Get("CreationDate"))
+	}
+	if t, ok := parsePdfDate(date); ok {
+		c.date = t
+		c.Date = t.Format(time.RFC3339)
+	}
+
+	if state := stringValue(dict.Get("State")); state != "" {
+		c.State = state
+		if model := stringValue(dict.Get("StateModel")); model != "" {
+			c.State = model + ": " + state
+		}
+	}
+
+	if arr, ok := pdfcore.TraceToDirectObject(dict.Get("Rect")).(*pdfcore.PdfObjectArray); ok && len(*arr) == 4 {
+		rect, err := pdf.NewPdfRectangle(*arr)
+		if err == nil {
+			c.Rect = [4]float64{rect.Llx, rect.Lly, rect.Urx, rect.Ury}
+		}
+	}
+	return c
+}
+
+// Nests the replies under the comments they reply to, and groups the other comments by page.  Returns the pages
+// with comments and the number of replies.
+func buildThreads(comments []*comment) ([]*pageComments, int) {
+	numReplies := 0
+	pages := []*pageComments{}
+	byPage := map[int]*pageComments{}
+	for _, c := range comments {
+		var parent *comment
+		if c.irt != nil {
+			for _, other := range comments {
+				if other != c && refersTo(c.irt, other.obj) {
+					parent = other
+					break
+				}
+			}
+		}
+		if parent != nil {
+			parent.Replies = append(parent.Replies, c)
+			numReplies++
+			continue
+		}
+
+		pc := byPage[c.page]
+		if pc == nil {
+			pc = &pageComments{Page: c.page}
+			byPage[c.page] = pc
+			pages = append(pages, pc)
+		}
+		pc.Comments = append(pc.Comments, c)
+	}
+
+	// The comments from top to bottom and left to right, the replies by date.
+	for _, pc := range pages {
+		sort.SliceStable(pc.Comments, func(i, j int) bool {
+			a, b := pc.Comments[i].Rect, pc.Comments[j].Rect
+			if a[3] != b[3] {
+				return a[3] > b[3]
+			}
+			return a[0] < b[0]
+		})
+	}
+	for _, c := range comments {
+		sort.SliceStable(c.Replies, func(i, j int) bool {
+			a, b := c.Replies[i], c.Replies[j]
+			if !a.date.Equal(b.date) {
+				return a.date.Before(b.date)
+			}
+			return a.order < b.order
+		})
+	}
+	return pages, numReplies
+}
+
+// Writes the summary PDF.
+func writeSummary(pages []*pageComments, fileName string, numComments, numReplies int, outputPath string) error {
+	c := creator.New()
+	c.NewPage()
+
+	title := creator.NewParagraph(winAnsiText("Review summary: " + fileName))
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(16)
+	title.SetMargins(0, 0, 0, 4)
+	err := c.Draw(title)
+	if err != nil {
+		return err
+	}
+	info := creator.NewParagraph(fmt.Sprintf("%d comments and %d replies on %d pages", numComments, numReplies,
+		len(pages)))
+	info.SetFontSize(10)
+	info.SetColor(creator.ColorRGBFrom8bit(100, 100, 100))
+	err = c.Draw(info)
+	if err != nil {
+		return err
+	}
+
+	for _, pc := range pages {
+		heading := creator.NewParagraph(fmt.Sprintf("Page %d", pc.Page))
+		heading.SetFont(fonts.NewFontHelveticaBold())
+		heading.SetFontSize(13)
+		heading.SetMargins(0, 0, 14, 2)
+		err = c.Draw(heading)
+		if err != nil {
+			return err
+		}
+		for _, cm := range pc.Comments {
+			err = drawComment(c, cm, 0)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Draws a comment and its replies, indented by the reply level.
+func drawComment(c *creator.Creator, cm *comment, level int) error {
+	indent := float64(level) * replyIndent
+
+	// Header: the type (for replies, that it is a reply), the author and the date.
+	header := cm.Type
+	if level > 0 {
+		header = "Reply"
+	}
+	if cm.Subject != "" && cm.Subject != cm.Type {
+		header += " (" + cm.Subject + ")"
+	}
+	if cm.Author != "" {
+		header += " by " + cm.Author
+	}
+	if !cm.date.IsZero() {
+		header += ", " + cm.date.Format("2006-01-02 15:04")
+	}
+	p := creator.NewParagraph(winAnsiText(header))
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(10)
+	p.SetMargins(indent, 0, 8, 1)
+	err := c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	if cm.State != "" {
+		p := creator.NewParagraph(winAnsiText("State: " + cm.State))
+		p.SetFont(fonts.NewFontHelveticaOblique())
+		p.SetFontSize(10)
+		p.SetColor(creator.ColorRGBFrom8bit(100, 100, 100))
+		p.SetMargins(indent, 0, 0, 1)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cm.Contents != "" {
+		p := creator.NewParagraph(winAnsiText(cm.Contents))
+		p.SetFontSize(10)
+		p.SetMargins(indent, 0, 0, 0)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, reply := range cm.Replies {
+		err := drawComment(c, reply, level+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the text with the line breaks as "\n", and the characters that are not in the WinAnsi encoding replaced by
+// "?".
+func winAnsiText(text string) string {
+	encoder := textencoding.NewWinAnsiTextEncoder()
+	text = strings.Replace(text, "\r", "\n", -1)
+	return strings.Map(func(r rune) rune {
+		if r == '\n' {
+			return r
+		}
+		if r == '\t' {
+			return ' '
+		}
+		if _, found := encoder.RuneToGlyph(r); !found || r < ' ' {
+			return '?'
+		}
+		return r
+	}, text)
+}
+
+// Returns true if obj is the target object or a reference to it.
+func refersTo(obj, target pdfcore.PdfObject) bool {
+	if obj == nil {
+		return false
+	}
+	if obj == target {
+		return true
+	}
+	ref, isRef := obj.(*pdfcore.PdfObjectReference)
+	ind, isIndirect := target.(*pdfcore.PdfIndirectObject)
+	return isRef && isIndirect && ref.ObjectNumber == ind.ObjectNumber
+}
+
+// Returns the value of a name object, or "" if obj is not a name.
+func nameValue(obj pdfcore.PdfObject) string {
+	if name, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectName); ok {
+		return string(*name)
+	}
+	return ""
+}
+
+// Returns the decoded value of a text string object, or the value of a name object, or "" for other objects.
+func stringValue(obj pdfcore.PdfObject) string {
+	switch t := pdfcore.TraceToDirectObject(obj).(type) {
+	case *pdfcore.PdfObjectString:
+		return decodePdfString(string(*t))
+	case *pdfcore.PdfObjectName:
+		return string(*t)
+	}
+	return ""
+}
+
+// Decodes a PDF text string: UTF-16BE with a byte order mark, or PDFDocEncoding (read as Latin-1).
+func decodePdfString(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := []uint16{}
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// Parses a PDF date string, e.g. D:20180213153000+01'00'.  All parts after the year are optional.
+func parsePdfDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(s, "D:")
+	if len(s) < 4 {
+		return time.Time{}, false
+	}
+
+	// Year, month, day, hour, minute, second.
+	fields := []int{0, 1, 1, 0, 0, 0}
+	widths := []int{4, 2, 2, 2, 2, 2}
+	pos := 0
+	for i, width := range widths {
+		if pos+width > len(s) || s[pos] < '0' || s[pos] > '9' {
+			break
+		}
+		v, err := strconv.Atoi(s[pos : pos+width])
+		if err != nil {
+			return time.Time{}, false
+		}
+		fields[i] = v
+		pos += width
+	}
+
+	loc := time.UTC
+	if pos < len(s) && (s[pos] == '+' || s[pos] == '-') {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, s[pos+1:])
+		offset := 0
+		if len(digits) >= 2 {
+			hours, _ := strconv.Atoi(digits[:2])
+			offset = hours * 3600
+		}
+		if len(digits) >= 4 {
+			minutes, _ := strconv.Atoi(digits[2:4])
+			offset += minutes * 60
+		}
+		if s[pos] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+
+	return time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, loc), true
+}
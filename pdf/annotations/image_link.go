@@ -0,0 +1,238 @@
+/*
+ * Make images clickable: a Link annotation with a URI action is placed over each image, so clicking the image opens
+ * the URL in a browser.
+ *
+ * The link rectangle is computed from where the creator placed the image and its size after scaling (the image
+ * Width and Height):
+ * - For an image at an absolute position (SetPos), the rectangle starts at that position.
+ * - For an image in the flow of the page, the creator places it at the current drawing context position offset by
+ *   the image's left and top margins, and advances the context by the image height plus the top and bottom margins.
+ *   The position is taken from the context before drawing, and checked against the context after drawing.
+ * The rectangles are converted to PDF coordinates (origin at the lower left corner of the page) for the annotations,
+ * so the links cover the images exactly.  Use -border to draw the link borders and check the alignment.
+ *
+ * Run as: go run image_link.go [-url https://unidoc.io] [-border] image.jpg output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run image_link.go [-url https://unidoc.io] [-border] image.jpg output.pdf\n"
+
+// imageLink is the link area of an image with the target URL.  Coordinates from the upper left corner of the page as
+// in the creator.
+type imageLink struct {
+	x, y, width, height float64
+	url                 string
+}
+
+func main() {
+	url := ""
+	drawBorders := false
+	flag.StringVar(&url, "url", "https://unidoc.io", "URL opened when clicking the images")
+	flag.BoolVar(&drawBorders, "border", false, "Draw the link borders")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	imagePath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := createImageLinkPage(imagePath, outputPath, url, drawBorders)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createImageLinkPage(imagePath, outputPath, url string, drawBorders bool) error {
+	c := creator.New()
+
+	// The page is created here rather than with c.NewPage, to be able to add the annotations to it.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: c.Width(), Ury: c.Height()}
+	page.Resources = pdf.NewPdfPageResources()
+	err := c.AddPage(page)
+	if err != nil {
+		return err
+	}
+
+	margin := 72.0
+	y := margin
+	links := []imageLink{}
+
+	caption := func(text string) error {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(11)
+		p.SetWidth(c.Width() - 2*margin)
+		p.SetPos(margin, y)
+		y += p.Height() + 6
+		return c.Draw(p)
+	}
+
+	title := creator.NewParagraph("Clickable images")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(18)
+	title.SetPos(margin, y)
+	err = c.Draw(title)
+	if err != nil {
+		return err
+	}
+	y += title.Height() + 16
+
+	// An image at an absolute position, scaled to a width.
+	err = caption("Scaled to a width of 150 points, at an absolute position:")
+	if err != nil {
+		return err
+	}
+	img, err := creator.NewImageFromFile(imagePath)
+	if err != nil {
+		return err
+	}
+	img.ScaleToWidth(150)
+	link, err := drawImageAt(c, img, margin, y, url)
+	if err != nil {
+		return err
+	}
+	links = append(links, link)
+	y += img.Height() + 20
+
+	// An image scaled non-uniformly, at an absolute position.
+	err = caption("Stretched horizontally (scaled by 2.5 x 0.5 of a width of 100 points):")
+	if err != nil {
+		return err
+	}
+	img, err = creator.NewImageFromFile(imagePath)
+	if err != nil {
+		return err
+	}
+	img.ScaleToWidth(100)
+	img.Scale(2.5, 0.5)
+	link, err = drawImageAt(c, img, margin, y, url)
+	if err != nil {
+		return err
+	}
+	links = append(links, link)
+	y += img.Height() + 20
+
+	// An image in the flow of the page with margins, which offset it from the drawing context position.
+	err = caption("In the page flow, scaled to a height of 60 points, with margins of 40 points left, 10 points " +
+		"top and 20 points bottom:")
+	if err != nil {
+		return err
+	}
+	img, err = creator.NewImageFromFile(imagePath)
+	if err != nil {
+		return err
+	}
+	img.ScaleToHeight(60)
+	img.SetMargins(40, 0, 10, 20)
+	c.MoveTo(margin, y)
+	link, err = drawImageInFlow(c, img, url)
+	if err != nil {
+		return err
+	}
+	links = append(links, link)
+	y = c.Context().Y
+
+	err = caption("The flow continues below the bottom margin of the image.")
+	if err != nil {
+		return err
+	}
+
+	addImageLinks(page, links, drawBorders)
+	fmt.Printf("Added %d image links\n", len(links))
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawImageAt draws the image with its upper left corner at (x, y) and returns the link area covering it.
+func drawImageAt(c *creator.Creator, img *creator.Image, x, y float64, url string) (imageLink, error) {
+	img.SetPos(x, y)
+	err := c.Draw(img)
+	if err != nil {
+		return imageLink{}, err
+	}
+	return imageLink{x: x, y: y, width: img.Width(), height: img.Height(), url: url}, nil
+}
+
+// drawImageInFlow draws the image at the current position of the drawing context and returns the link area covering
+// it.  The image must fit on the current page: the creator would otherwise move it to a new page, and the link would
+// be on the wrong page.
+func drawImageInFlow(c *creator.Creator, img *creator.Image, url string) (imageLink, error) {
+	left, _, top, bottom := img.GetMargins()
+	before := c.Context()
+	if before.Y+top+img.Height()+bottom > before.PageHeight {
+		return imageLink{}, errors.New("Image does not fit on the page")
+	}
+
+	err := c.Draw(img)
+	if err != nil {
+		return imageLink{}, err
+	}
+
+	// The image is drawn at the context position offset by the margins, and the context is moved below the image
+	// and its bottom margin.
+	link := imageLink{x: before.X + left, y: before.Y + top, width: img.Width(), height: img.Height(), url: url}
+	after := c.Context()
+	if after.Page != before.Page || math.Abs(after.Y-(link.y+link.height+bottom)) > 1e-6 {
+		return imageLink{}, errors.New("Image was not placed at the expected position")
+	}
+	return link, nil
+}
+
+// Adds a Link annotation with a URI action for each link to the page.  The borders are drawn in blue if drawBorders
+// is set, otherwise they are invisible (border width 0).
+func addImageLinks(page *pdf.PdfPage, links []imageLink, drawBorders bool) {
+	pageHeight := page.MediaBox.Ury - page.MediaBox.Lly
+
+	for _, l := range links {
+		action := pdfcore.MakeDict()
+		action.Set("S", pdfcore.MakeName("URI"))
+		action.Set("URI", pdfcore.MakeString(l.url))
+
+		annot := pdf.NewPdfAnnotationLink()
+		// The annotation rectangle is in PDF coordinates, with the origin in the lower left corner.
+		annot.Rect = pdfcore.MakeArrayFromFloats([]float64{
+			page.MediaBox.Llx + l.x, page.MediaBox.Lly + pageHeight - l.y - l.height,
+			page.MediaBox.Llx + l.x + l.width, page.MediaBox.Lly + pageHeight - l.y,
+		})
+		annot.A = action
+		// Invert the image area when clicked.
+		annot.H = pdfcore.MakeName("I")
+		if drawBorders {
+			annot.Border = pdfcore.MakeArrayFromIntegers([]int{0, 0, 1})
+			annot.C = pdfcore.MakeArrayFromFloats([]float64{0, 0.27, 0.67})
+		} else {
+			annot.Border = pdfcore.MakeArrayFromIntegers([]int{0, 0, 0})
+		}
+
+		page.Annotations = append(page.Annotations, annot.PdfAnnotation)
+	}
+}
@@ -0,0 +1,302 @@
+/*
+ * Mark up a page of a PDF file: highlight text and add a sticky note (text annotation) next to it.
+ *
+ * The highlighted area is given as rectangles, one per line of text, separated by semicolons: x,y,width,height;...
+ * The coordinates are in the PDF coordinate system, where 0,0 is in the lower left corner.  A highlight across
+ * several lines is a single annotation with one quadrilateral (QuadPoints) per line.  The sticky note is placed in
+ * the left margin of the first line.
+ *
+ * Both annotations get the author, contents (the note text) and creation date.  They also get appearance streams,
+ * so that they look the same in all viewers, including those that do not generate appearances themselves:
+ * - The highlight fills the quadrilaterals with the multiply blend mode, so the text remains visible.
+ * - The sticky note is drawn as a small note icon.
+ *
+ * Run as: go run markup.go [-author name] [-note text] [-color #ffeb3b] input.pdf <page> <rectangles> output.pdf
+ * For example: go run markup.go input.pdf 1 "72,700,468,12;72,686,200,12" output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run markup.go [-author name] [-note text] [-color #ffeb3b] input.pdf <page> <rectangles> " +
+	"output.pdf\n"
+
+// Size of the sticky note icon.
+const noteSize = 20.0
+
+// Annotation flags (F): print the annotation, and do not scale or rotate the note icon with the page.
+const (
+	annotFlagPrint    = 4
+	annotFlagNoZoom   = 8
+	annotFlagNoRotate = 16
+)
+
+// markup holds the properties common to the annotations.
+type markup struct {
+	author   string
+	contents string
+	date     string
+	color    creator.Color
+}
+
+func main() {
+	author := ""
+	note := ""
+	colorHex := ""
+	flag.StringVar(&author, "author", "Reviewer", "Author of the annotations")
+	flag.StringVar(&note, "note", "Please check this passage.", "Text of the sticky note")
+	flag.StringVar(&colorHex, "color", "#ffeb3b", "Highlight color")
+	flag.Parse()
+
+	if flag.NArg() < 4 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+	if len(colorHex) != 7 || colorHex[0] != '#' {
+		fmt.Printf("Error: invalid color %q (use #rrggbb)\n", colorHex)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	pageNum, err := strconv.Atoi(flag.Arg(1))
+	if err != nil {
+		fmt.Printf("Error: invalid page number %q\n", flag.Arg(1))
+		os.Exit(1)
+	}
+	rects, err := parseRectangles(flag.Arg(2))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputPath := flag.Arg(3)
+
+	m := markup{
+		author:   author,
+		contents: note,
+		date:     formatPdfDate(time.Now()),
+		color:    creator.ColorRGBFromHex(colorHex),
+	}
+
+	err = markupPdf(inputPath, outputPath, pageNum, rects, m)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func markupPdf(inputPath, outputPath string, pageNum int, rects []pdf.PdfRectangle, m markup) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if pageNum < 1 || pageNum > numPages {
+		return fmt.Errorf("Page %d out of range (document has %d pages)", pageNum, numPages)
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+
+		if i+1 == pageNum {
+			highlight, err := newHighlightAnnotation(rects, m)
+			if err != nil {
+				return err
+			}
+
+			// Note in the left margin, aligned with the top of the first line.
+			x := math.Max(rects[0].Llx-noteSize-4, 0)
+			y := rects[0].Ury - noteSize
+			note, err := newNoteAnnotation(x, y, m)
+			if err != nil {
+				return err
+			}
+
+			page.Annotations = append(page.Annotations, highlight, note)
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Parses the rectangles: x,y,width,height separated by semicolons.
+func parseRectangles(s string) ([]pdf.PdfRectangle, error) {
+	rects := []pdf.PdfRectangle{}
+	for _, part := range strings.Split(s, ";") {
+		values := strings.Split(part, ",")
+		if len(values) != 4 {
+			return nil, fmt.Errorf("Invalid rectangle %q (use x,y,width,height)", part)
+		}
+		v := make([]float64, 4)
+		for i, value := range values {
+			var err error
+			v[i], err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid rectangle %q (use x,y,width,height)", part)
+			}
+		}
+		if v[2] <= 0 || v[3] <= 0 {
+			return nil, fmt.Errorf("Invalid rectangle %q: width and height must be positive", part)
+		}
+		rects = append(rects, pdf.PdfRectangle{Llx: v[0], Lly: v[1], Urx: v[0] + v[2], Ury: v[1] + v[3]})
+	}
+	return rects, nil
+}
+
+// Formats the time as a PDF date string, e.g. D:20180213153000+01'00'.
+func formatPdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+// Sets the properties common to markup annotations.
+func setMarkupProperties(annot *pdf.PdfAnnotation, markupAnnot *pdf.PdfAnnotationMarkup, m markup) {
+	annot.Contents = pdfcore.MakeString(m.contents)
+	annot.M = pdfcore.MakeString(m.date)
+	markupAnnot.T = pdfcore.MakeString(m.author)
+	markupAnnot.CreationDate = pdfcore.MakeString(m.date)
+}
+
+// Creates a highlight annotation covering the rectangles, with a quadrilateral per rectangle.
+func newHighlightAnnotation(rects []pdf.PdfRectangle, m markup) (*pdf.PdfAnnotation, error) {
+	r, g, b := m.color.ToRGB()
+
+	highlight := pdf.NewPdfAnnotationHighlight()
+	setMarkupProperties(highlight.PdfAnnotation, highlight.PdfAnnotationMarkup, m)
+	highlight.C = pdfcore.MakeArrayFromFloats([]float64{r, g, b})
+	highlight.F = pdfcore.MakeInteger(annotFlagPrint)
+
+	// The points of each quadrilateral are in the order upper left, upper right, lower left, lower right, which is
+	// the order used by the common viewers (rather than the counterclockwise order of the specification).
+	quadPoints := []float64{}
+	bbox := rects[0]
+	content := fmt.Sprintf("q\n/GS0 gs\n%.4f %.4f %.4f rg\n", r, g, b)
+	for _, rect := range rects {
+		quadPoints = append(quadPoints,
+			rect.Llx, rect.Ury, rect.Urx, rect.Ury,
+			rect.Llx, rect.Lly, rect.Urx, rect.Lly)
+		content += fmt.Sprintf("%.4f %.4f %.4f %.4f re\n", rect.Llx, rect.Lly, rect.Urx-rect.Llx,
+			rect.Ury-rect.Lly)
+
+		bbox.Llx = math.Min(bbox.Llx, rect.Llx)
+		bbox.Lly = math.Min(bbox.Lly, rect.Lly)
+		bbox.Urx = math.Max(bbox.Urx, rect.Urx)
+		bbox.Ury = math.Max(bbox.Ury, rect.Ury)
+	}
+	content += "f\nQ\n"
+	highlight.QuadPoints = pdfcore.MakeArrayFromFloats(quadPoints)
+	highlight.Rect = bbox.ToPdfObject()
+
+	// Appearance in page coordinates: the BBox is the annotation Rect, so no transformation is needed.
+	gs := pdfcore.MakeDict()
+	gs.Set("BM", pdfcore.MakeName("Multiply"))
+	form := pdf.NewXObjectForm()
+	form.Resources = pdf.NewPdfPageResources()
+	err := form.Resources.AddExtGState("GS0", gs)
+	if err != nil {
+		return nil, err
+	}
+	form.BBox = bbox.ToPdfObject()
+	err = form.SetContentStream([]byte(content), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	apDict := pdfcore.MakeDict()
+	apDict.Set("N", form.ToPdfObject())
+	highlight.AP = apDict
+
+	return highlight.PdfAnnotation, nil
+}
+
+// Creates a sticky note annotation with the lower left corner of the icon at (x, y).
+func newNoteAnnotation(x, y float64, m markup) (*pdf.PdfAnnotation, error) {
+	note := pdf.NewPdfAnnotationText()
+	setMarkupProperties(note.PdfAnnotation, note.PdfAnnotationMarkup, m)
+	note.Rect = pdfcore.MakeArrayFromFloats([]float64{x, y, x + noteSize, y + noteSize})
+	note.C = pdfcore.MakeArrayFromFloats([]float64{1, 0.92, 0.23})
+	note.F = pdfcore.MakeInteger(annotFlagPrint | annotFlagNoZoom | annotFlagNoRotate)
+	note.Name = pdfcore.MakeName("Comment")
+	open := pdfcore.PdfObjectBool(false)
+	note.Open = &open
+
+	// Note icon: a yellow sheet with lines of text.
+	content := "q\n1 0.92 0.23 rg\n0.4 0.4 0.4 RG\n1 w\n0.5 0.5 19 19 re\nB\n" +
+		"0.75 w\n4 14 m\n16 14 l\n4 10 m\n16 10 l\n4 6 m\n12 6 l\nS\nQ\n"
+	form := pdf.NewXObjectForm()
+	form.Resources = pdf.NewPdfPageResources()
+	form.BBox = pdfcore.MakeArrayFromFloats([]float64{0, 0, noteSize, noteSize})
+	err := form.SetContentStream([]byte(content), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	apDict := pdfcore.MakeDict()
+	apDict.Set("N", form.ToPdfObject())
+	note.AP = apDict
+
+	return note.PdfAnnotation, nil
+}
@@ -0,0 +1,256 @@
+/*
+ * Remove the annotations from a PDF file: comments, highlights, links and other markup, leaving the page contents
+ * as they are.
+ *
+ * By default all annotations are removed except the widgets of the form fields, which are part of the interactive
+ * form (AcroForm): removing them would leave fields that cannot be seen or filled in.  With -forms, the widgets are
+ * removed as well, together with the form.  With -types, only the annotations of the given subtypes are removed,
+ * e.g. -types Link or -types Highlight,Underline,StrikeOut,Text (names as in the PDF, case is ignored; Widget in the
+ * list is the same as -forms).
+ *
+ * Annotations that belong to a removed annotation are removed with it: its popup window (Popup annotation), and the
+ * replies to it (annotations with an IRT entry pointing to it).  Popups are kept with the annotation they belong to.
+ *
+ * The Annots array of each page is rewritten with the remaining annotations.  The output contains only the objects
+ * referenced from the pages (and the form), so the removed annotation objects are not written.
+ *
+ * Run as: go run strip_annotations.go [-types Link,Text,...] [-forms] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run strip_annotations.go [-types Link,Text,...] [-forms] input.pdf output.pdf\n"
+
+func main() {
+	types := ""
+	forms := false
+	flag.StringVar(&types, "types", "", "Comma separated annotation subtypes to remove (default: all but Widget)")
+	flag.BoolVar(&forms, "forms", false, "Remove the form fields (Widget annotations and the AcroForm) too")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	// The subtypes to remove, lower case.  nil removes all.
+	var subtypes map[string]bool
+	if types != "" {
+		subtypes = map[string]bool{}
+		for _, t := range strings.Split(types, ",") {
+			t = strings.ToLower(strings.TrimSpace(t))
+			if t == "widget" {
+				forms = true
+			}
+			subtypes[t] = true
+		}
+	}
+
+	err := stripAnnotations(inputPath, outputPath, subtypes, forms)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func stripAnnotations(inputPath, outputPath string, subtypes map[string]bool, forms bool) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Encrypted PDF, a password is required")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	removed := map[string]int{}
+	kept := 0
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+
+		remove := annotationsToRemove(page.Annotations, subtypes, forms)
+		annotations := []*pdf.PdfAnnotation{}
+		for _, annot := range page.Annotations {
+			if remove[annot] {
+				removed[annotationSubtype(annot)]++
+			} else {
+				annotations = append(annotations, annot)
+			}
+		}
+		kept += len(annotations)
+
+		// Without annotations left, the page has no Annots entry.  The page dictionary read from the file has one,
+		// which is not removed by setting the annotations to nil.
+		page.Annotations = annotations
+		if len(annotations) == 0 {
+			page.Annotations = nil
+			page.GetPageDict().Remove("Annots")
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	pdfWriter.AddOutlineTree(pdfReader.GetOutlineTree())
+
+	if pdfReader.AcroForm != nil {
+		if forms {
+			fmt.Printf("Removed the form\n")
+		} else {
+			err = pdfWriter.SetForms(pdfReader.AcroForm)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	names := []string{}
+	total := 0
+	for name, count := range removed {
+		names = append(names, name)
+		total += count
+	}
+	sort.Strings(names)
+	fmt.Printf("Removed %d annotations:\n", total)
+	for _, name := range names {
+		fmt.Printf("  %s: %d\n", name, removed[name])
+	}
+	fmt.Printf("Kept %d annotations\n", kept)
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Returns the annotations of a page to remove: those of the subtypes (all if nil), except the widgets unless forms
+// is set, and the popups and replies belonging to them.
+func annotationsToRemove(annotations []*pdf.PdfAnnotation, subtypes map[string]bool,
+	forms bool) map[*pdf.PdfAnnotation]bool {
+	remove := map[*pdf.PdfAnnotation]bool{}
+	for _, annot := range annotations {
+		subtype := strings.ToLower(annotationSubtype(annot))
+		switch {
+		case subtype == "popup":
+			// Decided by the annotation it belongs to.
+		case subtype == "widget":
+			remove[annot] = forms
+		default:
+			remove[annot] = subtypes == nil || subtypes[subtype]
+		}
+	}
+
+	// Popups and replies of removed annotations, repeated for replies to replies.
+	for changed := true; changed; {
+		changed = false
+		for _, annot := range annotations {
+			if remove[annot] {
+				continue
+			}
+			dict := annotationDict(annot)
+			if dict == nil {
+				continue
+			}
+			for _, other := range annotations {
+				if !remove[other] {
+					continue
+				}
+				target := other.GetContainingPdfObject()
+				if refersTo(dict.Get("Parent"), target) || refersTo(dict.Get("IRT"), target) {
+					remove[annot] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return remove
+}
+
+// Returns the annotation dictionary as read from the file.
+func annotationDict(annot *pdf.PdfAnnotation) *pdfcore.PdfObjectDictionary {
+	dict, _ := pdfcore.TraceToDirectObject(annot.GetContainingPdfObject()).(*pdfcore.PdfObjectDictionary)
+	return dict
+}
+
+// Returns the Subtype of the annotation, e.g. "Link".
+func annotationSubtype(annot *pdf.PdfAnnotation) string {
+	dict := annotationDict(annot)
+	if dict == nil {
+		return "Unknown"
+	}
+	subtype, ok := pdfcore.TraceToDirectObject(dict.Get("Subtype")).(*pdfcore.PdfObjectName)
+	if !ok {
+		return "Unknown"
+	}
+	return string(*subtype)
+}
+
+// Returns true if obj is the target object or a reference to it.
+func refersTo(obj, target pdfcore.PdfObject) bool {
+	if obj == nil {
+		return false
+	}
+	if obj == target {
+		return true
+	}
+	ref, isRef := obj.(*pdfcore.PdfObjectReference)
+	ind, isIndirect := target.(*pdfcore.PdfIndirectObject)
+	return isRef && isIndirect && ref.ObjectNumber == ind.ObjectNumber
+}
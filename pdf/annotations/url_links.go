@@ -0,0 +1,204 @@
+/*
+ * Add clickable links to external URLs over text: clicking the text opens the URL in a browser.
+ *
+ * The text is laid out with the creator and the link rectangles are computed from the measured positions and sizes
+ * of the paragraphs: links on a whole paragraph, and links on parts of a line, where the line is drawn as a sequence
+ * of unwrapped paragraphs and the x position is advanced by the width of each part.
+ *
+ * The links are URI actions on Link annotations, which are added to the page after the text has been drawn.  Link
+ * borders are invisible by default, use -border to draw them (e.g. to check the link areas).
+ *
+ * Run as: go run url_links.go [-border] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run url_links.go [-border] output.pdf\n"
+
+// urlLink is a link area of a page with the target URL.  Coordinates from the upper left corner of the page as in
+// the creator.
+type urlLink struct {
+	x, y, width, height float64
+	url                 string
+}
+
+// textSegment is a part of a line of text, with a link if url is set.
+type textSegment struct {
+	text string
+	url  string
+}
+
+// Padding around the link rectangles.  The paragraph height is measured from the baseline, the padding also covers
+// the descenders.
+const linkPadding = 3.0
+
+var (
+	textColor = creator.ColorRGBFrom8bit(0, 0, 0)
+	linkColor = creator.ColorRGBFrom8bit(0, 70, 170)
+)
+
+func main() {
+	drawBorders := false
+	flag.BoolVar(&drawBorders, "border", false, "Draw the link borders")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := createLinkPage(outputPath, drawBorders)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createLinkPage(outputPath string, drawBorders bool) error {
+	c := creator.New()
+
+	// The page is created here rather than with c.NewPage, to be able to add the annotations to it.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: c.Width(), Ury: c.Height()}
+	page.Resources = pdf.NewPdfPageResources()
+	err := c.AddPage(page)
+	if err != nil {
+		return err
+	}
+
+	margin := 72.0
+	width := c.Width() - 2*margin
+	y := margin
+	links := []urlLink{}
+
+	title := creator.NewParagraph("Links to external resources")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(18)
+	title.SetPos(margin, y)
+	err = c.Draw(title)
+	if err != nil {
+		return err
+	}
+	y += title.Height() + 16
+
+	// Links on parts of lines, several per line.
+	lines := [][]textSegment{
+		{{"The UniDoc library: ", ""}, {"unidoc.io", "https://unidoc.io"}},
+		{{"Source code: ", ""}, {"library", "https://github.com/unidoc/unidoc"}, {" and ", ""},
+			{"examples", "https://github.com/unidoc/unidoc-examples"}},
+		{{"Documentation: ", ""}, {"GoDoc", "https://godoc.org/github.com/unidoc/unidoc"}, {", PDF specification: ", ""},
+			{"ISO 32000-1", "https://www.adobe.com/devnet/pdf/pdf_reference.html"}},
+	}
+	for _, line := range lines {
+		lineLinks, height, err := drawLine(c, line, margin, y)
+		if err != nil {
+			return err
+		}
+		links = append(links, lineLinks...)
+		y += height + 6
+	}
+	y += 12
+
+	// A link on a whole (wrapped) paragraph: the rectangle is the paragraph area.
+	p := creator.NewParagraph("This whole paragraph is a link to the Go website.  Its link rectangle covers the " +
+		"width the paragraph is wrapped to and the height of all its lines, so clicking anywhere within the " +
+		"paragraph opens the link.")
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(12)
+	p.SetLineHeight(1.2)
+	p.SetColor(linkColor)
+	p.SetWidth(width)
+	p.SetPos(margin, y)
+	err = c.Draw(p)
+	if err != nil {
+		return err
+	}
+	links = append(links, urlLink{x: margin, y: y, width: p.Width(), height: p.Height(), url: "https://golang.org"})
+
+	addURLLinks(page, links, drawBorders)
+	fmt.Printf("Added %d links\n", len(links))
+
+	return c.WriteToFile(outputPath)
+}
+
+// Draws a line of text segments starting at (x, y) and returns the link areas and the line height.  Each segment is
+// an unwrapped paragraph, so its width is the width of the text.
+func drawLine(c *creator.Creator, segments []textSegment, x, y float64) ([]urlLink, float64, error) {
+	links := []urlLink{}
+	height := 0.0
+	for _, segment := range segments {
+		p := creator.NewParagraph(segment.text)
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(12)
+		p.SetEnableWrap(false)
+		if len(segment.url) > 0 {
+			p.SetColor(linkColor)
+		} else {
+			p.SetColor(textColor)
+		}
+		p.SetPos(x, y)
+		err := c.Draw(p)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(segment.url) > 0 {
+			links = append(links, urlLink{x: x, y: y, width: p.Width(), height: p.Height(), url: segment.url})
+		}
+		x += p.Width()
+		if p.Height() > height {
+			height = p.Height()
+		}
+	}
+	return links, height, nil
+}
+
+// Adds a Link annotation with a URI action for each link to the page.  The borders are drawn in the link color if
+// drawBorders is set, otherwise they are invisible (border width 0).
+func addURLLinks(page *pdf.PdfPage, links []urlLink, drawBorders bool) {
+	pageHeight := page.MediaBox.Ury - page.MediaBox.Lly
+
+	for _, l := range links {
+		action := pdfcore.MakeDict()
+		action.Set("S", pdfcore.MakeName("URI"))
+		action.Set("URI", pdfcore.MakeString(l.url))
+
+		annot := pdf.NewPdfAnnotationLink()
+		// The annotation rectangle is in PDF coordinates, with the origin in the lower left corner.
+		annot.Rect = pdfcore.MakeArrayFromFloats([]float64{
+			page.MediaBox.Llx + l.x - linkPadding, page.MediaBox.Lly + pageHeight - l.y - l.height - linkPadding,
+			page.MediaBox.Llx + l.x + l.width + linkPadding, page.MediaBox.Lly + pageHeight - l.y + linkPadding,
+		})
+		annot.A = action
+		// Highlight the link area when clicked.
+		annot.H = pdfcore.MakeName("I")
+		if drawBorders {
+			r, g, b := linkColor.ToRGB()
+			annot.Border = pdfcore.MakeArrayFromIntegers([]int{0, 0, 1})
+			annot.C = pdfcore.MakeArrayFromFloats([]float64{r, g, b})
+		} else {
+			annot.Border = pdfcore.MakeArrayFromIntegers([]int{0, 0, 0})
+		}
+
+		page.Annotations = append(page.Annotations, annot.PdfAnnotation)
+	}
+}
@@ -0,0 +1,727 @@
+/*
+ * Embed files (e.g. the CSV or XML source of a report) into a PDF file.
+ *
+ * Each file is stored as an embedded file stream with its MIME type, size, modification date and checksum, in a file
+ * specification with the description.  The file specifications are listed in the EmbeddedFiles name tree of the
+ * document, which viewers show in their attachments panel, and a file attachment annotation (paperclip icon) is added
+ * for each file on the page, in a column from the given position from the upper left corner.
+ *
+ * The files are added in an incremental update appended to a copy of the input file, as the model does not give
+ * access to the document catalog.  The files are streamed through the compression into the output file, so large
+ * files are not loaded into memory.
+ *
+ * Run as: go run embed_file.go [-page 1] [-x 36] [-y 36] [-desc description ...] input.pdf output.pdf file ...
+ * The -desc flag can be given once per file, in the order of the files.
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run embed_file.go [-page 1] [-x 36] [-y 36] [-desc description ...] input.pdf output.pdf " +
+	"file ...\n"
+
+// Size of the attachment icons and the space between them.
+const (
+	iconSize    = 16.0
+	iconSpacing = 8.0
+)
+
+// Annotation flags.
+const (
+	annotFlagPrint    = 4
+	annotFlagNoZoom   = 8
+	annotFlagNoRotate = 16
+)
+
+// descriptions is a flag that can be given several times.
+type descriptions []string
+
+func (d *descriptions) String() string {
+	return strings.Join(*d, ", ")
+}
+
+func (d *descriptions) Set(value string) error {
+	*d = append(*d, value)
+	return nil
+}
+
+// attachment is a file to embed, with the numbers of its objects in the update.
+type attachment struct {
+	path        string
+	name        string
+	description string
+	mimeType    string
+	size        int64
+	modTime     time.Time
+	checksum    [md5.Size]byte
+	streamNum   int64
+	lengthNum   int64
+	filespecNum int64
+	annotNum    int64
+}
+
+// updateObject is an object in the incremental update.
+type updateObject struct {
+	number      int64
+	generation  int64
+	obj         pdfcore.PdfObject
+	description string
+}
+
+// updateWriter writes the incremental update after the copy of the input file, keeping track of the object offsets.
+type updateWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	entries []xrefEntry
+}
+
+// xrefEntry is the cross-reference entry of an object written in the update.
+type xrefEntry struct {
+	number     int64
+	generation int64
+	offset     int64
+}
+
+func main() {
+	pageNum := 0
+	x := 0.0
+	y := 0.0
+	var descs descriptions
+	flag.IntVar(&pageNum, "page", 1, "Page for the attachment annotations")
+	flag.Float64Var(&x, "x", 36, "Distance of the first icon from the left of the page")
+	flag.Float64Var(&y, "y", 36, "Distance of the first icon from the top of the page")
+	flag.Var(&descs, "desc", "Description of a file (once per file)")
+	flag.Parse()
+
+	if flag.NArg() < 3 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	attachments := []*attachment{}
+	for i, path := range flag.Args()[2:] {
+		description := ""
+		if i < len(descs) {
+			description = descs[i]
+		}
+		att, err := newAttachment(path, description)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		attachments = append(attachments, att)
+	}
+
+	err := embedFiles(inputPath, outputPath, attachments, pageNum, x, y)
+	if err != nil {
+		os.Remove(outputPath)
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Returns the attachment for the file.  The checksum is computed by reading the file once, without loading it.
+func newAttachment(path, description string) (*attachment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+
+	hash := md5.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return nil, err
+	}
+
+	att := &attachment{
+		path:        path,
+		name:        filepath.Base(path),
+		description: description,
+		mimeType:    "application/octet-stream",
+		size:        info.Size(),
+		modTime:     info.ModTime(),
+	}
+	copy(att.checksum[:], hash.Sum(nil))
+
+	// The MIME type without parameters such as the charset.
+	if mimeType := mime.TypeByExtension(filepath.Ext(path)); mimeType != "" {
+		if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+			att.mimeType = mediaType
+		}
+	}
+
+	return att, nil
+}
+
+func embedFiles(inputPath, outputPath string, attachments []*attachment, pageNum int, x, y float64) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		return errors.New("Embedding files in encrypted files is not supported")
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	rootRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return errors.New("Missing Root in trailer")
+	}
+	catalogObj, err := pdfReader.GetIndirectObjectByNumber(int(rootRef.ObjectNumber))
+	if err != nil {
+		return err
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(catalogObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Catalog not a dictionary")
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	nextNum := int64(*size)
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if pageNum < 1 || pageNum > numPages {
+		return fmt.Errorf("Page %d out of range (document has %d pages)", pageNum, numPages)
+	}
+	pageObj, err := pdfReader.GetPageAsIndirectObject(pageNum)
+	if err != nil {
+		return err
+	}
+	pageInd, ok := pageObj.(*pdfcore.PdfIndirectObject)
+	if !ok {
+		return errors.New("Page not an indirect object")
+	}
+	pageDict, ok := pageInd.PdfObject.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Page not a dictionary")
+	}
+	page, err := pdfReader.GetPage(pageNum)
+	if err != nil {
+		return err
+	}
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return err
+	}
+
+	for _, att := range attachments {
+		att.streamNum = nextNum
+		att.lengthNum = nextNum + 1
+		att.filespecNum = nextNum + 2
+		att.annotNum = nextNum + 3
+		nextNum += 4
+	}
+
+	objects := []updateObject{}
+
+	// The annotations, in a column down from the position.
+	annotRefs := []pdfcore.PdfObject{}
+	date := formatPdfDate(time.Now())
+	for i, att := range attachments {
+		llx := mediaBox.Llx + x
+		lly := mediaBox.Ury - y - iconSize - float64(i)*(iconSize+iconSpacing)
+		annot := pdf.NewPdfAnnotationFileAttachment()
+		annot.Rect = pdfcore.MakeArrayFromFloats([]float64{llx, lly, llx + iconSize, lly + iconSize})
+		annot.Contents = pdfcore.MakeString(encodePdfString(att.label()))
+		annot.M = pdfcore.MakeString(date)
+		annot.F = pdfcore.MakeInteger(annotFlagPrint | annotFlagNoZoom | annotFlagNoRotate)
+		annot.P = pageInd
+		annot.CreationDate = pdfcore.MakeString(date)
+		annot.FS = &pdfcore.PdfObjectReference{ObjectNumber: att.filespecNum}
+		annot.Name = pdfcore.MakeName("Paperclip")
+		annotDict := pdfcore.TraceToDirectObject(annot.ToPdfObject())
+		objects = append(objects, updateObject{number: att.annotNum, obj: annotDict,
+			description: "annotation for " + att.name})
+		annotRefs = append(annotRefs, &pdfcore.PdfObjectReference{ObjectNumber: att.annotNum})
+	}
+
+	// Add the references to the annotations array: in the page dictionary, or in the separate array object.
+	switch annots := pageDict.Get("Annots").(type) {
+	case nil:
+		pageDict.Set("Annots", pdfcore.MakeArray(annotRefs...))
+		objects = append(objects, updateObject{number: pageInd.ObjectNumber, generation: pageInd.GenerationNumber,
+			obj: pageDict, description: "page"})
+	case *pdfcore.PdfObjectArray:
+		*annots = append(*annots, annotRefs...)
+		objects = append(objects, updateObject{number: pageInd.ObjectNumber, generation: pageInd.GenerationNumber,
+			obj: pageDict, description: "page"})
+	case *pdfcore.PdfIndirectObject:
+		arr, ok := annots.PdfObject.(*pdfcore.PdfObjectArray)
+		if !ok {
+			return errors.New("Annots not an array")
+		}
+		*arr = append(*arr, annotRefs...)
+		objects = append(objects, updateObject{number: annots.ObjectNumber, generation: annots.GenerationNumber,
+			obj: arr, description: "annotations array"})
+	case *pdfcore.PdfObjectReference:
+		obj, err := pdfReader.GetIndirectObjectByNumber(int(annots.ObjectNumber))
+		if err != nil {
+			return err
+		}
+		arr, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectArray)
+		if !ok {
+			return errors.New("Annots not an array")
+		}
+		*arr = append(*arr, annotRefs...)
+		objects = append(objects, updateObject{number: annots.ObjectNumber, generation: annots.GenerationNumber,
+			obj: arr, description: "annotations array"})
+	default:
+		return fmt.Errorf("Invalid Annots (%T)", annots)
+	}
+
+	// The EmbeddedFiles name tree, with the existing entries and the new files.
+	treeNum := nextNum
+	nextNum++
+	tree, err := makeEmbeddedFilesTree(pdfReader, catalog, attachments)
+	if err != nil {
+		return err
+	}
+	objects = append(objects, updateObject{number: treeNum, obj: tree, description: "EmbeddedFiles name tree"})
+	treeRef := &pdfcore.PdfObjectReference{ObjectNumber: treeNum}
+
+	// The name dictionary is in the catalog or a separate object.
+	switch names := catalog.Get("Names").(type) {
+	case *pdfcore.PdfObjectReference:
+		obj, err := pdfReader.GetIndirectObjectByNumber(int(names.ObjectNumber))
+		if err != nil {
+			return err
+		}
+		namesDict, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			return errors.New("Names not a dictionary")
+		}
+		namesDict.Set("EmbeddedFiles", treeRef)
+		objects = append(objects, updateObject{number: names.ObjectNumber, generation: names.GenerationNumber,
+			obj: namesDict, description: "name dictionary"})
+	case *pdfcore.PdfObjectDictionary:
+		names.Set("EmbeddedFiles", treeRef)
+		objects = append(objects, updateObject{number: rootRef.ObjectNumber, generation: rootRef.GenerationNumber,
+			obj: catalog, description: "catalog"})
+	case nil:
+		namesDict := pdfcore.MakeDict()
+		namesDict.Set("EmbeddedFiles", treeRef)
+		catalog.Set("Names", namesDict)
+		objects = append(objects, updateObject{number: rootRef.ObjectNumber, generation: rootRef.GenerationNumber,
+			obj: catalog, description: "catalog"})
+	default:
+		return fmt.Errorf("Invalid Names (%T)", names)
+	}
+
+	// Find the previous cross-reference section, to write the update in the same form.
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	prevOffset, err := previousXrefOffset(f, info.Size())
+	if err != nil {
+		return err
+	}
+	xrefKeyword := make([]byte, 4)
+	_, err = f.ReadAt(xrefKeyword, prevOffset)
+	if err != nil {
+		return err
+	}
+	xrefStream := string(xrefKeyword) != "xref"
+
+	// Copy the input file to the output, then write the update.
+	fOut, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fOut.Close()
+
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	copied, err := io.Copy(fOut, f)
+	if err != nil {
+		return err
+	}
+
+	uw := &updateWriter{w: bufio.NewWriter(fOut), offset: copied}
+	// The update starts on a new line.
+	uw.writeString("\n")
+
+	for _, att := range attachments {
+		err = uw.writeEmbeddedFile(att)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Embedded %s (%s, %d bytes): objects %d-%d\n", att.name, att.mimeType, att.size, att.streamNum,
+			att.annotNum)
+	}
+	for _, obj := range objects {
+		uw.writeObject(obj.number, obj.generation, obj.obj)
+		fmt.Printf("Wrote object %d: %s\n", obj.number, obj.description)
+	}
+
+	err = uw.writeXref(trailer, prevOffset, nextNum, xrefStream)
+	if err != nil {
+		return err
+	}
+
+	return uw.w.Flush()
+}
+
+// Returns the text shown for the attachment: the description, or else the file name.
+func (att *attachment) label() string {
+	if att.description != "" {
+		return att.description
+	}
+	return att.name
+}
+
+// Returns the root of the EmbeddedFiles name tree with the existing entries and the attachments.  The tree is
+// flattened into a single node, which is allowed for name trees of any size.
+func makeEmbeddedFilesTree(pdfReader *pdf.PdfReader, catalog *pdfcore.PdfObjectDictionary,
+	attachments []*attachment) (*pdfcore.PdfObjectDictionary, error) {
+	entries := map[string]pdfcore.PdfObject{}
+
+	names, err := resolve(pdfReader, catalog.Get("Names"))
+	if err != nil {
+		return nil, err
+	}
+	if namesDict, ok := names.(*pdfcore.PdfObjectDictionary); ok {
+		tree, err := resolve(pdfReader, namesDict.Get("EmbeddedFiles"))
+		if err != nil {
+			return nil, err
+		}
+		if treeDict, ok := tree.(*pdfcore.PdfObjectDictionary); ok {
+			err = collectNameTree(pdfReader, treeDict, entries, 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The names must be unique, add a number to the name of new files if needed.
+	for _, att := range attachments {
+		key := att.name
+		ext := filepath.Ext(att.name)
+		for i := 2; entries[key] != nil; i++ {
+			key = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(att.name, ext), i, ext)
+		}
+		entries[key] = &pdfcore.PdfObjectReference{ObjectNumber: att.filespecNum}
+	}
+
+	// The names are sorted by byte values.
+	keys := []string{}
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	arr := pdfcore.PdfObjectArray{}
+	for _, key := range keys {
+		arr = append(arr, pdfcore.MakeString(key), entries[key])
+	}
+	tree := pdfcore.MakeDict()
+	tree.Set("Names", &arr)
+	return tree, nil
+}
+
+// Collects the entries of the name tree node and its kids.
+func collectNameTree(pdfReader *pdf.PdfReader, node *pdfcore.PdfObjectDictionary, entries map[string]pdfcore.PdfObject,
+	depth int) error {
+	if depth > 32 {
+		return errors.New("Name tree too deep")
+	}
+
+	if names, ok := pdfcore.TraceToDirectObject(node.Get("Names")).(*pdfcore.PdfObjectArray); ok {
+		for i := 0; i+1 < len(*names); i += 2 {
+			key, ok := pdfcore.TraceToDirectObject((*names)[i]).(*pdfcore.PdfObjectString)
+			if !ok {
+				continue
+			}
+			entries[string(*key)] = (*names)[i+1]
+		}
+	}
+
+	kids, err := resolve(pdfReader, node.Get("Kids"))
+	if err != nil {
+		return err
+	}
+	if arr, ok := kids.(*pdfcore.PdfObjectArray); ok {
+		for _, kid := range *arr {
+			kidObj, err := resolve(pdfReader, kid)
+			if err != nil {
+				return err
+			}
+			if kidDict, ok := kidObj.(*pdfcore.PdfObjectDictionary); ok {
+				err = collectNameTree(pdfReader, kidDict, entries, depth+1)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the direct object, looking up references.
+func resolve(pdfReader *pdf.PdfReader, obj pdfcore.PdfObject) (pdfcore.PdfObject, error) {
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		resolved, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, err
+		}
+		obj = resolved
+	}
+	return pdfcore.TraceToDirectObject(obj), nil
+}
+
+func (uw *updateWriter) writeString(s string) {
+	n, _ := uw.w.WriteString(s)
+	uw.offset += int64(n)
+}
+
+func (uw *updateWriter) Write(p []byte) (int, error) {
+	n, err := uw.w.Write(p)
+	uw.offset += int64(n)
+	return n, err
+}
+
+// Writes the object and records its offset.
+func (uw *updateWriter) writeObject(number, generation int64, obj pdfcore.PdfObject) {
+	uw.entries = append(uw.entries, xrefEntry{number: number, generation: generation, offset: uw.offset})
+	uw.writeString(fmt.Sprintf("%d %d obj\n%s\nendobj\n", number, generation, obj.DefaultWriteString()))
+}
+
+// Writes the embedded file stream of the attachment, its length and its file specification.  The file is compressed
+// while it is copied, the length of the compressed data is written after the stream as an indirect object.
+func (uw *updateWriter) writeEmbeddedFile(att *attachment) error {
+	f, err := os.Open(att.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	params := pdfcore.MakeDict()
+	params.Set("Size", pdfcore.MakeInteger(att.size))
+	params.Set("ModDate", pdfcore.MakeString(formatPdfDate(att.modTime)))
+	params.Set("CheckSum", pdfcore.MakeString(string(att.checksum[:])))
+
+	dict := pdfcore.MakeDict()
+	dict.Set("Type", pdfcore.MakeName("EmbeddedFile"))
+	dict.Set("Subtype", pdfcore.MakeName(att.mimeType))
+	dict.Set("Params", params)
+	dict.Set("Filter", pdfcore.MakeName("FlateDecode"))
+	dict.Set("Length", &pdfcore.PdfObjectReference{ObjectNumber: att.lengthNum})
+
+	uw.entries = append(uw.entries, xrefEntry{number: att.streamNum, offset: uw.offset})
+	uw.writeString(fmt.Sprintf("%d 0 obj\n%s\nstream\n", att.streamNum, dict.DefaultWriteString()))
+	start := uw.offset
+	zw := zlib.NewWriter(uw)
+	_, err = io.Copy(zw, f)
+	if err != nil {
+		return err
+	}
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	length := uw.offset - start
+	uw.writeString("\nendstream\nendobj\n")
+
+	uw.writeObject(att.lengthNum, 0, pdfcore.MakeInteger(length))
+
+	// The file specification, with the name as PDF string (F) and as text string (UF).
+	ef := pdfcore.MakeDict()
+	ef.Set("F", &pdfcore.PdfObjectReference{ObjectNumber: att.streamNum})
+	filespec := pdfcore.MakeDict()
+	filespec.Set("Type", pdfcore.MakeName("Filespec"))
+	filespec.Set("F", pdfcore.MakeString(att.name))
+	filespec.Set("UF", pdfcore.MakeString(encodePdfString(att.name)))
+	if att.description != "" {
+		filespec.Set("Desc", pdfcore.MakeString(encodePdfString(att.description)))
+	}
+	filespec.Set("EF", ef)
+	uw.writeObject(att.filespecNum, 0, filespec)
+
+	return nil
+}
+
+// Writes the cross-reference section for the objects of the update and the trailer: a table, or a stream if the
+// previous section is a stream.
+func (uw *updateWriter) writeXref(trailer *pdfcore.PdfObjectDictionary, prevOffset, size int64,
+	xrefStream bool) error {
+	entries := uw.entries
+	sort.Slice(entries, func(i, j int) bool { return entries[i].number < entries[j].number })
+
+	// The new trailer: the entries of the previous trailer that identify the document.
+	newTrailer := pdfcore.MakeDict()
+	for _, key := range []pdfcore.PdfObjectName{"Root", "Info", "ID"} {
+		if v := trailer.Get(key); v != nil {
+			newTrailer.Set(key, v)
+		}
+	}
+	newTrailer.Set("Prev", pdfcore.MakeInteger(prevOffset))
+
+	xrefOffset := uw.offset
+	if !xrefStream {
+		// Subsections of consecutive object numbers.
+		uw.writeString("xref\n")
+		for i := 0; i < len(entries); {
+			j := i + 1
+			for j < len(entries) && entries[j].number == entries[j-1].number+1 {
+				j++
+			}
+			uw.writeString(fmt.Sprintf("%d %d\n", entries[i].number, j-i))
+			for _, e := range entries[i:j] {
+				uw.writeString(fmt.Sprintf("%010d %05d n\r\n", e.offset, e.generation))
+			}
+			i = j
+		}
+		newTrailer.Set("Size", pdfcore.MakeInteger(size))
+		uw.writeString(fmt.Sprintf("trailer\n%s\n", newTrailer.DefaultWriteString()))
+	} else {
+		// The cross-reference stream is an object itself, with an entry for itself.  Entries are 1 byte type (1:
+		// in use), 4 bytes offset and 2 bytes generation.
+		xrefNum := size
+		entries = append(entries, xrefEntry{number: xrefNum, offset: xrefOffset})
+
+		var data bytes.Buffer
+		index := []int64{}
+		for _, e := range entries {
+			data.WriteByte(1)
+			binary.Write(&data, binary.BigEndian, uint32(e.offset))
+			binary.Write(&data, binary.BigEndian, uint16(e.generation))
+			index = append(index, e.number, 1)
+		}
+
+		stream, err := pdfcore.MakeStream(data.Bytes(), pdfcore.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+		xrefDict := stream.PdfObjectDictionary
+		xrefDict.Set("Type", pdfcore.MakeName("XRef"))
+		xrefDict.Set("Size", pdfcore.MakeInteger(size+1))
+		xrefDict.Set("Index", pdfcore.MakeArrayFromIntegers64(index))
+		xrefDict.Set("W", pdfcore.MakeArrayFromIntegers([]int{1, 4, 2}))
+		xrefDict.Merge(newTrailer)
+		uw.writeString(fmt.Sprintf("%d 0 obj\n%s\nstream\n", xrefNum, xrefDict.DefaultWriteString()))
+		uw.Write(stream.Stream)
+		uw.writeString("\nendstream\nendobj\n")
+	}
+	uw.writeString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefOffset))
+
+	return nil
+}
+
+// Returns the offset of the last cross-reference section of the file, from the startxref at the end.
+func previousXrefOffset(f *os.File, size int64) (int64, error) {
+	tailSize := int64(1024)
+	if tailSize > size {
+		tailSize = size
+	}
+	tail := make([]byte, tailSize)
+	_, err := f.ReadAt(tail, size-tailSize)
+	if err != nil {
+		return 0, err
+	}
+
+	idx := bytes.LastIndex(tail, []byte("startxref"))
+	if idx < 0 {
+		return 0, errors.New("Missing startxref")
+	}
+	fields := strings.Fields(string(tail[idx+len("startxref"):]))
+	if len(fields) == 0 {
+		return 0, errors.New("Invalid startxref")
+	}
+	offset, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil || offset < 0 || offset >= size {
+		return 0, errors.New("Invalid startxref")
+	}
+	return offset, nil
+}
+
+// Returns the string as PDF text string: PDFDocEncoding (ASCII) if possible, otherwise UTF-16BE with a byte order
+// mark.
+func encodePdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 128 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\xfe\xff")
+	binary.Write(&buf, binary.BigEndian, utf16.Encode([]rune(s)))
+	return buf.String()
+}
+
+// Formats the time as a PDF date string, e.g. D:20180213153000+01'00'.
+func formatPdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
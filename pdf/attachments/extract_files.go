@@ -0,0 +1,383 @@
+/*
+ * Extract the embedded files of a PDF file.
+ *
+ * The files are found in the EmbeddedFiles name tree of the document (the attachments panel of viewers) and in the
+ * file attachment annotations of the pages.  Each file is written to the output directory with its original name,
+ * and its size, MIME type and description are reported.  A file referenced both in the name tree and by an
+ * annotation is written once.  When names collide, within the document or with files already in the output
+ * directory, a number is added to the name.  Entries without an embedded file stream (e.g. references to external
+ * files) are skipped.
+ *
+ * Run as: go run extract_files.go [-outdir dir] input.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run extract_files.go [-outdir dir] input.pdf\n"
+
+// embeddedFile is a file specification found in the document.
+type embeddedFile struct {
+	source   string // Where the file specification was found.
+	filespec pdfcore.PdfObject
+}
+
+func main() {
+	outputDir := ""
+	flag.StringVar(&outputDir, "outdir", ".", "Output directory")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	err := extractFiles(inputPath, outputDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func extractFiles(inputPath, outputDir string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Unable to decrypt pdf with empty pass")
+		}
+	}
+
+	files, err := findEmbeddedFiles(pdfReader)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Printf("No embedded files\n")
+		return nil
+	}
+
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	written := map[int64]string{}
+	usedNames := map[string]bool{}
+	extracted := 0
+	for _, file := range files {
+		// The same file specification can be listed in the name tree and referenced by annotations.
+		if num, ok := objectNumber(file.filespec); ok {
+			if path, has := written[num]; has {
+				fmt.Printf("%s: same file as %s\n", file.source, path)
+				continue
+			}
+		}
+
+		path, err := extractFile(pdfReader, file, outputDir, usedNames)
+		if err != nil {
+			fmt.Printf("%s: skipped: %v\n", file.source, err)
+			continue
+		}
+		if num, ok := objectNumber(file.filespec); ok {
+			written[num] = path
+		}
+		extracted++
+	}
+
+	fmt.Printf("Extracted %d files to %s\n", extracted, outputDir)
+	return nil
+}
+
+// Returns the file specifications of the EmbeddedFiles name tree and the file attachment annotations.
+func findEmbeddedFiles(pdfReader *pdf.PdfReader) ([]embeddedFile, error) {
+	files := []embeddedFile{}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := resolve(pdfReader, trailer.Get("Root"))
+	if err != nil {
+		return nil, err
+	}
+	if catalogDict, ok := catalog.(*pdfcore.PdfObjectDictionary); ok {
+		names, err := resolve(pdfReader, catalogDict.Get("Names"))
+		if err != nil {
+			return nil, err
+		}
+		if namesDict, ok := names.(*pdfcore.PdfObjectDictionary); ok {
+			tree, err := resolve(pdfReader, namesDict.Get("EmbeddedFiles"))
+			if err != nil {
+				return nil, err
+			}
+			if treeDict, ok := tree.(*pdfcore.PdfObjectDictionary); ok {
+				files, err = collectNameTree(pdfReader, treeDict, files, 0)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		for _, annot := range page.Annotations {
+			fileAnnot, ok := annot.GetContext().(*pdf.PdfAnnotationFileAttachment)
+			if !ok || fileAnnot.FS == nil {
+				continue
+			}
+			files = append(files, embeddedFile{
+				source:   fmt.Sprintf("Attachment annotation on page %d", i+1),
+				filespec: fileAnnot.FS,
+			})
+		}
+	}
+
+	return files, nil
+}
+
+// Appends the entries of the name tree node and its kids to files.
+func collectNameTree(pdfReader *pdf.PdfReader, node *pdfcore.PdfObjectDictionary, files []embeddedFile,
+	depth int) ([]embeddedFile, error) {
+	if depth > 32 {
+		return nil, errors.New("Name tree too deep")
+	}
+
+	if names, ok := pdfcore.TraceToDirectObject(node.Get("Names")).(*pdfcore.PdfObjectArray); ok {
+		for i := 0; i+1 < len(*names); i += 2 {
+			key, ok := pdfcore.TraceToDirectObject((*names)[i]).(*pdfcore.PdfObjectString)
+			if !ok {
+				continue
+			}
+			files = append(files, embeddedFile{
+				source:   fmt.Sprintf("EmbeddedFiles entry \"%s\"", decodePdfString(string(*key))),
+				filespec: (*names)[i+1],
+			})
+		}
+	}
+
+	kids, err := resolve(pdfReader, node.Get("Kids"))
+	if err != nil {
+		return nil, err
+	}
+	if arr, ok := kids.(*pdfcore.PdfObjectArray); ok {
+		for _, kid := range *arr {
+			kidObj, err := resolve(pdfReader, kid)
+			if err != nil {
+				return nil, err
+			}
+			if kidDict, ok := kidObj.(*pdfcore.PdfObjectDictionary); ok {
+				files, err = collectNameTree(pdfReader, kidDict, files, depth+1)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// Writes the embedded file of the file specification to the output directory and returns the path.
+func extractFile(pdfReader *pdf.PdfReader, file embeddedFile, outputDir string, usedNames map[string]bool) (string,
+	error) {
+	obj, err := resolve(pdfReader, file.filespec)
+	if err != nil {
+		return "", err
+	}
+	filespec, ok := obj.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		// A file specification can also be a string, referring to an external file.
+		return "", errors.New("no embedded file")
+	}
+	obj, err = resolve(pdfReader, filespec.Get("EF"))
+	if err != nil {
+		return "", err
+	}
+	ef, ok := obj.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return "", errors.New("no embedded file")
+	}
+	var stream *pdfcore.PdfObjectStream
+	for _, key := range []pdfcore.PdfObjectName{"UF", "F", "DOS", "Mac", "Unix"} {
+		obj := ef.Get(key)
+		if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+			obj, err = pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+			if err != nil {
+				// Missing stream objects are treated as missing entries.
+				continue
+			}
+		}
+		if s, ok := obj.(*pdfcore.PdfObjectStream); ok {
+			stream = s
+			break
+		}
+	}
+	if stream == nil {
+		return "", errors.New("embedded file stream missing")
+	}
+
+	data, err := pdfcore.DecodeStream(stream)
+	if err != nil {
+		return "", err
+	}
+
+	// The file name: the Unicode name (UF) if present, otherwise the file name (F).
+	name := ""
+	for _, key := range []pdfcore.PdfObjectName{"UF", "F", "DOS", "Mac", "Unix"} {
+		if s, ok := pdfcore.TraceToDirectObject(filespec.Get(key)).(*pdfcore.PdfObjectString); ok && len(*s) > 0 {
+			name = decodePdfString(string(*s))
+			break
+		}
+	}
+	path := filepath.Join(outputDir, uniqueName(outputDir, safeFileName(name), usedNames))
+
+	mimeType := "unknown"
+	subtype, ok := pdfcore.TraceToDirectObject(stream.PdfObjectDictionary.Get("Subtype")).(*pdfcore.PdfObjectName)
+	if ok {
+		mimeType = string(*subtype)
+	}
+
+	fmt.Printf("%s: %s, %d bytes, %s\n", file.source, path, len(data), mimeType)
+	if desc, ok := pdfcore.TraceToDirectObject(filespec.Get("Desc")).(*pdfcore.PdfObjectString); ok {
+		fmt.Printf("  Description: %s\n", decodePdfString(string(*desc)))
+	}
+
+	// Compare with the size and checksum stored with the file, if any.
+	params, _ := pdfcore.TraceToDirectObject(stream.PdfObjectDictionary.Get("Params")).(*pdfcore.PdfObjectDictionary)
+	if params != nil {
+		if size, ok := pdfcore.TraceToDirectObject(params.Get("Size")).(*pdfcore.PdfObjectInteger); ok &&
+			int64(*size) != int64(len(data)) {
+			fmt.Printf("  Warning: size %d bytes expected\n", *size)
+		}
+		if checksum, ok := pdfcore.TraceToDirectObject(params.Get("CheckSum")).(*pdfcore.PdfObjectString); ok {
+			sum := md5.Sum(data)
+			if !bytes.Equal(sum[:], []byte(*checksum)) {
+				fmt.Printf("  Warning: checksum mismatch\n")
+			}
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// Returns the base name of the file name, without directories from the document.
+func safeFileName(name string) string {
+	name = strings.Replace(name, "\\", "/", -1)
+	name = filepath.Base(filepath.FromSlash(name))
+	if name == "." || name == ".." || name == string(filepath.Separator) || name == "" {
+		return "attachment"
+	}
+	return name
+}
+
+// Returns the name, with a number added if it is already used or a file with the name exists.
+func uniqueName(outputDir, name string, usedNames map[string]bool) string {
+	ext := filepath.Ext(name)
+	unique := name
+	for i := 2; ; i++ {
+		_, err := os.Stat(filepath.Join(outputDir, unique))
+		if !usedNames[unique] && os.IsNotExist(err) {
+			break
+		}
+		unique = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(name, ext), i, ext)
+	}
+	usedNames[unique] = true
+	return unique
+}
+
+// Returns the direct object, looking up references.
+func resolve(pdfReader *pdf.PdfReader, obj pdfcore.PdfObject) (pdfcore.PdfObject, error) {
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		resolved, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, err
+		}
+		obj = resolved
+	}
+	return pdfcore.TraceToDirectObject(obj), nil
+}
+
+func objectNumber(obj pdfcore.PdfObject) (int64, bool) {
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectReference:
+		return t.ObjectNumber, true
+	case *pdfcore.PdfIndirectObject:
+		return t.ObjectNumber, true
+	case *pdfcore.PdfObjectStream:
+		return t.ObjectNumber, true
+	}
+	return 0, false
+}
+
+// Decodes a PDF text string: UTF-16BE with a byte order mark, or PDFDocEncoding (read as Latin-1).
+func decodePdfString(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := []uint16{}
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,345 @@
+/*
+ * Generate two-dimensional barcodes: a QR code, a Data Matrix and a PDF417 symbol, each with a label describing the
+ * symbol and the encoded payload printed below it.
+ *
+ * The symbols are drawn as vector rectangles, like the linear barcodes: each row of modules is drawn as a rectangle
+ * per run of dark modules.  The size of a symbol follows from its number of modules and the module size (X
+ * dimension): the nominal module size is used when the symbol fits the width of the page, otherwise the module size
+ * is reduced to fit, down to a minimum below which the symbol would not scan reliably.  The rows of PDF417 are three
+ * modules high, and each symbol has its quiet zone: 4 modules for QR, 1 for Data Matrix and 2 for PDF417.
+ *
+ * The encoders choose the smallest symbol that holds the payload.  The largest symbol allowed can be limited with
+ * -qr-max-version and -dm-max-size, e.g. for a label with a fixed space.  A payload too large for the allowed symbol
+ * size, or for any size, is reported as an error naming the symbol, the payload size and the limit.
+ *
+ * Run as: go run 2d_barcodes.go [-payload text] [-module 0.5] [-qr-max-version 40] [-dm-max-size 144]
+ *                               [-security 2] output.pdf
+ */
+/*
+ * NOTE: This example depends on github.com/boombuler/barcode, MIT licensed.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/pdf417"
+	"github.com/boombuler/barcode/qr"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run 2d_barcodes.go [-payload text] [-module 0.5] [-qr-max-version 40] [-dm-max-size 144] " +
+	"[-security 2] output.pdf\n"
+
+const pageMargin = 50.0
+
+// The smallest module size (in mm) that is drawn; smaller modules do not scan reliably.
+const minModule = 0.25
+
+var black = creator.ColorRGBFrom8bit(0, 0, 0)
+
+// symbolOptions are the size settings of the symbols.
+type symbolOptions struct {
+	Module       float64 // Nominal module size (mm).
+	QRMaxVersion int     // Largest QR code version, 1 (21 x 21 modules) to 40 (177 x 177 modules).
+	DMMaxSize    int     // Largest Data Matrix size, 10 to 144 modules.
+	Security     int     // PDF417 error correction (security) level, 0 to 8.
+}
+
+// symbol is a barcode ready to draw: the modules and how to draw them.
+type symbol struct {
+	Name      string
+	Payload   string
+	Code      barcode.Barcode
+	Columns   int // Modules across.
+	Rows      int // Module rows.
+	RowPixels int // Height of a row in the barcode image, in pixels.
+	RowHeight int // Height of a row, in modules.
+	QuietZone int // Quiet zone on all sides, in modules.
+	Info      string
+}
+
+var samplePayloads = map[string]string{
+	"qr":         "https://example.com/track?order=2026-10-0042&lang=en",
+	"datamatrix": "PN 4711-0815 SN 2026100001 LOT A12",
+	"pdf417": "SHIP|2026-10-15|FROM:ACME GmbH, Hauptstrasse 1, 10115 Berlin|TO:Example Ltd, 10 High Street, " +
+		"London EC1A 1AA|PCS:3|WT:12.4KG|REF:PO-88213",
+}
+
+func main() {
+	payload := ""
+	opts := symbolOptions{}
+	flag.StringVar(&payload, "payload", "", "Text to encode in all symbols, instead of the sample payloads")
+	flag.Float64Var(&opts.Module, "module", 0.5, "Nominal module size (mm)")
+	flag.IntVar(&opts.QRMaxVersion, "qr-max-version", 40, "Largest QR code version (1-40)")
+	flag.IntVar(&opts.DMMaxSize, "dm-max-size", 144, "Largest Data Matrix size in modules (10-144)")
+	flag.IntVar(&opts.Security, "security", 2, "PDF417 security level (0-8)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if opts.Module < minModule {
+		fmt.Printf("Error: -module must be at least %.2f mm\n", minModule)
+		os.Exit(1)
+	}
+	if opts.QRMaxVersion < 1 || opts.QRMaxVersion > 40 {
+		fmt.Printf("Error: -qr-max-version must be between 1 and 40\n")
+		os.Exit(1)
+	}
+	if opts.DMMaxSize < 10 || opts.DMMaxSize > 144 {
+		fmt.Printf("Error: -dm-max-size must be between 10 and 144\n")
+		os.Exit(1)
+	}
+	if opts.Security < 0 || opts.Security > 8 {
+		fmt.Printf("Error: -security must be between 0 and 8\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	payloads := samplePayloads
+	if payload != "" {
+		payloads = map[string]string{"qr": payload, "datamatrix": payload, "pdf417": payload}
+	}
+
+	err := create2DBarcodes(payloads, opts, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// create2DBarcodes encodes the payloads and draws the symbols one below the other, starting a new page when the
+// next one does not fit.
+func create2DBarcodes(payloads map[string]string, opts symbolOptions, outputPath string) error {
+	qrSymbol, err := encodeQR(payloads["qr"], opts.QRMaxVersion)
+	if err != nil {
+		return err
+	}
+	dmSymbol, err := encodeDataMatrix(payloads["datamatrix"], opts.DMMaxSize)
+	if err != nil {
+		return err
+	}
+	pdfSymbol, err := encodePDF417(payloads["pdf417"], opts.Security)
+	if err != nil {
+		return err
+	}
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+	c.NewPage()
+
+	title := creator.NewParagraph("Two-dimensional barcodes")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(16)
+	title.SetPos(pageMargin, pageMargin)
+	err = c.Draw(title)
+	if err != nil {
+		return err
+	}
+
+	y := pageMargin + 36
+	for _, s := range []*symbol{qrSymbol, dmSymbol, pdfSymbol} {
+		y, err = drawSymbol(c, s, opts.Module, y)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// encodeQR encodes the payload as a QR code of at most the given version, at error correction level M.
+func encodeQR(payload string, maxVersion int) (*symbol, error) {
+	code, err := qr.Encode(payload, qr.M, qr.Auto)
+	if err != nil {
+		return nil, fmt.Errorf("QR code: payload of %d bytes does not fit in the largest symbol (version 40, %d "+
+			"bytes at error correction level M)", len(payload), 2331)
+	}
+	modules := code.Bounds().Dx()
+	version := (modules - 17) / 4
+	if version > maxVersion {
+		return nil, fmt.Errorf("QR code: payload of %d bytes needs version %d (%d x %d modules), above the "+
+			"maximum version %d", len(payload), version, modules, modules, maxVersion)
+	}
+
+	return &symbol{
+		Name:      "QR code",
+		Payload:   payload,
+		Code:      code,
+		Columns:   modules,
+		Rows:      modules,
+		RowPixels: 1,
+		RowHeight: 1,
+		QuietZone: 4,
+		Info:      fmt.Sprintf("version %d, %d x %d modules, error correction level M", version, modules, modules),
+	}, nil
+}
+
+// encodeDataMatrix encodes the payload as a square Data Matrix of at most maxSize x maxSize modules.
+func encodeDataMatrix(payload string, maxSize int) (*symbol, error) {
+	code, err := datamatrix.Encode(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Data Matrix: payload of %d bytes does not fit in the largest symbol (144 x 144 "+
+			"modules)", len(payload))
+	}
+	size := code.Bounds().Dx()
+	if size > maxSize {
+		return nil, fmt.Errorf("Data Matrix: payload of %d bytes needs %d x %d modules, above the maximum size of "+
+			"%d x %d", len(payload), size, size, maxSize, maxSize)
+	}
+
+	return &symbol{
+		Name:      "Data Matrix",
+		Payload:   payload,
+		Code:      code,
+		Columns:   size,
+		Rows:      size,
+		RowPixels: 1,
+		RowHeight: 1,
+		QuietZone: 1,
+		Info:      fmt.Sprintf("%d x %d modules, ECC 200", size, size),
+	}, nil
+}
+
+// encodePDF417 encodes the payload as a PDF417 symbol at the security level.
+func encodePDF417(payload string, security int) (*symbol, error) {
+	code, err := pdf417.Encode(payload, byte(security))
+	if err != nil {
+		return nil, fmt.Errorf("PDF417: payload of %d bytes does not fit in the largest symbol (30 columns x 30 "+
+			"rows) at security level %d", len(payload), security)
+	}
+
+	// The encoder draws each row two pixels high.
+	bounds := code.Bounds()
+	rows := bounds.Dy() / 2
+	columns := (bounds.Dx() - 1) / 17
+	return &symbol{
+		Name:      "PDF417",
+		Payload:   payload,
+		Code:      code,
+		Columns:   bounds.Dx(),
+		Rows:      rows,
+		RowPixels: 2,
+		RowHeight: 3,
+		QuietZone: 2,
+		Info: fmt.Sprintf("%d data columns x %d rows, %d x %d modules, security level %d", columns-4, rows,
+			bounds.Dx(), rows*3, security),
+	}, nil
+}
+
+// dark returns whether the module at column x of row y of the symbol is dark.
+func (s *symbol) dark(x, y int) bool {
+	r, _, _, _ := s.Code.At(x, y*s.RowPixels).RGBA()
+	return r < 0x8000
+}
+
+// drawSymbol draws the label, the symbol and the payload of s from y down, on a new page if they do not fit on the
+// current one.  Returns the position below them.
+func drawSymbol(c *creator.Creator, s *symbol, nominal float64, y float64) (float64, error) {
+	availWidth := c.Width() - 2*pageMargin
+
+	// The module size: the nominal size, or smaller to fit the width of the page with the quiet zone.
+	widthModules := s.Columns + 2*s.QuietZone
+	module := math.Min(nominal*creator.PPMM, availWidth/float64(widthModules))
+	if module < minModule*creator.PPMM {
+		return 0, fmt.Errorf("%s of %d modules across does not fit the page width at the minimum module size of "+
+			"%.2f mm", s.Name, s.Columns, minModule)
+	}
+	symbolWidth := float64(widthModules) * module
+	symbolHeight := float64(s.Rows*s.RowHeight+2*s.QuietZone) * module
+
+	label := newText(s.Name, fonts.NewFontHelveticaBold(), 12, availWidth)
+	info := newText(fmt.Sprintf("%s, module %.2f mm, %.1f x %.1f mm with the quiet zone", s.Info,
+		module/creator.PPMM, symbolWidth/creator.PPMM, symbolHeight/creator.PPMM), fonts.NewFontHelvetica(), 9,
+		availWidth)
+	payload := newText(fmt.Sprintf("Payload (%d bytes): %s", len(s.Payload), s.Payload), fonts.NewFontCourier(), 8,
+		availWidth)
+
+	height := label.Height() + info.Height() + 6 + symbolHeight + 4 + payload.Height()
+	if y+height > c.Height()-pageMargin {
+		c.NewPage()
+		y = pageMargin
+	}
+
+	label.SetPos(pageMargin, y)
+	y += label.Height()
+	info.SetPos(pageMargin, y)
+	y += info.Height() + 6
+	for _, p := range []*creator.Paragraph{label, info} {
+		err := c.Draw(p)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// The dark modules, a rectangle per run of dark modules in a row.  The rectangles are drawn on a block which is
+	// then drawn on the page at once, which is much faster than drawing each of them on the page.
+	block := creator.NewBlock(symbolWidth, symbolHeight)
+	offset := float64(s.QuietZone) * module
+	rowHeight := float64(s.RowHeight) * module
+	for row := 0; row < s.Rows; row++ {
+		for col := 0; col < s.Columns; {
+			if !s.dark(col, row) {
+				col++
+				continue
+			}
+			start := col
+			for col < s.Columns && s.dark(col, row) {
+				col++
+			}
+			rect := creator.NewRectangle(offset+float64(start)*module, offset+float64(row)*rowHeight,
+				float64(col-start)*module, rowHeight)
+			rect.SetFillColor(black)
+			rect.SetBorderWidth(0)
+			err := block.Draw(rect)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	block.SetPos(pageMargin, y)
+	err := c.Draw(block)
+	if err != nil {
+		return 0, err
+	}
+	y += symbolHeight + 4
+
+	payload.SetPos(pageMargin, y)
+	err = c.Draw(payload)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("%s: %s, module %.2f mm\n", s.Name, s.Info, module/creator.PPMM)
+
+	return y + payload.Height() + 24, nil
+}
+
+func newText(text string, font fonts.Font, fontSize, width float64) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetWidth(width)
+	p.SetColor(black)
+	return p
+}
@@ -0,0 +1,364 @@
+/*
+ * Generate a sheet of Code-128 and EAN-13 barcode labels.
+ *
+ * Each label has a caption above the bars and the human-readable number below them.  EAN-13 codes are validated:
+ * a 12 digit code gets its check digit appended, while a 13 digit code with a wrong check digit is rejected.
+ *
+ * The bars are drawn as vector rectangles rather than a scaled image.  The module (narrowest bar) width is snapped to
+ * a whole number of printer dots at the resolution given by -dpi, so every bar prints with the same width.
+ *
+ * Codes are given as type:data[:caption] where type is ean13 or code128.  If no codes are given, a set of sample
+ * labels is generated.
+ *
+ * Run as: go run linear_barcodes.go [-dpi 600] output.pdf [type:data[:caption] ...]
+ * Example: go run linear_barcodes.go labels.pdf ean13:590123412345:Widget "code128:SHIP-00042:Parcel 42"
+ */
+/*
+ * NOTE: This example depends on github.com/boombuler/barcode, MIT licensed.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/ean"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run linear_barcodes.go [-dpi 600] output.pdf [type:data[:caption] ...]\n"
+
+// Label sheet layout (in points): 3 columns of 60 x 35 mm labels on an A4 page.
+const (
+	sheetColumns = 3
+	sheetRows    = 7
+)
+
+var (
+	labelWidth   = 60 * creator.PPMM
+	labelHeight  = 35 * creator.PPMM
+	sheetMarginX = (210*creator.PPMM - sheetColumns*labelWidth) / 2
+	sheetMarginY = (297*creator.PPMM - sheetRows*labelHeight) / 2
+	labelPadding = 3 * creator.PPMM
+)
+
+// Nominal module widths: 0.33 mm is the EAN-13 width at 100% magnification.
+var (
+	ean13Module   = 0.33 * creator.PPMM
+	code128Module = 0.25 * creator.PPMM
+)
+
+type barcodeLabel struct {
+	kind    string // "ean13" or "code128".
+	data    string
+	caption string
+}
+
+var sampleLabels = []barcodeLabel{
+	{"ean13", "4006381333931", "Highlighter, yellow"},
+	{"ean13", "590123412345", "Widget (check digit added)"},
+	{"ean13", "978020137962", "Book, ISBN 0-201-37962"},
+	{"code128", "SHIP-00042", "Parcel 42"},
+	{"code128", "INV/2017/0815", "Invoice 815"},
+	{"code128", "Rack A3-12", "Storage location"},
+}
+
+func main() {
+	var dpi float64
+	flag.Float64Var(&dpi, "dpi", 600, "Printer resolution the bar widths are aligned to")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 1 || dpi < 72 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := args[0]
+
+	labels := sampleLabels
+	if len(args) > 1 {
+		labels = nil
+		for _, arg := range args[1:] {
+			label, err := parseLabel(arg)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			labels = append(labels, label)
+		}
+	}
+
+	err := createLabelSheet(labels, dpi, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// parseLabel parses a type:data[:caption] argument.
+func parseLabel(arg string) (barcodeLabel, error) {
+	parts := strings.SplitN(arg, ":", 3)
+	if len(parts) < 2 || parts[1] == "" {
+		return barcodeLabel{}, fmt.Errorf("Invalid code %q, expecting type:data[:caption]", arg)
+	}
+
+	label := barcodeLabel{kind: strings.ToLower(parts[0]), data: parts[1]}
+	if len(parts) == 3 {
+		label.caption = parts[2]
+	}
+	if label.kind != "ean13" && label.kind != "code128" {
+		return barcodeLabel{}, fmt.Errorf("Unsupported barcode type %q (use ean13 or code128)", parts[0])
+	}
+
+	return label, nil
+}
+
+// createLabelSheet draws the labels in a grid, starting a new page when the sheet is full.
+func createLabelSheet(labels []barcodeLabel, dpi float64, outputPath string) error {
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+
+	perPage := sheetColumns * sheetRows
+	for i, label := range labels {
+		if i%perPage == 0 {
+			c.NewPage()
+		}
+		col := i % sheetColumns
+		row := (i % perPage) / sheetColumns
+		x := sheetMarginX + float64(col)*labelWidth
+		y := sheetMarginY + float64(row)*labelHeight
+
+		err := drawLabel(c, label, x, y, dpi)
+		if err != nil {
+			return fmt.Errorf("Label %d (%s): %v", i+1, label.data, err)
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawLabel draws a label with its top left corner at x, y: a light outline, the caption, the bars and the
+// human-readable text.
+func drawLabel(c *creator.Creator, label barcodeLabel, x, y, dpi float64) error {
+	outline := creator.NewRectangle(x, y, labelWidth, labelHeight)
+	outline.SetBorderColor(creator.ColorRGBFrom8bit(200, 200, 200))
+	outline.SetBorderWidth(0.5)
+	_ = c.Draw(outline)
+
+	top := y + labelPadding
+	if label.caption != "" {
+		p := creator.NewParagraph(label.caption)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(8)
+		p.SetWidth(labelWidth - 2*labelPadding)
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		p.SetPos(x+labelPadding, top)
+		_ = c.Draw(p)
+		top += 12
+	}
+	bottom := y + labelHeight - labelPadding
+	maxWidth := labelWidth - 2*labelPadding
+
+	switch label.kind {
+	case "ean13":
+		code, err := validateEAN13(label.data)
+		if err != nil {
+			return err
+		}
+		bc, err := ean.Encode(code)
+		if err != nil {
+			return err
+		}
+		return drawEAN13(c, bc, code, x+labelPadding, top, maxWidth, bottom-top, dpi)
+	case "code128":
+		bc, err := code128.Encode(label.data)
+		if err != nil {
+			return err
+		}
+		return drawCode128(c, bc, label.data, x+labelPadding, top, maxWidth, bottom-top, dpi)
+	}
+
+	return fmt.Errorf("Unsupported barcode type %q", label.kind)
+}
+
+// ean13CheckDigit computes the check digit for the first 12 digits of an EAN-13 code: digits are weighted 1 and 3
+// alternately from the left, and the check digit brings the sum up to a multiple of 10.
+func ean13CheckDigit(digits string) int {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(digits[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// validateEAN13 returns the full 13 digit code.  A 12 digit code has its check digit appended, a 13 digit code must
+// carry the correct check digit.
+func validateEAN13(code string) (string, error) {
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("EAN-13 code %q must contain digits only", code)
+		}
+	}
+
+	switch len(code) {
+	case 12:
+		return fmt.Sprintf("%s%d", code, ean13CheckDigit(code)), nil
+	case 13:
+		expected := ean13CheckDigit(code)
+		if int(code[12]-'0') != expected {
+			return "", fmt.Errorf("EAN-13 code %s has check digit %c, expected %d", code, code[12], expected)
+		}
+		return code, nil
+	}
+
+	return "", fmt.Errorf("EAN-13 code %q must have 12 or 13 digits", code)
+}
+
+// moduleWidth returns the widest module width not exceeding the nominal width (and fitting modules into maxWidth)
+// that is a whole number of dots at the given printer resolution.
+func moduleWidth(nominal, maxWidth float64, modules int, dpi float64) (float64, error) {
+	dot := 72.0 / dpi
+	width := math.Min(nominal, maxWidth/float64(modules))
+	dots := math.Floor(width/dot + 1e-9)
+	if dots < 1 {
+		return 0, errors.New("Barcode does not fit on the label at this resolution")
+	}
+	return dots * dot, nil
+}
+
+// snapToDot rounds a position to the printer dot grid so that bar edges fall on dot boundaries.
+func snapToDot(v, dpi float64) float64 {
+	dot := 72.0 / dpi
+	return math.Floor(v/dot+0.5) * dot
+}
+
+// drawBars draws the dark modules of bc as filled rectangles, merging adjacent dark modules into a single bar.
+// The height of each bar is given by barHeight as a function of the module index where the bar starts.
+func drawBars(c *creator.Creator, bc barcode.Barcode, x, y, module float64, barHeight func(int) float64) {
+	bounds := bc.Bounds()
+	black := creator.ColorRGBFrom8bit(0, 0, 0)
+
+	for i := bounds.Min.X; i < bounds.Max.X; {
+		if !isDark(bc, i) {
+			i++
+			continue
+		}
+		start := i
+		for i < bounds.Max.X && isDark(bc, i) {
+			i++
+		}
+		bar := creator.NewRectangle(x+float64(start)*module, y, float64(i-start)*module, barHeight(start))
+		bar.SetFillColor(black)
+		bar.SetBorderWidth(0)
+		_ = c.Draw(bar)
+	}
+}
+
+func isDark(bc barcode.Barcode, x int) bool {
+	r, _, _, _ := bc.At(x, 0).RGBA()
+	return r == 0
+}
+
+// drawEAN13 draws an EAN-13 barcode in the box at x, y.  The guard bars extend below the others, the first digit is
+// printed in the left quiet zone and the remaining digits in two groups of six under the halves of the symbol.
+func drawEAN13(c *creator.Creator, bc barcode.Barcode, code string, x, y, width, height, dpi float64) error {
+	const quietLeft, quietRight = 11, 7
+	modules := bc.Bounds().Dx()
+
+	module, err := moduleWidth(ean13Module, width, modules+quietLeft+quietRight, dpi)
+	if err != nil {
+		return err
+	}
+
+	fontSize := 9 * module / ean13Module
+	fontSize = math.Min(fontSize, 10)
+	symbolWidth := float64(modules+quietLeft+quietRight) * module
+	left := snapToDot(x+(width-symbolWidth)/2, dpi)
+	barsX := left + quietLeft*module
+
+	barHeight := height - fontSize
+	guardHeight := barHeight + fontSize/2
+	isGuard := func(i int) bool {
+		return i < 3 || (i >= 45 && i < 50) || i >= 92
+	}
+	drawBars(c, bc, barsX, y, module, func(i int) float64 {
+		if isGuard(i) {
+			return guardHeight
+		}
+		return barHeight
+	})
+
+	textY := y + barHeight + 1
+	font := fonts.NewFontCourier()
+	drawDigit := func(digit string, center float64) {
+		p := creator.NewParagraph(digit)
+		p.SetFont(font)
+		p.SetFontSize(fontSize)
+		p.SetEnableWrap(false)
+		p.SetPos(center-p.Width()/2, textY)
+		_ = c.Draw(p)
+	}
+
+	// The first digit is encoded in the parity pattern of the left half and printed in the quiet zone.
+	drawDigit(code[:1], left+float64(quietLeft)*module/2)
+	for i := 0; i < 12; i++ {
+		start := 3 + 7*i
+		if i >= 6 {
+			start += 5 // Center guard.
+		}
+		drawDigit(code[i+1:i+2], barsX+(float64(start)+3.5)*module)
+	}
+
+	return nil
+}
+
+// drawCode128 draws a Code-128 barcode centered in the box at x, y with its content printed centered below.
+func drawCode128(c *creator.Creator, bc barcode.Barcode, text string, x, y, width, height, dpi float64) error {
+	const quietZone = 10
+	modules := bc.Bounds().Dx()
+
+	module, err := moduleWidth(code128Module, width, modules+2*quietZone, dpi)
+	if err != nil {
+		return err
+	}
+
+	const fontSize = 8
+	barsWidth := float64(modules) * module
+	barsX := snapToDot(x+(width-barsWidth)/2, dpi)
+	barHeight := height - fontSize - 2
+	drawBars(c, bc, barsX, y, module, func(int) float64 {
+		return barHeight
+	})
+
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontCourier())
+	p.SetFontSize(fontSize)
+	p.SetEnableWrap(false)
+	p.SetPos(x+(width-p.Width())/2, y+barHeight+1)
+	_ = c.Draw(p)
+
+	return nil
+}
@@ -0,0 +1,276 @@
+/*
+ * Create a QR code with a logo in the center and place it on a PDF page.
+ *
+ * The logo covers modules of the QR code, which the reader has to restore with the error correction, so the code is
+ * generated at the highest error correction level (H), which restores up to 30% of the codewords.  The logo is kept
+ * well below that:
+ * - The area covered by the logo is limited to maxCoverage of the QR code.  The covered modules damage more codewords
+ *   than their share of the area, as the codewords along the edges of the logo are partly covered, and the rest of the
+ *   error correction is left for damage and printing defects.
+ * - The logo must stay clear of the finder patterns in the corners and the timing patterns between them, which the
+ *   reader needs to locate the code at all.
+ * A logo larger than that is shrunk to the limit, with a warning.
+ *
+ * The logo area is aligned to the modules and cleared to white, with a margin of one module around the logo, so no
+ * modules are partly covered.  The logo is composited onto the white area with its alpha channel, so transparent
+ * parts of a PNG logo show white rather than black, and the result is an opaque image of the QR code with the logo,
+ * including the quiet zone around it.
+ *
+ * Without -logo, a sample logo with a transparent background and a transparent hole is generated.
+ *
+ * Run as: go run qr_with_logo.go [-logo logo.png] [-logo-size 0.25] [-size 200] "qr text" output.pdf
+ */
+/*
+ * NOTE: This example depends on github.com/boombuler/barcode, MIT licensed.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	goimage "image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+
+	"github.com/boombuler/barcode/qr"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run qr_with_logo.go [-logo logo.png] [-logo-size 0.25] [-size 200] \"qr text\" output.pdf\n"
+
+const (
+	quietZone    = 4    // Quiet zone around the QR code, in modules.
+	modulePixels = 10   // Pixels per module in the image.
+	maxCoverage  = 0.15 // Largest fraction of the QR code area that the logo area may cover.
+
+	// Modules from the edges that the logo must keep clear of: the finder patterns with their separators and the
+	// timing patterns.
+	finderClearing = 9
+)
+
+func main() {
+	logoPath := ""
+	logoSize := 0.0
+	size := 0.0
+	flag.StringVar(&logoPath, "logo", "", "Logo image (PNG or JPEG), a sample logo is generated if not set")
+	flag.Float64Var(&logoSize, "logo-size", 0.25, "Width of the logo area relative to the width of the QR code")
+	flag.Float64Var(&size, "size", 200, "Size of the QR code on the page, including the quiet zone (points)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if logoSize <= 0 || logoSize >= 1 {
+		fmt.Printf("Error: -logo-size must be between 0 and 1\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	text := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	var logo goimage.Image
+	if logoPath != "" {
+		var err error
+		logo, err = loadImage(logoPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		logo = sampleLogo(200)
+	}
+
+	err := createQrPage(text, logo, logoSize, size, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Creates a page with the QR code of the text with the logo, centered below a caption.
+func createQrPage(text string, logo goimage.Image, logoSize, size float64, outputPath string) error {
+	img, err := makeQrCodeWithLogo(text, logo, logoSize)
+	if err != nil {
+		return err
+	}
+
+	c := creator.New()
+	c.NewPage()
+
+	p := creator.NewParagraph("Scan the code to visit our web site")
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(16)
+	p.SetWidth(c.Width())
+	p.SetTextAlignment(creator.TextAlignmentCenter)
+	p.SetPos(0, 100)
+	err = c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	qrImage, err := creator.NewImageFromGoImage(img)
+	if err != nil {
+		return err
+	}
+	qrImage.SetWidth(size)
+	qrImage.SetHeight(size)
+	qrImage.SetPos((c.Width()-size)/2, 140)
+	err = c.Draw(qrImage)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Generates the QR code of the text at error correction level H, with the logo in the center.  logoSize is the
+// requested width of the logo area relative to the QR code; it is reduced to keep the QR code readable.
+func makeQrCodeWithLogo(text string, logo goimage.Image, logoSize float64) (goimage.Image, error) {
+	qrCode, err := qr.Encode(text, qr.H, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+	modules := qrCode.Bounds().Dx()
+
+	// The logo area in modules, with the same parity as the QR code (which has an odd number of modules) so it is
+	// centered on the module grid.
+	side := oddBelow(logoSize * float64(modules))
+	maxSide := oddBelow(math.Min(math.Sqrt(maxCoverage)*float64(modules), float64(modules-2*finderClearing)))
+	if maxSide < 3 {
+		return nil, fmt.Errorf("QR code of %d modules is too small for a logo, use a longer text", modules)
+	}
+	if side > maxSide {
+		fmt.Printf("Warning: logo area reduced from %d to %d modules to keep the QR code readable\n", side, maxSide)
+		side = maxSide
+	}
+	if side < 3 {
+		side = 3
+	}
+	fmt.Printf("QR code: %d x %d modules, error correction level H\n", modules, modules)
+	fmt.Printf("Logo area: %d x %d modules, %.1f%% of the QR code\n", side, side,
+		100*float64(side*side)/float64(modules*modules))
+
+	// Draw the modules, with the quiet zone, on a white image.
+	total := modules + 2*quietZone
+	img := goimage.NewRGBA(goimage.Rect(0, 0, total*modulePixels, total*modulePixels))
+	draw.Draw(img, img.Bounds(), goimage.White, goimage.ZP, draw.Src)
+	for y := 0; y < modules; y++ {
+		for x := 0; x < modules; x++ {
+			r, _, _, _ := qrCode.At(x, y).RGBA()
+			if r > 0x7fff {
+				continue
+			}
+			draw.Draw(img, moduleRect(x+quietZone, y+quietZone, 1), goimage.Black, goimage.ZP, draw.Src)
+		}
+	}
+
+	// Clear the logo area and composite the logo over it, one module inside the area.
+	start := quietZone + (modules-side)/2
+	draw.Draw(img, moduleRect(start, start, side), goimage.White, goimage.ZP, draw.Src)
+	logoArea := moduleRect(start+1, start+1, side-2)
+	scaled := scaleToFit(logo, logoArea.Dx(), logoArea.Dy())
+	offset := goimage.Pt(logoArea.Min.X+(logoArea.Dx()-scaled.Bounds().Dx())/2,
+		logoArea.Min.Y+(logoArea.Dy()-scaled.Bounds().Dy())/2)
+	draw.Draw(img, scaled.Bounds().Add(offset), scaled, goimage.ZP, draw.Over)
+
+	return img, nil
+}
+
+// Returns the largest odd number not above v.
+func oddBelow(v float64) int {
+	n := int(math.Floor(v))
+	if n%2 == 0 {
+		n--
+	}
+	return n
+}
+
+// Returns the pixel rectangle of n x n modules with the upper left module at (x, y).
+func moduleRect(x, y, n int) goimage.Rectangle {
+	return goimage.Rect(x*modulePixels, y*modulePixels, (x+n)*modulePixels, (y+n)*modulePixels)
+}
+
+// Scales the image to fit in width x height pixels, keeping the aspect ratio.  Each target pixel is the average
+// of the source pixels it covers, with premultiplied alpha, so semi-transparent edges are kept.
+func scaleToFit(src goimage.Image, width, height int) *goimage.RGBA {
+	b := src.Bounds()
+	scale := math.Min(float64(width)/float64(b.Dx()), float64(height)/float64(b.Dy()))
+	w := int(math.Max(1, math.Round(scale*float64(b.Dx()))))
+	h := int(math.Max(1, math.Round(scale*float64(b.Dy()))))
+
+	dst := goimage.NewRGBA(goimage.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := b.Min.Y + (y+1)*b.Dy()/h
+		if y1 == y0 {
+			y1++
+		}
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := b.Min.X + (x+1)*b.Dx()/w
+			if x1 == x0 {
+				x1++
+			}
+			var sr, sg, sb, sa, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, a := src.At(sx, sy).RGBA()
+					sr, sg, sb, sa = sr+r, sg+g, sb+b, sa+a
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{R: uint16(sr / n), G: uint16(sg / n), B: uint16(sb / n),
+				A: uint16(sa / n)})
+		}
+	}
+	return dst
+}
+
+// Loads an image file.
+func loadImage(path string) (goimage.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := goimage.Decode(f)
+	return img, err
+}
+
+// Generates a sample logo of size x size pixels: a blue disc with a transparent square hole, on a transparent
+// background, with anti-aliased edges.
+func sampleLogo(size int) goimage.Image {
+	img := goimage.NewNRGBA(goimage.Rect(0, 0, size, size))
+	center := float64(size) / 2
+	radius := 0.48 * float64(size)
+	hole := 0.18 * float64(size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-center, float64(y)+0.5-center
+			// Coverage of the disc, and of the hole, over about one pixel at the edges.
+			alpha := math.Max(0, math.Min(1, radius-math.Hypot(dx, dy)+0.5))
+			alpha *= math.Max(0, math.Min(1, math.Max(math.Abs(dx), math.Abs(dy))-hole+0.5))
+			img.SetNRGBA(x, y, color.NRGBA{R: 32, G: 96, B: 200, A: uint8(255 * alpha)})
+		}
+	}
+	return img
+}
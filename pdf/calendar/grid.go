@@ -0,0 +1,220 @@
+/*
+ * Month grid drawing shared by the calendar examples month.go and year.go, which are run together with this file:
+ *   go run month.go grid.go ...
+ *   go run year.go grid.go ...
+ */
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+var (
+	gridColor    = creator.ColorRGBFrom8bit(160, 160, 160)
+	noteColor    = creator.ColorRGBFrom8bit(225, 225, 225)
+	headerFill   = creator.ColorRGBFrom8bit(52, 73, 94)
+	weekendFill  = creator.ColorRGBFrom8bit(246, 246, 246)
+	textColor    = creator.ColorRGBFrom8bit(30, 30, 30)
+	otherMonth   = creator.ColorRGBFrom8bit(180, 180, 180)
+	holidayColor = creator.ColorRGBFrom8bit(253, 226, 226)
+	eventColor   = creator.ColorRGBFrom8bit(222, 235, 252)
+)
+
+// mark is a holiday or event marked on the calendar.  The date is in the format 2006-01-02, or 01-02 for holidays
+// on the same date every year.
+type mark struct {
+	Date  string
+	Label string
+	Color creator.Color
+}
+
+// holidays are marked in every year.  Holidays on varying dates are events of their year.
+var holidays = []mark{
+	{Date: "01-01", Label: "New Year's Day", Color: holidayColor},
+	{Date: "07-04", Label: "Independence Day", Color: holidayColor},
+	{Date: "11-11", Label: "Veterans Day", Color: holidayColor},
+	{Date: "12-24", Label: "Christmas Eve", Color: holidayColor},
+	{Date: "12-25", Label: "Christmas Day", Color: holidayColor},
+	{Date: "12-31", Label: "New Year's Eve", Color: holidayColor},
+}
+
+// monthGrid is the calendar grid of a month, with a column for each day of the week starting with firstWeekday and
+// a row for each week.  A mini grid is a compact grid for overviews: the weekday names are abbreviated, only the
+// days of the month are shown, marked days have no labels and the cells have no note lines.
+type monthGrid struct {
+	year         int
+	month        time.Month
+	firstWeekday time.Weekday
+	marks        []mark
+	mini         bool
+}
+
+// start returns the first day of the grid, the first day of the week on or before the first of the month, and the
+// number of weeks of the grid: the weeks that contain days of the month, 4 to 6.
+func (g monthGrid) start() (time.Time, int) {
+	first := time.Date(g.year, g.month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(first.Weekday()) - int(g.firstWeekday) + 7) % 7
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, -offset), (offset + daysInMonth + 6) / 7
+}
+
+// draw draws the grid in the area with the upper left corner (x, y), with rows rows (at least the number of weeks
+// of the month, 0 for as many rows as weeks), and returns the number of marked days.
+func (g monthGrid) draw(c *creator.Creator, x, y, width, height float64, rows int) (int, error) {
+	gridStart, weeks := g.start()
+	if rows < weeks {
+		rows = weeks
+	}
+
+	headerHeight, headerSize := 20.0, 10.0
+	if g.mini {
+		headerHeight, headerSize = 11.0, 6.5
+	}
+	cellWidth := width / 7
+	cellHeight := (height - headerHeight) / float64(rows)
+
+	// The weekday names.
+	header := creator.NewRectangle(x, y, width, headerHeight)
+	header.SetFillColor(headerFill)
+	header.SetBorderWidth(0)
+	err := c.Draw(header)
+	if err != nil {
+		return 0, err
+	}
+	for col := 0; col < 7; col++ {
+		name := time.Weekday((int(g.firstWeekday) + col) % 7).String()
+		if g.mini {
+			name = name[:2]
+		}
+		p := creator.NewParagraph(name)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(headerSize)
+		p.SetColor(creator.ColorRGBFrom8bit(255, 255, 255))
+		p.SetWidth(cellWidth)
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		p.SetPos(x+float64(col)*cellWidth, y+(headerHeight-headerSize)/2)
+		err := c.Draw(p)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	marked := 0
+	for i := 0; i < 7*rows; i++ {
+		day := gridStart.AddDate(0, 0, i)
+		cellX := x + float64(i%7)*cellWidth
+		cellY := y + headerHeight + float64(i/7)*cellHeight
+
+		dayMarks := []mark{}
+		if day.Month() == g.month {
+			dayMarks = marksOn(g.marks, day)
+			if len(dayMarks) > 0 {
+				marked++
+			}
+		}
+		err := g.drawCell(c, day, dayMarks, cellX, cellY, cellWidth, cellHeight)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return marked, nil
+}
+
+// drawCell draws the cell of the day with its upper left corner at (x, y): the background, the day number, the
+// labels of the marks and the note lines below them.  Days outside the month have only the number, in gray, or
+// nothing in mini grids.
+func (g monthGrid) drawCell(c *creator.Creator, day time.Time, dayMarks []mark, x, y, width, height float64) error {
+	inMonth := day.Month() == g.month
+
+	rect := creator.NewRectangle(x, y, width, height)
+	rect.SetBorderColor(gridColor)
+	rect.SetBorderWidth(0.5)
+	switch {
+	case len(dayMarks) > 0:
+		rect.SetFillColor(dayMarks[0].Color)
+	case day.Weekday() == time.Saturday || day.Weekday() == time.Sunday:
+		rect.SetFillColor(weekendFill)
+	default:
+		rect.SetFillColor(creator.ColorRGBFrom8bit(255, 255, 255))
+	}
+	if g.mini {
+		rect.SetBorderColor(noteColor)
+	}
+	err := c.Draw(rect)
+	if err != nil {
+		return err
+	}
+	if g.mini && !inMonth {
+		return nil
+	}
+
+	padding, numberSize := 4.0, 12.0
+	if g.mini {
+		padding, numberSize = 1.5, 6.5
+	}
+	number := creator.NewParagraph(fmt.Sprintf("%d", day.Day()))
+	number.SetFont(fonts.NewFontHelveticaBold())
+	number.SetFontSize(numberSize)
+	number.SetColor(textColor)
+	if !inMonth {
+		number.SetColor(otherMonth)
+	}
+	number.SetPos(x+padding, y+padding)
+	err = c.Draw(number)
+	if err != nil {
+		return err
+	}
+	if !inMonth || g.mini {
+		return nil
+	}
+
+	// The labels of the marks, one per line, below the day number.
+	textTop := y + padding + 14
+	if len(dayMarks) > 0 {
+		labels := []string{}
+		for _, m := range dayMarks {
+			labels = append(labels, m.Label)
+		}
+		p := creator.NewParagraph(strings.Join(labels, "\n"))
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(8)
+		p.SetColor(textColor)
+		p.SetWidth(width - 2*padding)
+		p.SetPos(x+padding, textTop)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+		textTop += p.Height()
+	}
+
+	// Note lines in the rest of the cell.
+	const noteSpacing = 14.0
+	for lineY := textTop + noteSpacing; lineY < y+height-padding; lineY += noteSpacing {
+		line := creator.NewLine(x+padding, lineY, x+width-padding, lineY)
+		line.SetLineWidth(0.5)
+		line.SetColor(noteColor)
+		err := c.Draw(line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marksOn returns the marks on the day.
+func marksOn(marks []mark, day time.Time) []mark {
+	dayMarks := []mark{}
+	for _, m := range marks {
+		if m.Date == day.Format("2006-01-02") || m.Date == day.Format("01-02") {
+			dayMarks = append(dayMarks, m)
+		}
+	}
+	return dayMarks
+}
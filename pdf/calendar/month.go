@@ -0,0 +1,124 @@
+/*
+ * Draw the calendar of a month: a grid with a column for each day of the week and a row for each week, with the
+ * weekday names above the columns and the day numbers in the cells.
+ *
+ * The first column is Sunday or Monday (-weekstart).  The month starts in the column of its first weekday, and the
+ * grid has as many rows as the weeks that the month spans: 4 to 6.  The rows share the height of the page, so the
+ * cells are smaller in six-week months.  The cells before the first and after the last day of the month show the
+ * days of the neighbouring months in gray, and the rest of each cell is ruled for notes.
+ *
+ * Holidays and events, defined in Go, are marked with a colored cell background and their names below the day
+ * number.  Holidays with a fixed date are marked every year; events only in their year.
+ *
+ * The grid is drawn by the code in grid.go, which is shared with year.go.
+ *
+ * Run as: go run month.go grid.go [-year 2017] [-month 12] [-weekstart sunday|monday] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run month.go grid.go [-year 2017] [-month 12] [-weekstart sunday|monday] output.pdf\n"
+
+const (
+	pageMargin  = 36.0 // Page margin on all sides.
+	titleHeight = 44.0 // Height of the month name above the grid.
+)
+
+// events are marked in their year, in addition to the holidays.
+var events = []mark{
+	{Date: "2017-11-23", Label: "Thanksgiving", Color: holidayColor},
+	{Date: "2017-12-05", Label: "Release 2.2", Color: eventColor},
+	{Date: "2017-12-14", Label: "Team offsite", Color: eventColor},
+	{Date: "2017-12-14", Label: "Budget review", Color: eventColor},
+	{Date: "2017-12-22", Label: "Office party", Color: eventColor},
+}
+
+func main() {
+	now := time.Now()
+	year := 0
+	month := 0
+	weekStart := ""
+	flag.IntVar(&year, "year", now.Year(), "Year")
+	flag.IntVar(&month, "month", int(now.Month()), "Month (1-12)")
+	flag.StringVar(&weekStart, "weekstart", "sunday", "First day of the week: sunday or monday")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if month < 1 || month > 12 {
+		fmt.Printf("Error: -month must be between 1 and 12\n")
+		os.Exit(1)
+	}
+	firstWeekday := time.Sunday
+	switch weekStart {
+	case "sunday":
+	case "monday":
+		firstWeekday = time.Monday
+	default:
+		fmt.Printf("Error: -weekstart must be sunday or monday\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	grid := monthGrid{
+		year:         year,
+		month:        time.Month(month),
+		firstWeekday: firstWeekday,
+		marks:        append(append([]mark{}, holidays...), events...),
+	}
+	err := drawMonth(grid, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func drawMonth(grid monthGrid, outputPath string) error {
+	c := creator.New()
+	c.SetPageSize(creator.PageSize{creator.PageSizeA4[1], creator.PageSizeA4[0]})
+	c.NewPage()
+
+	title := creator.NewParagraph(fmt.Sprintf("%s %d", grid.month, grid.year))
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(24)
+	title.SetColor(textColor)
+	title.SetPos(pageMargin, pageMargin)
+	err := c.Draw(title)
+	if err != nil {
+		return err
+	}
+
+	top := pageMargin + titleHeight
+	marked, err := grid.draw(c, pageMargin, top, c.Width()-2*pageMargin, c.Height()-pageMargin-top, 0)
+	if err != nil {
+		return err
+	}
+
+	_, weeks := grid.start()
+	fmt.Printf("%s %d: %d weeks, %d marked days\n", grid.month, grid.year, weeks, marked)
+
+	return c.WriteToFile(outputPath)
+}
@@ -0,0 +1,344 @@
+/*
+ * Create the calendar of a year: a cover page followed by either a page for each month (-layout months) or an
+ * overview page with the twelve months in a 3x4 grid (-layout overview).
+ *
+ * The months are drawn with the month grid of month.go, in grid.go: the month pages have the full grid with the
+ * labels of the holidays and events and space for notes, and the overview has compact grids of the same height for
+ * all months, where the holidays and events are only marked by color, and is followed by a list of the events.
+ * February has 29 days in leap years, which the cover page notes.
+ *
+ * Events are read from a text file (-events), one per line with the date and label separated by a space: the date
+ * is in the format 2006-01-02, or 01-02 for events on the same date every year.  Empty lines and lines starting with
+ * "#" are ignored.  The file events.txt in this directory has examples for 2020.
+ *
+ * Run as: go run year.go grid.go [-layout months|overview] [-weekstart sunday|monday] [-events events.txt]
+ *         [-title text] year output.pdf
+ */
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run year.go grid.go [-layout months|overview] [-weekstart sunday|monday] " +
+	"[-events events.txt] [-title text] year output.pdf\n"
+
+const (
+	pageMargin  = 36.0 // Page margin on all sides.
+	titleHeight = 44.0 // Height of the month name above the grid on the month pages.
+	miniGap     = 18.0 // Space between the months on the overview page.
+)
+
+func main() {
+	layout := ""
+	weekStart := ""
+	eventsPath := ""
+	title := ""
+	flag.StringVar(&layout, "layout", "months", "Layout: months (a page per month) or overview (a page with all "+
+		"months)")
+	flag.StringVar(&weekStart, "weekstart", "sunday", "First day of the week: sunday or monday")
+	flag.StringVar(&eventsPath, "events", "", "Text file with events to mark")
+	flag.StringVar(&title, "title", "", "Title on the cover page")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	year, err := strconv.Atoi(flag.Arg(0))
+	if err != nil || year < 1 || year > 9999 {
+		fmt.Printf("Error: invalid year %q\n", flag.Arg(0))
+		os.Exit(1)
+	}
+	if layout != "months" && layout != "overview" {
+		fmt.Printf("Error: -layout must be months or overview\n")
+		os.Exit(1)
+	}
+	firstWeekday := time.Sunday
+	switch weekStart {
+	case "sunday":
+	case "monday":
+		firstWeekday = time.Monday
+	default:
+		fmt.Printf("Error: -weekstart must be sunday or monday\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(1)
+
+	events := []mark{}
+	if eventsPath != "" {
+		events, err = readEvents(eventsPath, year)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	err = drawYear(year, firstWeekday, events, title, layout == "overview", outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// readEvents reads the events from the file at path.  Events dated in other years than year are skipped.
+func readEvents(path string, year int) ([]mark, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events := []mark{}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: missing event label", path, lineNum)
+		}
+		date, label := fields[0], strings.TrimSpace(fields[1])
+
+		// Parsing the dates rejects days that do not exist, such as February 29 in other years than leap years.
+		if len(date) == len("01-02") {
+			_, err = time.Parse("01-02", date)
+		} else {
+			var t time.Time
+			t, err = time.Parse("2006-01-02", date)
+			if err == nil && t.Year() != year {
+				fmt.Printf("Skipping event %q on %s, not in %d\n", label, date, year)
+				continue
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid date %q", path, lineNum, date)
+		}
+		events = append(events, mark{Date: date, Label: label, Color: eventColor})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func drawYear(year int, firstWeekday time.Weekday, events []mark, title string, overview bool,
+	outputPath string) error {
+	c := creator.New()
+	if overview {
+		c.SetPageSize(creator.PageSizeA4)
+	} else {
+		c.SetPageSize(creator.PageSize{creator.PageSizeA4[1], creator.PageSizeA4[0]})
+	}
+	marks := append(append([]mark{}, holidays...), events...)
+
+	err := drawCover(c, year, title)
+	if err != nil {
+		return err
+	}
+
+	marked := 0
+	for month := time.January; month <= time.December; month++ {
+		grid := monthGrid{year: year, month: month, firstWeekday: firstWeekday, marks: marks, mini: overview}
+		n := 0
+		if overview {
+			n, err = drawMiniMonth(c, grid)
+		} else {
+			n, err = drawMonthPage(c, grid)
+		}
+		if err != nil {
+			return err
+		}
+		marked += n
+	}
+	if overview {
+		err = drawEventList(c, year, marks)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%d: %d marked days\n", year, marked)
+
+	return c.WriteToFile(outputPath)
+}
+
+// isLeapYear returns true if year has a February 29.
+func isLeapYear(year int) bool {
+	return time.Date(year, time.February, 29, 0, 0, 0, 0, time.UTC).Day() == 29
+}
+
+// drawCover draws the cover page with the year, the title and the number of days of the year.
+func drawCover(c *creator.Creator, year int, title string) error {
+	c.NewPage()
+
+	days := "365 days"
+	if isLeapYear(year) {
+		days = "Leap year, 366 days"
+	}
+
+	lines := []struct {
+		text string
+		size float64
+		bold bool
+	}{
+		{strconv.Itoa(year), 96, true},
+		{title, 24, false},
+		{days, 12, false},
+	}
+
+	y := c.Height() / 3
+	for _, line := range lines {
+		if line.text == "" {
+			continue
+		}
+		p := creator.NewParagraph(line.text)
+		p.SetFont(fonts.NewFontHelvetica())
+		if line.bold {
+			p.SetFont(fonts.NewFontHelveticaBold())
+		}
+		p.SetFontSize(line.size)
+		p.SetColor(headerFill)
+		p.SetWidth(c.Width() - 2*pageMargin)
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		p.SetPos(pageMargin, y)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+		y += p.Height() + line.size/2
+	}
+	return nil
+}
+
+// drawMonthPage draws the month on a page of its own and returns the number of marked days.
+func drawMonthPage(c *creator.Creator, grid monthGrid) (int, error) {
+	c.NewPage()
+
+	title := creator.NewParagraph(fmt.Sprintf("%s %d", grid.month, grid.year))
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(24)
+	title.SetColor(textColor)
+	title.SetPos(pageMargin, pageMargin)
+	err := c.Draw(title)
+	if err != nil {
+		return 0, err
+	}
+
+	top := pageMargin + titleHeight
+	return grid.draw(c, pageMargin, top, c.Width()-2*pageMargin, c.Height()-pageMargin-top, 0)
+}
+
+// drawMiniMonth draws the month in its position on the overview page, 3 months per row, and returns the number of
+// marked days.  The overview page is started with January.
+func drawMiniMonth(c *creator.Creator, grid monthGrid) (int, error) {
+	if grid.month == time.January {
+		c.NewPage()
+
+		title := creator.NewParagraph(strconv.Itoa(grid.year))
+		title.SetFont(fonts.NewFontHelveticaBold())
+		title.SetFontSize(24)
+		title.SetColor(textColor)
+		title.SetPos(pageMargin, pageMargin)
+		err := c.Draw(title)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	index := int(grid.month) - 1
+	top := pageMargin + titleHeight
+	width := (c.Width() - 2*pageMargin - 2*miniGap) / 3
+	height := (c.Height() - pageMargin - top - 3*miniGap) / 4
+	x := pageMargin + float64(index%3)*(width+miniGap)
+	y := top + float64(index/3)*(height+miniGap)
+
+	name := creator.NewParagraph(grid.month.String())
+	name.SetFont(fonts.NewFontHelveticaBold())
+	name.SetFontSize(11)
+	name.SetColor(headerFill)
+	name.SetPos(x, y)
+	err := c.Draw(name)
+	if err != nil {
+		return 0, err
+	}
+
+	// All grids have 6 rows, so that the rows line up across the months.
+	return grid.draw(c, x, y+16, width, height-16, 6)
+}
+
+// drawEventList lists the holidays and events of the year in date order on a new page, for the overview where they
+// are marked only by color.
+func drawEventList(c *creator.Creator, year int, marks []mark) error {
+	c.NewPage()
+
+	title := creator.NewParagraph(fmt.Sprintf("Holidays and events %d", year))
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(18)
+	title.SetColor(textColor)
+	title.SetMargins(0, 0, 0, 12)
+	err := c.Draw(title)
+	if err != nil {
+		return err
+	}
+
+	table := creator.NewTable(3)
+	err = table.SetColumnWidths(0.04, 0.2, 0.76)
+	if err != nil {
+		return err
+	}
+	rows := 0
+	for day := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC); day.Year() == year; day = day.AddDate(0, 0, 1) {
+		for _, m := range marksOn(marks, day) {
+			swatch := table.NewCell()
+			swatch.SetBackgroundColor(m.Color)
+			swatch.SetBorder(creator.CellBorderStyleBox, 1)
+			swatch.SetBorderColor(creator.ColorRGBFrom8bit(255, 255, 255))
+			err := swatch.SetContent(creator.NewParagraph(" "))
+			if err != nil {
+				return err
+			}
+
+			for _, text := range []string{day.Format("Mon, Jan 2"), m.Label} {
+				p := creator.NewParagraph(text)
+				p.SetFont(fonts.NewFontHelvetica())
+				p.SetFontSize(10)
+				p.SetColor(textColor)
+				cell := table.NewCell()
+				cell.SetIndent(6)
+				err := cell.SetContent(p)
+				if err != nil {
+					return err
+				}
+			}
+			rows++
+		}
+	}
+	if rows == 0 {
+		return nil
+	}
+	return c.Draw(table)
+}
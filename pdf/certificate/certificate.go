@@ -0,0 +1,290 @@
+/*
+ * Create a certificate on a landscape page: a decorative ruled border with corner ornaments, a title, the recipient
+ * name, body text, the date and two signature lines.
+ *
+ * The border is drawn with the creator's graphics (rectangles, lines and ellipses).  The text is stacked and centered
+ * vertically within the border.  Long recipient names are set in a smaller font so that they fit on one line within
+ * the border.
+ *
+ * Run as: go run certificate.go [-name "..."] [-title "..."] [-body "..."] [-date "..."] [-signer1 "Name, Role"]
+ *                               [-signer2 "Name, Role"] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run certificate.go [-name ...] [-title ...] [-body ...] [-date ...] [-signer1 ...] " +
+	"[-signer2 ...] output.pdf\n"
+
+const (
+	borderMargin   = 28.0 // Distance from the page edge to the outer border rule.
+	borderWidth    = 22.0 // Distance between the outer and inner border rules.
+	textMargin     = 60.0 // Horizontal distance from the inner border rule to the text.
+	nameFontSize   = 40.0 // Font size of the recipient name, reduced for long names.
+	minNameSize    = 16.0 // Smallest font size for the recipient name.
+	signatureWidth = 200.0
+)
+
+var (
+	colorNavy = creator.ColorRGBFrom8bit(22, 43, 87)
+	colorGold = creator.ColorRGBFrom8bit(176, 141, 58)
+	colorText = creator.ColorRGBFrom8bit(40, 40, 40)
+)
+
+// certificate holds the texts of the certificate.
+type certificate struct {
+	title     string
+	subtitle  string
+	recipient string
+	body      string
+	date      string
+	signers   []string // "Name, Role"
+}
+
+// stackItem is an element of the vertically centered text stack: its height and a function drawing it with its top
+// at y.
+type stackItem struct {
+	height float64
+	draw   func(y float64)
+}
+
+func main() {
+	cert := certificate{subtitle: "This certificate is proudly presented to"}
+	signer1 := ""
+	signer2 := ""
+	flag.StringVar(&cert.title, "title", "Certificate of Achievement", "Certificate title")
+	flag.StringVar(&cert.recipient, "name", "Alexandra Montgomery-Richardson", "Recipient name")
+	flag.StringVar(&cert.body, "body", "In recognition of the successful completion of the Advanced PDF Processing "+
+		"course, demonstrating outstanding dedication, skill and a commitment to excellence.", "Body text")
+	flag.StringVar(&cert.date, "date", time.Now().Format("January 2, 2006"), "Date")
+	flag.StringVar(&signer1, "signer1", "Dr. Emily Carter, Program Director", "First signature (name, role)")
+	flag.StringVar(&signer2, "signer2", "James Whitfield, Head Instructor", "Second signature (name, role)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+	cert.signers = []string{signer1, signer2}
+
+	err := createCertificate(cert, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createCertificate(cert certificate, outputPath string) error {
+	c := creator.New()
+	// Landscape US Letter.
+	c.SetPageSize(creator.PageSize{creator.PageSizeLetter[1], creator.PageSizeLetter[0]})
+	c.NewPage()
+
+	pageWidth := c.Context().PageWidth
+	pageHeight := c.Context().PageHeight
+	drawBorder(c, pageWidth, pageHeight)
+
+	inner := borderMargin + borderWidth
+	textWidth := pageWidth - 2*(inner+textMargin)
+	textX := inner + textMargin
+
+	items := []stackItem{
+		textItem(c, cert.title, fonts.NewFontTimesBold(), 34, colorNavy, textX, textWidth),
+		spaceItem(18),
+		textItem(c, cert.subtitle, fonts.NewFontTimesItalic(), 15, colorText, textX, textWidth),
+		spaceItem(12),
+		nameItem(c, cert.recipient, textX, textWidth),
+		spaceItem(14),
+		textItem(c, cert.body, fonts.NewFontTimesRoman(), 14, colorText, textX+40, textWidth-80),
+		spaceItem(14),
+		textItem(c, cert.date, fonts.NewFontTimesItalic(), 13, colorText, textX, textWidth),
+		spaceItem(40),
+		signaturesItem(c, cert.signers, textX, textWidth),
+	}
+
+	// Center the stack vertically within the inner border.
+	total := 0.0
+	for _, item := range items {
+		total += item.height
+	}
+	y := inner + (pageHeight-2*inner-total)/2
+	for _, item := range items {
+		item.draw(y)
+		y += item.height
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawBorder draws the decorative border: a thick outer and thin inner navy rule with two gold rules between them,
+// and ornaments in the corners and in the middle of the top and bottom edges.
+func drawBorder(c *creator.Creator, pageWidth, pageHeight float64) {
+	rule := func(inset, width float64, col creator.Color) {
+		rect := creator.NewRectangle(inset, inset, pageWidth-2*inset, pageHeight-2*inset)
+		rect.SetBorderColor(col)
+		rect.SetBorderWidth(width)
+		_ = c.Draw(rect)
+	}
+
+	rule(borderMargin, 4, colorNavy)
+	rule(borderMargin+6, 1.5, colorGold)
+	rule(borderMargin+borderWidth-6, 1.5, colorGold)
+	rule(borderMargin+borderWidth, 1, colorNavy)
+
+	// Corner ornaments: a gold disc with a navy ring and a navy center dot.
+	mid := borderMargin + borderWidth/2
+	for _, corner := range [][2]float64{
+		{mid, mid},
+		{pageWidth - mid, mid},
+		{mid, pageHeight - mid},
+		{pageWidth - mid, pageHeight - mid},
+	} {
+		x, y := corner[0], corner[1]
+
+		disc := creator.NewEllipse(x, y, borderWidth+6, borderWidth+6)
+		disc.SetFillColor(colorGold)
+		disc.SetBorderColor(colorNavy)
+		disc.SetBorderWidth(2)
+		_ = c.Draw(disc)
+
+		dot := creator.NewEllipse(x, y, 6, 6)
+		dot.SetFillColor(colorNavy)
+		dot.SetBorderColor(colorNavy)
+		dot.SetBorderWidth(0)
+		_ = c.Draw(dot)
+	}
+
+	// Beads in the middle of the top and bottom edges.
+	for _, y := range []float64{mid, pageHeight - mid} {
+		for _, dx := range []float64{-18, 0, 18} {
+			size := 8.0
+			if dx == 0 {
+				size = 12
+			}
+			bead := creator.NewEllipse(pageWidth/2+dx, y, size, size)
+			bead.SetFillColor(colorNavy)
+			bead.SetBorderColor(colorGold)
+			bead.SetBorderWidth(1)
+			_ = c.Draw(bead)
+		}
+	}
+}
+
+func spaceItem(height float64) stackItem {
+	return stackItem{height: height, draw: func(float64) {}}
+}
+
+// textItem returns a centered paragraph wrapped to width.
+func textItem(c *creator.Creator, text string, font fonts.Font, fontSize float64, col creator.Color,
+	x, width float64) stackItem {
+	p := newParagraph(text, font, fontSize, col)
+	p.SetWidth(width)
+	p.SetTextAlignment(creator.TextAlignmentCenter)
+
+	return stackItem{
+		height: p.Height(),
+		draw: func(y float64) {
+			p.SetPos(x, y)
+			_ = c.Draw(p)
+		},
+	}
+}
+
+// nameItem returns the recipient name on a single line, shrinking the font size until it fits within width, with a
+// gold rule below it.
+func nameItem(c *creator.Creator, name string, x, width float64) stackItem {
+	font := fonts.NewFontTimesBoldItalic()
+	fontSize := nameFontSize
+	p := newParagraph(name, font, fontSize, colorNavy)
+	p.SetEnableWrap(false)
+	for p.Width() > width && fontSize > minNameSize {
+		fontSize--
+		p.SetFontSize(fontSize)
+	}
+	if p.Width() > width {
+		fmt.Printf("Warning: the name does not fit at the minimum font size of %.0f\n", minNameSize)
+	}
+
+	const ruleGap = 8
+	return stackItem{
+		height: p.Height() + ruleGap,
+		draw: func(y float64) {
+			p.SetPos(x+(width-p.Width())/2, y)
+			_ = c.Draw(p)
+
+			ruleY := y + p.Height() + ruleGap
+			line := creator.NewLine(x+width*0.15, ruleY, x+width*0.85, ruleY)
+			line.SetColor(colorGold)
+			line.SetLineWidth(1)
+			_ = c.Draw(line)
+		},
+	}
+}
+
+// signaturesItem returns the signature lines, spread evenly across the width, each with the signer's name and role
+// below the line.
+func signaturesItem(c *creator.Creator, signers []string, x, width float64) stackItem {
+	const nameSize, roleSize = 12.0, 10.0
+	gap := (width - float64(len(signers))*signatureWidth) / float64(len(signers)+1)
+
+	return stackItem{
+		height: 4 + nameSize + roleSize + 4,
+		draw: func(y float64) {
+			for i, signer := range signers {
+				left := x + gap + float64(i)*(signatureWidth+gap)
+				line := creator.NewLine(left, y, left+signatureWidth, y)
+				line.SetColor(colorText)
+				line.SetLineWidth(0.75)
+				_ = c.Draw(line)
+
+				name, role := signer, ""
+				if pos := strings.Index(signer, ","); pos >= 0 {
+					name, role = strings.TrimSpace(signer[:pos]), strings.TrimSpace(signer[pos+1:])
+				}
+
+				p := newParagraph(name, fonts.NewFontTimesBold(), nameSize, colorText)
+				p.SetWidth(signatureWidth)
+				p.SetTextAlignment(creator.TextAlignmentCenter)
+				p.SetPos(left, y+4)
+				_ = c.Draw(p)
+
+				p = newParagraph(role, fonts.NewFontTimesItalic(), roleSize, colorText)
+				p.SetWidth(signatureWidth)
+				p.SetTextAlignment(creator.TextAlignmentCenter)
+				p.SetPos(left, y+4+nameSize+2)
+				_ = c.Draw(p)
+			}
+		},
+	}
+}
+
+func newParagraph(text string, font fonts.Font, fontSize float64, col creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetColor(col)
+	p.SetLineHeight(1.2)
+	return p
+}
@@ -0,0 +1,460 @@
+/*
+ * Draw a Gantt chart of the tasks of a project with the creator's graphics: a horizontal bar for each task, spanning
+ * its start and end dates on a timeline, with date gridlines and task labels.
+ *
+ * The timeline runs from the earliest start to the latest end date, and the scale (points per day) is computed to fit
+ * it into the width of the chart.  The gridlines are placed on days, weeks (Mondays) or months, whichever is the
+ * finest unit that leaves enough room for the date labels.
+ *
+ * The tasks are given in Go with the resource (person or team) assigned to them.  With -by task every task has a row
+ * of its own, labelled with the task name.  With -by resource the rows are the resources: the tasks of a resource
+ * share a row as long as they do not overlap, and overlapping tasks are placed on additional rows below, so no bars
+ * are drawn on top of each other.  The task names are then drawn in the bars.
+ *
+ * Short tasks would have bars too narrow to see, so bars are at least minBarWidth wide.  Task names that do not fit
+ * in their bar are drawn to its right, or to its left at the end of the timeline, and count as part of the bar when
+ * placing the tasks on the rows.  Charts with more rows than fit on a page continue on the next pages, with the
+ * timeline repeated.
+ *
+ * Run as: go run gantt.go [-by task|resource] output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run gantt.go [-by task|resource] output.pdf\n"
+
+const (
+	pageMargin    = 36.0  // Page margin on all sides.
+	titleHeight   = 36.0  // Height of the title above the chart.
+	axisHeight    = 28.0  // Height of the timeline with the date labels above the rows.
+	labelWidth    = 150.0 // Width of the row labels left of the chart.
+	rowHeight     = 20.0  // Height of a row.
+	barHeight     = 12.0  // Height of a bar, centered in its row.
+	minBarWidth   = 4.0   // Bars of short tasks are widened to this width.
+	minLabelSpace = 40.0  // Minimum distance between the gridline date labels.
+	fontSize      = 8.0
+)
+
+var (
+	gridColor  = creator.ColorRGBFrom8bit(210, 210, 210)
+	textColor  = creator.ColorRGBFrom8bit(40, 40, 40)
+	stripeFill = creator.ColorRGBFrom8bit(245, 246, 248)
+)
+
+// task is a task of the project.  The dates are in the format 2006-01-02, and the end date is the last day of the
+// task, so a task of one day has the same start and end date.
+type task struct {
+	Name     string
+	Resource string
+	Start    string
+	End      string
+}
+
+var tasks = []task{
+	{Name: "Requirements", Resource: "Anna", Start: "2017-09-04", End: "2017-09-15"},
+	{Name: "Kick-off meeting", Resource: "Team", Start: "2017-09-04", End: "2017-09-04"},
+	{Name: "Architecture", Resource: "Ben", Start: "2017-09-11", End: "2017-09-22"},
+	{Name: "UI design", Resource: "Carla", Start: "2017-09-18", End: "2017-10-06"},
+	{Name: "Database schema", Resource: "Ben", Start: "2017-09-20", End: "2017-09-29"},
+	{Name: "Backend services", Resource: "Ben", Start: "2017-10-02", End: "2017-11-03"},
+	{Name: "API documentation", Resource: "Anna", Start: "2017-10-09", End: "2017-10-27"},
+	{Name: "Frontend", Resource: "Carla", Start: "2017-10-09", End: "2017-11-10"},
+	{Name: "Design review", Resource: "Team", Start: "2017-10-06", End: "2017-10-06"},
+	{Name: "Test plan", Resource: "Anna", Start: "2017-10-16", End: "2017-10-20"},
+	{Name: "Integration", Resource: "Ben", Start: "2017-11-06", End: "2017-11-17"},
+	{Name: "Accessibility audit", Resource: "Carla", Start: "2017-11-01", End: "2017-11-03"},
+	{Name: "System testing", Resource: "Anna", Start: "2017-11-13", End: "2017-12-01"},
+	{Name: "Bug fixing", Resource: "Ben", Start: "2017-11-20", End: "2017-12-08"},
+	{Name: "User training", Resource: "Carla", Start: "2017-11-27", End: "2017-12-08"},
+	{Name: "Release", Resource: "Team", Start: "2017-12-11", End: "2017-12-11"},
+}
+
+// Bar colors of the resources, in order of first appearance.
+var barColors = []creator.Color{
+	creator.ColorRGBFrom8bit(66, 133, 244),
+	creator.ColorRGBFrom8bit(52, 168, 83),
+	creator.ColorRGBFrom8bit(234, 134, 0),
+	creator.ColorRGBFrom8bit(142, 68, 173),
+	creator.ColorRGBFrom8bit(219, 68, 55),
+}
+
+// bar is a task with its dates parsed, placed on a row of the chart.
+type bar struct {
+	task       task
+	start, end time.Time // end is the day after the last day of the task.
+	color      creator.Color
+}
+
+// timeline maps the dates to the horizontal positions on the chart.
+type timeline struct {
+	first, last time.Time // last is the day after the last day of the tasks.
+	left, right float64
+}
+
+// barLayout is the position of a bar and the task name on the chart.
+type barLayout struct {
+	x, width    float64
+	nameX       float64
+	nameInside  bool
+	left, right float64 // The horizontal extent of the bar with the name, and a gap after them.
+}
+
+// chartRow is a row of the chart with its label (empty for the additional rows of a resource) and bars.  Rows of
+// odd groups (tasks, or resources with their additional rows) are shaded.
+type chartRow struct {
+	label string
+	group int
+	bars  []bar
+}
+
+func main() {
+	by := ""
+	flag.StringVar(&by, "by", "task", "Rows of the chart: task (a row per task) or resource (a row per resource, "+
+		"with overlapping tasks on additional rows)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if by != "task" && by != "resource" {
+		fmt.Printf("Error: -by must be task or resource\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := drawGantt(tasks, by == "resource", outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func drawGantt(tasks []task, byResource bool, outputPath string) error {
+	bars, err := parseTasks(tasks)
+	if err != nil {
+		return err
+	}
+
+	// The timeline spans the tasks.
+	first, last := bars[0].start, bars[0].end
+	for _, b := range bars {
+		if b.start.Before(first) {
+			first = b.start
+		}
+		if b.end.After(last) {
+			last = b.end
+		}
+	}
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSize{creator.PageSizeA4[1], creator.PageSizeA4[0]})
+
+	tl := timeline{first: first, last: last, left: pageMargin + labelWidth, right: c.Width() - pageMargin}
+
+	rows := []chartRow{}
+	if byResource {
+		rows = rowsByResource(bars, tl)
+	} else {
+		for i, b := range bars {
+			rows = append(rows, chartRow{label: b.task.Name, group: i, bars: []bar{b}})
+		}
+	}
+
+	rowsPerPage := int((c.Height() - 2*pageMargin - titleHeight - axisHeight) / rowHeight)
+	for pageStart := 0; pageStart < len(rows); pageStart += rowsPerPage {
+		pageRows := rows[pageStart:int(math.Min(float64(pageStart+rowsPerPage), float64(len(rows))))]
+		c.NewPage()
+
+		title := fmt.Sprintf("Project schedule, %s - %s", first.Format("Jan 2, 2006"),
+			last.AddDate(0, 0, -1).Format("Jan 2, 2006"))
+		if pageStart > 0 {
+			title += " (continued)"
+		}
+		p := creator.NewParagraph(title)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(14)
+		p.SetColor(textColor)
+		p.SetPos(pageMargin, pageMargin)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+
+		top := pageMargin + titleHeight + axisHeight
+		bottom := top + float64(len(pageRows))*rowHeight
+
+		for i, row := range pageRows {
+			if row.group%2 == 0 {
+				continue
+			}
+			stripe := creator.NewRectangle(pageMargin, top+float64(i)*rowHeight, c.Width()-2*pageMargin, rowHeight)
+			stripe.SetFillColor(stripeFill)
+			stripe.SetBorderWidth(0)
+			err := c.Draw(stripe)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = drawTimeline(c, tl, top, bottom)
+		if err != nil {
+			return err
+		}
+
+		for i, row := range pageRows {
+			y := top + float64(i)*rowHeight
+			if row.label != "" {
+				p := newLabel(row.label, textColor)
+				p.SetPos(pageMargin+4, y+(rowHeight-fontSize)/2)
+				err := c.Draw(p)
+				if err != nil {
+					return err
+				}
+			}
+			for _, b := range row.bars {
+				err := drawBar(c, b, tl, y+(rowHeight-barHeight)/2, byResource)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	fmt.Printf("%d tasks on %d rows, %.1f points per day\n", len(bars), len(rows), tl.scale())
+
+	return c.WriteToFile(outputPath)
+}
+
+// parseTasks parses the dates of the tasks and assigns the colors of their resources.
+func parseTasks(tasks []task) ([]bar, error) {
+	if len(tasks) == 0 {
+		return nil, errors.New("No tasks")
+	}
+
+	colors := map[string]creator.Color{}
+	bars := []bar{}
+	for _, t := range tasks {
+		start, err := time.Parse("2006-01-02", t.Start)
+		if err != nil {
+			return nil, fmt.Errorf("Task %q: %v", t.Name, err)
+		}
+		end, err := time.Parse("2006-01-02", t.End)
+		if err != nil {
+			return nil, fmt.Errorf("Task %q: %v", t.Name, err)
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("Task %q ends before it starts", t.Name)
+		}
+
+		color, ok := colors[t.Resource]
+		if !ok {
+			color = barColors[len(colors)%len(barColors)]
+			colors[t.Resource] = color
+		}
+		bars = append(bars, bar{task: t, start: start, end: end.AddDate(0, 0, 1), color: color})
+	}
+	return bars, nil
+}
+
+// rowsByResource places the bars on rows by resource, in order of first appearance of the resources.  Each task is
+// placed on the first row of its resource where its bar, including a name drawn to its right, does not overlap the
+// bars already placed, or on a new row.
+func rowsByResource(bars []bar, tl timeline) []chartRow {
+	resources := []string{}
+	byResource := map[string][]bar{}
+	for _, b := range bars {
+		if _, ok := byResource[b.task.Resource]; !ok {
+			resources = append(resources, b.task.Resource)
+		}
+		byResource[b.task.Resource] = append(byResource[b.task.Resource], b)
+	}
+
+	rows := []chartRow{}
+	for group, resource := range resources {
+		resourceBars := byResource[resource]
+		sort.SliceStable(resourceBars, func(i, j int) bool {
+			return resourceBars[i].start.Before(resourceBars[j].start)
+		})
+
+		// The right end of the bars on each row of the resource.  As the bars are placed in order of their start
+		// dates, a bar fits on a row if it starts after the bars on the row end.
+		resourceRows := []chartRow{}
+		rowEnds := []float64{}
+		for _, b := range resourceBars {
+			layout := layoutBar(b, tl)
+			end := layout.right
+			placed := false
+			for i := range resourceRows {
+				if layout.left >= rowEnds[i] {
+					resourceRows[i].bars = append(resourceRows[i].bars, b)
+					rowEnds[i] = math.Max(rowEnds[i], end)
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				resourceRows = append(resourceRows, chartRow{group: group, bars: []bar{b}})
+				rowEnds = append(rowEnds, end)
+			}
+		}
+		resourceRows[0].label = resource
+		if len(resourceRows) > 1 {
+			fmt.Printf("%s: overlapping tasks on %d rows\n", resource, len(resourceRows))
+		}
+		rows = append(rows, resourceRows...)
+	}
+	return rows
+}
+
+// drawTimeline draws the date gridlines from top to bottom with the date labels above them.  The gridlines are on
+// the days, weeks or months in the timeline, using the finest unit whose gridlines are at least minLabelSpace apart.
+func drawTimeline(c *creator.Creator, tl timeline, top, bottom float64) error {
+	first, last, scale := tl.first, tl.last, tl.scale()
+
+	// The gridline dates, starting at the first day, Monday or first of the month of the timeline.
+	var dates []time.Time
+	var format string
+	switch {
+	case scale >= minLabelSpace:
+		for t := first; !t.After(last); t = t.AddDate(0, 0, 1) {
+			dates = append(dates, t)
+		}
+		format = "Jan 2"
+	case 7*scale >= minLabelSpace:
+		t := first.AddDate(0, 0, (8-int(first.Weekday()))%7)
+		for ; !t.After(last); t = t.AddDate(0, 0, 7) {
+			dates = append(dates, t)
+		}
+		format = "Jan 2"
+	default:
+		t := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if t.Before(first) {
+			t = t.AddDate(0, 1, 0)
+		}
+		for ; !t.After(last); t = t.AddDate(0, 1, 0) {
+			dates = append(dates, t)
+		}
+		format = "Jan 2006"
+	}
+
+	for _, date := range dates {
+		x := tl.x(date)
+		line := creator.NewLine(x, top-4, x, bottom)
+		line.SetLineWidth(0.5)
+		line.SetColor(gridColor)
+		err := c.Draw(line)
+		if err != nil {
+			return err
+		}
+
+		// The label is centered on the gridline, but kept within the chart.
+		p := newLabel(date.Format(format), textColor)
+		labelX := math.Max(x-p.Width()/2, tl.left)
+		labelX = math.Min(labelX, tl.right-p.Width())
+		p.SetPos(labelX, top-4-fontSize-6)
+		err = c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The frame of the chart area.
+	frame := creator.NewRectangle(tl.left, top, tl.right-tl.left, bottom-top)
+	frame.SetBorderColor(gridColor)
+	frame.SetBorderWidth(0.5)
+	return c.Draw(frame)
+}
+
+// drawBar draws the bar of a task with its top at y, and the task name if withName is set.
+func drawBar(c *creator.Creator, b bar, tl timeline, y float64, withName bool) error {
+	layout := layoutBar(b, tl)
+
+	rect := creator.NewRectangle(layout.x, y, layout.width, barHeight)
+	rect.SetFillColor(b.color)
+	rect.SetBorderWidth(0)
+	err := c.Draw(rect)
+	if err != nil {
+		return err
+	}
+
+	if !withName {
+		return nil
+	}
+	color := textColor
+	if layout.nameInside {
+		color = creator.ColorRGBFrom8bit(255, 255, 255)
+	}
+	p := newLabel(b.task.Name, color)
+	p.SetPos(layout.nameX, y+(barHeight-fontSize)/2)
+	return c.Draw(p)
+}
+
+// scale returns the number of points per day.
+func (tl timeline) scale() float64 {
+	return (tl.right - tl.left) / tl.last.Sub(tl.first).Hours() * 24
+}
+
+// x returns the horizontal position of the start of day t.
+func (tl timeline) x(t time.Time) float64 {
+	return tl.left + t.Sub(tl.first).Hours()/24*tl.scale()
+}
+
+// layoutBar returns the position of the bar of a task and of its name.  The name is drawn in the bar if it fits,
+// otherwise to the right of the bar, or to its left if there is no room at the end of the timeline.
+func layoutBar(b bar, tl timeline) barLayout {
+	x := tl.x(b.start)
+	width := math.Max(tl.x(b.end)-x, minBarWidth)
+	// Bars widened at the end of the timeline are moved left to stay on the chart.
+	x = math.Min(x, tl.right-width)
+
+	layout := barLayout{x: x, width: width, left: x, right: x + width}
+	nameWidth := newLabel(b.task.Name, textColor).Width()
+	switch {
+	case nameWidth+6 <= width:
+		layout.nameX = x + 3
+		layout.nameInside = true
+	case x+width+3+nameWidth <= tl.right:
+		layout.nameX = x + width + 3
+		layout.right = layout.nameX + nameWidth
+	default:
+		layout.nameX = x - 3 - nameWidth
+		layout.left = layout.nameX
+	}
+	layout.right += 6
+	return layout
+}
+
+// newLabel returns an unwrapped paragraph with the text in the label font.
+func newLabel(text string, color creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(fontSize)
+	p.SetColor(color)
+	p.SetEnableWrap(false)
+	return p
+}
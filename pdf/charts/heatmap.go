@@ -0,0 +1,334 @@
+/*
+ * Draw a heat map of a matrix of values with the creator's graphics: a grid of cells colored by their values, with the
+ * row and column labels, and a legend showing the color scale.
+ *
+ * The values are normalized to the range of the color scale, from the smallest value (first color of the scale) to
+ * the largest (last color), and the cell colors are interpolated between the colors of the scale.  The scales are
+ * white-red (sequential, for values from low to high), blue-white-red (diverging, for values above and below a
+ * middle) and yellow-green-blue.  The values are also printed in the cells, in white on the dark colors.
+ *
+ * Missing values (NaN in the matrix) are not part of the range, and their cells are drawn in a neutral gray with a
+ * dash, which is also shown in the legend as "No data", so that they are not read as values of the scale.
+ *
+ * The matrix is given in Go: average monthly temperatures of a number of cities, with some months missing.
+ *
+ * Run as: go run heatmap.go [-scale white-red|blue-white-red|yellow-green-blue] [-values=false] output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run heatmap.go [-scale white-red|blue-white-red|yellow-green-blue] [-values=false] " +
+	"output.pdf\n"
+
+const (
+	pageMargin   = 36.0 // Page margin on all sides.
+	titleHeight  = 40.0 // Height of the title above the chart.
+	axisHeight   = 20.0 // Height of the column labels above the grid.
+	labelWidth   = 90.0 // Width of the row labels left of the grid.
+	legendGap    = 24.0 // Space between the grid and the legend.
+	legendWidth  = 14.0 // Width of the color bar of the legend.
+	legendLabels = 60.0 // Width of the legend labels right of the color bar.
+	maxCellSize  = 48.0 // Cells are square, at most this size.
+	fontSize     = 8.0
+)
+
+var (
+	textColor    = creator.ColorRGBFrom8bit(40, 40, 40)
+	gridColor    = creator.ColorRGBFrom8bit(255, 255, 255)
+	missingColor = creator.ColorRGBFrom8bit(200, 200, 200)
+)
+
+// The color scales, as the colors at evenly spaced points from the smallest to the largest value.
+var colorScales = map[string][][3]float64{
+	"white-red":         {{255, 255, 255}, {252, 187, 161}, {239, 59, 44}, {103, 0, 13}},
+	"blue-white-red":    {{33, 102, 172}, {146, 197, 222}, {247, 247, 247}, {244, 165, 130}, {178, 24, 43}},
+	"yellow-green-blue": {{255, 255, 204}, {161, 218, 180}, {65, 182, 196}, {44, 127, 184}, {37, 52, 148}},
+}
+
+// heatmap is a matrix of values with the labels of its rows and columns.  Missing values are NaN.
+type heatmap struct {
+	Title   string
+	Rows    []string
+	Columns []string
+	Values  [][]float64
+}
+
+var nan = math.NaN()
+
+var temperatures = heatmap{
+	Title:   "Average monthly temperatures (degrees Celsius)",
+	Rows:    []string{"Reykjavik", "Oslo", "Moscow", "London", "Berlin", "Madrid", "Cairo", "Singapore", "Sydney"},
+	Columns: []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	Values: [][]float64{
+		{-0.5, 0.4, 0.5, 2.9, 6.3, 9.0, 10.6, 10.3, 7.4, 4.4, 1.1, -0.2},
+		{-4.3, -4.0, -0.2, 4.5, 10.8, 15.2, 16.4, 15.2, 10.8, 6.3, 0.7, -3.1},
+		{-6.5, -6.7, -1.0, 6.7, 13.2, 17.0, 19.2, 17.0, 11.3, 5.6, -1.2, -5.2},
+		{5.2, 5.3, 7.6, 9.9, 13.3, 16.5, 18.7, 18.5, 15.7, 12.0, 8.0, 5.5},
+		{0.6, 2.3, 5.1, 10.2, 14.8, 17.9, nan, nan, 14.9, 9.7, 4.7, 1.2},
+		{6.3, 7.9, 11.2, 12.9, 16.7, 22.2, 25.6, 25.1, 20.9, 15.1, 9.9, 6.9},
+		{14.0, 15.1, 17.6, 21.4, 25.0, 27.3, 28.0, 28.0, 26.3, 23.4, 19.3, 15.5},
+		{26.5, 27.1, 27.5, 28.0, 28.3, 28.3, 27.9, 27.9, 27.6, nan, 26.9, 26.4},
+		{23.5, 23.4, 22.1, 19.5, 16.6, 14.2, 13.4, 14.5, 17.0, 18.9, 20.4, 22.2},
+	},
+}
+
+func main() {
+	scale := ""
+	values := true
+	flag.StringVar(&scale, "scale", "white-red", "Color scale: white-red, blue-white-red or yellow-green-blue")
+	flag.BoolVar(&values, "values", true, "Print the values in the cells")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	stops, ok := colorScales[scale]
+	if !ok {
+		fmt.Printf("Error: Unknown color scale %q\n", scale)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := drawHeatmap(temperatures, stops, values, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func drawHeatmap(h heatmap, stops [][3]float64, withValues bool, outputPath string) error {
+	if len(h.Rows) == 0 || len(h.Columns) == 0 || len(h.Values) != len(h.Rows) {
+		return errors.New("The matrix must have a row of values per row label")
+	}
+
+	// The range of the values, without the missing ones.
+	min, max := math.Inf(1), math.Inf(-1)
+	missing := 0
+	for i, row := range h.Values {
+		if len(row) != len(h.Columns) {
+			return fmt.Errorf("Row %q has %d values for %d columns", h.Rows[i], len(row), len(h.Columns))
+		}
+		for _, v := range row {
+			if math.IsNaN(v) {
+				missing++
+				continue
+			}
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+	}
+	if missing == len(h.Rows)*len(h.Columns) {
+		return errors.New("All values are missing")
+	}
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSize{creator.PageSizeA4[1], creator.PageSizeA4[0]})
+	c.NewPage()
+
+	p := creator.NewParagraph(h.Title)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(14)
+	p.SetColor(textColor)
+	p.SetPos(pageMargin, pageMargin)
+	err := c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	// Square cells, as large as fit on the page next to the legend.
+	top := pageMargin + titleHeight + axisHeight
+	left := pageMargin + labelWidth
+	availWidth := c.Width() - pageMargin - left - legendGap - legendWidth - legendLabels
+	availHeight := c.Height() - pageMargin - top
+	cell := math.Min(availWidth/float64(len(h.Columns)), availHeight/float64(len(h.Rows)))
+	cell = math.Min(cell, maxCellSize)
+	gridWidth := cell * float64(len(h.Columns))
+	gridHeight := cell * float64(len(h.Rows))
+
+	for j, label := range h.Columns {
+		p := newLabel(label, textColor)
+		p.SetPos(left+float64(j)*cell+(cell-p.Width())/2, top-fontSize-6)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, label := range h.Rows {
+		y := top + float64(i)*cell
+		p := newLabel(label, textColor)
+		p.SetPos(left-6-p.Width(), y+(cell-fontSize)/2)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+
+		for j, v := range h.Values[i] {
+			x := left + float64(j)*cell
+			color := missingColor
+			text := "-"
+			if !math.IsNaN(v) {
+				rgb := scaleColor(stops, normalize(v, min, max))
+				color = creator.ColorRGBFrom8bit(byte(rgb[0]), byte(rgb[1]), byte(rgb[2]))
+				text = fmt.Sprintf("%.1f", v)
+			}
+
+			rect := creator.NewRectangle(x, y, cell, cell)
+			rect.SetFillColor(color)
+			rect.SetBorderColor(gridColor)
+			rect.SetBorderWidth(1)
+			err := c.Draw(rect)
+			if err != nil {
+				return err
+			}
+
+			if !withValues && !math.IsNaN(v) {
+				continue
+			}
+			labelColor := textColor
+			if !math.IsNaN(v) && isDark(scaleColor(stops, normalize(v, min, max))) {
+				labelColor = creator.ColorRGBFrom8bit(255, 255, 255)
+			}
+			p := newLabel(text, labelColor)
+			p.SetPos(x+(cell-p.Width())/2, y+(cell-fontSize)/2)
+			err = c.Draw(p)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = drawLegend(c, stops, min, max, left+gridWidth+legendGap, top, gridHeight, missing > 0)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d x %d cells, values %.1f to %.1f, %d missing\n", len(h.Rows), len(h.Columns), min, max, missing)
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawLegend draws the color scale as a vertical bar at x, top, with the largest value at the top, and labels for
+// the range of the values.  The "No data" color is shown below it if there are missing values.
+func drawLegend(c *creator.Creator, stops [][3]float64, min, max, x, top, height float64, withMissing bool) error {
+	barHeight := height
+	if withMissing {
+		barHeight -= 2 * legendWidth
+	}
+
+	// The bar is drawn as thin slices, overlapping slightly to leave no gaps between them.
+	const slices = 100
+	sliceHeight := barHeight / slices
+	for i := 0; i < slices; i++ {
+		rgb := scaleColor(stops, 1-(float64(i)+0.5)/slices)
+		rect := creator.NewRectangle(x, top+float64(i)*sliceHeight, legendWidth, sliceHeight+0.5)
+		rect.SetFillColor(creator.ColorRGBFrom8bit(byte(rgb[0]), byte(rgb[1]), byte(rgb[2])))
+		rect.SetBorderWidth(0)
+		err := c.Draw(rect)
+		if err != nil {
+			return err
+		}
+	}
+	frame := creator.NewRectangle(x, top, legendWidth, barHeight)
+	frame.SetBorderColor(textColor)
+	frame.SetBorderWidth(0.5)
+	err := c.Draw(frame)
+	if err != nil {
+		return err
+	}
+
+	// Ticks with the values at the ends and quarters of the range.
+	for i := 0; i <= 4; i++ {
+		y := top + barHeight*float64(i)/4
+		tick := creator.NewLine(x+legendWidth, y, x+legendWidth+3, y)
+		tick.SetLineWidth(0.5)
+		tick.SetColor(textColor)
+		err := c.Draw(tick)
+		if err != nil {
+			return err
+		}
+		p := newLabel(fmt.Sprintf("%.1f", max-(max-min)*float64(i)/4), textColor)
+		p.SetPos(x+legendWidth+6, y-fontSize/2)
+		err = c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !withMissing {
+		return nil
+	}
+	y := top + height - legendWidth
+	rect := creator.NewRectangle(x, y, legendWidth, legendWidth)
+	rect.SetFillColor(missingColor)
+	rect.SetBorderColor(textColor)
+	rect.SetBorderWidth(0.5)
+	err = c.Draw(rect)
+	if err != nil {
+		return err
+	}
+	p := newLabel("No data", textColor)
+	p.SetPos(x+legendWidth+6, y+(legendWidth-fontSize)/2)
+	return c.Draw(p)
+}
+
+// normalize maps v from the range min to max to 0 to 1.  All values map to the middle of the scale if the range is
+// empty.
+func normalize(v, min, max float64) float64 {
+	if max <= min {
+		return 0.5
+	}
+	return (v - min) / (max - min)
+}
+
+// scaleColor returns the color at t (0 to 1) of the color scale, interpolated between the two closest colors.
+func scaleColor(stops [][3]float64, t float64) [3]float64 {
+	t = math.Max(0, math.Min(1, t))
+	pos := t * float64(len(stops)-1)
+	i := int(math.Min(math.Floor(pos), float64(len(stops)-2)))
+	f := pos - float64(i)
+
+	var rgb [3]float64
+	for k := range rgb {
+		rgb[k] = math.Round(stops[i][k] + (stops[i+1][k]-stops[i][k])*f)
+	}
+	return rgb
+}
+
+// isDark returns true if black text would be hard to read on the color, by its perceived lightness.
+func isDark(rgb [3]float64) bool {
+	return 0.299*rgb[0]+0.587*rgb[1]+0.114*rgb[2] < 128
+}
+
+// newLabel returns an unwrapped paragraph with the text in the label font.
+func newLabel(text string, color creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(fontSize)
+	p.SetColor(color)
+	p.SetEnableWrap(false)
+	return p
+}
@@ -0,0 +1,371 @@
+/*
+ * Draw a radar (spider) chart with the creator's graphics: an axis per criterion, spreading from the center at equal
+ * angles, with rings for the values, and a polygon per data series connecting its values on the axes.
+ *
+ * The axes start at the top and go clockwise, so axis i of n is at the angle -90 + i*360/n degrees, and a value v is
+ * drawn at the distance v/max*radius from the center on its axis.  The rings are polygons through the axes (as on most
+ * radar charts) at even steps of the value range, labelled along the first axis.  The axis labels are aligned to the
+ * side of the chart they are on, so they stay clear of it.
+ *
+ * The series are drawn in turn with translucent fills, so that series overlapping each other remain visible, and with
+ * opaque outlines and markers on top.  The creator has no translucent shapes, so the polygons are drawn by a Drawable
+ * of this example, which makes a block from a page with the fill and outline and the graphics state for the opacity.
+ * A legend below the chart shows the color of each series.
+ *
+ * Run as: go run radar.go [-opacity 0.25] output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run radar.go [-opacity 0.25] output.pdf\n"
+
+const (
+	pageMargin   = 50.0  // Page margin on all sides.
+	titleHeight  = 40.0  // Height of the title above the chart.
+	radius       = 170.0 // Radius of the chart, from the center to the ends of the axes.
+	labelGap     = 8.0   // Space between the ends of the axes and their labels.
+	legendTop    = 60.0  // Space between the chart and the legend.
+	legendRow    = 18.0  // Height of a legend entry.
+	markerSize   = 4.0   // Diameter of the markers on the values.
+	fontSize     = 10.0
+	ringFontSize = 7.0
+)
+
+var (
+	textColor = creator.ColorRGBFrom8bit(40, 40, 40)
+	gridColor = creator.ColorRGBFrom8bit(200, 200, 200)
+)
+
+// Colors of the series, in order.
+var seriesColors = []creator.Color{
+	creator.ColorRGBFrom8bit(66, 133, 244),
+	creator.ColorRGBFrom8bit(219, 68, 55),
+	creator.ColorRGBFrom8bit(52, 168, 83),
+	creator.ColorRGBFrom8bit(234, 134, 0),
+	creator.ColorRGBFrom8bit(142, 68, 173),
+}
+
+// radarChart is a set of series of values on the axes of a chart.  The values go from 0 at the center to Max at the
+// ends of the axes, with Rings rings.
+type radarChart struct {
+	Title  string
+	Axes   []string
+	Max    float64
+	Rings  int
+	Series []series
+}
+
+// series is a data series with a value per axis.
+type series struct {
+	Name   string
+	Values []float64
+}
+
+var laptops = radarChart{
+	Title: "Laptop comparison (scores 0 - 10)",
+	Axes:  []string{"Performance", "Battery life", "Display", "Portability", "Build quality", "Keyboard", "Price"},
+	Max:   10,
+	Rings: 5,
+	Series: []series{
+		{Name: "Model A", Values: []float64{9, 5, 8, 4, 8, 7, 3}},
+		{Name: "Model B", Values: []float64{6, 9, 6, 9, 7, 6, 6}},
+		{Name: "Model C", Values: []float64{5, 6, 5, 6, 5, 8, 9}},
+	},
+}
+
+// polygon is a closed polygon with a fill, which may be translucent, and an outline.  The points are in the creator's
+// coordinates, from the top left of the page.
+type polygon struct {
+	points      [][2]float64
+	fillColor   creator.Color // No fill if nil.
+	fillOpacity float64
+	borderColor creator.Color // No outline if nil.
+	borderWidth float64
+}
+
+func main() {
+	opacity := 0.0
+	flag.Float64Var(&opacity, "opacity", 0.25, "Opacity of the fills of the series, 0 to 1")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 || opacity < 0 || opacity > 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := drawRadar(laptops, opacity, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func drawRadar(chart radarChart, opacity float64, outputPath string) error {
+	if len(chart.Axes) < 3 {
+		return errors.New("A radar chart needs at least 3 axes")
+	}
+	if chart.Max <= 0 || chart.Rings < 1 {
+		return errors.New("Invalid value range")
+	}
+	for _, s := range chart.Series {
+		if len(s.Values) != len(chart.Axes) {
+			return fmt.Errorf("Series %q has %d values for %d axes", s.Name, len(s.Values), len(chart.Axes))
+		}
+	}
+
+	c := creator.New()
+	c.NewPage()
+
+	p := creator.NewParagraph(chart.Title)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(14)
+	p.SetColor(textColor)
+	p.SetPos(pageMargin, pageMargin)
+	err := c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	// The chart is centered horizontally, with room for the axis labels above it.
+	cx := c.Width() / 2
+	cy := pageMargin + titleHeight + fontSize + labelGap + radius
+
+	// vertex returns the position of value v on axis i.
+	vertex := func(i int, v float64) [2]float64 {
+		angle := -math.Pi/2 + 2*math.Pi*float64(i)/float64(len(chart.Axes))
+		r := radius * math.Max(0, math.Min(v, chart.Max)) / chart.Max
+		// The creator's y axis points down, so the angles go clockwise.
+		return [2]float64{cx + r*math.Cos(angle), cy + r*math.Sin(angle)}
+	}
+
+	// The rings.
+	for k := 1; k <= chart.Rings; k++ {
+		value := chart.Max * float64(k) / float64(chart.Rings)
+		ring := &polygon{borderColor: gridColor, borderWidth: 0.5}
+		for i := range chart.Axes {
+			ring.points = append(ring.points, vertex(i, value))
+		}
+		err := c.Draw(ring)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The axes and their labels.
+	for i, name := range chart.Axes {
+		end := vertex(i, chart.Max)
+		line := creator.NewLine(cx, cy, end[0], end[1])
+		line.SetLineWidth(0.5)
+		line.SetColor(gridColor)
+		err := c.Draw(line)
+		if err != nil {
+			return err
+		}
+
+		p := newLabel(name, fontSize, textColor)
+		x, y := labelPosition(end[0]-cx, end[1]-cy, p.Width())
+		p.SetPos(cx+x, cy+y)
+		err = c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The fills of all series first, then the outlines and markers, so that no fill covers an outline.
+	shapes := make([]*polygon, len(chart.Series))
+	for j, s := range chart.Series {
+		shapes[j] = &polygon{fillColor: seriesColors[j%len(seriesColors)], fillOpacity: opacity}
+		for i, v := range s.Values {
+			shapes[j].points = append(shapes[j].points, vertex(i, v))
+		}
+		err := c.Draw(shapes[j])
+		if err != nil {
+			return err
+		}
+	}
+	for j, s := range chart.Series {
+		color := seriesColors[j%len(seriesColors)]
+		outline := &polygon{points: shapes[j].points, borderColor: color, borderWidth: 1.5}
+		err := c.Draw(outline)
+		if err != nil {
+			return err
+		}
+		for i := range s.Values {
+			pos := vertex(i, s.Values[i])
+			marker := creator.NewEllipse(pos[0], pos[1], markerSize, markerSize)
+			marker.SetFillColor(color)
+			marker.SetBorderColor(color)
+			err := c.Draw(marker)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// The values of the rings along the first axis, on top of the series.
+	for k := 1; k <= chart.Rings; k++ {
+		value := chart.Max * float64(k) / float64(chart.Rings)
+		p := newLabel(formatValue(value), ringFontSize, textColor)
+		pos := vertex(0, value)
+		p.SetPos(pos[0]+3, pos[1]-ringFontSize/2)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The legend, centered below the chart: a swatch with the fill and outline of each series and its name.
+	legendWidth := 0.0
+	for _, s := range chart.Series {
+		legendWidth = math.Max(legendWidth, 20+newLabel(s.Name, fontSize, textColor).Width())
+	}
+	x := cx - legendWidth/2
+	y := cy + radius + legendTop
+	for j, s := range chart.Series {
+		color := seriesColors[j%len(seriesColors)]
+		swatch := &polygon{
+			points:      [][2]float64{{x, y}, {x + 12, y}, {x + 12, y + 10}, {x, y + 10}},
+			fillColor:   color,
+			fillOpacity: opacity,
+			borderColor: color,
+			borderWidth: 1.5,
+		}
+		err := c.Draw(swatch)
+		if err != nil {
+			return err
+		}
+		p := newLabel(s.Name, fontSize, textColor)
+		p.SetPos(x+20, y)
+		err = c.Draw(p)
+		if err != nil {
+			return err
+		}
+		y += legendRow
+	}
+
+	fmt.Printf("%d series on %d axes\n", len(chart.Series), len(chart.Axes))
+
+	return c.WriteToFile(outputPath)
+}
+
+// labelPosition returns the position of a label of the given width relative to the center, for an axis ending at
+// dx, dy from the center.  Labels of axes pointing left or right are aligned to the end of the axis on that side,
+// labels of axes pointing up or down are centered on it, above or below.
+func labelPosition(dx, dy, width float64) (float64, float64) {
+	const straight = 0.1 // Axes within this fraction of the radius of vertical count as vertical.
+	x := dx - width/2
+	switch {
+	case dx > straight*radius:
+		x = dx + labelGap
+	case dx < -straight*radius:
+		x = dx - labelGap - width
+	}
+	y := dy - fontSize/2
+	switch {
+	case dy < -(1-straight)*radius:
+		y = dy - labelGap - fontSize
+	case dy > (1-straight)*radius:
+		y = dy + labelGap
+	}
+	return x, y
+}
+
+// GeneratePageBlocks draws the polygon on a block of the size of the page.  Implements the creator's Drawable
+// interface.
+func (pg *polygon) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	if len(pg.points) < 3 {
+		return nil, ctx, errors.New("A polygon needs at least 3 points")
+	}
+
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = pdf.NewPdfPageResources()
+
+	// path adds the path of the polygon, in PDF coordinates from the bottom left.
+	cc := contentstream.NewContentCreator()
+	path := func() {
+		for i, pt := range pg.points {
+			if i == 0 {
+				cc.Add_m(pt[0], ctx.PageHeight-pt[1])
+			} else {
+				cc.Add_l(pt[0], ctx.PageHeight-pt[1])
+			}
+		}
+		cc.Add_h()
+	}
+
+	if pg.fillColor != nil {
+		cc.Add_q()
+		if pg.fillOpacity < 1 {
+			gs := pdfcore.MakeDict()
+			gs.Set("Type", pdfcore.MakeName("ExtGState"))
+			gs.Set("ca", pdfcore.MakeFloat(pg.fillOpacity))
+			err := page.AddExtGState("GS0", gs)
+			if err != nil {
+				return nil, ctx, err
+			}
+			cc.Add_gs("GS0")
+		}
+		cc.Add_rg(pg.fillColor.ToRGB())
+		path()
+		cc.Add_f()
+		cc.Add_Q()
+	}
+	if pg.borderColor != nil && pg.borderWidth > 0 {
+		cc.Add_q()
+		cc.Add_RG(pg.borderColor.ToRGB())
+		cc.Add_w(pg.borderWidth)
+		path()
+		cc.Add_S()
+		cc.Add_Q()
+	}
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	block.SetPos(0, 0)
+	return block.GeneratePageBlocks(ctx)
+}
+
+// formatValue formats a value without trailing zeros.
+func formatValue(v float64) string {
+	return fmt.Sprintf("%g", math.Round(v*100)/100)
+}
+
+// newLabel returns an unwrapped paragraph with the text in the label font.
+func newLabel(text string, size float64, color creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(size)
+	p.SetColor(color)
+	p.SetEnableWrap(false)
+	return p
+}
@@ -0,0 +1,285 @@
+/*
+ * Render a table with a sparkline in each row: a tiny line chart of the series of values of the row, drawn in a table
+ * cell next to its figures (minimum, maximum, last value and change).
+ *
+ * The creator's table cells hold paragraphs, images and divisions, so each sparkline is drawn as a small image,
+ * sized to fill the cell less a padding, at sparklineDPI so that it stays sharp when printed.  The cell size is known
+ * from the page width, the page margins and the column widths.  The line is anti-aliased by drawing each pixel with
+ * the coverage of the line, from its distance to the line.
+ *
+ * Each sparkline is scaled to its own series: the values from the minimum to the maximum of the series span the
+ * height, and the points span the width, however many there are.  So series of different lengths all fill their cell,
+ * and the number of days is shown in a column of its own.  A flat (constant) series has no range, and is drawn as a
+ * line through the middle.  The last value is marked with a dot, and the (first) minimum and maximum with smaller
+ * dots, except for flat series.  A series with a single value is drawn as a dot, and an empty series as "no data".
+ *
+ * Run as: go run sparklines.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// Table styling.
+const (
+	pageMargin   = 50.0
+	rowHeight    = 24.0
+	headerHeight = 22.0
+	cellPadding  = 3.0   // Space around the sparkline in its cell.
+	sparklineDPI = 300.0 // Resolution of the sparkline images.
+	lineWidth    = 0.8   // Width of the sparkline, in points.
+	dotSize      = 2.4   // Diameter of the dot on the last value, in points.
+)
+
+var (
+	headerColor  = creator.ColorRGBFrom8bit(44, 62, 80)
+	borderColor  = creator.ColorRGBFrom8bit(180, 180, 180)
+	columnWidths = []float64{0.22, 0.3, 0.08, 0.1, 0.1, 0.1, 0.1}
+	rightAligned = []bool{false, false, true, true, true, true, true}
+
+	lineColor = color.RGBA{70, 90, 110, 255}
+	lastColor = color.RGBA{219, 68, 55, 255}
+	minColor  = color.RGBA{66, 133, 244, 255}
+	maxColor  = color.RGBA{52, 168, 83, 255}
+)
+
+// metric is a named series of daily values.
+type metric struct {
+	Name   string
+	Values []float64
+}
+
+var metrics = []metric{
+	{Name: "Website visits (k)", Values: []float64{12.1, 13.4, 12.8, 14.2, 15.1, 9.8, 8.7, 13.9, 14.6, 15.3, 15.8,
+		16.4, 10.2, 9.1, 15.2, 16.8, 17.1, 17.5, 18.2, 11.3, 10.4, 17.9, 18.6, 19.2, 19.8, 20.3, 12.6, 11.8}},
+	{Name: "Sign-ups", Values: []float64{210, 225, 198, 240, 262, 150, 131, 244, 251, 270, 288, 301, 176, 160}},
+	{Name: "Conversion rate (%)", Values: []float64{2.4, 2.3, 2.5, 2.2, 2.1, 2.0, 2.1, 1.9, 1.8, 1.9, 1.7, 1.8, 1.6,
+		1.7, 1.5, 1.6, 1.4, 1.5, 1.5, 1.3, 1.4}},
+	{Name: "Average order (EUR)", Values: []float64{48.2, 51.7, 47.9, 55.3, 49.8, 62.4, 58.1, 45.2, 50.6, 53.9}},
+	{Name: "Support tickets", Values: []float64{34, 29, 41, 38, 33, 12, 9, 45, 52, 61, 40, 36, 14, 11, 39, 35}},
+	{Name: "Servers online", Values: []float64{24, 24, 24, 24, 24, 24, 24, 24, 24, 24, 24, 24, 24, 24}},
+	{Name: "Uptime (%)", Values: []float64{100, 100, 99.8, 100, 100, 100, 97.2, 100, 100, 99.9, 100, 100}},
+	{Name: "New region (k visits)", Values: []float64{0.4, 1.1, 1.9}},
+	{Name: "Partner referrals", Values: []float64{17}},
+	{Name: "App downloads", Values: []float64{}},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run sparklines.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := renderSparklineTable(metrics, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func renderSparklineTable(metrics []metric, outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(pageMargin, pageMargin, pageMargin, pageMargin)
+	c.NewPage()
+
+	heading := creator.NewParagraph("Key metrics, last 4 weeks")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 10)
+	err := c.Draw(heading)
+	if err != nil {
+		return err
+	}
+
+	// The size of the sparklines: the cells of the trend column, less the padding.
+	tableWidth := c.Width() - 2*pageMargin
+	width := tableWidth*columnWidths[1] - 2*cellPadding
+	height := rowHeight - 2*cellPadding
+
+	table := creator.NewTable(len(columnWidths))
+	table.SetColumnWidths(columnWidths...)
+	addRow(table, []string{"Metric", "Trend", "Days", "Min", "Max", "Last", "Change"}, nil, true)
+
+	for _, m := range metrics {
+		values := []string{m.Name, "", fmt.Sprintf("%d", len(m.Values)), "", "", "", ""}
+		if len(m.Values) == 0 {
+			values[1] = "no data"
+			addRow(table, values, nil, false)
+			continue
+		}
+
+		min, max := seriesRange(m.Values)
+		first, last := m.Values[0], m.Values[len(m.Values)-1]
+		values[3] = formatValue(min)
+		values[4] = formatValue(max)
+		values[5] = formatValue(last)
+		if len(m.Values) > 1 && first != 0 {
+			values[6] = fmt.Sprintf("%+.1f%%", (last-first)/math.Abs(first)*100)
+		}
+
+		img, err := creator.NewImageFromGoImage(drawSparkline(m.Values, width, height))
+		if err != nil {
+			return err
+		}
+		img.SetWidth(width)
+		img.SetHeight(height)
+		addRow(table, values, img, false)
+	}
+
+	err = c.Draw(table)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Adds a row of cells with the values, or the header row.  The sparkline image, if any, is placed in the second cell.
+func addRow(table *creator.Table, values []string, sparkline *creator.Image, header bool) {
+	for col, text := range values {
+		cell := table.NewCell()
+		cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+		cell.SetBorderColor(borderColor)
+		cell.SetVerticalAlignment(creator.CellVerticalAlignmentMiddle)
+		if rightAligned[col] {
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+		}
+
+		if col == 1 && sparkline != nil {
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentCenter)
+			cell.SetContent(sparkline)
+			continue
+		}
+
+		p := creator.NewParagraph(text)
+		p.SetFontSize(10)
+		if header {
+			p.SetFont(fonts.NewFontHelveticaBold())
+			p.SetColor(creator.ColorWhite)
+			cell.SetBackgroundColor(headerColor)
+			cell.SetBorderColor(headerColor)
+		} else {
+			p.SetFont(fonts.NewFontHelvetica())
+		}
+		if text == "no data" {
+			p.SetColor(creator.ColorRGBFrom8bit(150, 150, 150))
+		}
+		cell.SetContent(p)
+	}
+	if header {
+		table.SetRowHeight(table.CurRow(), headerHeight)
+	} else {
+		table.SetRowHeight(table.CurRow(), rowHeight)
+	}
+}
+
+// Returns the sparkline of the values as an image of width x height points at sparklineDPI.  The values span the
+// image, within a margin for the line and the dots.
+func drawSparkline(values []float64, width, height float64) image.Image {
+	scale := sparklineDPI / 72
+	w, h := int(math.Round(width*scale)), int(math.Round(height*scale))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+
+	margin := dotSize / 2 * scale
+	min, max := seriesRange(values)
+	points := make([][2]float64, len(values))
+	for i, v := range values {
+		x := float64(w) / 2
+		if len(values) > 1 {
+			x = margin + float64(i)*(float64(w)-2*margin)/float64(len(values)-1)
+		}
+		// A flat series has no range, and is drawn in the middle.
+		y := float64(h) / 2
+		if max > min {
+			y = margin + (max-v)/(max-min)*(float64(h)-2*margin)
+		}
+		points[i] = [2]float64{x, y}
+	}
+
+	for i := 1; i < len(points); i++ {
+		drawSegment(img, points[i-1], points[i], lineWidth*scale/2, lineColor)
+	}
+	if max > min {
+		// The first minimum and maximum, if the value is repeated.
+		minIdx, maxIdx := -1, -1
+		for i, v := range values {
+			if v == min && minIdx < 0 {
+				minIdx = i
+			}
+			if v == max && maxIdx < 0 {
+				maxIdx = i
+			}
+		}
+		drawSegment(img, points[minIdx], points[minIdx], dotSize*0.35*scale, minColor)
+		drawSegment(img, points[maxIdx], points[maxIdx], dotSize*0.35*scale, maxColor)
+	}
+	drawSegment(img, points[len(points)-1], points[len(points)-1], dotSize/2*scale, lastColor)
+	return img
+}
+
+// Draws the segment from a to b with round ends, as the pixels within r of it, blended with the background by the
+// part of the pixel covered.  A segment from a point to itself is a dot.
+func drawSegment(img *image.RGBA, a, b [2]float64, r float64, col color.RGBA) {
+	x0 := int(math.Floor(math.Min(a[0], b[0]) - r - 1))
+	x1 := int(math.Ceil(math.Max(a[0], b[0]) + r + 1))
+	y0 := int(math.Floor(math.Min(a[1], b[1]) - r - 1))
+	y1 := int(math.Ceil(math.Max(a[1], b[1]) + r + 1))
+
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	lengthSq := dx*dx + dy*dy
+	for y := y0; y <= y1; y++ {
+		for x := x0; x <= x1; x++ {
+			if !(image.Point{x, y}.In(img.Bounds())) {
+				continue
+			}
+			// The distance from the pixel center to the nearest point of the segment.
+			px, py := float64(x)+0.5, float64(y)+0.5
+			t := 0.0
+			if lengthSq > 0 {
+				t = math.Max(0, math.Min(1, ((px-a[0])*dx+(py-a[1])*dy)/lengthSq))
+			}
+			d := math.Hypot(px-(a[0]+t*dx), py-(a[1]+t*dy))
+			coverage := math.Max(0, math.Min(1, r+0.5-d))
+			if coverage == 0 {
+				continue
+			}
+
+			bg := img.RGBAAt(x, y)
+			blend := func(c, b uint8) uint8 {
+				return uint8(math.Round(float64(c)*coverage + float64(b)*(1-coverage)))
+			}
+			img.SetRGBA(x, y, color.RGBA{blend(col.R, bg.R), blend(col.G, bg.G), blend(col.B, bg.B), 255})
+		}
+	}
+}
+
+// Returns the smallest and largest of the values.
+func seriesRange(values []float64) (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	return min, max
+}
+
+// Formats a value with up to 2 decimals, without trailing zeros.
+func formatValue(v float64) string {
+	return fmt.Sprintf("%g", math.Round(v*100)/100)
+}
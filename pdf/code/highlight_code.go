@@ -0,0 +1,413 @@
+/*
+ * Render a Go source file to PDF with syntax highlighting and line numbers.
+ *
+ * The source is split into tokens by a simple tokenizer, which recognizes line and block comments, interpreted, raw
+ * and rune literals, numbers, keywords and predeclared identifiers, and the tokens are colored by their kind.  Block
+ * comments and raw strings can span several lines.  The tokenizer does not parse the source, so it also works for
+ * incomplete or invalid code.
+ *
+ * The text is set in a monospace TrueType font, which is embedded in the PDF.  As the font is monospace, every
+ * character is placed on a grid of columns: tabs are expanded to the next tab stop, and lines longer than the page
+ * width are wrapped at the last column, with a "»" in place of the line number marking the continued lines.
+ * Characters that are not in the WinAnsi encoding of the font are replaced by "?".
+ *
+ * A monospace font is not included in this repository; e.g. Go Mono (Go-Mono.ttf from golang.org/x/image/font/gofont)
+ * or DejaVu Sans Mono can be used.
+ *
+ * Run as: go run highlight_code.go -font mono.ttf [-size 9] [-tab 4] input.go output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+const usage = "Usage: go run highlight_code.go -font mono.ttf [-size 9] [-tab 4] input.go output.pdf\n"
+
+const (
+	pageMargin  = 50.0 // Left and right margins.
+	topMargin   = 60.0 // Top margin, with the file name in the header above it.
+	lineSpacing = 1.3  // Line height relative to the font size.
+	gutterGap   = 10.0 // Space between the line numbers and the code.
+)
+
+// tokenKind is the kind of a source token, which determines its color.
+type tokenKind int
+
+const (
+	tokenPlain tokenKind = iota
+	tokenKeyword
+	tokenPredeclared
+	tokenString
+	tokenNumber
+	tokenComment
+)
+
+var tokenColors = map[tokenKind]creator.Color{
+	tokenPlain:       creator.ColorRGBFrom8bit(30, 30, 30),
+	tokenKeyword:     creator.ColorRGBFrom8bit(0, 51, 179),
+	tokenPredeclared: creator.ColorRGBFrom8bit(128, 0, 128),
+	tokenString:      creator.ColorRGBFrom8bit(6, 125, 23),
+	tokenNumber:      creator.ColorRGBFrom8bit(23, 80, 235),
+	tokenComment:     creator.ColorRGBFrom8bit(128, 128, 128),
+}
+
+var (
+	gutterColor     = creator.ColorRGBFrom8bit(153, 153, 153)
+	gutterLineColor = creator.ColorRGBFrom8bit(220, 220, 220)
+)
+
+var goKeywords = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true, "defer": true,
+	"else": true, "fallthrough": true, "for": true, "func": true, "go": true, "goto": true, "if": true,
+	"import": true, "interface": true, "map": true, "package": true, "range": true, "return": true, "select": true,
+	"struct": true, "switch": true, "type": true, "var": true,
+}
+
+// goPredeclared are the predeclared types, constants and functions.
+var goPredeclared = map[string]bool{
+	"bool": true, "byte": true, "complex64": true, "complex128": true, "error": true, "float32": true,
+	"float64": true, "int": true, "int8": true, "int16": true, "int32": true, "int64": true, "rune": true,
+	"string": true, "uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "close": true, "complex": true, "copy": true, "delete": true, "imag": true,
+	"len": true, "make": true, "new": true, "panic": true, "print": true, "println": true, "real": true,
+	"recover": true,
+}
+
+// token is a piece of source text of one kind.  The text of a token does not contain line breaks.
+type token struct {
+	text string
+	kind tokenKind
+}
+
+func main() {
+	fontPath := ""
+	fontSize := 0.0
+	tabWidth := 0
+	flag.StringVar(&fontPath, "font", "", "Monospace TrueType font for the code (required)")
+	flag.Float64Var(&fontSize, "size", 9, "Font size")
+	flag.IntVar(&tabWidth, "tab", 4, "Tab width in columns")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 || fontPath == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if fontSize <= 0 || tabWidth < 1 {
+		fmt.Printf("Error: -size and -tab must be positive\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := highlightCode(inputPath, outputPath, fontPath, fontSize, tabWidth)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func highlightCode(inputPath, outputPath, fontPath string, fontSize float64, tabWidth int) error {
+	source, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	ttf, err := fonts.TtfParse(fontPath)
+	if err != nil {
+		return err
+	}
+	if !ttf.IsFixedPitch {
+		return fmt.Errorf("Font %s is not monospace", ttf.PostScriptName)
+	}
+	font, err := pdf.NewPdfFontFromTTFFile(fontPath)
+	if err != nil {
+		return err
+	}
+	metrics, found := font.GetGlyphCharMetrics("space")
+	if !found {
+		return errors.New("Font has no space glyph")
+	}
+	charWidth := metrics.Wx * fontSize / 1000
+
+	text := strings.Replace(string(source), "\r\n", "\n", -1)
+	text = strings.TrimRight(text, "\n")
+	lines := splitLines(tokenizeGo(replaceUnsupported(expandTabs(text, tabWidth))))
+
+	c := creator.New()
+	pageWidth, pageHeight := c.Width(), c.Height()
+	lineHeight := fontSize * lineSpacing
+
+	// The gutter fits the largest line number.
+	gutterWidth := float64(len(strconv.Itoa(len(lines))))*charWidth + gutterGap
+	columns := int((pageWidth - 2*pageMargin - gutterWidth) / charWidth)
+	if columns < 1 {
+		return errors.New("Font too large for the page width")
+	}
+	rowsPerPage := int((pageHeight - topMargin - pageMargin) / lineHeight)
+
+	row := rowsPerPage
+	numRows := 0
+	for i, line := range lines {
+		for j, segment := range wrapTokens(line, columns) {
+			if row == rowsPerPage {
+				c.NewPage()
+				row = 0
+			}
+			y := topMargin + float64(row)*lineHeight + (lineHeight-fontSize)/2
+
+			// The line number, or a mark for continued lines, right aligned in the gutter.
+			number := strconv.Itoa(i + 1)
+			if j > 0 {
+				number = "»"
+			}
+			p := newCodeParagraph(number, font, fontSize, gutterColor)
+			p.SetPos(pageMargin+gutterWidth-gutterGap-float64(len([]rune(number)))*charWidth, y)
+			err := c.Draw(p)
+			if err != nil {
+				return err
+			}
+
+			col := 0
+			for _, t := range segment {
+				p := newCodeParagraph(t.text, font, fontSize, tokenColors[t.kind])
+				p.SetPos(pageMargin+gutterWidth+float64(col)*charWidth, y)
+				err := c.Draw(p)
+				if err != nil {
+					return err
+				}
+				col += len([]rune(t.text))
+			}
+			row++
+			numRows++
+		}
+	}
+
+	// The line separating the gutter from the code, and the file name in the header, on every page.
+	fileName := filepath.Base(inputPath)
+	c.DrawHeader(func(block *creator.Block, args creator.HeaderFunctionArgs) {
+		x := pageMargin + gutterWidth - gutterGap/2
+		line := creator.NewLine(x, topMargin-4, x, pageHeight-pageMargin+4)
+		line.SetLineWidth(0.5)
+		line.SetColor(gutterLineColor)
+		_ = block.Draw(line)
+
+		p := creator.NewParagraph(fmt.Sprintf("%s - page %d of %d", fileName, args.PageNum, args.TotalPages))
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(9)
+		p.SetColor(gutterColor)
+		p.SetPos(pageMargin, 30)
+		_ = block.Draw(p)
+	})
+
+	fmt.Printf("%d lines in %d rows of %d columns\n", len(lines), numRows, columns)
+
+	return c.WriteToFile(outputPath)
+}
+
+// newCodeParagraph returns an unwrapped paragraph with text in the code font.
+func newCodeParagraph(text string, font *pdf.PdfFont, fontSize float64, color creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetColor(color)
+	p.SetEnableWrap(false)
+	return p
+}
+
+// expandTabs replaces the tabs in each line of text with spaces up to the next multiple of tabWidth columns.
+func expandTabs(text string, tabWidth int) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		expanded := []rune{}
+		for _, r := range line {
+			if r == '\t' {
+				for n := tabWidth - len(expanded)%tabWidth; n > 0; n-- {
+					expanded = append(expanded, ' ')
+				}
+				continue
+			}
+			expanded = append(expanded, r)
+		}
+		lines[i] = string(expanded)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replaceUnsupported replaces the characters that cannot be encoded in WinAnsi, which the TrueType font is used
+// with, by "?".
+func replaceUnsupported(text string) string {
+	encoder := textencoding.NewWinAnsiTextEncoder()
+	return strings.Map(func(r rune) rune {
+		if r == '\n' {
+			return r
+		}
+		if _, ok := encoder.RuneToCharcode(r); !ok || unicode.IsControl(r) {
+			return '?'
+		}
+		return r
+	}, text)
+}
+
+// tokenizeGo splits Go source text into tokens.  Line breaks are tokens of their own, so that tokens spanning lines
+// (block comments and raw strings) are split at the line breaks.
+func tokenizeGo(text string) []token {
+	tokens := []token{}
+	add := func(s string, kind tokenKind) {
+		for i, part := range strings.Split(s, "\n") {
+			if i > 0 {
+				tokens = append(tokens, token{text: "\n"})
+			}
+			if part != "" {
+				tokens = append(tokens, token{text: part, kind: kind})
+			}
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		start := i
+		r := runes[i]
+		switch {
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			add(string(runes[start:i]), tokenComment)
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i = minInt(i+2, len(runes))
+			add(string(runes[start:i]), tokenComment)
+		case r == '`':
+			i++
+			for i < len(runes) && runes[i] != '`' {
+				i++
+			}
+			i = minInt(i+1, len(runes))
+			add(string(runes[start:i]), tokenString)
+		case r == '"' || r == '\'':
+			// Interpreted strings and runes end at the closing quote or, if unterminated, at the end of the line.
+			i++
+			for i < len(runes) && runes[i] != r && runes[i] != '\n' {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] != '\n' {
+					i++
+				}
+				i++
+			}
+			if i < len(runes) && runes[i] == r {
+				i++
+			}
+			add(string(runes[start:i]), tokenString)
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			// Decimal, hexadecimal, octal and floating point numbers, with exponents: e in decimal numbers and p
+			// in hexadecimal ones, where e is a digit.
+			hex := i+1 < len(runes) && r == '0' && (runes[i+1] == 'x' || runes[i+1] == 'X')
+			exponents := "eE"
+			if hex {
+				exponents = "pP"
+			}
+			for i < len(runes) {
+				c := runes[i]
+				sign := (c == '+' || c == '-') && strings.ContainsRune(exponents, runes[i-1])
+				if !sign && c != '.' && c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+					break
+				}
+				i++
+			}
+			add(string(runes[start:i]), tokenNumber)
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			word := string(runes[start:i])
+			kind := tokenPlain
+			if goKeywords[word] {
+				kind = tokenKeyword
+			} else if goPredeclared[word] && (start == 0 || runes[start-1] != '.') {
+				// Selectors such as x.len are not the predeclared identifiers.
+				kind = tokenPredeclared
+			}
+			add(word, kind)
+		default:
+			// Operators, punctuation and white space, up to the start of the next token.
+			i++
+			for i < len(runes) && runes[i] != '\n' && !strings.ContainsRune("/`\"'_", runes[i]) &&
+				!unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+				i++
+			}
+			add(string(runes[start:i]), tokenPlain)
+		}
+	}
+	return tokens
+}
+
+// splitLines groups the tokens into lines at the line break tokens.
+func splitLines(tokens []token) [][]token {
+	lines := [][]token{{}}
+	for _, t := range tokens {
+		if t.text == "\n" {
+			lines = append(lines, []token{})
+			continue
+		}
+		lines[len(lines)-1] = append(lines[len(lines)-1], t)
+	}
+	return lines
+}
+
+// wrapTokens splits a line into segments of at most columns characters, splitting tokens at the segment ends.  An
+// empty line is a single empty segment.
+func wrapTokens(line []token, columns int) [][]token {
+	segments := [][]token{{}}
+	col := 0
+	for _, t := range line {
+		runes := []rune(t.text)
+		for len(runes) > 0 {
+			if col == columns {
+				segments = append(segments, []token{})
+				col = 0
+			}
+			n := minInt(len(runes), columns-col)
+			last := len(segments) - 1
+			segments[last] = append(segments[last], token{text: string(runes[:n]), kind: t.kind})
+			runes = runes[n:]
+			col += n
+		}
+	}
+	return segments
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
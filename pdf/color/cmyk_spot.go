@@ -0,0 +1,511 @@
+/*
+ * Draw color swatches in process (CMYK) colors and in a named spot color for print production, with ICC profiles
+ * embedded to define what the colors look like.
+ *
+ * The page has three sections:
+ * - Process colors: CMYK swatches, each labeled with its CMYK values.  Without an ICC profile they are in the
+ *   DeviceCMYK color space, set with the k operator, and the printer prints the values as given.  With a CMYK
+ *   profile (-icc), they are in an ICCBased color space with the profile embedded, so that a color managed viewer
+ *   or printer knows which CMYK the values refer to (e.g. coated or uncoated paper).
+ * - Spot color: tints of a named colorant (-spot), in a Separation color space.  A printer with a plate or ink of
+ *   that name prints it directly.  Everything else (screen viewers, office printers) shows the alternate color
+ *   instead: the CMYK values of the spot color, scaled by the tint with the tint transform function.
+ * - RGB colors converted to CMYK: RGB colors, in an ICCBased color space with an sRGB profile, next to the CMYK
+ *   values they convert to.
+ *
+ * Caveats:
+ * - The RGB to CMYK conversion here is the naive formula (K = 1 - max(R, G, B), and C, M, Y from the rest).  It is
+ *   not color managed: real CMYK depends on the inks, paper and press, and a print shop converts with the ICC
+ *   profile of the output condition, with a limit on the total ink.  Use it for previews and defaults only.
+ * - Bright RGB colors, such as the violet and the green here, are outside the gamut of CMYK inks and print duller
+ *   than they appear on screen.  The naive formulas convert back to the original RGB exactly, so the converted
+ *   swatches look the same on screen; it is the print that differs.
+ * - How the spot color looks on screen depends only on the alternate CMYK values; check the printed color against
+ *   the ink swatch book.
+ * - Overprint is not set, so a spot color drawn over other colors knocks them out.  Trapping and overprint are
+ *   usually set up in prepress.
+ * - The labels are drawn by the creator in DeviceRGB, which the print shop converts to CMYK.
+ *
+ * The built-in sRGB profile is the minimal profile from pdf/pdfa/pdfa_convert.go.  With -icc, the profile header
+ * tells if it is a CMYK or an RGB profile, and it replaces the DeviceCMYK or the built-in sRGB profile.
+ *
+ * Run as: go run cmyk_spot.go [-icc profile.icc] [-spot "Brand Red"] output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run cmyk_spot.go [-icc profile.icc] [-spot \"Brand Red\"] output.pdf\n"
+
+// Page layout (points).
+const (
+	margin        = 50.0
+	swatchGap     = 12.0
+	swatchHeight  = 50.0
+	labelSpace    = 30.0 // Space for the label below a swatch.
+	swatchColumns = 4
+)
+
+// cmyk is a CMYK color, with the components from 0 to 1.
+type cmyk [4]float64
+
+// The spot color's appearance in CMYK, used for the alternate color.
+var spotCMYK = cmyk{0, 0.85, 0.75, 0.05}
+
+var processColors = []struct {
+	Name  string
+	Color cmyk
+}{
+	{"Cyan", cmyk{1, 0, 0, 0}},
+	{"Magenta", cmyk{0, 1, 0, 0}},
+	{"Yellow", cmyk{0, 0, 1, 0}},
+	{"Black", cmyk{0, 0, 0, 1}},
+	{"Red", cmyk{0, 1, 1, 0}},
+	{"Green", cmyk{1, 0, 1, 0}},
+	{"Blue", cmyk{1, 1, 0, 0}},
+	{"Rich black", cmyk{0.6, 0.4, 0.4, 1}},
+}
+
+var spotTints = []float64{1, 0.8, 0.6, 0.4, 0.2, 0.1}
+
+var rgbColors = []struct {
+	Name    string
+	R, G, B uint8
+}{
+	{"Orange", 255, 140, 0},
+	{"Green", 0, 200, 80},
+	{"Sky blue", 70, 160, 230},
+	{"Violet", 128, 0, 255},
+}
+
+func main() {
+	iccPath := ""
+	spotName := ""
+	flag.StringVar(&iccPath, "icc", "", "ICC profile, CMYK or RGB (optional)")
+	flag.StringVar(&spotName, "spot", "Brand Red", "Name of the spot color")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if spotName == "" {
+		fmt.Printf("Error: -spot must not be empty\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := createColorPage(iccPath, spotName, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createColorPage(iccPath, spotName, outputPath string) error {
+	// The color spaces.  A nil CMYK color space stands for DeviceCMYK.
+	var cmykSpace pdf.PdfColorspace
+	cmykName := "DeviceCMYK"
+	rgbSpace, err := newICCBased(newSRGBProfile(), 3, pdf.NewPdfColorspaceDeviceRGB())
+	if err != nil {
+		return err
+	}
+	if iccPath != "" {
+		data, err := ioutil.ReadFile(iccPath)
+		if err != nil {
+			return err
+		}
+		space, err := iccColorSpace(data)
+		if err != nil {
+			return err
+		}
+		if space == "CMYK" {
+			cmykSpace, err = newICCBased(data, 4, pdf.NewPdfColorspaceDeviceCMYK())
+			cmykName = "ICCBased CMYK"
+		} else {
+			rgbSpace, err = newICCBased(data, 3, pdf.NewPdfColorspaceDeviceRGB())
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Using the %s profile %s\n", space, iccPath)
+	}
+	spotSpace := newSeparation(spotName, spotCMYK)
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+	c.SetPageMargins(margin, margin, margin, margin)
+	c.NewPage()
+
+	y := margin
+	err = drawText(c, "CMYK and spot colors", fonts.NewFontHelveticaBold(), 20, margin, y)
+	if err != nil {
+		return err
+	}
+	y += 34
+	width := (c.Width() - 2*margin - (swatchColumns-1)*swatchGap) / swatchColumns
+
+	// Process colors.
+	y, err = drawSection(c, "Process colors ("+cmykName+")", y)
+	if err != nil {
+		return err
+	}
+	for i, pc := range processColors {
+		x, top := gridPos(i, y, width)
+		err := drawSwatch(c, x, top, width, cmykSpace, pc.Color[:])
+		if err != nil {
+			return err
+		}
+		r, g, b := cmykToRGB(pc.Color)
+		err = drawLabel(c, fmt.Sprintf("%s\n%s\nRGB approx. %s", pc.Name, formatCMYK(pc.Color), formatRGB(r, g, b)),
+			x, top+swatchHeight+3, width)
+		if err != nil {
+			return err
+		}
+	}
+	y += gridHeight(len(processColors))
+
+	// Spot color tints, 6 in a row.
+	y, err = drawSection(c, "Spot color \""+spotName+"\" (Separation, alternate "+formatCMYK(spotCMYK)+")", y)
+	if err != nil {
+		return err
+	}
+	tintWidth := (c.Width() - 2*margin - float64(len(spotTints)-1)*swatchGap/2) / float64(len(spotTints))
+	for i, tint := range spotTints {
+		x := margin + float64(i)*(tintWidth+swatchGap/2)
+		err := drawSwatch(c, x, y, tintWidth, spotSpace, []float64{tint})
+		if err != nil {
+			return err
+		}
+		alternate := cmyk{}
+		for j := range alternate {
+			alternate[j] = tint * spotCMYK[j]
+		}
+		err = drawLabel(c, fmt.Sprintf("Tint %.0f%%\nshows as\n%s", tint*100, formatCMYK(alternate)), x,
+			y+swatchHeight+3, tintWidth)
+		if err != nil {
+			return err
+		}
+	}
+	y += swatchHeight + labelSpace + swatchGap
+
+	// RGB colors and their CMYK conversion, side by side in each swatch.
+	y, err = drawSection(c, "RGB (ICCBased) converted to CMYK", y)
+	if err != nil {
+		return err
+	}
+	for i, rc := range rgbColors {
+		x, top := gridPos(i, y, width)
+		r, g, b := float64(rc.R)/255, float64(rc.G)/255, float64(rc.B)/255
+		err := drawSwatch(c, x, top, width/2, rgbSpace, []float64{r, g, b})
+		if err != nil {
+			return err
+		}
+		converted := rgbToCMYK(r, g, b)
+		err = drawSwatch(c, x+width/2, top, width/2, cmykSpace, converted[:])
+		if err != nil {
+			return err
+		}
+		r2, g2, b2 := cmykToRGB(converted)
+		err = drawLabel(c, fmt.Sprintf("%s RGB %d %d %d\nto %s\nback to RGB %s", rc.Name, rc.R, rc.G, rc.B,
+			formatCMYK(converted), formatRGB(r2, g2, b2)), x, top+swatchHeight+3, width)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-8s RGB %3d %3d %3d -> %s\n", rc.Name, rc.R, rc.G, rc.B, formatCMYK(converted))
+	}
+	y += gridHeight(len(rgbColors))
+
+	p := creator.NewParagraph("The conversion is not color managed: the printed colors depend on the inks, paper " +
+		"and press.  Bright RGB colors are out of the CMYK gamut and print duller.  Viewers show the spot color " +
+		"with its alternate CMYK values; only the printed ink shows the real color.")
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(9)
+	p.SetWidth(c.Width() - 2*margin)
+	p.SetPos(margin, y)
+	err = c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Returns the position of the swatch with the index in a grid of swatchColumns columns, starting at y.
+func gridPos(i int, y, width float64) (float64, float64) {
+	return margin + float64(i%swatchColumns)*(width+swatchGap),
+		y + float64(i/swatchColumns)*(swatchHeight+labelSpace+swatchGap)
+}
+
+// Returns the height of a grid of n swatches, with the gap below it.
+func gridHeight(n int) float64 {
+	rows := (n + swatchColumns - 1) / swatchColumns
+	return float64(rows) * (swatchHeight + labelSpace + swatchGap)
+}
+
+// Draws a section heading at y and returns the y position below it.
+func drawSection(c *creator.Creator, title string, y float64) (float64, error) {
+	err := drawText(c, title, fonts.NewFontHelveticaBold(), 12, margin, y)
+	return y + 20, err
+}
+
+func drawText(c *creator.Creator, text string, font fonts.Font, fontSize float64, x, y float64) error {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetPos(x, y)
+	return c.Draw(p)
+}
+
+func drawLabel(c *creator.Creator, text string, x, y, width float64) error {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(7)
+	p.SetWidth(width)
+	p.SetPos(x, y)
+	return c.Draw(p)
+}
+
+func drawSwatch(c *creator.Creator, x, y, width float64, cs pdf.PdfColorspace, components []float64) error {
+	return c.Draw(&swatch{x: x, y: y, width: width, height: swatchHeight, cs: cs, components: components})
+}
+
+// swatch is a rectangle filled with a color in a color space, with the upper left corner at (x, y) from the top
+// left of the page.  Implements the creator Drawable interface.
+type swatch struct {
+	x, y, width, height float64
+	cs                  pdf.PdfColorspace // nil for DeviceCMYK.
+	components          []float64
+}
+
+// GeneratePageBlocks draws the swatch on a block representing the page.
+func (s *swatch) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	resources := pdf.NewPdfPageResources()
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q()
+	if s.cs == nil {
+		if len(s.components) != 4 {
+			return nil, ctx, fmt.Errorf("DeviceCMYK needs 4 components, got %d", len(s.components))
+		}
+		cc.Add_k(s.components[0], s.components[1], s.components[2], s.components[3])
+	} else {
+		if len(s.components) != s.cs.GetNumComponents() {
+			return nil, ctx, fmt.Errorf("%s needs %d components, got %d", s.cs, s.cs.GetNumComponents(),
+				len(s.components))
+		}
+		err := resources.SetColorspaceByName("CS0", s.cs)
+		if err != nil {
+			return nil, ctx, err
+		}
+		cc.Add_cs("CS0").
+			Add_scn(s.components...)
+	}
+	// The frame is drawn in 40% black.
+	cc.Add_re(s.x, ctx.PageHeight-s.y-s.height, s.width, s.height).
+		Add_f().
+		Add_K(0, 0, 0, 0.4).
+		Add_w(0.5).
+		Add_re(s.x, ctx.PageHeight-s.y-s.height, s.width, s.height).
+		Add_S().
+		Add_Q()
+
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = resources
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+// Returns an ICCBased color space with the profile of n components, and the device color space to use instead
+// when the profile is not supported.
+func newICCBased(profile []byte, n int, alternate pdf.PdfColorspace) (*pdf.PdfColorspaceICCBased, error) {
+	cs, err := pdf.NewPdfColorspaceICCBased(n)
+	if err != nil {
+		return nil, err
+	}
+	cs.Alternate = alternate
+	cs.Data = profile
+	return cs, nil
+}
+
+// Returns a Separation color space for the colorant, with an alternate color in DeviceCMYK: the tint transform
+// scales the CMYK values of the full tint linearly from 0 (no ink) to the tint.
+func newSeparation(name string, full cmyk) *pdf.PdfColorspaceSpecialSeparation {
+	cs := pdf.NewPdfColorspaceSpecialSeparation()
+	cs.ColorantName = pdfcore.MakeName(name)
+	cs.AlternateSpace = pdf.NewPdfColorspaceDeviceCMYK()
+	cs.TintTransform = &pdf.PdfFunctionType2{
+		Domain: []float64{0, 1},
+		C0:     []float64{0, 0, 0, 0},
+		C1:     full[:],
+		N:      1,
+	}
+	return cs
+}
+
+// Returns the color space of the ICC profile, "CMYK" or "RGB", from its header.
+func iccColorSpace(profile []byte) (string, error) {
+	if len(profile) < 128 || string(profile[36:40]) != "acsp" {
+		return "", fmt.Errorf("Not an ICC profile")
+	}
+	switch string(profile[16:20]) {
+	case "CMYK":
+		return "CMYK", nil
+	case "RGB ":
+		return "RGB", nil
+	}
+	return "", fmt.Errorf("Unsupported ICC profile color space %q, need CMYK or RGB", profile[16:20])
+}
+
+// rgbToCMYK converts an RGB color (0-1) to CMYK with the naive formula, putting as much as possible in the black
+// component.  It is not color managed, see the caveats above.
+func rgbToCMYK(r, g, b float64) cmyk {
+	k := 1 - math.Max(r, math.Max(g, b))
+	if k >= 1 {
+		return cmyk{0, 0, 0, 1}
+	}
+	return cmyk{(1 - r - k) / (1 - k), (1 - g - k) / (1 - k), (1 - b - k) / (1 - k), k}
+}
+
+// cmykToRGB converts a CMYK color to RGB (0-1) with the naive formula, the inverse of rgbToCMYK.
+func cmykToRGB(c cmyk) (float64, float64, float64) {
+	return (1 - c[0]) * (1 - c[3]), (1 - c[1]) * (1 - c[3]), (1 - c[2]) * (1 - c[3])
+}
+
+// Formats a CMYK color in percent, e.g. "C 0 M 85 Y 75 K 5".
+func formatCMYK(c cmyk) string {
+	return fmt.Sprintf("C %.0f M %.0f Y %.0f K %.0f", c[0]*100, c[1]*100, c[2]*100, c[3]*100)
+}
+
+// Formats an RGB color (0-1) in 8 bit values, e.g. "255 140 0".
+func formatRGB(r, g, b float64) string {
+	return fmt.Sprintf("%.0f %.0f %.0f", r*255, g*255, b*255)
+}
+
+// Returns an ICC (version 2) display profile for sRGB: the sRGB primaries adapted to the D50 profile connection
+// space, and the sRGB tone curve as a table.
+func newSRGBProfile() []byte {
+	curve := make([]uint16, 1024)
+	for i := range curve {
+		v := float64(i) / float64(len(curve)-1)
+		if v <= 0.04045 {
+			v = v / 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		curve[i] = uint16(math.Round(v * 65535))
+	}
+	trc := iccCurve(curve)
+
+	tags := []struct {
+		signature string
+		data      []byte
+	}{
+		{"desc", iccDescription("sRGB IEC61966-2.1")},
+		{"cprt", iccText("No copyright, use freely")},
+		{"wtpt", iccXYZ(0.9505, 1, 1.0891)},
+		{"rXYZ", iccXYZ(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", iccXYZ(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", iccXYZ(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	// Header, tag table, then the tag data aligned on 4 bytes.
+	var table, tagData bytes.Buffer
+	dataOffset := 128 + 4 + 12*len(tags)
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	for _, tag := range tags {
+		table.WriteString(tag.signature)
+		binary.Write(&table, binary.BigEndian, uint32(dataOffset+tagData.Len()))
+		binary.Write(&table, binary.BigEndian, uint32(len(tag.data)))
+		tagData.Write(tag.data)
+		for tagData.Len()%4 != 0 {
+			tagData.WriteByte(0)
+		}
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(128+table.Len()+tagData.Len()))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // Version 2.1.
+	copy(header[12:], "mntr")                          // Display device profile.
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	now := time.Now().UTC()
+	for i, v := range []int{now.Year(), int(now.Month()), now.Day(), now.Hour(), now.Minute(), now.Second()} {
+		binary.BigEndian.PutUint16(header[24+2*i:], uint16(v))
+	}
+	copy(header[36:], "acsp")
+	// Illuminant of the profile connection space: D50.
+	copy(header[68:], iccXYZ(0.9642, 1, 0.8249)[8:])
+
+	profile := append(header, table.Bytes()...)
+	return append(profile, tagData.Bytes()...)
+}
+
+// Returns an ICC XYZ tag with the values as s15Fixed16 numbers.
+func iccXYZ(x, y, z float64) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("XYZ \x00\x00\x00\x00")
+	for _, v := range []float64{x, y, z} {
+		binary.Write(&buf, binary.BigEndian, int32(math.Round(v*65536)))
+	}
+	return buf.Bytes()
+}
+
+// Returns an ICC curve tag with the table of values.
+func iccCurve(values []uint16) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("curv\x00\x00\x00\x00")
+	binary.Write(&buf, binary.BigEndian, uint32(len(values)))
+	binary.Write(&buf, binary.BigEndian, values)
+	return buf.Bytes()
+}
+
+// Returns an ICC text tag.
+func iccText(s string) []byte {
+	return []byte("text\x00\x00\x00\x00" + s + "\x00")
+}
+
+// Returns an ICC text description tag, with only the ASCII description (empty Unicode and ScriptCode parts).
+func iccDescription(s string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("desc\x00\x00\x00\x00")
+	binary.Write(&buf, binary.BigEndian, uint32(len(s)+1))
+	buf.WriteString(s + "\x00")
+	buf.Write(make([]byte, 4+4+2+1+67))
+	return buf.Bytes()
+}
@@ -0,0 +1,594 @@
+/*
+ * Make a dark mode reading copy of a PDF: light text on a dark background, by inverting the colors of the content.
+ *
+ * Plain inversion of each RGB component (1 - c) turns dark text light, but also turns red into cyan and blue into
+ * yellow, so colors that mean something (red warnings, blue links, the colors of a chart) are lost.  Instead, the
+ * lightness of each color is inverted and its hue and saturation are kept, as in the HSL color model: black becomes
+ * light, white becomes dark, a dark blue becomes a light blue.  The lightness is inverted into the range from
+ * darkLevel to lightLevel rather than from 0 to 1, as light text on a black background glares.
+ *
+ * The changes to each page:
+ * - The page is painted with the background, the inverted white of the paper, before the content.  Then the fill
+ *   and stroke colors are set to the inverted black, the initial color of content that does not set a color.
+ * - The colors set with g/G, rg/RG, k/K and sc/scn/SC/SCN (in any color space but patterns) are converted to RGB,
+ *   inverted and set with rg/RG.
+ * - Images, both XObject images and inline images, are inverted the same way, pixel by pixel, and recompressed with
+ *   the Flate encoder.  Gray images stay gray, and indexed images are converted to RGB through the palette.  Image
+ *   masks are painted with the fill color, which is inverted already, and are left unchanged.
+ * - Forms are inverted with their content.  An image or form shared by several pages is inverted once.
+ *
+ * Photos and other images with many colors usually look wrong inverted.  With -keep-images, images are left as they
+ * are; they then stand out as light areas on the dark page.
+ *
+ * Caveats:
+ * - Patterns and shadings are left unchanged, with a warning.
+ * - Annotations, such as form fields, have their own appearance streams, which are not inverted.
+ *
+ * Run as: go run invert.go [-keep-images] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run invert.go [-keep-images] input.pdf output.pdf\n"
+
+// The range of the inverted lightness: white is inverted to darkLevel and black to lightLevel.
+const (
+	darkLevel  = 0.12
+	lightLevel = 0.9
+)
+
+// inverter inverts the colors of the pages of a document.
+type inverter struct {
+	keepImages bool
+
+	// The inverted XObjects by the XObject before inversion, and the inverted XObjects themselves, so that an XObject
+	// shared by several pages or forms is inverted only once.
+	inverted map[*pdfcore.PdfObjectStream]*pdfcore.PdfObjectStream
+
+	// Counts for the summary.
+	colors, images, keptImages, imageMasks, patterns, shadings int
+}
+
+func main() {
+	keepImages := false
+	flag.BoolVar(&keepImages, "keep-images", false, "Leave images unchanged")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inv := &inverter{keepImages: keepImages, inverted: map[*pdfcore.PdfObjectStream]*pdfcore.PdfObjectStream{}}
+	err := inv.invertPdf(inputPath, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Colors inverted: %d\n", inv.colors)
+	fmt.Printf("Images inverted: %d, left unchanged: %d, image masks: %d\n", inv.images, inv.keptImages,
+		inv.imageMasks)
+	if inv.patterns > 0 || inv.shadings > 0 {
+		fmt.Printf("Warning: %d pattern and %d shading uses left unchanged\n", inv.patterns, inv.shadings)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func (inv *inverter) invertPdf(inputPath, outputPath string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Encrypted PDF, a password is required")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+
+		err = inv.invertPage(page)
+		if err != nil {
+			return fmt.Errorf("Page %d: %v", i+1, err)
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// invertPage inverts the content of the page and paints the background behind it.
+func (inv *inverter) invertPage(page *pdf.PdfPage) error {
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	invertedContents, err := inv.invertContentStream(contents, page.Resources)
+	if err != nil {
+		return err
+	}
+
+	// The visible area of the page is the crop box, which is the media box if not set.
+	box := page.CropBox
+	if box == nil {
+		box, err = page.GetMediaBox()
+		if err != nil {
+			return err
+		}
+	}
+
+	bgR, bgG, bgB := invertRGB(1, 1, 1)
+	fgR, fgG, fgB := invertRGB(0, 0, 0)
+	background := fmt.Sprintf("q %.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f Q\n%.3f %.3f %.3f rg %.3f %.3f %.3f RG\n",
+		bgR, bgG, bgB, box.Llx, box.Lly, box.Urx-box.Llx, box.Ury-box.Lly, fgR, fgG, fgB, fgR, fgG, fgB)
+
+	return page.SetContentStreams([]string{background + string(invertedContents)}, pdfcore.NewFlateEncoder())
+}
+
+// invertContentStream returns the content stream with the colors inverted, and inverts the images and forms that it
+// draws in the resources.
+func (inv *inverter) invertContentStream(contents string, resources *pdf.PdfPageResources) ([]byte, error) {
+	cstreamParser := pdfcontent.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	processed := pdfcontent.ContentStreamOperations{}
+
+	// The processor keeps track of the color spaces and colors in the graphics state, which are updated by an
+	// operation before the handlers are called with it.
+	processor := pdfcontent.NewContentStreamProcessor(*operations)
+	processor.AddHandler(pdfcontent.HandlerConditionEnumAllOperands, "",
+		func(op *pdfcontent.ContentStreamOperation, gs pdfcontent.GraphicsState,
+			resources *pdf.PdfPageResources) error {
+			switch op.Operand {
+			case "CS", "SC", "SCN", "G", "RG", "K":
+				invertedOp, err := inv.invertColorOperation(op, "RG", gs.ColorspaceStroking, gs.ColorStroking)
+				if err != nil {
+					return err
+				}
+				processed = append(processed, invertedOp)
+			case "cs", "sc", "scn", "g", "rg", "k":
+				invertedOp, err := inv.invertColorOperation(op, "rg", gs.ColorspaceNonStroking, gs.ColorNonStroking)
+				if err != nil {
+					return err
+				}
+				processed = append(processed, invertedOp)
+			case "sh":
+				inv.shadings++
+				processed = append(processed, op)
+			case "BI":
+				invertedOp, err := inv.invertInlineImage(op, resources)
+				if err != nil {
+					return err
+				}
+				processed = append(processed, invertedOp)
+			case "Do":
+				err := inv.invertXObject(op, resources)
+				if err != nil {
+					return err
+				}
+				processed = append(processed, op)
+			default:
+				processed = append(processed, op)
+			}
+			return nil
+		})
+
+	err = processor.Process(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	return processed.Bytes(), nil
+}
+
+// invertColorOperation returns the operation that sets the inverted color of op with the RGB operator rgbOperand
+// (rg or RG): the color after op in the color space cs.  Operations in pattern color spaces are returned unchanged.
+func (inv *inverter) invertColorOperation(op *pdfcontent.ContentStreamOperation, rgbOperand string,
+	cs pdf.PdfColorspace, color pdf.PdfColor) (*pdfcontent.ContentStreamOperation, error) {
+	if _, isPattern := cs.(*pdf.PdfColorspaceSpecialPattern); isPattern {
+		if op.Operand != "cs" && op.Operand != "CS" {
+			inv.patterns++
+		}
+		return op, nil
+	}
+
+	rgb, err := cs.ColorToRGB(color)
+	if err != nil {
+		return nil, err
+	}
+	rgbColor, ok := rgb.(*pdf.PdfColorDeviceRGB)
+	if !ok {
+		return nil, fmt.Errorf("Unexpected RGB color type %T", rgb)
+	}
+	r, g, b := invertRGB(rgbColor.R(), rgbColor.G(), rgbColor.B())
+	inv.colors++
+
+	invertedOp := pdfcontent.ContentStreamOperation{}
+	invertedOp.Operand = rgbOperand
+	invertedOp.Params = []pdfcore.PdfObject{pdfcore.MakeFloat(r), pdfcore.MakeFloat(g), pdfcore.MakeFloat(b)}
+	return &invertedOp, nil
+}
+
+// invertXObject inverts the image or the form drawn by the Do operation in the resources, unless it is inverted
+// already.  A form is inverted with its own resources, or the resources of the content stream that draws it if it
+// has none.
+func (inv *inverter) invertXObject(op *pdfcontent.ContentStreamOperation, resources *pdf.PdfPageResources) error {
+	if len(op.Params) != 1 {
+		return errors.New("Do operator should have 1 parameter")
+	}
+	name, ok := op.Params[0].(*pdfcore.PdfObjectName)
+	if !ok {
+		return errors.New("Do parameter should be a name")
+	}
+
+	stream, xtype := resources.GetXObjectByName(*name)
+	if stream == nil {
+		return fmt.Errorf("XObject %s not found", *name)
+	}
+	if inverted, has := inv.inverted[stream]; has {
+		if inverted != stream {
+			return resources.SetXObjectByName(*name, inverted)
+		}
+		return nil
+	}
+
+	switch xtype {
+	case pdf.XObjectTypeImage:
+		ximg, err := resources.GetXObjectImageByName(*name)
+		if err != nil {
+			return err
+		}
+		invertedImg, err := inv.invertXObjectImage(ximg)
+		if err != nil {
+			return fmt.Errorf("Image %s: %v", *name, err)
+		}
+		if invertedImg == nil {
+			inv.inverted[stream] = stream
+			return nil
+		}
+		err = resources.SetXObjectImageByName(*name, invertedImg)
+		if err != nil {
+			return err
+		}
+		invertedStream, _ := resources.GetXObjectByName(*name)
+		inv.inverted[stream] = invertedStream
+		inv.inverted[invertedStream] = invertedStream
+	case pdf.XObjectTypeForm:
+		// The form is updated in place, and marked first in case it draws itself.
+		inv.inverted[stream] = stream
+		xform, err := resources.GetXObjectFormByName(*name)
+		if err != nil {
+			return err
+		}
+		content, err := xform.GetContentStream()
+		if err != nil {
+			return err
+		}
+		formResources := xform.Resources
+		if formResources == nil {
+			formResources = resources
+		}
+		invertedContent, err := inv.invertContentStream(string(content), formResources)
+		if err != nil {
+			return fmt.Errorf("Form %s: %v", *name, err)
+		}
+		err = xform.SetContentStream(invertedContent, pdfcore.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+		return resources.SetXObjectFormByName(*name, xform)
+	}
+	return nil
+}
+
+// invertXObjectImage returns the inverted image, or nil if it is left unchanged.
+func (inv *inverter) invertXObjectImage(ximg *pdf.XObjectImage) (*pdf.XObjectImage, error) {
+	if isMask, ok := pdfcore.TraceToDirectObject(ximg.ImageMask).(*pdfcore.PdfObjectBool); ok && bool(*isMask) {
+		inv.imageMasks++
+		return nil, nil
+	}
+	if inv.keepImages {
+		inv.keptImages++
+		return nil, nil
+	}
+
+	img, err := ximg.ToImage()
+	if err != nil {
+		return nil, err
+	}
+	invertedImg, cs, err := invertImage(img, ximg.ColorSpace)
+	if err != nil {
+		return nil, err
+	}
+	invertedXimg, err := pdf.UpdateXObjectImageFromImage(ximg, invertedImg, cs, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	// The soft mask is kept by the update; an explicit mask is kept here.  A color key mask, which gives ranges of
+	// colors before the inversion, is dropped.
+	if _, isStream := pdfcore.TraceToDirectObject(ximg.Mask).(*pdfcore.PdfObjectStream); isStream {
+		invertedXimg.Mask = ximg.Mask
+	}
+
+	inv.images++
+	return invertedXimg, nil
+}
+
+// invertInlineImage returns the BI operation with the inline image inverted, or unchanged if images are kept or it
+// is an image mask.
+func (inv *inverter) invertInlineImage(op *pdfcontent.ContentStreamOperation,
+	resources *pdf.PdfPageResources) (*pdfcontent.ContentStreamOperation, error) {
+	if len(op.Params) != 1 {
+		return nil, errors.New("BI operator should have 1 parameter")
+	}
+	iimg, ok := op.Params[0].(*pdfcontent.ContentStreamInlineImage)
+	if !ok {
+		return nil, errors.New("Invalid inline image")
+	}
+
+	isMask, err := iimg.IsMask()
+	if err != nil {
+		return nil, err
+	}
+	if isMask {
+		inv.imageMasks++
+		return op, nil
+	}
+	if inv.keepImages {
+		inv.keptImages++
+		return op, nil
+	}
+
+	cs, err := iimg.GetColorSpace(resources)
+	if err != nil {
+		return nil, err
+	}
+	img, err := iimg.ToImage(resources)
+	if err != nil {
+		return nil, err
+	}
+	invertedImg, _, err := invertImage(img, cs)
+	if err != nil {
+		return nil, fmt.Errorf("Inline image: %v", err)
+	}
+	invertedIimg, err := pdfcontent.NewInlineImageFromImage(*invertedImg, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+
+	inv.images++
+	invertedOp := pdfcontent.ContentStreamOperation{}
+	invertedOp.Operand = "BI"
+	invertedOp.Params = []pdfcore.PdfObject{invertedIimg}
+	return &invertedOp, nil
+}
+
+// invertImage returns the image in the color space cs inverted, and its new color space: DeviceGray for a gray image,
+// otherwise DeviceRGB.
+func invertImage(img *pdf.Image, cs pdf.PdfColorspace) (*pdf.Image, pdf.PdfColorspace, error) {
+	if _, isGray := cs.(*pdf.PdfColorspaceDeviceGray); isGray {
+		maxVal := math.Pow(2, float64(img.BitsPerComponent)) - 1
+		samples := img.GetSamples()
+		for i, s := range samples {
+			samples[i] = uint32(invertLightness(float64(s)/maxVal)*maxVal + 0.5)
+		}
+		invertedImg := *img
+		invertedImg.SetSamples(samples)
+		return &invertedImg, pdf.NewPdfColorspaceDeviceGray(), nil
+	}
+
+	var rgbImg pdf.Image
+	if indexed, ok := cs.(*pdf.PdfColorspaceSpecialIndexed); ok {
+		var err error
+		rgbImg, err = indexedImageToRGB(img, indexed)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var err error
+		rgbImg, err = cs.ImageToRGB(*img)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	maxVal := math.Pow(2, float64(rgbImg.BitsPerComponent)) - 1
+	samples := rgbImg.GetSamples()
+	for i := 0; i+2 < len(samples); i += 3 {
+		r, g, b := invertRGB(float64(samples[i])/maxVal, float64(samples[i+1])/maxVal, float64(samples[i+2])/maxVal)
+		samples[i] = uint32(r*maxVal + 0.5)
+		samples[i+1] = uint32(g*maxVal + 0.5)
+		samples[i+2] = uint32(b*maxVal + 0.5)
+	}
+	rgbImg.SetSamples(samples)
+	rgbImg.ColorComponents = 3
+	return &rgbImg, pdf.NewPdfColorspaceDeviceRGB(), nil
+}
+
+// indexedImageToRGB converts an indexed image to 8 bit RGB through the palette.  Pixels with an index beyond the
+// palette are given its last entry.
+func indexedImageToRGB(img *pdf.Image, cs *pdf.PdfColorspaceSpecialIndexed) (pdf.Image, error) {
+	var lookup []byte
+	switch obj := pdfcore.TraceToDirectObject(cs.Lookup).(type) {
+	case *pdfcore.PdfObjectString:
+		lookup = []byte(*obj)
+	case *pdfcore.PdfObjectStream:
+		decoded, err := pdfcore.DecodeStream(obj)
+		if err != nil {
+			return pdf.Image{}, err
+		}
+		lookup = decoded
+	default:
+		return pdf.Image{}, errors.New("Invalid indexed color space lookup table")
+	}
+
+	// The palette in RGB: the lookup table has one entry per index up to HiVal, each with a byte per component of
+	// the base color space.
+	n := cs.Base.GetNumComponents()
+	entries := cs.HiVal + 1
+	if len(lookup)/n < entries {
+		entries = len(lookup) / n
+	}
+	if entries == 0 {
+		return pdf.Image{}, errors.New("Empty indexed color space lookup table")
+	}
+	palette := make([]byte, 3*entries)
+	for i := 0; i < entries; i++ {
+		vals := make([]float64, n)
+		for j := range vals {
+			vals[j] = float64(lookup[i*n+j]) / 255
+		}
+		color, err := cs.Base.ColorFromFloats(vals)
+		if err != nil {
+			return pdf.Image{}, err
+		}
+		rgb, err := cs.Base.ColorToRGB(color)
+		if err != nil {
+			return pdf.Image{}, err
+		}
+		rgbColor, ok := rgb.(*pdf.PdfColorDeviceRGB)
+		if !ok {
+			return pdf.Image{}, fmt.Errorf("Unexpected RGB color type %T", rgb)
+		}
+		palette[3*i] = byte(rgbColor.R()*255 + 0.5)
+		palette[3*i+1] = byte(rgbColor.G()*255 + 0.5)
+		palette[3*i+2] = byte(rgbColor.B()*255 + 0.5)
+	}
+
+	samples := img.GetSamples()
+	data := make([]byte, 0, 3*len(samples))
+	for _, index := range samples {
+		if int(index) >= entries {
+			index = uint32(entries - 1)
+		}
+		data = append(data, palette[3*index:3*index+3]...)
+	}
+
+	rgbImg := pdf.Image{}
+	rgbImg.Width = img.Width
+	rgbImg.Height = img.Height
+	rgbImg.BitsPerComponent = 8
+	rgbImg.ColorComponents = 3
+	rgbImg.Data = data
+	return rgbImg, nil
+}
+
+// invertRGB inverts the lightness of the color, with the components from 0 to 1, and keeps its hue and saturation.
+func invertRGB(r, g, b float64) (float64, float64, float64) {
+	h, s, l := rgbToHsl(r, g, b)
+	return hslToRgb(h, s, invertLightness(l))
+}
+
+// invertLightness maps the lightness l, from 0 to 1, to the inverted lightness from lightLevel to darkLevel.
+func invertLightness(l float64) float64 {
+	return lightLevel - (lightLevel-darkLevel)*l
+}
+
+// rgbToHsl returns the hue (from 0 to 6), saturation and lightness of the color.
+func rgbToHsl(r, g, b float64) (float64, float64, float64) {
+	hi := math.Max(r, math.Max(g, b))
+	lo := math.Min(r, math.Min(g, b))
+	l := (hi + lo) / 2
+	if hi == lo {
+		return 0, 0, l
+	}
+
+	d := hi - lo
+	s := d / (1 - math.Abs(2*l-1))
+	var h float64
+	switch hi {
+	case r:
+		h = math.Mod((g-b)/d+6, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h, s, l
+}
+
+// hslToRgb returns the color of the hue (from 0 to 6), saturation and lightness.
+func hslToRgb(h, s, l float64) (float64, float64, float64) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 1:
+		r, g, b = c, x, 0
+	case h < 2:
+		r, g, b = x, c, 0
+	case h < 3:
+		r, g, b = 0, c, x
+	case h < 4:
+		r, g, b = 0, x, c
+	case h < 5:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return r + m, g + m, b + m
+}
@@ -0,0 +1,518 @@
+/*
+ * Convert a PDF to grayscale for cheaper printing: the colors of fills, strokes and images are replaced by gray
+ * levels of the same lightness, so a color printer prints the document with black ink (or toner) only.
+ *
+ * The content streams of the pages, and of the forms they draw, are rewritten:
+ * - Colors set with rg/RG (RGB), k/K (CMYK) and sc/scn/SC/SCN in other color spaces (ICC based, Lab, Indexed,
+ *   Separation, DeviceN) are converted to RGB by the color space and then to a gray level, 0.3 R + 0.59 G + 0.11 B,
+ *   which is set with g/G.
+ * - A color space set with cs/CS is replaced by its initial color in gray, set with g/G; the colors set in the color
+ *   space afterwards are converted as above.
+ * - Gray colors are left unchanged: g/G, and colors in DeviceGray, CalGray and single component ICC based color
+ *   spaces.
+ *
+ * Images, both XObject images and inline images, are converted to DeviceGray and recompressed: DCT (JPEG) images
+ * with the DCT encoder at one component, others with the Flate encoder.  CMYK and ICC based images are converted by
+ * their color space.  Indexed images are converted through the palette: each entry of the palette is converted to a
+ * gray level, and the pixels are looked up in the gray palette as 8 bit gray.  Images that are already gray, and
+ * image masks, which are painted with the current fill color, are left unchanged.  Soft masks (SMask) and explicit
+ * masks of the images are kept.
+ *
+ * Caveats:
+ * - Patterns and shadings are left in color, with a warning.  See pdf/advanced/pdf_grayscale_transform.go for the
+ *   conversion of their color spaces.
+ * - Color key masks (a Mask array of color ranges) are given in the color space of the image and are dropped, with a
+ *   warning: the masked colors become visible.
+ * - Annotations, such as form fields, have their own appearance streams, which are not converted.
+ *
+ * Run as: go run to_grayscale.go input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+// conversionStats counts what was converted and what was left unchanged, for the summary.
+type conversionStats struct {
+	ColorsConverted int
+	ColorsGray      int
+	ImagesConverted map[string]int // By color space family.
+	ImagesGray      int
+	ImageMasks      int
+	Patterns        int
+	Shadings        int
+	ColorKeyMasks   int
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Printf("Usage: go run to_grayscale.go input.pdf output.pdf\n")
+		os.Exit(1)
+	}
+
+	inputPath := os.Args[1]
+	outputPath := os.Args[2]
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	stats := &conversionStats{ImagesConverted: map[string]int{}}
+	err := convertToGrayscale(inputPath, outputPath, stats)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Colors converted: %d, already gray: %d\n", stats.ColorsConverted, stats.ColorsGray)
+	var names []string
+	for name := range stats.ImagesConverted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("Images converted from %s: %d\n", name, stats.ImagesConverted[name])
+	}
+	fmt.Printf("Images already gray: %d, image masks: %d\n", stats.ImagesGray, stats.ImageMasks)
+	if stats.Patterns > 0 || stats.Shadings > 0 {
+		fmt.Printf("Warning: %d pattern and %d shading uses left in color\n", stats.Patterns, stats.Shadings)
+	}
+	if stats.ColorKeyMasks > 0 {
+		fmt.Printf("Warning: color key masks dropped from %d images\n", stats.ColorKeyMasks)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func convertToGrayscale(inputPath, outputPath string, stats *conversionStats) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Encrypted PDF, a password is required")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return err
+		}
+		grayContents, err := convertContentStream(contents, page.Resources, stats)
+		if err != nil {
+			return fmt.Errorf("Page %d: %v", i+1, err)
+		}
+		err = page.SetContentStreams([]string{string(grayContents)}, pdfcore.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// convertContentStream returns the content stream with the colors set in gray, and converts the images and forms
+// that it draws in the resources.
+func convertContentStream(contents string, resources *pdf.PdfPageResources, stats *conversionStats) ([]byte,
+	error) {
+	cstreamParser := pdfcontent.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	processed := pdfcontent.ContentStreamOperations{}
+
+	// The processor keeps track of the color spaces and colors in the graphics state, which are updated by an
+	// operation before the handlers are called with it.
+	processor := pdfcontent.NewContentStreamProcessor(*operations)
+	processor.AddHandler(pdfcontent.HandlerConditionEnumAllOperands, "",
+		func(op *pdfcontent.ContentStreamOperation, gs pdfcontent.GraphicsState,
+			resources *pdf.PdfPageResources) error {
+			switch op.Operand {
+			case "CS", "SC", "SCN", "RG", "K":
+				grayOp, err := convertColorOperation(op, "G", gs.ColorspaceStroking, gs.ColorStroking, stats)
+				if err != nil {
+					return err
+				}
+				processed = append(processed, grayOp)
+			case "cs", "sc", "scn", "rg", "k":
+				grayOp, err := convertColorOperation(op, "g", gs.ColorspaceNonStroking, gs.ColorNonStroking, stats)
+				if err != nil {
+					return err
+				}
+				processed = append(processed, grayOp)
+			case "g", "G":
+				stats.ColorsGray++
+				processed = append(processed, op)
+			case "sh":
+				stats.Shadings++
+				processed = append(processed, op)
+			case "BI":
+				grayOp, err := convertInlineImage(op, resources, stats)
+				if err != nil {
+					return err
+				}
+				processed = append(processed, grayOp)
+			case "Do":
+				err := convertXObject(op, resources, stats)
+				if err != nil {
+					return err
+				}
+				processed = append(processed, op)
+			default:
+				processed = append(processed, op)
+			}
+			return nil
+		})
+
+	err = processor.Process(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	return processed.Bytes(), nil
+}
+
+// convertColorOperation returns the operation that sets the color of op in gray, with the gray operator grayOperand
+// (g or G): the color after op in the color space cs.  Operations in gray color spaces are returned unchanged, and so
+// are operations in pattern color spaces, which are left in color.
+func convertColorOperation(op *pdfcontent.ContentStreamOperation, grayOperand string, cs pdf.PdfColorspace,
+	color pdf.PdfColor, stats *conversionStats) (*pdfcontent.ContentStreamOperation, error) {
+	if _, isPattern := cs.(*pdf.PdfColorspaceSpecialPattern); isPattern {
+		if op.Operand != "cs" && op.Operand != "CS" {
+			stats.Patterns++
+		}
+		return op, nil
+	}
+	if isGrayColorspace(cs) {
+		stats.ColorsGray++
+		return op, nil
+	}
+
+	gray, err := colorToGray(cs, color)
+	if err != nil {
+		return nil, err
+	}
+	stats.ColorsConverted++
+
+	grayOp := pdfcontent.ContentStreamOperation{}
+	grayOp.Operand = grayOperand
+	grayOp.Params = []pdfcore.PdfObject{pdfcore.MakeFloat(gray)}
+	return &grayOp, nil
+}
+
+// convertXObject converts the image or the form drawn by the Do operation in the resources.  A form is converted
+// with its own resources, or the resources of the content stream that draws it if it has none.
+func convertXObject(op *pdfcontent.ContentStreamOperation, resources *pdf.PdfPageResources,
+	stats *conversionStats) error {
+	if len(op.Params) != 1 {
+		return errors.New("Do operator should have 1 parameter")
+	}
+	name, ok := op.Params[0].(*pdfcore.PdfObjectName)
+	if !ok {
+		return errors.New("Do parameter should be a name")
+	}
+
+	_, xtype := resources.GetXObjectByName(*name)
+	switch xtype {
+	case pdf.XObjectTypeImage:
+		ximg, err := resources.GetXObjectImageByName(*name)
+		if err != nil {
+			return err
+		}
+		grayImg, err := convertXObjectImage(ximg, stats)
+		if err != nil {
+			return fmt.Errorf("Image %s: %v", *name, err)
+		}
+		if grayImg != nil {
+			return resources.SetXObjectImageByName(*name, grayImg)
+		}
+	case pdf.XObjectTypeForm:
+		xform, err := resources.GetXObjectFormByName(*name)
+		if err != nil {
+			return err
+		}
+		content, err := xform.GetContentStream()
+		if err != nil {
+			return err
+		}
+		formResources := xform.Resources
+		if formResources == nil {
+			formResources = resources
+		}
+		grayContent, err := convertContentStream(string(content), formResources, stats)
+		if err != nil {
+			return fmt.Errorf("Form %s: %v", *name, err)
+		}
+		err = xform.SetContentStream(grayContent, pdfcore.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+		return resources.SetXObjectFormByName(*name, xform)
+	}
+	return nil
+}
+
+// convertXObjectImage returns the image converted to DeviceGray, or nil if it is already gray or is an image mask.
+func convertXObjectImage(ximg *pdf.XObjectImage, stats *conversionStats) (*pdf.XObjectImage, error) {
+	if isMask, ok := pdfcore.TraceToDirectObject(ximg.ImageMask).(*pdfcore.PdfObjectBool); ok && bool(*isMask) {
+		stats.ImageMasks++
+		return nil, nil
+	}
+	if isGrayColorspace(ximg.ColorSpace) {
+		stats.ImagesGray++
+		return nil, nil
+	}
+
+	img, err := ximg.ToImage()
+	if err != nil {
+		return nil, err
+	}
+	grayImg, err := imageToGray(img, ximg.ColorSpace)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := grayEncoder(ximg.Filter)
+	grayXimg, err := pdf.UpdateXObjectImageFromImage(ximg, grayImg, pdf.NewPdfColorspaceDeviceGray(), encoder)
+	if err != nil {
+		return nil, err
+	}
+
+	// The soft mask is kept by the update, the mask is not.  An explicit mask is an image mask, which applies to the
+	// gray image as well, but a color key mask gives ranges of colors in the original color space.
+	switch pdfcore.TraceToDirectObject(ximg.Mask).(type) {
+	case *pdfcore.PdfObjectStream:
+		grayXimg.Mask = ximg.Mask
+	case *pdfcore.PdfObjectArray:
+		stats.ColorKeyMasks++
+	}
+
+	stats.ImagesConverted[ximg.ColorSpace.String()]++
+	return grayXimg, nil
+}
+
+// convertInlineImage returns the BI operation with the inline image converted to DeviceGray, or unchanged if it is
+// already gray or is an image mask.
+func convertInlineImage(op *pdfcontent.ContentStreamOperation, resources *pdf.PdfPageResources,
+	stats *conversionStats) (*pdfcontent.ContentStreamOperation, error) {
+	if len(op.Params) != 1 {
+		return nil, errors.New("BI operator should have 1 parameter")
+	}
+	iimg, ok := op.Params[0].(*pdfcontent.ContentStreamInlineImage)
+	if !ok {
+		return nil, errors.New("Invalid inline image")
+	}
+
+	isMask, err := iimg.IsMask()
+	if err != nil {
+		return nil, err
+	}
+	if isMask {
+		stats.ImageMasks++
+		return op, nil
+	}
+	cs, err := iimg.GetColorSpace(resources)
+	if err != nil {
+		return nil, err
+	}
+	if isGrayColorspace(cs) {
+		stats.ImagesGray++
+		return op, nil
+	}
+
+	img, err := iimg.ToImage(resources)
+	if err != nil {
+		return nil, err
+	}
+	grayImg, err := imageToGray(img, cs)
+	if err != nil {
+		return nil, fmt.Errorf("Inline image: %v", err)
+	}
+	encoder, err := iimg.GetEncoder()
+	if err != nil {
+		return nil, err
+	}
+	grayIimg, err := pdfcontent.NewInlineImageFromImage(*grayImg, grayEncoder(encoder))
+	if err != nil {
+		return nil, err
+	}
+
+	stats.ImagesConverted[cs.String()]++
+	grayOp := pdfcontent.ContentStreamOperation{}
+	grayOp.Operand = "BI"
+	grayOp.Params = []pdfcore.PdfObject{grayIimg}
+	return &grayOp, nil
+}
+
+// imageToGray returns the image in the color space cs converted to gray, with one component.
+func imageToGray(img *pdf.Image, cs pdf.PdfColorspace) (*pdf.Image, error) {
+	if indexed, ok := cs.(*pdf.PdfColorspaceSpecialIndexed); ok {
+		return indexedImageToGray(img, indexed)
+	}
+
+	rgbImg, err := cs.ImageToRGB(*img)
+	if err != nil {
+		return nil, err
+	}
+	grayImg, err := pdf.NewPdfColorspaceDeviceRGB().ImageToGray(rgbImg)
+	if err != nil {
+		return nil, err
+	}
+	return &grayImg, nil
+}
+
+// indexedImageToGray converts an indexed image to 8 bit gray through the palette, which is converted to gray levels
+// once.  Pixels with an index beyond the palette are given its last entry.
+func indexedImageToGray(img *pdf.Image, cs *pdf.PdfColorspaceSpecialIndexed) (*pdf.Image, error) {
+	palette, err := grayPalette(cs)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := img.GetSamples()
+	data := make([]byte, len(samples))
+	for i, index := range samples {
+		if int(index) >= len(palette) {
+			index = uint32(len(palette) - 1)
+		}
+		data[i] = palette[index]
+	}
+
+	grayImg := pdf.Image{}
+	grayImg.Width = img.Width
+	grayImg.Height = img.Height
+	grayImg.BitsPerComponent = 8
+	grayImg.ColorComponents = 1
+	grayImg.Data = data
+	return &grayImg, nil
+}
+
+// grayPalette returns the palette of the indexed color space as 8 bit gray levels.  The lookup table has one entry
+// per index up to HiVal, each with a byte per component of the base color space.
+func grayPalette(cs *pdf.PdfColorspaceSpecialIndexed) ([]byte, error) {
+	var lookup []byte
+	switch obj := pdfcore.TraceToDirectObject(cs.Lookup).(type) {
+	case *pdfcore.PdfObjectString:
+		lookup = []byte(*obj)
+	case *pdfcore.PdfObjectStream:
+		decoded, err := pdfcore.DecodeStream(obj)
+		if err != nil {
+			return nil, err
+		}
+		lookup = decoded
+	default:
+		return nil, errors.New("Invalid indexed color space lookup table")
+	}
+
+	n := cs.Base.GetNumComponents()
+	entries := cs.HiVal + 1
+	if len(lookup)/n < entries {
+		entries = len(lookup) / n
+	}
+	if entries == 0 {
+		return nil, errors.New("Empty indexed color space lookup table")
+	}
+
+	palette := make([]byte, entries)
+	for i := range palette {
+		vals := make([]float64, n)
+		for j := range vals {
+			vals[j] = float64(lookup[i*n+j]) / 255
+		}
+		color, err := cs.Base.ColorFromFloats(vals)
+		if err != nil {
+			return nil, err
+		}
+		gray, err := colorToGray(cs.Base, color)
+		if err != nil {
+			return nil, err
+		}
+		palette[i] = byte(gray*255 + 0.5)
+	}
+	return palette, nil
+}
+
+// colorToGray returns the gray level, from 0 to 1, of the color in the color space cs.
+func colorToGray(cs pdf.PdfColorspace, color pdf.PdfColor) (float64, error) {
+	rgb, err := cs.ColorToRGB(color)
+	if err != nil {
+		return 0, err
+	}
+	rgbColor, ok := rgb.(*pdf.PdfColorDeviceRGB)
+	if !ok {
+		return 0, fmt.Errorf("Unexpected RGB color type %T", rgb)
+	}
+	return rgbColor.ToGray().Val(), nil
+}
+
+// grayEncoder returns the encoder for the gray version of an image encoded with encoder: the DCT encoder for one
+// component for a DCT encoded image, otherwise the Flate encoder.
+func grayEncoder(encoder pdfcore.StreamEncoder) pdfcore.StreamEncoder {
+	if dctEncoder, ok := encoder.(*pdfcore.DCTEncoder); ok {
+		grayDct := *dctEncoder
+		grayDct.ColorComponents = 1
+		return &grayDct
+	}
+	return pdfcore.NewFlateEncoder()
+}
+
+// isGrayColorspace returns true if colors in cs are gray levels already: DeviceGray, CalGray and ICC based with one
+// component.  Separation color spaces also have one component, but it is the tint of a colorant of any color.
+func isGrayColorspace(cs pdf.PdfColorspace) bool {
+	switch cs := cs.(type) {
+	case *pdf.PdfColorspaceDeviceGray, *pdf.PdfColorspaceCalGray:
+		return true
+	case *pdf.PdfColorspaceICCBased:
+		return cs.N == 1
+	}
+	return false
+}
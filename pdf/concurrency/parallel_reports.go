@@ -0,0 +1,270 @@
+/*
+ * Generate many independent reports in parallel with a pool of worker goroutines, each report written to its own
+ * file, and collect the errors.
+ *
+ * What can be shared between goroutines?
+ *
+ * - creator.Creator: no.  A creator holds the pages and drawing state of one document, so every report has its own,
+ *   created, used and written by one goroutine.
+ *
+ * - Loaded fonts (*model.PdfFont from NewPdfFontFromTTFFile): no, not while documents using them are built or
+ *   written at the same time.  A loaded font looks immutable, but it is not: drawing text with it calls its
+ *   ToPdfObject, which builds a new font dictionary in the font's cached indirect object, and writing a document
+ *   assigns object numbers to the font's objects (font, descriptor, widths, font file) in place, the same objects in
+ *   every document.  Two goroutines using a font thus race on these objects: besides a data race (which the race
+ *   detector reports), a document can be written with the object numbers another document gave the font, referring
+ *   to wrong objects.  A font can be reused by documents one after the other, as the numbers are assigned again each
+ *   time.  The standard fonts (fonts.NewFontHelvetica() etc.) are values making new objects on each use, so they are
+ *   safe to share.
+ *
+ * So each worker loads its own set of fonts once, when it starts, and uses it for all the reports it generates, one
+ * at a time.  Loading costs little compared to generating many reports, and is done workers times, not once per
+ * report.  With -shared, all workers use a single font set instead, to show the problem: run with go run -race to see
+ * the data races reported (the files may or may not come out broken, depending on the timing).
+ *
+ * Errors: a failed report does not stop the others.  Each worker sends the result of each job (with a panic in the
+ * library turned into an error) to the collector, which prints a summary, and the program exits with status 1 if any
+ * report failed.
+ *
+ * Run as: go run parallel_reports.go [-n 20] [-workers 4] [-font regular.ttf] [-bold bold.ttf] [-shared] outdir
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run parallel_reports.go [-n 20] [-workers 4] [-font regular.ttf] [-bold bold.ttf] [-shared] " +
+	"outdir\n"
+
+var regions = []string{"North", "South", "East", "West", "Central"}
+
+// fontSet is the fonts used by a report.  A set is used by one report at a time.
+type fontSet struct {
+	regular fonts.Font
+	bold    fonts.Font
+}
+
+// job is a report to generate.
+type job struct {
+	index      int
+	region     string
+	outputPath string
+}
+
+// result is the outcome of a job.
+type result struct {
+	job      job
+	worker   int
+	duration time.Duration
+	err      error
+}
+
+func main() {
+	count := 0
+	workers := 0
+	fontPath := ""
+	boldFontPath := ""
+	shared := false
+	flag.IntVar(&count, "n", 20, "Number of reports")
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "Number of worker goroutines")
+	flag.StringVar(&fontPath, "font", "../report/Roboto-Regular.ttf", "TrueType font for the text")
+	flag.StringVar(&boldFontPath, "bold", "../report/Roboto-Bold.ttf", "TrueType font for the headings")
+	flag.BoolVar(&shared, "shared", false, "Share a single font set between the workers (unsafe, to show the "+
+		"data races with go run -race)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 || count < 1 || workers < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outDir := flag.Arg(0)
+	err := os.MkdirAll(outDir, 0755)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// The font sets of the workers, loaded before starting, so that a missing font fails at once.
+	fontSets := make([]*fontSet, workers)
+	for i := range fontSets {
+		if shared && i > 0 {
+			fontSets[i] = fontSets[0]
+			continue
+		}
+		fontSets[i], err = loadFonts(fontPath, boldFontPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	start := time.Now()
+	results := runJobs(count, outDir, fontSets)
+
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+			fmt.Printf("Report %d (%s): Error: %v\n", r.job.index, r.job.outputPath, r.err)
+			continue
+		}
+		fmt.Printf("Report %d: %s, worker %d, %v\n", r.job.index, r.job.outputPath, r.worker,
+			r.duration.Round(time.Millisecond))
+	}
+	fmt.Printf("%d reports by %d workers in %v, %d failed\n", len(results), workers,
+		time.Since(start).Round(time.Millisecond), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output directory: %s\n", outDir)
+}
+
+// runJobs generates count reports in outDir, with a worker per font set, and returns the results in the order of
+// the jobs.
+func runJobs(count int, outDir string, fontSets []*fontSet) []result {
+	jobs := make(chan job)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i, fs := range fontSets {
+		wg.Add(1)
+		go func(worker int, fs *fontSet) {
+			defer wg.Done()
+			for j := range jobs {
+				start := time.Now()
+				err := generateReport(j, fs)
+				results <- result{job: j, worker: worker, duration: time.Since(start), err: err}
+			}
+		}(i, fs)
+	}
+
+	// Feed the jobs, and close the results once all workers are done.
+	go func() {
+		for i := 1; i <= count; i++ {
+			jobs <- job{
+				index:      i,
+				region:     regions[(i-1)%len(regions)],
+				outputPath: filepath.Join(outDir, fmt.Sprintf("report_%03d.pdf", i)),
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	all := []result{}
+	for r := range results {
+		all = append(all, r)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].job.index < all[j].job.index })
+	return all
+}
+
+// generateReport builds and writes the report of a job with its own creator.  A panic in the library is returned as
+// an error, so that it fails the job and not the program.
+func generateReport(j job, fs *fontSet) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	heading := creator.NewParagraph(fmt.Sprintf("Sales report %d: %s region", j.index, j.region))
+	heading.SetFont(fs.bold)
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 10)
+	err = c.Draw(heading)
+	if err != nil {
+		return err
+	}
+
+	// The data of each report is different, but the same on every run.
+	rnd := rand.New(rand.NewSource(int64(j.index)))
+	rows := 40 + rnd.Intn(120)
+
+	table := creator.NewTable(4)
+	table.SetColumnWidths(0.15, 0.45, 0.2, 0.2)
+	for _, text := range []string{"No.", "Product", "Quantity", "Amount"} {
+		addCell(table, text, fs.bold)
+	}
+	total := 0.0
+	for i := 1; i <= rows; i++ {
+		qty := 1 + rnd.Intn(12)
+		amount := float64(qty) * (2.5 + float64(rnd.Intn(4000))/100)
+		total += amount
+		addCell(table, fmt.Sprintf("%d", i), fs.regular)
+		addCell(table, fmt.Sprintf("Product %04d", 1000+rnd.Intn(9000)), fs.regular)
+		addCell(table, fmt.Sprintf("%d", qty), fs.regular)
+		addCell(table, fmt.Sprintf("%.2f", amount), fs.regular)
+	}
+	addCell(table, "", fs.bold)
+	addCell(table, "Total", fs.bold)
+	addCell(table, "", fs.bold)
+	addCell(table, fmt.Sprintf("%.2f", total), fs.bold)
+
+	err = c.Draw(table)
+	if err != nil {
+		return err
+	}
+
+	c.DrawFooter(func(footer *creator.Block, args creator.FooterFunctionArgs) {
+		p := creator.NewParagraph(fmt.Sprintf("%s region - page %d of %d", j.region, args.PageNum, args.TotalPages))
+		p.SetFont(fs.regular)
+		p.SetFontSize(8)
+		p.SetPos(footer.Width()-50-p.Width(), 20)
+		footer.Draw(p)
+	})
+
+	return c.WriteToFile(j.outputPath)
+}
+
+// loadFonts loads a set of fonts from the TrueType font files.
+func loadFonts(fontPath, boldFontPath string) (*fontSet, error) {
+	regular, err := pdf.NewPdfFontFromTTFFile(fontPath)
+	if err != nil {
+		return nil, fmt.Errorf("Font %s: %v", fontPath, err)
+	}
+	bold, err := pdf.NewPdfFontFromTTFFile(boldFontPath)
+	if err != nil {
+		return nil, fmt.Errorf("Font %s: %v", boldFontPath, err)
+	}
+	return &fontSet{regular: regular, bold: bold}, nil
+}
+
+// addCell adds a cell with the text to the table.
+func addCell(table *creator.Table, text string, font fonts.Font) {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(10)
+	cell := table.NewCell()
+	cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+	cell.SetContent(p)
+}
@@ -0,0 +1,390 @@
+/*
+ * Convert a CSV file into a PDF table report.  The header row of the CSV gives the column titles, which are repeated
+ * at the top of every page.
+ *
+ * The CSV is parsed with encoding/csv, so quoted fields containing commas, quotes ("" within quotes) and line breaks
+ * are handled.  Rows with fewer fields than the header are padded with empty cells.
+ *
+ * Column widths are computed from the content: the widest value of each column, up to a maximum beyond which the
+ * text wraps.  Columns are detected as numeric when most of their values are numbers (with optional sign, currency
+ * symbol, thousands separators or percent sign), and are right aligned.
+ *
+ * Tables that are too wide for the page are handled in two steps: first the font is shrunk so that the columns fit,
+ * down to a minimum size (-minfont).  If they still do not fit, the columns are split into groups that are printed
+ * one after the other, each with all the rows, and the first (key) column is repeated in every group.
+ *
+ * Run as: go run csv_to_pdf.go [-landscape] [-minfont 6] input.csv output.pdf
+ */
+
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run csv_to_pdf.go [-landscape] [-minfont 6] input.csv output.pdf\n"
+
+// Table layout parameters.  The cell indent and minimum row height match the creator table defaults.
+const (
+	baseFontSize     = 9.0
+	maxColumnWidth   = 180.0 // Widest column at the base font size; longer values wrap.
+	cellIndent       = 5.0
+	defaultRowHeight = 10.0
+	lineHeight       = 1.1
+	numericFraction  = 0.8 // Fraction of the non-empty values that need to be numbers for a numeric column.
+)
+
+var (
+	headerColor = creator.ColorRGBFrom8bit(220, 220, 220)
+	borderColor = creator.ColorRGBFrom8bit(150, 150, 150)
+)
+
+// numberRegexp matches numbers like 42, -3.5, 1,234.50, $99, €1,000 or 12.5%.
+var numberRegexp = regexp.MustCompile(`^[-+]?[$€£]?(\d{1,3}(,\d{3})+|\d+)(\.\d+)?%?$`)
+
+// csvTable is the parsed CSV with the column layout.
+type csvTable struct {
+	header  []string
+	rows    [][]string
+	numeric []bool
+	widths  []float64 // Column widths at the base font size, including the cell indents.
+}
+
+// columnGroup is a set of columns printed together, with the font size they are printed at.
+type columnGroup struct {
+	columns  []int
+	fontSize float64
+}
+
+func main() {
+	landscape := false
+	minFontSize := 0.0
+	flag.BoolVar(&landscape, "landscape", false, "Use landscape pages")
+	flag.Float64Var(&minFontSize, "minfont", 6, "Smallest font size to shrink wide tables to before splitting them")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if minFontSize <= 0 || minFontSize > baseFontSize {
+		fmt.Printf("Error: -minfont must be between 0 and %g\n", baseFontSize)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := csvToPdf(inputPath, outputPath, landscape, minFontSize)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func csvToPdf(inputPath, outputPath string, landscape bool, minFontSize float64) error {
+	table, err := readCSV(inputPath)
+	if err != nil {
+		return err
+	}
+
+	c := creator.New()
+	if landscape {
+		c.SetPageSize(creator.PageSize{creator.PageSizeLetter[1], creator.PageSizeLetter[0]})
+	}
+	c.SetPageMargins(36, 36, 36, 36)
+	c.NewPage()
+
+	groups := table.columnGroups(c.Context().Width, minFontSize)
+	for i, group := range groups {
+		if i > 0 {
+			c.NewPage()
+		}
+
+		title := filepath.Base(inputPath)
+		if len(groups) > 1 {
+			title += fmt.Sprintf(" (columns part %d of %d)", i+1, len(groups))
+		}
+		heading := creator.NewParagraph(title)
+		heading.SetFont(fonts.NewFontHelveticaBold())
+		heading.SetFontSize(14)
+		heading.SetMargins(0, 0, 0, 10)
+		err = c.Draw(heading)
+		if err != nil {
+			return err
+		}
+
+		pages, err := table.draw(c, group)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Columns %v at %.1fpt: %d rows on %d page(s)\n", columnNumbers(group.columns), group.fontSize,
+			len(table.rows), pages)
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// readCSV reads the CSV file and computes the column widths and alignment.
+func readCSV(path string) (*csvTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	// Allow rows with a varying number of fields, they are padded or checked below.
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("The CSV file is empty")
+	}
+
+	table := &csvTable{header: records[0]}
+	// Drop a byte order mark, which is written by some spreadsheet applications.
+	table.header[0] = strings.TrimPrefix(table.header[0], "\uFEFF")
+
+	numCols := len(table.header)
+	for i, record := range records[1:] {
+		if len(record) > numCols {
+			return nil, fmt.Errorf("Row %d has %d fields, the header only %d", i+2, len(record), numCols)
+		}
+		for len(record) < numCols {
+			record = append(record, "")
+		}
+		table.rows = append(table.rows, record)
+	}
+
+	// Numeric columns and the column widths.
+	for col := 0; col < numCols; col++ {
+		numbers, values := 0, 0
+		width, err := textWidth(table.header[col], true, baseFontSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range table.rows {
+			value := strings.TrimSpace(row[col])
+			if value != "" {
+				values++
+				if numberRegexp.MatchString(value) {
+					numbers++
+				}
+			}
+			// Multiline values are as wide as the widest line.
+			for _, line := range strings.Split(row[col], "\n") {
+				w, err := textWidth(line, false, baseFontSize)
+				if err != nil {
+					return nil, fmt.Errorf("Column %q: %v", table.header[col], err)
+				}
+				if w > width {
+					width = w
+				}
+			}
+		}
+		if width > maxColumnWidth {
+			width = maxColumnWidth
+		}
+
+		table.numeric = append(table.numeric, values > 0 && float64(numbers) >= numericFraction*float64(values))
+		table.widths = append(table.widths, width+2*cellIndent)
+	}
+
+	return table, nil
+}
+
+// textWidth returns the width of the text on a single line.
+func textWidth(text string, bold bool, fontSize float64) (float64, error) {
+	p := newCellParagraph(text, bold, fontSize)
+	p.SetEnableWrap(false)
+	w := p.Width()
+	if w < 0 {
+		return 0, fmt.Errorf("Unsupported character in %q", text)
+	}
+	return w, nil
+}
+
+// columnGroups returns the groups of columns to print so that each fits the available width: all the columns at
+// the base font size if they fit, or at a smaller font size if that is at least minFontSize, and split into several
+// groups at the minimum font size otherwise, each starting with the first column.
+func (t *csvTable) columnGroups(availWidth, minFontSize float64) []columnGroup {
+	// The text widths scale with the font size, the cell indents do not.
+	totalTextWidth := func(cols []int) float64 {
+		w := 0.0
+		for _, col := range cols {
+			w += t.widths[col] - 2*cellIndent
+		}
+		return w
+	}
+	fontSizeFor := func(cols []int) float64 {
+		textAvail := availWidth - float64(len(cols))*2*cellIndent
+		if textAvail <= 0 {
+			return 0
+		}
+		return baseFontSize * textAvail / totalTextWidth(cols)
+	}
+
+	all := []int{}
+	for col := range t.header {
+		all = append(all, col)
+	}
+	if size := fontSizeFor(all); size >= baseFontSize {
+		return []columnGroup{{columns: all, fontSize: baseFontSize}}
+	} else if size >= minFontSize {
+		return []columnGroup{{columns: all, fontSize: size}}
+	}
+
+	// Split the columns at the minimum font size.  A single column that is too wide on its own is printed anyway,
+	// its text wraps.
+	groups := []columnGroup{}
+	current := []int{0}
+	for col := 1; col < len(t.header); col++ {
+		candidate := append(append([]int{}, current...), col)
+		if fontSizeFor(candidate) < minFontSize && len(current) > 1 {
+			groups = append(groups, columnGroup{columns: current, fontSize: minFontSize})
+			current = []int{0, col}
+			continue
+		}
+		current = candidate
+	}
+	return append(groups, columnGroup{columns: current, fontSize: minFontSize})
+}
+
+// draw draws the columns of the group for all the rows, starting at the current position and continuing on new
+// pages as needed, with the header row at the top of each page.  Returns the number of pages.
+func (t *csvTable) draw(c *creator.Creator, group columnGroup) (int, error) {
+	tableWidth := c.Context().Width
+
+	// Column widths as fractions of the table width, scaled to the font size.  The table is as wide as its
+	// columns, which can be narrower than the page.
+	fractions := []float64{}
+	for _, col := range group.columns {
+		w := (t.widths[col]-2*cellIndent)*group.fontSize/baseFontSize + 2*cellIndent
+		fractions = append(fractions, w/tableWidth)
+	}
+
+	headerHeight := t.rowHeight(t.header, group, fractions, tableWidth, true)
+
+	pages := 1
+	rowIdx := 0
+	freshPage := false // Nothing drawn on the page yet.
+	for rowIdx < len(t.rows) {
+		avail := c.Context().Height - headerHeight
+
+		table := creator.NewTable(len(group.columns))
+		table.SetColumnWidths(fractions...)
+		t.addRow(table, t.header, group, true)
+
+		count := 0
+		for rowIdx < len(t.rows) {
+			h := t.rowHeight(t.rows[rowIdx], group, fractions, tableWidth, false)
+			// A row taller than a whole page is placed anyway, otherwise continue on the next page.
+			if h > avail && (count > 0 || !freshPage) {
+				break
+			}
+			t.addRow(table, t.rows[rowIdx], group, false)
+			avail -= h
+			rowIdx++
+			count++
+		}
+
+		if count > 0 {
+			err := c.Draw(table)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if rowIdx < len(t.rows) {
+			c.NewPage()
+			pages++
+			freshPage = true
+		}
+	}
+
+	return pages, nil
+}
+
+// addRow adds the cells of the group's columns of a row to the table.
+func (t *csvTable) addRow(table *creator.Table, values []string, group columnGroup, header bool) {
+	for _, col := range group.columns {
+		cell := table.NewCell()
+		cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+		cell.SetBorderColor(borderColor)
+		if header {
+			cell.SetBackgroundColor(headerColor)
+		}
+		p := newCellParagraph(values[col], header, group.fontSize)
+		if t.numeric[col] {
+			// The wrapped paragraph is as wide as the cell less the indent, which the right aligned cell puts on
+			// the right side, and the text is aligned within the paragraph.
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+			p.SetTextAlignment(creator.TextAlignmentRight)
+		}
+		cell.SetContent(p)
+	}
+}
+
+// rowHeight measures the height of a row the way the creator table sizes rows: the tallest wrapped paragraph plus
+// its bottom margin (counted twice by the table) and half a line of top padding.
+func (t *csvTable) rowHeight(values []string, group columnGroup, fractions []float64, tableWidth float64,
+	header bool) float64 {
+	h := defaultRowHeight
+	for i, col := range group.columns {
+		p := newCellParagraph(values[col], header, group.fontSize)
+		p.SetWidth(fractions[i]*tableWidth - cellIndent)
+		_, _, _, bottom := p.GetMargins()
+		ph := p.Height() + 2*bottom + 0.5*group.fontSize*lineHeight
+		if ph > h {
+			h = ph
+		}
+	}
+	return h
+}
+
+// newCellParagraph returns a wrapping paragraph for a table cell.
+func newCellParagraph(text string, bold bool, fontSize float64) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	if bold {
+		p.SetFont(fonts.NewFontHelveticaBold())
+	} else {
+		p.SetFont(fonts.NewFontHelvetica())
+	}
+	p.SetFontSize(fontSize)
+	p.SetLineHeight(lineHeight)
+	// Paragraphs in table cells are not wrapped unless enabled explicitly.
+	p.SetEnableWrap(true)
+	p.SetMargins(0, 0, 0, 2)
+	return p
+}
+
+// columnNumbers returns the 1-based column numbers, for printing.
+func columnNumbers(columns []int) []int {
+	numbers := []int{}
+	for _, col := range columns {
+		numbers = append(numbers, col+1)
+	}
+	return numbers
+}
@@ -0,0 +1,509 @@
+/*
+ * Dump the structure of a PDF file for debugging: the cross-reference sections with their trailers, the object types
+ * and the tree of indirect objects reachable from the trailer.
+ *
+ * The cross-reference sections are read by following the chain from startxref through the Prev entries of the
+ * trailers, newest first.  Both forms are supported: classic cross-reference tables and cross-reference streams
+ * (PDF 1.5), including hybrid files where a table trailer points to a stream with XRefStm.  With -xref all the
+ * entries are listed: in use at a file offset, compressed in an object stream (with the index in the stream), or
+ * free.
+ *
+ * The object tree starts at the trailer (Root, Info, ...) or at the object given with -obj, and follows the
+ * references down to the given depth.  Objects in object streams are resolved by the parser like any other object.
+ * Each object is expanded once, later references to it are marked as already shown.  Parent references are not
+ * followed, to keep the tree readable.  With -stream the decoded data of the object given with -obj is printed if it
+ * is a stream.
+ *
+ * Run as: go run dump_structure.go [-xref] [-obj num] [-depth 3] [-stream] input.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+)
+
+const usage = "Usage: go run dump_structure.go [-xref] [-obj num] [-depth 3] [-stream] input.pdf\n"
+
+// Maximum length of the one line value of an array or string.
+const maxValueLength = 80
+
+// xrefEntry is an entry of a cross-reference section.
+type xrefEntry struct {
+	objectNumber int64
+	generation   int64
+	kind         string // "n": in use, "c": compressed in an object stream, "f": free.
+	offset       int64  // File offset, or the object stream number for compressed objects.
+	index        int64  // Index in the object stream.
+}
+
+// xrefSection is a cross-reference table or stream with its trailer.
+type xrefSection struct {
+	offset        int64
+	streamNumber  int64 // Object number of the cross-reference stream, 0 for a table.
+	entries       []xrefEntry
+	trailer       *pdfcore.PdfObjectDictionary
+	fromXRefStm   bool // Stream referenced by the XRefStm entry of a hybrid file.
+	previousError error
+}
+
+// treePrinter prints the tree of objects, expanding each indirect object once.
+type treePrinter struct {
+	parser  *pdfcore.PdfParser
+	visited map[int64]bool
+}
+
+func main() {
+	listXrefs := false
+	objNum := 0
+	depth := 0
+	printStream := false
+	flag.BoolVar(&listXrefs, "xref", false, "List all cross-reference entries")
+	flag.IntVar(&objNum, "obj", 0, "Object to dump (default: the trailer)")
+	flag.IntVar(&depth, "depth", 3, "Depth of references to follow")
+	flag.BoolVar(&printStream, "stream", false, "Print the decoded stream data of the object")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	err := dumpStructure(inputPath, listXrefs, int64(objNum), depth, printStream)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func dumpStructure(inputPath string, listXrefs bool, objNum int64, depth int, printStream bool) error {
+	data, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	parser, err := pdfcore.NewParser(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := parser.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := parser.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	printer := &treePrinter{parser: parser, visited: map[int64]bool{}}
+
+	if objNum > 0 {
+		obj, err := parser.LookupByNumber(int(objNum))
+		if err != nil {
+			return err
+		}
+		printer.printNode(fmt.Sprintf("Object %d", objNum), obj, 0, depth)
+
+		if stream, ok := obj.(*pdfcore.PdfObjectStream); ok && printStream {
+			decoded, err := pdfcore.DecodeStream(stream)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Decoded stream data (%d bytes):\n%s\n", len(decoded), decoded)
+		}
+		return nil
+	}
+
+	fmt.Printf("File: %s (%d bytes)\n", inputPath, len(data))
+
+	sections, err := readXrefSections(parser, data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nCross-reference sections (newest first): %d\n", len(sections))
+	for i, section := range sections {
+		form := "table"
+		if section.streamNumber > 0 {
+			form = fmt.Sprintf("stream (object %d)", section.streamNumber)
+			if section.fromXRefStm {
+				form += " referenced by XRefStm"
+			}
+		}
+		fmt.Printf("Section %d at offset %d: %s, %d entries\n", i+1, section.offset, form, len(section.entries))
+		fmt.Printf("  Trailer: %s\n", oneLine(section.trailer))
+		if listXrefs {
+			for _, e := range section.entries {
+				switch e.kind {
+				case "n":
+					fmt.Printf("  %6d %5d  in use at offset %d\n", e.objectNumber, e.generation, e.offset)
+				case "c":
+					fmt.Printf("  %6d %5d  in object stream %d, index %d\n", e.objectNumber, e.generation, e.offset,
+						e.index)
+				case "f":
+					fmt.Printf("  %6d %5d  free\n", e.objectNumber, e.generation)
+				}
+			}
+		}
+		if section.previousError != nil {
+			fmt.Printf("  Cannot read the previous section: %v\n", section.previousError)
+		}
+	}
+
+	types, err := parser.Inspect()
+	if err != nil {
+		return err
+	}
+	typeNames := []string{}
+	for name := range types {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	fmt.Printf("\nObject types:\n")
+	for _, name := range typeNames {
+		fmt.Printf("  %s: %d\n", name, types[name])
+	}
+
+	fmt.Printf("\nObject tree:\n")
+	printer.printNode("Trailer", parser.GetTrailer(), 0, depth)
+
+	return nil
+}
+
+// Reads the cross-reference sections following the chain from startxref.
+func readXrefSections(parser *pdfcore.PdfParser, data []byte) ([]*xrefSection, error) {
+	idx := bytes.LastIndex(data, []byte("startxref"))
+	if idx < 0 {
+		return nil, errors.New("Missing startxref")
+	}
+	fields := strings.Fields(string(data[idx+len("startxref"):]))
+	if len(fields) == 0 {
+		return nil, errors.New("Invalid startxref")
+	}
+	offset, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("Invalid startxref")
+	}
+
+	sections := []*xrefSection{}
+	visited := map[int64]bool{}
+	// Offsets to read: the previous section, and for hybrid files first the XRefStm stream.
+	pending := []int64{offset}
+	fromXRefStm := map[int64]bool{}
+	for len(pending) > 0 {
+		offset := pending[0]
+		pending = pending[1:]
+		if visited[offset] {
+			continue
+		}
+		visited[offset] = true
+
+		section, err := readXrefSection(parser, data, offset)
+		if err != nil {
+			if len(sections) == 0 {
+				return nil, err
+			}
+			sections[len(sections)-1].previousError = err
+			break
+		}
+		section.fromXRefStm = fromXRefStm[offset]
+		sections = append(sections, section)
+
+		if xrefStm, ok := section.trailer.Get("XRefStm").(*pdfcore.PdfObjectInteger); ok {
+			fromXRefStm[int64(*xrefStm)] = true
+			pending = append(pending, int64(*xrefStm))
+		}
+		if prev, ok := section.trailer.Get("Prev").(*pdfcore.PdfObjectInteger); ok {
+			pending = append(pending, int64(*prev))
+		}
+	}
+
+	return sections, nil
+}
+
+// Reads the cross-reference table or stream at the offset.
+func readXrefSection(parser *pdfcore.PdfParser, data []byte, offset int64) (*xrefSection, error) {
+	if offset < 0 || offset >= int64(len(data)) {
+		return nil, fmt.Errorf("Offset %d outside the file", offset)
+	}
+	start := offset
+	for start < int64(len(data)) && strings.IndexByte(" \t\r\n", data[start]) >= 0 {
+		start++
+	}
+	if bytes.HasPrefix(data[start:], []byte("xref")) {
+		return readXrefTable(parser, data, offset, start+int64(len("xref")))
+	}
+	return readXrefStream(parser, offset)
+}
+
+// Reads a classic cross-reference table: subsections with the first object number and count, then the entries
+// (offset, generation, n or f), followed by the trailer dictionary.
+func readXrefTable(parser *pdfcore.PdfParser, data []byte, offset, start int64) (*xrefSection, error) {
+	trailerPos := bytes.Index(data[start:], []byte("trailer"))
+	if trailerPos < 0 {
+		return nil, fmt.Errorf("Missing trailer for the table at offset %d", offset)
+	}
+	trailerPos += int(start)
+
+	section := &xrefSection{offset: offset}
+	fields := strings.Fields(string(data[start:trailerPos]))
+	for i := 0; i+1 < len(fields); {
+		first, err1 := strconv.ParseInt(fields[i], 10, 64)
+		count, err2 := strconv.ParseInt(fields[i+1], 10, 64)
+		if err1 != nil || err2 != nil || i+2+3*int(count) > len(fields) {
+			return nil, fmt.Errorf("Invalid table at offset %d", offset)
+		}
+		i += 2
+		for j := int64(0); j < count; j++ {
+			entryOffset, _ := strconv.ParseInt(fields[i], 10, 64)
+			generation, _ := strconv.ParseInt(fields[i+1], 10, 64)
+			section.entries = append(section.entries, xrefEntry{objectNumber: first + j, generation: generation,
+				kind: fields[i+2], offset: entryOffset})
+			i += 3
+		}
+	}
+
+	dictPos := bytes.Index(data[trailerPos:], []byte("<<"))
+	if dictPos < 0 {
+		return nil, fmt.Errorf("Invalid trailer for the table at offset %d", offset)
+	}
+	parser.SetFileOffset(int64(trailerPos + dictPos))
+	trailer, err := parser.ParseDict()
+	if err != nil {
+		return nil, err
+	}
+	section.trailer = trailer
+
+	return section, nil
+}
+
+// Reads a cross-reference stream.  The stream dictionary is the trailer.  The entries are binary, with the field
+// widths given by W (type, field 2, field 3), for the object number ranges in Index (default: 0 to Size).
+func readXrefStream(parser *pdfcore.PdfParser, offset int64) (*xrefSection, error) {
+	parser.SetFileOffset(offset)
+	obj, err := parser.ParseIndirectObject()
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := obj.(*pdfcore.PdfObjectStream)
+	if !ok {
+		return nil, fmt.Errorf("No cross-reference table or stream at offset %d", offset)
+	}
+
+	section := &xrefSection{offset: offset, streamNumber: stream.ObjectNumber, trailer: stream.PdfObjectDictionary}
+
+	widths, err := getIntegers(stream.PdfObjectDictionary.Get("W"))
+	if err != nil || len(widths) != 3 {
+		return nil, fmt.Errorf("Invalid W in the cross-reference stream at offset %d", offset)
+	}
+	index, err := getIntegers(stream.PdfObjectDictionary.Get("Index"))
+	if err != nil {
+		size, ok := pdfcore.TraceToDirectObject(stream.PdfObjectDictionary.Get("Size")).(*pdfcore.PdfObjectInteger)
+		if !ok {
+			return nil, fmt.Errorf("Missing Size in the cross-reference stream at offset %d", offset)
+		}
+		index = []int64{0, int64(*size)}
+	}
+
+	decoded, err := pdfcore.DecodeStream(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	entrySize := int(widths[0] + widths[1] + widths[2])
+	pos := 0
+	for i := 0; i+1 < len(index); i += 2 {
+		for objNum := index[i]; objNum < index[i]+index[i+1]; objNum++ {
+			if pos+entrySize > len(decoded) {
+				return nil, fmt.Errorf("Cross-reference stream at offset %d too short", offset)
+			}
+			// The type defaults to 1 if its width is 0.
+			fields := [3]int64{1, 0, 0}
+			for f, width := range widths {
+				if width == 0 {
+					continue
+				}
+				v := int64(0)
+				for _, b := range decoded[pos : pos+int(width)] {
+					v = v<<8 | int64(b)
+				}
+				fields[f] = v
+				pos += int(width)
+			}
+
+			entry := xrefEntry{objectNumber: objNum}
+			switch fields[0] {
+			case 0:
+				entry.kind = "f"
+				entry.generation = fields[2]
+			case 1:
+				entry.kind = "n"
+				entry.offset = fields[1]
+				entry.generation = fields[2]
+			case 2:
+				entry.kind = "c"
+				entry.offset = fields[1]
+				entry.index = fields[2]
+			default:
+				// Unknown types are to be ignored (treated as null references).
+				continue
+			}
+			section.entries = append(section.entries, entry)
+		}
+	}
+
+	return section, nil
+}
+
+// Returns the integers of an array.
+func getIntegers(obj pdfcore.PdfObject) ([]int64, error) {
+	arr, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return nil, errors.New("Not an array")
+	}
+	vals := []int64{}
+	for _, v := range *arr {
+		i, ok := pdfcore.TraceToDirectObject(v).(*pdfcore.PdfObjectInteger)
+		if !ok {
+			return nil, errors.New("Not an integer")
+		}
+		vals = append(vals, int64(*i))
+	}
+	return vals, nil
+}
+
+// Prints the object with the label, following references until the depth is exhausted.
+func (p *treePrinter) printNode(label string, obj pdfcore.PdfObject, indent, depth int) {
+	prefix := strings.Repeat("  ", indent)
+
+	// Resolve references to the indirect objects.
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		if depth <= 0 || label == "/Parent" {
+			fmt.Printf("%s%s: %d %d R\n", prefix, label, ref.ObjectNumber, ref.GenerationNumber)
+			return
+		}
+		resolved, err := p.parser.LookupByReference(*ref)
+		if err != nil {
+			fmt.Printf("%s%s: %d %d R (error: %v)\n", prefix, label, ref.ObjectNumber, ref.GenerationNumber, err)
+			return
+		}
+		obj = resolved
+		depth--
+	}
+
+	switch t := obj.(type) {
+	case *pdfcore.PdfIndirectObject:
+		fmt.Printf("%s%s: %d %d obj %s\n", prefix, label, t.ObjectNumber, t.GenerationNumber, summary(t.PdfObject))
+		if p.visited[t.ObjectNumber] {
+			fmt.Printf("%s  (already shown)\n", prefix)
+			return
+		}
+		p.visited[t.ObjectNumber] = true
+		p.printChildren(t.PdfObject, indent+1, depth)
+	case *pdfcore.PdfObjectStream:
+		fmt.Printf("%s%s: %d %d obj %s\n", prefix, label, t.ObjectNumber, t.GenerationNumber, summary(t))
+		if p.visited[t.ObjectNumber] {
+			fmt.Printf("%s  (already shown)\n", prefix)
+			return
+		}
+		p.visited[t.ObjectNumber] = true
+		p.printChildren(t.PdfObjectDictionary, indent+1, depth)
+	case *pdfcore.PdfObjectDictionary, *pdfcore.PdfObjectArray:
+		fmt.Printf("%s%s: %s\n", prefix, label, summary(t))
+		p.printChildren(t, indent+1, depth)
+	default:
+		fmt.Printf("%s%s: %s\n", prefix, label, oneLine(obj))
+	}
+}
+
+// Prints the entries of a dictionary or the elements of an array.  Arrays of direct values are printed on one line
+// by printNode.
+func (p *treePrinter) printChildren(obj pdfcore.PdfObject, indent, depth int) {
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			p.printNode("/"+string(key), t.Get(key), indent, depth)
+		}
+	case *pdfcore.PdfObjectArray:
+		if isSimpleArray(t) {
+			fmt.Printf("%s%s\n", strings.Repeat("  ", indent), oneLine(t))
+			return
+		}
+		for i, v := range *t {
+			p.printNode(fmt.Sprintf("[%d]", i), v, indent, depth)
+		}
+	}
+}
+
+// Returns true if the array contains no dictionaries, arrays or references.
+func isSimpleArray(arr *pdfcore.PdfObjectArray) bool {
+	for _, v := range *arr {
+		switch v.(type) {
+		case *pdfcore.PdfObjectDictionary, *pdfcore.PdfObjectArray, *pdfcore.PdfObjectReference,
+			*pdfcore.PdfIndirectObject, *pdfcore.PdfObjectStream:
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a short description of the object: the type, and for dictionaries and streams the Type and Subtype.
+func summary(obj pdfcore.PdfObject) string {
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectDictionary:
+		return "Dictionary" + dictTypes(t)
+	case *pdfcore.PdfObjectStream:
+		s := "Stream" + dictTypes(t.PdfObjectDictionary)
+		if filter := t.PdfObjectDictionary.Get("Filter"); filter != nil {
+			s += " " + oneLine(filter)
+		}
+		return s + fmt.Sprintf(", %d bytes", len(t.Stream))
+	case *pdfcore.PdfObjectArray:
+		return fmt.Sprintf("Array (%d elements)", len(*t))
+	}
+	return oneLine(obj)
+}
+
+// Returns the Type and Subtype of the dictionary, e.g. " /Font /TrueType".
+func dictTypes(dict *pdfcore.PdfObjectDictionary) string {
+	s := ""
+	for _, key := range []pdfcore.PdfObjectName{"Type", "Subtype", "S", "FT"} {
+		if name, ok := pdfcore.TraceToDirectObject(dict.Get(key)).(*pdfcore.PdfObjectName); ok {
+			s += " /" + string(*name)
+		}
+	}
+	return s
+}
+
+// Returns the object as written in the file, shortened to a line.
+func oneLine(obj pdfcore.PdfObject) string {
+	if obj == nil {
+		return "null"
+	}
+	s := strings.Join(strings.Fields(obj.DefaultWriteString()), " ")
+	if len(s) > maxValueLength {
+		s = s[:maxValueLength-3] + "..."
+	}
+	return s
+}
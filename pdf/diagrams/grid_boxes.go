@@ -0,0 +1,386 @@
+/*
+ * Draw a periodic table of the elements: a fixed grid of colored boxes, each with the atomic number, the symbol and
+ * the name of an element, colored by the category of the element.
+ *
+ * The boxes are not a flowing table: every element has a row and a column in the grid, and its box is drawn at the
+ * absolute position computed from them.  Cells without an element are simply left empty, like the gap between the
+ * main table and the lanthanide and actinide rows below it, or the space at the top which holds the legend.  The box
+ * size is chosen so that the grid fills the page.  Two elements in the same cell, or outside the grid, are an error.
+ *
+ * Run as: go run grid_boxes.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const (
+	pageMargin  = 36.0 // Page margin on all sides.
+	titleHeight = 30.0 // Height of the title above the grid.
+	gridRows    = 10   // Rows of the grid: 7 periods, a gap and the lanthanide and actinide rows.
+	gridCols    = 18   // Columns of the grid: the 18 groups.
+	cellGap     = 2.0  // Space between neighbouring boxes.
+)
+
+var (
+	textColor   = creator.ColorRGBFrom8bit(30, 30, 30)
+	borderColor = creator.ColorRGBFrom8bit(90, 90, 90)
+)
+
+// Categories of the elements.
+const (
+	alkaliMetal = iota
+	alkalineEarthMetal
+	transitionMetal
+	postTransitionMetal
+	metalloid
+	nonmetal
+	nobleGas
+	lanthanide
+	actinide
+	unknownProperties
+)
+
+// categoryStyle is the name shown in the legend and the fill color of a category.
+type categoryStyle struct {
+	Name  string
+	Color creator.Color
+}
+
+var categories = []categoryStyle{
+	alkaliMetal:         {"Alkali metal", creator.ColorRGBFrom8bit(255, 166, 140)},
+	alkalineEarthMetal:  {"Alkaline earth metal", creator.ColorRGBFrom8bit(255, 214, 153)},
+	transitionMetal:     {"Transition metal", creator.ColorRGBFrom8bit(255, 232, 200)},
+	postTransitionMetal: {"Post-transition metal", creator.ColorRGBFrom8bit(204, 220, 204)},
+	metalloid:           {"Metalloid", creator.ColorRGBFrom8bit(204, 204, 153)},
+	nonmetal:            {"Reactive nonmetal", creator.ColorRGBFrom8bit(170, 222, 170)},
+	nobleGas:            {"Noble gas", creator.ColorRGBFrom8bit(192, 212, 255)},
+	lanthanide:          {"Lanthanide", creator.ColorRGBFrom8bit(255, 191, 230)},
+	actinide:            {"Actinide", creator.ColorRGBFrom8bit(255, 153, 204)},
+	unknownProperties:   {"Unknown properties", creator.ColorRGBFrom8bit(225, 225, 225)},
+}
+
+// gridBox is a box of the grid: an element, or a reference to the lanthanide or actinide row when Symbol is empty.
+// Row and Col are the position in the grid, from 1.
+type gridBox struct {
+	Number   int
+	Symbol   string
+	Name     string
+	Category int
+	Row, Col int
+}
+
+var elements = []gridBox{
+	{1, "H", "Hydrogen", nonmetal, 1, 1},
+	{2, "He", "Helium", nobleGas, 1, 18},
+	{3, "Li", "Lithium", alkaliMetal, 2, 1},
+	{4, "Be", "Beryllium", alkalineEarthMetal, 2, 2},
+	{5, "B", "Boron", metalloid, 2, 13},
+	{6, "C", "Carbon", nonmetal, 2, 14},
+	{7, "N", "Nitrogen", nonmetal, 2, 15},
+	{8, "O", "Oxygen", nonmetal, 2, 16},
+	{9, "F", "Fluorine", nonmetal, 2, 17},
+	{10, "Ne", "Neon", nobleGas, 2, 18},
+	{11, "Na", "Sodium", alkaliMetal, 3, 1},
+	{12, "Mg", "Magnesium", alkalineEarthMetal, 3, 2},
+	{13, "Al", "Aluminium", postTransitionMetal, 3, 13},
+	{14, "Si", "Silicon", metalloid, 3, 14},
+	{15, "P", "Phosphorus", nonmetal, 3, 15},
+	{16, "S", "Sulfur", nonmetal, 3, 16},
+	{17, "Cl", "Chlorine", nonmetal, 3, 17},
+	{18, "Ar", "Argon", nobleGas, 3, 18},
+	{19, "K", "Potassium", alkaliMetal, 4, 1},
+	{20, "Ca", "Calcium", alkalineEarthMetal, 4, 2},
+	{21, "Sc", "Scandium", transitionMetal, 4, 3},
+	{22, "Ti", "Titanium", transitionMetal, 4, 4},
+	{23, "V", "Vanadium", transitionMetal, 4, 5},
+	{24, "Cr", "Chromium", transitionMetal, 4, 6},
+	{25, "Mn", "Manganese", transitionMetal, 4, 7},
+	{26, "Fe", "Iron", transitionMetal, 4, 8},
+	{27, "Co", "Cobalt", transitionMetal, 4, 9},
+	{28, "Ni", "Nickel", transitionMetal, 4, 10},
+	{29, "Cu", "Copper", transitionMetal, 4, 11},
+	{30, "Zn", "Zinc", transitionMetal, 4, 12},
+	{31, "Ga", "Gallium", postTransitionMetal, 4, 13},
+	{32, "Ge", "Germanium", metalloid, 4, 14},
+	{33, "As", "Arsenic", metalloid, 4, 15},
+	{34, "Se", "Selenium", nonmetal, 4, 16},
+	{35, "Br", "Bromine", nonmetal, 4, 17},
+	{36, "Kr", "Krypton", nobleGas, 4, 18},
+	{37, "Rb", "Rubidium", alkaliMetal, 5, 1},
+	{38, "Sr", "Strontium", alkalineEarthMetal, 5, 2},
+	{39, "Y", "Yttrium", transitionMetal, 5, 3},
+	{40, "Zr", "Zirconium", transitionMetal, 5, 4},
+	{41, "Nb", "Niobium", transitionMetal, 5, 5},
+	{42, "Mo", "Molybdenum", transitionMetal, 5, 6},
+	{43, "Tc", "Technetium", transitionMetal, 5, 7},
+	{44, "Ru", "Ruthenium", transitionMetal, 5, 8},
+	{45, "Rh", "Rhodium", transitionMetal, 5, 9},
+	{46, "Pd", "Palladium", transitionMetal, 5, 10},
+	{47, "Ag", "Silver", transitionMetal, 5, 11},
+	{48, "Cd", "Cadmium", transitionMetal, 5, 12},
+	{49, "In", "Indium", postTransitionMetal, 5, 13},
+	{50, "Sn", "Tin", postTransitionMetal, 5, 14},
+	{51, "Sb", "Antimony", metalloid, 5, 15},
+	{52, "Te", "Tellurium", metalloid, 5, 16},
+	{53, "I", "Iodine", nonmetal, 5, 17},
+	{54, "Xe", "Xenon", nobleGas, 5, 18},
+	{55, "Cs", "Caesium", alkaliMetal, 6, 1},
+	{56, "Ba", "Barium", alkalineEarthMetal, 6, 2},
+	{57, "La", "Lanthanum", lanthanide, 9, 3},
+	{58, "Ce", "Cerium", lanthanide, 9, 4},
+	{59, "Pr", "Praseodymium", lanthanide, 9, 5},
+	{60, "Nd", "Neodymium", lanthanide, 9, 6},
+	{61, "Pm", "Promethium", lanthanide, 9, 7},
+	{62, "Sm", "Samarium", lanthanide, 9, 8},
+	{63, "Eu", "Europium", lanthanide, 9, 9},
+	{64, "Gd", "Gadolinium", lanthanide, 9, 10},
+	{65, "Tb", "Terbium", lanthanide, 9, 11},
+	{66, "Dy", "Dysprosium", lanthanide, 9, 12},
+	{67, "Ho", "Holmium", lanthanide, 9, 13},
+	{68, "Er", "Erbium", lanthanide, 9, 14},
+	{69, "Tm", "Thulium", lanthanide, 9, 15},
+	{70, "Yb", "Ytterbium", lanthanide, 9, 16},
+	{71, "Lu", "Lutetium", lanthanide, 9, 17},
+	{72, "Hf", "Hafnium", transitionMetal, 6, 4},
+	{73, "Ta", "Tantalum", transitionMetal, 6, 5},
+	{74, "W", "Tungsten", transitionMetal, 6, 6},
+	{75, "Re", "Rhenium", transitionMetal, 6, 7},
+	{76, "Os", "Osmium", transitionMetal, 6, 8},
+	{77, "Ir", "Iridium", transitionMetal, 6, 9},
+	{78, "Pt", "Platinum", transitionMetal, 6, 10},
+	{79, "Au", "Gold", transitionMetal, 6, 11},
+	{80, "Hg", "Mercury", transitionMetal, 6, 12},
+	{81, "Tl", "Thallium", postTransitionMetal, 6, 13},
+	{82, "Pb", "Lead", postTransitionMetal, 6, 14},
+	{83, "Bi", "Bismuth", postTransitionMetal, 6, 15},
+	{84, "Po", "Polonium", postTransitionMetal, 6, 16},
+	{85, "At", "Astatine", postTransitionMetal, 6, 17},
+	{86, "Rn", "Radon", nobleGas, 6, 18},
+	{87, "Fr", "Francium", alkaliMetal, 7, 1},
+	{88, "Ra", "Radium", alkalineEarthMetal, 7, 2},
+	{89, "Ac", "Actinium", actinide, 10, 3},
+	{90, "Th", "Thorium", actinide, 10, 4},
+	{91, "Pa", "Protactinium", actinide, 10, 5},
+	{92, "U", "Uranium", actinide, 10, 6},
+	{93, "Np", "Neptunium", actinide, 10, 7},
+	{94, "Pu", "Plutonium", actinide, 10, 8},
+	{95, "Am", "Americium", actinide, 10, 9},
+	{96, "Cm", "Curium", actinide, 10, 10},
+	{97, "Bk", "Berkelium", actinide, 10, 11},
+	{98, "Cf", "Californium", actinide, 10, 12},
+	{99, "Es", "Einsteinium", actinide, 10, 13},
+	{100, "Fm", "Fermium", actinide, 10, 14},
+	{101, "Md", "Mendelevium", actinide, 10, 15},
+	{102, "No", "Nobelium", actinide, 10, 16},
+	{103, "Lr", "Lawrencium", actinide, 10, 17},
+	{104, "Rf", "Rutherfordium", transitionMetal, 7, 4},
+	{105, "Db", "Dubnium", transitionMetal, 7, 5},
+	{106, "Sg", "Seaborgium", transitionMetal, 7, 6},
+	{107, "Bh", "Bohrium", transitionMetal, 7, 7},
+	{108, "Hs", "Hassium", transitionMetal, 7, 8},
+	{109, "Mt", "Meitnerium", unknownProperties, 7, 9},
+	{110, "Ds", "Darmstadtium", unknownProperties, 7, 10},
+	{111, "Rg", "Roentgenium", unknownProperties, 7, 11},
+	{112, "Cn", "Copernicium", unknownProperties, 7, 12},
+	{113, "Nh", "Nihonium", unknownProperties, 7, 13},
+	{114, "Fl", "Flerovium", unknownProperties, 7, 14},
+	{115, "Mc", "Moscovium", unknownProperties, 7, 15},
+	{116, "Lv", "Livermorium", unknownProperties, 7, 16},
+	{117, "Ts", "Tennessine", unknownProperties, 7, 17},
+	{118, "Og", "Oganesson", unknownProperties, 7, 18},
+
+	// The lanthanides and actinides are shown below the main table; these boxes mark their place in it.
+	{0, "", "57-71", lanthanide, 6, 3},
+	{0, "", "89-103", actinide, 7, 3},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run grid_boxes.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	err := drawPeriodicTable(elements, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func drawPeriodicTable(boxes []gridBox, outputPath string) error {
+	// Check that every box is in the grid and no two boxes share a cell.
+	occupied := map[[2]int]gridBox{}
+	for _, box := range boxes {
+		if box.Row < 1 || box.Row > gridRows || box.Col < 1 || box.Col > gridCols {
+			return fmt.Errorf("Box %s at row %d, column %d is outside the grid", box.label(), box.Row, box.Col)
+		}
+		cell := [2]int{box.Row, box.Col}
+		if other, ok := occupied[cell]; ok {
+			return fmt.Errorf("Boxes %s and %s are both at row %d, column %d", other.label(), box.label(), box.Row,
+				box.Col)
+		}
+		occupied[cell] = box
+	}
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSize{creator.PageSizeA4[1], creator.PageSizeA4[0]})
+	c.NewPage()
+
+	title := creator.NewParagraph("Periodic Table of the Elements")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(16)
+	title.SetColor(textColor)
+	title.SetPos(pageMargin, pageMargin)
+	err := c.Draw(title)
+	if err != nil {
+		return err
+	}
+
+	// The largest square boxes for which the grid fits the page, and the grid centered horizontally.
+	availWidth := c.Width() - 2*pageMargin
+	availHeight := c.Height() - 2*pageMargin - titleHeight
+	size := math.Min((availWidth-(gridCols-1)*cellGap)/gridCols, (availHeight-(gridRows-1)*cellGap)/gridRows)
+	g := grid{
+		c:       c,
+		size:    size,
+		originX: pageMargin + (availWidth-gridCols*size-(gridCols-1)*cellGap)/2,
+		originY: pageMargin + titleHeight,
+	}
+
+	for _, box := range boxes {
+		err := g.drawBox(box)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The legend goes into the empty cells at the top of the grid, between groups 2 and 13.
+	err = g.drawLegend(1, 3)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d boxes in a grid of %d x %d cells, %d cells empty, box size %.1f points\n", len(boxes), gridRows,
+		gridCols, gridRows*gridCols-len(occupied), size)
+
+	return c.WriteToFile(outputPath)
+}
+
+// label returns the symbol of an element box, or the label of a reference box.
+func (box gridBox) label() string {
+	if box.Symbol == "" {
+		return box.Name
+	}
+	return box.Symbol
+}
+
+// grid positions boxes of the size by row and column, with the upper left corner of the grid at the origin.
+type grid struct {
+	c                *creator.Creator
+	size             float64
+	originX, originY float64
+}
+
+// cellPos returns the position of the upper left corner of the cell at row and column (from 1).
+func (g grid) cellPos(row, col int) (float64, float64) {
+	return g.originX + float64(col-1)*(g.size+cellGap), g.originY + float64(row-1)*(g.size+cellGap)
+}
+
+// drawBox draws the box in its cell: the atomic number at the top left, the symbol in the middle and the name below
+// it.  A reference box shows the range of atomic numbers it stands for.
+func (g grid) drawBox(box gridBox) error {
+	x, y := g.cellPos(box.Row, box.Col)
+
+	rect := creator.NewRectangle(x, y, g.size, g.size)
+	rect.SetFillColor(categories[box.Category].Color)
+	rect.SetBorderColor(borderColor)
+	rect.SetBorderWidth(0.5)
+	err := g.c.Draw(rect)
+	if err != nil {
+		return err
+	}
+
+	if box.Symbol == "" {
+		p := newBoxText(box.Name, fonts.NewFontHelveticaBold(), 0.16*g.size, g.size)
+		p.SetPos(x, y+(g.size-p.Height())/2)
+		return g.c.Draw(p)
+	}
+
+	number := newBoxText(fmt.Sprintf("%d", box.Number), fonts.NewFontHelvetica(), 0.17*g.size, g.size-4)
+	number.SetTextAlignment(creator.TextAlignmentLeft)
+	number.SetPos(x+2, y+2)
+	err = g.c.Draw(number)
+	if err != nil {
+		return err
+	}
+
+	symbol := newBoxText(box.Symbol, fonts.NewFontHelveticaBold(), 0.38*g.size, g.size)
+	symbol.SetPos(x, y+0.24*g.size)
+	err = g.c.Draw(symbol)
+	if err != nil {
+		return err
+	}
+
+	name := newBoxText(box.Name, fonts.NewFontHelvetica(), 0.125*g.size, g.size)
+	name.SetPos(x, y+0.73*g.size)
+	return g.c.Draw(name)
+}
+
+// drawLegend draws a swatch and the name of each category, in three columns from the cell at row and column.
+func (g grid) drawLegend(row, col int) error {
+	x0, y0 := g.cellPos(row, col)
+	const columns = 3
+	rows := (len(categories) + columns - 1) / columns
+	columnWidth := 10 * (g.size + cellGap) / columns
+	rowHeight := 0.3 * g.size
+
+	for i, category := range categories {
+		x := x0 + float64(i/rows)*columnWidth
+		y := y0 + float64(i%rows)*rowHeight
+
+		swatch := creator.NewRectangle(x, y, 0.6*rowHeight, 0.6*rowHeight)
+		swatch.SetFillColor(category.Color)
+		swatch.SetBorderColor(borderColor)
+		swatch.SetBorderWidth(0.5)
+		err := g.c.Draw(swatch)
+		if err != nil {
+			return err
+		}
+
+		p := newBoxText(category.Name, fonts.NewFontHelvetica(), 0.5*rowHeight, columnWidth-rowHeight)
+		p.SetTextAlignment(creator.TextAlignmentLeft)
+		p.SetPos(x+rowHeight, y)
+		err = g.c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newBoxText returns a paragraph with the text centered in the width.
+func newBoxText(text string, font fonts.Font, fontSize, width float64) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetColor(textColor)
+	p.SetTextAlignment(creator.TextAlignmentCenter)
+	p.SetWidth(width)
+	return p
+}
@@ -0,0 +1,452 @@
+/*
+ * Draw an organization chart from a tree of people: a box for each person with the name and job title, and connector
+ * lines from each manager to the direct reports.
+ *
+ * The boxes are positioned automatically.  The width of each subtree is computed bottom up, from the widths of the
+ * subtrees of the children, and the children are placed side by side within the width of the subtree with their
+ * parent centered above them, so subtrees never overlap.  Children that have no reports of their own are stacked
+ * vertically below their parent instead, which keeps wide teams narrow.
+ *
+ * Trees that are too large for a page are handled in one of two ways (-overflow):
+ * - scale: the whole chart is drawn on one page, scaled down to fit.
+ * - pages: the chart is drawn with as many levels as fit on the page height.  The people whose reports do not fit
+ *   are marked "continued on page N", and their subtrees are drawn on additional pages, starting with them at the
+ *   top.  Pages that are too wide are scaled down to fit the page width.
+ *
+ * Run as: go run org_chart.go [-overflow scale|pages] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run org_chart.go [-overflow scale|pages] output.pdf\n"
+
+const (
+	pageMargin   = 36.0  // Page margin on all sides.
+	titleHeight  = 34.0  // Height of the title above the chart.
+	boxWidth     = 120.0 // Size of the boxes.
+	boxHeight    = 40.0
+	levelGap     = 26.0 // Vertical space between the levels, with the connectors.
+	levelHeight  = boxHeight + levelGap
+	siblingGap   = 12.0 // Horizontal space between neighbouring subtrees.
+	stackIndent  = 14.0 // Indent of stacked children from the left edge of their parent.
+	nameFontSize = 8.5
+	roleFontSize = 7.5
+)
+
+var (
+	boxFill   = creator.ColorRGBFrom8bit(236, 242, 250)
+	boxBorder = creator.ColorRGBFrom8bit(52, 101, 164)
+	lineColor = creator.ColorRGBFrom8bit(120, 120, 120)
+	textColor = creator.ColorRGBFrom8bit(30, 30, 30)
+	noteColor = creator.ColorRGBFrom8bit(180, 60, 40)
+)
+
+// orgNode is a person in the organization, with the direct reports as children.
+type orgNode struct {
+	Name     string
+	Title    string
+	Children []*orgNode
+}
+
+// n returns a node with the children, for defining the tree compactly.
+func n(name, title string, children ...*orgNode) *orgNode {
+	return &orgNode{Name: name, Title: title, Children: children}
+}
+
+var organization = n("Helen Morgan", "Chief Executive Officer",
+	n("David Chen", "Chief Technology Officer",
+		n("Priya Nair", "Engineering Manager",
+			n("Tom Fischer", "Team Lead, Platform",
+				n("Ana Lopez", "Software Engineer"),
+				n("Kenji Sato", "Software Engineer"),
+				n("Lena Novak", "Site Reliability Engineer"),
+				n("Omar Haddad", "Software Engineer"),
+				n("Julia Berg", "Software Engineer"),
+			),
+			n("Grace Kim", "Team Lead, Mobile",
+				n("Ravi Patel", "iOS Developer"),
+				n("Sofia Rossi", "Android Developer"),
+			),
+		),
+		n("Mark Evans", "QA Manager",
+			n("Nina Ivanova", "Test Engineer"),
+			n("Paul Dubois", "Test Engineer"),
+		),
+	),
+	n("Laura Smith", "Chief Financial Officer",
+		n("Ben Walker", "Controller",
+			n("Chloe Martin", "Accountant"),
+			n("Ethan Brown", "Accountant"),
+		),
+		n("Maria Garcia", "Payroll Specialist"),
+	),
+	n("Samuel Okafor", "VP Sales",
+		n("Emma Wilson", "Sales Manager, EMEA",
+			n("Lucas Meyer", "Account Executive"),
+			n("Isabel Costa", "Account Executive"),
+			n("Hugo Lambert", "Sales Engineer"),
+		),
+		n("Noah Taylor", "Sales Manager, Americas",
+			n("Olivia Clark", "Account Executive"),
+			n("Liam Young", "Account Executive"),
+		),
+	),
+	n("Rachel Adams", "Head of People",
+		n("Daniel Lee", "Recruiter"),
+		n("Zoe Turner", "HR Generalist"),
+	),
+)
+
+// placedNode is a node positioned on a page: the left edge of its box and its level from the top of the page, in
+// units of levelHeight.
+type placedNode struct {
+	node     *orgNode
+	x        float64
+	level    int
+	width    float64 // Width of the subtree.
+	stacked  bool    // The children are stacked vertically.
+	cut      bool    // The children do not fit on the page and are drawn on another page.
+	children []*placedNode
+}
+
+func main() {
+	overflow := ""
+	flag.StringVar(&overflow, "overflow", "pages", "Handling of charts too large for a page: scale (scale down to "+
+		"fit) or pages (continue subtrees on additional pages)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if overflow != "scale" && overflow != "pages" {
+		fmt.Printf("Error: -overflow must be scale or pages\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := drawOrgChart(organization, overflow == "pages", outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func drawOrgChart(root *orgNode, spill bool, outputPath string) error {
+	c := creator.New()
+	c.SetPageSize(creator.PageSize{creator.PageSizeA4[1], creator.PageSizeA4[0]})
+
+	availWidth := c.Width() - 2*pageMargin
+	availHeight := c.Height() - 2*pageMargin - titleHeight
+
+	// The number of levels that fit on a page, unlimited when scaling the chart to fit.
+	maxLevels := math.MaxInt32
+	if spill {
+		maxLevels = int((availHeight + levelGap) / levelHeight)
+	}
+
+	// Lay out the pages: the first page starts with the root, and every node whose children are cut starts a page
+	// of its own.  The page numbers of the continued nodes are known before drawing, as the pages are laid out in
+	// order.
+	pageRoots := []*placedNode{}
+	pageOf := map[*orgNode]int{}
+	fromPage := map[*orgNode]int{}
+	queue := []*orgNode{root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		placed := layoutTree(node, 0, maxLevels)
+		positionTree(placed, 0)
+		pageRoots = append(pageRoots, placed)
+		pageOf[node] = len(pageRoots)
+
+		for _, cut := range cutNodes(placed) {
+			queue = append(queue, cut.node)
+			fromPage[cut.node] = len(pageRoots)
+		}
+	}
+
+	for i, placed := range pageRoots {
+		c.NewPage()
+
+		title := "Organization chart"
+		if i > 0 {
+			title = fmt.Sprintf("Organization chart: %s (continued from page %d)", placed.node.Name,
+				fromPage[placed.node])
+		}
+		p := creator.NewParagraph(title)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(14)
+		p.SetColor(textColor)
+		p.SetPos(pageMargin, pageMargin)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+
+		// Scale the chart down to fit, and center it horizontally.
+		levels := maxLevel(placed) + 1
+		height := float64(levels)*levelHeight - levelGap
+		scale := math.Min(1, math.Min(availWidth/placed.width, availHeight/height))
+		originX := pageMargin + (availWidth-scale*placed.width)/2
+		originY := pageMargin + titleHeight
+
+		ch := chart{c: c, scale: scale, originX: originX, originY: originY, pageOf: pageOf}
+		err = ch.drawTree(placed)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Page %d: %s, %d levels, scale %.2f\n", i+1, placed.node.Name, levels, scale)
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// layoutTree computes the subtree widths of the node at level and its descendants, limited to maxLevels levels.
+// The children of a node are stacked if none of them has children of its own, and placed side by side otherwise.
+// Children that do not fit in the remaining levels are cut, except stacked children of the root of the page, which
+// are placed side by side instead as they would not fit on any page.
+func layoutTree(node *orgNode, level, maxLevels int) *placedNode {
+	p := &placedNode{node: node, level: level, width: boxWidth}
+	if len(node.Children) == 0 {
+		return p
+	}
+
+	leaves := true
+	for _, child := range node.Children {
+		if len(child.Children) > 0 {
+			leaves = false
+		}
+	}
+
+	switch {
+	case leaves && level+len(node.Children) < maxLevels:
+		p.stacked = true
+		p.width = boxWidth + stackIndent
+		for i, child := range node.Children {
+			p.children = append(p.children, &placedNode{node: child, level: level + 1 + i, width: boxWidth})
+		}
+	case (!leaves || level == 0) && level+1 < maxLevels:
+		childrenWidth := -siblingGap
+		for _, child := range node.Children {
+			placed := layoutTree(child, level+1, maxLevels)
+			p.children = append(p.children, placed)
+			childrenWidth += placed.width + siblingGap
+		}
+		p.width = math.Max(boxWidth, childrenWidth)
+	default:
+		p.cut = true
+	}
+	return p
+}
+
+// positionTree positions the boxes of the subtree with the left edge of the subtree at left.  The children are
+// centered in the width of the subtree, and a parent is centered above its children.
+func positionTree(p *placedNode, left float64) {
+	if p.stacked {
+		p.x = left
+		for _, child := range p.children {
+			child.x = left + stackIndent
+		}
+		return
+	}
+	if len(p.children) == 0 {
+		p.x = left + (p.width-boxWidth)/2
+		return
+	}
+
+	childrenWidth := -siblingGap
+	for _, child := range p.children {
+		childrenWidth += child.width + siblingGap
+	}
+	childLeft := left + (p.width-childrenWidth)/2
+	for _, child := range p.children {
+		positionTree(child, childLeft)
+		childLeft += child.width + siblingGap
+	}
+	p.x = (p.children[0].x + p.children[len(p.children)-1].x) / 2
+}
+
+// cutNodes returns the nodes of the subtree whose children are cut, in breadth first order.
+func cutNodes(p *placedNode) []*placedNode {
+	cut := []*placedNode{}
+	queue := []*placedNode{p}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node.cut {
+			cut = append(cut, node)
+		}
+		queue = append(queue, node.children...)
+	}
+	return cut
+}
+
+// maxLevel returns the deepest level of the subtree.
+func maxLevel(p *placedNode) int {
+	level := p.level
+	for _, child := range p.children {
+		if l := maxLevel(child); l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// chart draws the positioned nodes of a page, scaled by scale and offset by the origin.
+type chart struct {
+	c                *creator.Creator
+	scale            float64
+	originX, originY float64
+	pageOf           map[*orgNode]int
+}
+
+// pos returns the page position of a point (x, y) of the chart.
+func (ch chart) pos(x, y float64) (float64, float64) {
+	return ch.originX + ch.scale*x, ch.originY + ch.scale*y
+}
+
+// line draws a connector line between points of the chart.
+func (ch chart) line(x1, y1, x2, y2 float64) error {
+	px1, py1 := ch.pos(x1, y1)
+	px2, py2 := ch.pos(x2, y2)
+	l := creator.NewLine(px1, py1, px2, py2)
+	l.SetLineWidth(0.75 * ch.scale)
+	l.SetColor(lineColor)
+	return ch.c.Draw(l)
+}
+
+// drawTree draws the box of the node, the connectors to its children and the subtrees of the children.
+func (ch chart) drawTree(p *placedNode) error {
+	err := ch.drawBox(p)
+	if err != nil {
+		return err
+	}
+
+	top := float64(p.level) * levelHeight
+	bottom := top + boxHeight
+	center := p.x + boxWidth/2
+
+	if p.stacked {
+		// A vertical line down the left side, with a branch to each child.
+		x := p.x + stackIndent/2
+		lastMiddle := float64(p.children[len(p.children)-1].level)*levelHeight + boxHeight/2
+		err := ch.line(x, bottom, x, lastMiddle)
+		if err != nil {
+			return err
+		}
+		for _, child := range p.children {
+			middle := float64(child.level)*levelHeight + boxHeight/2
+			err := ch.line(x, middle, child.x, middle)
+			if err != nil {
+				return err
+			}
+			err = ch.drawBox(child)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(p.children) == 0 {
+		return nil
+	}
+
+	// A line down from the parent to a horizontal bar over the children, and a line down to each child.
+	barY := bottom + levelGap/2
+	err = ch.line(center, bottom, center, barY)
+	if err != nil {
+		return err
+	}
+	first := p.children[0].x + boxWidth/2
+	last := p.children[len(p.children)-1].x + boxWidth/2
+	if len(p.children) > 1 {
+		err := ch.line(first, barY, last, barY)
+		if err != nil {
+			return err
+		}
+	}
+	for _, child := range p.children {
+		childCenter := child.x + boxWidth/2
+		err := ch.line(childCenter, barY, childCenter, bottom+levelGap)
+		if err != nil {
+			return err
+		}
+		err = ch.drawTree(child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawBox draws the box of a node with the name and title, and a note below it if its children are cut.
+func (ch chart) drawBox(p *placedNode) error {
+	top := float64(p.level) * levelHeight
+	x, y := ch.pos(p.x, top)
+
+	rect := creator.NewRectangle(x, y, boxWidth*ch.scale, boxHeight*ch.scale)
+	rect.SetFillColor(boxFill)
+	rect.SetBorderColor(boxBorder)
+	rect.SetBorderWidth(ch.scale)
+	err := ch.c.Draw(rect)
+	if err != nil {
+		return err
+	}
+
+	name := newBoxText(p.node.Name, fonts.NewFontHelveticaBold(), nameFontSize*ch.scale, (boxWidth-6)*ch.scale)
+	role := newBoxText(p.node.Title, fonts.NewFontHelvetica(), roleFontSize*ch.scale, (boxWidth-6)*ch.scale)
+	textY := y + (boxHeight*ch.scale-name.Height()-role.Height())/2
+	name.SetPos(x+3*ch.scale, textY)
+	role.SetPos(x+3*ch.scale, textY+name.Height())
+	err = ch.c.Draw(name)
+	if err != nil {
+		return err
+	}
+	err = ch.c.Draw(role)
+	if err != nil {
+		return err
+	}
+
+	if !p.cut {
+		return nil
+	}
+	note := newBoxText(fmt.Sprintf("%d reports, continued on page %d", len(p.node.Children), ch.pageOf[p.node]),
+		fonts.NewFontHelveticaOblique(), roleFontSize*ch.scale, boxWidth*ch.scale)
+	note.SetColor(noteColor)
+	note.SetPos(x, y+(boxHeight+3)*ch.scale)
+	return ch.c.Draw(note)
+}
+
+// newBoxText returns a paragraph with the text centered in the width.
+func newBoxText(text string, font fonts.Font, fontSize, width float64) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetColor(textColor)
+	p.SetTextAlignment(creator.TextAlignmentCenter)
+	p.SetWidth(width)
+	return p
+}
@@ -0,0 +1,597 @@
+/*
+ * Compare the text of two versions of a PDF and create a PDF report of the differences.
+ *
+ * The text of each PDF is extracted line by line in reading order, as in pdf/extract/extract_layout.go: the glyph
+ * positions are computed from the content streams with text_layout.go, and the lines are ordered by cutting the pages
+ * into columns and blocks.  The white space within the lines is normalized, so changes in spacing are not reported.
+ *
+ * The lines of both versions are compared with the Myers diff algorithm, which finds the fewest deletions and
+ * additions turning the old text into the new text.  The report lists the changes with a few unchanged lines of
+ * context around them: deleted lines in red marked "-", added lines in green marked "+", and the unchanged lines in
+ * gray, under the page numbers where each change starts.
+ *
+ * When a paragraph is edited, its text is often reflowed, so all of its following lines differ in the line diff.
+ * With -words, the texts are compared word by word instead, ignoring the line breaks, and the report shows the
+ * deleted and added runs of words with the unchanged words around them.  -context sets the number of unchanged
+ * lines, or words with -words, shown around each change.
+ *
+ * Running headers and footers with page numbers are compared as text too, so pages moving by a page show up as
+ * changes of the page numbers.  Rotated text is not extracted.  The characters are decoded with the simple font
+ * encodings (WinAnsi and Differences) only, and characters outside of the WinAnsi encoding are shown as "?" in the
+ * report.
+ *
+ * The exit status is 0 if the texts are the same, 2 if they differ.
+ *
+ * Run as: go run text_diff.go text_layout.go [-words] [-context 3] old.pdf new.pdf report.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+const usage = "Usage: go run text_diff.go text_layout.go [-words] [-context 3] old.pdf new.pdf report.pdf\n"
+
+// Smallest gap that splits a line into fragments, relative to the text height.  Wider than the spaces of justified
+// text, narrower than the gutter between columns.
+const fragmentGap = 1.0
+
+var (
+	deletedColor   = creator.ColorRGBFrom8bit(192, 0, 0)
+	addedColor     = creator.ColorRGBFrom8bit(0, 128, 0)
+	unchangedColor = creator.ColorRGBFrom8bit(128, 128, 128)
+)
+
+func main() {
+	words := false
+	context := 0
+	flag.BoolVar(&words, "words", false, "Compare word by word instead of line by line, for reflowed text")
+	flag.IntVar(&context, "context", 3, "Number of unchanged lines (or words with -words) shown around changes")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 3 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if context < 0 {
+		fmt.Printf("Error: -context must not be negative\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	oldPath := flag.Arg(0)
+	newPath := flag.Arg(1)
+	outputPath := flag.Arg(2)
+
+	same, err := textDiff(oldPath, newPath, outputPath, words, context)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+	if !same {
+		os.Exit(2)
+	}
+}
+
+// token is a line or a word of the text, with the number of the page it is on.
+type token struct {
+	text string
+	page int
+}
+
+// Edit kinds.
+const (
+	editSame   = ' '
+	editDelete = '-'
+	editAdd    = '+'
+)
+
+// edit is a step of the diff: an unchanged token, a token of the old text deleted, or a token of the new text added.
+type edit struct {
+	kind     byte
+	old, new int // Index of the token in the old and new text, -1 if not in it.
+}
+
+// Compares the texts of the PDFs and writes the report.  Returns true if the texts are the same.
+func textDiff(oldPath, newPath, outputPath string, words bool, context int) (bool, error) {
+	oldTokens, err := extractTokens(oldPath, words)
+	if err != nil {
+		return false, err
+	}
+	newTokens, err := extractTokens(newPath, words)
+	if err != nil {
+		return false, err
+	}
+
+	edits := diff(oldTokens, newTokens)
+	deleted, added := 0, 0
+	for _, e := range edits {
+		switch e.kind {
+		case editDelete:
+			deleted++
+		case editAdd:
+			added++
+		}
+	}
+	unit := "lines"
+	if words {
+		unit = "words"
+	}
+	summary := fmt.Sprintf("%d %s deleted, %d %s added", deleted, unit, added, unit)
+	fmt.Println(summary)
+
+	c := creator.New()
+	c.NewPage()
+
+	title := creator.NewParagraph("Text comparison")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(16)
+	title.SetMargins(0, 0, 0, 8)
+	err = c.Draw(title)
+	if err != nil {
+		return false, err
+	}
+	for _, line := range []string{
+		fmt.Sprintf("Old: %s", filepath.Base(oldPath)),
+		fmt.Sprintf("New: %s", filepath.Base(newPath)),
+		summary,
+	} {
+		err = drawLine(c, line, nil, false)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	for _, hunk := range hunks(edits, context) {
+		err = drawHunk(c, hunk, oldTokens, newTokens, words)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = c.WriteToFile(outputPath)
+	if err != nil {
+		return false, err
+	}
+	return deleted == 0 && added == 0, nil
+}
+
+// Returns the changes with context unchanged tokens around them, as groups of edits: changes less than 2*context
+// tokens apart are in the same group.
+func hunks(edits []edit, context int) [][]edit {
+	groups := [][]edit{}
+	start, end := -1, -1 // Range of the current group.
+	for i, e := range edits {
+		if e.kind == editSame {
+			continue
+		}
+		if start >= 0 && i-context <= end {
+			end = i + context + 1
+			continue
+		}
+		if start >= 0 {
+			groups = append(groups, edits[start:minInt(end, len(edits))])
+		}
+		start, end = maxInt(i-context, 0), i+context+1
+	}
+	if start >= 0 {
+		groups = append(groups, edits[start:minInt(end, len(edits))])
+	}
+	return groups
+}
+
+// Draws the edits of a hunk under a heading with the page numbers.  Runs of edits of the same kind are drawn as one
+// paragraph with -words, and each line on its own otherwise.
+func drawHunk(c *creator.Creator, hunk []edit, oldTokens, newTokens []token, words bool) error {
+	// The pages of the first tokens of the hunk in both texts.
+	oldPage, newPage := 0, 0
+	for _, e := range hunk {
+		if oldPage == 0 && e.old >= 0 {
+			oldPage = oldTokens[e.old].page
+		}
+		if newPage == 0 && e.new >= 0 {
+			newPage = newTokens[e.new].page
+		}
+	}
+	heading := fmt.Sprintf("Old page %d, new page %d", oldPage, newPage)
+	if oldPage == 0 {
+		heading = fmt.Sprintf("New page %d", newPage)
+	} else if newPage == 0 {
+		heading = fmt.Sprintf("Old page %d", oldPage)
+	}
+	p := creator.NewParagraph(heading)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(11)
+	p.SetMargins(0, 0, 14, 4)
+	err := c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(hunk); {
+		kind := hunk[i].kind
+		texts := []string{}
+		for ; i < len(hunk) && hunk[i].kind == kind && (words || len(texts) == 0); i++ {
+			if kind == editAdd {
+				texts = append(texts, newTokens[hunk[i].new].text)
+			} else {
+				texts = append(texts, oldTokens[hunk[i].old].text)
+			}
+		}
+
+		text := strings.Join(texts, " ")
+		var color creator.Color
+		switch kind {
+		case editDelete:
+			color = deletedColor
+		case editAdd:
+			color = addedColor
+		default:
+			color = unchangedColor
+		}
+		err = drawLine(c, string(kind)+" "+text, color, kind != editSame)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Draws a line of text in the flow of the page, in bold for changes.  Characters that are not in the WinAnsi
+// encoding are replaced by "?".
+func drawLine(c *creator.Creator, text string, color creator.Color, bold bool) error {
+	encoder := textencoding.NewWinAnsiTextEncoder()
+	text = strings.Map(func(r rune) rune {
+		if _, found := encoder.RuneToGlyph(r); !found {
+			return '?'
+		}
+		return r
+	}, text)
+
+	p := creator.NewParagraph(text)
+	p.SetFontSize(10)
+	if bold {
+		p.SetFont(fonts.NewFontHelveticaBold())
+	}
+	if color != nil {
+		p.SetColor(color)
+	}
+	p.SetMargins(0, 0, 1, 1)
+	return c.Draw(p)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// Returns the edits turning a into b with the fewest deletions and additions, found with the Myers algorithm
+// ("An O(ND) Difference Algorithm and Its Variations", 1986).
+func diff(a, b []token) []edit {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// For each number of changes d, the furthest x reached on each diagonal k = x-y, kept for the backtracking.
+	// Only the diagonals -d..d are kept.
+	trace := [][]int{}
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			x := 0
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Down: an addition.
+			} else {
+				x = v[offset+k-1] + 1 // Right: a deletion.
+			}
+			y := x - k
+			for x < n && y < m && a[x].text == b[y].text {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Backtrack from the end to the start, collecting the edits in reverse.
+	edits := []edit{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		at := func(k int) int { return vd[k+d] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{editSame, x, y})
+		}
+		if d > 0 {
+			if x == prevX {
+				edits = append(edits, edit{editAdd, -1, prevY})
+			} else {
+				edits = append(edits, edit{editDelete, prevX, -1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// Extracts the text of the PDF as lines, or with words, as words.
+func extractTokens(inputPath string, words bool) ([]token, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, fmt.Errorf("Encrypted PDF %s, a password is required", inputPath)
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []token{}
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return nil, err
+		}
+
+		layout := newTextLayout()
+		err = layout.process(contents, page.Resources, identityMatrix(), 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range layout.lines() {
+			if !words {
+				tokens = append(tokens, token{line, pageNum})
+				continue
+			}
+			for _, w := range strings.Fields(line) {
+				tokens = append(tokens, token{w, pageNum})
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// word is a word on a line with its bounding box.
+type word struct {
+	text string
+	box  pdf.PdfRectangle
+}
+
+// textLine is a line, or a fragment of a line, with its words from left to right.
+type textLine struct {
+	words []word
+	box   pdf.PdfRectangle
+}
+
+// block is a block of text read top to bottom, with its lines from top to bottom.
+type block []textLine
+
+// Returns the lines of the page in reading order, with the words separated by single spaces.
+func (layout *textLayout) lines() []string {
+	// Join the horizontal glyphs into words, in content stream order.
+	words := []word{}
+	var w *word
+	for i, g := range layout.glyphs {
+		if g.rotated || unicode.IsSpace(g.r) || g.box.Ury-g.box.Lly <= 0 {
+			w = nil
+			continue
+		}
+		if w != nil && i > 0 && (!sameLine(layout.glyphs[i-1], g) || isGap(layout.glyphs[i-1], g)) {
+			w = nil
+		}
+		if w == nil {
+			words = append(words, word{box: g.box})
+			w = &words[len(words)-1]
+		}
+		w.text += string(g.r)
+		w.box = union(w.box, g.box)
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	lines := []string{}
+	for _, b := range xyCut(splitLines(groupLines(words))) {
+		for _, l := range b {
+			texts := []string{}
+			for _, w := range l.words {
+				texts = append(texts, w.text)
+			}
+			lines = append(lines, strings.Join(texts, " "))
+		}
+	}
+	return lines
+}
+
+// Groups the words into lines, from top to bottom: a word belongs to a line if it overlaps it vertically by at least
+// half its height.
+func groupLines(words []word) []textLine {
+	sort.SliceStable(words, func(i, j int) bool {
+		return words[i].box.Ury > words[j].box.Ury
+	})
+	lines := []textLine{}
+	for _, w := range words {
+		var line *textLine
+		for i := len(lines) - 1; i >= 0 && line == nil; i-- {
+			l := &lines[i]
+			overlap := math.Min(l.box.Ury, w.box.Ury) - math.Max(l.box.Lly, w.box.Lly)
+			if overlap > 0.5*math.Min(l.box.Ury-l.box.Lly, w.box.Ury-w.box.Lly) {
+				line = l
+			}
+		}
+		if line == nil {
+			lines = append(lines, textLine{box: w.box})
+			line = &lines[len(lines)-1]
+		}
+		line.words = append(line.words, w)
+		line.box = union(line.box, w.box)
+	}
+	for _, l := range lines {
+		words := l.words
+		sort.SliceStable(words, func(i, j int) bool {
+			return words[i].box.Llx < words[j].box.Llx
+		})
+	}
+	return lines
+}
+
+// Splits the lines into fragments at the gaps wider than fragmentGap times the text height.
+func splitLines(lines []textLine) []textLine {
+	fragments := []textLine{}
+	for _, l := range lines {
+		frag := textLine{words: l.words[:1], box: l.words[0].box}
+		for _, w := range l.words[1:] {
+			last := frag.words[len(frag.words)-1]
+			height := math.Min(last.box.Ury-last.box.Lly, w.box.Ury-w.box.Lly)
+			if w.box.Llx-last.box.Urx > fragmentGap*height {
+				fragments = append(fragments, frag)
+				frag = textLine{box: w.box}
+			}
+			frag.words = append(frag.words, w)
+			frag.box = union(frag.box, w.box)
+		}
+		fragments = append(fragments, frag)
+	}
+	return fragments
+}
+
+// Cuts the fragments into blocks in reading order.
+func xyCut(fragments []textLine) []block {
+	if len(fragments) < 2 {
+		return []block{fragments}
+	}
+
+	// Columns: groups of fragments separated by x ranges that no fragment covers.
+	sort.SliceStable(fragments, func(i, j int) bool {
+		return fragments[i].box.Llx < fragments[j].box.Llx
+	})
+	columns := [][]textLine{}
+	start, urx := 0, fragments[0].box.Urx
+	for i := 1; i < len(fragments); i++ {
+		if fragments[i].box.Llx > urx {
+			columns = append(columns, fragments[start:i])
+			start = i
+		}
+		urx = math.Max(urx, fragments[i].box.Urx)
+	}
+	if len(columns) > 0 {
+		columns = append(columns, fragments[start:])
+		blocks := []block{}
+		for _, col := range columns {
+			blocks = append(blocks, xyCut(col)...)
+		}
+		return blocks
+	}
+
+	// No columns: cut at the widest vertical gap between the fragments.
+	sort.SliceStable(fragments, func(i, j int) bool {
+		return fragments[i].box.Ury > fragments[j].box.Ury
+	})
+	cut, widest := 0, 0.0
+	lly := fragments[0].box.Lly
+	for i := 1; i < len(fragments); i++ {
+		if gap := lly - fragments[i].box.Ury; gap > widest {
+			cut, widest = i, gap
+		}
+		lly = math.Min(lly, fragments[i].box.Lly)
+	}
+	if cut == 0 {
+		return []block{joinLines(fragments)}
+	}
+
+	top := xyCut(append([]textLine{}, fragments[:cut]...))
+	bottom := xyCut(append([]textLine{}, fragments[cut:]...))
+	if len(top) == 1 && len(bottom) == 1 {
+		return []block{joinLines(append(top[0], bottom[0]...))}
+	}
+	return append(top, bottom...)
+}
+
+// Joins the fragments of a block that are on the same line, returning the lines from top to bottom.
+func joinLines(fragments []textLine) block {
+	words := []word{}
+	for _, f := range fragments {
+		words = append(words, f.words...)
+	}
+	return groupLines(words)
+}
@@ -0,0 +1,363 @@
+/*
+ * Compare two PDFs visually: render the pages of both and create a PDF showing the differences in red.
+ *
+ * The pages are paired by page index and rendered with the basic renderer of render.go (copied from pdf/render), with
+ * the glyphs positioned by text_layout.go, so its limitations apply: content it does not render is not compared.  Each
+ * page of the output shows the page of the second PDF, faded, with the pixels that differ from the first PDF in red, in
+ * a light red area so that small changes stand out.  Pages of different sizes are compared aligned at the upper left
+ * corner.  When the page counts differ, the pages without a counterpart are included, faded, with a red frame and a
+ * note which PDF they are from.
+ *
+ * Rendering the same content twice gives the same pixels, but small changes in positions or fonts give anti-aliasing
+ * differences at the edges of the text and graphics, which are not meaningful changes.  So a pixel is marked only if
+ * it differs from all pixels of the other page within one pixel around it by more than the tolerance (the largest
+ * difference of the red, green and blue values, 0-255).  Raise -tolerance to ignore faint changes, such as small
+ * color differences.
+ *
+ * The number of differing pixels of each page is printed.  The exit status is 0 if the PDFs look the same, 2 if they
+ * differ.
+ *
+ * Run as: go run visual_diff.go render.go text_layout.go [-dpi 72] [-tolerance 48] first.pdf second.pdf diff.pdf
+ */
+/*
+ * NOTE: This example depends on golang.org/x/image (vector, font/sfnt and the Go fonts), BSD licensed.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	goimage "image"
+	"image/color"
+	"image/draw"
+	"math"
+	"os"
+	"path/filepath"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run visual_diff.go render.go text_layout.go [-dpi 72] [-tolerance 48] first.pdf second.pdf " +
+	"diff.pdf\n"
+
+const (
+	fade       = 0.3 // Contrast of the faded pages in the output.
+	frameWidth = 8   // Width of the frame around pages without a counterpart, in pixels.
+	haloWidth  = 4   // Width of the light red area around differences, in points, so small changes stand out.
+)
+
+var (
+	diffColor = color.RGBA{255, 0, 0, 255}
+	haloColor = color.RGBA{255, 200, 200, 255}
+)
+
+func main() {
+	dpi := 0.0
+	tolerance := 0
+	flag.Float64Var(&dpi, "dpi", 72, "Resolution to compare the pages at")
+	flag.IntVar(&tolerance, "tolerance", 48, "Largest color difference (0-255) of pixels considered the same")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 3 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if dpi < 10 || dpi > 600 {
+		fmt.Printf("Error: -dpi must be between 10 and 600\n")
+		os.Exit(1)
+	}
+	if tolerance < 0 || tolerance > 255 {
+		fmt.Printf("Error: -tolerance must be between 0 and 255\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	firstPath := flag.Arg(0)
+	secondPath := flag.Arg(1)
+	outputPath := flag.Arg(2)
+
+	same, err := visualDiff(firstPath, secondPath, outputPath, dpi, uint8(tolerance))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+	if !same {
+		os.Exit(2)
+	}
+}
+
+// Compares the PDFs and writes the differences to outputPath.  Returns true if the PDFs look the same.
+func visualDiff(firstPath, secondPath, outputPath string, dpi float64, tolerance uint8) (bool, error) {
+	fFirst, err := os.Open(firstPath)
+	if err != nil {
+		return false, err
+	}
+	defer fFirst.Close()
+	first, err := newPdfReader(fFirst)
+	if err != nil {
+		return false, err
+	}
+
+	fSecond, err := os.Open(secondPath)
+	if err != nil {
+		return false, err
+	}
+	defer fSecond.Close()
+	second, err := newPdfReader(fSecond)
+	if err != nil {
+		return false, err
+	}
+
+	numFirst, err := first.GetNumPages()
+	if err != nil {
+		return false, err
+	}
+	numSecond, err := second.GetNumPages()
+	if err != nil {
+		return false, err
+	}
+	if numFirst != numSecond {
+		fmt.Printf("Page counts differ: %d in %s, %d in %s\n", numFirst, firstPath, numSecond, secondPath)
+	}
+
+	c := creator.New()
+	same := numFirst == numSecond
+	r := newPageRenderer()
+	for i := 0; i < numFirst || i < numSecond; i++ {
+		pageNum := i + 1
+
+		var imgFirst, imgSecond *goimage.RGBA
+		if pageNum <= numFirst {
+			imgFirst, err = renderPage(r, first, pageNum, dpi)
+			if err != nil {
+				return false, err
+			}
+		}
+		if pageNum <= numSecond {
+			imgSecond, err = renderPage(r, second, pageNum, dpi)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		var img *goimage.RGBA
+		note := ""
+		switch {
+		case imgSecond == nil:
+			img = markExtraPage(imgFirst)
+			note = fmt.Sprintf("Page %d only in %s", pageNum, filepath.Base(firstPath))
+		case imgFirst == nil:
+			img = markExtraPage(imgSecond)
+			note = fmt.Sprintf("Page %d only in %s", pageNum, filepath.Base(secondPath))
+		default:
+			var count int
+			img, count = diffImages(imgFirst, imgSecond, tolerance, int(math.Ceil(haloWidth*dpi/72)))
+			if count > 0 {
+				same = false
+				b := img.Bounds()
+				note = fmt.Sprintf("Page %d: %d pixels differ (%.2f%%)", pageNum, count,
+					100*float64(count)/float64(b.Dx()*b.Dy()))
+			}
+		}
+		if note != "" {
+			fmt.Println(note)
+		} else {
+			fmt.Printf("Page %d: no differences\n", pageNum)
+		}
+
+		err = addImagePage(c, img, dpi, note)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	err = c.WriteToFile(outputPath)
+	if err != nil {
+		return false, err
+	}
+	return same, nil
+}
+
+// Returns a reader of the PDF file, decrypted if needed.
+func newPdfReader(f *os.File) (*pdf.PdfReader, error) {
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, fmt.Errorf("Encrypted PDF %s, a password is required", f.Name())
+		}
+	}
+	return pdfReader, nil
+}
+
+func renderPage(r *pageRenderer, pdfReader *pdf.PdfReader, pageNum int, dpi float64) (*goimage.RGBA, error) {
+	page, err := pdfReader.GetPage(pageNum)
+	if err != nil {
+		return nil, err
+	}
+	return r.RenderPage(page, dpi)
+}
+
+// Returns the second image faded with the differences to the first image in red, and the number of differing
+// pixels.  The images are aligned at the upper left corner, and the parts outside of an image are taken as white.
+// The differences are surrounded by a light red halo of the given width in pixels.
+func diffImages(a, b *goimage.RGBA, tolerance uint8, halo int) (*goimage.RGBA, int) {
+	bounds := a.Bounds().Union(b.Bounds())
+	a, b = extendImage(a, bounds), extendImage(b, bounds)
+
+	out := fadeImage(b)
+	diff := []goimage.Point{}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if differs(a, b, x, y, tolerance) || differs(b, a, x, y, tolerance) {
+				diff = append(diff, goimage.Pt(x, y))
+			}
+		}
+	}
+
+	// The halo darkens the faded page to the halo color, with the differences on top.
+	inHalo := make([]bool, bounds.Dx()*bounds.Dy())
+	for _, p := range diff {
+		r := goimage.Rect(p.X-halo, p.Y-halo, p.X+halo+1, p.Y+halo+1).Intersect(bounds)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				i := (y-bounds.Min.Y)*bounds.Dx() + x - bounds.Min.X
+				if inHalo[i] {
+					continue
+				}
+				inHalo[i] = true
+				c := out.RGBAAt(x, y)
+				out.SetRGBA(x, y, color.RGBA{minByte(c.R, haloColor.R), minByte(c.G, haloColor.G),
+					minByte(c.B, haloColor.B), 255})
+			}
+		}
+	}
+	for _, p := range diff {
+		out.SetRGBA(p.X, p.Y, diffColor)
+	}
+	return out, len(diff)
+}
+
+// Returns true if the pixel of a at (x, y) differs from the pixels of b at and around (x, y) by more than the
+// tolerance.
+func differs(a, b *goimage.RGBA, x, y int, tolerance uint8) bool {
+	ca := a.RGBAAt(x, y)
+	bounds := b.Bounds()
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			p := goimage.Pt(x+dx, y+dy)
+			if !p.In(bounds) {
+				continue
+			}
+			cb := b.RGBAAt(p.X, p.Y)
+			if absDiff(ca.R, cb.R) <= tolerance && absDiff(ca.G, cb.G) <= tolerance && absDiff(ca.B, cb.B) <= tolerance {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func minByte(a, b uint8) uint8 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// Returns the image extended to the bounds with white, or the image itself if it has these bounds.
+func extendImage(img *goimage.RGBA, bounds goimage.Rectangle) *goimage.RGBA {
+	if img.Bounds() == bounds {
+		return img
+	}
+	ext := goimage.NewRGBA(bounds)
+	draw.Draw(ext, bounds, goimage.White, goimage.Point{}, draw.Src)
+	draw.Draw(ext, img.Bounds(), img, img.Bounds().Min, draw.Src)
+	return ext
+}
+
+// Returns a faded copy of the image: the colors are blended with white, keeping fade of the contrast.
+func fadeImage(img *goimage.RGBA) *goimage.RGBA {
+	out := goimage.NewRGBA(img.Bounds())
+	for i, v := range img.Pix {
+		if i%4 == 3 {
+			out.Pix[i] = v
+			continue
+		}
+		out.Pix[i] = uint8(255 - math.Round(fade*float64(255-v)))
+	}
+	return out
+}
+
+// Returns the page image of a page without a counterpart: faded, with a red frame.
+func markExtraPage(img *goimage.RGBA) *goimage.RGBA {
+	out := fadeImage(img)
+	b := out.Bounds()
+	red := goimage.NewUniform(diffColor)
+	draw.Draw(out, goimage.Rect(b.Min.X, b.Min.Y, b.Max.X, b.Min.Y+frameWidth), red, goimage.Point{}, draw.Src)
+	draw.Draw(out, goimage.Rect(b.Min.X, b.Max.Y-frameWidth, b.Max.X, b.Max.Y), red, goimage.Point{}, draw.Src)
+	draw.Draw(out, goimage.Rect(b.Min.X, b.Min.Y, b.Min.X+frameWidth, b.Max.Y), red, goimage.Point{}, draw.Src)
+	draw.Draw(out, goimage.Rect(b.Max.X-frameWidth, b.Min.Y, b.Max.X, b.Max.Y), red, goimage.Point{}, draw.Src)
+	return out
+}
+
+// Adds a page of the size of the image at the given resolution, showing the image and the note at the top.
+func addImagePage(c *creator.Creator, img *goimage.RGBA, dpi float64, note string) error {
+	b := img.Bounds()
+	width := float64(b.Dx()) * 72 / dpi
+	height := float64(b.Dy()) * 72 / dpi
+	c.SetPageSize(creator.PageSize{width, height})
+	c.NewPage()
+
+	pageImage, err := creator.NewImageFromGoImage(img)
+	if err != nil {
+		return err
+	}
+	pageImage.SetWidth(width)
+	pageImage.SetHeight(height)
+	pageImage.SetPos(0, 0)
+	err = c.Draw(pageImage)
+	if err != nil {
+		return err
+	}
+
+	if note == "" {
+		return nil
+	}
+	p := creator.NewParagraph(note)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(10)
+	p.SetColor(creator.ColorRGBFrom8bit(diffColor.R, diffColor.G, diffColor.B))
+	p.SetPos(frameWidth*72/dpi+6, frameWidth*72/dpi+6)
+	return c.Draw(p)
+}
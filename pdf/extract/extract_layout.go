@@ -0,0 +1,373 @@
+/*
+ * Extract the text of a PDF in reading order, keeping the approximate layout of each page.
+ *
+ * The extractor of this UniDoc version returns the text in content stream order without positions, so the glyph
+ * positions are computed from the content streams with text_layout.go, as in the search example
+ * (pdf/search/find_text.go).  The words are grouped into lines, and the lines are split at wide gaps into fragments.
+ * The reading order is found by cutting the page recursively at the gaps between the fragments (XY-cut):
+ * - A region with a vertical gutter, a range of x positions not covered by any fragment over the whole height of the
+ *   region, is cut into columns, read from left to right.
+ * - Otherwise, the region is cut at its widest horizontal gap, and the parts are read from top to bottom.  Parts
+ *   that have no columns are joined again into one block.
+ * So a page with a title over two columns is read as the title, then the left column top to bottom, then the right
+ * column.  The rows of a table are cut into their cells, which come out one by one; use -layout for tables.
+ *
+ * Within a block, the lines and fragments are placed at the character positions of their x positions (relative to
+ * the left edge of the block, in units of the average glyph width of the block), and vertical gaps of a line height
+ * or more become blank lines.  With -layout, the whole page is output as a single block in this way, keeping the
+ * columns side by side.
+ *
+ * Only horizontal text is extracted: rotated (and upside down) text is skipped, and the number of rotated characters
+ * on a page is noted after its text.  The characters are decoded with the simple font encodings (WinAnsi and
+ * Differences) only.
+ *
+ * Run as: go run extract_layout.go text_layout.go [-layout] input.pdf [output.txt]
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run extract_layout.go text_layout.go [-layout] input.pdf [output.txt]\n"
+
+// Smallest gap that splits a line into fragments, relative to the text height.  Wider than the spaces of justified
+// text, narrower than the gutter between columns.
+const fragmentGap = 1.0
+
+func main() {
+	layoutMode := false
+	flag.BoolVar(&layoutMode, "layout", false, "Keep the physical layout of the whole page instead of reading order")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	text, err := extractLayout(inputPath, layoutMode)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if flag.NArg() < 2 {
+		fmt.Print(text)
+		return
+	}
+	outputPath := flag.Arg(1)
+	err = ioutil.WriteFile(outputPath, []byte(text), 0644)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Returns the text of all pages, each page preceded by a page header.
+func extractLayout(inputPath string, layoutMode bool) (string, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return "", err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return "", err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return "", err
+		}
+		if !auth {
+			return "", errors.New("Encrypted PDF, a password is required")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return "", err
+		}
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return "", err
+		}
+
+		layout := newTextLayout()
+		err = layout.process(contents, page.Resources, identityMatrix(), 0)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&buf, "--- Page %d ---\n", pageNum)
+		buf.WriteString(layout.text(layoutMode))
+		rotated := 0
+		for _, g := range layout.glyphs {
+			if g.rotated && !unicode.IsSpace(g.r) {
+				rotated++
+			}
+		}
+		if rotated > 0 {
+			fmt.Fprintf(&buf, "[%d rotated characters skipped]\n", rotated)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
+
+// word is a word on a line with its bounding box.
+type word struct {
+	text string
+	box  pdf.PdfRectangle
+}
+
+// textLine is a line, or a fragment of a line, with its words from left to right.
+type textLine struct {
+	words []word
+	box   pdf.PdfRectangle
+}
+
+// block is a block of text read top to bottom, with its lines from top to bottom.
+type block []textLine
+
+// Returns the text of the page: the blocks in reading order separated by blank lines, or with layoutMode, the whole
+// page as a single block.
+func (layout *textLayout) text(layoutMode bool) string {
+	// Join the horizontal glyphs into words, in content stream order.
+	words := []word{}
+	var w *word
+	for i, g := range layout.glyphs {
+		if g.rotated || unicode.IsSpace(g.r) || g.box.Ury-g.box.Lly <= 0 {
+			w = nil
+			continue
+		}
+		if w != nil && i > 0 && (!sameLine(layout.glyphs[i-1], g) || isGap(layout.glyphs[i-1], g)) {
+			w = nil
+		}
+		if w == nil {
+			words = append(words, word{box: g.box})
+			w = &words[len(words)-1]
+		}
+		w.text += string(g.r)
+		w.box = union(w.box, g.box)
+	}
+	if len(words) == 0 {
+		return ""
+	}
+
+	lines := groupLines(words)
+	if layoutMode {
+		return renderBlock(lines)
+	}
+
+	texts := []string{}
+	for _, b := range xyCut(splitLines(lines)) {
+		texts = append(texts, renderBlock(b))
+	}
+	return strings.Join(texts, "\n")
+}
+
+// Groups the words into lines, from top to bottom: a word belongs to a line if it overlaps it vertically by at least
+// half its height.
+func groupLines(words []word) []textLine {
+	sort.SliceStable(words, func(i, j int) bool {
+		return words[i].box.Ury > words[j].box.Ury
+	})
+	lines := []textLine{}
+	for _, w := range words {
+		var line *textLine
+		for i := len(lines) - 1; i >= 0 && line == nil; i-- {
+			l := &lines[i]
+			overlap := math.Min(l.box.Ury, w.box.Ury) - math.Max(l.box.Lly, w.box.Lly)
+			if overlap > 0.5*math.Min(l.box.Ury-l.box.Lly, w.box.Ury-w.box.Lly) {
+				line = l
+			}
+		}
+		if line == nil {
+			lines = append(lines, textLine{box: w.box})
+			line = &lines[len(lines)-1]
+		}
+		line.words = append(line.words, w)
+		line.box = union(line.box, w.box)
+	}
+	for _, l := range lines {
+		words := l.words
+		sort.SliceStable(words, func(i, j int) bool {
+			return words[i].box.Llx < words[j].box.Llx
+		})
+	}
+	return lines
+}
+
+// Splits the lines into fragments at the gaps wider than fragmentGap times the text height.
+func splitLines(lines []textLine) []textLine {
+	fragments := []textLine{}
+	for _, l := range lines {
+		frag := textLine{words: l.words[:1], box: l.words[0].box}
+		for _, w := range l.words[1:] {
+			last := frag.words[len(frag.words)-1]
+			height := math.Min(last.box.Ury-last.box.Lly, w.box.Ury-w.box.Lly)
+			if w.box.Llx-last.box.Urx > fragmentGap*height {
+				fragments = append(fragments, frag)
+				frag = textLine{box: w.box}
+			}
+			frag.words = append(frag.words, w)
+			frag.box = union(frag.box, w.box)
+		}
+		fragments = append(fragments, frag)
+	}
+	return fragments
+}
+
+// Cuts the fragments into blocks in reading order.
+func xyCut(fragments []textLine) []block {
+	if len(fragments) < 2 {
+		return []block{fragments}
+	}
+
+	// Columns: groups of fragments separated by x ranges that no fragment covers.
+	sort.SliceStable(fragments, func(i, j int) bool {
+		return fragments[i].box.Llx < fragments[j].box.Llx
+	})
+	columns := [][]textLine{}
+	start, urx := 0, fragments[0].box.Urx
+	for i := 1; i < len(fragments); i++ {
+		if fragments[i].box.Llx > urx {
+			columns = append(columns, fragments[start:i])
+			start = i
+		}
+		urx = math.Max(urx, fragments[i].box.Urx)
+	}
+	if len(columns) > 0 {
+		columns = append(columns, fragments[start:])
+		blocks := []block{}
+		for _, col := range columns {
+			blocks = append(blocks, xyCut(col)...)
+		}
+		return blocks
+	}
+
+	// No columns: cut at the widest vertical gap between the fragments.
+	sort.SliceStable(fragments, func(i, j int) bool {
+		return fragments[i].box.Ury > fragments[j].box.Ury
+	})
+	cut, widest := 0, 0.0
+	lly := fragments[0].box.Lly
+	for i := 1; i < len(fragments); i++ {
+		if gap := lly - fragments[i].box.Ury; gap > widest {
+			cut, widest = i, gap
+		}
+		lly = math.Min(lly, fragments[i].box.Lly)
+	}
+	if cut == 0 {
+		return []block{joinLines(fragments)}
+	}
+
+	top := xyCut(append([]textLine{}, fragments[:cut]...))
+	bottom := xyCut(append([]textLine{}, fragments[cut:]...))
+	if len(top) == 1 && len(bottom) == 1 {
+		return []block{joinLines(append(top[0], bottom[0]...))}
+	}
+	return append(top, bottom...)
+}
+
+// Joins the fragments of a block that are on the same line, returning the lines from top to bottom.
+func joinLines(fragments []textLine) block {
+	words := []word{}
+	for _, f := range fragments {
+		words = append(words, f.words...)
+	}
+	return groupLines(words)
+}
+
+// Renders the lines as text, placing the lines and the fragments at the character positions of their x positions
+// relative to the left edge of the lines, in units of the average glyph width.  Vertical gaps of at least a line
+// height are output as blank lines.
+func renderBlock(lines []textLine) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	left := lines[0].box.Llx
+	heights := []float64{}
+	width, runes := 0.0, 0
+	for _, l := range lines {
+		left = math.Min(left, l.box.Llx)
+		heights = append(heights, l.box.Ury-l.box.Lly)
+		for _, w := range l.words {
+			width += w.box.Urx - w.box.Llx
+			runes += utf8.RuneCountInString(w.text)
+		}
+	}
+	glyphWidth := width / float64(runes)
+	sort.Float64s(heights)
+	lineHeight := heights[len(heights)/2]
+
+	var buf bytes.Buffer
+	for i, l := range lines {
+		if i > 0 {
+			gap := lines[i-1].box.Lly - l.box.Ury
+			for n := int(gap / lineHeight); n > 0; n-- {
+				buf.WriteString("\n")
+			}
+		}
+		col := 0
+		for j, w := range l.words {
+			// Words are separated by a single space, the start of the line and words after a wide gap are placed at
+			// their position.
+			pad := int(math.Floor((w.box.Llx-left)/glyphWidth+0.5)) - col
+			if j > 0 {
+				prev := l.words[j-1]
+				if pad < 1 || w.box.Llx-prev.box.Urx <= fragmentGap*(prev.box.Ury-prev.box.Lly) {
+					pad = 1
+				}
+			}
+			if pad > 0 {
+				buf.WriteString(strings.Repeat(" ", pad))
+				col += pad
+			}
+			buf.WriteString(w.text)
+			col += utf8.RuneCountInString(w.text)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
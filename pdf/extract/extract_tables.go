@@ -0,0 +1,374 @@
+/*
+ * Extract tables from a PDF as CSV, inferring the rows and columns from the positions of the text.
+ *
+ * The extractor of this UniDoc version returns the plain text only, so the glyph positions are computed from the
+ * content streams with text_layout.go, as in the search example (pdf/search/find_text.go).  The tables are found from
+ * the alignment of the text alone, ruling lines are not needed (and not used):
+ * - The glyphs are joined into words, split at spaces and at gaps between glyphs.
+ * - Words overlapping vertically form a text row, and the words of a row are joined into cells, split where the gap
+ *   between words is wider than a space (more than cellGap times the text height).
+ * - A table is a run of at least -min-rows rows with two or more cells each, with no more than maxRowGap line heights
+ *   between the rows.  A row with a single cell within the table (e.g. a wrapped line or a section heading) is kept
+ *   if the table continues below it.
+ * - The columns are the ranges of x positions covered by the cells, separated by the gutters between them.  A gutter
+ *   that is crossed by a few cells only (at most spanFraction of the rows) is still a gutter, those cells are taken
+ *   as merged cells spanning several columns.
+ * Each cell is placed in the column it overlaps.  A merged cell is placed in the first column it spans, leaving the
+ * other columns empty, and cells falling in the same column of a row are joined with a space.  This is best effort:
+ * the structure of a table is not in the PDF, and rows of text that happen to line up in columns are found as tables
+ * too.
+ *
+ * The tables are printed as CSV, and with -outdir, each table is also written to page_<page>_table_<n>.csv.
+ *
+ * Limitations: only horizontal text is handled, cells wrapped on several lines come out as several rows, and the
+ * characters are decoded with the simple font encodings (WinAnsi and Differences) only.
+ *
+ * Run as: go run extract_tables.go text_layout.go [-min-rows 3] [-outdir dir] input.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run extract_tables.go text_layout.go [-min-rows 3] [-outdir dir] input.pdf\n"
+
+const (
+	cellGap      = 0.6  // Smallest gap between cells of a row, relative to the text height.
+	maxRowGap    = 1.5  // Largest gap between the rows of a table, relative to the text height.
+	spanFraction = 0.25 // Largest fraction of the rows that may cross a gutter between columns.
+)
+
+func main() {
+	minRows := 0
+	outDir := ""
+	flag.IntVar(&minRows, "min-rows", 3, "Minimum number of rows of a table")
+	flag.StringVar(&outDir, "outdir", "", "Directory to write each table to as a CSV file")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if minRows < 2 {
+		fmt.Printf("Error: -min-rows must be at least 2\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	err := extractTables(inputPath, minRows, outDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func extractTables(inputPath string, minRows int, outDir string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Encrypted PDF, a password is required")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	if outDir != "" {
+		err = os.MkdirAll(outDir, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	total := 0
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return err
+		}
+
+		layout := newTextLayout()
+		err = layout.process(contents, page.Resources, identityMatrix(), 0)
+		if err != nil {
+			return err
+		}
+
+		for n, table := range findTables(layout.rows(), minRows) {
+			total++
+			var buf bytes.Buffer
+			w := csv.NewWriter(&buf)
+			w.WriteAll(table.cells)
+			if err := w.Error(); err != nil {
+				return err
+			}
+
+			fmt.Printf("Page %d, table %d (%d columns, %d rows", pageNum, n+1, len(table.cells[0]), len(table.cells))
+			if table.merged > 0 {
+				fmt.Printf(", %d merged cells", table.merged)
+			}
+			fmt.Printf("):\n%s\n", buf.String())
+
+			if outDir != "" {
+				outputPath := filepath.Join(outDir, fmt.Sprintf("page_%d_table_%d.csv", pageNum, n+1))
+				err = ioutil.WriteFile(outputPath, buf.Bytes(), 0644)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	fmt.Printf("%d tables found\n", total)
+	if outDir != "" && total > 0 {
+		fmt.Printf("Complete, see output directory: %s\n", outDir)
+	}
+	return nil
+}
+
+// phrase is text on a line with its bounding box: a word, or the text of a cell.
+type phrase struct {
+	text string
+	box  pdf.PdfRectangle
+}
+
+// textRow is a row of text: the cells on a line, from left to right.
+type textRow struct {
+	cells []phrase
+	box   pdf.PdfRectangle
+}
+
+// table is a table found on a page.
+type table struct {
+	cells  [][]string // Text of the cells by row and column.
+	merged int        // Number of cells spanning several columns.
+}
+
+// Returns the rows of text on the page, from top to bottom.
+func (layout *textLayout) rows() []textRow {
+	// Join the glyphs into words, in content stream order.
+	words := []phrase{}
+	var word *phrase
+	for i, g := range layout.glyphs {
+		if unicode.IsSpace(g.r) || g.box.Ury-g.box.Lly <= 0 {
+			word = nil
+			continue
+		}
+		if word != nil && i > 0 && (!sameLine(layout.glyphs[i-1], g) || isGap(layout.glyphs[i-1], g)) {
+			word = nil
+		}
+		if word == nil {
+			words = append(words, phrase{box: g.box})
+			word = &words[len(words)-1]
+		}
+		word.text += string(g.r)
+		word.box = union(word.box, g.box)
+	}
+
+	// Group the words into rows: a word belongs to a row if it overlaps it vertically by at least half its height.
+	sort.SliceStable(words, func(i, j int) bool {
+		return words[i].box.Ury > words[j].box.Ury
+	})
+	rows := []textRow{}
+	for _, w := range words {
+		var row *textRow
+		for i := len(rows) - 1; i >= 0 && row == nil; i-- {
+			r := &rows[i]
+			overlap := math.Min(r.box.Ury, w.box.Ury) - math.Max(r.box.Lly, w.box.Lly)
+			if overlap > 0.5*math.Min(r.box.Ury-r.box.Lly, w.box.Ury-w.box.Lly) {
+				row = r
+			}
+		}
+		if row == nil {
+			rows = append(rows, textRow{box: w.box})
+			row = &rows[len(rows)-1]
+		}
+		row.cells = append(row.cells, w)
+		row.box = union(row.box, w.box)
+	}
+
+	// Join the words of each row into cells.
+	for i := range rows {
+		words := rows[i].cells
+		sort.SliceStable(words, func(a, b int) bool {
+			return words[a].box.Llx < words[b].box.Llx
+		})
+		cells := []phrase{words[0]}
+		for _, w := range words[1:] {
+			last := &cells[len(cells)-1]
+			height := math.Min(last.box.Ury-last.box.Lly, w.box.Ury-w.box.Lly)
+			if w.box.Llx-last.box.Urx > cellGap*height {
+				cells = append(cells, w)
+				continue
+			}
+			last.text += " " + w.text
+			last.box = union(last.box, w.box)
+		}
+		rows[i].cells = cells
+	}
+	return rows
+}
+
+// Finds the tables in the rows of text: runs of at least minRows rows with several cells, close to each other.
+func findTables(rows []textRow, minRows int) []table {
+	tables := []table{}
+	for i := 0; i < len(rows); {
+		if len(rows[i].cells) < 2 {
+			i++
+			continue
+		}
+
+		// Extend the run while the rows are close, allowing single cell rows followed by a row with several cells.
+		end := i + 1
+		for end < len(rows) && rowsAdjacent(rows[end-1], rows[end]) {
+			if len(rows[end].cells) < 2 {
+				if end+1 >= len(rows) || len(rows[end+1].cells) < 2 || !rowsAdjacent(rows[end], rows[end+1]) {
+					break
+				}
+			}
+			end++
+		}
+
+		if end-i >= minRows {
+			if t, ok := makeTable(rows[i:end]); ok {
+				tables = append(tables, t)
+			}
+		}
+		i = end
+	}
+	return tables
+}
+
+// Returns true if row b directly follows row a: the gap between them is at most maxRowGap times the text height.
+func rowsAdjacent(a, b textRow) bool {
+	height := math.Min(a.box.Ury-a.box.Lly, b.box.Ury-b.box.Lly)
+	return a.box.Lly-b.box.Ury <= maxRowGap*height
+}
+
+// Arranges the rows in columns.  Returns false if the rows do not form at least two columns.
+func makeTable(rows []textRow) (table, bool) {
+	// The x positions where cells start or end divide the width of the table into elementary ranges, each covered
+	// by the same cells over its whole width.
+	xs := []float64{}
+	for _, row := range rows {
+		for _, c := range row.cells {
+			xs = append(xs, c.box.Llx, c.box.Urx)
+		}
+	}
+	sort.Float64s(xs)
+
+	// A range is a gutter if it is covered by few cells, all of which extend past it on both sides (merged cells).
+	// The cells at the edge of a column end within the range, so they do not make a gutter of a sparse column.
+	maxCrossing := int(math.Max(1, spanFraction*float64(len(rows))))
+	type column struct{ llx, urx float64 }
+	columns := []column{}
+	inColumn := false
+	for i := 0; i+1 < len(xs); i++ {
+		x0, x1 := xs[i], xs[i+1]
+		if x1-x0 < 1e-6 {
+			continue
+		}
+		covering, crossing := 0, 0
+		for _, row := range rows {
+			for _, c := range row.cells {
+				if c.box.Llx <= x0 && c.box.Urx >= x1 {
+					covering++
+					if c.box.Llx < x0 && c.box.Urx > x1 {
+						crossing++
+					}
+				}
+			}
+		}
+		gutter := covering == 0 || (covering == crossing && crossing <= maxCrossing)
+		if gutter {
+			inColumn = false
+			continue
+		}
+		if !inColumn {
+			columns = append(columns, column{x0, x1})
+			inColumn = true
+		} else {
+			columns[len(columns)-1].urx = x1
+		}
+	}
+	if len(columns) < 2 {
+		return table{}, false
+	}
+
+	t := table{}
+	for _, row := range rows {
+		texts := make([]string, len(columns))
+		for _, c := range row.cells {
+			// The first column the cell overlaps, counting the spanned columns.
+			first, spanned := -1, 0
+			for j, col := range columns {
+				if c.box.Llx < col.urx && c.box.Urx > col.llx {
+					if first < 0 {
+						first = j
+					}
+					spanned++
+				}
+			}
+			if first < 0 {
+				continue
+			}
+			if spanned > 1 {
+				t.merged++
+			}
+			if texts[first] != "" {
+				texts[first] += " "
+			}
+			texts[first] += c.text
+		}
+		t.cells = append(t.cells, texts)
+	}
+	return t, true
+}
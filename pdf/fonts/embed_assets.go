@@ -0,0 +1,194 @@
+/*
+ * Create a PDF with fonts and a logo embedded in the program with go:embed, so that the binary is self-contained and
+ * does not need the asset files on disk at run time.
+ *
+ * The assets are in the assets directory next to this file, embedded as an embed.FS (which requires Go 1.16 or
+ * later).  The logo is loaded from the embedded bytes with creator.NewImageFromData.  This version of UniDoc has no
+ * API to load a TrueType font from bytes or a reader: model.NewPdfFontFromTTFFile (and the TTF parser it uses) only
+ * take a file path, and the model package is part of the library, not of these examples.  So newPdfFontFromTTFReader
+ * provides that API for the example: it copies the font to a temporary file, loads it, and removes the file, which
+ * only needs a writable temporary directory (os.TempDir).  It has the signature such an API would have in the
+ * library, model.NewPdfFontFromTTFReader(io.Reader), so it can be replaced by it where available.
+ *
+ * Build a self-contained binary with: go build embed_assets.go (from this directory, so that the assets are found),
+ * and run it anywhere.
+ *
+ * NOTE: The embedded fonts are Roboto (Roboto-Regular.ttf, Roboto-Bold.ttf), Apache-2 licensed, copies of those of
+ *       the report example.
+ *
+ * Run as: go run embed_assets.go output.pdf
+ */
+
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+//go:embed assets
+var assets embed.FS
+
+const (
+	regularFontAsset = "assets/Roboto-Regular.ttf"
+	boldFontAsset    = "assets/Roboto-Bold.ttf"
+	logoAsset        = "assets/unidoc-logo.png"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run embed_assets.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	err := createDocument(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createDocument(outputPath string) error {
+	regular, err := loadFontAsset(regularFontAsset)
+	if err != nil {
+		return err
+	}
+	bold, err := loadFontAsset(boldFontAsset)
+	if err != nil {
+		return err
+	}
+
+	logoData, err := assets.ReadFile(logoAsset)
+	if err != nil {
+		return err
+	}
+	logo, err := creator.NewImageFromData(logoData)
+	if err != nil {
+		return fmt.Errorf("%s: %v", logoAsset, err)
+	}
+
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	logo.ScaleToWidth(120)
+	logo.SetPos(c.Width()-50-logo.Width(), 40)
+	err = c.Draw(logo)
+	if err != nil {
+		return err
+	}
+
+	heading := creator.NewParagraph("Embedded assets")
+	heading.SetFont(bold)
+	heading.SetFontSize(20)
+	heading.SetMargins(0, 0, 10, 20)
+	err = c.Draw(heading)
+	if err != nil {
+		return err
+	}
+
+	p := creator.NewParagraph("This document was created by a program with its fonts and logo embedded in the " +
+		"binary with go:embed. The text is set in Roboto, loaded from the embedded font files, and the logo is " +
+		"decoded from the embedded PNG data. No asset files are read from disk.")
+	p.SetFont(regular)
+	p.SetFontSize(11)
+	p.SetLineHeight(1.4)
+	p.SetMargins(0, 0, 0, 20)
+	err = c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	sub := creator.NewParagraph("Assets in the binary")
+	sub.SetFont(bold)
+	sub.SetFontSize(13)
+	sub.SetMargins(0, 0, 0, 8)
+	err = c.Draw(sub)
+	if err != nil {
+		return err
+	}
+
+	// The files of the embed.FS, with their sizes.
+	table := creator.NewTable(2)
+	table.SetColumnWidths(0.7, 0.3)
+	err = fs.WalkDir(assets, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		for _, text := range []string{path, fmt.Sprintf("%d bytes", info.Size())} {
+			cellText := creator.NewParagraph(text)
+			cellText.SetFont(regular)
+			cellText.SetFontSize(10)
+			cell := table.NewCell()
+			cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+			cell.SetContent(cellText)
+		}
+		fmt.Printf("Embedded: %s (%d bytes)\n", path, info.Size())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = c.Draw(table)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// loadFontAsset loads a TrueType font from the embedded assets.
+func loadFontAsset(name string) (*pdf.PdfFont, error) {
+	f, err := assets.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	font, err := newPdfFontFromTTFReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return font, nil
+}
+
+// newPdfFontFromTTFReader loads a TrueType font from a reader.  The library only loads fonts from files, so the font
+// is copied to a temporary file, which is removed after loading (the font data is kept in memory by the font).
+func newPdfFontFromTTFReader(r io.Reader) (*pdf.PdfFont, error) {
+	tmp, err := ioutil.TempFile("", "font-*.ttf")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return pdf.NewPdfFontFromTTFFile(tmp.Name())
+}
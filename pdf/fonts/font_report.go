@@ -0,0 +1,273 @@
+/*
+ * Report the fonts used in a PDF file, e.g. for font license compliance checks.
+ *
+ * Lists every font with its name, subtype (Type1, TrueType, Type0 with the CIDFont subtype, Type3, ...), whether it
+ * is embedded (and subset) or only referenced by name, and the pages on which it is used.
+ *
+ * The fonts are found through the page resources, including the resources of form XObjects used on the pages.  A
+ * font can be listed under different resource names on different pages (e.g. /F1 on page 1 and /F3 on page 2), so
+ * the fonts are deduplicated on the underlying font object rather than on the resource name.
+ *
+ * Run as: go run font_report.go input.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+// FontInfo describes a font used in the document.
+type FontInfo struct {
+	BaseFont string
+	Subtype  string // Font subtype, for Type0 fonts including the descendant CIDFont subtype.
+	Embedded bool
+	Subset   bool // Subset fonts have a tag such as ABCDEF+ prefixed to the name.
+
+	ResourceNames []string // Resource names the font appears under.
+	Pages         []int
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run font_report.go input.pdf\n")
+		os.Exit(1)
+	}
+
+	// Enable debug-level logging.
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := os.Args[1]
+
+	fonts, err := getFontReport(inputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Fonts in %s: %d\n", inputPath, len(fonts))
+	for i, font := range fonts {
+		embedded := "not embedded"
+		if font.Embedded {
+			embedded = "embedded"
+			if font.Subset {
+				embedded = "embedded subset"
+			}
+		}
+		fmt.Printf("%d. %s\n", i+1, font.BaseFont)
+		fmt.Printf("   Type: %s, %s\n", font.Subtype, embedded)
+		names := font.ResourceNames
+		if len(names) > 5 {
+			names = append(names[:5:5], fmt.Sprintf("... (%d names)", len(font.ResourceNames)))
+		}
+		fmt.Printf("   Resource names: %s\n", strings.Join(names, ", "))
+		fmt.Printf("   Pages: %s\n", formatPageRanges(font.Pages))
+	}
+}
+
+// Returns the fonts used in the document, in order of first use.
+func getFontReport(inputPath string) ([]*FontInfo, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fonts keyed by the underlying font object (indirect object or dictionary).
+	fontMap := map[pdfcore.PdfObject]*FontInfo{}
+	fonts := []*FontInfo{}
+
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+
+		visited := map[*pdfcore.PdfObjectStream]bool{}
+		err = collectFonts(page.Resources, func(name pdfcore.PdfObjectName, fontObj pdfcore.PdfObject) {
+			info, has := fontMap[fontObj]
+			if !has {
+				info = newFontInfo(fontObj)
+				fontMap[fontObj] = info
+				fonts = append(fonts, info)
+			}
+			if !containsString(info.ResourceNames, string(name)) {
+				info.ResourceNames = append(info.ResourceNames, string(name))
+			}
+			if len(info.Pages) == 0 || info.Pages[len(info.Pages)-1] != pageNum {
+				info.Pages = append(info.Pages, pageNum)
+			}
+		}, visited)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, info := range fonts {
+		sort.Strings(info.ResourceNames)
+	}
+
+	return fonts, nil
+}
+
+// Calls onFont for each font in the resources, and in the resources of the form XObjects in the resources.
+func collectFonts(resources *pdf.PdfPageResources, onFont func(pdfcore.PdfObjectName, pdfcore.PdfObject),
+	visited map[*pdfcore.PdfObjectStream]bool) error {
+	if resources == nil {
+		return nil
+	}
+
+	if fontDict, ok := pdfcore.TraceToDirectObject(resources.Font).(*pdfcore.PdfObjectDictionary); ok {
+		for _, name := range fontDict.Keys() {
+			fontObj, found := resources.GetFontByName(name)
+			if !found {
+				continue
+			}
+			onFont(name, fontObj)
+		}
+	}
+
+	xobjDict, ok := pdfcore.TraceToDirectObject(resources.XObject).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	for _, name := range xobjDict.Keys() {
+		stream, xtype := resources.GetXObjectByName(name)
+		if xtype != pdf.XObjectTypeForm || visited[stream] {
+			continue
+		}
+		visited[stream] = true
+
+		xform, err := pdf.NewXObjectFormFromStream(stream)
+		if err != nil {
+			return err
+		}
+		err = collectFonts(xform.Resources, onFont, visited)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Gets the font properties from the font dictionary.
+func newFontInfo(fontObj pdfcore.PdfObject) *FontInfo {
+	info := &FontInfo{BaseFont: "(unnamed)", Subtype: "unknown"}
+
+	fontDict, ok := pdfcore.TraceToDirectObject(fontObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		unicommon.Log.Debug("Font not a dictionary (%T)", fontObj)
+		return info
+	}
+
+	if name, ok := pdfcore.TraceToDirectObject(fontDict.Get("BaseFont")).(*pdfcore.PdfObjectName); ok {
+		info.BaseFont = string(*name)
+	} else if name, ok := pdfcore.TraceToDirectObject(fontDict.Get("Name")).(*pdfcore.PdfObjectName); ok {
+		// Type3 fonts do not have a BaseFont.
+		info.BaseFont = string(*name)
+	}
+	if idx := strings.Index(info.BaseFont, "+"); idx == 6 {
+		info.Subset = true
+	}
+
+	subtype, ok := pdfcore.TraceToDirectObject(fontDict.Get("Subtype")).(*pdfcore.PdfObjectName)
+	if ok {
+		info.Subtype = string(*subtype)
+	}
+
+	descriptorDict := fontDict
+	switch info.Subtype {
+	case "Type0":
+		// Composite font: the glyphs and font descriptor are in the descendant CIDFont.
+		arr, ok := pdfcore.TraceToDirectObject(fontDict.Get("DescendantFonts")).(*pdfcore.PdfObjectArray)
+		if !ok || len(*arr) == 0 {
+			return info
+		}
+		descendant, ok := pdfcore.TraceToDirectObject((*arr)[0]).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			return info
+		}
+		if cidSubtype, ok := pdfcore.TraceToDirectObject(descendant.Get("Subtype")).(*pdfcore.PdfObjectName); ok {
+			info.Subtype += "/" + string(*cidSubtype)
+		}
+		descriptorDict = descendant
+	case "Type3":
+		// Type3 glyphs are defined by content streams in the font itself.
+		info.Embedded = true
+		return info
+	}
+
+	descriptor, ok := pdfcore.TraceToDirectObject(descriptorDict.Get("FontDescriptor")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		// No font descriptor: one of the standard 14 fonts, never embedded.
+		return info
+	}
+	for _, key := range []pdfcore.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+		if descriptor.Get(key) != nil {
+			info.Embedded = true
+		}
+	}
+
+	return info
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Formats a sorted list of page numbers as ranges, e.g. 1-3, 5, 7-9.
+func formatPageRanges(pages []int) string {
+	parts := []string{}
+	for i := 0; i < len(pages); {
+		j := i
+		for j+1 < len(pages) && pages[j+1] == pages[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", pages[i], pages[j]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d", pages[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,325 @@
+/*
+ * Add a Print and an Email push button to the pages of a PDF file.
+ *
+ * The buttons are push button fields of the interactive form (AcroForm), added to the form of the file if it has
+ * one, with their actions set on the widgets:
+ * - Print: the named action Print, which opens the print dialog of the viewer, as File > Print does.
+ * - Email: a URI action with a mailto URL, which opens a new message to the address in the email program, with the
+ *   subject filled in.  With -submit, a SubmitForm action to the mailto URL is used instead, submitting the whole
+ *   PDF: the viewer opens a new message with the document attached, including the filled in fields.
+ * The buttons are placed in the lower right corner of the page given by -page, or of every page with -page 0 (the
+ * widgets of all pages belong to the same two fields).  Each button has appearance streams, a normal and a down
+ * (pressed) appearance with the caption, so that it is displayed the same way in all viewers.
+ *
+ * Viewers differ in the actions they run, and many restrict them:
+ * - Adobe Acrobat and Reader run all these actions, but ask for permission before opening a mailto URL or
+ *   submitting, depending on the security settings.  The PDF is attached by -submit in Acrobat and Reader only.
+ * - Browser viewers and most other viewers run the Print action and open mailto URLs (after asking, or according to
+ *   the browser settings), but do not submit forms, so the -submit button does nothing there.
+ * - Viewers without form support, or in a restricted (protected) mode, show the buttons but do nothing on a click.
+ * As fallbacks, the tooltip of each button tells how to do the same by hand (File > Print, the email address), and
+ * the buttons are not printed, so that a printed copy does not show buttons that do not work on paper.
+ *
+ * Run as: go run action_buttons.go [-page 1] [-subject text] [-submit] -email address input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run action_buttons.go [-page 1] [-subject text] [-submit] -email address input.pdf " +
+	"output.pdf\n"
+
+// Field flags (Ff).
+const fieldFlagPushbutton = 1 << 16
+
+// Submit form action flags: submit the whole PDF instead of the field values.
+const submitFlagSubmitPDF = 1 << 8
+
+// Layout of the buttons, in the lower right corner of the page.
+const (
+	margin        = 36.0
+	buttonWidth   = 72.0
+	buttonHeight  = 24.0
+	buttonSpacing = 8.0
+	fontSize      = 11.0
+)
+
+// The fill colors of the buttons, normal and pressed.
+var (
+	buttonColor = []float64{0.16, 0.38, 0.66}
+	downColor   = []float64{0.10, 0.25, 0.45}
+)
+
+// button is a push button field with its caption and action.
+type button struct {
+	field   *pdf.PdfField
+	caption string
+	action  *pdfcore.PdfObjectDictionary
+}
+
+func main() {
+	pageNum := 0
+	email := ""
+	subject := ""
+	submit := false
+	flag.IntVar(&pageNum, "page", 1, "Page to add the buttons to, 0 for all pages")
+	flag.StringVar(&email, "email", "", "Email address of the Email button (required)")
+	flag.StringVar(&subject, "subject", "", "Subject of the email (default: the name of the output file)")
+	flag.BoolVar(&submit, "submit", false, "Submit the PDF as an email attachment instead of opening a message")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 || email == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+	if subject == "" {
+		subject = filepath.Base(outputPath)
+	}
+
+	err := addActionButtons(inputPath, outputPath, pageNum, email, subject, submit)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func addActionButtons(inputPath, outputPath string, pageNum int, email, subject string, submit bool) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Encrypted PDF, a password is required")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if pageNum < 0 || pageNum > numPages {
+		return fmt.Errorf("Page %d out of range (1-%d)", pageNum, numPages)
+	}
+
+	// The form of the file, or a new one.
+	form := pdfReader.AcroForm
+	if form == nil {
+		form = pdf.NewPdfAcroForm()
+	}
+	fields := []*pdf.PdfField{}
+	if form.Fields != nil {
+		fields = *form.Fields
+	}
+	for _, field := range fields {
+		name, ok := pdfcore.TraceToDirectObject(field.T).(*pdfcore.PdfObjectString)
+		if ok && (string(*name) == "print_button" || string(*name) == "email_button") {
+			return fmt.Errorf("The form already has a field named %s", string(*name))
+		}
+	}
+
+	mailto := "mailto:" + email + "?subject=" + url.PathEscape(subject)
+	emailAction := pdfcore.MakeDict()
+	emailTooltip := ""
+	if submit {
+		fileSpec := pdfcore.MakeDict()
+		fileSpec.Set("FS", pdfcore.MakeName("URL"))
+		fileSpec.Set("F", pdfcore.MakeString(mailto))
+		emailAction.Set("S", pdfcore.MakeName("SubmitForm"))
+		emailAction.Set("F", fileSpec)
+		emailAction.Set("Flags", pdfcore.MakeInteger(submitFlagSubmitPDF))
+		emailTooltip = fmt.Sprintf("Email this document to %s (or attach it to an email by hand)", email)
+	} else {
+		emailAction.Set("S", pdfcore.MakeName("URI"))
+		emailAction.Set("URI", pdfcore.MakeString(mailto))
+		emailTooltip = fmt.Sprintf("Send an email to %s", email)
+	}
+	printAction := pdfcore.MakeDict()
+	printAction.Set("S", pdfcore.MakeName("Named"))
+	printAction.Set("N", pdfcore.MakeName("Print"))
+
+	buttons := []button{
+		{field: newButtonField("print_button", "Print this document (or use File > Print)"), caption: "Print",
+			action: printAction},
+		{field: newButtonField("email_button", emailTooltip), caption: "Email", action: emailAction},
+	}
+
+	// The font of the captions, in the resources of the appearance streams.
+	helvetica := pdfcore.MakeDict()
+	helvetica.Set("Type", pdfcore.MakeName("Font"))
+	helvetica.Set("Subtype", pdfcore.MakeName("Type1"))
+	helvetica.Set("BaseFont", pdfcore.MakeName("Helvetica"))
+	helvetica.Set("Encoding", pdfcore.MakeName("WinAnsiEncoding"))
+	resources := pdf.NewPdfPageResources()
+	resources.SetFontByName("Helv", pdfcore.MakeIndirectObject(helvetica))
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+
+		if pageNum == 0 || pageNum == i+1 {
+			mediaBox, err := page.GetMediaBox()
+			if err != nil {
+				return err
+			}
+			x := mediaBox.Urx - margin - float64(len(buttons))*buttonWidth - float64(len(buttons)-1)*buttonSpacing
+			for _, b := range buttons {
+				widget := addButtonWidget(page, b, x, mediaBox.Lly+margin, resources)
+				b.field.KidsA = append(b.field.KidsA, widget.PdfAnnotation)
+				x += buttonWidth + buttonSpacing
+			}
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, b := range buttons {
+		fields = append(fields, b.field)
+	}
+	form.Fields = &fields
+	if form.DA == nil {
+		form.DA = pdfcore.MakeString(fmt.Sprintf("/Helv %g Tf 0 g", fontSize))
+	}
+	if form.DR == nil {
+		form.DR = resources
+	}
+	err = pdfWriter.SetForms(form)
+	if err != nil {
+		return err
+	}
+
+	pdfWriter.AddOutlineTree(pdfReader.GetOutlineTree())
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Returns a new push button field with the name and tooltip (alternate name).
+func newButtonField(name, tooltip string) *pdf.PdfField {
+	field := pdf.NewPdfField()
+	field.FT = pdfcore.MakeName("Btn")
+	field.T = pdfcore.MakeString(name)
+	field.TU = pdfcore.MakeString(tooltip)
+	field.Ff = pdfcore.MakeInteger(fieldFlagPushbutton)
+	return field
+}
+
+// Adds a widget of the button to the page with the lower left corner at (x, y), in PDF coordinates.
+func addButtonWidget(page *pdf.PdfPage, b button, x, y float64,
+	resources *pdf.PdfPageResources) *pdf.PdfAnnotationWidget {
+	widget := pdf.NewPdfAnnotationWidget()
+	widget.Rect = pdfcore.MakeArrayFromFloats([]float64{x, y, x + buttonWidth, y + buttonHeight})
+	// No flags: the button is shown on screen but not printed.
+	widget.F = pdfcore.MakeInteger(0)
+	widget.P = page.GetPageAsIndirectObject()
+	widget.Parent = b.field.GetContainingPdfObject()
+	widget.A = b.action
+	// Push down when clicked, showing the down appearance.
+	widget.H = pdfcore.MakeName("P")
+
+	ap := pdfcore.MakeDict()
+	ap.Set("N", makeButtonAppearance(b.caption, buttonColor, resources))
+	ap.Set("D", makeButtonAppearance(b.caption, downColor, resources))
+	widget.AP = ap
+
+	// The appearance characteristics, used by viewers that regenerate the appearance.
+	mk := pdfcore.MakeDict()
+	mk.Set("BG", pdfcore.MakeArrayFromFloats(buttonColor))
+	mk.Set("CA", pdfcore.MakeString(b.caption))
+	widget.MK = mk
+
+	page.Annotations = append(page.Annotations, widget.PdfAnnotation)
+	return widget
+}
+
+// Returns the appearance stream of a button: a rounded box of the color with the caption centered in white.
+func makeButtonAppearance(caption string, color []float64,
+	resources *pdf.PdfPageResources) *pdfcore.PdfObjectStream {
+	captionWidth := textWidth(caption, fonts.NewFontHelvetica(), fontSize)
+	content := fmt.Sprintf("%.2f %.2f %.2f rg\n", color[0], color[1], color[2]) +
+		roundedRectPath(0, 0, buttonWidth, buttonHeight, 4) + "f\n" +
+		fmt.Sprintf("BT 1 g /Helv %g Tf %.2f %.2f Td %s Tj ET\n", fontSize, (buttonWidth-captionWidth)/2,
+			(buttonHeight-fontSize*0.7)/2, pdfcore.MakeString(caption).DefaultWriteString())
+
+	xform := pdf.NewXObjectForm()
+	xform.BBox = pdfcore.MakeArrayFromFloats([]float64{0, 0, buttonWidth, buttonHeight})
+	xform.Resources = resources
+	xform.SetContentStream([]byte(content), nil)
+	return xform.ToPdfObject().(*pdfcore.PdfObjectStream)
+}
+
+// Returns the path of a rectangle with rounded corners of radius r.
+func roundedRectPath(x, y, width, height, r float64) string {
+	k := 0.5523 * r
+	return fmt.Sprintf("%.2f %.2f m ", x+r, y) +
+		fmt.Sprintf("%.2f %.2f l ", x+width-r, y) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", x+width-r+k, y, x+width, y+r-k, x+width, y+r) +
+		fmt.Sprintf("%.2f %.2f l ", x+width, y+height-r) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", x+width, y+height-r+k, x+width-r+k, y+height, x+width-r,
+			y+height) +
+		fmt.Sprintf("%.2f %.2f l ", x+r, y+height) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", x+r-k, y+height, x, y+height-r+k, x, y+height-r) +
+		fmt.Sprintf("%.2f %.2f l ", x, y+r) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c h\n", x, y+r-k, x+r-k, y, x+r, y)
+}
+
+// Returns the width of the text in the font.
+func textWidth(text string, font fonts.Font, size float64) float64 {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetEnableWrap(false)
+	return p.Width()
+}
@@ -0,0 +1,472 @@
+/*
+ * Create a PDF file with an interactive form (AcroForm): text fields, a checkbox, a radio button group, a dropdown
+ * and a push button that submits the form.
+ *
+ * The labels are laid out with the creator.  The fields are PdfField objects, each with its widget annotations
+ * (the areas on the page where the field is shown), which are added to the page and to the form of the creator.
+ *
+ * Every widget has appearance streams, so that the fields are displayed (and printed) the same way in all viewers
+ * before any user interaction: a box with the value for text fields and the dropdown, and the on and off states of
+ * the checkbox and the radio buttons.  The fonts used by the viewers to display edited values are in the default
+ * resources of the form.
+ *
+ * The widgets are added to the page in the order of the layout, top to bottom and left to right, and the page tab
+ * order is set to row order, so that the tab key moves through the fields in this sequence.
+ *
+ * Run as: go run create_form.go [-url https://example.com/submit] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run create_form.go [-url https://example.com/submit] output.pdf\n"
+
+// Field flags (Ff).
+const (
+	fieldFlagRequired      = 1 << 1
+	fieldFlagMultiline     = 1 << 12
+	fieldFlagNoToggleToOff = 1 << 14
+	fieldFlagRadio         = 1 << 15
+	fieldFlagPushbutton    = 1 << 16
+	fieldFlagCombo         = 1 << 17
+)
+
+// Annotation flags.
+const annotFlagPrint = 4
+
+// Submit form action flags: submit in HTML form format instead of FDF.
+const submitFlagExportFormat = 1 << 2
+
+// Layout: position of the labels and the fields, from the upper left corner of the page.
+const (
+	margin      = 72.0
+	fieldX      = 200.0
+	fieldWidth  = 300.0
+	fieldHeight = 20.0
+	boxSize     = 14.0
+	rowSpacing  = 14.0
+	fontSize    = 10.0
+)
+
+// formBuilder creates the fields and their widgets on the page.
+type formBuilder struct {
+	c         *creator.Creator
+	page      *pdf.PdfPage
+	fields    []*pdf.PdfField
+	resources *pdf.PdfPageResources
+}
+
+func main() {
+	submitURL := ""
+	flag.StringVar(&submitURL, "url", "https://example.com/submit", "URL the form is submitted to")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := createForm(outputPath, submitURL)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createForm(outputPath, submitURL string) error {
+	c := creator.New()
+
+	// The page is created here rather than with c.NewPage, to be able to add the widget annotations to it.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: c.Width(), Ury: c.Height()}
+	page.Resources = pdf.NewPdfPageResources()
+	// Tab order by rows.
+	page.Tabs = pdfcore.MakeName("R")
+	err := c.AddPage(page)
+	if err != nil {
+		return err
+	}
+
+	// The fonts for the appearance streams and for the viewers, as default resources of the form.
+	helvetica := pdfcore.MakeDict()
+	helvetica.Set("Type", pdfcore.MakeName("Font"))
+	helvetica.Set("Subtype", pdfcore.MakeName("Type1"))
+	helvetica.Set("BaseFont", pdfcore.MakeName("Helvetica"))
+	helvetica.Set("Encoding", pdfcore.MakeName("WinAnsiEncoding"))
+	zapfDingbats := pdfcore.MakeDict()
+	zapfDingbats.Set("Type", pdfcore.MakeName("Font"))
+	zapfDingbats.Set("Subtype", pdfcore.MakeName("Type1"))
+	zapfDingbats.Set("BaseFont", pdfcore.MakeName("ZapfDingbats"))
+	resources := pdf.NewPdfPageResources()
+	resources.SetFontByName("Helv", pdfcore.MakeIndirectObject(helvetica))
+	resources.SetFontByName("ZaDb", pdfcore.MakeIndirectObject(zapfDingbats))
+
+	fb := &formBuilder{c: c, page: page, resources: resources}
+
+	y := margin
+	title := creator.NewParagraph("Registration")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(18)
+	title.SetPos(margin, y)
+	err = c.Draw(title)
+	if err != nil {
+		return err
+	}
+	y += title.Height() + 24
+
+	err = fb.addTextField("name", "Name", "Your full name", "", y, fieldHeight, fieldFlagRequired)
+	if err != nil {
+		return err
+	}
+	y += fieldHeight + rowSpacing
+
+	err = fb.addTextField("email", "Email", "Your email address", "", y, fieldHeight, fieldFlagRequired)
+	if err != nil {
+		return err
+	}
+	y += fieldHeight + rowSpacing
+
+	countries := []string{"Belgium", "France", "Germany", "Iceland", "Netherlands", "United Kingdom"}
+	err = fb.addDropdown("country", "Country", "Country of residence", countries, countries[0], y)
+	if err != nil {
+		return err
+	}
+	y += fieldHeight + rowSpacing
+
+	err = fb.addRadioGroup("contact", "Contact by", "Preferred way to contact you",
+		[]string{"Email", "Phone", "Mail"}, "Email", y)
+	if err != nil {
+		return err
+	}
+	y += fieldHeight + rowSpacing
+
+	err = fb.addCheckbox("newsletter", "Newsletter", "Send me the newsletter", false, y)
+	if err != nil {
+		return err
+	}
+	y += fieldHeight + rowSpacing
+
+	err = fb.addTextField("comments", "Comments", "Comments or questions", "", y, 4*fieldHeight,
+		fieldFlagMultiline)
+	if err != nil {
+		return err
+	}
+	y += 4*fieldHeight + 2*rowSpacing
+
+	err = fb.addSubmitButton("submit", "Submit", submitURL, y)
+	if err != nil {
+		return err
+	}
+
+	form := pdf.NewPdfAcroForm()
+	form.Fields = &fb.fields
+	form.DR = resources
+	form.DA = pdfcore.MakeString(fmt.Sprintf("/Helv %g Tf 0 g", fontSize))
+	err = c.SetForms(form)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %d fields\n", len(fb.fields))
+
+	return c.WriteToFile(outputPath)
+}
+
+// Adds a text field with its label.  Multiline fields have the given height.
+func (fb *formBuilder) addTextField(name, label, tooltip, value string, y, height float64, flags int64) error {
+	err := fb.drawLabel(label, margin, y, fieldHeight)
+	if err != nil {
+		return err
+	}
+
+	field := newField("Tx", name, tooltip, flags)
+	field.V = pdfcore.MakeString(value)
+	field.DA = pdfcore.MakeString(fmt.Sprintf("/Helv %g Tf 0 g", fontSize))
+
+	// Single line text is centered vertically, multiline text starts at the top.
+	textY := (height - fontSize*0.7) / 2
+	if flags&fieldFlagMultiline != 0 {
+		textY = height - 2 - fontSize
+	}
+	normal := fb.makeAppearance(fieldWidth, height, boxContent(fieldWidth, height)+textContent(value, 4, textY))
+
+	widget := fb.addWidget(field, fieldX, y, fieldWidth, height)
+	widget.AP = makeAppearanceDict(normal)
+	widget.MK = makeMK()
+	return nil
+}
+
+// Adds a dropdown (combo box) with the options and the selected value.
+func (fb *formBuilder) addDropdown(name, label, tooltip string, options []string, value string, y float64) error {
+	err := fb.drawLabel(label, margin, y, fieldHeight)
+	if err != nil {
+		return err
+	}
+
+	field := newField("Ch", name, tooltip, fieldFlagCombo)
+	opts := pdfcore.PdfObjectArray{}
+	for _, option := range options {
+		opts = append(opts, pdfcore.MakeString(option))
+	}
+	field.V = pdfcore.MakeString(value)
+	field.DV = pdfcore.MakeString(value)
+	field.DA = pdfcore.MakeString(fmt.Sprintf("/Helv %g Tf 0 g", fontSize))
+	// The options are not part of the field model, they are set in the field dictionary.
+	if dict, ok := pdfcore.TraceToDirectObject(field.GetContainingPdfObject()).(*pdfcore.PdfObjectDictionary); ok {
+		dict.Set("Opt", &opts)
+	}
+
+	// The value, and an arrow at the right as a hint that this is a dropdown.
+	arrowX := fieldWidth - fieldHeight/2
+	arrow := fmt.Sprintf("0.3 g %.2f %.2f m %.2f %.2f l %.2f %.2f l f\n", arrowX-4, fieldHeight/2+2, arrowX+4,
+		fieldHeight/2+2, arrowX, fieldHeight/2-3)
+	content := boxContent(fieldWidth, fieldHeight) + arrow + textContent(value, 4, (fieldHeight-fontSize*0.7)/2)
+	normal := fb.makeAppearance(fieldWidth, fieldHeight, content)
+
+	widget := fb.addWidget(field, fieldX, y, fieldWidth, fieldHeight)
+	widget.AP = makeAppearanceDict(normal)
+	widget.MK = makeMK()
+	return nil
+}
+
+// Adds a group of radio buttons, one per option, of which one can be selected.  The buttons of a group share the
+// field, each widget has an appearance for its own option (on) and for Off.
+func (fb *formBuilder) addRadioGroup(name, label, tooltip string, options []string, value string, y float64) error {
+	err := fb.drawLabel(label, margin, y, fieldHeight)
+	if err != nil {
+		return err
+	}
+
+	field := newField("Btn", name, tooltip, fieldFlagRadio|fieldFlagNoToggleToOff)
+	field.V = pdfcore.MakeName(value)
+	field.DV = pdfcore.MakeName(value)
+
+	r := boxSize / 2
+	off := fb.makeAppearance(boxSize, boxSize, "1 g 0.5 G 1 w "+circlePath(r, r, r-0.5)+"b\n")
+	on := fb.makeAppearance(boxSize, boxSize, "1 g 0.5 G 1 w "+circlePath(r, r, r-0.5)+"b\n0 g "+
+		circlePath(r, r, r/2.5)+"f\n")
+
+	x := fieldX
+	boxY := y + (fieldHeight-boxSize)/2
+	for _, option := range options {
+		widget := fb.addWidget(field, x, boxY, boxSize, boxSize)
+		ap := pdfcore.MakeDict()
+		n := pdfcore.MakeDict()
+		n.Set(pdfcore.PdfObjectName(option), on)
+		n.Set("Off", off)
+		ap.Set("N", n)
+		widget.AP = ap
+		// The appearance state is the option if selected.
+		if option == value {
+			widget.AS = pdfcore.MakeName(option)
+		} else {
+			widget.AS = pdfcore.MakeName("Off")
+		}
+		mk := makeMK()
+		mk.Set("CA", pdfcore.MakeString("l"))
+		widget.MK = mk
+
+		width, err := fb.drawText(option, x+boxSize+4, y, fieldHeight)
+		if err != nil {
+			return err
+		}
+		x += boxSize + 4 + width + 20
+	}
+	return nil
+}
+
+// Adds a checkbox with the text next to it.  The on state is named Yes.
+func (fb *formBuilder) addCheckbox(name, label, text string, checked bool, y float64) error {
+	err := fb.drawLabel(label, margin, y, fieldHeight)
+	if err != nil {
+		return err
+	}
+
+	state := pdfcore.MakeName("Off")
+	if checked {
+		state = pdfcore.MakeName("Yes")
+	}
+	field := newField("Btn", name, text, 0)
+	field.V = state
+
+	box := "1 g 0.5 G 1 w 0.5 0.5 " + fmt.Sprintf("%.2f %.2f re b\n", boxSize-1, boxSize-1)
+	off := fb.makeAppearance(boxSize, boxSize, box)
+	// The check mark is character 4 of ZapfDingbats.
+	on := fb.makeAppearance(boxSize, boxSize, box+fmt.Sprintf("BT 0 g /ZaDb %g Tf 2.5 3 Td (4) Tj ET\n",
+		boxSize-4))
+
+	widget := fb.addWidget(field, fieldX, y+(fieldHeight-boxSize)/2, boxSize, boxSize)
+	ap := pdfcore.MakeDict()
+	n := pdfcore.MakeDict()
+	n.Set("Yes", on)
+	n.Set("Off", off)
+	ap.Set("N", n)
+	widget.AP = ap
+	widget.AS = state
+	mk := makeMK()
+	mk.Set("CA", pdfcore.MakeString("4"))
+	widget.MK = mk
+
+	_, err = fb.drawText(text, fieldX+boxSize+4, y, fieldHeight)
+	return err
+}
+
+// Adds a push button which submits the form fields to the URL in HTML form format.
+func (fb *formBuilder) addSubmitButton(name, caption, url string, y float64) error {
+	field := newField("Btn", name, caption, fieldFlagPushbutton)
+
+	width := 100.0
+	height := 24.0
+	captionWidth := textWidth(caption, fonts.NewFontHelvetica(), fontSize+2)
+	content := fmt.Sprintf("0.16 0.38 0.66 rg 0 0 %.2f %.2f re f\n", width, height) +
+		fmt.Sprintf("BT 1 g /Helv %g Tf %.2f %.2f Td %s Tj ET\n", fontSize+2, (width-captionWidth)/2,
+			(height-(fontSize+2)*0.7)/2, pdfcore.MakeString(caption).DefaultWriteString())
+	normal := fb.makeAppearance(width, height, content)
+
+	fileSpec := pdfcore.MakeDict()
+	fileSpec.Set("FS", pdfcore.MakeName("URL"))
+	fileSpec.Set("F", pdfcore.MakeString(url))
+	action := pdfcore.MakeDict()
+	action.Set("S", pdfcore.MakeName("SubmitForm"))
+	action.Set("F", fileSpec)
+	action.Set("Flags", pdfcore.MakeInteger(submitFlagExportFormat))
+
+	widget := fb.addWidget(field, fieldX, y, width, height)
+	widget.AP = makeAppearanceDict(normal)
+	widget.A = action
+	// Push down when clicked.
+	widget.H = pdfcore.MakeName("P")
+	mk := pdfcore.MakeDict()
+	mk.Set("BG", pdfcore.MakeArrayFromFloats([]float64{0.16, 0.38, 0.66}))
+	mk.Set("CA", pdfcore.MakeString(caption))
+	widget.MK = mk
+	return nil
+}
+
+// Returns a new terminal field of the type with the name, tooltip (alternate name) and flags.
+func newField(fieldType, name, tooltip string, flags int64) *pdf.PdfField {
+	field := pdf.NewPdfField()
+	field.FT = pdfcore.MakeName(fieldType)
+	field.T = pdfcore.MakeString(name)
+	field.TU = pdfcore.MakeString(tooltip)
+	if flags != 0 {
+		field.Ff = pdfcore.MakeInteger(flags)
+	}
+	return field
+}
+
+// Adds a widget annotation for the field at the position from the upper left corner of the page.  The field is
+// added to the form with its first widget.
+func (fb *formBuilder) addWidget(field *pdf.PdfField, x, y, width, height float64) *pdf.PdfAnnotationWidget {
+	mediaBox := fb.page.MediaBox
+
+	widget := pdf.NewPdfAnnotationWidget()
+	// The annotation rectangle is in PDF coordinates, with the origin in the lower left corner.
+	widget.Rect = pdfcore.MakeArrayFromFloats([]float64{mediaBox.Llx + x, mediaBox.Ury - y - height,
+		mediaBox.Llx + x + width, mediaBox.Ury - y})
+	widget.F = pdfcore.MakeInteger(annotFlagPrint)
+	widget.P = fb.page.GetPageAsIndirectObject()
+	widget.Parent = field.GetContainingPdfObject()
+
+	if len(field.KidsA) == 0 {
+		fb.fields = append(fb.fields, field)
+	}
+	field.KidsA = append(field.KidsA, widget.PdfAnnotation)
+	fb.page.Annotations = append(fb.page.Annotations, widget.PdfAnnotation)
+	return widget
+}
+
+// Returns an appearance stream (form XObject) of the size with the content, using the form fonts.
+func (fb *formBuilder) makeAppearance(width, height float64, content string) *pdfcore.PdfObjectStream {
+	xform := pdf.NewXObjectForm()
+	xform.BBox = pdfcore.MakeArrayFromFloats([]float64{0, 0, width, height})
+	xform.Resources = fb.resources
+	xform.SetContentStream([]byte(content), nil)
+	return xform.ToPdfObject().(*pdfcore.PdfObjectStream)
+}
+
+// Returns the appearance dictionary with the normal appearance.
+func makeAppearanceDict(normal *pdfcore.PdfObjectStream) *pdfcore.PdfObjectDictionary {
+	ap := pdfcore.MakeDict()
+	ap.Set("N", normal)
+	return ap
+}
+
+// Returns the appearance characteristics (border and background colors) used by viewers when regenerating
+// appearances.
+func makeMK() *pdfcore.PdfObjectDictionary {
+	mk := pdfcore.MakeDict()
+	mk.Set("BC", pdfcore.MakeArrayFromFloats([]float64{0.5, 0.5, 0.5}))
+	mk.Set("BG", pdfcore.MakeArrayFromFloats([]float64{1, 1, 1}))
+	return mk
+}
+
+// Returns the content drawing a white box with a gray border.
+func boxContent(width, height float64) string {
+	return fmt.Sprintf("1 g 0.5 G 1 w 0.5 0.5 %.2f %.2f re b\n", width-1, height-1)
+}
+
+// Returns the content showing the text at the position, marked as variable text (Tx) and clipped to the box.
+func textContent(text string, x, y float64) string {
+	if text == "" {
+		return "/Tx BMC EMC\n"
+	}
+	return fmt.Sprintf("/Tx BMC\nq BT 0 g /Helv %g Tf %.2f %.2f Td %s Tj ET Q\nEMC\n", fontSize, x, y,
+		pdfcore.MakeString(text).DefaultWriteString())
+}
+
+// Returns the path of a circle with 4 Bezier curves.
+func circlePath(cx, cy, r float64) string {
+	k := 0.5523 * r
+	return fmt.Sprintf("%.2f %.2f m ", cx+r, cy) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", cx+r, cy+k, cx+k, cy+r, cx, cy+r) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", cx-k, cy+r, cx-r, cy+k, cx-r, cy) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", cx-r, cy-k, cx-k, cy-r, cx, cy-r) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", cx+k, cy-r, cx+r, cy-k, cx+r, cy)
+}
+
+// Draws a label for a row of the height.
+func (fb *formBuilder) drawLabel(label string, x, y, height float64) error {
+	p := creator.NewParagraph(label)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(fontSize + 1)
+	p.SetEnableWrap(false)
+	p.SetPos(x, y+(height-p.Height())/2)
+	return fb.c.Draw(p)
+}
+
+// Draws the text vertically centered in a row of the height and returns its width.
+func (fb *formBuilder) drawText(text string, x, y, height float64) (float64, error) {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(fontSize)
+	p.SetEnableWrap(false)
+	p.SetPos(x, y+(height-p.Height())/2)
+	return p.Width(), fb.c.Draw(p)
+}
+
+// Returns the width of the text in the font.
+func textWidth(text string, font fonts.Font, size float64) float64 {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetEnableWrap(false)
+	return p.Width()
+}
@@ -0,0 +1,390 @@
+/*
+ * Export the field values of a filled form (AcroForm) as JSON and as FDF.
+ *
+ * The fields of a form are a tree: a field name such as "address.city" is the field "city" with the parent field
+ * "address", and only the terminal fields (the leaves) have values.  The tree is traversed from the fields of the
+ * form, and the output follows it: in JSON, a parent field is an object with its kids as members, and in FDF a
+ * parent field has its kids in a Kids array.  The field type, flags and value are inherited from the parent fields
+ * if not set on a terminal field.  Kids without a name are the widget annotations of a field, not fields.
+ *
+ * The values are converted according to the field type:
+ * - text fields: the text, or "" if there is none,
+ * - checkboxes: true when on, false when off,
+ * - radio button groups: the selected option, or null if none is selected,
+ * - choice fields (list boxes and dropdowns): the selected option, or a list of the selected options if several
+ *   can be selected,
+ * - signature fields: whether the field is signed.
+ * Push buttons have no value and are left out.  Some viewers only update the appearance states of the widgets of
+ * checkboxes and radio buttons and not the field value, so when the value is missing the on widget is looked for.
+ *
+ * The FDF file has the values as they are in the form (e.g. the export value of a checkbox instead of true), so that
+ * it can be imported into the same form by a viewer.  Signature fields are not exported to FDF.  The JSON members
+ * are sorted by name.
+ *
+ * Run as: go run export_data.go [-json data.json] [-fdf data.fdf] input.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run export_data.go [-json data.json] [-fdf data.fdf] input.pdf\n"
+
+// Field flags (Ff).
+const (
+	fieldFlagRadio       = 1 << 15
+	fieldFlagPushbutton  = 1 << 16
+	fieldFlagMultiSelect = 1 << 21
+)
+
+// fieldNode is a field of the field tree: a parent field with kids, or a terminal field with a value.
+type fieldNode struct {
+	Name  string
+	T     *pdfcore.PdfObjectString // Partial name as in the form, for FDF.
+	Kids  []*fieldNode
+	Value interface{}       // Value of a terminal field for JSON.
+	V     pdfcore.PdfObject // Value of a terminal field as in the form, for FDF, or nil if not exported.
+}
+
+// inherited is the field attributes inherited from the parent fields.
+type inherited struct {
+	FT string
+	Ff int64
+	V  pdfcore.PdfObject
+}
+
+func main() {
+	jsonPath := ""
+	fdfPath := ""
+	flag.StringVar(&jsonPath, "json", "", "JSON output file (default: print to the console)")
+	flag.StringVar(&fdfPath, "fdf", "", "FDF output file")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	err := exportFormData(inputPath, jsonPath, fdfPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func exportFormData(inputPath, jsonPath, fdfPath string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	// Try decrypting with an empty password.
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return fmt.Errorf("The file is encrypted with a password")
+		}
+	}
+
+	if pdfReader.AcroForm == nil || pdfReader.AcroForm.Fields == nil {
+		return fmt.Errorf("No form in %s", inputPath)
+	}
+
+	nodes := buildFieldTree(*pdfReader.AcroForm.Fields, inherited{}, "")
+	fmt.Fprintf(os.Stderr, "Exported %d fields\n", countFields(nodes))
+
+	data, err := json.MarshalIndent(toJSON(nodes), "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if jsonPath == "" {
+		os.Stdout.Write(data)
+	} else {
+		err := ioutil.WriteFile(jsonPath, data, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "JSON written to %s\n", jsonPath)
+	}
+
+	if fdfPath != "" {
+		err := ioutil.WriteFile(fdfPath, makeFDF(nodes, filepath.Base(inputPath)), 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "FDF written to %s\n", fdfPath)
+	}
+	return nil
+}
+
+// Returns the nodes of the fields, with the attributes inherited from their parents.  Fields without a name are
+// widgets, or unnamed fields whose kids belong to the parent.  parentName is the full name of the parent, for
+// messages.
+func buildFieldTree(fields []*pdf.PdfField, parent inherited, parentName string) []*fieldNode {
+	nodes := []*fieldNode{}
+	byName := map[string]bool{}
+	for _, field := range fields {
+		attrs := parent
+		if field.FT != nil {
+			attrs.FT = string(*field.FT)
+		}
+		if ff, ok := pdfcore.TraceToDirectObject(field.Ff).(*pdfcore.PdfObjectInteger); ok {
+			attrs.Ff = int64(*ff)
+		}
+		if field.V != nil {
+			attrs.V = field.V
+		}
+
+		kids := []*pdf.PdfField{}
+		for _, kid := range field.KidsF {
+			if kf, ok := kid.(*pdf.PdfField); ok {
+				kids = append(kids, kf)
+			}
+		}
+
+		s, ok := pdfcore.TraceToDirectObject(field.T).(*pdfcore.PdfObjectString)
+		if !ok {
+			// An unnamed field: its named kids are listed with its siblings.
+			nodes = append(nodes, buildFieldTree(kids, attrs, parentName)...)
+			continue
+		}
+		name := decodePdfString(string(*s))
+		fullName := name
+		if parentName != "" {
+			fullName = parentName + "." + name
+		}
+		if byName[name] {
+			fmt.Fprintf(os.Stderr, "Skipping duplicate field %s\n", fullName)
+			continue
+		}
+		byName[name] = true
+
+		node := &fieldNode{Name: name, T: s}
+		if hasNamedKids(kids) {
+			node.Kids = buildFieldTree(kids, attrs, fullName)
+		} else {
+			exported := setValue(node, field, attrs)
+			if !exported {
+				continue
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// Returns true if one of the fields, or of the kids of an unnamed field, has a name.  Kids without a name are the
+// widgets of a terminal field.
+func hasNamedKids(kids []*pdf.PdfField) bool {
+	for _, kid := range kids {
+		if kid.T != nil {
+			return true
+		}
+		for _, k := range kid.KidsF {
+			if kf, ok := k.(*pdf.PdfField); ok && hasNamedKids([]*pdf.PdfField{kf}) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Sets the JSON and FDF values of the terminal field node from the field value.  Returns false if the field has no
+// value to export.
+func setValue(node *fieldNode, field *pdf.PdfField, attrs inherited) bool {
+	v := pdfcore.TraceToDirectObject(attrs.V)
+
+	switch attrs.FT {
+	case "Tx":
+		node.Value = ""
+		node.V = pdfcore.MakeString("")
+		if s, ok := v.(*pdfcore.PdfObjectString); ok {
+			node.Value = decodePdfString(string(*s))
+			node.V = s
+		}
+
+	case "Btn":
+		if attrs.Ff&fieldFlagPushbutton != 0 {
+			return false
+		}
+		state := buttonState(field, v)
+		node.V = pdfcore.MakeName(state)
+		if attrs.Ff&fieldFlagRadio != 0 {
+			if state == "Off" {
+				node.Value = nil
+			} else {
+				node.Value = state
+			}
+		} else {
+			node.Value = state != "Off"
+		}
+
+	case "Ch":
+		multiple := attrs.Ff&fieldFlagMultiSelect != 0
+		selected := []string{}
+		switch t := v.(type) {
+		case *pdfcore.PdfObjectString:
+			selected = append(selected, decodePdfString(string(*t)))
+			node.V = t
+		case *pdfcore.PdfObjectArray:
+			for _, obj := range *t {
+				if s, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectString); ok {
+					selected = append(selected, decodePdfString(string(*s)))
+				}
+			}
+			node.V = t
+		}
+		if multiple {
+			node.Value = selected
+		} else if len(selected) > 0 {
+			node.Value = selected[0]
+		} else {
+			node.Value = ""
+		}
+		if node.V == nil {
+			node.V = pdfcore.MakeString("")
+		}
+
+	case "Sig":
+		// The value is the signature dictionary if signed.  Not exported to FDF.
+		_, signed := v.(*pdfcore.PdfObjectDictionary)
+		node.Value = signed
+
+	default:
+		fmt.Fprintf(os.Stderr, "Skipping field %s of unknown type %q\n", node.Name, attrs.FT)
+		return false
+	}
+	return true
+}
+
+// Returns the state of a checkbox or radio button field: the name of the on state, or Off.  If the field has no
+// value, the state is taken from the widget that is on.
+func buttonState(field *pdf.PdfField, v pdfcore.PdfObject) string {
+	if name, ok := v.(*pdfcore.PdfObjectName); ok {
+		return string(*name)
+	}
+
+	// The widgets are read as kids of the field, or merged into the field if there is only one.
+	annots := field.KidsA
+	for _, kid := range field.KidsF {
+		if kf, ok := kid.(*pdf.PdfField); ok {
+			annots = append(annots, kf.KidsA...)
+		}
+	}
+	for _, annot := range annots {
+		if state, ok := pdfcore.TraceToDirectObject(annot.AS).(*pdfcore.PdfObjectName); ok && *state != "Off" {
+			return string(*state)
+		}
+	}
+	return "Off"
+}
+
+// Returns the number of terminal fields.
+func countFields(nodes []*fieldNode) int {
+	n := 0
+	for _, node := range nodes {
+		if node.Kids != nil {
+			n += countFields(node.Kids)
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+// Returns the nodes as nested maps for JSON: the terminal fields with their values, and the parent fields as maps of
+// their kids.
+func toJSON(nodes []*fieldNode) map[string]interface{} {
+	m := map[string]interface{}{}
+	for _, node := range nodes {
+		if node.Kids != nil {
+			m[node.Name] = toJSON(node.Kids)
+		} else {
+			m[node.Name] = node.Value
+		}
+	}
+	return m
+}
+
+// Returns an FDF file with the field values, for the form in the file named pdfName.
+func makeFDF(nodes []*fieldNode, pdfName string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%FDF-1.2\n%\xe2\xe3\xcf\xd3\n")
+	buf.WriteString("1 0 obj\n<< /FDF << /F " + pdfcore.MakeString(pdfName).DefaultWriteString() + " /Fields [\n")
+	writeFDFFields(&buf, nodes, 1)
+	buf.WriteString("] >> >>\nendobj\n")
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+// Writes the field dictionaries of the nodes, indented by the depth.
+func writeFDFFields(buf *bytes.Buffer, nodes []*fieldNode, depth int) {
+	indent := bytes.Repeat([]byte("  "), depth)
+	for _, node := range nodes {
+		if node.Kids == nil && node.V == nil {
+			continue
+		}
+		buf.Write(indent)
+		buf.WriteString("<< /T " + node.T.DefaultWriteString())
+		if node.Kids != nil {
+			buf.WriteString(" /Kids [\n")
+			writeFDFFields(buf, node.Kids, depth+1)
+			buf.Write(indent)
+			buf.WriteString("] >>\n")
+			continue
+		}
+		buf.WriteString(" /V " + node.V.DefaultWriteString() + " >>\n")
+	}
+}
+
+// Decodes a PDF text string: UTF-16BE with a byte order mark, or PDFDocEncoding (read as Latin-1).
+func decodePdfString(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := []uint16{}
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
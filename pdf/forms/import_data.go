@@ -0,0 +1,1135 @@
+/*
+ * Import form field values from an FDF or XFDF file into a form (AcroForm) template, as exported by export_data.go
+ * or by a viewer, and optionally flatten the filled form.
+ *
+ * The data file names the fields by their full names, either as a tree (Kids in FDF, nested field elements in XFDF)
+ * or with dots, e.g. "address.city".  The values are matched with the terminal fields of the template by full name.
+ * Fields in the data that are not in the form are reported and ignored, as are values that do not fit the field,
+ * e.g. a checkbox state that the checkbox does not have.
+ *
+ * The two formats encode the values differently:
+ * - FDF strings are PDFDocEncoding or UTF-16BE with a byte order mark, as in PDF, and XFDF is XML text (UTF-8).
+ * - Checkbox and radio button values are names in FDF (/Yes), and text in XFDF.  A checkbox also accepts true/false,
+ *   on/off, yes/no and 1/0 when it has a single on state, whatever the name of the state.
+ * - Multiple selected options of a list box are an array in FDF, and several value elements in XFDF.
+ * The values are written to the form as text strings (UTF-16BE if not ASCII) and names, whatever the data format.
+ *
+ * The appearance streams of the widgets are regenerated for the new values, so the form is displayed the same in all
+ * viewers: the on or off state is selected for checkboxes and radio buttons, and text fields and dropdowns get a new
+ * appearance with the background and border of the widget and the text in the font, size and color of the default
+ * appearance (DA), aligned as the field quadding (Q).  The text is measured with the Helvetica metrics, as in
+ * forms made with the standard fonts.  For list boxes, comb fields and text that the font encoding cannot show,
+ * NeedAppearances is set for viewers to generate the appearances.
+ *
+ * The changes are appended to the template as an incremental update, so the objects of the form are changed in
+ * place: the field tree stays as it is in the template.  The update is written by writeUpdate in update.go, shared
+ * with js_calculation.go.  With -flatten, the appearances of the widgets are drawn in the page contents and the
+ * widgets and the form are removed, so the values can no longer be edited.
+ *
+ * Run as: go run import_data.go update.go [-flatten] data.fdf|data.xfdf template.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+const usage = "Usage: go run import_data.go update.go [-flatten] data.fdf|data.xfdf template.pdf output.pdf\n"
+
+// Field flags (Ff).
+const (
+	fieldFlagMultiline   = 1 << 12
+	fieldFlagPassword    = 1 << 13
+	fieldFlagRadio       = 1 << 15
+	fieldFlagPushbutton  = 1 << 16
+	fieldFlagCombo       = 1 << 17
+	fieldFlagMultiSelect = 1 << 21
+	fieldFlagComb        = 1 << 24
+)
+
+// Annotation flags.
+const annotFlagHidden = 2
+
+// dataField is a field value from the data file: one value, or several for a list box with multiple selection.
+type dataField struct {
+	Name   string
+	Values []string
+}
+
+// formField is a terminal field of the template with its widgets.
+type formField struct {
+	Name    string
+	Dict    *pdfcore.PdfObjectDictionary
+	Owner   int64 // Number of the object containing the field dictionary.
+	FT      string
+	Ff      int64
+	DA      string
+	Q       int64
+	Widgets []formWidget
+}
+
+// formWidget is a widget annotation of a field.
+type formWidget struct {
+	Dict  *pdfcore.PdfObjectDictionary
+	Owner int64
+}
+
+// inherited is the field attributes inherited from the parent fields.
+type inherited struct {
+	FT string
+	Ff int64
+	DA string
+	Q  int64
+}
+
+// document gives access to the objects of the template and collects the changed and new objects.
+type document struct {
+	reader  *pdf.PdfReader
+	objects map[int64]*updateObject
+	size    int64
+}
+
+// xfdfField is a field element of an XFDF file, with nested fields or values.
+type xfdfField struct {
+	Name   string      `xml:"name,attr"`
+	Values []string    `xml:"value"`
+	Fields []xfdfField `xml:"field"`
+}
+
+func main() {
+	flatten := false
+	flag.BoolVar(&flatten, "flatten", false, "Draw the field appearances in the pages and remove the form")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 3 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	dataPath := flag.Arg(0)
+	templatePath := flag.Arg(1)
+	outputPath := flag.Arg(2)
+
+	err := importFormData(dataPath, templatePath, outputPath, flatten)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func importFormData(dataPath, templatePath, outputPath string, flatten bool) error {
+	values, err := readDataFile(dataPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(templatePath)
+	if err != nil {
+		return err
+	}
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		return errors.New("Encrypted templates are not supported")
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	doc := &document{reader: pdfReader, objects: map[int64]*updateObject{}, size: int64(*size)}
+
+	catalog, catalogNum := doc.resolveDict(trailer.Get("Root"))
+	if catalog == nil {
+		return errors.New("Missing catalog")
+	}
+	form, formNum := doc.resolveDict(catalog.Get("AcroForm"))
+	if formNum == 0 {
+		formNum = catalogNum
+	}
+	if form == nil || form.Get("Fields") == nil {
+		return fmt.Errorf("No form in %s", templatePath)
+	}
+	if form.Get("XFA") != nil {
+		return errors.New("XFA forms are not supported")
+	}
+
+	// The terminal fields of the template by full name.
+	fields := map[string]*formField{}
+	attrs := inherited{}
+	if da, ok := pdfcore.TraceToDirectObject(form.Get("DA")).(*pdfcore.PdfObjectString); ok {
+		attrs.DA = string(*da)
+	}
+	if q, ok := pdfcore.TraceToDirectObject(form.Get("Q")).(*pdfcore.PdfObjectInteger); ok {
+		attrs.Q = int64(*q)
+	}
+	fieldArray, _ := doc.resolveArray(form.Get("Fields"))
+	if fieldArray != nil {
+		for _, obj := range *fieldArray {
+			doc.collectFields(obj, "", nil, 0, attrs, fields, 0)
+		}
+	}
+	fmt.Printf("The template has %d fields, the data file %d values\n", len(fields), len(values))
+
+	fontResources := fontResources(doc, form)
+	needAppearances := false
+	imported := 0
+	var missing []string
+	for _, value := range values {
+		field, ok := fields[value.Name]
+		if !ok {
+			missing = append(missing, value.Name)
+			continue
+		}
+		generated, err := doc.setFieldValue(field, value.Values, fontResources)
+		if err != nil {
+			fmt.Printf("Not imported: %s: %v\n", value.Name, err)
+			continue
+		}
+		if !generated {
+			needAppearances = true
+		}
+		imported++
+	}
+	fmt.Printf("Imported %d values\n", imported)
+	if len(missing) > 0 {
+		fmt.Printf("%d fields of the data file are not in the form:\n", len(missing))
+		for _, name := range missing {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+
+	if flatten {
+		err := doc.flattenForm(catalog)
+		if err != nil {
+			return err
+		}
+		catalog.Remove("AcroForm")
+		doc.changed(catalogNum, catalog)
+		fmt.Printf("Flattened the form\n")
+	} else if needAppearances {
+		needAppearances := pdfcore.PdfObjectBool(true)
+		form.Set("NeedAppearances", &needAppearances)
+		doc.changed(formNum, form)
+		fmt.Printf("Some appearances are left to the viewer (NeedAppearances)\n")
+	}
+
+	var objects []updateObject
+	for _, obj := range doc.objects {
+		objects = append(objects, *obj)
+	}
+	update, err := writeUpdate(data, objects, trailer, doc.size)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(outputPath, append(data, update...), 0644)
+}
+
+// Reads the field values of an FDF or XFDF file.  The format is recognized from the file content.
+func readDataFile(path string) ([]dataField, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("%FDF-")) {
+		return readFDF(data)
+	}
+	if bytes.Contains(data, []byte("<xfdf")) {
+		return readXFDF(data)
+	}
+	return nil, fmt.Errorf("%s is neither FDF nor XFDF", path)
+}
+
+var objectHeader = regexp.MustCompile(`(?m)^\s*(\d+)\s+(\d+)\s+obj\b`)
+
+// Reads the field values of an FDF file.  An FDF file has the same syntax as a PDF file, with the fields in the FDF
+// dictionary of the catalog.  The objects are parsed one by one, without the cross-reference table.
+func readFDF(data []byte) ([]dataField, error) {
+	objects := map[int64]pdfcore.PdfObject{}
+	var root *pdfcore.PdfObjectDictionary
+	for _, loc := range objectHeader.FindAllSubmatchIndex(data, -1) {
+		num, _ := strconv.ParseInt(string(data[loc[2]:loc[3]]), 10, 64)
+		parser := pdfcore.NewParserFromString(string(data[loc[0]:]))
+		obj, err := parser.ParseIndirectObject()
+		if err != nil {
+			return nil, fmt.Errorf("Invalid FDF object %d: %v", num, err)
+		}
+		obj = pdfcore.TraceToDirectObject(obj)
+		objects[num] = obj
+		if dict, ok := obj.(*pdfcore.PdfObjectDictionary); ok && dict.Get("FDF") != nil {
+			root = dict
+		}
+	}
+	if root == nil {
+		return nil, errors.New("Missing FDF dictionary")
+	}
+
+	resolve := func(obj pdfcore.PdfObject) pdfcore.PdfObject {
+		if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+			return objects[ref.ObjectNumber]
+		}
+		return obj
+	}
+	fdf, ok := resolve(root.Get("FDF")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("Invalid FDF dictionary")
+	}
+	if enc, ok := resolve(fdf.Get("Encoding")).(*pdfcore.PdfObjectName); ok && *enc != "PDFDocEncoding" {
+		fmt.Printf("Note: the %s encoding is not supported, strings are read as PDFDocEncoding\n", *enc)
+	}
+
+	var values []dataField
+	var walk func(obj pdfcore.PdfObject, parentName string, depth int)
+	walk = func(obj pdfcore.PdfObject, parentName string, depth int) {
+		field, ok := resolve(obj).(*pdfcore.PdfObjectDictionary)
+		if !ok || depth > 32 {
+			return
+		}
+		name := parentName
+		if t, ok := resolve(field.Get("T")).(*pdfcore.PdfObjectString); ok {
+			name = joinName(parentName, decodePdfString(string(*t)))
+		}
+		if v := resolve(field.Get("V")); v != nil {
+			var list []string
+			if arr, ok := v.(*pdfcore.PdfObjectArray); ok {
+				for _, item := range *arr {
+					if s, ok := fdfValue(resolve(item)); ok {
+						list = append(list, s)
+					}
+				}
+			} else if s, ok := fdfValue(v); ok {
+				list = append(list, s)
+			}
+			if list != nil {
+				values = append(values, dataField{Name: name, Values: list})
+			}
+		}
+		if kids, ok := resolve(field.Get("Kids")).(*pdfcore.PdfObjectArray); ok {
+			for _, kid := range *kids {
+				walk(kid, name, depth+1)
+			}
+		}
+	}
+	if fields, ok := resolve(fdf.Get("Fields")).(*pdfcore.PdfObjectArray); ok {
+		for _, obj := range *fields {
+			walk(obj, "", 0)
+		}
+	}
+	return values, nil
+}
+
+// Returns an FDF value, a string or a name, as text.
+func fdfValue(obj pdfcore.PdfObject) (string, bool) {
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectString:
+		return decodePdfString(string(*t)), true
+	case *pdfcore.PdfObjectName:
+		return string(*t), true
+	}
+	return "", false
+}
+
+// Reads the field values of an XFDF file.
+func readXFDF(data []byte) ([]dataField, error) {
+	var doc struct {
+		Fields []xfdfField `xml:"fields>field"`
+	}
+	err := xml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []dataField
+	var walk func(field xfdfField, parentName string)
+	walk = func(field xfdfField, parentName string) {
+		name := joinName(parentName, field.Name)
+		if len(field.Values) > 0 {
+			values = append(values, dataField{Name: name, Values: field.Values})
+		}
+		for _, kid := range field.Fields {
+			walk(kid, name)
+		}
+	}
+	for _, field := range doc.Fields {
+		walk(field, "")
+	}
+	return values, nil
+}
+
+// Returns the full name of a field from the full name of the parent and the partial name.
+func joinName(parentName, name string) string {
+	if parentName == "" {
+		return name
+	}
+	if name == "" {
+		return parentName
+	}
+	return parentName + "." + name
+}
+
+// Adds the terminal fields of the field tree to fields.  Kids without a name are widgets, or parts of the same
+// field, and terminal fields without kids are their own widget.
+func (doc *document) collectFields(obj pdfcore.PdfObject, parentName string, named *formField, owner int64,
+	attrs inherited, fields map[string]*formField, depth int) {
+	dict, num := doc.resolveDict(obj)
+	if dict == nil || depth > 32 {
+		return
+	}
+	if num == 0 {
+		num = owner
+	}
+
+	if ft, ok := pdfcore.TraceToDirectObject(dict.Get("FT")).(*pdfcore.PdfObjectName); ok {
+		attrs.FT = string(*ft)
+	}
+	if ff, ok := pdfcore.TraceToDirectObject(dict.Get("Ff")).(*pdfcore.PdfObjectInteger); ok {
+		attrs.Ff = int64(*ff)
+	}
+	if da, ok := pdfcore.TraceToDirectObject(dict.Get("DA")).(*pdfcore.PdfObjectString); ok {
+		attrs.DA = string(*da)
+	}
+	if q, ok := pdfcore.TraceToDirectObject(dict.Get("Q")).(*pdfcore.PdfObjectInteger); ok {
+		attrs.Q = int64(*q)
+	}
+
+	name := parentName
+	if t, ok := pdfcore.TraceToDirectObject(dict.Get("T")).(*pdfcore.PdfObjectString); ok {
+		name = joinName(parentName, decodePdfString(string(*t)))
+		named = &formField{Name: name, Dict: dict, Owner: num}
+	}
+	if named == nil {
+		return
+	}
+
+	kids, _ := doc.resolveArray(dict.Get("Kids"))
+	if kids == nil {
+		field, ok := fields[name]
+		if !ok {
+			field = named
+			field.FT, field.Ff, field.DA, field.Q = attrs.FT, attrs.Ff, attrs.DA, attrs.Q
+			fields[name] = field
+		}
+		if subtype, ok := dict.Get("Subtype").(*pdfcore.PdfObjectName); ok && *subtype == "Widget" {
+			field.Widgets = append(field.Widgets, formWidget{Dict: dict, Owner: num})
+		}
+		return
+	}
+	for _, kid := range *kids {
+		doc.collectFields(kid, name, named, num, attrs, fields, depth+1)
+	}
+}
+
+// Sets the value of the field and updates the appearances of its widgets.  Returns false if the appearances are
+// left to the viewer.
+func (doc *document) setFieldValue(field *formField, values []string, fontResources pdfcore.PdfObject) (bool,
+	error) {
+	switch field.FT {
+	case "Tx":
+		text := normalizeNewlines(strings.Join(values, "\n"))
+		if field.Ff&fieldFlagMultiline == 0 {
+			text = strings.Replace(text, "\n", " ", -1)
+		}
+		field.Dict.Set("V", pdfcore.MakeString(encodePdfString(text)))
+		doc.changed(field.Owner, field.Dict)
+		if field.Ff&fieldFlagComb != 0 {
+			return false, nil
+		}
+		if field.Ff&fieldFlagPassword != 0 {
+			text = strings.Repeat("*", len([]rune(text)))
+		}
+		return doc.setTextAppearances(field, text, fontResources), nil
+
+	case "Btn":
+		if field.Ff&fieldFlagPushbutton != 0 {
+			return false, errors.New("push buttons have no value")
+		}
+		if len(values) != 1 {
+			return false, errors.New("a single value expected")
+		}
+		state, err := buttonState(field, values[0])
+		if err != nil {
+			return false, err
+		}
+		field.Dict.Set("V", pdfcore.MakeName(state))
+		doc.changed(field.Owner, field.Dict)
+		generated := true
+		for _, w := range field.Widgets {
+			states := appearanceStates(w.Dict)
+			if len(states) == 0 {
+				generated = false
+			}
+			as := "Off"
+			if _, ok := states[state]; ok || len(states) == 0 {
+				as = state
+			}
+			w.Dict.Set("AS", pdfcore.MakeName(as))
+			doc.changed(w.Owner, w.Dict)
+		}
+		return generated, nil
+
+	case "Ch":
+		if len(values) > 1 && field.Ff&fieldFlagMultiSelect == 0 {
+			return false, errors.New("a single option can be selected")
+		}
+		if len(values) == 1 {
+			field.Dict.Set("V", pdfcore.MakeString(encodePdfString(values[0])))
+		} else {
+			arr := pdfcore.MakeArray()
+			for _, v := range values {
+				arr.Append(pdfcore.MakeString(encodePdfString(v)))
+			}
+			field.Dict.Set("V", arr)
+		}
+		// The indices of the selected options are from the old value.
+		field.Dict.Remove("I")
+		doc.changed(field.Owner, field.Dict)
+		if field.Ff&fieldFlagCombo == 0 {
+			return false, nil
+		}
+		return doc.setTextAppearances(field, optionText(field.Dict, values[0]), fontResources), nil
+
+	case "Sig":
+		return false, errors.New("signature fields cannot be imported")
+	}
+	return false, fmt.Errorf("unsupported field type %q", field.FT)
+}
+
+// Returns the appearance state for a checkbox or radio button value.
+func buttonState(field *formField, value string) (string, error) {
+	states := map[string]bool{}
+	for _, w := range field.Widgets {
+		for state := range appearanceStates(w.Dict) {
+			states[state] = true
+		}
+	}
+	if value == "" || value == "Off" || states[value] {
+		if value == "" {
+			return "Off", nil
+		}
+		return value, nil
+	}
+
+	// A boolean for a checkbox with a single on state, Yes if the states are not known.
+	if field.Ff&fieldFlagRadio == 0 && len(states) <= 1 {
+		switch strings.ToLower(value) {
+		case "true", "on", "yes", "1":
+			for state := range states {
+				return state, nil
+			}
+			return "Yes", nil
+		case "false", "off", "no", "0":
+			return "Off", nil
+		}
+	}
+	// Without appearances, the states are not known.
+	if len(states) == 0 {
+		return value, nil
+	}
+
+	var names []string
+	for state := range states {
+		names = append(names, state)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("invalid value %q, the states are %s", value, strings.Join(names, ", "))
+}
+
+// Returns the on states of a widget: the names of its normal appearances other than Off.
+func appearanceStates(widget *pdfcore.PdfObjectDictionary) map[string]struct{} {
+	states := map[string]struct{}{}
+	ap, ok := pdfcore.TraceToDirectObject(widget.Get("AP")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return states
+	}
+	n, ok := pdfcore.TraceToDirectObject(ap.Get("N")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return states
+	}
+	for _, key := range n.Keys() {
+		if key != "Off" {
+			states[string(key)] = struct{}{}
+		}
+	}
+	return states
+}
+
+// Returns the text shown for a dropdown option: the display text if the options (Opt) have export values.
+func optionText(field *pdfcore.PdfObjectDictionary, value string) string {
+	opt, ok := pdfcore.TraceToDirectObject(field.Get("Opt")).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return value
+	}
+	for _, obj := range *opt {
+		pair, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectArray)
+		if !ok || len(*pair) != 2 {
+			continue
+		}
+		export, ok1 := pdfcore.TraceToDirectObject((*pair)[0]).(*pdfcore.PdfObjectString)
+		display, ok2 := pdfcore.TraceToDirectObject((*pair)[1]).(*pdfcore.PdfObjectString)
+		if ok1 && ok2 && decodePdfString(string(*export)) == value {
+			return decodePdfString(string(*display))
+		}
+	}
+	return value
+}
+
+// Regenerates the normal appearances of the widgets of a text field or dropdown with the text.  Returns false if
+// the text has characters that the font encoding cannot show, which are shown as question marks.
+func (doc *document) setTextAppearances(field *formField, text string, fontResources pdfcore.PdfObject) bool {
+	_, encodable := encodeWinAnsi(strings.Replace(text, "\n", "", -1))
+
+	fontName, fontSize, da := parseDA(field.DA)
+	for _, w := range field.Widgets {
+		rect, ok := pdfcore.TraceToDirectObject(w.Dict.Get("Rect")).(*pdfcore.PdfObjectArray)
+		if !ok {
+			continue
+		}
+		r, err := rect.ToFloat64Array()
+		if err != nil || len(r) != 4 {
+			continue
+		}
+		width := math.Abs(r[2] - r[0])
+		height := math.Abs(r[3] - r[1])
+
+		var buf bytes.Buffer
+		borderWidth := 0.0
+		mk, _ := pdfcore.TraceToDirectObject(w.Dict.Get("MK")).(*pdfcore.PdfObjectDictionary)
+		if mk != nil {
+			if bg := colorOperator(mk.Get("BG"), false); bg != "" {
+				buf.WriteString(fmt.Sprintf("%s 0 0 %.2f %.2f re f\n", bg, width, height))
+			}
+			if bc := colorOperator(mk.Get("BC"), true); bc != "" {
+				borderWidth = 1
+				if bs, ok := pdfcore.TraceToDirectObject(w.Dict.Get("BS")).(*pdfcore.PdfObjectDictionary); ok {
+					if bw, err := getNumberAsFloat(pdfcore.TraceToDirectObject(bs.Get("W"))); err == nil {
+						borderWidth = bw
+					}
+				}
+				if borderWidth > 0 {
+					buf.WriteString(fmt.Sprintf("%s %.2f w %.2f %.2f %.2f %.2f re S\n", bc, borderWidth,
+						borderWidth/2, borderWidth/2, width-borderWidth, height-borderWidth))
+				}
+			}
+		}
+
+		// The text is clipped to the area inside the border, with a padding.
+		padding := borderWidth + 2
+		size := fontSize
+		multiline := field.FT == "Tx" && field.Ff&fieldFlagMultiline != 0
+		if size == 0 {
+			// Auto size: fit the height of a single line.
+			size = math.Min(12, (height-2*padding)/1.2)
+			if multiline {
+				size = 10
+			}
+		}
+		var lines []string
+		if multiline {
+			lines = wrapText(text, width-2*padding, size)
+		} else {
+			lines = []string{text}
+		}
+
+		buf.WriteString(fmt.Sprintf("/Tx BMC\nq %.2f %.2f %.2f %.2f re W n\nBT %s\n", borderWidth, borderWidth,
+			width-2*borderWidth, height-2*borderWidth, strings.Replace(da, "{size}", fmt.Sprintf("%g", size), 1)))
+		y := (height - size*0.7) / 2
+		if multiline {
+			y = height - padding - size
+		}
+		for _, line := range lines {
+			x := padding
+			switch field.Q {
+			case 1:
+				x = (width - textWidth(line, fonts.NewFontHelvetica(), size)) / 2
+			case 2:
+				x = width - padding - textWidth(line, fonts.NewFontHelvetica(), size)
+			}
+			s, _ := encodeWinAnsi(line)
+			buf.WriteString(fmt.Sprintf("1 0 0 1 %.2f %.2f Tm %s Tj\n", x, y, pdfcore.MakeString(s).DefaultWriteString()))
+			y -= size * 1.15
+		}
+		buf.WriteString("ET Q\nEMC\n")
+
+		xform := pdfcore.MakeDict()
+		xform.Set("Type", pdfcore.MakeName("XObject"))
+		xform.Set("Subtype", pdfcore.MakeName("Form"))
+		xform.Set("BBox", pdfcore.MakeArrayFromFloats([]float64{0, 0, width, height}))
+		resources := pdfcore.MakeDict()
+		if fontResources != nil {
+			fontDict := pdfcore.MakeDict()
+			fontDict.Set(pdfcore.PdfObjectName(fontName), fontResources)
+			resources.Set("Font", fontDict)
+		}
+		xform.Set("Resources", resources)
+
+		ap := pdfcore.MakeDict()
+		ap.Set("N", doc.add(xform, buf.Bytes()))
+		w.Dict.Set("AP", ap)
+		doc.changed(w.Owner, w.Dict)
+	}
+	return encodable
+}
+
+// Parses a default appearance string, e.g. "/Helv 10 Tf 0 g".  Returns the font name, the font size (0 for auto
+// size) and the string with the size replaced by {size}.
+func parseDA(da string) (string, float64, string) {
+	fields := strings.Fields(da)
+	for i, f := range fields {
+		if f == "Tf" && i >= 2 {
+			size, _ := strconv.ParseFloat(fields[i-1], 64)
+			fontName := strings.TrimPrefix(fields[i-2], "/")
+			fields[i-1] = "{size}"
+			return fontName, size, strings.Join(fields, " ")
+		}
+	}
+	return "Helv", 0, "/Helv {size} Tf " + da
+}
+
+// Returns the resources for the font of the default appearance of the form: the font dictionary in the default
+// resources (DR) of the form, or Helvetica if there is none.
+func fontResources(doc *document, form *pdfcore.PdfObjectDictionary) pdfcore.PdfObject {
+	fontName := "Helv"
+	if da, ok := pdfcore.TraceToDirectObject(form.Get("DA")).(*pdfcore.PdfObjectString); ok {
+		fontName, _, _ = parseDA(string(*da))
+	}
+	dr, _ := doc.resolveDict(form.Get("DR"))
+	if dr != nil {
+		if fontDict, _ := doc.resolveDict(dr.Get("Font")); fontDict != nil {
+			if font := fontDict.Get(pdfcore.PdfObjectName(fontName)); font != nil {
+				return font
+			}
+		}
+	}
+	helvetica := pdfcore.MakeDict()
+	helvetica.Set("Type", pdfcore.MakeName("Font"))
+	helvetica.Set("Subtype", pdfcore.MakeName("Type1"))
+	helvetica.Set("BaseFont", pdfcore.MakeName("Helvetica"))
+	helvetica.Set("Encoding", pdfcore.MakeName("WinAnsiEncoding"))
+	return helvetica
+}
+
+// Returns the color operator for an MK color array: gray, RGB or CMYK by the number of components, for stroking or
+// filling.  Returns "" for no color.
+func colorOperator(obj pdfcore.PdfObject, stroke bool) string {
+	arr, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return ""
+	}
+	values, err := arr.ToFloat64Array()
+	if err != nil {
+		return ""
+	}
+	var ops []string
+	switch len(values) {
+	case 1:
+		ops = []string{"G", "g"}
+	case 3:
+		ops = []string{"RG", "rg"}
+	case 4:
+		ops = []string{"K", "k"}
+	default:
+		return ""
+	}
+	op := ops[1]
+	if stroke {
+		op = ops[0]
+	}
+	var parts []string
+	for _, v := range values {
+		parts = append(parts, fmt.Sprintf("%g", v))
+	}
+	return strings.Join(parts, " ") + " " + op
+}
+
+// Wraps the text into lines of the width, in Helvetica of the size.  Words longer than a line are not broken.
+func wrapText(text string, width, size float64) []string {
+	var lines []string
+	font := fonts.NewFontHelvetica()
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" && textWidth(line+" "+word, font, size) > width {
+				lines = append(lines, line)
+				line = word
+			} else if line != "" {
+				line += " " + word
+			} else {
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Encodes the text in WinAnsiEncoding, the encoding of the standard fonts in forms.  Returns false if characters
+// could not be encoded, which are replaced by question marks.
+func encodeWinAnsi(text string) (string, bool) {
+	encoder := textencoding.NewWinAnsiTextEncoder()
+	var buf bytes.Buffer
+	ok := true
+	for _, r := range text {
+		code, found := encoder.RuneToCharcode(r)
+		if !found {
+			code = '?'
+			ok = false
+		}
+		buf.WriteByte(code)
+	}
+	return buf.String(), ok
+}
+
+// Replaces the Windows (CR LF) and Mac (CR) line endings by LF.
+func normalizeNewlines(s string) string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	return strings.Replace(s, "\r", "\n", -1)
+}
+
+// Draws the appearances of the visible widgets in the page contents and removes the widgets from the pages.  The
+// appearances are form XObjects, painted in the rectangle of the widget.
+func (doc *document) flattenForm(catalog *pdfcore.PdfObjectDictionary) error {
+	pages, _ := doc.resolveDict(catalog.Get("Pages"))
+	if pages == nil {
+		return errors.New("Missing page tree")
+	}
+	count := 0
+	err := doc.walkPages(pages, nil, 0, func(page *pdfcore.PdfObjectDictionary, pageNum int64,
+		resources *pdfcore.PdfObjectDictionary) error {
+		annots, _ := doc.resolveArray(page.Get("Annots"))
+		if annots == nil {
+			return nil
+		}
+
+		xobjects := pdfcore.MakeDict()
+		if existing, _ := doc.resolveDict(resources.Get("XObject")); existing != nil {
+			for _, key := range existing.Keys() {
+				xobjects.Set(key, existing.Get(key))
+			}
+		}
+
+		var content bytes.Buffer
+		kept := pdfcore.MakeArray()
+		for _, obj := range *annots {
+			annot, _ := doc.resolveDict(obj)
+			if annot == nil {
+				continue
+			}
+			if subtype, ok := annot.Get("Subtype").(*pdfcore.PdfObjectName); !ok || *subtype != "Widget" {
+				kept.Append(obj)
+				continue
+			}
+			if f, ok := pdfcore.TraceToDirectObject(annot.Get("F")).(*pdfcore.PdfObjectInteger); ok &&
+				*f&annotFlagHidden != 0 {
+				continue
+			}
+			appearance := normalAppearance(annot)
+			stream, _ := doc.resolveDict(appearance)
+			rect, ok := pdfcore.TraceToDirectObject(annot.Get("Rect")).(*pdfcore.PdfObjectArray)
+			if stream == nil || !ok {
+				continue
+			}
+			r, err := rect.ToFloat64Array()
+			if err != nil || len(r) != 4 {
+				continue
+			}
+			matrix, ok := appearanceMatrix(stream, r)
+			if !ok {
+				continue
+			}
+
+			name := ""
+			for i := 1; name == "" || xobjects.Get(pdfcore.PdfObjectName(name)) != nil; i++ {
+				name = fmt.Sprintf("Fm%d", i)
+			}
+			xobjects.Set(pdfcore.PdfObjectName(name), appearance)
+			content.WriteString(fmt.Sprintf("q %s cm /%s Do Q\n", matrix, name))
+			count++
+		}
+
+		if len(*kept) > 0 {
+			page.Set("Annots", kept)
+		} else {
+			page.Remove("Annots")
+		}
+		if content.Len() > 0 {
+			resources.Set("XObject", xobjects)
+			page.Set("Resources", resources)
+
+			// The existing contents are enclosed in q/Q, so that the appearances are drawn in the default
+			// coordinate space of the page.
+			contents := pdfcore.MakeArray(doc.add(pdfcore.MakeDict(), []byte("q\n")))
+			if arr, _ := doc.resolveArray(page.Get("Contents")); arr != nil {
+				for _, c := range *arr {
+					contents.Append(c)
+				}
+			} else if c := page.Get("Contents"); c != nil {
+				contents.Append(c)
+			}
+			contents.Append(doc.add(pdfcore.MakeDict(), append([]byte("Q\n"), content.Bytes()...)))
+			page.Set("Contents", contents)
+		}
+		doc.changed(pageNum, page)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Drew %d widget appearances in the pages\n", count)
+	return nil
+}
+
+// Calls fn for the pages of the page tree, with a copy of the resources of the page, inherited if not set on the
+// page.
+func (doc *document) walkPages(node *pdfcore.PdfObjectDictionary, resources *pdfcore.PdfObjectDictionary, depth int,
+	fn func(page *pdfcore.PdfObjectDictionary, pageNum int64, resources *pdfcore.PdfObjectDictionary) error) error {
+	if r, _ := doc.resolveDict(node.Get("Resources")); r != nil {
+		resources = r
+	}
+	kids, _ := doc.resolveArray(node.Get("Kids"))
+	if kids == nil || depth > 32 {
+		return nil
+	}
+	for _, kid := range *kids {
+		dict, num := doc.resolveDict(kid)
+		if dict == nil {
+			continue
+		}
+		if t, ok := dict.Get("Type").(*pdfcore.PdfObjectName); ok && *t == "Pages" {
+			err := doc.walkPages(dict, resources, depth+1, fn)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if num == 0 {
+			return errors.New("Page not an indirect object")
+		}
+		pageResources := resources
+		if r, _ := doc.resolveDict(dict.Get("Resources")); r != nil {
+			pageResources = r
+		}
+		copied := pdfcore.MakeDict()
+		if pageResources != nil {
+			for _, key := range pageResources.Keys() {
+				copied.Set(key, pageResources.Get(key))
+			}
+		}
+		err := fn(dict, num, copied)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the normal appearance of a widget: the N entry, or for a widget with appearance states, the appearance of
+// the current state (AS).
+func normalAppearance(annot *pdfcore.PdfObjectDictionary) pdfcore.PdfObject {
+	ap, ok := pdfcore.TraceToDirectObject(annot.Get("AP")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	n := ap.Get("N")
+	if states, ok := pdfcore.TraceToDirectObject(n).(*pdfcore.PdfObjectDictionary); ok {
+		as, ok := pdfcore.TraceToDirectObject(annot.Get("AS")).(*pdfcore.PdfObjectName)
+		if !ok {
+			return nil
+		}
+		return states.Get(*as)
+	}
+	return n
+}
+
+// Returns the matrix (cm operands) that paints an appearance stream in the rectangle of its annotation: the bounding
+// box, transformed by the matrix of the stream, is scaled and moved to fit the rectangle.
+func appearanceMatrix(stream *pdfcore.PdfObjectDictionary, rect []float64) (string, bool) {
+	bboxArr, ok := pdfcore.TraceToDirectObject(stream.Get("BBox")).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return "", false
+	}
+	bbox, err := bboxArr.ToFloat64Array()
+	if err != nil || len(bbox) != 4 {
+		return "", false
+	}
+	m := []float64{1, 0, 0, 1, 0, 0}
+	if arr, ok := pdfcore.TraceToDirectObject(stream.Get("Matrix")).(*pdfcore.PdfObjectArray); ok {
+		if values, err := arr.ToFloat64Array(); err == nil && len(values) == 6 {
+			m = values
+		}
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [][2]float64{{bbox[0], bbox[1]}, {bbox[2], bbox[1]}, {bbox[0], bbox[3]}, {bbox[2], bbox[3]}} {
+		x := m[0]*p[0] + m[2]*p[1] + m[4]
+		y := m[1]*p[0] + m[3]*p[1] + m[5]
+		minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+		minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+	}
+	if maxX-minX == 0 || maxY-minY == 0 {
+		return "", false
+	}
+
+	llx, lly := math.Min(rect[0], rect[2]), math.Min(rect[1], rect[3])
+	sx := math.Abs(rect[2]-rect[0]) / (maxX - minX)
+	sy := math.Abs(rect[3]-rect[1]) / (maxY - minY)
+	return fmt.Sprintf("%.4f 0 0 %.4f %.4f %.4f", sx, sy, llx-sx*minX, lly-sy*minY), true
+}
+
+// Returns the dictionary of an object, resolving references, and the number of the object if it is an indirect
+// object (0 for a direct object).  The dictionary of a stream is returned for streams.  Changed and new objects are
+// returned as changed.
+func (doc *document) resolveDict(obj pdfcore.PdfObject) (*pdfcore.PdfObjectDictionary, int64) {
+	obj, num := doc.resolve(obj)
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectDictionary:
+		return t, num
+	case *pdfcore.PdfObjectStream:
+		return t.PdfObjectDictionary, num
+	}
+	return nil, 0
+}
+
+// Returns the array of an object, resolving references, and the number of the object if it is an indirect object.
+func (doc *document) resolveArray(obj pdfcore.PdfObject) (*pdfcore.PdfObjectArray, int64) {
+	obj, num := doc.resolve(obj)
+	arr, ok := obj.(*pdfcore.PdfObjectArray)
+	if !ok {
+		return nil, 0
+	}
+	return arr, num
+}
+
+// Returns the direct object of an object, resolving references, and the number of the object.
+func (doc *document) resolve(obj pdfcore.PdfObject) (pdfcore.PdfObject, int64) {
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		if u, ok := doc.objects[ref.ObjectNumber]; ok {
+			return u.obj, ref.ObjectNumber
+		}
+		resolved, err := doc.reader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, 0
+		}
+		obj = resolved
+	}
+	switch t := obj.(type) {
+	case *pdfcore.PdfIndirectObject:
+		return t.PdfObject, t.ObjectNumber
+	case *pdfcore.PdfObjectStream:
+		return t, t.ObjectNumber
+	}
+	return obj, 0
+}
+
+// Records that the dictionary of the object with the number was changed.
+func (doc *document) changed(num int64, dict *pdfcore.PdfObjectDictionary) {
+	if num == 0 {
+		return
+	}
+	if _, ok := doc.objects[num]; !ok {
+		doc.objects[num] = &updateObject{number: num, obj: dict}
+	}
+}
+
+// Adds a new stream with the dictionary and data.  Returns a reference to it.
+func (doc *document) add(dict *pdfcore.PdfObjectDictionary, data []byte) *pdfcore.PdfObjectReference {
+	num := doc.size
+	doc.size++
+	doc.objects[num] = &updateObject{number: num,
+		obj: &pdfcore.PdfObjectStream{PdfObjectDictionary: dict, Stream: data}}
+	return &pdfcore.PdfObjectReference{ObjectNumber: num}
+}
+
+// Returns the number value of an integer or float object.
+func getNumberAsFloat(obj pdfcore.PdfObject) (float64, error) {
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectFloat:
+		return float64(*t), nil
+	case *pdfcore.PdfObjectInteger:
+		return float64(*t), nil
+	}
+	return 0, errors.New("Not a number")
+}
+
+// Returns the width of the text in the font.
+func textWidth(text string, font fonts.Font, size float64) float64 {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetEnableWrap(false)
+	return p.Width()
+}
+
+// Decodes a PDF text string: UTF-16BE with a byte order mark, or PDFDocEncoding (read as Latin-1).
+func decodePdfString(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := []uint16{}
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// Encodes a text string: as is if ASCII, otherwise as UTF-16BE with a byte order mark.
+func encodePdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 128 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\xfe\xff")
+	binary.Write(&buf, binary.BigEndian, utf16.Encode([]rune(s)))
+	return buf.String()
+}
@@ -0,0 +1,358 @@
+/*
+ * Create an order form with JavaScript calculations: the subtotal, tax and total are calculated from the entered
+ * amounts, and all amounts are formatted as currency.
+ *
+ * The scripts are set in the additional actions (AA) of the fields:
+ * - K (keystroke): only digits and a decimal point can be typed in the amount fields,
+ * - V (validate): negative amounts are rejected,
+ * - F (format): the value is displayed as currency, e.g. $1,234.50, while the field value remains a plain number,
+ * - C (calculate): the value of a calculated field is computed from other fields.
+ * The calculation order (CO) of the form lists the calculated fields in the order they must be computed: the tax
+ * depends on the subtotal, and the total on both.  The functions used by the scripts are document-level JavaScript,
+ * in the JavaScript name tree of the catalog, which is added in an incremental update as the model does not give
+ * access to the catalog.  The update is written by writeUpdate in update.go, shared with import_data.go.
+ *
+ * Viewers that do not run JavaScript do not calculate or format the values.  Therefore the fields have default
+ * values, calculated by this program, and appearance streams showing the formatted values, so that the form is
+ * displayed and printed correctly anyway.  The calculated fields are read only.
+ *
+ * Run as: go run js_calculation.go update.go output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// Field flags (Ff).
+const fieldFlagReadOnly = 1 << 0
+
+// Annotation flags.
+const annotFlagPrint = 4
+
+// Layout: position of the labels and the fields, from the upper left corner of the page.
+const (
+	margin      = 72.0
+	fieldX      = 300.0
+	fieldWidth  = 150.0
+	fieldHeight = 20.0
+	rowSpacing  = 10.0
+	fontSize    = 10.0
+)
+
+const taxRate = 0.21
+
+// The document-level JavaScript: functions used by the field scripts.  The tax rate is defined before.
+const documentScript = `// Returns the numeric value of a field value, 0 if empty or invalid.
+function parseAmount(value) {
+  var n = parseFloat(String(value).replace(/[^0-9.\-]/g, ""));
+  return isNaN(n) ? 0 : n;
+}
+
+// Formats an amount as currency with thousands separators, e.g. $1,234.50.
+function formatAmount(value) {
+  var parts = parseAmount(value).toFixed(2).split(".");
+  parts[0] = parts[0].replace(/\B(?=(\d{3})+(?!\d))/g, ",");
+  return "$" + parts.join(".");
+}
+
+// Returns the sum of the values of the fields.
+function sumFields(doc, names) {
+  var total = 0;
+  for (var i = 0; i < names.length; i++) {
+    total += parseAmount(doc.getField(names[i]).value);
+  }
+  return Math.round(total * 100) / 100;
+}
+`
+
+// Field scripts.
+const (
+	keystrokeScript = `if (!event.willCommit) event.rc = /^[0-9.]*$/.test(event.change);`
+	validateScript  = `if (parseAmount(event.value) < 0) {
+  app.alert("The amount cannot be negative.");
+  event.rc = false;
+}`
+	formatScript = `event.value = formatAmount(event.value);`
+)
+
+// amountField is a row of the form: an entered amount, or a calculated amount if calculate is set.
+type amountField struct {
+	name      string
+	label     string
+	value     float64
+	calculate string
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run js_calculation.go update.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	err := createOrderForm(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createOrderForm(outputPath string) error {
+	// The default values, calculated here as the scripts would.
+	item1 := 120.0
+	item2 := 35.5
+	subtotal := item1 + item2
+	tax := math.Floor(subtotal*taxRate*100+0.5) / 100
+	rows := []amountField{
+		{name: "item1", label: "Item 1", value: item1},
+		{name: "item2", label: "Item 2", value: item2},
+		{name: "subtotal", label: "Subtotal", value: subtotal,
+			calculate: `event.value = sumFields(this, ["item1", "item2"]);`},
+		{name: "tax", label: fmt.Sprintf("Tax (%g%%)", taxRate*100), value: tax,
+			calculate: `var subtotal = parseAmount(this.getField("subtotal").value);
+event.value = Math.round(subtotal * taxRate * 100) / 100;`},
+		{name: "total", label: "Total", value: subtotal + tax,
+			calculate: `event.value = sumFields(this, ["subtotal", "tax"]);`},
+	}
+
+	c := creator.New()
+
+	// The page is created here rather than with c.NewPage, to be able to add the widget annotations to it.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: c.Width(), Ury: c.Height()}
+	page.Resources = pdf.NewPdfPageResources()
+	err := c.AddPage(page)
+	if err != nil {
+		return err
+	}
+
+	helvetica := pdfcore.MakeDict()
+	helvetica.Set("Type", pdfcore.MakeName("Font"))
+	helvetica.Set("Subtype", pdfcore.MakeName("Type1"))
+	helvetica.Set("BaseFont", pdfcore.MakeName("Helvetica"))
+	helvetica.Set("Encoding", pdfcore.MakeName("WinAnsiEncoding"))
+	resources := pdf.NewPdfPageResources()
+	resources.SetFontByName("Helv", pdfcore.MakeIndirectObject(helvetica))
+
+	y := margin
+	title := creator.NewParagraph("Order")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(18)
+	title.SetPos(margin, y)
+	err = c.Draw(title)
+	if err != nil {
+		return err
+	}
+	y += title.Height() + 12
+
+	note := creator.NewParagraph("Enter the amounts of the items, the subtotal, tax and total are calculated " +
+		"automatically in viewers that support JavaScript.")
+	note.SetFont(fonts.NewFontHelvetica())
+	note.SetFontSize(fontSize)
+	note.SetWidth(fieldX + fieldWidth - margin)
+	note.SetPos(margin, y)
+	err = c.Draw(note)
+	if err != nil {
+		return err
+	}
+	y += note.Height() + 24
+
+	fields := []*pdf.PdfField{}
+	calculationOrder := pdfcore.PdfObjectArray{}
+	for _, row := range rows {
+		label := creator.NewParagraph(row.label)
+		label.SetFont(fonts.NewFontHelveticaBold())
+		label.SetFontSize(fontSize + 1)
+		label.SetEnableWrap(false)
+		label.SetPos(margin, y+(fieldHeight-label.Height())/2)
+		err = c.Draw(label)
+		if err != nil {
+			return err
+		}
+
+		field := pdf.NewPdfField()
+		field.FT = pdfcore.MakeName("Tx")
+		field.T = pdfcore.MakeString(row.name)
+		field.TU = pdfcore.MakeString(row.label)
+		// The value is the plain number, the format script displays it as currency.
+		value := strconv.FormatFloat(row.value, 'f', 2, 64)
+		field.V = pdfcore.MakeString(value)
+		field.DV = pdfcore.MakeString(value)
+		field.DA = pdfcore.MakeString(fmt.Sprintf("/Helv %g Tf 0 g", fontSize))
+		// Right aligned.
+		field.Q = pdfcore.MakeInteger(2)
+
+		aa := pdfcore.MakeDict()
+		aa.Set("F", makeJavaScriptAction(formatScript))
+		if row.calculate != "" {
+			field.Ff = pdfcore.MakeInteger(fieldFlagReadOnly)
+			aa.Set("C", makeJavaScriptAction(row.calculate))
+			calculationOrder = append(calculationOrder, field.GetContainingPdfObject())
+		} else {
+			aa.Set("K", makeJavaScriptAction(keystrokeScript))
+			aa.Set("V", makeJavaScriptAction(validateScript))
+		}
+		field.AA = aa
+
+		// The appearance shows the formatted value, as the format script would.
+		text := formatAmount(row.value)
+		textX := fieldWidth - 4 - textWidth(text, fonts.NewFontHelvetica(), fontSize)
+		content := fmt.Sprintf("1 g 0.5 G 1 w 0.5 0.5 %.2f %.2f re b\n", fieldWidth-1, fieldHeight-1) +
+			fmt.Sprintf("/Tx BMC\nq BT 0 g /Helv %g Tf %.2f %.2f Td %s Tj ET Q\nEMC\n", fontSize, textX,
+				(fieldHeight-fontSize*0.7)/2, pdfcore.MakeString(text).DefaultWriteString())
+		xform := pdf.NewXObjectForm()
+		xform.BBox = pdfcore.MakeArrayFromFloats([]float64{0, 0, fieldWidth, fieldHeight})
+		xform.Resources = resources
+		xform.SetContentStream([]byte(content), nil)
+		ap := pdfcore.MakeDict()
+		ap.Set("N", xform.ToPdfObject())
+
+		mk := pdfcore.MakeDict()
+		mk.Set("BC", pdfcore.MakeArrayFromFloats([]float64{0.5, 0.5, 0.5}))
+		mk.Set("BG", pdfcore.MakeArrayFromFloats([]float64{1, 1, 1}))
+
+		// The widget, with the rectangle in PDF coordinates (origin in the lower left corner).
+		widget := pdf.NewPdfAnnotationWidget()
+		widget.Rect = pdfcore.MakeArrayFromFloats([]float64{fieldX, page.MediaBox.Ury - y - fieldHeight,
+			fieldX + fieldWidth, page.MediaBox.Ury - y})
+		widget.F = pdfcore.MakeInteger(annotFlagPrint)
+		widget.P = page.GetPageAsIndirectObject()
+		widget.Parent = field.GetContainingPdfObject()
+		widget.AP = ap
+		widget.MK = mk
+		field.KidsA = append(field.KidsA, widget.PdfAnnotation)
+		page.Annotations = append(page.Annotations, widget.PdfAnnotation)
+
+		fields = append(fields, field)
+		y += fieldHeight + rowSpacing
+	}
+
+	form := pdf.NewPdfAcroForm()
+	form.Fields = &fields
+	form.DR = resources
+	form.DA = pdfcore.MakeString(fmt.Sprintf("/Helv %g Tf 0 g", fontSize))
+	form.CO = &calculationOrder
+	err = c.SetForms(form)
+	if err != nil {
+		return err
+	}
+
+	err = c.WriteToFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	script := fmt.Sprintf("var taxRate = %g;\n\n", taxRate) + documentScript
+	return addDocumentJavaScript(outputPath, "OrderForm", script)
+}
+
+// Returns a JavaScript action with the script.
+func makeJavaScriptAction(script string) *pdfcore.PdfObjectDictionary {
+	action := pdfcore.MakeDict()
+	action.Set("S", pdfcore.MakeName("JavaScript"))
+	action.Set("JS", pdfcore.MakeString(script))
+	return action
+}
+
+// Adds the script as document-level JavaScript with the name: a JavaScript action in the JavaScript name tree of the
+// catalog, appended to the file as an incremental update.
+func addDocumentJavaScript(path, name, script string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	rootRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return errors.New("Missing Root in trailer")
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	catalogObj, err := pdfReader.GetIndirectObjectByNumber(int(rootRef.ObjectNumber))
+	if err != nil {
+		return err
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(catalogObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Catalog not a dictionary")
+	}
+	if catalog.Get("Names") != nil {
+		return errors.New("The catalog already has a name dictionary")
+	}
+
+	actionNum := int64(*size)
+	jsTree := pdfcore.MakeDict()
+	jsTree.Set("Names", pdfcore.MakeArray(pdfcore.MakeString(name),
+		&pdfcore.PdfObjectReference{ObjectNumber: actionNum}))
+	names := pdfcore.MakeDict()
+	names.Set("JavaScript", jsTree)
+	catalog.Set("Names", names)
+
+	objects := []updateObject{
+		{number: rootRef.ObjectNumber, obj: catalog},
+		{number: actionNum, obj: makeJavaScriptAction(script)},
+	}
+	update, err := writeUpdate(data, objects, trailer, actionNum+1)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(update)
+	return err
+}
+
+// Formats the amount as currency with thousands separators, as the formatAmount script function.
+func formatAmount(amount float64) string {
+	s := strconv.FormatFloat(amount, 'f', 2, 64)
+	intPart := s[:len(s)-3]
+	for i := len(intPart) - 3; i > 0; i -= 3 {
+		intPart = intPart[:i] + "," + intPart[i:]
+	}
+	return "$" + intPart + s[len(s)-3:]
+}
+
+// Returns the width of the text in the font.
+func textWidth(text string, font fonts.Font, size float64) float64 {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetEnableWrap(false)
+	return p.Width()
+}
@@ -0,0 +1,531 @@
+/*
+ * Create a multiple-choice quiz as an interactive form (AcroForm), and score a completed copy of the form against
+ * the answer key.
+ *
+ * Each question is a radio button group: one field named after the question (q1, q2, ...) with a widget for each
+ * option, whose on state is the option letter (A, B, ...).  When a respondent selects an option, the viewer sets
+ * the value (V) of the field to that letter and the appearance state (AS) of the widgets.  A question without a
+ * selection has the value Off.  The respondent's name is a text field.
+ *
+ * The answer key is in this program, not in the PDF file, where respondents could find it.
+ *
+ * With -score, the completed form is read back: the value of each question field is compared with the answer key,
+ * and the number of correct answers is reported, along with the questions left unanswered.  A partially completed
+ * form is scored on the answered questions, out of the total.  Some viewers only update the appearance states of the
+ * widgets and not the field value, so if the value is missing the selected widget is looked for.  Questions missing
+ * from the form, e.g. from an older version of the quiz, and values that are not one of the options are reported
+ * as such and count as not correct.
+ *
+ * To try it without filling in the form in a viewer, -answers preselects answers when creating the form, e.g.
+ * -answers 1=B,2=A,4=C for a partially completed form.
+ *
+ * Run as: go run survey.go [-answers 1=B,2=A] output.pdf
+ *     or: go run survey.go -score completed.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run survey.go [-answers 1=B,2=A] output.pdf\n" +
+	"       go run survey.go -score completed.pdf\n"
+
+// Field flags (Ff).
+const (
+	fieldFlagNoToggleToOff = 1 << 14
+	fieldFlagRadio         = 1 << 15
+)
+
+// Annotation flags.
+const annotFlagPrint = 4
+
+// Layout: from the upper left corner of the page.
+const (
+	margin       = 60.0
+	fontSize     = 10.0
+	boxSize      = 12.0
+	optionHeight = 18.0
+	optionIndent = 16.0
+	nameWidth    = 250.0
+	fieldHeight  = 20.0
+)
+
+// question is a multiple-choice question with its answer key: the index of the correct option.
+type question struct {
+	Name    string
+	Text    string
+	Options []string
+	Answer  int
+}
+
+var quiz = []question{
+	{"q1", "Which page size is 210 x 297 mm?", []string{"Letter", "A4", "Legal", "A5"}, 1},
+	{"q2", "How many points are there in an inch?", []string{"72", "96", "100", "25.4"}, 0},
+	{"q3", "Which PDF object holds the interactive form of a document?",
+		[]string{"The page tree", "The outline", "The AcroForm dictionary", "The document information"}, 2},
+	{"q4", "Where is the origin of the default PDF coordinate system on a page?",
+		[]string{"Upper left corner", "Center", "Lower right corner", "Lower left corner"}, 3},
+	{"q5", "Which field type are radio buttons?", []string{"Tx", "Btn", "Ch", "Sig"}, 1},
+	{"q6", "What does the appearance stream of a widget annotation define?",
+		[]string{"How the field is displayed", "The field value", "The tab order", "The font embedding"}, 0},
+	{"q7", "Which color space has four components?", []string{"DeviceGray", "DeviceRGB", "DeviceCMYK", "Lab"}, 2},
+	{"q8", "Which of these is a standard 14 font that needs no embedding?",
+		[]string{"Arial", "Roboto", "Calibri", "Helvetica"}, 3},
+}
+
+// formBuilder creates the fields and their widgets on the pages.
+type formBuilder struct {
+	c         *creator.Creator
+	page      *pdf.PdfPage
+	y         float64
+	fields    []*pdf.PdfField
+	resources *pdf.PdfPageResources
+}
+
+func main() {
+	answers := ""
+	score := false
+	flag.StringVar(&answers, "answers", "", "Answers to preselect, e.g. 1=B,2=A")
+	flag.BoolVar(&score, "score", false, "Score a completed form")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	if score {
+		err := scoreForm(flag.Arg(0))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	selected, err := parseAnswers(answers)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	outputPath := flag.Arg(0)
+
+	err = createSurvey(outputPath, selected)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Parses answers in the form 1=B,2=A to a map from question name to option letter.
+func parseAnswers(s string) (map[string]string, error) {
+	selected := map[string]string{}
+	if s == "" {
+		return selected, nil
+	}
+	for _, answer := range strings.Split(s, ",") {
+		parts := strings.Split(answer, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid answer %q (use number=letter)", answer)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil || n < 1 || n > len(quiz) {
+			return nil, fmt.Errorf("Invalid question number in %q (1-%d)", answer, len(quiz))
+		}
+		letter := strings.ToUpper(strings.TrimSpace(parts[1]))
+		if optionIndex(quiz[n-1], letter) < 0 {
+			return nil, fmt.Errorf("Invalid option in %q", answer)
+		}
+		selected[quiz[n-1].Name] = letter
+	}
+	return selected, nil
+}
+
+func createSurvey(outputPath string, selected map[string]string) error {
+	c := creator.New()
+
+	// The fonts for the appearance streams and for the viewers, as default resources of the form.
+	helvetica := pdfcore.MakeDict()
+	helvetica.Set("Type", pdfcore.MakeName("Font"))
+	helvetica.Set("Subtype", pdfcore.MakeName("Type1"))
+	helvetica.Set("BaseFont", pdfcore.MakeName("Helvetica"))
+	helvetica.Set("Encoding", pdfcore.MakeName("WinAnsiEncoding"))
+	resources := pdf.NewPdfPageResources()
+	resources.SetFontByName("Helv", pdfcore.MakeIndirectObject(helvetica))
+
+	fb := &formBuilder{c: c, resources: resources}
+	err := fb.newPage()
+	if err != nil {
+		return err
+	}
+
+	title := creator.NewParagraph("Quiz: PDF basics")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(18)
+	title.SetPos(margin, fb.y)
+	err = c.Draw(title)
+	if err != nil {
+		return err
+	}
+	fb.y += title.Height() + 8
+
+	intro := newParagraph(fmt.Sprintf("Select one answer for each of the %d questions.", len(quiz)),
+		fonts.NewFontHelvetica(), c.Width()-2*margin)
+	intro.SetPos(margin, fb.y)
+	err = c.Draw(intro)
+	if err != nil {
+		return err
+	}
+	fb.y += intro.Height() + 12
+
+	err = fb.addNameField()
+	if err != nil {
+		return err
+	}
+
+	for i, q := range quiz {
+		err := fb.addQuestion(i+1, q, selected[q.Name])
+		if err != nil {
+			return err
+		}
+	}
+
+	form := pdf.NewPdfAcroForm()
+	form.Fields = &fb.fields
+	form.DR = resources
+	form.DA = pdfcore.MakeString(fmt.Sprintf("/Helv %g Tf 0 g", fontSize))
+	err = c.SetForms(form)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %d questions on %d pages, %d answers preselected\n", len(quiz), c.Context().Page,
+		len(selected))
+
+	return c.WriteToFile(outputPath)
+}
+
+// Starts a new page.  The page is created here rather than with c.NewPage, to be able to add the widget
+// annotations to it.
+func (fb *formBuilder) newPage() error {
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: fb.c.Width(), Ury: fb.c.Height()}
+	page.Resources = pdf.NewPdfPageResources()
+	// Tab order by rows.
+	page.Tabs = pdfcore.MakeName("R")
+	err := fb.c.AddPage(page)
+	if err != nil {
+		return err
+	}
+	fb.page = page
+	fb.y = margin
+	return nil
+}
+
+// Adds the text field for the respondent's name.
+func (fb *formBuilder) addNameField() error {
+	label := creator.NewParagraph("Name")
+	label.SetFont(fonts.NewFontHelveticaBold())
+	label.SetFontSize(fontSize + 1)
+	label.SetEnableWrap(false)
+	label.SetPos(margin, fb.y+(fieldHeight-label.Height())/2)
+	err := fb.c.Draw(label)
+	if err != nil {
+		return err
+	}
+
+	field := newField("Tx", "name", "Your name", 0)
+	field.V = pdfcore.MakeString("")
+	field.DA = pdfcore.MakeString(fmt.Sprintf("/Helv %g Tf 0 g", fontSize))
+	box := fmt.Sprintf("1 g 0.5 G 1 w 0.5 0.5 %.2f %.2f re b\n/Tx BMC EMC\n", nameWidth-1, fieldHeight-1)
+	widget := fb.addWidget(field, margin+50, fb.y, nameWidth, fieldHeight)
+	ap := pdfcore.MakeDict()
+	ap.Set("N", fb.makeAppearance(nameWidth, fieldHeight, box))
+	widget.AP = ap
+	mk := pdfcore.MakeDict()
+	mk.Set("BC", pdfcore.MakeArrayFromFloats([]float64{0.5, 0.5, 0.5}))
+	mk.Set("BG", pdfcore.MakeArrayFromFloats([]float64{1, 1, 1}))
+	widget.MK = mk
+
+	fb.y += fieldHeight + 20
+	return nil
+}
+
+// Adds a question with a radio button for each option, below each other, with the option selected if not empty.
+// The question starts on a new page if it does not fit on the current one.
+func (fb *formBuilder) addQuestion(number int, q question, selected string) error {
+	p := newParagraph(fmt.Sprintf("%d. %s", number, q.Text), fonts.NewFontHelveticaBold(),
+		fb.c.Width()-2*margin)
+	height := p.Height() + 4 + float64(len(q.Options))*optionHeight
+	if fb.y+height > fb.c.Height()-margin {
+		err := fb.newPage()
+		if err != nil {
+			return err
+		}
+	}
+	p.SetPos(margin, fb.y)
+	err := fb.c.Draw(p)
+	if err != nil {
+		return err
+	}
+	fb.y += p.Height() + 4
+
+	field := newField("Btn", q.Name, fmt.Sprintf("Question %d", number), fieldFlagRadio|fieldFlagNoToggleToOff)
+	if selected != "" {
+		field.V = pdfcore.MakeName(selected)
+	} else {
+		field.V = pdfcore.MakeName("Off")
+	}
+
+	r := boxSize / 2
+	off := fb.makeAppearance(boxSize, boxSize, "1 g 0.5 G 1 w "+circlePath(r, r, r-0.5)+"b\n")
+	on := fb.makeAppearance(boxSize, boxSize, "1 g 0.5 G 1 w "+circlePath(r, r, r-0.5)+"b\n0 g "+
+		circlePath(r, r, r/2.5)+"f\n")
+
+	for i, option := range q.Options {
+		letter := optionLetter(i)
+		x := margin + optionIndent
+		widget := fb.addWidget(field, x, fb.y+(optionHeight-boxSize)/2, boxSize, boxSize)
+		ap := pdfcore.MakeDict()
+		n := pdfcore.MakeDict()
+		n.Set(pdfcore.PdfObjectName(letter), on)
+		n.Set("Off", off)
+		ap.Set("N", n)
+		widget.AP = ap
+		// The appearance state is the option letter if selected.
+		if letter == selected {
+			widget.AS = pdfcore.MakeName(letter)
+		} else {
+			widget.AS = pdfcore.MakeName("Off")
+		}
+		mk := pdfcore.MakeDict()
+		mk.Set("BC", pdfcore.MakeArrayFromFloats([]float64{0.5, 0.5, 0.5}))
+		mk.Set("BG", pdfcore.MakeArrayFromFloats([]float64{1, 1, 1}))
+		mk.Set("CA", pdfcore.MakeString("l"))
+		widget.MK = mk
+
+		text := newParagraph(letter+". "+option, fonts.NewFontHelvetica(), 0)
+		text.SetPos(x+boxSize+6, fb.y+(optionHeight-text.Height())/2)
+		err := fb.c.Draw(text)
+		if err != nil {
+			return err
+		}
+		fb.y += optionHeight
+	}
+
+	fb.y += 14
+	return nil
+}
+
+// Returns a new terminal field of the type with the name, tooltip (alternate name) and flags.
+func newField(fieldType, name, tooltip string, flags int64) *pdf.PdfField {
+	field := pdf.NewPdfField()
+	field.FT = pdfcore.MakeName(fieldType)
+	field.T = pdfcore.MakeString(name)
+	field.TU = pdfcore.MakeString(tooltip)
+	if flags != 0 {
+		field.Ff = pdfcore.MakeInteger(flags)
+	}
+	return field
+}
+
+// Adds a widget annotation for the field at the position from the upper left corner of the current page.  The
+// field is added to the form with its first widget.
+func (fb *formBuilder) addWidget(field *pdf.PdfField, x, y, width, height float64) *pdf.PdfAnnotationWidget {
+	mediaBox := fb.page.MediaBox
+
+	widget := pdf.NewPdfAnnotationWidget()
+	// The annotation rectangle is in PDF coordinates, with the origin in the lower left corner.
+	widget.Rect = pdfcore.MakeArrayFromFloats([]float64{mediaBox.Llx + x, mediaBox.Ury - y - height,
+		mediaBox.Llx + x + width, mediaBox.Ury - y})
+	widget.F = pdfcore.MakeInteger(annotFlagPrint)
+	widget.P = fb.page.GetPageAsIndirectObject()
+	widget.Parent = field.GetContainingPdfObject()
+
+	if len(field.KidsA) == 0 {
+		fb.fields = append(fb.fields, field)
+	}
+	field.KidsA = append(field.KidsA, widget.PdfAnnotation)
+	fb.page.Annotations = append(fb.page.Annotations, widget.PdfAnnotation)
+	return widget
+}
+
+// Returns an appearance stream (form XObject) of the size with the content, using the form fonts.
+func (fb *formBuilder) makeAppearance(width, height float64, content string) *pdfcore.PdfObjectStream {
+	xform := pdf.NewXObjectForm()
+	xform.BBox = pdfcore.MakeArrayFromFloats([]float64{0, 0, width, height})
+	xform.Resources = fb.resources
+	xform.SetContentStream([]byte(content), nil)
+	return xform.ToPdfObject().(*pdfcore.PdfObjectStream)
+}
+
+// Returns the path of a circle with 4 Bezier curves.
+func circlePath(cx, cy, r float64) string {
+	k := 0.5523 * r
+	return fmt.Sprintf("%.2f %.2f m ", cx+r, cy) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", cx+r, cy+k, cx+k, cy+r, cx, cy+r) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", cx-k, cy+r, cx-r, cy+k, cx-r, cy) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", cx-r, cy-k, cx-k, cy-r, cx, cy-r) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c ", cx+k, cy-r, cx+r, cy-k, cx+r, cy)
+}
+
+// Returns a paragraph in the font, wrapped to the width if not 0.
+func newParagraph(text string, font fonts.Font, width float64) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	if width > 0 {
+		p.SetWidth(width)
+	} else {
+		p.SetEnableWrap(false)
+	}
+	return p
+}
+
+// Returns the letter of the option with the index: A, B, ...
+func optionLetter(i int) string {
+	return string(rune('A' + i))
+}
+
+// Returns the index of the option with the letter, or -1 if there is none.
+func optionIndex(q question, letter string) int {
+	for i := range q.Options {
+		if optionLetter(i) == letter {
+			return i
+		}
+	}
+	return -1
+}
+
+// Reads the completed form and reports the score against the answer key.
+func scoreForm(inputPath string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+	if pdfReader.AcroForm == nil || pdfReader.AcroForm.Fields == nil {
+		return fmt.Errorf("No form in %s", inputPath)
+	}
+
+	fields := map[string]*pdf.PdfField{}
+	collectFields(*pdfReader.AcroForm.Fields, "", fields)
+
+	name := ""
+	if field, ok := fields["name"]; ok {
+		if s, ok := pdfcore.TraceToDirectObject(field.V).(*pdfcore.PdfObjectString); ok {
+			name = strings.TrimSpace(string(*s))
+		}
+	}
+	if name == "" {
+		name = "(no name)"
+	}
+	fmt.Printf("Respondent: %s\n", name)
+
+	correct, unanswered := 0, 0
+	for i, q := range quiz {
+		key := optionLetter(q.Answer)
+		field, ok := fields[q.Name]
+		if !ok {
+			fmt.Printf("%2d. Missing from the form\n", i+1)
+			continue
+		}
+		answer := radioValue(field)
+		switch {
+		case answer == "":
+			unanswered++
+			fmt.Printf("%2d. Unanswered (correct answer %s)\n", i+1, key)
+		case optionIndex(q, answer) < 0:
+			fmt.Printf("%2d. Invalid answer %q (correct answer %s)\n", i+1, answer, key)
+		case answer == key:
+			correct++
+			fmt.Printf("%2d. Correct: %s\n", i+1, answer)
+		default:
+			fmt.Printf("%2d. Wrong: %s (correct answer %s)\n", i+1, answer, key)
+		}
+	}
+
+	if unanswered == len(quiz) {
+		fmt.Printf("The form has not been filled in\n")
+		return nil
+	}
+	fmt.Printf("Score: %d of %d (%.0f%%)", correct, len(quiz), 100*float64(correct)/float64(len(quiz)))
+	if unanswered > 0 {
+		fmt.Printf(", %d of %d questions unanswered", unanswered, len(quiz))
+	}
+	fmt.Printf("\n")
+	return nil
+}
+
+// Adds the fields and their descendants with a name to the map by their fully qualified names (the partial names
+// of the ancestors and the field, separated by periods).  The widgets of a field have no name of their own.
+func collectFields(fields []*pdf.PdfField, prefix string, found map[string]*pdf.PdfField) {
+	for _, field := range fields {
+		s, ok := pdfcore.TraceToDirectObject(field.T).(*pdfcore.PdfObjectString)
+		if !ok {
+			continue
+		}
+		name := prefix + string(*s)
+		found[name] = field
+
+		kids := []*pdf.PdfField{}
+		for _, kid := range field.KidsF {
+			if kf, ok := kid.(*pdf.PdfField); ok {
+				kids = append(kids, kf)
+			}
+		}
+		collectFields(kids, name+".", found)
+	}
+}
+
+// Returns the selected option of a radio button field, or "" if none.  The value of the field is the selected
+// option, or Off.  If the field has no value, the selected widget is the one with an appearance state other than
+// Off.
+func radioValue(field *pdf.PdfField) string {
+	if v, ok := pdfcore.TraceToDirectObject(field.V).(*pdfcore.PdfObjectName); ok {
+		if *v == "Off" {
+			return ""
+		}
+		return string(*v)
+	}
+
+	// The widgets are read as kids of the field, or merged into the field if there is only one.
+	annots := field.KidsA
+	for _, kid := range field.KidsF {
+		if kf, ok := kid.(*pdf.PdfField); ok {
+			annots = append(annots, kf.KidsA...)
+		}
+	}
+	for _, annot := range annots {
+		if state, ok := pdfcore.TraceToDirectObject(annot.AS).(*pdfcore.PdfObjectName); ok && *state != "Off" {
+			return string(*state)
+		}
+	}
+	return ""
+}
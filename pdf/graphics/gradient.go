@@ -0,0 +1,299 @@
+/*
+ * Draw linear and radial gradients as page backgrounds with text on top.
+ *
+ * The gradients are PDF shadings: an axial (type 2) shading for the linear gradient and a radial (type 3) shading,
+ * both with an exponential interpolation function (type 2) between the start and end colors.  The shading is painted
+ * with the sh operator, clipped to the page, before any other page content.
+ *
+ * To keep the text readable on any gradient, the text is placed on a translucent white panel (the opacity is set
+ * with an ExtGState); set the panel opacity to 0 to draw the text directly on the gradient.
+ *
+ * The first page has the linear gradient, in the given direction (angle in degrees: 0 is left to right, 90 bottom to
+ * top), and the second page the radial gradient from the page center outwards.
+ *
+ * Run as: go run gradient.go [-from #hex] [-to #hex] [-angle 90] [-panel 0.85] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run gradient.go [-from #hex] [-to #hex] [-angle 90] [-panel 0.85] output.pdf\n"
+
+var text = []string{
+	"A gradient is a shading: a smooth transition of colors defined by a function rather than by pixels, so it " +
+		"scales to any size and resolution without banding from image compression, and takes only a few hundred " +
+		"bytes in the file.",
+	"Axial shadings vary the color along a line between two points and are constant perpendicular to it.  Radial " +
+		"shadings vary the color between two circles.  With the Extend entry, the shading continues beyond the " +
+		"start and end points, so the whole clipped area is covered.",
+	"Text on a busy or dark background is hard to read.  Here the text is drawn on a translucent panel, which " +
+		"keeps the contrast high while the background still shows through.",
+}
+
+func main() {
+	fromHex := ""
+	toHex := ""
+	angle := 0.0
+	panelOpacity := 0.0
+	flag.StringVar(&fromHex, "from", "#2c3e50", "Start color")
+	flag.StringVar(&toHex, "to", "#4ca1af", "End color")
+	flag.Float64Var(&angle, "angle", 90, "Direction of the linear gradient in degrees (0: left to right)")
+	flag.Float64Var(&panelOpacity, "panel", 0.85, "Opacity of the text panel (0: no panel)")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+	for _, hex := range []string{fromHex, toHex} {
+		if len(hex) != 7 || hex[0] != '#' {
+			fmt.Printf("Error: invalid color %q (use #rrggbb)\n", hex)
+			os.Exit(1)
+		}
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	from := creator.ColorRGBFromHex(fromHex)
+	to := creator.ColorRGBFromHex(toHex)
+
+	err := createGradientPages(outputPath, from, to, angle, panelOpacity)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createGradientPages(outputPath string, from, to creator.Color, angle, panelOpacity float64) error {
+	c := creator.New()
+
+	for _, linear := range []bool{true, false} {
+		c.NewPage()
+
+		var shading *pdfcore.PdfObjectDictionary
+		title := ""
+		if linear {
+			shading = newLinearShading(c.Width(), c.Height(), angle, from, to)
+			title = fmt.Sprintf("Linear gradient (%.0f degrees)", angle)
+		} else {
+			shading = newRadialShading(c.Width(), c.Height(), from, to)
+			title = "Radial gradient"
+		}
+
+		err := c.Draw(&shadingBackground{shading: shading})
+		if err != nil {
+			return err
+		}
+
+		err = drawTextPanel(c, title, panelOpacity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Returns an axial shading dictionary covering the page in the direction of the angle (degrees).
+func newLinearShading(width, height, angle float64, from, to creator.Color) *pdfcore.PdfObjectDictionary {
+	// Project the page corners on the direction to find the start and end points.
+	dx, dy := math.Cos(angle*math.Pi/180), math.Sin(angle*math.Pi/180)
+	cx, cy := width/2, height/2
+	extent := 0.0
+	for _, corner := range [][2]float64{{0, 0}, {width, 0}, {0, height}, {width, height}} {
+		extent = math.Max(extent, math.Abs((corner[0]-cx)*dx+(corner[1]-cy)*dy))
+	}
+
+	shading := pdfcore.MakeDict()
+	shading.Set("ShadingType", pdfcore.MakeInteger(2))
+	shading.Set("ColorSpace", pdfcore.MakeName("DeviceRGB"))
+	shading.Set("Coords", pdfcore.MakeArrayFromFloats([]float64{
+		cx - dx*extent, cy - dy*extent, cx + dx*extent, cy + dy*extent,
+	}))
+	shading.Set("Function", newColorFunction(from, to).ToPdfObject())
+	shading.Set("Extend", makeExtend())
+	return shading
+}
+
+// Returns a radial shading dictionary from the page center to the corners.
+func newRadialShading(width, height float64, from, to creator.Color) *pdfcore.PdfObjectDictionary {
+	cx, cy := width/2, height/2
+	radius := math.Hypot(cx, cy)
+
+	shading := pdfcore.MakeDict()
+	shading.Set("ShadingType", pdfcore.MakeInteger(3))
+	shading.Set("ColorSpace", pdfcore.MakeName("DeviceRGB"))
+	// Start circle (center, radius 0) and end circle.
+	shading.Set("Coords", pdfcore.MakeArrayFromFloats([]float64{cx, cy, 0, cx, cy, radius}))
+	shading.Set("Function", newColorFunction(from, to).ToPdfObject())
+	shading.Set("Extend", makeExtend())
+	return shading
+}
+
+// Returns the Extend array [true true]: the shading continues beyond its start and end.
+func makeExtend() *pdfcore.PdfObjectArray {
+	extend := pdfcore.PdfObjectBool(true)
+	return pdfcore.MakeArray(&extend, &extend)
+}
+
+// Returns a function interpolating linearly between the colors over the domain [0 1].
+func newColorFunction(from, to creator.Color) *pdf.PdfFunctionType2 {
+	r0, g0, b0 := from.ToRGB()
+	r1, g1, b1 := to.ToRGB()
+	return &pdf.PdfFunctionType2{
+		Domain: []float64{0, 1},
+		C0:     []float64{r0, g0, b0},
+		C1:     []float64{r1, g1, b1},
+		N:      1,
+	}
+}
+
+// shadingBackground paints a shading over the whole page.  Implements the creator Drawable interface.
+type shadingBackground struct {
+	shading *pdfcore.PdfObjectDictionary
+}
+
+// GeneratePageBlocks draws the shading on a block representing the page.
+func (bg *shadingBackground) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext,
+	error) {
+	resources := pdf.NewPdfPageResources()
+	err := resources.SetShadingByName("Sh1", bg.shading)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	// The shading paints the whole clipping region: clip to the page.
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q().
+		Add_re(0, 0, ctx.PageWidth, ctx.PageHeight).
+		Add_W().
+		Add_n().
+		Add_sh("Sh1").
+		Add_Q()
+
+	block, err := newContentBlock(ctx, cc.String(), resources)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+// translucentPanel is a white rectangle with the given opacity.  Coordinates from the upper left corner of the
+// page.  Implements the creator Drawable interface.
+type translucentPanel struct {
+	x, y, width, height float64
+	opacity             float64
+}
+
+// GeneratePageBlocks draws the panel on a block representing the page.
+func (panel *translucentPanel) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext,
+	error) {
+	gs := pdfcore.MakeDict()
+	gs.Set("Type", pdfcore.MakeName("ExtGState"))
+	gs.Set("ca", pdfcore.MakeFloat(panel.opacity))
+
+	resources := pdf.NewPdfPageResources()
+	err := resources.AddExtGState("GS1", gs)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q().
+		Add_gs("GS1").
+		Add_rg(1, 1, 1).
+		Add_re(panel.x, ctx.PageHeight-panel.y-panel.height, panel.width, panel.height).
+		Add_f().
+		Add_Q()
+
+	block, err := newContentBlock(ctx, cc.String(), resources)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+// Creates a page size block with the contents and resources.  Blocks with custom contents are created from a page.
+func newContentBlock(ctx creator.DrawContext, contents string, resources *pdf.PdfPageResources) (*creator.Block,
+	error) {
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = resources
+	page.AddContentStreamByString(contents)
+
+	return creator.NewBlockFromPage(page)
+}
+
+// Draws the title and text on a panel, if the opacity is non-zero.
+func drawTextPanel(c *creator.Creator, title string, panelOpacity float64) error {
+	margin := 72.0
+	padding := 24.0
+	width := c.Width() - 2*margin - 2*padding
+
+	heading := creator.NewParagraph(title)
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(20)
+	heading.SetColor(creator.ColorRGBFrom8bit(20, 30, 40))
+	heading.SetWidth(width)
+
+	paragraphs := []*creator.Paragraph{heading}
+	for _, s := range text {
+		p := creator.NewParagraph(s)
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(12)
+		p.SetLineHeight(1.3)
+		p.SetColor(creator.ColorRGBFrom8bit(20, 30, 40))
+		p.SetWidth(width)
+		p.SetTextAlignment(creator.TextAlignmentJustify)
+		paragraphs = append(paragraphs, p)
+	}
+
+	// Panel height from the paragraph heights.
+	spacing := 12.0
+	height := 2 * padding
+	for i, p := range paragraphs {
+		height += p.Height()
+		if i > 0 {
+			height += spacing
+		}
+	}
+
+	if panelOpacity > 0 {
+		panel := &translucentPanel{x: margin, y: margin, width: c.Width() - 2*margin, height: height,
+			opacity: panelOpacity}
+		err := c.Draw(panel)
+		if err != nil {
+			return err
+		}
+	}
+
+	y := margin + padding
+	for _, p := range paragraphs {
+		p.SetPos(margin+padding, y)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+		y += p.Height() + spacing
+	}
+
+	return nil
+}
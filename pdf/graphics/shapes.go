@@ -0,0 +1,449 @@
+/*
+ * Draw vector graphics with the creator: lines, Bezier curves, polygons and filled circles with stroke and fill
+ * colors and line widths, followed by a simple illustration composed of these shapes.
+ *
+ * The creator's shapes (Line, Curve, FilledCurve, Ellipse, Rectangle) cover the basics.  For dashed lines and line
+ * join/cap styles, the example defines StyledPath, a creator Drawable that writes the path operations directly with
+ * the content stream creator.
+ *
+ * Run as: go run shapes.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	"github.com/unidoc/unidoc/pdf/contentstream/draw"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// Line join styles.
+const (
+	LineJoinMiter = 0
+	LineJoinRound = 1
+	LineJoinBevel = 2
+)
+
+// Line cap styles.
+const (
+	LineCapButt   = 0
+	LineCapRound  = 1
+	LineCapSquare = 2
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run shapes.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	err := drawShapes(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// pathSegment is a path construction operation: 'm' move, 'l' line, 'c' curve (2 control points and end point) or
+// 'h' close.
+type pathSegment struct {
+	op  byte
+	pts []float64
+}
+
+// StyledPath is a path of lines and cubic Bezier curves which is stroked and/or filled, with dash pattern, line join
+// and line cap styles.  The coordinates are as in the creator: from the upper left corner of the page.
+// Implements the creator Drawable interface.
+type StyledPath struct {
+	segments    []pathSegment
+	fillColor   creator.Color
+	strokeColor creator.Color
+	lineWidth   float64
+	lineJoin    int
+	lineCap     int
+	dashArray   []int64
+	dashPhase   int64
+}
+
+// NewStyledPath returns a new path, stroked in black with line width 1 and miter joins.
+func NewStyledPath() *StyledPath {
+	return &StyledPath{
+		strokeColor: creator.ColorRGBFrom8bit(0, 0, 0),
+		lineWidth:   1,
+	}
+}
+
+// NewPolygon returns a closed path through the points (x1, y1, x2, y2, ...).
+func NewPolygon(coords ...float64) *StyledPath {
+	path := NewStyledPath()
+	for i := 0; i+1 < len(coords); i += 2 {
+		if i == 0 {
+			path.MoveTo(coords[i], coords[i+1])
+		} else {
+			path.LineTo(coords[i], coords[i+1])
+		}
+	}
+	return path.Close()
+}
+
+// MoveTo starts a new subpath at (x, y).
+func (path *StyledPath) MoveTo(x, y float64) *StyledPath {
+	path.segments = append(path.segments, pathSegment{'m', []float64{x, y}})
+	return path
+}
+
+// LineTo adds a straight line to (x, y).
+func (path *StyledPath) LineTo(x, y float64) *StyledPath {
+	path.segments = append(path.segments, pathSegment{'l', []float64{x, y}})
+	return path
+}
+
+// CurveTo adds a cubic Bezier curve to (x, y) with control points (cx1, cy1) and (cx2, cy2).
+func (path *StyledPath) CurveTo(cx1, cy1, cx2, cy2, x, y float64) *StyledPath {
+	path.segments = append(path.segments, pathSegment{'c', []float64{cx1, cy1, cx2, cy2, x, y}})
+	return path
+}
+
+// Close closes the current subpath.
+func (path *StyledPath) Close() *StyledPath {
+	path.segments = append(path.segments, pathSegment{'h', nil})
+	return path
+}
+
+// SetFillColor sets the fill color.  The path is not filled unless a fill color is set.
+func (path *StyledPath) SetFillColor(col creator.Color) *StyledPath {
+	path.fillColor = col
+	return path
+}
+
+// SetStrokeColor sets the stroke color, nil for no stroke.
+func (path *StyledPath) SetStrokeColor(col creator.Color) *StyledPath {
+	path.strokeColor = col
+	return path
+}
+
+// SetLineWidth sets the stroke line width.
+func (path *StyledPath) SetLineWidth(lw float64) *StyledPath {
+	path.lineWidth = lw
+	return path
+}
+
+// SetLineJoin sets the line join style: LineJoinMiter, LineJoinRound or LineJoinBevel.
+func (path *StyledPath) SetLineJoin(join int) *StyledPath {
+	path.lineJoin = join
+	return path
+}
+
+// SetLineCap sets the line cap style: LineCapButt, LineCapRound or LineCapSquare.
+func (path *StyledPath) SetLineCap(lineCap int) *StyledPath {
+	path.lineCap = lineCap
+	return path
+}
+
+// SetDash sets the dash pattern: alternating dash and gap lengths, starting at the phase offset in the pattern.
+// An empty pattern is a solid line.
+func (path *StyledPath) SetDash(dashArray []int64, phase int64) *StyledPath {
+	path.dashArray = dashArray
+	path.dashPhase = phase
+	return path
+}
+
+// GeneratePageBlocks draws the path on a block representing the page.  Implements the Drawable interface.
+func (path *StyledPath) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q()
+
+	if path.fillColor != nil {
+		cc.Add_rg(path.fillColor.ToRGB())
+	}
+	if path.strokeColor != nil {
+		cc.Add_RG(path.strokeColor.ToRGB())
+		cc.Add_w(path.lineWidth)
+		if len(path.dashArray) > 0 {
+			cc.Add_d(path.dashArray, path.dashPhase)
+		}
+		// The content creator's Add_j and Add_J write the style as a name, but it needs to be an integer.
+		ops := cc.Operations()
+		*ops = append(*ops,
+			&pdfcontent.ContentStreamOperation{
+				Operand: "j",
+				Params:  []pdfcore.PdfObject{pdfcore.MakeInteger(int64(path.lineJoin))},
+			},
+			&pdfcontent.ContentStreamOperation{
+				Operand: "J",
+				Params:  []pdfcore.PdfObject{pdfcore.MakeInteger(int64(path.lineCap))},
+			})
+	}
+
+	// Convert to PDF coordinates with the origin in the lower left corner.
+	for _, seg := range path.segments {
+		switch seg.op {
+		case 'm':
+			cc.Add_m(seg.pts[0], ctx.PageHeight-seg.pts[1])
+		case 'l':
+			cc.Add_l(seg.pts[0], ctx.PageHeight-seg.pts[1])
+		case 'c':
+			cc.Add_c(seg.pts[0], ctx.PageHeight-seg.pts[1], seg.pts[2], ctx.PageHeight-seg.pts[3],
+				seg.pts[4], ctx.PageHeight-seg.pts[5])
+		case 'h':
+			cc.Add_h()
+		}
+	}
+
+	switch {
+	case path.fillColor != nil && path.strokeColor != nil:
+		cc.Add_B()
+	case path.fillColor != nil:
+		cc.Add_f()
+	case path.strokeColor != nil:
+		cc.Add_S()
+	default:
+		cc.Add_n()
+	}
+	cc.Add_Q()
+
+	// Blocks with custom contents are created from a page with the contents.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = pdf.NewPdfPageResources()
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+func drawShapes(outputPath string) error {
+	c := creator.New()
+	c.NewPage()
+
+	black := creator.ColorRGBFrom8bit(0, 0, 0)
+	gray := creator.ColorRGBFrom8bit(150, 150, 150)
+	blue := creator.ColorRGBFrom8bit(41, 128, 185)
+	red := creator.ColorRGBFrom8bit(192, 57, 43)
+	green := creator.ColorRGBFrom8bit(39, 174, 96)
+	orange := creator.ColorRGBFrom8bit(230, 126, 34)
+
+	drawables := []creator.Drawable{}
+	add := func(d ...creator.Drawable) {
+		drawables = append(drawables, d...)
+	}
+	label := func(text string, x, y float64) {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(9)
+		p.SetPos(x, y)
+		add(p)
+	}
+	heading := func(text string, x, y float64) {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(12)
+		p.SetPos(x, y)
+		add(p)
+	}
+
+	title := creator.NewParagraph("Vector graphics")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(18)
+	title.SetPos(50, 40)
+	add(title)
+
+	// Line widths, with the creator's Line.
+	heading("Line widths", 50, 80)
+	for i, lw := range []float64{0.5, 1, 2, 4} {
+		y := 105 + float64(i)*14
+		line := creator.NewLine(110, y, 250, y)
+		line.SetLineWidth(lw)
+		line.SetColor(blue)
+		add(line)
+		label(fmt.Sprintf("%.1f pt", lw), 50, y-5)
+	}
+
+	// Dash patterns.
+	heading("Dash patterns", 300, 80)
+	for i, dash := range []struct {
+		array []int64
+		phase int64
+		name  string
+	}{
+		{nil, 0, "solid"},
+		{[]int64{6, 3}, 0, "[6 3] 0"},
+		{[]int64{2, 2}, 0, "[2 2] 0"},
+		{[]int64{8, 3, 2, 3}, 4, "[8 3 2 3] 4"},
+	} {
+		y := 105 + float64(i)*14
+		path := NewStyledPath().MoveTo(380, y).LineTo(540, y)
+		path.SetLineWidth(2).SetStrokeColor(red).SetDash(dash.array, dash.phase)
+		add(path)
+		label(dash.name, 300, y-5)
+	}
+
+	// Line joins: the same zigzag with a wide line in each join style, with the path drawn thin on top.
+	heading("Line joins and caps", 50, 175)
+	for i, style := range []struct {
+		join, lineCap int
+		name          string
+	}{
+		{LineJoinMiter, LineCapButt, "miter join, butt cap"},
+		{LineJoinRound, LineCapRound, "round join, round cap"},
+		{LineJoinBevel, LineCapSquare, "bevel join, square cap"},
+	} {
+		x := 60 + float64(i)*170
+		zigzag := func() *StyledPath {
+			return NewStyledPath().MoveTo(x, 260).LineTo(x+35, 205).LineTo(x+70, 260).LineTo(x+105, 205)
+		}
+		add(zigzag().SetLineWidth(12).SetStrokeColor(orange).SetLineJoin(style.join).SetLineCap(style.lineCap))
+		add(zigzag().SetLineWidth(0.5).SetStrokeColor(black))
+		label(style.name, x, 272)
+	}
+
+	// Bezier curves: the creator's Curve (one control point) and a cubic curve with its control points shown.
+	heading("Bezier curves", 50, 300)
+	curve := creator.NewCurve(60, 420, 130, 330, 200, 420)
+	curve.SetWidth(2)
+	curve.SetColor(blue)
+	add(curve)
+	add(NewStyledPath().MoveTo(60, 420).LineTo(130, 330).LineTo(200, 420).SetStrokeColor(gray).
+		SetDash([]int64{2, 2}, 0))
+	add(newDot(130, 330, gray))
+
+	cubic := NewStyledPath().MoveTo(260, 420).CurveTo(280, 330, 400, 450, 420, 350)
+	add(cubic.SetLineWidth(2).SetStrokeColor(red))
+	add(NewStyledPath().MoveTo(260, 420).LineTo(280, 330).SetStrokeColor(gray).SetDash([]int64{2, 2}, 0))
+	add(NewStyledPath().MoveTo(420, 350).LineTo(400, 450).SetStrokeColor(gray).SetDash([]int64{2, 2}, 0))
+	add(newDot(280, 330, gray), newDot(400, 450, gray))
+	label("Curve (quadratic, one control point)", 60, 430)
+	label("Cubic curve (two control points)", 430, 345)
+
+	// Polygons and circles.
+	heading("Polygons and circles", 50, 475)
+	add(NewPolygon(60, 575, 100, 505, 140, 575).SetFillColor(green).SetLineWidth(2))
+	add(NewPolygon(170, 540, 190, 505, 230, 505, 250, 540, 230, 575, 190, 575).SetFillColor(orange).
+		SetStrokeColor(nil))
+	add(newStar(310, 540, 38, 16, 5).SetFillColor(red).SetStrokeColor(black).SetLineJoin(LineJoinRound))
+	for i, col := range []creator.Color{blue, green, orange} {
+		circle := creator.NewEllipse(390+float64(i)*45, 540, 40, 40)
+		circle.SetFillColor(col)
+		circle.SetBorderColor(black)
+		circle.SetBorderWidth(1.5)
+		add(circle)
+	}
+
+	// Composed illustration.
+	heading("Illustration", 50, 600)
+	addIllustration(add, c.Height(), 50, 625, 512, 140)
+
+	for _, d := range drawables {
+		err := c.Draw(d)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Adds a landscape with a house, sun, hills and a fence in the area with the upper left corner (x, y).
+func addIllustration(add func(...creator.Drawable), pageHeight, x, y, width, height float64) {
+	// Sky.
+	sky := creator.NewRectangle(x, y, width, height)
+	sky.SetFillColor(creator.ColorRGBFrom8bit(214, 234, 248))
+	sky.SetBorderColor(creator.ColorRGBFrom8bit(120, 120, 120))
+	add(sky)
+
+	// Sun with dashed rays.
+	sunX, sunY := x+width-70, y+45
+	for i := 0; i < 12; i++ {
+		v := draw.NewVectorPolar(1, float64(i)*math.Pi/6)
+		add(NewStyledPath().MoveTo(sunX+24*v.Dx, sunY+24*v.Dy).LineTo(sunX+38*v.Dx, sunY+38*v.Dy).
+			SetStrokeColor(creator.ColorRGBFrom8bit(243, 156, 18)).SetLineWidth(2).SetLineCap(LineCapRound).
+			SetDash([]int64{4, 3}, 0))
+	}
+	sun := creator.NewEllipse(sunX, sunY, 36, 36)
+	sun.SetFillColor(creator.ColorRGBFrom8bit(241, 196, 15))
+	sun.SetBorderColor(creator.ColorRGBFrom8bit(243, 156, 18))
+	add(sun)
+
+	// Hills, as filled curves.  Note that FilledCurve uses PDF coordinates, i.e. from the lower left corner.
+	bottom := pageHeight - (y + height)
+	hills := creator.NewFilledCurve()
+	hills.AppendCurve(draw.NewCubicBezierCurve(x, bottom+50, x+100, bottom+110, x+200, bottom+20, x+300, bottom+60))
+	hills.AppendCurve(draw.NewCubicBezierCurve(x+300, bottom+60, x+380, bottom+95, x+450, bottom+40, x+width,
+		bottom+55))
+	hills.AppendCurve(draw.NewCubicBezierCurve(x+width, bottom+55, x+width, bottom, x+width, bottom, x+width,
+		bottom))
+	hills.AppendCurve(draw.NewCubicBezierCurve(x+width, bottom, x, bottom, x, bottom, x, bottom))
+	hills.FillEnabled = true
+	hills.SetFillColor(creator.ColorRGBFrom8bit(88, 180, 100))
+	add(hills)
+
+	// House: walls and roof polygons with round and miter joins.
+	hx, hy := x+140, y+height-35
+	add(NewPolygon(hx, hy, hx+70, hy, hx+70, hy-50, hx, hy-50).
+		SetFillColor(creator.ColorRGBFrom8bit(236, 240, 241)).SetLineWidth(2).SetLineJoin(LineJoinRound))
+	add(NewPolygon(hx-10, hy-50, hx+35, hy-85, hx+80, hy-50).
+		SetFillColor(creator.ColorRGBFrom8bit(192, 57, 43)).SetLineWidth(2).SetLineJoin(LineJoinMiter))
+	door := creator.NewRectangle(hx+28, hy-28, 14, 28)
+	door.SetFillColor(creator.ColorRGBFrom8bit(120, 80, 50))
+	add(door)
+
+	// Winding path to the door.
+	walk := creator.NewCurve(hx+35, hy, hx+10, hy+20, hx+60, y+height)
+	walk.SetWidth(3)
+	walk.SetColor(creator.ColorRGBFrom8bit(190, 160, 110))
+	add(walk)
+
+	// Fence.
+	for i := 0; i < 10; i++ {
+		fx := x + 330 + float64(i)*12
+		post := creator.NewLine(fx, y+height-8, fx, y+height-30)
+		post.SetLineWidth(2)
+		post.SetColor(creator.ColorRGBFrom8bit(110, 80, 50))
+		add(post)
+	}
+	rail := creator.NewLine(x+326, y+height-22, x+442, y+height-22)
+	rail.SetLineWidth(1.5)
+	rail.SetColor(creator.ColorRGBFrom8bit(110, 80, 50))
+	add(rail)
+}
+
+// Returns a small filled circle, e.g. to mark a point.
+func newDot(x, y float64, col creator.Color) *creator.Ellipse {
+	dot := creator.NewEllipse(x, y, 5, 5)
+	dot.SetFillColor(col)
+	dot.SetBorderColor(col)
+	return dot
+}
+
+// Returns a star polygon centered at (x, y) with the given outer and inner radius and number of points.
+func newStar(x, y, outer, inner float64, points int) *StyledPath {
+	coords := []float64{}
+	for i := 0; i < 2*points; i++ {
+		r := outer
+		if i%2 == 1 {
+			r = inner
+		}
+		// Start at the top.
+		v := draw.NewVectorPolar(r, -math.Pi/2+float64(i)*math.Pi/float64(points))
+		coords = append(coords, x+v.Dx, y+v.Dy)
+	}
+	return NewPolygon(coords...)
+}
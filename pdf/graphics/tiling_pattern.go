@@ -0,0 +1,302 @@
+/*
+ * Fill page backgrounds with a repeating motif, using a PDF tiling pattern.
+ *
+ * A tiling pattern is a small content stream, the pattern cell, that the viewer repeats at fixed horizontal and
+ * vertical steps over the area being filled.  It is used like a color: the fill color space is set to Pattern with
+ * the cs operator, the pattern is selected with scn, and any shape filled afterwards shows the tiles.  The cell is
+ * written once however large the area, so the file stays small and the motif stays sharp at any zoom.
+ *
+ * There is a page for each motif: a grid, dots and diamonds, with the given tile spacing (-spacing, in points) and
+ * color (-color).  The patterns are colored patterns (PaintType 1): the color is part of the cell.  Uncolored
+ * patterns (PaintType 2) take the color when they are selected, as extra operands of scn, but the creator does not
+ * carry pattern resources over for scn with more than a name operand when it draws blocks, so this example does not
+ * use them.
+ *
+ * Pattern space is the default coordinate space of the page, whatever the transformations in effect when the
+ * pattern is used.  The pattern matrix moves the first tile to the top left corner of the page, so the tiles line
+ * up with the top and left edges.
+ *
+ * To keep the content readable, the text is drawn on an opaque white panel, which covers the pattern; a pattern
+ * color that is too dark for text to be drawn on directly is reported.  A light color, like the default, makes the
+ * background subtle.
+ *
+ * Run as: go run tiling_pattern.go [-spacing 24] [-color #hex] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run tiling_pattern.go [-spacing 24] [-color #hex] output.pdf\n"
+
+// Bezier control point distance for a quarter circle of radius 1.
+const kappa = 0.5523
+
+// motif draws the content of a pattern cell of size x size points, in the pattern color.
+type motif struct {
+	Name        string
+	Description string
+	Draw        func(cc *pdfcontent.ContentCreator, size float64)
+}
+
+var motifs = []motif{
+	{
+		Name: "Grid",
+		Description: "The cell is a thin line along its bottom and left edges, filled as rectangles so that " +
+			"they are not clipped by the cell boundary.  Repeated, the lines form a grid like graph paper.",
+		Draw: func(cc *pdfcontent.ContentCreator, size float64) {
+			cc.Add_re(0, 0, size, 0.5).
+				Add_re(0, 0, 0.5, size).
+				Add_f()
+		},
+	},
+	{
+		Name: "Dots",
+		Description: "The cell is a small circle in its center, made of four Bezier curves.  The space around it " +
+			"is left unpainted, so whatever is below the pattern shows through.",
+		Draw: func(cc *pdfcontent.ContentCreator, size float64) {
+			addCircle(cc, size/2, size/2, size/10)
+			cc.Add_f()
+		},
+	},
+	{
+		Name: "Diamonds",
+		Description: "The cell is a diamond in its center with a smaller one in the corners.  The corner diamond is " +
+			"drawn in four parts, one in each corner of the cell, which join up with the neighboring tiles.",
+		Draw: func(cc *pdfcontent.ContentCreator, size float64) {
+			addDiamond(cc, size/2, size/2, size/5)
+			for _, corner := range [][2]float64{{0, 0}, {size, 0}, {0, size}, {size, size}} {
+				addDiamond(cc, corner[0], corner[1], size/10)
+			}
+			cc.Add_f()
+		},
+	},
+}
+
+var textColor = creator.ColorRGBFrom8bit(20, 30, 40)
+
+func main() {
+	spacing := 0.0
+	colorHex := ""
+	flag.Float64Var(&spacing, "spacing", 24, "Tile spacing in points")
+	flag.StringVar(&colorHex, "color", "#c8d4e3", "Pattern color")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if spacing < 4 || spacing > 200 {
+		fmt.Printf("Error: -spacing must be between 4 and 200 points\n")
+		os.Exit(1)
+	}
+	if len(colorHex) != 7 || colorHex[0] != '#' {
+		fmt.Printf("Error: invalid color %q (use #rrggbb)\n", colorHex)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	color := creator.ColorRGBFromHex(colorHex)
+	r, g, b := color.ToRGB()
+	if luminance := 0.2126*r + 0.7152*g + 0.0722*b; luminance < 0.6 {
+		fmt.Printf("Note: the pattern color %s is dark (luminance %.2f), text must not be drawn on it directly\n",
+			colorHex, luminance)
+	}
+
+	err := createPatternPages(outputPath, spacing, color)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createPatternPages(outputPath string, spacing float64, color creator.Color) error {
+	c := creator.New()
+
+	for _, m := range motifs {
+		c.NewPage()
+
+		err := c.Draw(&patternBackground{motif: m, spacing: spacing, color: color})
+		if err != nil {
+			return err
+		}
+
+		err = drawTextPanel(c, fmt.Sprintf("%s pattern, %.0f point tiles", m.Name, spacing), []string{
+			m.Description,
+			"The pattern fills a rectangle over the whole page, drawn first so that everything else is on top of " +
+				"it.  The cell is stored once, with the horizontal and vertical steps at which the viewer repeats " +
+				"it.",
+			"This panel is opaque white: it covers the pattern, so the text has the same contrast as on a plain " +
+				"page, whatever the pattern color.",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// patternBackground fills the whole page with a tiling pattern of the motif.  Implements the creator Drawable
+// interface.
+type patternBackground struct {
+	motif   motif
+	spacing float64
+	color   creator.Color
+}
+
+// GeneratePageBlocks draws the pattern on a block representing the page.
+func (bg *patternBackground) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext,
+	error) {
+	pattern, err := newTilingPattern(bg.motif, bg.spacing, bg.color, ctx.PageHeight)
+	if err != nil {
+		return nil, ctx, err
+	}
+	resources := pdf.NewPdfPageResources()
+	err = resources.SetPatternByName("P1", pattern)
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q().
+		Add_cs("Pattern").
+		Add_scn_pattern("P1").
+		Add_re(0, 0, ctx.PageWidth, ctx.PageHeight).
+		Add_f().
+		Add_Q()
+
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = resources
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+// Returns a colored tiling pattern with a cell of spacing x spacing points with the motif in the color.  The first
+// tile is at the top left corner of a page of the page height.
+func newTilingPattern(m motif, spacing float64, color creator.Color, pageHeight float64) (*pdfcore.PdfObjectStream,
+	error) {
+	cc := pdfcontent.NewContentCreator()
+	r, g, b := color.ToRGB()
+	cc.Add_rg(r, g, b)
+	m.Draw(cc, spacing)
+
+	pattern, err := pdfcore.MakeStream(cc.Bytes(), nil)
+	if err != nil {
+		return nil, err
+	}
+	dict := pattern.PdfObjectDictionary
+	dict.Set("Type", pdfcore.MakeName("Pattern"))
+	dict.Set("PatternType", pdfcore.MakeInteger(1)) // Tiling.
+	dict.Set("PaintType", pdfcore.MakeInteger(1))   // Colored.
+	dict.Set("TilingType", pdfcore.MakeInteger(1))  // Constant spacing.
+	dict.Set("BBox", pdfcore.MakeArrayFromFloats([]float64{0, 0, spacing, spacing}))
+	dict.Set("XStep", pdfcore.MakeFloat(spacing))
+	dict.Set("YStep", pdfcore.MakeFloat(spacing))
+	dict.Set("Resources", pdfcore.MakeDict())
+	dict.Set("Matrix", pdfcore.MakeArrayFromFloats([]float64{1, 0, 0, 1, 0, pageHeight}))
+	return pattern, nil
+}
+
+// Adds a circle path with the center (x, y) and radius r.
+func addCircle(cc *pdfcontent.ContentCreator, x, y, r float64) {
+	k := kappa * r
+	cc.Add_m(x+r, y).
+		Add_c(x+r, y+k, x+k, y+r, x, y+r).
+		Add_c(x-k, y+r, x-r, y+k, x-r, y).
+		Add_c(x-r, y-k, x-k, y-r, x, y-r).
+		Add_c(x+k, y-r, x+r, y-k, x+r, y).
+		Add_h()
+}
+
+// Adds a diamond path with the center (x, y) and the distance r from the center to the corners.
+func addDiamond(cc *pdfcontent.ContentCreator, x, y, r float64) {
+	cc.Add_m(x, y-r).
+		Add_l(x+r, y).
+		Add_l(x, y+r).
+		Add_l(x-r, y).
+		Add_h()
+}
+
+// Draws the title and text on an opaque white panel with a border.
+func drawTextPanel(c *creator.Creator, title string, text []string) error {
+	margin := 72.0
+	padding := 24.0
+	width := c.Width() - 2*margin - 2*padding
+
+	heading := creator.NewParagraph(title)
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(20)
+	heading.SetColor(textColor)
+	heading.SetWidth(width)
+
+	paragraphs := []*creator.Paragraph{heading}
+	for _, s := range text {
+		p := creator.NewParagraph(s)
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(12)
+		p.SetLineHeight(1.3)
+		p.SetColor(textColor)
+		p.SetWidth(width)
+		p.SetTextAlignment(creator.TextAlignmentJustify)
+		paragraphs = append(paragraphs, p)
+	}
+
+	// Panel height from the paragraph heights.
+	spacing := 12.0
+	height := 2 * padding
+	for i, p := range paragraphs {
+		height += p.Height()
+		if i > 0 {
+			height += spacing
+		}
+	}
+
+	panel := creator.NewRectangle(margin, margin, c.Width()-2*margin, height)
+	panel.SetFillColor(creator.ColorWhite)
+	panel.SetBorderColor(textColor)
+	panel.SetBorderWidth(1)
+	err := c.Draw(panel)
+	if err != nil {
+		return err
+	}
+
+	y := margin + padding
+	for _, p := range paragraphs {
+		p.SetPos(margin+padding, y)
+		err := c.Draw(p)
+		if err != nil {
+			return err
+		}
+		y += p.Height() + spacing
+	}
+
+	return nil
+}
@@ -0,0 +1,432 @@
+/*
+ * Draw overlapping translucent shapes to show the transparency features of PDF: constant opacity, blend modes,
+ * transparency groups and soft masks.  All of them are set with ExtGState (graphics state parameter) dictionaries,
+ * selected in the content stream with the gs operator.
+ *
+ * Each sample has three overlapping circles in red, green and blue over a checkerboard, so that the backdrop shows
+ * through.  The page has three sections:
+ * - Opacity: the fill opacity (ca) of the circles is set per object, so the overlaps are more opaque than the rest.
+ *   In a transparency group the circles are first composited opaquely and the group is then painted with the
+ *   opacity, so the overlaps do not show.  In a knockout group each circle replaces the ones below it instead of
+ *   being composited with them.
+ * - Blend modes (BM): how the colors of a circle combine with the colors below it.
+ * - Soft mask (SMask): the opacity varies over the shapes, taken from the luminosity of a gradient.
+ * The groups are form XObjects with a Group dictionary.  The page itself is also given a transparency group, which
+ * sets the color space that the viewer blends in.
+ *
+ * A graphics state parameter stays in effect until it is changed or the graphics state is restored, so every
+ * translucent drawing is enclosed in a q/Q pair, which saves and restores the graphics state.  The creator wraps the
+ * content of each block it draws in the same way, but content streams written with the content stream API may also
+ * be added to pages directly, so the content does not rely on it.  The box and text drawn last are fully opaque.
+ *
+ * Run as: go run transparency.go [-alpha 0.5] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run transparency.go [-alpha 0.5] output.pdf\n"
+
+// Page layout (points).
+const (
+	margin     = 50.0
+	sampleGap  = 15.0
+	labelSpace = 16.0 // Space for the label below a sample.
+	checker    = 8.0  // Size of the checkerboard squares.
+)
+
+// The circle colors (RGB, 0-1).
+var circleColors = [][3]float64{{0.9, 0.15, 0.15}, {0.15, 0.7, 0.25}, {0.15, 0.35, 0.9}}
+
+var blendModes = []string{"Normal", "Multiply", "Screen", "Overlay", "Darken", "Lighten", "Difference",
+	"Exclusion"}
+
+func main() {
+	alpha := 0.0
+	flag.Float64Var(&alpha, "alpha", 0.5, "Opacity of the translucent shapes, from 0 (transparent) to 1 (opaque)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if alpha < 0 || alpha > 1 {
+		fmt.Printf("Error: -alpha must be between 0 and 1\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := createTransparencyPage(alpha, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createTransparencyPage(alpha float64, outputPath string) error {
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA4)
+
+	// The page transparency group: the colors are blended in DeviceRGB.
+	group := pdfcore.MakeDict()
+	group.Set("Type", pdfcore.MakeName("Group"))
+	group.Set("S", pdfcore.MakeName("Transparency"))
+	group.Set("CS", pdfcore.MakeName("DeviceRGB"))
+
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: c.Width(), Ury: c.Height()}
+	page.Group = group
+	err := c.AddPage(page)
+	if err != nil {
+		return err
+	}
+
+	y := margin
+	err = drawText(c, "Transparency", fonts.NewFontHelveticaBold(), 20, margin, y)
+	if err != nil {
+		return err
+	}
+	y += 34
+
+	// Opacity: per object, as a transparency group and as a knockout group.
+	width := (c.Width() - 2*margin - 2*sampleGap) / 3
+	height := 130.0
+	y, err = drawSection(c, fmt.Sprintf("Opacity %.2f", alpha), y)
+	if err != nil {
+		return err
+	}
+	for i, opacity := range []struct {
+		label string
+		draw  func(s *sample)
+	}{
+		{"Per object", func(s *sample) { s.circles(alpha, "Normal") }},
+		{"Transparency group", func(s *sample) { s.group(alpha, false) }},
+		{"Knockout group", func(s *sample) { s.group(alpha, true) }},
+	} {
+		err := drawSample(c, opacity.label, margin+float64(i)*(width+sampleGap), y, width, height, opacity.draw)
+		if err != nil {
+			return err
+		}
+	}
+	y += height + labelSpace + sampleGap
+
+	// Blend modes, 4 per row.
+	y, err = drawSection(c, "Blend modes", y)
+	if err != nil {
+		return err
+	}
+	width = (c.Width() - 2*margin - 3*sampleGap) / 4
+	height = 90.0
+	for i, mode := range blendModes {
+		mode := mode
+		x := margin + float64(i%4)*(width+sampleGap)
+		top := y + float64(i/4)*(height+labelSpace+sampleGap/2)
+		err := drawSample(c, mode, x, top, width, height, func(s *sample) { s.circles(alpha, mode) })
+		if err != nil {
+			return err
+		}
+	}
+	y += 2*(height+labelSpace) + sampleGap/2 + sampleGap
+
+	// Soft mask.
+	y, err = drawSection(c, "Soft mask", y)
+	if err != nil {
+		return err
+	}
+	width = c.Width() - 2*margin
+	height = 90.0
+	err = drawSample(c, "Luminosity soft mask from a gradient, transparent on the left to opaque on the right",
+		margin, y, width, height, func(s *sample) { s.softMask() })
+	if err != nil {
+		return err
+	}
+	y += height + labelSpace + sampleGap
+
+	// Opaque content drawn after the translucent content: the graphics state was restored.
+	frame := creator.NewRectangle(margin, y, c.Width()-2*margin, 36)
+	frame.SetFillColor(creator.ColorRGBFrom8bit(44, 62, 80))
+	frame.SetBorderWidth(0)
+	err = c.Draw(frame)
+	if err != nil {
+		return err
+	}
+	p := creator.NewParagraph("This box and its text are drawn last and are fully opaque: every translucent " +
+		"drawing above restores the graphics state when it ends.")
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(9)
+	p.SetColor(creator.ColorWhite)
+	p.SetWidth(c.Width() - 2*margin - 20)
+	p.SetPos(margin+10, y+8)
+	err = c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Draws a section heading at y and returns the y position below it.
+func drawSection(c *creator.Creator, title string, y float64) (float64, error) {
+	err := drawText(c, title, fonts.NewFontHelveticaBold(), 12, margin, y)
+	return y + 20, err
+}
+
+func drawText(c *creator.Creator, text string, font fonts.Font, fontSize float64, x, y float64) error {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetPos(x, y)
+	return c.Draw(p)
+}
+
+// Draws a sample in the area with the upper left corner (x, y): the checkerboard backdrop, the shapes drawn by the
+// draw function and the label below.
+func drawSample(c *creator.Creator, label string, x, y, width, height float64, draw func(s *sample)) error {
+	s := &sample{
+		cc:        pdfcontent.NewContentCreator(),
+		resources: pdf.NewPdfPageResources(),
+		x:         x,
+		y:         c.Height() - y - height,
+		width:     width,
+		height:    height,
+	}
+	s.checkerboard()
+	draw(s)
+	if s.err != nil {
+		return s.err
+	}
+	err := c.Draw(s)
+	if err != nil {
+		return err
+	}
+
+	return drawText(c, label, fonts.NewFontHelvetica(), 9, x, y+height+4)
+}
+
+// sample is the content of a sample area, written with the content stream API.  The coordinates are PDF user space
+// coordinates, with the origin in the lower left corner of the page and (x, y) the lower left corner of the area.
+// Implements the creator Drawable interface.
+type sample struct {
+	cc                  *pdfcontent.ContentCreator
+	resources           *pdf.PdfPageResources
+	x, y, width, height float64
+	names               int   // Number of resource names used.
+	err                 error // First error adding resources.
+}
+
+// Draws a light gray checkerboard over the area.
+func (s *sample) checkerboard() {
+	s.cc.Add_q().
+		Add_rg(1, 1, 1).
+		Add_re(s.x, s.y, s.width, s.height).
+		Add_f().
+		Add_rg(0.8, 0.8, 0.8)
+	for row := 0; float64(row)*checker < s.height; row++ {
+		for col := row % 2; float64(col)*checker < s.width; col += 2 {
+			w := minFloat(checker, s.width-float64(col)*checker)
+			h := minFloat(checker, s.height-float64(row)*checker)
+			s.cc.Add_re(s.x+float64(col)*checker, s.y+float64(row)*checker, w, h)
+		}
+	}
+	s.cc.Add_f().
+		Add_Q()
+}
+
+// Draws the circles with the fill opacity and blend mode set per object.
+func (s *sample) circles(alpha float64, blendMode string) {
+	gs := s.addExtGState(newExtGState(alpha, blendMode))
+	s.cc.Add_q().
+		Add_gs(gs)
+	addCircles(s.cc, s.x, s.y, s.width, s.height)
+	s.cc.Add_Q()
+}
+
+// Draws the circles in a transparency group, painted with the fill opacity.  In a knockout group, the opacity is set
+// on the circles instead, and each circle knocks out the ones below it.
+func (s *sample) group(alpha float64, knockout bool) {
+	group := pdfcore.MakeDict()
+	group.Set("Type", pdfcore.MakeName("Group"))
+	group.Set("S", pdfcore.MakeName("Transparency"))
+	isolated := pdfcore.PdfObjectBool(true)
+	group.Set("I", &isolated)
+	k := pdfcore.PdfObjectBool(knockout)
+	group.Set("K", &k)
+
+	form := pdf.NewXObjectForm()
+	form.Resources = pdf.NewPdfPageResources()
+	form.BBox = pdfcore.MakeArrayFromFloats([]float64{s.x, s.y, s.x + s.width, s.y + s.height})
+	form.Group = group
+	groupAlpha := alpha
+	cc := pdfcontent.NewContentCreator()
+	if knockout {
+		err := form.Resources.AddExtGState("GS0", newExtGState(alpha, "Normal"))
+		if err != nil {
+			s.err = err
+			return
+		}
+		cc.Add_gs("GS0")
+		groupAlpha = 1
+	}
+	addCircles(cc, s.x, s.y, s.width, s.height)
+	err := form.SetContentStream(cc.Bytes(), nil)
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	gs := s.addExtGState(newExtGState(groupAlpha, "Normal"))
+	name := s.addForm(form)
+	s.cc.Add_q().
+		Add_gs(gs).
+		Add_Do(name).
+		Add_Q()
+}
+
+// Draws a row of the circles with a soft mask: the luminosity of a gradient from black on the left to white on the
+// right gives the opacity, from transparent to opaque.
+func (s *sample) softMask() {
+	shading := pdfcore.MakeDict()
+	shading.Set("ShadingType", pdfcore.MakeInteger(2))
+	shading.Set("ColorSpace", pdfcore.MakeName("DeviceGray"))
+	shading.Set("Coords", pdfcore.MakeArrayFromFloats([]float64{s.x, 0, s.x + s.width, 0}))
+	function := &pdf.PdfFunctionType2{Domain: []float64{0, 1}, C0: []float64{0}, C1: []float64{1}, N: 1}
+	shading.Set("Function", function.ToPdfObject())
+	extend := pdfcore.PdfObjectBool(true)
+	shading.Set("Extend", pdfcore.MakeArray(&extend, &extend))
+
+	// The mask is a form XObject with a transparency group, painting the gradient.
+	group := pdfcore.MakeDict()
+	group.Set("Type", pdfcore.MakeName("Group"))
+	group.Set("S", pdfcore.MakeName("Transparency"))
+	group.Set("CS", pdfcore.MakeName("DeviceGray"))
+	mask := pdf.NewXObjectForm()
+	mask.Resources = pdf.NewPdfPageResources()
+	mask.BBox = pdfcore.MakeArrayFromFloats([]float64{s.x, s.y, s.x + s.width, s.y + s.height})
+	mask.Group = group
+	err := mask.Resources.SetShadingByName("Sh0", shading)
+	if err != nil {
+		s.err = err
+		return
+	}
+	err = mask.SetContentStream([]byte("/Sh0 sh\n"), nil)
+	if err != nil {
+		s.err = err
+		return
+	}
+
+	smask := pdfcore.MakeDict()
+	smask.Set("Type", pdfcore.MakeName("Mask"))
+	smask.Set("S", pdfcore.MakeName("Luminosity"))
+	smask.Set("G", mask.ToPdfObject())
+	gs := newExtGState(1, "Normal")
+	gs.Set("SMask", smask)
+	name := s.addExtGState(gs)
+
+	s.cc.Add_q().
+		Add_gs(name)
+	for i := 0; i < 4; i++ {
+		w := s.width / 4
+		addCircles(s.cc, s.x+float64(i)*w, s.y, w, s.height)
+	}
+	s.cc.Add_Q()
+}
+
+// Adds the graphics state to the resources and returns its name.
+func (s *sample) addExtGState(gs *pdfcore.PdfObjectDictionary) pdfcore.PdfObjectName {
+	s.names++
+	name := pdfcore.PdfObjectName(fmt.Sprintf("GS%d", s.names))
+	err := s.resources.AddExtGState(name, gs)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+	return name
+}
+
+// Adds the form XObject to the resources and returns its name.
+func (s *sample) addForm(form *pdf.XObjectForm) pdfcore.PdfObjectName {
+	s.names++
+	name := pdfcore.PdfObjectName(fmt.Sprintf("Fm%d", s.names))
+	err := s.resources.SetXObjectFormByName(name, form)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+	return name
+}
+
+// GeneratePageBlocks draws the sample on a block representing the page.  The content is enclosed in q/Q, so that
+// the graphics state set in it does not apply to the content drawn after it.
+func (s *sample) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = s.resources
+	page.AddContentStreamByString("q\n" + s.cc.String() + "Q\n")
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+// Returns a graphics state parameter dictionary setting the stroke and fill opacity and the blend mode.
+func newExtGState(alpha float64, blendMode string) *pdfcore.PdfObjectDictionary {
+	gs := pdfcore.MakeDict()
+	gs.Set("Type", pdfcore.MakeName("ExtGState"))
+	gs.Set("CA", pdfcore.MakeFloat(alpha))
+	gs.Set("ca", pdfcore.MakeFloat(alpha))
+	gs.Set("BM", pdfcore.MakeName(blendMode))
+	return gs
+}
+
+// Adds three overlapping circles centered in the area with the lower left corner (x, y), each filled separately.
+func addCircles(cc *pdfcontent.ContentCreator, x, y, width, height float64) {
+	r := 0.3 * minFloat(width, height)
+	cx, cy := x+width/2, y+height/2
+	centers := [][2]float64{{cx - 0.5*r, cy + 0.3*r}, {cx + 0.5*r, cy + 0.3*r}, {cx, cy - 0.55*r}}
+	for i, center := range centers {
+		color := circleColors[i]
+		cc.Add_rg(color[0], color[1], color[2])
+		addCircle(cc, center[0], center[1], r)
+		cc.Add_f()
+	}
+}
+
+// Adds a circle path, approximated by four Bezier curves.
+func addCircle(cc *pdfcontent.ContentCreator, cx, cy, r float64) {
+	k := 0.5523 * r // Distance of the control points from the ends of the quarter circles.
+	cc.Add_m(cx+r, cy).
+		Add_c(cx+r, cy+k, cx+k, cy+r, cx, cy+r).
+		Add_c(cx-k, cy+r, cx-r, cy+k, cx-r, cy).
+		Add_c(cx-r, cy-k, cx-k, cy-r, cx, cy-r).
+		Add_c(cx+k, cy-r, cx+r, cy-k, cx+r, cy).
+		Add_h()
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
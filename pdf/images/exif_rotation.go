@@ -0,0 +1,433 @@
+/*
+ * Place JPEG images upright in a PDF according to their EXIF orientation.
+ *
+ * Cameras and phones often store a photo as the sensor read it and record how to turn it upright in the Orientation
+ * tag of the EXIF metadata, which viewers apply when displaying the photo.  The creator ignores this metadata (it
+ * decodes the image with image.Decode, which does not read it), so such photos come out sideways or mirrored.  Here
+ * the Orientation tag is read from the EXIF segment (APP1) of the JPEG, and the corresponding transform is applied
+ * to the decoded image before placing it:
+ *
+ *   1: none (upright)                  5: flip horizontally and rotate 90 degrees counterclockwise (transpose)
+ *   2: flip horizontally               6: rotate 90 degrees clockwise
+ *   3: rotate 180 degrees              7: flip horizontally and rotate 90 degrees clockwise (transverse)
+ *   4: flip vertically                 8: rotate 90 degrees counterclockwise
+ *
+ * The transform is applied to the pixels, as the creator can rotate an image (Image.SetAngle, about its top left
+ * corner) but not flip it.  This costs no quality: the creator decodes and re-encodes JPEG images in any case.
+ * Images without EXIF data or Orientation tag, and images in other formats, are placed as they are.  So are images
+ * with invalid EXIF data, with a warning.
+ *
+ * With -demo, the orientation cases are shown side by side: an upright image (-image) is stored as a JPEG with each
+ * orientation, i.e. transformed the other way and tagged with the orientation, and each JPEG is shown as stored and
+ * as placed by this program, which is upright in every case.
+ *
+ * Run as: go run exif_rotation.go output.pdf photo1.jpg photo2.jpg ...
+ *     or: go run exif_rotation.go -demo [-image image.png] output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	goimage "image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run exif_rotation.go output.pdf photo1.jpg photo2.jpg ...\n" +
+	"   or: go run exif_rotation.go -demo [-image image.png] output.pdf\n"
+
+// The EXIF tag with the orientation.
+const orientationTag = 0x0112
+
+// orientationNames describes the transform that makes an image with each orientation upright.
+var orientationNames = map[int]string{
+	1: "Upright",
+	2: "Flip horizontally",
+	3: "Rotate 180 degrees",
+	4: "Flip vertically",
+	5: "Flip horizontally, rotate 90 degrees counterclockwise",
+	6: "Rotate 90 degrees clockwise",
+	7: "Flip horizontally, rotate 90 degrees clockwise",
+	8: "Rotate 90 degrees counterclockwise",
+}
+
+func main() {
+	demo := false
+	demoImagePath := ""
+	flag.BoolVar(&demo, "demo", false, "Show all the orientation cases with JPEGs made from -image")
+	flag.StringVar(&demoImagePath, "image", "../report/unidoc-logo.png", "Upright image for -demo (PNG or JPEG)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 || (!demo && flag.NArg() < 2) {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	var err error
+	if demo {
+		err = createDemo(demoImagePath, outputPath)
+	} else {
+		err = imagesToPdf(flag.Args()[1:], outputPath)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// imagesToPdf places the images upright, one per page, scaled to fit the page.
+func imagesToPdf(inputPaths []string, outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+
+	for _, path := range inputPaths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		img, orientation, err := newUprightImage(path, data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		fmt.Printf("%s: orientation %d (%s)\n", path, orientation, orientationNames[orientation])
+
+		c.NewPage()
+		caption := creator.NewParagraph(fmt.Sprintf("%s - EXIF orientation %d: %s", path, orientation,
+			orientationNames[orientation]))
+		caption.SetFont(fonts.NewFontHelvetica())
+		caption.SetFontSize(9)
+		caption.SetMargins(0, 0, 0, 10)
+		err = c.Draw(caption)
+		if err != nil {
+			return err
+		}
+
+		fitImage(img, c.Width()-100, c.Height()-100-caption.Height()-10)
+		err = c.Draw(img)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// createDemo shows each orientation with a JPEG made from the upright image: the JPEG as stored, and as placed.
+func createDemo(uprightPath, outputPath string) error {
+	f, err := os.Open(uprightPath)
+	if err != nil {
+		return err
+	}
+	upright, _, err := goimage.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %v", uprightPath, err)
+	}
+
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	heading := creator.NewParagraph("EXIF orientation")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 6)
+	err = c.Draw(heading)
+	if err != nil {
+		return err
+	}
+	intro := creator.NewParagraph("Each JPEG stores the image transformed and tagged with an orientation telling how " +
+		"to turn it upright. Left: the JPEG placed as stored, ignoring the tag. Right: placed with the transform of " +
+		"the tag applied.")
+	intro.SetFont(fonts.NewFontHelvetica())
+	intro.SetFontSize(9)
+	intro.SetMargins(0, 0, 0, 10)
+	err = c.Draw(intro)
+	if err != nil {
+		return err
+	}
+
+	// A panel per orientation, in two columns: title, then the image as stored and as placed side by side.
+	const panelHeight = 150
+	const boxSize = 105
+	top := 50 + heading.Height() + 6 + intro.Height() + 10
+	panelWidth := (c.Width() - 100) / 2
+	for orientation := 1; orientation <= 8; orientation++ {
+		data, err := encodeJpegWithOrientation(transform(upright, inverseOrientation(orientation)), orientation)
+		if err != nil {
+			return err
+		}
+
+		x := 50 + float64((orientation-1)%2)*panelWidth
+		y := top + float64((orientation-1)/2)*panelHeight
+
+		title := creator.NewParagraph(fmt.Sprintf("%d: %s", orientation, orientationNames[orientation]))
+		title.SetFont(fonts.NewFontHelveticaBold())
+		title.SetFontSize(9)
+		title.SetWidth(panelWidth - 10)
+		title.SetPos(x, y)
+		err = c.Draw(title)
+		if err != nil {
+			return err
+		}
+
+		// As stored: the creator ignores the orientation.
+		stored, err := creator.NewImageFromData(data)
+		if err != nil {
+			return err
+		}
+		placed, got, err := newUprightImage(fmt.Sprintf("Orientation %d", orientation), data)
+		if err != nil {
+			return err
+		}
+		if got != orientation {
+			return fmt.Errorf("Orientation %d read as %d", orientation, got)
+		}
+
+		for i, img := range []*creator.Image{stored, placed} {
+			boxX := x + float64(i)*(boxSize+15)
+			boxY := y + 24
+			frame := creator.NewRectangle(boxX, boxY, boxSize, boxSize)
+			frame.SetBorderColor(creator.ColorRGBFrom8bit(200, 200, 200))
+			frame.SetBorderWidth(0.5)
+			err = c.Draw(frame)
+			if err != nil {
+				return err
+			}
+
+			fitImage(img, boxSize-10, boxSize-10)
+			img.SetPos(boxX+(boxSize-img.Width())/2, boxY+(boxSize-img.Height())/2)
+			err = c.Draw(img)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// newUprightImage creates an Image from image data, upright according to its EXIF orientation if it is a JPEG with
+// one.  Returns the image and its orientation, 1 if none.  The name is used in warnings.
+func newUprightImage(name string, data []byte) (*creator.Image, int, error) {
+	goimg, _, err := goimage.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	orientation, err := readOrientation(data)
+	if err != nil {
+		fmt.Printf("Warning: %s: Invalid EXIF data, placing the image as is: %v\n", name, err)
+		orientation = 1
+	}
+
+	img, err := creator.NewImageFromGoImage(transform(goimg, orientation))
+	if err != nil {
+		return nil, 0, err
+	}
+	return img, orientation, nil
+}
+
+// readOrientation returns the EXIF orientation of a JPEG image, 1 if there is none or it is not a JPEG.
+func readOrientation(data []byte) (int, error) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1, nil
+	}
+
+	// The segments before the image data: marker (0xff, type), length (including itself), data.  The EXIF data is
+	// in an APP1 segment starting with "Exif\0\0".
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return 1, errors.New("Invalid JPEG segment")
+		}
+		marker := data[pos+1]
+		if marker == 0xda || marker == 0xd9 {
+			// Start of the image data, or end of image: no EXIF.
+			return 1, nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1, errors.New("Invalid JPEG segment length")
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1, nil
+}
+
+// exifOrientation returns the orientation in EXIF data, 1 if there is none.  The data is in the TIFF format: a
+// header with the byte order and the offset of the first IFD (image file directory), which has the Orientation tag.
+func exifOrientation(tiff []byte) (int, error) {
+	if len(tiff) < 8 {
+		return 1, errors.New("EXIF data too short")
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1, errors.New("Invalid EXIF byte order")
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 1, errors.New("Invalid EXIF header")
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1, errors.New("Invalid EXIF IFD offset")
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	if ifd+2+count*12 > len(tiff) {
+		return 1, errors.New("EXIF IFD truncated")
+	}
+
+	// The entries: tag, type, count, value (or its offset if longer than 4 bytes).  Orientation is a SHORT (type 3),
+	// stored in the first 2 bytes of the value.
+	for i := 0; i < count; i++ {
+		entry := tiff[ifd+2+i*12:]
+		if order.Uint16(entry) != orientationTag {
+			continue
+		}
+		if order.Uint16(entry[2:]) != 3 {
+			return 1, errors.New("Invalid EXIF orientation type")
+		}
+		orientation := int(order.Uint16(entry[8:]))
+		if orientation < 1 || orientation > 8 {
+			return 1, fmt.Errorf("Invalid EXIF orientation %d", orientation)
+		}
+		return orientation, nil
+	}
+	return 1, nil
+}
+
+// transform returns the image transformed as needed to make an image with the orientation upright.
+func transform(img goimage.Image, orientation int) goimage.Image {
+	if orientation == 1 {
+		return img
+	}
+
+	b := img.Bounds()
+	src := goimage.NewRGBA(goimage.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	w, h := b.Dx(), b.Dy()
+
+	// The orientations from 5 on swap width and height.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := goimage.NewRGBA(goimage.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			default:
+				dx, dy = x, y
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):])
+		}
+	}
+	return dst
+}
+
+// inverseOrientation returns the orientation whose transform undoes that of the orientation.  All are their own
+// inverse except the rotations by 90 degrees.
+func inverseOrientation(orientation int) int {
+	switch orientation {
+	case 6:
+		return 8
+	case 8:
+		return 6
+	}
+	return orientation
+}
+
+// encodeJpegWithOrientation encodes the image as a JPEG with an EXIF segment with the orientation.
+func encodeJpegWithOrientation(img goimage.Image, orientation int) ([]byte, error) {
+	// JPEG has no transparency: flatten the image on white.
+	flat := goimage.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), goimage.NewUniform(color.White), goimage.ZP, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: 90})
+	if err != nil {
+		return nil, err
+	}
+	encoded := buf.Bytes()
+
+	// The APP1 segment: "Exif\0\0", a big-endian TIFF header, and an IFD with the Orientation entry only.
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08")
+	exif = append(exif, 0, 1) // Number of entries.
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry[0:], orientationTag)
+	binary.BigEndian.PutUint16(entry[2:], 3) // SHORT.
+	binary.BigEndian.PutUint32(entry[4:], 1) // Count.
+	binary.BigEndian.PutUint16(entry[8:], uint16(orientation))
+	exif = append(exif, entry...)
+	exif = append(exif, 0, 0, 0, 0) // No next IFD.
+
+	segment := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+len(exif)))
+	segment = append(segment, exif...)
+
+	// Insert it right after the start of image marker.
+	data := append([]byte{}, encoded[:2]...)
+	data = append(data, segment...)
+	return append(data, encoded[2:]...), nil
+}
+
+// fitImage scales the image down to fit in width x height, keeping its aspect ratio.
+func fitImage(img *creator.Image, width, height float64) {
+	if img.Width() > width {
+		img.ScaleToWidth(width)
+	}
+	if img.Height() > height {
+		img.ScaleToHeight(height)
+	}
+}
@@ -0,0 +1,211 @@
+/*
+ * Create a PDF with an image read from an io.Reader: the body of an HTTP response, a file or standard input, without
+ * reading the image into a byte slice first.
+ *
+ * The creator loads images from a file (NewImageFromFile), bytes (NewImageFromData) or a decoded Go image
+ * (NewImageFromGoImage); this version has no creator.NewImageFromReader, and the creator is part of the library, not
+ * of these examples.  So newImageFromReader provides it for the example, with the signature it would have in the
+ * creator: it decodes the image with image.Decode straight from the reader, which reads the data as it decodes it
+ * (the reader is not buffered whole), and makes the creator image from the decoded image.  PNG, JPEG and GIF images
+ * are supported.
+ *
+ * The errors say what failed: the request (with the HTTP status), reading the data (e.g. a connection dropped
+ * midway, or an image larger than maxImageSize), or an unknown format, which is detected from the first bytes of the
+ * data and reported with the media type announced by the server if any.
+ *
+ * Run as: go run image_from_reader.go http://example.com/image.png|image.png|- output.pdf
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	goimage "image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// Images larger than this are rejected, to limit what a server can make the program read.
+const maxImageSize = 50 << 20
+
+// errTooLarge is returned when reading more than maxImageSize bytes.
+var errTooLarge = fmt.Errorf("Image larger than %d bytes", maxImageSize)
+
+// limitedReader reads up to limit bytes from r, and fails with errTooLarge after that.
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Printf("Usage: go run image_from_reader.go http://example.com/image.png|image.png|- output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	source := os.Args[1]
+	outputPath := os.Args[2]
+
+	err := imageToPdf(source, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func imageToPdf(source, outputPath string) error {
+	img, err := loadImage(source)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Image: %.0f x %.0f pixels\n", img.Width(), img.Height())
+
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	caption := creator.NewParagraph("Source: " + source)
+	caption.SetFont(fonts.NewFontHelvetica())
+	caption.SetFontSize(9)
+	caption.SetMargins(0, 0, 0, 10)
+	err = c.Draw(caption)
+	if err != nil {
+		return err
+	}
+
+	// Fit the image in the page, without enlarging it.
+	maxWidth := c.Width() - 100
+	maxHeight := c.Height() - 100 - caption.Height() - 10
+	if img.Width() > maxWidth {
+		img.ScaleToWidth(maxWidth)
+	}
+	if img.Height() > maxHeight {
+		img.ScaleToHeight(maxHeight)
+	}
+	err = c.Draw(img)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// loadImage loads the image from a URL, a file, or standard input for "-".
+func loadImage(source string) (*creator.Image, error) {
+	if source == "-" {
+		img, err := newImageFromReader(os.Stdin)
+		if err != nil {
+			return nil, sourceError("standard input", "", err)
+		}
+		return img, nil
+	}
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		img, err := newImageFromReader(f)
+		if err != nil {
+			return nil, sourceError(source, "", err)
+		}
+		return img, nil
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", source, resp.Status)
+	}
+
+	img, err := newImageFromReader(resp.Body)
+	if err != nil {
+		// The Content-Type tells what the server sent instead of an image, e.g. an HTML error page.
+		return nil, sourceError(source, resp.Header.Get("Content-Type"), err)
+	}
+	return img, nil
+}
+
+// sourceError returns err prefixed with the name of the source, and for an unknown format, with the media type of
+// the data if known.
+func sourceError(name, mediaType string, err error) error {
+	if err != goimage.ErrFormat {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if mediaType != "" {
+		return fmt.Errorf("%s: Unknown image format (Content-Type %q), supported are PNG, JPEG and GIF", name,
+			mediaType)
+	}
+	return fmt.Errorf("%s: Unknown image format, supported are PNG, JPEG and GIF", name)
+}
+
+// newImageFromReader creates an Image from image data read from r.  Returns image.ErrFormat if the data is not in a
+// supported format.
+func newImageFromReader(r io.Reader) (*creator.Image, error) {
+	br := bufio.NewReader(&limitedReader{r: r, limit: maxImageSize})
+
+	// Check the format first, so that a reader error is not reported as an unknown format.
+	header, err := br.Peek(8)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("Reading image: %v", err)
+	}
+	if len(header) == 0 {
+		return nil, errors.New("No image data")
+	}
+	if !isKnownFormat(header) {
+		return nil, goimage.ErrFormat
+	}
+
+	goimg, _, err := goimage.Decode(br)
+	if err != nil {
+		if err == io.ErrUnexpectedEOF || err == errTooLarge {
+			return nil, fmt.Errorf("Reading image: %v", err)
+		}
+		return nil, fmt.Errorf("Decoding image: %v", err)
+	}
+	return creator.NewImageFromGoImage(goimg)
+}
+
+// isKnownFormat returns true if the data starts with the signature of a supported image format.
+func isKnownFormat(header []byte) bool {
+	for _, magic := range []string{"\x89PNG\r\n\x1a\n", "\xff\xd8", "GIF87a", "GIF89a"} {
+		if strings.HasPrefix(string(header), magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Read reads from the underlying reader, up to the limit.
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.limit <= 0 {
+		return 0, errTooLarge
+	}
+	if int64(len(p)) > lr.limit {
+		p = p[:lr.limit]
+	}
+	n, err := lr.r.Read(p)
+	lr.limit -= int64(n)
+	return n, err
+}
@@ -0,0 +1,507 @@
+/*
+ * Print a large image or PDF page as a poster: enlarge it and tile it across several sheets of paper, to be trimmed
+ * and taped together.
+ *
+ * The poster is the source scaled by -scale: a PDF page at its displayed size (upright if rotated), an image at one
+ * point per pixel, as the creator places images.  Each sheet prints the part of the poster that fits within its
+ * margins (which the printer needs, and which hold the marks), with the source drawn once in the file, as a Form
+ * XObject or image, and clipped to the part on each sheet.  A PDF source remains vector.  The grid of sheets is the
+ * smallest that covers the poster; with -orientation auto, the sheet orientation needing fewer sheets is used.
+ *
+ * Adjacent tiles overlap by -overlap: each tile repeats the last strip of the tile to its left and the tile above
+ * it, so the tiles can be taped with their edges overlapping and no gap shows.  To assemble, trim each tile along the
+ * crop marks at its left and top edges, and lay it over its neighbor with the trimmed edge on the gray overlap marks
+ * of that neighbor: the content then lines up, as the strip under the edge is the same on both tiles.  The crop marks
+ * at the right and bottom edges of the last tiles mark the edge of the poster.
+ *
+ * Run as: go run poster.go [-scale 4] [-paper a4|a3|letter] [-orientation auto|portrait|landscape] [-overlap 10]
+ *                          [-margin 10] [-page 1] input.pdf|image.jpg output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run poster.go [-scale 4] [-paper a4|a3|letter] [-orientation auto|portrait|landscape] " +
+	"[-overlap 10] [-margin 10] [-page 1] input.pdf|image.jpg output.pdf\n"
+
+const (
+	mm        = 72 / 25.4 // Points per millimeter.
+	markGap   = 3.0       // Space between the crop marks and the corners they mark.
+	maxSheets = 500       // Upper limit of the number of sheets, against a scale given by mistake.
+)
+
+// Portrait sheet sizes in points.
+var paperSizes = map[string][2]float64{
+	"a4":     {595.276, 841.89},
+	"a3":     {841.89, 1190.55},
+	"letter": {612, 792},
+}
+
+// posterSource is the page or image the poster is made from, and its size at scale 1.
+type posterSource struct {
+	xform  *pdf.XObjectForm
+	mbox   *pdf.PdfRectangle
+	rotate int64
+	ximg   *pdf.XObjectImage
+	width  float64
+	height float64
+}
+
+// posterLayout is the grid of sheets of a poster, in points.
+type posterLayout struct {
+	sheetSize     [2]float64
+	margin        float64
+	overlap       float64
+	posterWidth   float64
+	posterHeight  float64
+	cols, rows    int
+	width, height float64 // Area of the poster on each sheet, within the margins.
+}
+
+func main() {
+	scale := 0.0
+	paper := ""
+	orientation := ""
+	overlap := 0.0
+	margin := 0.0
+	pageNum := 0
+	flag.Float64Var(&scale, "scale", 4, "Poster size relative to the source")
+	flag.StringVar(&paper, "paper", "a4", "Sheet size: a4, a3 or letter")
+	flag.StringVar(&orientation, "orientation", "auto", "Sheet orientation: auto (the one needing fewer sheets), "+
+		"portrait or landscape")
+	flag.Float64Var(&overlap, "overlap", 10, "Overlap of adjacent tiles in mm")
+	flag.Float64Var(&margin, "margin", 10, "Margin around the tile on each sheet in mm, with the marks")
+	flag.IntVar(&pageNum, "page", 1, "Page of a PDF input")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if scale <= 0 {
+		fmt.Printf("Error: -scale must be positive\n")
+		os.Exit(1)
+	}
+	if _, ok := paperSizes[paper]; !ok {
+		fmt.Printf("Error: unknown paper size %q\n", paper)
+		os.Exit(1)
+	}
+	if orientation != "auto" && orientation != "portrait" && orientation != "landscape" {
+		fmt.Printf("Error: -orientation must be auto, portrait or landscape\n")
+		os.Exit(1)
+	}
+	if overlap < 0 || margin < 5 {
+		fmt.Printf("Error: -overlap must not be negative, and -margin must be at least 5 mm for the marks\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := createPoster(inputPath, outputPath, pageNum, scale, paperSizes[paper], orientation, overlap*mm, margin*mm)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createPoster(inputPath, outputPath string, pageNum int, scale float64, paperSize [2]float64, orientation string,
+	overlap, margin float64) error {
+	var src *posterSource
+	var err error
+	if strings.ToLower(filepath.Ext(inputPath)) == ".pdf" {
+		src, err = loadPdfPage(inputPath, pageNum)
+	} else {
+		src, err = loadImage(inputPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	layout, err := computeLayout(src.width*scale, src.height*scale, paperSize, orientation, overlap, margin)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Poster %.0f x %.0f mm, %s sheets: %d columns x %d rows\n", layout.posterWidth/mm,
+		layout.posterHeight/mm, sheetOrientation(layout), layout.cols, layout.rows)
+	if src.ximg != nil {
+		fmt.Printf("Image resolution on the poster: %.0f dpi\n", 72/scale)
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for row := 0; row < layout.rows; row++ {
+		for col := 0; col < layout.cols; col++ {
+			sheet, err := makeTile(src, layout, col, row)
+			if err != nil {
+				return err
+			}
+			err = pdfWriter.AddPage(sheet)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// computeLayout returns the grid of sheets covering a poster of the given size.  Each tile after the first of a row
+// or column starts overlap before the end of the previous one.
+func computeLayout(posterWidth, posterHeight float64, paperSize [2]float64, orientation string,
+	overlap, margin float64) (*posterLayout, error) {
+	layouts := []*posterLayout{}
+	for _, landscape := range []bool{false, true} {
+		if (orientation == "portrait" && landscape) || (orientation == "landscape" && !landscape) {
+			continue
+		}
+		sheetSize := paperSize
+		if landscape {
+			sheetSize[0], sheetSize[1] = sheetSize[1], sheetSize[0]
+		}
+		l := &posterLayout{
+			sheetSize:    sheetSize,
+			margin:       margin,
+			overlap:      overlap,
+			posterWidth:  posterWidth,
+			posterHeight: posterHeight,
+			width:        sheetSize[0] - 2*margin,
+			height:       sheetSize[1] - 2*margin,
+		}
+		if l.width-overlap < 1 || l.height-overlap < 1 {
+			return nil, errors.New("The overlap and margins leave no room on the sheet")
+		}
+		l.cols = tileCount(posterWidth, l.width, overlap)
+		l.rows = tileCount(posterHeight, l.height, overlap)
+		layouts = append(layouts, l)
+	}
+
+	best := layouts[0]
+	for _, l := range layouts[1:] {
+		if l.cols*l.rows < best.cols*best.rows {
+			best = l
+		}
+	}
+	if best.cols*best.rows > maxSheets {
+		return nil, fmt.Errorf("The poster needs %d sheets (more than %d), use a smaller scale",
+			best.cols*best.rows, maxSheets)
+	}
+	return best, nil
+}
+
+// tileCount returns the number of tiles of the given size, each overlapping the previous one, covering length.
+func tileCount(length, size, overlap float64) int {
+	if length <= size {
+		return 1
+	}
+	// A small tolerance, so that rounding errors do not add a tile for a fraction of a point.
+	return int(math.Ceil((length-overlap)/(size-overlap) - 1e-6))
+}
+
+// sheetOrientation returns the orientation of the sheets of the layout.
+func sheetOrientation(l *posterLayout) string {
+	if l.sheetSize[0] > l.sheetSize[1] {
+		return "landscape"
+	}
+	return "portrait"
+}
+
+// makeTile returns the sheet with the tile of the poster in the column and row, with its marks and label.
+func makeTile(src *posterSource, l *posterLayout, col, row int) (*pdf.PdfPage, error) {
+	sheet := pdf.NewPdfPage()
+	sheet.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: l.sheetSize[0], Ury: l.sheetSize[1]}
+	sheet.Resources = pdf.NewPdfPageResources()
+
+	var err error
+	if src.xform != nil {
+		err = sheet.Resources.SetXObjectFormByName("Poster", src.xform)
+	} else {
+		err = sheet.Resources.SetXObjectImageByName("Poster", src.ximg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = sheet.Resources.SetFontByName("Helv", fonts.NewFontHelvetica().ToPdfObject())
+	if err != nil {
+		return nil, err
+	}
+
+	// The part of the poster on this tile, from its upper left corner, and the area it takes on the sheet, which is
+	// smaller than the whole area for the last tiles.
+	px := float64(col) * (l.width - l.overlap)
+	py := float64(row) * (l.height - l.overlap)
+	w := math.Min(l.width, l.posterWidth-px)
+	h := math.Min(l.height, l.posterHeight-py)
+	left := l.margin
+	top := l.sheetSize[1] - l.margin
+
+	cc := pdfcontent.NewContentCreator()
+
+	// Clip to the tile and move the poster so that the part of the tile is in it.  The poster is drawn with its lower
+	// left corner at the origin.
+	cc.Add_q()
+	cc.Add_re(left, top-h, w, h)
+	cc.Add_W()
+	cc.Add_n()
+	cc.Add_cm(1, 0, 0, 1, left-px, top+py-l.posterHeight)
+	drawSource(cc, src, l.posterWidth, l.posterHeight)
+	cc.Add_Q()
+
+	drawCropMarks(cc, left, top-h, left+w, top, l.margin)
+
+	// Where the tiles to the right and below are laid: their edges start overlap before the end of this tile.
+	if col < l.cols-1 {
+		drawOverlapMarks(cc, left+l.width-l.overlap, top-h, top, l.margin, false)
+	}
+	if row < l.rows-1 {
+		drawOverlapMarks(cc, top-l.height+l.overlap, left, left+w, l.margin, true)
+	}
+
+	label := fmt.Sprintf("Row %d of %d, column %d of %d.  Trim the left and top edges at the crop marks and lay "+
+		"them on the gray marks of the neighbors.", row+1, l.rows, col+1, l.cols)
+	cc.Add_BT()
+	cc.Add_g(0.3)
+	cc.Add_Tf("Helv", 6)
+	cc.Add_Td(left+6, l.sheetSize[1]-l.margin/2-2)
+	cc.Add_Tj(pdfcore.PdfObjectString(label))
+	cc.Add_ET()
+
+	err = sheet.SetContentStreams([]string{cc.String()}, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+// drawSource draws the source scaled to fill the area from the origin to (width, height).
+func drawSource(cc *pdfcontent.ContentCreator, src *posterSource, width, height float64) {
+	if src.ximg != nil {
+		cc.Add_q()
+		cc.Add_cm(width, 0, 0, height, 0, 0)
+		cc.Add_Do("Poster")
+		cc.Add_Q()
+		return
+	}
+	drawForm(cc, "Poster", src.mbox, src.rotate, 0, 0, width, height)
+}
+
+// drawCropMarks draws crop marks at the corners of the rectangle from (llx, lly) to (urx, ury), in the margin
+// around it: lines continuing its edges, which do not reach into it.
+func drawCropMarks(cc *pdfcontent.ContentCreator, llx, lly, urx, ury, margin float64) {
+	length := margin - markGap - 2
+
+	cc.Add_q()
+	cc.Add_G(0)
+	cc.Add_w(0.25)
+	for _, x := range []float64{llx, urx} {
+		for _, y := range []float64{lly, ury} {
+			// Outward directions from the corner.
+			dx, dy := -1.0, -1.0
+			if x == urx {
+				dx = 1
+			}
+			if y == ury {
+				dy = 1
+			}
+			cc.Add_m(x+dx*markGap, y)
+			cc.Add_l(x+dx*(markGap+length), y)
+			cc.Add_m(x, y+dy*markGap)
+			cc.Add_l(x, y+dy*(markGap+length))
+		}
+	}
+	cc.Add_S()
+	cc.Add_Q()
+}
+
+// drawOverlapMarks draws gray marks in the margins at the position where the edge of the next tile is laid: a
+// vertical line at x = pos in the top and bottom margins (from and to are the bottom and top of the tile), or with
+// horizontal, a horizontal line at y = pos in the left and right margins (from and to are the left and right).
+func drawOverlapMarks(cc *pdfcontent.ContentCreator, pos, from, to, margin float64, horizontal bool) {
+	length := margin - markGap - 2
+
+	cc.Add_q()
+	cc.Add_G(0.5)
+	cc.Add_w(0.75)
+	cc.Add_d([]int64{2, 1}, 0)
+	for _, edge := range [][2]float64{{from - markGap, -1}, {to + markGap, 1}} {
+		if horizontal {
+			cc.Add_m(edge[0], pos)
+			cc.Add_l(edge[0]+edge[1]*length, pos)
+		} else {
+			cc.Add_m(pos, edge[0])
+			cc.Add_l(pos, edge[0]+edge[1]*length)
+		}
+	}
+	cc.Add_S()
+	cc.Add_Q()
+}
+
+// loadPdfPage loads a page of a PDF file as the source.
+func loadPdfPage(inputPath string, pageNum int) (*posterSource, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	if pageNum < 1 || pageNum > numPages {
+		return nil, fmt.Errorf("Page %d out of range (1-%d)", pageNum, numPages)
+	}
+	page, err := pdfReader.GetPage(pageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	xform, mbox, err := pageToXObjectForm(page)
+	if err != nil {
+		return nil, err
+	}
+	src := &posterSource{
+		xform:  xform,
+		mbox:   mbox,
+		rotate: pageRotation(page),
+		width:  mbox.Urx - mbox.Llx,
+		height: mbox.Ury - mbox.Lly,
+	}
+	if src.rotate == 90 || src.rotate == 270 {
+		src.width, src.height = src.height, src.width
+	}
+	return src, nil
+}
+
+// loadImage loads an image file as the source, at one point per pixel.
+func loadImage(inputPath string) (*posterSource, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := pdf.ImageHandling.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", inputPath, err)
+	}
+	ximg, err := pdf.NewXObjectImageFromImage(img, nil, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	return &posterSource{ximg: ximg, width: float64(img.Width), height: float64(img.Height)}, nil
+}
+
+// Converts a page to a Form XObject with the page contents and resources.  The BBox of the form is the page MediaBox,
+// which is also returned.
+func pageToXObjectForm(page *pdf.PdfPage) (*pdf.XObjectForm, *pdf.PdfRectangle, error) {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	xform := pdf.NewXObjectForm()
+	xform.Resources = page.Resources
+	xform.BBox = mbox.ToPdfObject()
+	xform.Filter = pdfcore.NewFlateEncoder()
+	err = xform.SetContentStream([]byte(contents), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return xform, mbox, nil
+}
+
+// pageRotation returns the page rotation normalized to 0, 90, 180 or 270 degrees clockwise.
+func pageRotation(page *pdf.PdfPage) int64 {
+	if page.Rotate == nil {
+		return 0
+	}
+	return (*page.Rotate%360 + 360) % 360
+}
+
+// drawForm draws the form of a page with the MediaBox mbox and rotation, upright and scaled uniformly to fit the
+// area with the lower left corner (x, y), centered in it.
+func drawForm(cc *pdfcontent.ContentCreator, name pdfcore.PdfObjectName, mbox *pdf.PdfRectangle, rotate int64,
+	x, y, width, height float64) {
+	w, h := mbox.Urx-mbox.Llx, mbox.Ury-mbox.Lly
+
+	// Size of the page as displayed, after the rotation.
+	displayWidth, displayHeight := w, h
+	if rotate == 90 || rotate == 270 {
+		displayWidth, displayHeight = h, w
+	}
+	scale := math.Min(width/displayWidth, height/displayHeight)
+	tx := x + (width-scale*displayWidth)/2
+	ty := y + (height-scale*displayHeight)/2
+
+	cc.Add_q()
+	// The operators are applied to the form in reverse order: move the MediaBox to the origin, rotate it clockwise
+	// into the quadrant with positive coordinates, then scale and move it into place.
+	cc.Add_cm(scale, 0, 0, scale, tx, ty)
+	switch rotate {
+	case 90:
+		cc.Add_cm(0, -1, 1, 0, 0, w)
+	case 180:
+		cc.Add_cm(-1, 0, 0, -1, w, h)
+	case 270:
+		cc.Add_cm(0, 1, -1, 0, h, 0)
+	}
+	cc.Add_cm(1, 0, 0, 1, -mbox.Llx, -mbox.Lly)
+	cc.Add_Do(name)
+	cc.Add_Q()
+}
@@ -0,0 +1,291 @@
+/*
+ * Impose a PDF as a booklet for saddle stitching: the pages are placed two per side on landscape sheets, in the
+ * order that gives the original page order when the printed sheets are stacked, folded in the middle and stapled
+ * along the fold.
+ *
+ * A booklet needs a page count that is a multiple of four (each sheet holds four pages, two on each side), so blank
+ * pages are added at the end as needed.  For n pages, the outer sheet holds pages n and 1 on the front and 2 and
+ * n-1 on the back, the next sheet n-2 and 3, 4 and n-3, and so on to the middle sheet.  The page sequence is printed
+ * when imposing.
+ *
+ * The output alternates front and back sides, for printing two-sided.  With -flip short (the default) the sheets
+ * are expected to be turned over along the short edge, as is usual for landscape sheets, and all sides are upright.
+ * With -flip long, the back sides are rotated by 180 degrees to compensate for turning along the long edge.
+ *
+ * Input pages of different sizes are normalized: each page is scaled uniformly to fit its half of the sheet and
+ * centered in it, and rotated pages are shown upright.  The sheet size is twice the largest page by default, so
+ * pages of that size are not scaled, or can be a standard paper size with -sheet.
+ *
+ * Run as: go run booklet.go [-sheet auto|a4|a3|letter|tabloid] [-flip short|long] [-marks] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run booklet.go [-sheet auto|a4|a3|letter|tabloid] [-flip short|long] [-marks] " +
+	"input.pdf output.pdf\n"
+
+// Standard sheet sizes in landscape orientation.
+var sheetSizes = map[string]creator.PageSize{
+	"a4":      {creator.PageSizeA4[1], creator.PageSizeA4[0]},
+	"a3":      {creator.PageSizeA3[1], creator.PageSizeA3[0]},
+	"letter":  {creator.PageSizeLetter[1], creator.PageSizeLetter[0]},
+	"tabloid": {17 * 72, 11 * 72},
+}
+
+func main() {
+	sheet := ""
+	flip := ""
+	marks := false
+	flag.StringVar(&sheet, "sheet", "auto", "Sheet size: auto (twice the largest page), a4, a3, letter or tabloid")
+	flag.StringVar(&flip, "flip", "short", "Edge the sheets are turned over for the back side: short or long")
+	flag.BoolVar(&marks, "marks", false, "Draw fold marks at the middle of the sheets")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	sheet = strings.ToLower(sheet)
+	if _, ok := sheetSizes[sheet]; !ok && sheet != "auto" {
+		fmt.Printf("Error: unknown sheet size %q\n", sheet)
+		os.Exit(1)
+	}
+	if flip != "short" && flip != "long" {
+		fmt.Printf("Error: -flip must be short or long\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := createBooklet(inputPath, outputPath, sheet, flip == "long", marks)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// bookletSequence returns the page numbers for each side of the sheets in printing order, as pairs of the left and
+// right page: front of the first sheet, back of the first sheet, front of the second sheet and so on.  Pages beyond
+// numPages are blanks that pad the count to a multiple of four, numbered as 0.
+func bookletSequence(numPages int) [][2]int {
+	total := (numPages + 3) / 4 * 4
+	page := func(n int) int {
+		if n > numPages {
+			return 0
+		}
+		return n
+	}
+
+	sides := [][2]int{}
+	for s := 0; s < total/4; s++ {
+		sides = append(sides, [2]int{page(total - 2*s), page(2*s + 1)})
+		sides = append(sides, [2]int{page(2*s + 2), page(total - 2*s - 1)})
+	}
+	return sides
+}
+
+// displaySize returns the size of the page as displayed, i.e. after its rotation.
+func displaySize(page *pdf.PdfPage) (float64, float64, error) {
+	box, err := page.GetMediaBox()
+	if err != nil {
+		return 0, 0, err
+	}
+	width, height := box.Urx-box.Llx, box.Ury-box.Lly
+	if pageRotation(page)%180 != 0 {
+		width, height = height, width
+	}
+	return width, height, nil
+}
+
+// pageRotation returns the page rotation normalized to 0, 90, 180 or 270 degrees clockwise.
+func pageRotation(page *pdf.PdfPage) int64 {
+	if page.Rotate == nil {
+		return 0
+	}
+	return (*page.Rotate%360 + 360) % 360
+}
+
+func createBooklet(inputPath, outputPath, sheet string, rotateBacks, marks bool) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if numPages == 0 {
+		return errors.New("No pages in the input")
+	}
+
+	pages := []*pdf.PdfPage{}
+	maxWidth, maxHeight := 0.0, 0.0
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+		width, height, err := displaySize(page)
+		if err != nil {
+			return err
+		}
+		maxWidth = math.Max(maxWidth, width)
+		maxHeight = math.Max(maxHeight, height)
+		pages = append(pages, page)
+	}
+
+	sheetSize := creator.PageSize{2 * maxWidth, maxHeight}
+	if sheet != "auto" {
+		sheetSize = sheetSizes[sheet]
+	}
+
+	c := creator.New()
+	c.SetPageSize(sheetSize)
+	slotWidth, slotHeight := sheetSize[0]/2, sheetSize[1]
+
+	sides := bookletSequence(numPages)
+	fmt.Printf("%d pages, %d blank, %d sheets\n", numPages, 2*len(sides)-numPages, len(sides)/2)
+	for i, side := range sides {
+		back := i%2 == 1
+		name := "front"
+		if back {
+			name = "back"
+		}
+		fmt.Printf("Sheet %d %s: %s | %s\n", i/2+1, name, pageLabel(side[0]), pageLabel(side[1]))
+
+		c.NewPage()
+		for slot, pageNum := range side {
+			if pageNum == 0 {
+				continue
+			}
+			extra := int64(0)
+			x := float64(slot) * slotWidth
+			if back && rotateBacks {
+				// Rotated by 180 degrees, the left page ends up on the right half of the sheet.
+				extra = 180
+				x = float64(1-slot) * slotWidth
+			}
+			err = drawPage(c, pages[pageNum-1], extra, x, 0, slotWidth, slotHeight)
+			if err != nil {
+				return err
+			}
+		}
+
+		if marks {
+			drawFoldMarks(c, sheetSize)
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// pageLabel returns the page number as text, or "blank" for padding pages.
+func pageLabel(pageNum int) string {
+	if pageNum == 0 {
+		return "blank"
+	}
+	return fmt.Sprintf("%d", pageNum)
+}
+
+// drawPage draws the page upright, rotated by a further extra degrees clockwise, scaled to fit and centered in the
+// area with upper left corner (x, y).
+func drawPage(c *creator.Creator, page *pdf.PdfPage, extra int64, x, y, width, height float64) error {
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return err
+	}
+
+	rotate := (pageRotation(page) + extra) % 360
+
+	displayWidth, displayHeight := block.Width(), block.Height()
+	if rotate == 90 || rotate == 270 {
+		displayWidth, displayHeight = displayHeight, displayWidth
+	}
+
+	scale := math.Min(width/displayWidth, height/displayHeight)
+	block.Scale(scale, scale)
+	displayWidth *= scale
+	displayHeight *= scale
+
+	// Target position of the upper left corner of the displayed page.
+	tx := x + (width-displayWidth)/2
+	ty := y + (height-displayHeight)/2
+
+	if rotate == 0 {
+		block.SetPos(tx, ty)
+	} else {
+		// The block is rotated about its upper left corner, counterclockwise, whereas /Rotate is clockwise.
+		// Compute the extent of the rotated block relative to that corner to position it at the target.
+		block.SetAngle(float64(360 - rotate))
+		angle := float64(360-rotate) * math.Pi / 180
+		minX, maxY := math.Inf(1), math.Inf(-1)
+		for _, corner := range [][2]float64{{0, 0}, {block.Width(), 0}, {0, -block.Height()},
+			{block.Width(), -block.Height()}} {
+			// Corners relative to the rotation origin, with the y axis pointing up.
+			rx := corner[0]*math.Cos(angle) - corner[1]*math.Sin(angle)
+			ry := corner[0]*math.Sin(angle) + corner[1]*math.Cos(angle)
+			minX = math.Min(minX, rx)
+			maxY = math.Max(maxY, ry)
+		}
+		block.SetPos(tx-minX, ty+maxY)
+	}
+
+	return c.Draw(block)
+}
+
+// drawFoldMarks draws short lines at the top and bottom edges of the sheet, marking the fold in the middle.
+func drawFoldMarks(c *creator.Creator, sheetSize creator.PageSize) {
+	const length = 12.0
+	mid := sheetSize[0] / 2
+	for _, y := range []float64{0, sheetSize[1] - length} {
+		mark := creator.NewLine(mid, y, mid, y+length)
+		mark.SetLineWidth(0.5)
+		mark.SetColor(creator.ColorRGBFrom8bit(0, 0, 0))
+		_ = c.Draw(mark)
+	}
+}
@@ -0,0 +1,298 @@
+/*
+ * N-up imposition: place 2, 4, 6 or 9 pages of a PDF scaled down on each output sheet, in a grid of rows and columns
+ * in reading order.
+ *
+ * Each source page is imported as a Form XObject (its content stream and resources copied into the form, with the
+ * MediaBox as the form BBox) and drawn on the sheets with a transformation that scales it uniformly to fit its slot,
+ * so the aspect ratio is preserved, and centers it there.  Rotated pages are shown upright.  The pages are not
+ * redrawn or rasterized, so the output remains vector and the text selectable.
+ *
+ * The grid depends on N and the sheet orientation: 2-up is two rows on a portrait sheet and two columns on a
+ * landscape sheet, 6-up is 2 columns by 3 rows on portrait and 3 by 2 on landscape, 4-up and 9-up are square grids.
+ * The slots on the last sheet that are left over when the page count is not a multiple of N stay blank.
+ *
+ * Run as: go run nup.go [-n 4] [-paper a4|letter] [-orientation portrait|landscape] [-lines] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run nup.go [-n 4] [-paper a4|letter] [-orientation portrait|landscape] [-lines] " +
+	"input.pdf output.pdf\n"
+
+const (
+	sheetMargin = 18.0 // Margin around the grid on the sheet.
+	slotPadding = 6.0  // Space between the slot edges and the page within it.
+)
+
+// Portrait sheet sizes in points.
+var paperSizes = map[string][2]float64{
+	"a4":     {595.276, 841.89},
+	"letter": {612, 792},
+}
+
+func main() {
+	n := 0
+	paper := ""
+	orientation := ""
+	lines := false
+	flag.IntVar(&n, "n", 4, "Number of pages per sheet: 2, 4, 6 or 9")
+	flag.StringVar(&paper, "paper", "a4", "Sheet size: a4 or letter")
+	flag.StringVar(&orientation, "orientation", "", "Sheet orientation: portrait or landscape (default landscape "+
+		"for 2-up and 6-up, portrait otherwise)")
+	flag.BoolVar(&lines, "lines", false, "Draw thin separator lines between the slots")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if n != 2 && n != 4 && n != 6 && n != 9 {
+		fmt.Printf("Error: -n must be 2, 4, 6 or 9\n")
+		os.Exit(1)
+	}
+	if _, ok := paperSizes[paper]; !ok {
+		fmt.Printf("Error: unknown paper size %q\n", paper)
+		os.Exit(1)
+	}
+	if orientation == "" {
+		// Two or six portrait pages fit best side by side on a landscape sheet.
+		orientation = "portrait"
+		if n == 2 || n == 6 {
+			orientation = "landscape"
+		}
+	}
+	if orientation != "portrait" && orientation != "landscape" {
+		fmt.Printf("Error: -orientation must be portrait or landscape\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	sheetSize := paperSizes[paper]
+	if orientation == "landscape" {
+		sheetSize[0], sheetSize[1] = sheetSize[1], sheetSize[0]
+	}
+
+	err := createNup(inputPath, outputPath, n, sheetSize, lines)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// gridSize returns the number of columns and rows for n pages per sheet.
+func gridSize(n int, landscape bool) (int, int) {
+	cols, rows := 1, 2
+	switch n {
+	case 4:
+		cols, rows = 2, 2
+	case 6:
+		cols, rows = 2, 3
+	case 9:
+		cols, rows = 3, 3
+	}
+	if landscape {
+		cols, rows = rows, cols
+	}
+	return cols, rows
+}
+
+func createNup(inputPath, outputPath string, n int, sheetSize [2]float64, lines bool) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	cols, rows := gridSize(n, sheetSize[0] > sheetSize[1])
+	slotWidth := (sheetSize[0] - 2*sheetMargin) / float64(cols)
+	slotHeight := (sheetSize[1] - 2*sheetMargin) / float64(rows)
+
+	pdfWriter := pdf.NewPdfWriter()
+
+	for first := 0; first < numPages; first += n {
+		sheet := pdf.NewPdfPage()
+		sheet.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: sheetSize[0], Ury: sheetSize[1]}
+		sheet.Resources = pdf.NewPdfPageResources()
+
+		cc := pdfcontent.NewContentCreator()
+		for slot := 0; slot < n && first+slot < numPages; slot++ {
+			page, err := pdfReader.GetPage(first + slot + 1)
+			if err != nil {
+				return err
+			}
+
+			xform, mbox, err := pageToXObjectForm(page)
+			if err != nil {
+				return err
+			}
+			name := pdfcore.PdfObjectName(fmt.Sprintf("Page%d", slot+1))
+			err = sheet.Resources.SetXObjectFormByName(name, xform)
+			if err != nil {
+				return err
+			}
+
+			// Lower left corner of the slot, in PDF coordinates.
+			x := sheetMargin + float64(slot%cols)*slotWidth
+			y := sheetSize[1] - sheetMargin - float64(slot/cols+1)*slotHeight
+			drawForm(cc, name, mbox, pageRotation(page), x+slotPadding, y+slotPadding,
+				slotWidth-2*slotPadding, slotHeight-2*slotPadding)
+		}
+
+		if lines {
+			drawSeparators(cc, sheetSize, cols, rows)
+		}
+
+		err = sheet.SetContentStreams([]string{cc.String()}, pdfcore.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+		err = pdfWriter.AddPage(sheet)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Converts a page to a Form XObject with the page contents and resources.  The BBox of the form is the page MediaBox,
+// which is also returned.
+func pageToXObjectForm(page *pdf.PdfPage) (*pdf.XObjectForm, *pdf.PdfRectangle, error) {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	xform := pdf.NewXObjectForm()
+	xform.Resources = page.Resources
+	xform.BBox = mbox.ToPdfObject()
+	xform.Filter = pdfcore.NewFlateEncoder()
+	err = xform.SetContentStream([]byte(contents), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return xform, mbox, nil
+}
+
+// pageRotation returns the page rotation normalized to 0, 90, 180 or 270 degrees clockwise.
+func pageRotation(page *pdf.PdfPage) int64 {
+	if page.Rotate == nil {
+		return 0
+	}
+	return (*page.Rotate%360 + 360) % 360
+}
+
+// drawForm draws the form of a page with the MediaBox mbox and rotation, upright and scaled uniformly to fit the
+// area with the lower left corner (x, y), centered in it.
+func drawForm(cc *pdfcontent.ContentCreator, name pdfcore.PdfObjectName, mbox *pdf.PdfRectangle, rotate int64,
+	x, y, width, height float64) {
+	w, h := mbox.Urx-mbox.Llx, mbox.Ury-mbox.Lly
+
+	// Size of the page as displayed, after the rotation.
+	displayWidth, displayHeight := w, h
+	if rotate == 90 || rotate == 270 {
+		displayWidth, displayHeight = h, w
+	}
+	scale := math.Min(width/displayWidth, height/displayHeight)
+	tx := x + (width-scale*displayWidth)/2
+	ty := y + (height-scale*displayHeight)/2
+
+	cc.Add_q()
+	// The operators are applied to the form in reverse order: move the MediaBox to the origin, rotate it clockwise
+	// into the quadrant with positive coordinates, then scale and move it into place.
+	cc.Add_cm(scale, 0, 0, scale, tx, ty)
+	switch rotate {
+	case 90:
+		cc.Add_cm(0, -1, 1, 0, 0, w)
+	case 180:
+		cc.Add_cm(-1, 0, 0, -1, w, h)
+	case 270:
+		cc.Add_cm(0, 1, -1, 0, h, 0)
+	}
+	cc.Add_cm(1, 0, 0, 1, -mbox.Llx, -mbox.Lly)
+	cc.Add_Do(name)
+	cc.Add_Q()
+}
+
+// drawSeparators draws thin gray lines between the columns and rows of the grid.
+func drawSeparators(cc *pdfcontent.ContentCreator, sheetSize [2]float64, cols, rows int) {
+	left, right := sheetMargin, sheetSize[0]-sheetMargin
+	bottom, top := sheetMargin, sheetSize[1]-sheetMargin
+
+	cc.Add_q()
+	cc.Add_RG(0.6, 0.6, 0.6)
+	cc.Add_w(0.5)
+	for i := 1; i < cols; i++ {
+		x := left + float64(i)*(right-left)/float64(cols)
+		cc.Add_m(x, bottom)
+		cc.Add_l(x, top)
+	}
+	for i := 1; i < rows; i++ {
+		y := bottom + float64(i)*(top-bottom)/float64(rows)
+		cc.Add_m(left, y)
+		cc.Add_l(right, y)
+	}
+	cc.Add_S()
+	cc.Add_Q()
+}
@@ -0,0 +1,459 @@
+/*
+ * Add a sticky note (text annotation) to a signed PDF file with an incremental update, so that the existing
+ * signatures remain valid.
+ *
+ * A signature covers the bytes of the file as it was when signed (the ByteRange of the signature, which excludes
+ * only the signature value itself).  Rewriting the file, as the PdfWriter does, changes those bytes and breaks the
+ * signature.  An incremental update instead appends the changed and new objects to the unchanged original bytes,
+ * followed by a cross-reference section for these objects only and a trailer pointing to the previous
+ * cross-reference section (Prev).  Here the update contains:
+ * - the new annotation,
+ * - the page (or its annotations array, if it is a separate object) with the reference to the annotation added.
+ *
+ * The cross-reference section is written in the same form as in the original file: a table, or a cross-reference
+ * stream for files that use them (PDF 1.5 and later).
+ *
+ * Signatures are found through the signature fields of the interactive form.  For each signature, a digest of the
+ * signed byte range is computed before and after the update to verify that the signed bytes are untouched.  A
+ * certification signature (DocMDP) can forbid changes: the update is only written if annotations are allowed.
+ *
+ * Encrypted files are not supported, as the new objects would need to be encrypted.
+ *
+ * The update is written by writeUpdate in update.go, which is shared with the other examples that update files
+ * incrementally.
+ *
+ * Run as: go run incremental_update.go update.go [-page 1] [-x 36] [-y 36] [-author name] [-note text]
+ *                 input.pdf output.pdf
+ * The position of the note (x, y) is from the upper left corner of the page.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run incremental_update.go update.go [-page 1] [-x 36] [-y 36] [-author name] [-note text] " +
+	"input.pdf output.pdf\n"
+
+// Size of the sticky note icon.
+const noteSize = 20.0
+
+// Annotation flags (F): print the annotation, and do not scale or rotate the note icon with the page.
+const (
+	annotFlagPrint    = 4
+	annotFlagNoZoom   = 8
+	annotFlagNoRotate = 16
+)
+
+// signature is a signature found in the document, with the digest of the bytes it covers.
+type signature struct {
+	name      string
+	byteRange []int64
+	digest    [sha256.Size]byte
+}
+
+func main() {
+	pageNum := 0
+	x := 0.0
+	y := 0.0
+	author := ""
+	note := ""
+	flag.IntVar(&pageNum, "page", 1, "Page to add the note to")
+	flag.Float64Var(&x, "x", 36, "Horizontal position of the note from the left edge of the page")
+	flag.Float64Var(&y, "y", 36, "Vertical position of the note from the top edge of the page")
+	flag.StringVar(&author, "author", "Reviewer", "Author of the note")
+	flag.StringVar(&note, "note", "Reviewed, no changes required.", "Text of the note")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := addNoteIncrementally(inputPath, outputPath, pageNum, x, y, author, note)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func addNoteIncrementally(inputPath, outputPath string, pageNum int, x, y float64, author, note string) error {
+	data, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		return errors.New("Incremental updates of encrypted files are not supported")
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	catalog, err := getCatalog(pdfReader, trailer)
+	if err != nil {
+		return err
+	}
+
+	err = checkDocMDP(pdfReader, catalog)
+	if err != nil {
+		return err
+	}
+
+	signatures, err := findSignatures(pdfReader, catalog, data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Signatures: %d\n", len(signatures))
+	for _, sig := range signatures {
+		r := sig.byteRange
+		covers := "the whole file"
+		if r[2]+r[3] < int64(len(data)) {
+			covers = fmt.Sprintf("the revision ending at offset %d", r[2]+r[3])
+		}
+		fmt.Printf("  %s: byte range %v covers %s\n", sig.name, r, covers)
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if pageNum < 1 || pageNum > numPages {
+		return fmt.Errorf("Page %d out of range (document has %d pages)", pageNum, numPages)
+	}
+
+	pageObj, err := pdfReader.GetPageAsIndirectObject(pageNum)
+	if err != nil {
+		return err
+	}
+	pageInd, ok := pageObj.(*pdfcore.PdfIndirectObject)
+	if !ok {
+		return errors.New("Page not an indirect object")
+	}
+	pageDict, ok := pageInd.PdfObject.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Page not a dictionary")
+	}
+	page, err := pdfReader.GetPage(pageNum)
+	if err != nil {
+		return err
+	}
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return err
+	}
+
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	annotNum := int64(*size)
+
+	// The note, with the lower left corner of the icon at the position from the upper left corner of the page.
+	llx := mediaBox.Llx + x
+	lly := mediaBox.Ury - y - noteSize
+	date := formatPdfDate(time.Now())
+	annot := pdf.NewPdfAnnotationText()
+	annot.Rect = pdfcore.MakeArrayFromFloats([]float64{llx, lly, llx + noteSize, lly + noteSize})
+	annot.Contents = pdfcore.MakeString(note)
+	annot.M = pdfcore.MakeString(date)
+	annot.F = pdfcore.MakeInteger(annotFlagPrint | annotFlagNoZoom | annotFlagNoRotate)
+	annot.P = pageInd
+	annot.T = pdfcore.MakeString(author)
+	annot.CreationDate = pdfcore.MakeString(date)
+	annot.Name = pdfcore.MakeName("Comment")
+	annotDict := pdfcore.TraceToDirectObject(annot.ToPdfObject())
+
+	objects := []updateObject{{number: annotNum, obj: annotDict, description: "new annotation"}}
+	annotRef := &pdfcore.PdfObjectReference{ObjectNumber: annotNum}
+
+	// Add the reference to the annotations array: in the page dictionary, or in the separate array object.
+	switch annots := pageDict.Get("Annots").(type) {
+	case nil:
+		pageDict.Set("Annots", pdfcore.MakeArray(annotRef))
+		objects = append(objects, updateObject{number: pageInd.ObjectNumber, generation: pageInd.GenerationNumber,
+			obj: pageDict, description: "page"})
+	case *pdfcore.PdfObjectArray:
+		*annots = append(*annots, annotRef)
+		objects = append(objects, updateObject{number: pageInd.ObjectNumber, generation: pageInd.GenerationNumber,
+			obj: pageDict, description: "page"})
+	case *pdfcore.PdfIndirectObject:
+		arr, ok := annots.PdfObject.(*pdfcore.PdfObjectArray)
+		if !ok {
+			return errors.New("Annots not an array")
+		}
+		*arr = append(*arr, annotRef)
+		objects = append(objects, updateObject{number: annots.ObjectNumber, generation: annots.GenerationNumber,
+			obj: arr, description: "annotations array"})
+	case *pdfcore.PdfObjectReference:
+		obj, err := pdfReader.GetIndirectObjectByNumber(int(annots.ObjectNumber))
+		if err != nil {
+			return err
+		}
+		arr, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectArray)
+		if !ok {
+			return errors.New("Annots not an array")
+		}
+		*arr = append(*arr, annotRef)
+		objects = append(objects, updateObject{number: annots.ObjectNumber, generation: annots.GenerationNumber,
+			obj: arr, description: "annotations array"})
+	default:
+		return fmt.Errorf("Invalid Annots (%T)", annots)
+	}
+
+	update, err := writeUpdate(data, objects, trailer, annotNum+1)
+	if err != nil {
+		return err
+	}
+	output := append(append([]byte{}, data...), update...)
+	for _, obj := range objects {
+		fmt.Printf("Updated object %d: %s\n", obj.number, obj.description)
+	}
+
+	err = verifyUpdate(data, output, signatures, pageNum)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(outputPath, output, 0644)
+}
+
+// Returns the document catalog.
+func getCatalog(pdfReader *pdf.PdfReader, trailer *pdfcore.PdfObjectDictionary) (*pdfcore.PdfObjectDictionary,
+	error) {
+	root := trailer.Get("Root")
+	if ref, ok := root.(*pdfcore.PdfObjectReference); ok {
+		obj, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, err
+		}
+		root = obj
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(root).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("Missing catalog")
+	}
+	return catalog, nil
+}
+
+// Checks that the certification signature, if any, allows adding annotations.  The permissions (P) are 1: no
+// changes, 2: form filling and signing, 3: also annotations.
+func checkDocMDP(pdfReader *pdf.PdfReader, catalog *pdfcore.PdfObjectDictionary) error {
+	perms, ok := resolve(pdfReader, catalog.Get("Perms")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	sigDict, ok := resolve(pdfReader, perms.Get("DocMDP")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	refs, ok := resolve(pdfReader, sigDict.Get("Reference")).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return nil
+	}
+
+	for _, refObj := range *refs {
+		ref, ok := resolve(pdfReader, refObj).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		params, ok := resolve(pdfReader, ref.Get("TransformParams")).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		// P defaults to 2.
+		p := int64(2)
+		if pObj, ok := resolve(pdfReader, params.Get("P")).(*pdfcore.PdfObjectInteger); ok {
+			p = int64(*pObj)
+		}
+		if p < 3 {
+			return fmt.Errorf("The document is certified with permissions %d, adding annotations would invalidate "+
+				"the certification", p)
+		}
+	}
+	return nil
+}
+
+// Returns the signatures of the signature fields, with the digests of their byte ranges in the file data.
+func findSignatures(pdfReader *pdf.PdfReader, catalog *pdfcore.PdfObjectDictionary, data []byte) ([]*signature,
+	error) {
+	acroForm, ok := resolve(pdfReader, catalog.Get("AcroForm")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, nil
+	}
+	fields, ok := resolve(pdfReader, acroForm.Get("Fields")).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return nil, nil
+	}
+
+	signatures := []*signature{}
+	err := collectSignatures(pdfReader, *fields, "", "", data, &signatures, map[pdfcore.PdfObject]bool{})
+	return signatures, err
+}
+
+// Collects the signatures of the fields and their descendants.  The field type (FT) is inherited.
+func collectSignatures(pdfReader *pdf.PdfReader, fields pdfcore.PdfObjectArray, parentName, parentType string,
+	data []byte, signatures *[]*signature, visited map[pdfcore.PdfObject]bool) error {
+	for _, fieldObj := range fields {
+		field, ok := resolve(pdfReader, fieldObj).(*pdfcore.PdfObjectDictionary)
+		if !ok || visited[field] {
+			continue
+		}
+		visited[field] = true
+
+		name := parentName
+		if t, ok := resolve(pdfReader, field.Get("T")).(*pdfcore.PdfObjectString); ok {
+			if len(name) > 0 {
+				name += "."
+			}
+			name += string(*t)
+		}
+		fieldType := parentType
+		if ft, ok := resolve(pdfReader, field.Get("FT")).(*pdfcore.PdfObjectName); ok {
+			fieldType = string(*ft)
+		}
+
+		if kids, ok := resolve(pdfReader, field.Get("Kids")).(*pdfcore.PdfObjectArray); ok {
+			err := collectSignatures(pdfReader, *kids, name, fieldType, data, signatures, visited)
+			if err != nil {
+				return err
+			}
+		}
+
+		if fieldType != "Sig" {
+			continue
+		}
+		sigDict, ok := resolve(pdfReader, field.Get("V")).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			// Signature field not signed yet.
+			continue
+		}
+		arr, ok := resolve(pdfReader, sigDict.Get("ByteRange")).(*pdfcore.PdfObjectArray)
+		if !ok || len(*arr) != 4 {
+			return fmt.Errorf("Signature %s: invalid ByteRange", name)
+		}
+		sig := &signature{name: name}
+		for _, v := range *arr {
+			i, ok := resolve(pdfReader, v).(*pdfcore.PdfObjectInteger)
+			if !ok {
+				return fmt.Errorf("Signature %s: invalid ByteRange", name)
+			}
+			sig.byteRange = append(sig.byteRange, int64(*i))
+		}
+		digest, err := byteRangeDigest(data, sig.byteRange)
+		if err != nil {
+			return fmt.Errorf("Signature %s: %v", name, err)
+		}
+		sig.digest = digest
+		*signatures = append(*signatures, sig)
+	}
+	return nil
+}
+
+// Returns the direct object of obj, loaded with the reader if it is a reference.
+func resolve(pdfReader *pdf.PdfReader, obj pdfcore.PdfObject) pdfcore.PdfObject {
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		resolved, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil
+		}
+		obj = resolved
+	}
+	return pdfcore.TraceToDirectObject(obj)
+}
+
+// Returns the digest of the bytes in the byte range: pairs of offset and length.
+func byteRangeDigest(data []byte, byteRange []int64) ([sha256.Size]byte, error) {
+	h := sha256.New()
+	for i := 0; i+1 < len(byteRange); i += 2 {
+		offset, length := byteRange[i], byteRange[i+1]
+		if offset < 0 || length < 0 || offset+length > int64(len(data)) {
+			return [sha256.Size]byte{}, fmt.Errorf("Byte range %v outside the file", byteRange)
+		}
+		h.Write(data[offset : offset+length])
+	}
+	var digest [sha256.Size]byte
+	copy(digest[:], h.Sum(nil))
+	return digest, nil
+}
+
+// Verifies the updated file: the original bytes are unchanged, the signed byte ranges have the same digests, and the
+// page has the new annotation.
+func verifyUpdate(original, output []byte, signatures []*signature, pageNum int) error {
+	if !bytes.Equal(output[:len(original)], original) {
+		return errors.New("The original bytes were modified")
+	}
+	for _, sig := range signatures {
+		digest, err := byteRangeDigest(output, sig.byteRange)
+		if err != nil {
+			return fmt.Errorf("Signature %s: %v", sig.name, err)
+		}
+		if digest != sig.digest {
+			return fmt.Errorf("Signature %s: the signed bytes were modified", sig.name)
+		}
+		fmt.Printf("Signature %s: signed bytes unchanged (sha256 %x)\n", sig.name, digest[:8])
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(output))
+	if err != nil {
+		return fmt.Errorf("Cannot read the updated file: %v", err)
+	}
+	originalReader, err := pdf.NewPdfReader(bytes.NewReader(original))
+	if err != nil {
+		return err
+	}
+	page, err := pdfReader.GetPage(pageNum)
+	if err != nil {
+		return err
+	}
+	originalPage, err := originalReader.GetPage(pageNum)
+	if err != nil {
+		return err
+	}
+	if len(page.Annotations) != len(originalPage.Annotations)+1 {
+		return fmt.Errorf("Expected %d annotations on page %d, found %d", len(originalPage.Annotations)+1, pageNum,
+			len(page.Annotations))
+	}
+	return nil
+}
+
+// Formats the time as a PDF date string, e.g. D:20180213153000+01'00'.
+func formatPdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,546 @@
+/*
+ * Create a back-of-book index: the terms in alphabetical order under letter headings, each with the numbers of the
+ * pages it appears on, in two columns.
+ *
+ * The terms are read from a text file, one per line:
+ *   relay: 3, 7, 12-14         the term with its pages,
+ *   sensor                     the term, searched in the document given with -pdf,
+ *   sensor = sensors; probe    the term, searched as well as the alternatives after = (e.g. plurals).
+ * Empty lines and lines starting with # are ignored.  A term listed more than once gets the pages of all its lines.
+ *
+ * The search is case-insensitive and matches whole words only, so "heat" does not match "heater".  The page text is
+ * extracted from the content streams as is: text in fonts with custom encodings is not found, and words of text runs
+ * drawn without a space between them (e.g. a heading and the paragraph after it) may be joined.
+ *
+ * The terms are sorted case-insensitively, with accented letters sorted as the plain letters, and grouped under the
+ * letter they start with.  Terms that do not start with a letter are listed first, under #.  The pages of an entry
+ * are comma-separated, with consecutive pages collapsed into ranges: 3, 5, 6, 7, 9 is written as 3, 5–7, 9.  Entries
+ * that do not fit the column width are wrapped with a hanging indent.
+ *
+ * Run as: go run back_index.go [-pdf document.pdf] [-title Index] terms.txt output.pdf
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run back_index.go [-pdf document.pdf] [-title Index] terms.txt output.pdf\n"
+
+// termSpec is a term of the terms file, with the given pages and the words to search for.
+type termSpec struct {
+	term   string
+	pages  []int
+	search []string
+}
+
+// indexEntry is a term of the index with its pages.
+type indexEntry struct {
+	term  string
+	pages []int
+}
+
+// indexGroup is the entries under a letter heading.
+type indexGroup struct {
+	heading string
+	entries []indexEntry
+}
+
+const (
+	margin       = 72.0
+	columnGap    = 24.0
+	entrySize    = 10.0
+	lineHeight   = 13.0
+	headingSize  = 13.0
+	hangingSpace = 12.0 // Indentation of the wrapped lines of an entry.
+)
+
+// Replacements of accented letters by the plain letters for sorting and grouping (Latin-1, as in WinAnsi).
+var accentFolding = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n", "ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "æ", "ae", "œ", "oe", "ß", "ss",
+)
+
+func main() {
+	pdfPath := ""
+	title := ""
+	flag.StringVar(&pdfPath, "pdf", "", "Document to search for the terms without pages")
+	flag.StringVar(&title, "title", "Index", "Title of the index")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	termsPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := createIndex(termsPath, pdfPath, title, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createIndex(termsPath, pdfPath, title, outputPath string) error {
+	specs, err := readTerms(termsPath)
+	if err != nil {
+		return err
+	}
+
+	// Search the terms without pages in the document.
+	needSearch := false
+	for _, spec := range specs {
+		if len(spec.search) > 0 {
+			needSearch = true
+		}
+	}
+	pageTexts := []string{}
+	if needSearch {
+		if pdfPath == "" {
+			return errors.New("Terms without pages need the document to search, give it with -pdf")
+		}
+		pageTexts, err = extractPageTexts(pdfPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The entries by term, merging the pages of terms listed more than once (the case of the first one is used).
+	byTerm := map[string]*indexEntry{}
+	entries := []*indexEntry{}
+	for _, spec := range specs {
+		pages := spec.pages
+		if len(spec.search) > 0 {
+			pages = searchPages(pageTexts, spec.search)
+			if len(pages) == 0 {
+				fmt.Printf("Warning: %q not found in the document, not listed\n", spec.term)
+				continue
+			}
+		}
+		key := strings.ToLower(spec.term)
+		entry, ok := byTerm[key]
+		if !ok {
+			entry = &indexEntry{term: spec.term}
+			byTerm[key] = entry
+			entries = append(entries, entry)
+		}
+		entry.pages = append(entry.pages, pages...)
+	}
+	if len(entries) == 0 {
+		return errors.New("No terms to index")
+	}
+
+	groups := groupEntries(entries)
+	for _, g := range groups {
+		fmt.Printf("%s: %d terms\n", g.heading, len(g.entries))
+	}
+
+	return writeIndex(groups, title, outputPath)
+}
+
+// Reads the terms file.
+func readTerms(path string) ([]termSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	specs := []termSpec{}
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		spec := termSpec{}
+		if i := strings.Index(line, ":"); i >= 0 {
+			spec.term = strings.TrimSpace(line[:i])
+			spec.pages, err = parsePages(line[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("Line %d: %v", lineNum, err)
+			}
+		} else {
+			parts := strings.SplitN(line, "=", 2)
+			spec.term = strings.TrimSpace(parts[0])
+			spec.search = []string{spec.term}
+			if len(parts) > 1 {
+				for _, alt := range strings.Split(parts[1], ";") {
+					if alt = strings.TrimSpace(alt); alt != "" {
+						spec.search = append(spec.search, alt)
+					}
+				}
+			}
+		}
+		if spec.term == "" {
+			return nil, fmt.Errorf("Line %d: missing term", lineNum)
+		}
+		specs = append(specs, spec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// Parses comma-separated pages and page ranges, e.g. 3, 7, 12-14.
+func parsePages(s string) ([]int, error) {
+	pages := []int{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			first, last = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+		from, err := strconv.Atoi(first)
+		if err != nil || from < 1 {
+			return nil, fmt.Errorf("Invalid page %q", part)
+		}
+		to, err := strconv.Atoi(last)
+		if err != nil || to < from {
+			return nil, fmt.Errorf("Invalid page range %q", part)
+		}
+		for page := from; page <= to; page++ {
+			pages = append(pages, page)
+		}
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("Missing pages")
+	}
+	return pages, nil
+}
+
+// Returns the text of each page of the document, in lower case with the white space collapsed to single spaces.
+func extractPageTexts(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("Need to decrypt with password")
+		}
+	}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	texts := []string{}
+	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return nil, err
+		}
+		text, err := pdfcontent.NewContentStreamParser(contents).ExtractText()
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, normalizeText(text))
+	}
+	return texts, nil
+}
+
+// Returns the text in lower case without accents, with the white space collapsed to single spaces.
+func normalizeText(text string) string {
+	return accentFolding.Replace(strings.ToLower(strings.Join(strings.Fields(text), " ")))
+}
+
+// Returns the pages (from 1) on which any of the words appear as whole words.
+func searchPages(pageTexts []string, words []string) []int {
+	pages := []int{}
+	for i, text := range pageTexts {
+		for _, word := range words {
+			if containsWord(text, normalizeText(word)) {
+				pages = append(pages, i+1)
+				break
+			}
+		}
+	}
+	return pages
+}
+
+// Returns true if the text contains the word, not as part of a longer word.
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	for start := 0; ; {
+		i := strings.Index(text[start:], word)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(word)
+		before := i == 0 || !isWordChar(text[i-1])
+		after := end == len(text) || !isWordChar(text[end])
+		if before && after {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+// Returns true for the bytes of letters and digits.  Bytes of multi-byte characters count as letters.
+func isWordChar(b byte) bool {
+	return b >= 0x80 || b == '_' || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+// Returns the entries sorted case-insensitively and grouped by their first letter.
+func groupEntries(entries []*indexEntry) []indexGroup {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ki, kj := sortKey(entries[i].term), sortKey(entries[j].term)
+		if ki != kj {
+			return ki < kj
+		}
+		return entries[i].term < entries[j].term
+	})
+
+	groups := []indexGroup{}
+	for _, entry := range entries {
+		heading := groupHeading(entry.term)
+		if len(groups) == 0 || groups[len(groups)-1].heading != heading {
+			groups = append(groups, indexGroup{heading: heading})
+		}
+		g := &groups[len(groups)-1]
+		g.entries = append(g.entries, indexEntry{term: entry.term, pages: entry.pages})
+	}
+	return groups
+}
+
+// Returns the key for sorting the term: lower case without accents.  Terms that do not start with a letter sort
+// first.
+func sortKey(term string) string {
+	key := normalizeText(term)
+	if groupHeading(term) == "#" {
+		return "\x00" + key
+	}
+	return key
+}
+
+// Returns the letter heading of the term: its first letter in upper case without accents, or # if it does not start
+// with a letter.
+func groupHeading(term string) string {
+	key := normalizeText(term)
+	for _, r := range key {
+		if r >= 'a' && r <= 'z' {
+			return string(unicode.ToUpper(r))
+		}
+		break
+	}
+	return "#"
+}
+
+// Returns the pages as comma-separated numbers, sorted and without duplicates, with consecutive pages collapsed into
+// ranges.
+func formatPages(pages []int) string {
+	sort.Ints(pages)
+	parts := []string{}
+	for i := 0; i < len(pages); {
+		j := i
+		for j+1 < len(pages) && pages[j+1] <= pages[j]+1 {
+			j++
+		}
+		if pages[j] > pages[i] {
+			// An en dash (WinAnsi) between the first and last page.
+			parts = append(parts, fmt.Sprintf("%d–%d", pages[i], pages[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(pages[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+// indexLayout lays out the index in two columns, flowing to new pages as needed.
+type indexLayout struct {
+	c           *creator.Creator
+	columnWidth float64
+	column      int
+	top         float64 // Top of the columns on the current page.
+	y           float64
+}
+
+// Writes the index to the output file.
+func writeIndex(groups []indexGroup, title, outputPath string) error {
+	c := creator.New()
+	c.NewPage()
+
+	p := creator.NewParagraph(title)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(20)
+	p.SetEnableWrap(false)
+	p.SetPos(margin, margin)
+	err := c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	l := &indexLayout{
+		c:           c,
+		columnWidth: (c.Width() - 2*margin - columnGap) / 2,
+		top:         margin + p.Height() + 20,
+	}
+	l.y = l.top
+
+	for _, g := range groups {
+		err = l.drawGroup(g)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Draws the letter heading and the entries of the group.
+func (l *indexLayout) drawGroup(g indexGroup) error {
+	heading := creator.NewParagraph(g.heading)
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(headingSize)
+	heading.SetEnableWrap(false)
+
+	// The space above the heading, except at the top of a column.
+	if l.y > l.top {
+		l.y += 10
+	}
+	// Keep the heading with the first entry.
+	err := l.ensureSpace(heading.Height() + 4 + 2*lineHeight)
+	if err != nil {
+		return err
+	}
+	heading.SetPos(l.x(), l.y)
+	err = l.c.Draw(heading)
+	if err != nil {
+		return err
+	}
+	l.y += heading.Height() + 4
+
+	font := fonts.NewFontHelvetica()
+	for _, entry := range g.entries {
+		lines := wrapEntry(entry.term+", "+formatPages(entry.pages), font, l.columnWidth)
+		// Keep the entry together.
+		err = l.ensureSpace(float64(len(lines)) * lineHeight)
+		if err != nil {
+			return err
+		}
+		for i, line := range lines {
+			p := creator.NewParagraph(line)
+			p.SetFont(font)
+			p.SetFontSize(entrySize)
+			p.SetEnableWrap(false)
+			indent := 0.0
+			if i > 0 {
+				indent = hangingSpace
+			}
+			p.SetPos(l.x()+indent, l.y)
+			err = l.c.Draw(p)
+			if err != nil {
+				return err
+			}
+			l.y += lineHeight
+		}
+	}
+	return nil
+}
+
+// Moves to the next column or page if there is not enough space for the height left in the column.
+func (l *indexLayout) ensureSpace(height float64) error {
+	if l.y+height <= l.c.Height()-margin {
+		return nil
+	}
+	if l.column == 0 {
+		l.column = 1
+	} else {
+		l.c.NewPage()
+		l.column = 0
+		l.top = margin
+	}
+	l.y = l.top
+	return nil
+}
+
+// Returns the x position of the current column.
+func (l *indexLayout) x() float64 {
+	return margin + float64(l.column)*(l.columnWidth+columnGap)
+}
+
+// Wraps the entry to the column width, breaking at the spaces.  Lines after the first are indented by hangingSpace.
+// A single word that is wider than the line is not broken.
+func wrapEntry(text string, font fonts.Font, width float64) []string {
+	parts := strings.SplitAfter(text, " ")
+	lines := []string{}
+	line := ""
+	for _, part := range parts {
+		lineWidth := width
+		if len(lines) > 0 {
+			lineWidth -= hangingSpace
+		}
+		if line != "" && textWidth(strings.TrimSpace(line+part), font, entrySize) > lineWidth {
+			lines = append(lines, strings.TrimSpace(line))
+			line = ""
+		}
+		line += part
+	}
+	return append(lines, strings.TrimSpace(line))
+}
+
+// Returns the width of the text in the font.
+func textWidth(text string, font fonts.Font, size float64) float64 {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetEnableWrap(false)
+	return p.Width()
+}
@@ -0,0 +1,344 @@
+/*
+ * Create sheets of mailing labels matching a label template such as Avery 5160 (US Letter, 3 x 10 labels).
+ *
+ * The addresses are read from a text file with one address per block, blocks separated by blank lines.  Without an
+ * input file a set of sample addresses is used.  Each address is left aligned and centered vertically on its label,
+ * and the font size is reduced when an address does not fit at the default size.
+ *
+ * The layout comes from the template given with -template and any of its values can be overridden with the layout
+ * flags (all in points, 72 points per inch).  Use -start to begin at a later label position (counting from 1, left to
+ * right and top to bottom) to reuse a partially used sheet.  Addresses that do not fit on the first sheet continue
+ * on additional sheets.  Use -outline to draw the label outlines, e.g. for a test print on plain paper to check the
+ * alignment.
+ *
+ * Run as: go run label_sheet.go [-template 5160] [-start 1] [-outline] [layout flags] output.pdf [addresses.txt]
+ * Layout flags: [-cols n] [-rows n] [-width w] [-height h] [-top t] [-left l] [-hgap g] [-vgap g]
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run label_sheet.go [-template 5160] [-start 1] [-outline] [layout flags] output.pdf " +
+	"[addresses.txt]\n"
+
+const (
+	labelPadding = 9.0  // Horizontal padding of the text within a label.
+	maxFontSize  = 10.0 // Font size used when the address fits.
+	minFontSize  = 6.0  // Smallest font size when shrinking an address to fit.
+	lineSpacing  = 1.2  // Line height relative to the font size.
+)
+
+// labelTemplate describes a sheet of labels.  All dimensions are in points; the margins are measured from the top
+// left corner of the page to the top left corner of the first label.
+type labelTemplate struct {
+	Description string
+	PageSize    creator.PageSize
+	Cols, Rows  int
+	Width       float64 // Label width.
+	Height      float64 // Label height.
+	Top, Left   float64 // Page margins.
+	HGap, VGap  float64 // Gaps between columns and between rows.
+}
+
+var templates = map[string]labelTemplate{
+	"5160": {
+		Description: "Address labels 1\" x 2-5/8\", 30 per sheet (US Letter)",
+		PageSize:    creator.PageSizeLetter,
+		Cols:        3, Rows: 10,
+		Width: 2.625 * 72, Height: 1 * 72,
+		Top: 0.5 * 72, Left: 0.1875 * 72,
+		HGap: 0.125 * 72, VGap: 0,
+	},
+	"5163": {
+		Description: "Shipping labels 2\" x 4\", 10 per sheet (US Letter)",
+		PageSize:    creator.PageSizeLetter,
+		Cols:        2, Rows: 5,
+		Width: 4 * 72, Height: 2 * 72,
+		Top: 0.5 * 72, Left: 0.15625 * 72,
+		HGap: 0.1875 * 72, VGap: 0,
+	},
+	"L7160": {
+		Description: "Address labels 63.5 x 38.1 mm, 21 per sheet (A4)",
+		PageSize:    creator.PageSizeA4,
+		Cols:        3, Rows: 7,
+		Width: 63.5 * creator.PPMM, Height: 38.1 * creator.PPMM,
+		Top: 15.15 * creator.PPMM, Left: 7.25 * creator.PPMM,
+		HGap: 2.54 * creator.PPMM, VGap: 0,
+	},
+	"L7163": {
+		Description: "Address labels 99.1 x 38.1 mm, 14 per sheet (A4)",
+		PageSize:    creator.PageSizeA4,
+		Cols:        2, Rows: 7,
+		Width: 99.1 * creator.PPMM, Height: 38.1 * creator.PPMM,
+		Top: 15.15 * creator.PPMM, Left: 4.65 * creator.PPMM,
+		HGap: 2.5 * creator.PPMM, VGap: 0,
+	},
+}
+
+var sampleAddresses = [][]string{
+	{"Jane Doe", "123 Main Street", "Springfield, IL 62701"},
+	{"John Smith", "Acme Corporation", "Attn: Purchasing", "4500 Industrial Parkway, Suite 210", "Dayton, OH 45414"},
+	{"Maria Garcia", "77 Ocean Avenue, Apt. 5B", "San Francisco, CA 94112"},
+	{"Robert Johnson", "PO Box 1024", "Austin, TX 78767"},
+	{"Linda Williams", "9 Elm Court", "Portland, ME 04101"},
+	{"Michael Brown", "2500 Lakeshore Drive", "Chicago, IL 60614"},
+}
+
+func main() {
+	templateName := ""
+	start := 0
+	outline := false
+	t := labelTemplate{}
+	flag.StringVar(&templateName, "template", "5160", "Label template ("+templateNames()+")")
+	flag.IntVar(&start, "start", 1, "Label position to start at (1 is the top left label)")
+	flag.BoolVar(&outline, "outline", false, "Draw the label outlines")
+	flag.IntVar(&t.Cols, "cols", 0, "Number of label columns")
+	flag.IntVar(&t.Rows, "rows", 0, "Number of label rows")
+	flag.Float64Var(&t.Width, "width", 0, "Label width (points)")
+	flag.Float64Var(&t.Height, "height", 0, "Label height (points)")
+	flag.Float64Var(&t.Top, "top", 0, "Top margin (points)")
+	flag.Float64Var(&t.Left, "left", 0, "Left margin (points)")
+	flag.Float64Var(&t.HGap, "hgap", 0, "Gap between label columns (points)")
+	flag.Float64Var(&t.VGap, "vgap", 0, "Gap between label rows (points)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	template, ok := templates[templateName]
+	if !ok {
+		fmt.Printf("Error: Unknown template %q, available: %s\n", templateName, templateNames())
+		os.Exit(1)
+	}
+
+	// Only the layout flags given on the command line override the template.
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "cols":
+			template.Cols = t.Cols
+		case "rows":
+			template.Rows = t.Rows
+		case "width":
+			template.Width = t.Width
+		case "height":
+			template.Height = t.Height
+		case "top":
+			template.Top = t.Top
+		case "left":
+			template.Left = t.Left
+		case "hgap":
+			template.HGap = t.HGap
+		case "vgap":
+			template.VGap = t.VGap
+		}
+	})
+
+	err := validateTemplate(template)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	perSheet := template.Cols * template.Rows
+	if start < 1 || start > perSheet {
+		fmt.Printf("Error: Start position must be between 1 and %d\n", perSheet)
+		os.Exit(1)
+	}
+
+	addresses := sampleAddresses
+	if flag.NArg() > 1 {
+		addresses, err = readAddresses(flag.Arg(1))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	sheets, err := createLabelSheets(addresses, template, start, outline, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%d labels on %d sheet(s)\n", len(addresses), sheets)
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func templateNames() string {
+	names := []string{}
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// validateTemplate checks that the label grid has positive dimensions and fits on the page.
+func validateTemplate(t labelTemplate) error {
+	if t.Cols < 1 || t.Rows < 1 {
+		return errors.New("The label grid needs at least one row and column")
+	}
+	if t.Width <= 0 || t.Height <= 0 {
+		return errors.New("The label width and height must be positive")
+	}
+	if t.Top < 0 || t.Left < 0 || t.HGap < 0 || t.VGap < 0 {
+		return errors.New("The margins and gaps cannot be negative")
+	}
+
+	right := t.Left + float64(t.Cols)*t.Width + float64(t.Cols-1)*t.HGap
+	bottom := t.Top + float64(t.Rows)*t.Height + float64(t.Rows-1)*t.VGap
+	if right > t.PageSize[0]+0.01 || bottom > t.PageSize[1]+0.01 {
+		return fmt.Errorf("The labels (%.1f x %.1f) do not fit on the page (%.1f x %.1f)",
+			right, bottom, t.PageSize[0], t.PageSize[1])
+	}
+
+	return nil
+}
+
+// readAddresses reads addresses from a text file, one line per address line and addresses separated by blank lines.
+func readAddresses(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	addresses := [][]string{}
+	address := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			if len(address) > 0 {
+				addresses = append(addresses, address)
+				address = []string{}
+			}
+			continue
+		}
+		address = append(address, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(address) > 0 {
+		addresses = append(addresses, address)
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("No addresses found in %s", path)
+	}
+	return addresses, nil
+}
+
+// createLabelSheets places the addresses on the labels starting at position start (1-based) on the first sheet and
+// returns the number of sheets.
+func createLabelSheets(addresses [][]string, t labelTemplate, start int, outline bool, outputPath string) (int,
+	error) {
+	c := creator.New()
+	c.SetPageSize(t.PageSize)
+
+	perSheet := t.Cols * t.Rows
+	sheets := 0
+	for i, address := range addresses {
+		pos := start - 1 + i
+		if pos%perSheet == 0 || i == 0 {
+			c.NewPage()
+			sheets++
+			if outline {
+				drawOutlines(c, t)
+			}
+		}
+		pos %= perSheet
+		col := pos % t.Cols
+		row := pos / t.Cols
+		x := t.Left + float64(col)*(t.Width+t.HGap)
+		y := t.Top + float64(row)*(t.Height+t.VGap)
+
+		drawAddress(c, address, x, y, t.Width, t.Height)
+	}
+
+	err := c.WriteToFile(outputPath)
+	if err != nil {
+		return 0, err
+	}
+
+	return sheets, nil
+}
+
+// drawOutlines draws the outlines of all labels on the current sheet.
+func drawOutlines(c *creator.Creator, t labelTemplate) {
+	for row := 0; row < t.Rows; row++ {
+		for col := 0; col < t.Cols; col++ {
+			x := t.Left + float64(col)*(t.Width+t.HGap)
+			y := t.Top + float64(row)*(t.Height+t.VGap)
+			rect := creator.NewRectangle(x, y, t.Width, t.Height)
+			rect.SetBorderColor(creator.ColorRGBFrom8bit(180, 180, 180))
+			rect.SetBorderWidth(0.5)
+			_ = c.Draw(rect)
+		}
+	}
+}
+
+// drawAddress draws the address lines in the label at x, y, reducing the font size until the lines fit.  Lines that
+// are still too wide at the minimum font size extend past the label padding.
+func drawAddress(c *creator.Creator, lines []string, x, y, width, height float64) {
+	font := fonts.NewFontHelvetica()
+	fontSize := maxFontSize
+	for ; fontSize > minFontSize; fontSize -= 0.5 {
+		if addressFits(lines, font, fontSize, width-2*labelPadding, height) {
+			break
+		}
+	}
+
+	lineHeight := lineSpacing * fontSize
+	textY := y + (height-float64(len(lines))*lineHeight)/2
+	for i, line := range lines {
+		p := newLine(line, font, fontSize)
+		p.SetPos(x+labelPadding, textY+float64(i)*lineHeight)
+		_ = c.Draw(p)
+	}
+}
+
+func addressFits(lines []string, font fonts.Font, fontSize, width, height float64) bool {
+	if float64(len(lines))*lineSpacing*fontSize > height {
+		return false
+	}
+	for _, line := range lines {
+		if newLine(line, font, fontSize).Width() > width {
+			return false
+		}
+	}
+	return true
+}
+
+func newLine(text string, font fonts.Font, fontSize float64) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetLineHeight(lineSpacing)
+	p.SetEnableWrap(false)
+	return p
+}
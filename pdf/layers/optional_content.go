@@ -0,0 +1,400 @@
+/*
+ * Create a PDF file with layers (optional content groups, OCGs) that can be shown and hidden in the viewer: a "Draft"
+ * layer with a DRAFT watermark behind the text of each page, and an "Annotations" layer with review marks and notes
+ * on top of it.  The text of the pages is not in a layer and is always shown.
+ *
+ * Each layer is an OCG dictionary with the layer name.  The content of a layer is marked in the content stream of the
+ * page as a marked content sequence with the OC tag, "/OC /Layer1 BDC ... EMC", where Layer1 is the name of the OCG
+ * in the Properties of the page resources.  The OCGs are listed in the optional content properties (OCProperties) of
+ * the catalog, with the default configuration: the order of the layers in the layers panel, and the layers that are
+ * visible (ON) or hidden (OFF) when the document is opened.  The layers given by -hide are hidden, the others
+ * visible.  The page mode opens the layers panel.
+ *
+ * The pages are created with the creator, and the layer content is added to their content streams.  The optional
+ * content properties are added to the catalog in an incremental update, as the model does not give access to the
+ * catalog; the update is written by writeUpdate in update.go.  Optional content requires PDF 1.5: viewers that do
+ * not support it show all layers.
+ *
+ * Run as: go run optional_content.go update.go [-hide Annotations] output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run optional_content.go update.go [-hide Annotations] output.pdf\n"
+
+const margin = 72.0
+
+// layer is an optional content group with the name shown in the layers panel, and its property name in the page
+// resources.
+type layer struct {
+	name    string
+	tag     pdfcore.PdfObjectName
+	visible bool
+	ocg     *pdfcore.PdfIndirectObject
+}
+
+// The text of the pages, a paragraph per string.
+var pages = [][]string{
+	{
+		"This proposal describes the migration of the document archive to the new storage system. The archive " +
+			"holds about 1.2 million documents, most of them scanned letters and contracts.",
+		"The migration is done in three phases of four weeks each. In the first phase, the documents are " +
+			"converted to PDF/A and checked. In the second phase, they are moved to the new system, and in the " +
+			"third phase the old system is shut down.",
+		"The total cost is estimated at 180,000 euros, of which 40,000 euros for the conversion software.",
+	},
+	{
+		"Risks: the conversion of damaged scans may fail, and these documents have to be scanned again. We " +
+			"expect this for less than one percent of the documents.",
+		"During the migration, the archive remains available. Documents that are being moved can be read, but " +
+			"not changed.",
+	},
+}
+
+// review is a review mark of a paragraph: a change bar, or an ellipse around it if circle is set, with a note.
+type review struct {
+	paragraph int
+	circle    bool
+	note      string
+}
+
+// The review marks of the pages, in the Annotations layer.
+var reviews = [][]review{
+	{
+		{paragraph: 1, note: "Phases too short? Four weeks for a million documents."},
+		{paragraph: 2, circle: true, note: "Check the cost with finance."},
+	},
+	{
+		{paragraph: 0, note: "Add a fallback plan for documents that cannot be scanned again."},
+	},
+}
+
+func main() {
+	hide := ""
+	flag.StringVar(&hide, "hide", "Annotations", "Comma separated layers hidden when the document is opened")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	layers := []*layer{
+		{name: "Draft", tag: "Layer1", visible: true},
+		{name: "Annotations", tag: "Layer2", visible: true},
+	}
+	for _, name := range strings.Split(hide, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, l := range layers {
+			if strings.EqualFold(l.name, name) {
+				l.visible = false
+				found = true
+			}
+		}
+		if !found {
+			fmt.Printf("Error: Unknown layer %q, the layers are Draft and Annotations\n", name)
+			os.Exit(1)
+		}
+	}
+
+	err := createLayeredPdf(outputPath, layers)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createLayeredPdf(outputPath string, layers []*layer) error {
+	c := creator.New()
+	// Optional content was introduced in PDF 1.5.
+	c.SetPdfWriterAccessFunc(func(w *pdf.PdfWriter) error {
+		w.SetVersion(1, 5)
+		return nil
+	})
+
+	for _, l := range layers {
+		ocg := pdfcore.MakeDict()
+		ocg.Set("Type", pdfcore.MakeName("OCG"))
+		ocg.Set("Name", pdfcore.MakeString(l.name))
+		l.ocg = pdfcore.MakeIndirectObject(ocg)
+	}
+	draft, annotations := layers[0], layers[1]
+
+	// The fonts of the layer content.
+	helveticaBold := pdfcore.MakeDict()
+	helveticaBold.Set("Type", pdfcore.MakeName("Font"))
+	helveticaBold.Set("Subtype", pdfcore.MakeName("Type1"))
+	helveticaBold.Set("BaseFont", pdfcore.MakeName("Helvetica-Bold"))
+	helveticaBold.Set("Encoding", pdfcore.MakeName("WinAnsiEncoding"))
+	helveticaBoldObj := pdfcore.MakeIndirectObject(helveticaBold)
+
+	for i, texts := range pages {
+		// The pages are created here rather than with c.NewPage, to be able to add the layer content to them.
+		page := pdf.NewPdfPage()
+		page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: c.Width(), Ury: c.Height()}
+		page.Resources = pdf.NewPdfPageResources()
+		err := c.AddPage(page)
+		if err != nil {
+			return err
+		}
+
+		// The text, with the box of each paragraph from the upper left corner of the page.
+		y := margin
+		if i == 0 {
+			title := creator.NewParagraph("Archive migration proposal")
+			title.SetFont(fonts.NewFontHelveticaBold())
+			title.SetFontSize(20)
+			title.SetPos(margin, y)
+			err = c.Draw(title)
+			if err != nil {
+				return err
+			}
+			y += title.Height() + 18
+		}
+		boxes := []pdf.PdfRectangle{}
+		for _, text := range texts {
+			p := creator.NewParagraph(text)
+			p.SetFont(fonts.NewFontHelvetica())
+			p.SetFontSize(12)
+			p.SetLineHeight(1.3)
+			p.SetWidth(c.Width() - 2*margin)
+			p.SetPos(margin, y)
+			err = c.Draw(p)
+			if err != nil {
+				return err
+			}
+			boxes = append(boxes, pdf.PdfRectangle{Llx: margin, Lly: y, Urx: c.Width() - margin,
+				Ury: y + p.Height()})
+			y += p.Height() + 12
+		}
+
+		// The layer content, in PDF coordinates.  The review marks are numbered in the right margin, with the
+		// numbered notes below the text.
+		height := page.MediaBox.Ury
+		draftContent := watermarkContent(page.MediaBox)
+		notes := []string{}
+		y += 12
+		for j, r := range reviews[i] {
+			box := boxes[r.paragraph]
+			// The text of the paragraph is a little lower than its box, because of the line height.
+			top, bottom := height-box.Lly-3, height-box.Ury-3
+			label := strconv.Itoa(j + 1)
+			if r.circle {
+				notes = append(notes, ellipseMark(box.Llx-10, bottom-10, box.Urx-box.Llx+20, top-bottom+20))
+			} else {
+				notes = append(notes, barMark(box.Urx+10, bottom, top))
+			}
+			notes = append(notes, noteText(label, box.Urx+16, top-8))
+			notes = append(notes, noteText(label+"  "+r.note, margin, height-y-10))
+			y += 16
+		}
+
+		page.Resources.SetFontByName("LayerFont", helveticaBoldObj)
+		properties := pdfcore.MakeDict()
+		properties.Set(draft.tag, draft.ocg)
+		properties.Set(annotations.tag, annotations.ocg)
+		page.Resources.Properties = properties
+
+		// The draft watermark is drawn behind the text, the annotations on top of it.
+		contents, err := page.GetContentStreams()
+		if err != nil {
+			return err
+		}
+		contents = append([]string{markLayer(draft, draftContent)}, contents...)
+		contents = append(contents, markLayer(annotations, strings.Join(notes, "")))
+		err = page.SetContentStreams(contents, pdfcore.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+	}
+
+	err := c.WriteToFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	return addOptionalContentProperties(outputPath, layers)
+}
+
+// Returns the content of the layer as a marked content sequence tagged with the OCG, in its own graphics state.
+func markLayer(l *layer, content string) string {
+	return fmt.Sprintf("/OC /%s BDC\nq\n%sQ\nEMC\n", l.tag, content)
+}
+
+// Returns the content of a large light red DRAFT, diagonal across the page.
+func watermarkContent(mediaBox *pdf.PdfRectangle) string {
+	width := mediaBox.Urx - mediaBox.Llx
+	height := mediaBox.Ury - mediaBox.Lly
+	fontSize := 120.0
+	// Width of DRAFT in Helvetica-Bold: D 722, R 722, A 722, F 611, T 611 per 1000.
+	textWidth := (722 + 722 + 722 + 611 + 611) * fontSize / 1000
+	angle := math.Atan2(height, width)
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	// Start so that the center of the text is at the center of the page.
+	x := mediaBox.Llx + width/2 - cos*textWidth/2 + sin*fontSize*0.35
+	y := mediaBox.Lly + height/2 - sin*textWidth/2 - cos*fontSize*0.35
+	return fmt.Sprintf("1 0.85 0.85 rg\nBT /LayerFont %g Tf %.4f %.4f %.4f %.4f %.2f %.2f Tm (DRAFT) Tj ET\n",
+		fontSize, cos, sin, -sin, cos, x, y)
+}
+
+// Returns the content of a red ellipse around the box with the lower left corner at (x, y).
+func ellipseMark(x, y, width, height float64) string {
+	rx, ry := width/2, height/2
+	cx, cy := x+rx, y+ry
+	kx, ky := 0.5523*rx, 0.5523*ry
+	return "0.85 0 0 RG 1.5 w\n" +
+		fmt.Sprintf("%.2f %.2f m\n", cx+rx, cy) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx+rx, cy+ky, cx+kx, cy+ry, cx, cy+ry) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx-kx, cy+ry, cx-rx, cy+ky, cx-rx, cy) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx-rx, cy-ky, cx-kx, cy-ry, cx, cy-ry) +
+		fmt.Sprintf("%.2f %.2f %.2f %.2f %.2f %.2f c\n", cx+kx, cy-ry, cx+rx, cy-ky, cx+rx, cy) +
+		"S\n"
+}
+
+// Returns the content of a red change bar at x, from y1 to y2.
+func barMark(x, y1, y2 float64) string {
+	return fmt.Sprintf("0.85 0 0 RG 3 w\n%.2f %.2f m %.2f %.2f l S\n", x, y1, x, y2)
+}
+
+// Returns the content of a red note text with the baseline starting at (x, y).
+func noteText(text string, x, y float64) string {
+	return fmt.Sprintf("0.85 0 0 rg\nBT /LayerFont 10 Tf %.2f %.2f Td %s Tj ET\n", x, y,
+		pdfcore.MakeString(text).DefaultWriteString())
+}
+
+// Adds the optional content properties to the catalog of the PDF file in an incremental update: the OCGs of the
+// layers, and the default configuration with their order in the layers panel and their visibility.
+func addOptionalContentProperties(path string, layers []*layer) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	rootRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return errors.New("Missing Root in trailer")
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	catalogObj, err := pdfReader.GetIndirectObjectByNumber(int(rootRef.ObjectNumber))
+	if err != nil {
+		return err
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(catalogObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Catalog not a dictionary")
+	}
+
+	// The object numbers of the OCGs as written, found by their names.
+	numbers := map[string]int64{}
+	for num := 1; num < int(*size); num++ {
+		obj, err := pdfReader.GetIndirectObjectByNumber(num)
+		if err != nil {
+			continue
+		}
+		dict, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		if t, ok := dict.Get("Type").(*pdfcore.PdfObjectName); !ok || *t != "OCG" {
+			continue
+		}
+		if name, ok := pdfcore.TraceToDirectObject(dict.Get("Name")).(*pdfcore.PdfObjectString); ok {
+			numbers[string(*name)] = int64(num)
+		}
+	}
+
+	ocgs := pdfcore.PdfObjectArray{}
+	on := pdfcore.PdfObjectArray{}
+	off := pdfcore.PdfObjectArray{}
+	for _, l := range layers {
+		num, ok := numbers[l.name]
+		if !ok {
+			return fmt.Errorf("Layer %s not found in the output", l.name)
+		}
+		ref := &pdfcore.PdfObjectReference{ObjectNumber: num}
+		ocgs = append(ocgs, ref)
+		if l.visible {
+			on = append(on, ref)
+			fmt.Printf("Layer %s: visible\n", l.name)
+		} else {
+			off = append(off, ref)
+			fmt.Printf("Layer %s: hidden\n", l.name)
+		}
+	}
+
+	config := pdfcore.MakeDict()
+	config.Set("Name", pdfcore.MakeString("Default"))
+	config.Set("BaseState", pdfcore.MakeName("ON"))
+	config.Set("Order", &ocgs)
+	if len(on) > 0 {
+		config.Set("ON", &on)
+	}
+	if len(off) > 0 {
+		config.Set("OFF", &off)
+	}
+	properties := pdfcore.MakeDict()
+	properties.Set("OCGs", &ocgs)
+	properties.Set("D", config)
+	catalog.Set("OCProperties", properties)
+	catalog.Set("PageMode", pdfcore.MakeName("UseOC"))
+
+	objects := []updateObject{{number: rootRef.ObjectNumber, obj: catalog}}
+	update, err := writeUpdate(data, objects, trailer, int64(*size))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(update)
+	return err
+}
@@ -0,0 +1,137 @@
+/*
+ * Incremental update writer of optional_content.go, which changes a PDF file by appending an incremental update to the
+ * unchanged original bytes, and is run together with this file:
+ *   go run optional_content.go update.go ...
+ *
+ * This is a copy of pdf/incremental/update.go.  Changes are made there and copied here, and
+ * pdf/testing/check_copies.go checks that the copies are the same.
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+)
+
+// updateObject is an object written in the incremental update.
+type updateObject struct {
+	number      int64
+	generation  int64
+	obj         pdfcore.PdfObject
+	description string
+}
+
+// Returns the incremental update for the file data: the objects, a cross-reference section in the same form as the
+// previous one, and the trailer.  The Root, Info and ID entries of the new trailer are taken from trailer.  size is
+// the number of objects including the new ones.
+func writeUpdate(data []byte, objects []updateObject, trailer *pdfcore.PdfObjectDictionary, size int64) ([]byte,
+	error) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].number < objects[j].number })
+	prevOffset := previousXrefOffset(data)
+	if prevOffset < 0 || prevOffset >= len(data) {
+		return nil, errors.New("Invalid startxref")
+	}
+	xrefStream := !bytes.HasPrefix(data[prevOffset:], []byte("xref"))
+
+	var buf bytes.Buffer
+	// The update starts on a new line.
+	if len(data) > 0 && data[len(data)-1] != '\n' && data[len(data)-1] != '\r' {
+		buf.WriteString("\n")
+	}
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = len(data) + buf.Len()
+		// The string of a stream object is a reference to it: the dictionary and data are written instead.
+		if stream, ok := obj.obj.(*pdfcore.PdfObjectStream); ok {
+			stream.PdfObjectDictionary.Set("Length", pdfcore.MakeInteger(int64(len(stream.Stream))))
+			buf.WriteString(fmt.Sprintf("%d %d obj\n%s\nstream\n", obj.number, obj.generation,
+				stream.PdfObjectDictionary.DefaultWriteString()))
+			buf.Write(stream.Stream)
+			buf.WriteString("\nendstream\nendobj\n")
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%d %d obj\n%s\nendobj\n", obj.number, obj.generation,
+			obj.obj.DefaultWriteString()))
+	}
+
+	// The new trailer: the entries of the previous trailer that identify the document.
+	newTrailer := pdfcore.MakeDict()
+	for _, key := range []pdfcore.PdfObjectName{"Root", "Info", "ID"} {
+		if v := trailer.Get(key); v != nil {
+			newTrailer.Set(key, v)
+		}
+	}
+	newTrailer.Set("Prev", pdfcore.MakeInteger(int64(prevOffset)))
+
+	xrefOffset := len(data) + buf.Len()
+	if !xrefStream {
+		// One subsection per object, as the object numbers are not contiguous.
+		buf.WriteString("xref\n")
+		for i, obj := range objects {
+			buf.WriteString(fmt.Sprintf("%d 1\n%010d %05d n\r\n", obj.number, offsets[i], obj.generation))
+		}
+		newTrailer.Set("Size", pdfcore.MakeInteger(size))
+		buf.WriteString("trailer\n")
+		buf.WriteString(newTrailer.DefaultWriteString())
+		buf.WriteString("\n")
+	} else {
+		// The cross-reference stream is an object itself, with an entry for itself.  Entries are 1 byte type (1:
+		// in use), 4 bytes offset and 2 bytes generation.
+		xrefNum := size
+		objects = append(objects, updateObject{number: xrefNum})
+		offsets = append(offsets, xrefOffset)
+
+		var entries bytes.Buffer
+		index := []int64{}
+		for i, obj := range objects {
+			entries.WriteByte(1)
+			binary.Write(&entries, binary.BigEndian, uint32(offsets[i]))
+			binary.Write(&entries, binary.BigEndian, uint16(obj.generation))
+			index = append(index, obj.number, 1)
+		}
+
+		stream, err := pdfcore.MakeStream(entries.Bytes(), pdfcore.NewFlateEncoder())
+		if err != nil {
+			return nil, err
+		}
+		xrefDict := stream.PdfObjectDictionary
+		xrefDict.Set("Type", pdfcore.MakeName("XRef"))
+		xrefDict.Set("Size", pdfcore.MakeInteger(size+1))
+		xrefDict.Set("Index", pdfcore.MakeArrayFromIntegers64(index))
+		xrefDict.Set("W", pdfcore.MakeArrayFromIntegers([]int{1, 4, 2}))
+		xrefDict.Merge(newTrailer)
+		buf.WriteString(fmt.Sprintf("%d 0 obj\n%s\nstream\n", xrefNum, xrefDict.DefaultWriteString()))
+		buf.Write(stream.Stream)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefOffset))
+
+	return buf.Bytes(), nil
+}
+
+// Returns the offset of the last cross-reference section of the file data, from the startxref at the end, or -1 if
+// not found.
+func previousXrefOffset(data []byte) int {
+	idx := bytes.LastIndex(data, []byte("startxref"))
+	if idx < 0 {
+		return -1
+	}
+	fields := strings.Fields(string(data[idx+len("startxref"):]))
+	if len(fields) == 0 {
+		return -1
+	}
+	offset, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return -1
+	}
+	return offset
+}
@@ -0,0 +1,605 @@
+/*
+ * Typeset text with footnotes: superscript markers in the body text refer to numbered notes at the bottom of the same
+ * page, below a short separator rule.
+ *
+ * The text is laid out line by line.  When a line with footnote markers is placed, the space of its footnotes is
+ * reserved at the bottom of the page, so the body text of the page gets shorter as footnotes are added.  A line whose
+ * footnotes do not fit on the rest of the page is moved to the next page together with its footnotes.  A long
+ * footnote is split instead if at least a couple of its lines fit: the rest of it continues at the bottom of the next
+ * page, above the footnotes of that page.
+ *
+ * The markers are written in the text as [^key] and refer to the footnotes by key.  The footnotes are numbered in the
+ * order of their markers.
+ *
+ * Run as: go run footnotes.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+const pageMargin = 72.0
+
+// Font sizes and line heights (in points) of the body text and the footnotes.
+const (
+	bodyFontSize   = 11.0
+	bodyLineHeight = 15.0
+	noteFontSize   = 8.5
+	noteLineHeight = 11.0
+	paragraphGap   = 7.0
+)
+
+// Superscript markers are set in a smaller size and raised from the baseline, relative to the font size.
+const (
+	markerScale = 0.65
+	markerRise  = 0.35
+)
+
+// separatorSpace is the space taken by the separator rule above the footnotes, and minSplitLines the smallest number
+// of lines of a footnote that is left at the bottom of a page when the footnote is split.
+const (
+	separatorSpace = 14.0
+	minSplitLines  = 2
+)
+
+// footnote is a note referred to from the body text, with its number and text wrapped into lines.
+type footnote struct {
+	key    string
+	text   string
+	number int
+	lines  []textLine
+}
+
+// textRun is a piece of text: normal text, or the superscript marker of a footnote.
+type textRun struct {
+	text string
+	note *footnote
+}
+
+// textLine is a line of words (each a list of runs, e.g. a word and the marker after it) and the footnotes whose
+// markers are on the line.
+type textLine struct {
+	words [][]textRun
+	notes []*footnote
+}
+
+// document is the text to typeset: the paragraphs and the footnotes by key.
+type document struct {
+	title      string
+	paragraphs []string
+	footnotes  map[string]string
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run footnotes.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := typeset(sampleDocument(), outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func typeset(doc document, outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(pageMargin, pageMargin, pageMargin, pageMargin)
+	c.DrawFooter(func(block *creator.Block, args creator.FooterFunctionArgs) {
+		p := creator.NewParagraph(fmt.Sprintf("%d", args.PageNum))
+		p.SetFont(fonts.NewFontTimesRoman())
+		p.SetFontSize(9)
+		p.SetWidth(c.Width())
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		p.SetPos(0, block.Height()-40)
+		_ = block.Draw(p)
+	})
+
+	l := newFootnoteLayout(c)
+	l.placeHeading(doc.title)
+
+	numbered := []*footnote{}
+	used := map[string]*footnote{}
+	for _, text := range doc.paragraphs {
+		runs, err := parseMarkers(text, doc.footnotes, used, &numbered)
+		if err != nil {
+			return err
+		}
+		lines, err := wrapRuns(runs, bodyFontSize, l.width)
+		if err != nil {
+			return err
+		}
+
+		// Wrap the footnotes of the paragraph before placing its lines, the layout needs their heights.
+		for _, note := range numbered {
+			if note.lines != nil {
+				continue
+			}
+			runs := []textRun{{text: fmt.Sprintf("%d", note.number), note: note}, {text: " " + note.text}}
+			note.lines, err = wrapRuns(runs, noteFontSize, l.width)
+			if err != nil {
+				return err
+			}
+			// The number at the start of the footnote does not refer to it again.
+			note.lines[0].notes = nil
+		}
+
+		for i, line := range lines {
+			gap := 0.0
+			if i == 0 {
+				gap = paragraphGap
+			}
+			err := l.placeLine(line, gap)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for key := range doc.footnotes {
+		if used[key] == nil {
+			fmt.Printf("Warning: footnote %q is not referenced in the text\n", key)
+		}
+	}
+
+	err := l.finish()
+	if err != nil {
+		return err
+	}
+	return c.WriteToFile(outputPath)
+}
+
+// footnoteLayout places the lines of the body text and the footnotes on the pages.  The contents of a page are
+// collected until the page is full and then drawn.
+type footnoteLayout struct {
+	c           *creator.Creator
+	left, width float64
+	top, bottom float64
+
+	y     float64            // Top of the next body line.
+	body  []creator.Drawable // Body text of the current page.
+	notes []textLine         // Footnote lines of the current page.
+	carry []textLine         // Lines of a footnote that continue on the next page.
+}
+
+func newFootnoteLayout(c *creator.Creator) *footnoteLayout {
+	l := &footnoteLayout{
+		c:      c,
+		left:   pageMargin,
+		width:  c.Width() - 2*pageMargin,
+		top:    pageMargin,
+		bottom: c.Height() - pageMargin,
+	}
+	l.y = l.top
+	return l
+}
+
+// notesHeight returns the height of the footnote area with n lines: the separator and the lines.
+func notesHeight(n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return separatorSpace + float64(n)*noteLineHeight
+}
+
+// placeHeading places a heading at the current position.
+func (l *footnoteLayout) placeHeading(text string) {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontTimesBold())
+	p.SetFontSize(18)
+	p.SetEnableWrap(false)
+	p.SetPos(l.left, l.y)
+	l.body = append(l.body, p)
+	l.y += 30
+}
+
+// placeLine places a line of body text, gap points below the previous line unless it is the first line of the page,
+// and reserves the space of the footnotes whose markers are on the line.  If the footnotes do not fit, the last of
+// them is split when at least minSplitLines of it fit, otherwise the line moves to the next page.
+func (l *footnoteLayout) placeLine(line textLine, gap float64) error {
+	for {
+		y := l.y
+		if len(l.body) > 0 {
+			y += gap
+		}
+
+		noteLines := len(l.notes)
+		for _, note := range line.notes {
+			noteLines += len(note.lines)
+		}
+		if y+bodyLineHeight <= l.bottom-notesHeight(noteLines) {
+			l.addLine(line, y)
+			for _, note := range line.notes {
+				l.notes = append(l.notes, note.lines...)
+			}
+			return nil
+		}
+
+		if n := len(line.notes); n > 0 {
+			last := line.notes[n-1]
+			before := noteLines - len(last.lines)
+			fit := int((l.bottom-y-bodyLineHeight-separatorSpace)/noteLineHeight) - before
+			if fit >= minSplitLines || (fit > 0 && len(l.body) == 0) {
+				l.addLine(line, y)
+				for _, note := range line.notes[:n-1] {
+					l.notes = append(l.notes, note.lines...)
+				}
+				l.notes = append(l.notes, last.lines[:fit]...)
+				l.carry = append(l.carry, last.lines[fit:]...)
+				return l.newPage()
+			}
+		}
+
+		if len(l.body) == 0 {
+			return fmt.Errorf("A line with footnotes %s does not fit on a page", noteNumbers(line.notes))
+		}
+		err := l.newPage()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// addLine adds a body line at the position y.
+func (l *footnoteLayout) addLine(line textLine, y float64) {
+	l.body = append(l.body, &lineText{line: line, x: l.left, y: y, fontSize: bodyFontSize,
+		lineHeight: bodyLineHeight})
+	l.y = y + bodyLineHeight
+}
+
+// newPage draws the current page and starts the next one.  The footnote lines carried over from the current page go
+// first in the footnotes of the next page, up to half of the page; the rest is carried further.
+func (l *footnoteLayout) newPage() error {
+	err := l.drawPage()
+	if err != nil {
+		return err
+	}
+
+	l.y = l.top
+	l.body = nil
+	l.notes = nil
+
+	maxLines := int(((l.bottom-l.top)/2 - separatorSpace) / noteLineHeight)
+	if len(l.carry) > maxLines {
+		l.notes = l.carry[:maxLines]
+		l.carry = l.carry[maxLines:]
+	} else {
+		l.notes = l.carry
+		l.carry = nil
+	}
+	return nil
+}
+
+// finish draws the last page, and more pages for the rest of a footnote that continues from it.
+func (l *footnoteLayout) finish() error {
+	err := l.drawPage()
+	if err != nil {
+		return err
+	}
+	for len(l.carry) > 0 {
+		l.body = nil
+		l.notes = nil
+		err := l.newPage()
+		if err != nil {
+			return err
+		}
+		err = l.drawPage()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawPage draws the body text of the current page and the footnotes at the bottom of it.
+func (l *footnoteLayout) drawPage() error {
+	l.c.NewPage()
+	for _, d := range l.body {
+		err := l.c.Draw(d)
+		if err != nil {
+			return err
+		}
+	}
+	if len(l.notes) == 0 {
+		return nil
+	}
+
+	y := l.bottom - notesHeight(len(l.notes))
+	rule := creator.NewLine(l.left, y+separatorSpace/2, l.left+l.width/3, y+separatorSpace/2)
+	rule.SetLineWidth(0.5)
+	err := l.c.Draw(rule)
+	if err != nil {
+		return err
+	}
+
+	y += separatorSpace
+	for _, line := range l.notes {
+		err := l.c.Draw(&lineText{line: line, x: l.left, y: y, fontSize: noteFontSize, lineHeight: noteLineHeight})
+		if err != nil {
+			return err
+		}
+		y += noteLineHeight
+	}
+	return nil
+}
+
+// noteNumbers returns the numbers of the footnotes as text, e.g. "1, 2".
+func noteNumbers(notes []*footnote) string {
+	numbers := []string{}
+	for _, note := range notes {
+		numbers = append(numbers, fmt.Sprintf("%d", note.number))
+	}
+	return strings.Join(numbers, ", ")
+}
+
+// parseMarkers splits the text into runs at the footnote markers [^key].  The footnotes are numbered in the order
+// of their first marker and appended to numbered; used maps the keys to the numbered footnotes.
+func parseMarkers(text string, footnotes map[string]string, used map[string]*footnote,
+	numbered *[]*footnote) ([]textRun, error) {
+	runs := []textRun{}
+	for {
+		start := strings.Index(text, "[^")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(text[start:], "]")
+		if end < 0 {
+			return nil, fmt.Errorf("Missing ] after footnote marker in %q", text[start:])
+		}
+		key := text[start+2 : start+end]
+		noteText, ok := footnotes[key]
+		if !ok {
+			return nil, fmt.Errorf("Unknown footnote %q", key)
+		}
+		if used[key] != nil {
+			return nil, fmt.Errorf("Footnote %q is referenced more than once", key)
+		}
+
+		note := &footnote{key: key, text: noteText, number: len(*numbered) + 1}
+		used[key] = note
+		*numbered = append(*numbered, note)
+
+		runs = append(runs, textRun{text: text[:start]}, textRun{text: fmt.Sprintf("%d", note.number), note: note})
+		text = text[start+end+1:]
+	}
+	runs = append(runs, textRun{text: text})
+	return runs, nil
+}
+
+// runStyle returns the font size and text rise of a run.
+func runStyle(run textRun, fontSize float64) (float64, float64) {
+	if run.note != nil {
+		return markerScale * fontSize, markerRise * fontSize
+	}
+	return fontSize, 0
+}
+
+// textWidth returns the width of text in Times Roman of the given size.
+func textWidth(text string, fontSize float64) (float64, error) {
+	font := fonts.NewFontTimesRoman()
+	encoder := textencoding.NewWinAnsiTextEncoder()
+	width := 0.0
+	for _, r := range text {
+		glyph, found := encoder.RuneToGlyph(r)
+		if !found {
+			return 0, fmt.Errorf("Character %q is not supported by the text encoding", r)
+		}
+		metrics, found := font.GetGlyphCharMetrics(glyph)
+		if !found {
+			return 0, fmt.Errorf("Character %q (%s) is not in the font", r, glyph)
+		}
+		width += fontSize * metrics.Wx / 1000.0
+	}
+	return width, nil
+}
+
+// wrapRuns splits the runs into words at spaces and wraps the words into lines of the width.  A marker belongs to
+// the word it follows, so it is never moved to the next line on its own.
+func wrapRuns(runs []textRun, fontSize, width float64) ([]textLine, error) {
+	words := [][]textRun{}
+	word := []textRun{}
+	for _, run := range runs {
+		if run.note != nil {
+			word = append(word, run)
+			continue
+		}
+		for i, part := range strings.Split(run.text, " ") {
+			if i > 0 && len(word) > 0 {
+				words = append(words, word)
+				word = []textRun{}
+			}
+			if part != "" {
+				word = append(word, textRun{text: part})
+			}
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+
+	spaceWidth, err := textWidth(" ", fontSize)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []textLine{}
+	line := textLine{}
+	lineWidth := 0.0
+	for _, w := range words {
+		wordWidth := 0.0
+		for _, run := range w {
+			size, _ := runStyle(run, fontSize)
+			runWidth, err := textWidth(run.text, size)
+			if err != nil {
+				return nil, err
+			}
+			wordWidth += runWidth
+		}
+		if len(line.words) > 0 && lineWidth+spaceWidth+wordWidth > width {
+			lines = append(lines, line)
+			line = textLine{}
+			lineWidth = 0
+		}
+		if len(line.words) > 0 {
+			lineWidth += spaceWidth
+		}
+		line.words = append(line.words, w)
+		lineWidth += wordWidth
+
+		for _, run := range w {
+			if run.note != nil {
+				line.notes = append(line.notes, run.note)
+			}
+		}
+	}
+	if len(line.words) > 0 {
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// lineText is a line of text drawn at an absolute position, the top of the line at y.  Implements the creator
+// Drawable interface.
+type lineText struct {
+	line       textLine
+	x, y       float64
+	fontSize   float64
+	lineHeight float64
+}
+
+// GeneratePageBlocks draws the line on a block representing the page.  Implements the Drawable interface.
+func (lt *lineText) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	font := fonts.NewFontTimesRoman()
+	encoder := textencoding.NewWinAnsiTextEncoder()
+
+	resources := pdf.NewPdfPageResources()
+	err := resources.SetFontByName("F1", font.ToPdfObject())
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	// The baseline is placed so that the space above and below the text is shared like in a creator paragraph.
+	baseline := lt.y + lt.lineHeight - (lt.lineHeight-lt.fontSize)/2 - 0.2*lt.fontSize
+
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q()
+	cc.Add_BT()
+	cc.Add_Td(lt.x, ctx.PageHeight-baseline)
+	for i, word := range lt.line.words {
+		if i > 0 {
+			cc.Add_Tf("F1", lt.fontSize).Add_Ts(0)
+			cc.Add_Tj(pdfcore.PdfObjectString(encoder.Encode(" ")))
+		}
+		for _, run := range word {
+			size, rise := runStyle(run, lt.fontSize)
+			cc.Add_Tf("F1", size).Add_Ts(rise)
+			cc.Add_Tj(pdfcore.PdfObjectString(encoder.Encode(run.text)))
+		}
+	}
+	cc.Add_ET()
+	cc.Add_Q()
+
+	// Blocks with custom contents are created from a page with the contents.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = resources
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+// sampleDocument returns a short essay with footnotes of different lengths, including a long one that continues on
+// the next page.
+func sampleDocument() document {
+	return document{
+		title: "A Brief History of the Printed Page",
+		paragraphs: []string{
+			"Long before movable type, books were copied by hand in monasteries and, later, in the workshops of " +
+				"professional scribes.[^scribes] A single copy of a large book could take a scribe many months, and " +
+				"the cost of the parchment alone made books the possessions of churches, universities and the very " +
+				"rich. Block printing, in which a whole page was carved into a single piece of wood, was known in " +
+				"Europe from the early fifteenth century, but it was suited to pictures with short captions rather " +
+				"than to long texts.",
+			"The press that Johannes Gutenberg developed in Mainz around 1450 combined several inventions: type " +
+				"cast from metal in a hand mould, an oil-based ink that adhered to metal, and a screw press adapted " +
+				"from those used for wine and paper.[^press] None of these was entirely new on its own; the " +
+				"achievement lay in making them work together well enough to print a book of more than twelve " +
+				"hundred pages in an edition of some one hundred and eighty copies.",
+			"The new craft spread quickly along the trade routes of Europe. By 1500 presses were at work in more " +
+				"than two hundred and fifty towns, and they had produced perhaps twenty thousand editions, known " +
+				"today as incunabula.[^incunabula] Venice alone had over a hundred printing shops, and its printers " +
+				"set many of the conventions of the printed page that are still in use: roman and italic type, " +
+				"title pages, page numbers and the small pocket format that made books portable.",
+			"Printers soon learned that readers wanted more than the bare text. Marginal notes, which in " +
+				"manuscripts had been added by readers as well as scribes, became part of the printed page, and " +
+				"with the growth of scholarly editions in the sixteenth and seventeenth centuries the apparatus of " +
+				"references, glosses and commentary grew with them. The notes moved from the margins to the foot " +
+				"of the page, where they could be set in a smaller type across the full width of the text block.",
+			"The footnote in its modern form, a numbered note at the bottom of the page referred to by a small " +
+				"raised figure in the text, became common in the eighteenth century.[^gibbon] It lets an author " +
+				"support a statement, cite a source or add an aside without interrupting the argument, and it " +
+				"lets a reader decide whether to follow the digression or not. Typesetting footnotes was " +
+				"nevertheless one of the harder tasks of the compositor, since every note had to end up on the " +
+				"same page as its reference.",
+			"A compositor making up pages had to measure the text and the notes together. When the reference fell " +
+				"near the bottom of a page there was often no room for the whole note, and the compositor had to " +
+				"choose between moving the line with the reference to the next page and breaking the note.[^break] " +
+				"The same choices are made by typesetting programs today, which reserve space for the notes of " +
+				"each line as the page is filled and carry the rest of a long note over to the next page.",
+			"Mechanical typesetting arrived at the end of the nineteenth century with the Linotype and Monotype " +
+				"machines, which cast type from a keyboard and were many times faster than setting by hand.[^linotype] " +
+				"Photocomposition replaced hot metal in the second half of the twentieth century, and was in turn " +
+				"replaced by digital typesetting, in which the page is described by a program and rendered by the " +
+				"printer or the screen. Formats such as PDF describe the finished page exactly, with every glyph " +
+				"placed at its final position.",
+			"The conventions of the printed page have survived each of these changes. A reader opening a book " +
+				"printed today will find the same elements that a Venetian reader of 1500 would have recognized: " +
+				"the title page, the running text in roman type, the page numbers, and, at the foot of the page, " +
+				"the notes.[^survival]",
+		},
+		footnotes: map[string]string{
+			"scribes": "Professional scribes were organized in guilds in many cities by the fourteenth century.",
+			"press": "The screw press was used for pressing grapes and olives, and in papermaking to squeeze the " +
+				"water out of freshly made sheets.",
+			"incunabula": "From the Latin word for swaddling clothes or cradle, i.e. books from the infancy of " +
+				"printing. The term covers books printed in Europe before 1501.",
+			"gibbon": "Edward Gibbon's History of the Decline and Fall of the Roman Empire (1776-1789) is famous " +
+				"for its footnotes, many of them more entertaining than the main text.",
+			"break": "Manuals for compositors give rules for this case. A note should not be broken at all if it " +
+				"can be avoided, and the line with the reference should then be moved to the next page together " +
+				"with the note, even if this leaves the page a little short. If a note is too long to be moved, " +
+				"it is broken after at least two lines, never in the middle of a sentence if there is a choice, " +
+				"and the rest of it is set at the foot of the next page, above the notes that belong to that " +
+				"page. Some houses mark the continuation with a short rule across the full width of the text, " +
+				"or with the words \"continued\" at the end of the first part, so that the reader does not " +
+				"mistake the continuation for a note of its own. The notes of the following page are then set " +
+				"after the continued note in the usual way, and the page is made up as if the continued lines " +
+				"were a note of that page. When several long notes fall on the same page, each of them may have " +
+				"to be broken, and the compositor has to balance the length of the text block against the length " +
+				"of the notes on each of the pages involved.",
+			"linotype": "The Linotype cast a whole line of type as a single piece of metal, a slug, while the " +
+				"Monotype cast individual letters.",
+			"survival": "Even electronic books, whose pages are reflowed to fit the screen, usually keep the notes, " +
+				"although they are shown as pop-ups or at the end of a chapter.",
+		},
+	}
+}
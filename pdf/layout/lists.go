@@ -0,0 +1,290 @@
+/*
+ * Render nested bulleted and numbered lists with the creator.
+ *
+ * Each nesting level has its own style: ordered (1. 2. 3., a. b. c., i. ii. iii.) or unordered with a custom bullet
+ * glyph, and an indentation width.  Wrapped lines use a hanging indent, i.e. continuation lines are aligned with the
+ * item text rather than with the marker.  Ordered and unordered levels can be mixed freely.
+ *
+ * Deeply nested lists (5+ levels) are handled by shrinking the indentation of the deeper levels so that the item text
+ * always keeps a minimum width and never runs over the right page margin.
+ *
+ * Run as: go run lists.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+// Numbering styles for ordered list levels.
+const (
+	numberingDecimal = iota
+	numberingLowerAlpha
+	numberingLowerRoman
+)
+
+// ListLevelStyle defines how the items of a single nesting level are rendered.
+type ListLevelStyle struct {
+	Ordered   bool
+	Numbering int // One of the numbering* constants (ordered levels only).
+
+	// Bullet is the marker for unordered levels.  If Dingbat is set, the bullet is drawn with the ZapfDingbats font,
+	// otherwise with the text font (WinAnsi encoding).
+	Bullet  string
+	Dingbat bool
+
+	// Indent is the horizontal offset of the marker relative to the start of the parent item's text (relative to the
+	// left margin for the top level).
+	Indent float64
+	// MarkerWidth is the width reserved for the marker, i.e. the hanging indent of wrapped lines.
+	MarkerWidth float64
+}
+
+// ListItem is a single list entry, optionally with a nested sub-list.
+type ListItem struct {
+	Text     string
+	Children []*ListItem
+}
+
+// ListRenderer draws a tree of list items with the creator, flowing over pages as needed.
+type ListRenderer struct {
+	Levels   []ListLevelStyle
+	FontSize float64
+
+	// MinTextWidth is the minimum width kept for the item text at any depth.  The indentation of deep levels is
+	// reduced to honor it.
+	MinTextWidth float64
+
+	font fonts.Font
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run lists.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := renderLists(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func renderLists(outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(60, 60, 60, 60)
+	c.NewPage()
+
+	r := NewListRenderer()
+
+	heading := creator.NewParagraph("Lists with hanging indents")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 10)
+	c.Draw(heading)
+
+	// Mixed ordered and unordered levels.
+	items := []*ListItem{
+		{
+			Text: "Preparation. This item is deliberately long so that it wraps over several lines, demonstrating " +
+				"that the continuation lines line up with the text of the first line and not with the number.",
+			Children: []*ListItem{
+				{Text: "Gather the input documents."},
+				{
+					Text: "Check the page sizes and orientation of every input file before processing.",
+					Children: []*ListItem{
+						{Text: "Portrait pages."},
+						{Text: "Landscape pages, which may need to be rotated prior to merging with the rest."},
+					},
+				},
+			},
+		},
+		{Text: "Processing.", Children: []*ListItem{{Text: "Merge."}, {Text: "Compress."}}},
+		{Text: "Review the output."},
+	}
+	err := r.Draw(c, items)
+	if err != nil {
+		return err
+	}
+
+	// Deep nesting, to show that the text never runs into the right margin.
+	heading = creator.NewParagraph("Deeply nested list (8 levels)")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 20, 10)
+	c.Draw(heading)
+
+	err = r.Draw(c, makeDeepList(8))
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Creates a list nested depth levels deep with a long item on each level.
+func makeDeepList(depth int) []*ListItem {
+	if depth == 0 {
+		return nil
+	}
+	text := fmt.Sprintf("Level %d item with enough words in it to wrap onto a second line when the indentation "+
+		"gets large and the available width gets small.", 9-depth)
+	return []*ListItem{
+		{Text: text, Children: makeDeepList(depth - 1)},
+		{Text: "Sibling item."},
+	}
+}
+
+// NewListRenderer returns a ListRenderer with default level styles, alternating between numbered and bulleted levels.
+func NewListRenderer() *ListRenderer {
+	r := &ListRenderer{}
+	r.FontSize = 11
+	r.MinTextWidth = 150
+	r.font = fonts.NewFontHelvetica()
+	r.Levels = []ListLevelStyle{
+		{Ordered: true, Numbering: numberingDecimal, Indent: 0, MarkerWidth: 20},
+		{Bullet: "•", Indent: 4, MarkerWidth: 12},
+		{Ordered: true, Numbering: numberingLowerAlpha, Indent: 4, MarkerWidth: 18},
+		{Bullet: "➢", Dingbat: true, Indent: 4, MarkerWidth: 14},
+		{Ordered: true, Numbering: numberingLowerRoman, Indent: 4, MarkerWidth: 24},
+		{Bullet: "–", Indent: 4, MarkerWidth: 12},
+	}
+	return r
+}
+
+// Draw draws the list items on the creator at the current position.
+func (r *ListRenderer) Draw(c *creator.Creator, items []*ListItem) error {
+	return r.drawLevel(c, items, 0, 0)
+}
+
+// Returns the style for a nesting level.  Levels deeper than the defined styles reuse the styles cyclically.
+func (r *ListRenderer) levelStyle(level int) ListLevelStyle {
+	return r.Levels[level%len(r.Levels)]
+}
+
+func (r *ListRenderer) drawLevel(c *creator.Creator, items []*ListItem, level int, offset float64) error {
+	style := r.levelStyle(level)
+
+	// Limit the indentation so the text keeps at least MinTextWidth.  The limit is applied to the offset of the
+	// level, so all deeper levels get squeezed together rather than pushed off the page.
+	avail := c.Context().Width
+	maxOffset := avail - style.MarkerWidth - r.MinTextWidth
+	if maxOffset < 0 {
+		maxOffset = 0
+	}
+	offset += style.Indent
+	if offset > maxOffset {
+		offset = maxOffset
+	}
+
+	for idx, item := range items {
+		err := r.drawItem(c, item, r.marker(style, idx+1), style, offset)
+		if err != nil {
+			return err
+		}
+
+		if len(item.Children) > 0 {
+			err = r.drawLevel(c, item.Children, level+1, offset+style.MarkerWidth)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Draws a single item: the marker in the hanging indent area and the (wrapped) text next to it.
+func (r *ListRenderer) drawItem(c *creator.Creator, item *ListItem, marker string, style ListLevelStyle,
+	offset float64) error {
+	p := creator.NewParagraph(item.Text)
+	p.SetFont(r.font)
+	p.SetFontSize(r.FontSize)
+	p.SetLineHeight(1.2)
+	p.SetMargins(offset+style.MarkerWidth, 0, 0, 3)
+
+	// The marker is placed with absolute coordinates, so we need to know where the text will go.  If the text does
+	// not fit on the current page, the creator would move it to the next one: do the page break up front so the
+	// marker follows it.
+	ctx := c.Context()
+	p.SetWidth(ctx.Width - offset - style.MarkerWidth)
+	if p.Height()+3 > ctx.Height {
+		c.NewPage()
+		ctx = c.Context()
+	}
+
+	m := creator.NewParagraph(marker)
+	markerSize := r.FontSize
+	if style.Dingbat {
+		markerSize = 0.8 * r.FontSize
+		m.SetFont(fonts.NewFontZapfDingbats())
+		m.SetEncoder(textencoding.NewZapfDingbatsEncoder())
+	} else {
+		m.SetFont(r.font)
+	}
+	m.SetFontSize(markerSize)
+	// Put the marker on the same baseline as the first line of text.
+	m.SetLineHeight(1.2 * r.FontSize / markerSize)
+	m.SetEnableWrap(false)
+	m.SetPos(ctx.X+offset, ctx.Y)
+	err := c.Draw(m)
+	if err != nil {
+		return err
+	}
+
+	return c.Draw(p)
+}
+
+// Returns the marker string for the item number n (1-based) in a level.
+func (r *ListRenderer) marker(style ListLevelStyle, n int) string {
+	if !style.Ordered {
+		return style.Bullet
+	}
+
+	switch style.Numbering {
+	case numberingLowerAlpha:
+		return alphaNumber(n) + "."
+	case numberingLowerRoman:
+		return romanNumber(n) + "."
+	default:
+		return fmt.Sprintf("%d.", n)
+	}
+}
+
+// Converts n (1-based) to a, b, ..., z, aa, ab, ...
+func alphaNumber(n int) string {
+	s := ""
+	for n > 0 {
+		n--
+		s = string(rune('a'+n%26)) + s
+		n /= 26
+	}
+	return s
+}
+
+// Converts n to lower case roman numerals.
+func romanNumber(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"m", "cm", "d", "cd", "c", "xc", "l", "xl", "x", "ix", "v", "iv", "i"}
+
+	var sb strings.Builder
+	for i, v := range values {
+		for n >= v {
+			sb.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return sb.String()
+}
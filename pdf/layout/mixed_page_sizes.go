@@ -0,0 +1,341 @@
+/*
+ * Create a document with pages of different sizes and orientations: a portrait A4 report, a landscape A4 page with a
+ * wide table, a large fold-out page with a diagram, three A4 pages wide, and a portrait A4 appendix.
+ *
+ * The creator's page size applies to the pages created after it is set, so the size is switched with SetPageSize
+ * before calling NewPage.  Pages that the creator adds by itself, when content flows over the end of a page, get the
+ * current size too.  SetPageSize also resets the page margins to 10% of the page width, so the margins are set again
+ * after each size change.
+ *
+ * The header and footer are drawn with the DrawHeader and DrawFooter callbacks, which run when the document is
+ * written, after all pages are created.  In this unidoc version the callbacks get only the page number, and the
+ * header and footer blocks are created and positioned with the size of the last page.  So:
+ * - The page sizes are recorded as they change, by page number, to look up the size of the page in the callbacks.
+ *   The header and footer text is aligned to the width of that page, and the header names the orientation.
+ * - The footer block is placed at the bottom of the page, which is correct for any page height.  The header block
+ *   is placed at the top of a page of the last page height, so on pages of another height its content is moved by
+ *   the difference.
+ *
+ * Run as: go run mixed_page_sizes.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const pageMargin = 50.0
+
+var (
+	portraitA4  = creator.PageSizeA4
+	landscapeA4 = creator.PageSize{creator.PageSizeA4[1], creator.PageSizeA4[0]}
+	foldOut     = creator.PageSize{3 * creator.PageSizeA4[0], creator.PageSizeA4[1]} // Folds to A4 portrait.
+)
+
+var (
+	headerColor = creator.ColorRGBFrom8bit(44, 62, 80)
+	mutedColor  = creator.ColorRGBFrom8bit(120, 120, 120)
+	boxColor    = creator.ColorRGBFrom8bit(214, 226, 240)
+)
+
+// sizeChange records that the pages from page number FirstPage on have the size Size.
+type sizeChange struct {
+	FirstPage int
+	Size      creator.PageSize
+}
+
+// document creates pages of different sizes and keeps track of the size of each page.
+type document struct {
+	c       *creator.Creator
+	changes []sizeChange
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run mixed_page_sizes.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := createDocument(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createDocument(outputPath string) error {
+	d := &document{c: creator.New()}
+	c := d.c
+
+	c.DrawHeader(func(block *creator.Block, args creator.HeaderFunctionArgs) {
+		size := d.pageSize(args.PageNum)
+		// The header block is at the top of a page of the creator's current (last) page height.
+		offset := c.Height() - size[1]
+
+		title := newParagraph("Mixed page sizes", fonts.NewFontHelveticaBold(), 9, headerColor)
+		title.SetPos(pageMargin, 20+offset)
+		_ = block.Draw(title)
+
+		info := newParagraph(fmt.Sprintf("%s, %.0f x %.0f mm", orientation(size), size[0]*25.4/72,
+			size[1]*25.4/72), fonts.NewFontHelvetica(), 9, mutedColor)
+		info.SetWidth(size[0] - 2*pageMargin)
+		info.SetTextAlignment(creator.TextAlignmentRight)
+		info.SetPos(pageMargin, 20+offset)
+		_ = block.Draw(info)
+	})
+	c.DrawFooter(func(block *creator.Block, args creator.FooterFunctionArgs) {
+		size := d.pageSize(args.PageNum)
+
+		p := newParagraph(fmt.Sprintf("Page %d of %d", args.PageNum, args.TotalPages), fonts.NewFontHelvetica(), 9,
+			mutedColor)
+		p.SetWidth(size[0])
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		p.SetPos(0, block.Height()-25)
+		_ = block.Draw(p)
+	})
+
+	// Portrait report, long enough to continue on a second page.
+	d.newPage(portraitA4)
+	err := drawHeading(c, "Quarterly report")
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 14; i++ {
+		err := drawText(c, strings.Repeat(fmt.Sprintf("Paragraph %d of the report on portrait A4 pages.  The "+
+			"text flows over to the next page, which the creator adds with the current page size. ", i+1), 3))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Landscape page with a table too wide for portrait pages.
+	d.newPage(landscapeA4)
+	err = drawHeading(c, "Sales by month")
+	if err != nil {
+		return err
+	}
+	err = drawWideTable(c)
+	if err != nil {
+		return err
+	}
+
+	// Fold-out page with a diagram.
+	d.newPage(foldOut)
+	err = drawHeading(c, "Order process")
+	if err != nil {
+		return err
+	}
+	err = drawFoldOut(c)
+	if err != nil {
+		return err
+	}
+
+	// Portrait appendix.
+	d.newPage(portraitA4)
+	err = drawHeading(c, "Appendix")
+	if err != nil {
+		return err
+	}
+	err = drawText(c, "The appendix is back on portrait A4 pages.  Note that the header and footer of every page "+
+		"are aligned to the size of that page.")
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i <= c.Context().Page; i++ {
+		size := d.pageSize(i)
+		fmt.Printf("Page %d: %.0f x %.0f points, %s\n", i, size[0], size[1], orientation(size))
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// newPage starts a new page of the size, with the page margins.
+func (d *document) newPage(size creator.PageSize) {
+	d.c.SetPageSize(size)
+	d.c.SetPageMargins(pageMargin, pageMargin, pageMargin, pageMargin)
+	d.c.NewPage()
+	d.changes = append(d.changes, sizeChange{FirstPage: d.c.Context().Page, Size: size})
+}
+
+// pageSize returns the size of the page with the page number: the size set last before the page was created.
+func (d *document) pageSize(pageNum int) creator.PageSize {
+	size := d.changes[0].Size
+	for _, change := range d.changes {
+		if change.FirstPage > pageNum {
+			break
+		}
+		size = change.Size
+	}
+	return size
+}
+
+// orientation returns the name of the page orientation.
+func orientation(size creator.PageSize) string {
+	switch {
+	case size[0] > 2*size[1] || size[1] > 2*size[0]:
+		return "fold-out"
+	case size[0] > size[1]:
+		return "landscape"
+	}
+	return "portrait"
+}
+
+func drawHeading(c *creator.Creator, text string) error {
+	p := newParagraph(text, fonts.NewFontHelveticaBold(), 18, headerColor)
+	p.SetMargins(0, 0, 0, 12)
+	return c.Draw(p)
+}
+
+func drawText(c *creator.Creator, text string) error {
+	p := newParagraph(text, fonts.NewFontHelvetica(), 11, creator.ColorBlack)
+	p.SetLineHeight(1.3)
+	p.SetMargins(0, 0, 0, 10)
+	return c.Draw(p)
+}
+
+// Draws a table with a column for each month, which fits the width of a landscape page.
+func drawWideTable(c *creator.Creator) error {
+	months := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	products := []string{"Desk lamp", "Office chair", "Standing desk", "Monitor arm", "Bookshelf", "Whiteboard"}
+
+	table := creator.NewTable(1 + len(months))
+	widths := []float64{0.16}
+	for range months {
+		widths = append(widths, 0.84/float64(len(months)))
+	}
+	err := table.SetColumnWidths(widths...)
+	if err != nil {
+		return err
+	}
+
+	addCell := func(text string, header, right bool) error {
+		var font fonts.Font = fonts.NewFontHelvetica()
+		if header {
+			font = fonts.NewFontHelveticaBold()
+		}
+		cell := table.NewCell()
+		cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+		if header {
+			cell.SetBackgroundColor(boxColor)
+		}
+		if right {
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+		}
+		return cell.SetContent(newParagraph(text, font, 9, creator.ColorBlack))
+	}
+
+	err = addCell("Product", true, false)
+	if err != nil {
+		return err
+	}
+	for _, month := range months {
+		err := addCell(month, true, true)
+		if err != nil {
+			return err
+		}
+	}
+	table.SetRowHeight(table.CurRow(), 20)
+	for i, product := range products {
+		err := addCell(product, false, false)
+		if err != nil {
+			return err
+		}
+		for m := range months {
+			err := addCell(fmt.Sprintf("%d", 40+(i*37+m*53)%160), false, true)
+			if err != nil {
+				return err
+			}
+		}
+		table.SetRowHeight(table.CurRow(), 20)
+	}
+
+	return c.Draw(table)
+}
+
+// Draws a flow diagram across the fold-out page, with fold marks in the top and bottom margins where the page is
+// folded to the size of a portrait A4 page.
+func drawFoldOut(c *creator.Creator) error {
+	panelWidth := creator.PageSizeA4[0]
+	for x := panelWidth; x < c.Width()-1; x += panelWidth {
+		for _, y := range [][2]float64{{0, pageMargin / 2}, {c.Height() - pageMargin/2, c.Height()}} {
+			mark := creator.NewLine(x, y[0], x, y[1])
+			mark.SetLineWidth(0.5)
+			mark.SetColor(mutedColor)
+			err := c.Draw(mark)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	steps := []string{"Order received", "Payment check", "Stock check", "Picking", "Packing", "Label printing",
+		"Dispatch", "Delivery", "Invoice"}
+	width := c.Width() - 2*pageMargin
+	boxWidth, boxHeight := 120.0, 60.0
+	gap := (width - float64(len(steps))*boxWidth) / float64(len(steps)-1)
+	y := c.Height() / 2
+
+	for i, step := range steps {
+		x := pageMargin + float64(i)*(boxWidth+gap)
+		// Alternate the boxes above and below the middle line.
+		top := y - boxHeight - 20
+		if i%2 == 1 {
+			top = y + 20
+		}
+
+		box := creator.NewRectangle(x, top, boxWidth, boxHeight)
+		box.SetFillColor(boxColor)
+		box.SetBorderColor(headerColor)
+		box.SetBorderWidth(1)
+		err := c.Draw(box)
+		if err != nil {
+			return err
+		}
+		p := newParagraph(fmt.Sprintf("%d. %s", i+1, step), fonts.NewFontHelveticaBold(), 11, headerColor)
+		p.SetWidth(boxWidth)
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		p.SetPos(x, top+boxHeight/2-6)
+		err = c.Draw(p)
+		if err != nil {
+			return err
+		}
+
+		// Connector from the box to the middle line.
+		connectorY := top + boxHeight
+		if i%2 == 1 {
+			connectorY = top
+		}
+		line := creator.NewLine(x+boxWidth/2, connectorY, x+boxWidth/2, y)
+		line.SetLineWidth(1)
+		line.SetColor(headerColor)
+		err = c.Draw(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The middle line, from the first to the last step.
+	line := creator.NewLine(pageMargin+boxWidth/2, y, pageMargin+width-boxWidth/2, y)
+	line.SetLineWidth(2)
+	line.SetColor(headerColor)
+	return c.Draw(line)
+}
+
+func newParagraph(text string, font fonts.Font, fontSize float64, color creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetColor(color)
+	return p
+}
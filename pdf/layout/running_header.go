@@ -0,0 +1,217 @@
+/*
+ * Create a book-like document with running headers: the header of each page shows the title of the chapter on that
+ * page, and changes as the chapters change.  The footer shows the page number.
+ *
+ * The chapters follow each other without page breaks, so a chapter can start anywhere on a page.  The headers are
+ * drawn with the DrawHeader callback, which runs when the document is written, after the layout.  So the pages of the
+ * chapters are recorded during the layout: the page on which each chapter heading is drawn.  A heading that does not
+ * fit at the bottom of a page with the first paragraph of its text is moved to the next page, and the page is recorded after
+ * the move.
+ *
+ * The chapter of a page is chosen with the rule:
+ * - a page on which a chapter starts shows the new chapter, the first one if several chapters start on the page,
+ * - other pages show the chapter continued from the previous page,
+ * - pages before the first chapter show the book title only.
+ *
+ * Run as: go run running_header.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const pageMargin = 60.0
+
+const bookTitle = "A Short Guide to Home Gardening"
+
+var (
+	headerColor = creator.ColorRGBFrom8bit(44, 62, 80)
+	mutedColor  = creator.ColorRGBFrom8bit(120, 120, 120)
+	ruleColor   = creator.ColorRGBFrom8bit(180, 180, 180)
+)
+
+// chapter is a chapter of the book: the title and the paragraphs of text.
+type chapter struct {
+	Title      string
+	Paragraphs int
+}
+
+var chapters = []chapter{
+	{"Planning the Garden", 9},
+	{"Soil and Compost", 3},
+	{"Sowing", 1},
+	{"Watering", 2},
+	{"Pests and Diseases", 11},
+	{"Harvest and Storage", 4},
+}
+
+// chapterStart records that the chapter with the number (from 1) starts on the page.
+type chapterStart struct {
+	Page   int
+	Number int
+	Title  string
+}
+
+// book lays out the chapters and keeps track of the page on which each chapter starts.
+type book struct {
+	c      *creator.Creator
+	starts []chapterStart
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run running_header.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := createBook(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createBook(outputPath string) error {
+	b := &book{c: creator.New()}
+	c := b.c
+	c.SetPageMargins(pageMargin, pageMargin, pageMargin, pageMargin)
+
+	c.DrawHeader(func(block *creator.Block, args creator.HeaderFunctionArgs) {
+		title := newParagraph(bookTitle, fonts.NewFontHelvetica(), 9, mutedColor)
+		title.SetPos(pageMargin, 25)
+		_ = block.Draw(title)
+
+		if start, ok := b.pageChapter(args.PageNum); ok {
+			p := newParagraph(fmt.Sprintf("Chapter %d: %s", start.Number, start.Title), fonts.NewFontHelveticaBold(),
+				9, headerColor)
+			p.SetWidth(c.Width() - 2*pageMargin)
+			p.SetTextAlignment(creator.TextAlignmentRight)
+			p.SetPos(pageMargin, 25)
+			_ = block.Draw(p)
+		}
+
+		rule := creator.NewRectangle(pageMargin, 39, c.Width()-2*pageMargin, 0.5)
+		rule.SetFillColor(ruleColor)
+		_ = block.Draw(rule)
+	})
+	c.DrawFooter(func(block *creator.Block, args creator.FooterFunctionArgs) {
+		p := newParagraph(fmt.Sprintf("%d", args.PageNum), fonts.NewFontHelvetica(), 9, mutedColor)
+		p.SetWidth(c.Width())
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		p.SetPos(0, block.Height()-30)
+		_ = block.Draw(p)
+	})
+
+	// A title page without a chapter.
+	c.NewPage()
+	title := newParagraph(bookTitle, fonts.NewFontHelveticaBold(), 26, headerColor)
+	title.SetMargins(0, 0, 200, 0)
+	err := c.Draw(title)
+	if err != nil {
+		return err
+	}
+
+	// The chapters, starting on a new page after the title page and then following each other.
+	c.NewPage()
+	for i, ch := range chapters {
+		err := b.drawChapter(i+1, ch)
+		if err != nil {
+			return err
+		}
+	}
+
+	numPages := c.Context().Page
+	for page := 1; page <= numPages; page++ {
+		start, ok := b.pageChapter(page)
+		switch {
+		case !ok:
+			fmt.Printf("Page %d: no chapter\n", page)
+		case start.Page == page:
+			fmt.Printf("Page %d: chapter %d, %s (starts on this page)\n", page, start.Number, start.Title)
+		default:
+			fmt.Printf("Page %d: chapter %d, %s\n", page, start.Number, start.Title)
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawChapter draws the heading and text of the chapter and records the page on which it starts.  The heading is
+// kept with the first paragraph of the text: a paragraph that does not fit on the rest of the page is moved to the
+// next page, so if the heading and the paragraph do not fit together, the chapter starts on a new page.
+func (b *book) drawChapter(number int, ch chapter) error {
+	width := b.c.Width() - 2*pageMargin
+
+	heading := newParagraph(fmt.Sprintf("%d. %s", number, ch.Title), fonts.NewFontHelveticaBold(), 18, headerColor)
+	heading.SetMargins(0, 0, 18, 10)
+	heading.SetWidth(width)
+
+	var paragraphs []*creator.Paragraph
+	for i := 0; i < ch.Paragraphs; i++ {
+		p := newParagraph(strings.Repeat(fmt.Sprintf("Paragraph %d of the chapter on %s.  The running header "+
+			"of the page shows the title of the chapter. ", i+1, strings.ToLower(ch.Title)), 4),
+			fonts.NewFontHelvetica(), 11, creator.ColorBlack)
+		p.SetLineHeight(1.3)
+		p.SetMargins(0, 0, 0, 8)
+		p.SetWidth(width)
+		paragraphs = append(paragraphs, p)
+	}
+
+	keepHeight := 18 + heading.Height() + 10
+	if len(paragraphs) > 0 {
+		keepHeight += paragraphs[0].Height() + 8
+	}
+	if b.c.Context().Height < keepHeight {
+		b.c.NewPage()
+	}
+	b.starts = append(b.starts, chapterStart{Page: b.c.Context().Page, Number: number, Title: ch.Title})
+
+	err := b.c.Draw(heading)
+	if err != nil {
+		return err
+	}
+	for _, p := range paragraphs {
+		err := b.c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pageChapter returns the chapter of the page: the first chapter that starts on the page, otherwise the last chapter
+// that started before it.  Returns false for pages before the first chapter.
+func (b *book) pageChapter(pageNum int) (chapterStart, bool) {
+	found := false
+	current := chapterStart{}
+	for _, start := range b.starts {
+		if start.Page > pageNum {
+			break
+		}
+		if start.Page == pageNum {
+			return start, true
+		}
+		current = start
+		found = true
+	}
+	return current, found
+}
+
+func newParagraph(text string, font fonts.Font, size float64, color creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetColor(color)
+	return p
+}
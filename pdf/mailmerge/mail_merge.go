@@ -0,0 +1,240 @@
+/*
+ * Mail merge: generate a form letter for each record of a list from a template, and concatenate the letters into a
+ * single PDF.
+ *
+ * The template is a list of paragraphs with {{placeholders}}, defined in Go along with the records, which are maps
+ * from the placeholder names to values.  For each record the placeholders are substituted and the paragraphs are
+ * laid out in the flow of the page, so the text is wrapped anew for the values of the record.  Values can contain
+ * blank lines, which split them into several paragraphs.
+ *
+ * Placeholders without a value in a record are replaced by the -default text (empty by default) and reported.  Lines
+ * that consist only of placeholders and end up empty are dropped, so e.g. an optional second address line leaves no
+ * gap in the address.
+ *
+ * Every letter starts on a new page, and a letter that is too long for one page continues on the next.  Letters of
+ * more than one page get a footer with the recipient and the page number within the letter.
+ *
+ * Run as: go run mail_merge.go [-default text] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run mail_merge.go [-default text] output.pdf\n"
+
+// templateParagraph is a paragraph of the letter template, with placeholders in the form {{name}}.
+type templateParagraph struct {
+	Text       string
+	Bold       bool
+	Alignment  creator.TextAlignment
+	SpaceAfter float64
+}
+
+// letterTemplate is the template of the letter.
+var letterTemplate = []templateParagraph{
+	{Text: "Riverside Public Library\n12 Harbour Street\nPortsmouth PO1 2AB", Bold: true,
+		Alignment: creator.TextAlignmentRight, SpaceAfter: 30},
+	{Text: "{{name}}\n{{address1}}\n{{address2}}\n{{city}} {{postcode}}", SpaceAfter: 20},
+	{Text: "{{date}}", Alignment: creator.TextAlignmentRight, SpaceAfter: 20},
+	{Text: "Membership renewal: card number {{card}}", Bold: true, SpaceAfter: 12},
+	{Text: "Dear {{title}} {{surname}},", SpaceAfter: 10},
+	{Text: "Thank you for being a member of Riverside Public Library since {{since}}.  Your membership expires on " +
+		"{{expires}}, and we would like to invite you to renew it for another year.  The renewal fee for a " +
+		"{{plan}} membership is {{fee}}, and it can be paid at any of our branches or online.", SpaceAfter: 10},
+	{Text: "{{message}}", SpaceAfter: 10},
+	{Text: "If you have any questions, please do not hesitate to contact us at the front desk.", SpaceAfter: 20},
+	{Text: "Yours sincerely,", SpaceAfter: 30},
+	{Text: "Margaret Ellis\nHead Librarian"},
+}
+
+// records are the recipients of the letter, with the values for the placeholders.
+var records = []map[string]string{
+	{
+		"name": "Mr. John Smith", "address1": "4 Elm Road", "city": "Portsmouth", "postcode": "PO2 8QT",
+		"date": "June 1, 2017", "card": "100234", "title": "Mr.", "surname": "Smith", "since": "2009",
+		"expires": "June 30, 2017", "plan": "standard", "fee": "£12.00",
+		"message": "As a thank you for your loyalty, renewals before the end of June include a free pass to our " +
+			"summer reading events.",
+	},
+	{
+		"name": "Dr. Amelia Fairweather-Montgomery", "address1": "Flat 18, Seaview Court",
+		"address2": "221 Southsea Esplanade", "city": "Southsea", "postcode": "PO4 0SW", "date": "June 1, 2017",
+		"card": "104871", "title": "Dr.", "surname": "Fairweather-Montgomery", "since": "2015",
+		"expires": "July 15, 2017", "plan": "family", "fee": "£30.00",
+		"message": "Your family membership covers up to five members of your household, who can each borrow up to " +
+			"fifteen items at a time.  We have recently extended the family membership with access to our digital " +
+			"library, including e-books, audiobooks and magazines, which can be borrowed from home with the " +
+			"library app.\n\n" +
+			"We would also like to let you know about the changes to our opening hours, which take effect on " +
+			"July 1: the main branch will be open until 8 pm on weekdays and from 10 am to 4 pm on Sundays, while " +
+			"the Southsea branch will be closed on Mondays.  The children's section will host story time on " +
+			"Saturday mornings throughout the summer, and the teenage reading group meets on the first Thursday " +
+			"of every month.\n\n" +
+			"Finally, we are looking for volunteers to help with our home delivery service for readers who are " +
+			"unable to visit the library.  If you, or a member of your family, would like to help, please ask at " +
+			"the front desk or reply to this letter.  Volunteers receive a free membership for the year.\n\n" +
+			"The library relies on the support of its members, and we are grateful for your continued interest.  " +
+			"Membership fees fund the purchase of new books and the maintenance of our reading rooms, and the " +
+			"renewal fee for your family membership has not changed since last year.\n\n" +
+			"In addition, members who renew before the expiry date can take part in the annual survey, which " +
+			"helps us to plan our collections and events.  Last year, suggestions from the survey led to the new " +
+			"graphic novel section and the extended study hours during the exam period.\n\n" +
+			"We look forward to welcoming you and your family in the coming year.  Please remember to bring your " +
+			"card when renewing at a branch, or have the card number at hand when renewing online.",
+	},
+	{
+		// No title, plan or message: they are replaced by the default, and without one the message is left out.
+		"name": "Priya Raman", "address1": "77 Kingston Crescent", "city": "Portsmouth", "postcode": "PO2 8AA",
+		"date": "June 1, 2017", "card": "109322", "surname": "Raman", "since": "2016", "expires": "June 20, 2017",
+		"fee": "£12.00",
+	},
+}
+
+// placeholderRegexp matches placeholders {{name}} with the name as the first submatch, and a following space as the
+// second, which is removed along with an empty value so that no double spaces are left.
+var placeholderRegexp = regexp.MustCompile(`{{\s*(\w+)\s*}}( ?)`)
+
+// letterPages is the range of pages of a letter.
+type letterPages struct {
+	first, last int
+	recipient   string
+}
+
+func main() {
+	defaultValue := ""
+	flag.StringVar(&defaultValue, "default", "", "Text used for placeholders without a value in a record")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := mailMerge(letterTemplate, records, defaultValue, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// substitute replaces the placeholders in text with the values of the record, or defaultValue for placeholders
+// without a value, which are returned as missing.  Lines containing only placeholders that are replaced by empty
+// values are removed.
+func substitute(text string, record map[string]string, defaultValue string) (string, []string) {
+	missing := []string{}
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		replaced := placeholderRegexp.ReplaceAllStringFunc(line, func(placeholder string) string {
+			match := placeholderRegexp.FindStringSubmatch(placeholder)
+			value, ok := record[match[1]]
+			if !ok {
+				missing = append(missing, match[1])
+				value = defaultValue
+			}
+			if value == "" {
+				return ""
+			}
+			return value + match[2]
+		})
+
+		onlyPlaceholders := strings.TrimSpace(placeholderRegexp.ReplaceAllString(line, "")) == ""
+		if onlyPlaceholders && strings.TrimSpace(replaced) == "" {
+			continue
+		}
+		lines = append(lines, replaced)
+	}
+	return strings.Join(lines, "\n"), missing
+}
+
+func mailMerge(template []templateParagraph, records []map[string]string, defaultValue, outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(72, 72, 60, 72)
+
+	regular := fonts.NewFontTimesRoman()
+	bold := fonts.NewFontTimesBold()
+
+	letters := []letterPages{}
+	for i, record := range records {
+		c.NewPage()
+		first := c.Context().Page
+
+		for _, tp := range template {
+			text, missing := substitute(tp.Text, record, defaultValue)
+			for _, name := range missing {
+				fmt.Printf("Record %d: no value for {{%s}}, using %q\n", i+1, name, defaultValue)
+			}
+
+			// Values with blank lines make several paragraphs.
+			parts := strings.Split(text, "\n\n")
+			for j, part := range parts {
+				if strings.TrimSpace(part) == "" {
+					continue
+				}
+				p := creator.NewParagraph(part)
+				if tp.Bold {
+					p.SetFont(bold)
+				} else {
+					p.SetFont(regular)
+				}
+				p.SetFontSize(11)
+				p.SetLineHeight(1.2)
+				p.SetTextAlignment(tp.Alignment)
+				spaceAfter := tp.SpaceAfter
+				if j < len(parts)-1 {
+					spaceAfter = 10
+				}
+				p.SetMargins(0, 0, 0, spaceAfter)
+				err := c.Draw(p)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		letter := letterPages{first: first, last: c.Context().Page, recipient: record["name"]}
+		letters = append(letters, letter)
+		fmt.Printf("Letter %d to %s: %d page(s)\n", i+1, letter.recipient, letter.last-letter.first+1)
+	}
+
+	// The footers are drawn when writing the output, when the page ranges of all the letters are known.
+	c.DrawFooter(func(block *creator.Block, args creator.FooterFunctionArgs) {
+		for _, letter := range letters {
+			if args.PageNum < letter.first || args.PageNum > letter.last || letter.first == letter.last {
+				continue
+			}
+			p := creator.NewParagraph(fmt.Sprintf("%s - page %d of %d", letter.recipient,
+				args.PageNum-letter.first+1, letter.last-letter.first+1))
+			p.SetFont(regular)
+			p.SetFontSize(9)
+			p.SetColor(creator.ColorRGBFrom8bit(100, 100, 100))
+			p.SetWidth(block.Width() - 144)
+			p.SetTextAlignment(creator.TextAlignmentCenter)
+			p.SetPos(72, 30)
+			_ = block.Draw(p)
+		}
+	})
+
+	return c.WriteToFile(outputPath)
+}
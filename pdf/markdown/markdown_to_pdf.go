@@ -0,0 +1,605 @@
+/*
+ * Convert a Markdown document to PDF.  A limited subset of Markdown is supported:
+ * - Headings: "#" headings become numbered chapters and "##" headings subchapters of the current chapter, with
+ *   entries in the creator's table of contents.  Deeper headings are bold paragraphs.
+ * - Paragraphs of consecutive lines, with inline **bold**, *italic* (also __bold__ and _italic_), ***both*** and
+ *   `code` spans.  A backslash escapes the following character.
+ * - Bullet lists ("-", "*" or "+") and numbered lists ("1." or "1)"), nested by indenting the items.  Indented lines
+ *   without a marker continue the previous item.
+ * - Fenced code blocks (```), drawn in a monospace font on a light background.
+ *
+ * Paragraphs and list items are wrapped with the inline styles mixed on a line.  Every line is drawn as a creator
+ * block in the flow of the page, so the text breaks across pages line by line, and headings are kept with at least
+ * two lines of the following text.
+ *
+ * Code lines that are too long for the page are either wrapped, with a "»" marking the continued lines, or clipped
+ * at the right edge with an ellipsis "…" (-code clip).
+ *
+ * Only characters of the WinAnsi encoding are supported.  The file sample.md in this directory shows the supported
+ * syntax.
+ *
+ * Run as: go run markdown_to_pdf.go [-code wrap|clip] input.md output.pdf
+ */
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run markdown_to_pdf.go [-code wrap|clip] input.md output.pdf\n"
+
+// Layout parameters.
+const (
+	bodyFontSize   = 10.5
+	codeFontSize   = 9.0
+	lineSpacing    = 1.4  // Line height as a multiple of the font size.
+	paragraphSpace = 8.0  // Space after paragraphs, lists and code blocks.
+	listIndent     = 18.0 // Indent per list level, which also holds the bullet or number.
+	codePadding    = 6.0  // Padding around code blocks.
+	pageMargin     = 60.0
+)
+
+var (
+	codeBackground = creator.ColorRGBFrom8bit(243, 244, 246)
+	codeMarkColor  = creator.ColorRGBFrom8bit(150, 150, 150)
+)
+
+var (
+	headingRegexp  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	listItemRegexp = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	fenceRegexp    = regexp.MustCompile("^\\s*```")
+)
+
+// inlineStyle is the style of a span of text.
+type inlineStyle struct {
+	bold, italic, code bool
+}
+
+// textPiece is a span of text in a single style.
+type textPiece struct {
+	text  string
+	style inlineStyle
+}
+
+// listItem is an item of a list, at a nesting level starting at 0.
+type listItem struct {
+	level   int
+	ordered bool
+	text    string
+}
+
+// mdRenderer draws Markdown blocks with the creator.
+type mdRenderer struct {
+	c        *creator.Creator
+	chapter  *creator.Chapter
+	clipCode bool
+}
+
+func main() {
+	codeMode := ""
+	flag.StringVar(&codeMode, "code", "wrap", "Long code lines: wrap or clip")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if codeMode != "wrap" && codeMode != "clip" {
+		fmt.Printf("Error: -code must be wrap or clip\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := markdownToPdf(inputPath, outputPath, codeMode == "clip")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func markdownToPdf(inputPath, outputPath string, clipCode bool) error {
+	lines, err := readLines(inputPath)
+	if err != nil {
+		return err
+	}
+
+	c := creator.New()
+	c.SetPageMargins(pageMargin, pageMargin, pageMargin, pageMargin)
+	c.NewPage()
+
+	r := &mdRenderer{c: c, clipCode: clipCode}
+	err = r.render(lines)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// readLines returns the lines of the file with tabs expanded to 4 spaces.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, strings.Replace(scanner.Text(), "\t", "    ", -1))
+	}
+	return lines, scanner.Err()
+}
+
+// render parses the lines into blocks (headings, paragraphs, lists and code blocks) and draws them.
+func (r *mdRenderer) render(lines []string) error {
+	isBlank := func(line string) bool {
+		return strings.TrimSpace(line) == ""
+	}
+	startsBlock := func(line string) bool {
+		return headingRegexp.MatchString(line) || listItemRegexp.MatchString(line) || fenceRegexp.MatchString(line)
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+
+		case fenceRegexp.MatchString(line):
+			// Everything up to the closing fence, or the end of the document.
+			code := []string{}
+			for i++; i < len(lines) && !fenceRegexp.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			err := r.drawCodeBlock(code)
+			if err != nil {
+				return err
+			}
+
+		case headingRegexp.MatchString(line):
+			m := headingRegexp.FindStringSubmatch(line)
+			err := r.drawHeading(len(m[1]), m[2])
+			if err != nil {
+				return err
+			}
+			i++
+
+		case listItemRegexp.MatchString(line):
+			items := []listItem{}
+			indents := []int{} // Indentation of the items at each level.
+			for i < len(lines) {
+				if isBlank(lines[i]) {
+					// A blank line ends the list unless another item follows.
+					if i+1 < len(lines) && listItemRegexp.MatchString(lines[i+1]) {
+						i++
+						continue
+					}
+					break
+				}
+				m := listItemRegexp.FindStringSubmatch(lines[i])
+				if m == nil {
+					if len(items) == 0 || !strings.HasPrefix(lines[i], " ") || startsBlock(lines[i]) {
+						break
+					}
+					// Continuation of the previous item.
+					items[len(items)-1].text += " " + strings.TrimSpace(lines[i])
+					i++
+					continue
+				}
+
+				// The level is the number of enclosing items with less indentation.
+				indent := len(m[1])
+				for len(indents) > 0 && indent < indents[len(indents)-1] {
+					indents = indents[:len(indents)-1]
+				}
+				if len(indents) == 0 || indent > indents[len(indents)-1] {
+					indents = append(indents, indent)
+				}
+				items = append(items, listItem{level: len(indents) - 1, ordered: m[2][0] >= '0' && m[2][0] <= '9',
+					text: m[3]})
+				i++
+			}
+			err := r.drawList(items)
+			if err != nil {
+				return err
+			}
+
+		default:
+			text := []string{}
+			for ; i < len(lines) && !isBlank(lines[i]) && !startsBlock(lines[i]); i++ {
+				text = append(text, strings.TrimSpace(lines[i]))
+			}
+			err := r.drawText(strings.Join(text, " "), 0, "")
+			if err != nil {
+				return err
+			}
+			r.c.MoveDown(paragraphSpace)
+		}
+	}
+	return nil
+}
+
+// ensureSpace starts a new page if there is less than height left on the current page.
+func (r *mdRenderer) ensureSpace(height float64) {
+	ctx := r.c.Context()
+	if ctx.Y+height > ctx.PageHeight-pageMargin {
+		r.c.NewPage()
+	}
+}
+
+// drawHeading draws a chapter heading for level 1, a subchapter heading for level 2 and a bold paragraph otherwise.
+func (r *mdRenderer) drawHeading(level int, text string) error {
+	// Inline markup is not styled in headings.
+	plain := ""
+	for _, piece := range parseInline(text) {
+		plain += piece.text
+	}
+
+	// Keep the heading with the first two lines of the following text.
+	r.ensureSpace(40 + 2*bodyFontSize*lineSpacing)
+
+	if level == 1 || (level == 2 && r.chapter == nil) {
+		r.chapter = r.c.NewChapter(plain)
+		heading := r.chapter.GetHeading()
+		heading.SetFont(fonts.NewFontHelveticaBold())
+		heading.SetFontSize(18)
+		heading.SetMargins(0, 0, 6, 10)
+		return r.c.Draw(r.chapter)
+	}
+	if level == 2 {
+		// The subchapter is drawn by itself rather than as part of the chapter, which has been drawn already.
+		subchapter := r.c.NewSubchapter(r.chapter, plain)
+		heading := subchapter.GetHeading()
+		heading.SetFont(fonts.NewFontHelveticaBold())
+		heading.SetFontSize(14)
+		heading.SetMargins(0, 0, 6, 8)
+		return r.c.Draw(subchapter)
+	}
+
+	p := creator.NewParagraph(plain)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(12)
+	p.SetMargins(0, 0, 4, 6)
+	return r.c.Draw(p)
+}
+
+// drawList draws the items with their bullets or numbers, indented by their level.  Numbered items are numbered
+// from 1 within each list, independently of the numbers in the source.
+func (r *mdRenderer) drawList(items []listItem) error {
+	bullets := []string{"•", "–"}
+	counters := []int{}
+	for _, item := range items {
+		for len(counters) <= item.level {
+			counters = append(counters, 0)
+		}
+		counters = counters[:item.level+1]
+		counters[item.level]++
+
+		marker := bullets[item.level%len(bullets)]
+		if item.ordered {
+			marker = strconv.Itoa(counters[item.level]) + "."
+		}
+		err := r.drawText(item.text, float64(item.level+1)*listIndent, marker)
+		if err != nil {
+			return err
+		}
+		r.c.MoveDown(2)
+	}
+	r.c.MoveDown(paragraphSpace)
+	return nil
+}
+
+// drawText draws text with inline markup, wrapped to the page width less the indent.  The marker is drawn in front
+// of the first line, in the indent.
+func (r *mdRenderer) drawText(text string, indent float64, marker string) error {
+	ctx := r.c.Context()
+	width := ctx.PageWidth - 2*pageMargin - indent
+
+	lines, err := wrapPieces(parseInline(text), width)
+	if err != nil {
+		return err
+	}
+
+	lineHeight := bodyFontSize * lineSpacing
+	for i, line := range lines {
+		r.ensureSpace(lineHeight)
+
+		block := creator.NewBlock(width+indent, lineHeight)
+		if i == 0 && marker != "" {
+			p := newPieceParagraph(textPiece{text: marker})
+			p.SetPos(indent-p.Width()-6, (lineHeight-bodyFontSize)/2)
+			err := block.Draw(p)
+			if err != nil {
+				return err
+			}
+		}
+		x := indent
+		for _, piece := range line {
+			p := newPieceParagraph(piece)
+			p.SetPos(x, (lineHeight-bodyFontSize)/2)
+			err := block.Draw(p)
+			if err != nil {
+				return err
+			}
+			x += p.Width()
+		}
+
+		r.c.MoveX(pageMargin)
+		err := r.c.Draw(block)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drawCodeBlock draws the lines in a monospace font on a light background, wrapping or clipping long lines.
+func (r *mdRenderer) drawCodeBlock(code []string) error {
+	ctx := r.c.Context()
+	width := ctx.PageWidth - 2*pageMargin
+	charWidth := codeFontSize * 0.6 // Courier glyphs are 600 units wide.
+	maxChars := int((width - 2*codePadding) / charWidth)
+
+	// The lines as drawn, with a flag for continued lines.
+	type codeLine struct {
+		text      string
+		continued bool
+		clipped   bool
+	}
+	codeLines := []codeLine{}
+	for _, line := range code {
+		runes := []rune(strings.TrimRight(line, " "))
+		if len(runes) <= maxChars {
+			codeLines = append(codeLines, codeLine{text: string(runes)})
+			continue
+		}
+		if r.clipCode {
+			codeLines = append(codeLines, codeLine{text: string(runes[:maxChars-1]), clipped: true})
+			continue
+		}
+		for start := 0; start < len(runes); start += maxChars {
+			end := start + maxChars
+			if end > len(runes) {
+				end = len(runes)
+			}
+			codeLines = append(codeLines, codeLine{text: string(runes[start:end]), continued: start > 0})
+		}
+	}
+
+	// Each line is a block with its part of the background, so the code block can break across pages.  The first
+	// and last lines include the padding.
+	lineHeight := codeFontSize * lineSpacing
+	for i, line := range codeLines {
+		top, bottom := 0.0, 0.0
+		if i == 0 {
+			top = codePadding
+		}
+		if i == len(codeLines)-1 {
+			bottom = codePadding
+		}
+		r.ensureSpace(top + lineHeight + bottom)
+
+		block := creator.NewBlock(width, top+lineHeight+bottom)
+		background := creator.NewRectangle(0, 0, width, top+lineHeight+bottom)
+		background.SetFillColor(codeBackground)
+		background.SetBorderWidth(0)
+		err := block.Draw(background)
+		if err != nil {
+			return err
+		}
+
+		y := top + (lineHeight-codeFontSize)/2
+		p := newPieceParagraph(textPiece{text: line.text, style: inlineStyle{code: true}})
+		p.SetFontSize(codeFontSize)
+		p.SetPos(codePadding, y)
+		err = block.Draw(p)
+		if err != nil {
+			return err
+		}
+
+		mark, markX := "", 0.0
+		if line.continued {
+			mark, markX = "»", 0.5
+		} else if line.clipped {
+			mark, markX = "…", codePadding+float64(maxChars-1)*charWidth
+		}
+		if mark != "" {
+			m := creator.NewParagraph(mark)
+			m.SetFont(fonts.NewFontCourier())
+			m.SetFontSize(codeFontSize)
+			m.SetColor(codeMarkColor)
+			m.SetPos(markX, y)
+			err = block.Draw(m)
+			if err != nil {
+				return err
+			}
+		}
+
+		r.c.MoveX(pageMargin)
+		err = r.c.Draw(block)
+		if err != nil {
+			return err
+		}
+	}
+	r.c.MoveDown(paragraphSpace)
+	return nil
+}
+
+// parseInline splits text with inline markup into pieces of the same style.
+func parseInline(text string) []textPiece {
+	pieces := []textPiece{}
+	style := inlineStyle{}
+	current := []rune{}
+	flush := func() {
+		if len(current) > 0 {
+			pieces = append(pieces, textPiece{text: string(current), style: style})
+			current = []rune{}
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case ch == '`':
+			flush()
+			style.code = !style.code
+		case style.code:
+			// No markup within code spans.
+			current = append(current, ch)
+		case ch == '\\' && i+1 < len(runes):
+			i++
+			current = append(current, runes[i])
+		case ch == '_' && i > 0 && i+1 < len(runes) && isWordRune(runes[i-1]) && isWordRune(runes[i+1]):
+			// Underscores within words, as in file names, are not markup.
+			current = append(current, ch)
+		case ch == '*' || ch == '_':
+			// A run of 1, 2 or 3 markers toggles italic, bold or both.
+			n := 1
+			for i+n < len(runes) && runes[i+n] == ch && n < 3 {
+				n++
+			}
+			flush()
+			if n != 2 {
+				style.italic = !style.italic
+			}
+			if n >= 2 {
+				style.bold = !style.bold
+			}
+			i += n - 1
+		default:
+			current = append(current, ch)
+		}
+	}
+	flush()
+	return pieces
+}
+
+// isWordRune returns true for letters and digits.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// wrapPieces breaks the pieces into lines that fit the width.  Lines break at spaces; a word can consist of pieces
+// in different styles.  Words wider than the width are put on a line of their own.
+func wrapPieces(pieces []textPiece, width float64) ([][]textPiece, error) {
+	// Split the pieces into words and single spaces.
+	type token struct {
+		pieces []textPiece
+		space  bool
+		width  float64
+	}
+	tokens := []token{}
+	for _, piece := range pieces {
+		for i, word := range strings.Split(piece.text, " ") {
+			if i > 0 {
+				tokens = append(tokens, token{pieces: []textPiece{{" ", piece.style}}, space: true})
+			}
+			if word == "" {
+				continue
+			}
+			wp := textPiece{word, piece.style}
+			if n := len(tokens); n > 0 && !tokens[n-1].space {
+				// Continues the previous word in another style.
+				tokens[n-1].pieces = append(tokens[n-1].pieces, wp)
+			} else {
+				tokens = append(tokens, token{pieces: []textPiece{wp}})
+			}
+		}
+	}
+	for i := range tokens {
+		for _, piece := range tokens[i].pieces {
+			w := newPieceParagraph(piece).Width()
+			if w < 0 {
+				return nil, fmt.Errorf("Unsupported character in %q", piece.text)
+			}
+			tokens[i].width += w
+		}
+	}
+
+	lines := [][]textPiece{}
+	line := []token{}
+	lineWidth := 0.0
+	flushLine := func() {
+		// Drop trailing spaces.
+		for len(line) > 0 && line[len(line)-1].space {
+			line = line[:len(line)-1]
+		}
+		if len(line) == 0 {
+			return
+		}
+		pieces := []textPiece{}
+		for _, t := range line {
+			for _, piece := range t.pieces {
+				// Merge pieces of the same style, so they are drawn as one paragraph.
+				if n := len(pieces); n > 0 && pieces[n-1].style == piece.style {
+					pieces[n-1].text += piece.text
+				} else {
+					pieces = append(pieces, piece)
+				}
+			}
+		}
+		lines = append(lines, pieces)
+		line = []token{}
+		lineWidth = 0
+	}
+	for _, t := range tokens {
+		if t.space && len(line) == 0 {
+			continue
+		}
+		if !t.space && lineWidth+t.width > width && len(line) > 0 {
+			flushLine()
+		}
+		line = append(line, t)
+		lineWidth += t.width
+	}
+	flushLine()
+
+	return lines, nil
+}
+
+// newPieceParagraph returns an unwrapped paragraph with the text of the piece in the font of its style.
+func newPieceParagraph(piece textPiece) *creator.Paragraph {
+	p := creator.NewParagraph(piece.text)
+	switch {
+	case piece.style.code:
+		p.SetFont(fonts.NewFontCourier())
+	case piece.style.bold && piece.style.italic:
+		p.SetFont(fonts.NewFontHelveticaBoldOblique())
+	case piece.style.bold:
+		p.SetFont(fonts.NewFontHelveticaBold())
+	case piece.style.italic:
+		p.SetFont(fonts.NewFontHelveticaOblique())
+	default:
+		p.SetFont(fonts.NewFontHelvetica())
+	}
+	p.SetFontSize(bodyFontSize)
+	p.SetEnableWrap(false)
+	return p
+}
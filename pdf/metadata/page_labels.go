@@ -0,0 +1,317 @@
+/*
+ * Set the page labels of a PDF file, the page numbers that viewers show and use in "go to page": e.g. roman numbers
+ * i, ii, iii for the front matter and 1, 2, 3 for the body, restarting at the body.
+ *
+ * The labels are given as ranges, each from a page to the next range: page:style[:prefix[:start]]
+ * - page: the first page of the range (1 for the first page),
+ * - style: D (1, 2, 3), r (i, ii, iii), R (I, II, III), a (a, b, c, ..., aa), A (A, B, C, ..., AA), or - for no number
+ *   (the label is the prefix only),
+ * - prefix: text before the number, e.g. A- for A-1, A-2 in an appendix,
+ * - start: the number of the first page of the range (1 by default).
+ * For example, 1:r 5:D 21:D:A- labels a cover and 3 pages of front matter i-iv, the body 1-16 and the appendix A-1,
+ * A-2.  The -front flag is a shortcut for the common case: -front 4 is 1:r 5:D.  Labels must cover the first page, so
+ * a range 1:D is added if needed.
+ *
+ * The labels are a number tree in the catalog (PageLabels), from page indexes (from 0) to label dictionaries with the
+ * style (S), prefix (P) and start (St).  Existing page labels are replaced.  The tree is added in an incremental update
+ * appended to a copy of the input file, as the model does not give access to the document catalog.  The update is
+ * written by writeUpdate in update.go.  Use print_page_labels.go to print the labels of a file.
+ *
+ * Run as: go run page_labels.go update.go [-front 4] input.pdf output.pdf [page:style[:prefix[:start]] ...]
+ */
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run page_labels.go update.go [-front 4] input.pdf output.pdf " +
+	"[page:style[:prefix[:start]] ...]\n" +
+	"Styles: D (1, 2, 3), r (i, ii, iii), R (I, II, III), a (a, b, c), A (A, B, C), - (prefix only)\n"
+
+// labelRange is a range of page labels, from its first page to the next range.
+type labelRange struct {
+	page   int // First page, from 1.
+	style  string
+	prefix string
+	start  int
+}
+
+func main() {
+	front := 0
+	flag.IntVar(&front, "front", 0, "Number of front matter pages, labeled i, ii, iii before the body from 1")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 || (flag.NArg() == 2 && front <= 0) {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	ranges := []labelRange{}
+	if front > 0 {
+		ranges = append(ranges, labelRange{page: 1, style: "r", start: 1},
+			labelRange{page: front + 1, style: "D", start: 1})
+	}
+	for _, spec := range flag.Args()[2:] {
+		r, err := parseRange(spec)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		ranges = append(ranges, r)
+	}
+
+	err := setPageLabels(inputPath, outputPath, ranges)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Parses a range page:style[:prefix[:start]].
+func parseRange(spec string) (labelRange, error) {
+	parts := strings.SplitN(spec, ":", 4)
+	if len(parts) < 2 {
+		return labelRange{}, fmt.Errorf("Invalid range %q, expected page:style[:prefix[:start]]", spec)
+	}
+	r := labelRange{style: parts[1], start: 1}
+	page, err := strconv.Atoi(parts[0])
+	if err != nil || page < 1 {
+		return labelRange{}, fmt.Errorf("Invalid page in range %q", spec)
+	}
+	r.page = page
+	switch r.style {
+	case "D", "r", "R", "a", "A":
+	case "-", "":
+		r.style = ""
+	default:
+		return labelRange{}, fmt.Errorf("Invalid style %q in range %q", parts[1], spec)
+	}
+	if len(parts) > 2 {
+		r.prefix = parts[2]
+	}
+	if len(parts) > 3 {
+		start, err := strconv.Atoi(parts[3])
+		if err != nil || start < 1 {
+			return labelRange{}, fmt.Errorf("Invalid start in range %q", spec)
+		}
+		r.start = start
+	}
+	if r.style == "" && r.prefix == "" {
+		return labelRange{}, fmt.Errorf("Empty labels in range %q, give a style or a prefix", spec)
+	}
+	return r, nil
+}
+
+func setPageLabels(inputPath, outputPath string, ranges []labelRange) error {
+	data, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		return errors.New("Setting page labels of encrypted files is not supported")
+	}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	// The ranges by first page, a later range replacing an earlier one for the same page.
+	byPage := map[int]labelRange{}
+	for _, r := range ranges {
+		if r.page > numPages {
+			return fmt.Errorf("Range from page %d out of range (document has %d pages)", r.page, numPages)
+		}
+		byPage[r.page] = r
+	}
+	if _, ok := byPage[1]; !ok {
+		fmt.Printf("No range from page 1, adding 1:D\n")
+		byPage[1] = labelRange{page: 1, style: "D", start: 1}
+	}
+	ranges = ranges[:0]
+	for _, r := range byPage {
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].page < ranges[j].page })
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	rootRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return errors.New("Missing Root in trailer")
+	}
+	catalog, err := getCatalog(pdfReader, trailer)
+	if err != nil {
+		return err
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	nextNum := int64(*size)
+	if catalog.Get("PageLabels") != nil {
+		fmt.Printf("Replacing the existing page labels\n")
+	}
+
+	// The number tree, a single node with the page indexes and label dictionaries.
+	nums := pdfcore.MakeArray()
+	for i, r := range ranges {
+		label := pdfcore.MakeDict()
+		label.Set("Type", pdfcore.MakeName("PageLabel"))
+		if r.style != "" {
+			label.Set("S", pdfcore.MakeName(r.style))
+		}
+		if r.prefix != "" {
+			label.Set("P", pdfcore.MakeString(encodePdfString(r.prefix)))
+		}
+		if r.start != 1 {
+			label.Set("St", pdfcore.MakeInteger(int64(r.start)))
+		}
+		nums.Append(pdfcore.MakeInteger(int64(r.page - 1)))
+		nums.Append(label)
+
+		last := numPages
+		if i+1 < len(ranges) {
+			last = ranges[i+1].page - 1
+		}
+		if last == r.page {
+			fmt.Printf("Page %d: %s\n", r.page, formatLabel(r, r.page))
+		} else {
+			fmt.Printf("Pages %d-%d: %s to %s\n", r.page, last, formatLabel(r, r.page), formatLabel(r, last))
+		}
+	}
+	tree := pdfcore.MakeDict()
+	tree.Set("Nums", nums)
+	treeNum := nextNum
+	nextNum++
+	catalog.Set("PageLabels", &pdfcore.PdfObjectReference{ObjectNumber: treeNum})
+
+	objects := []updateObject{
+		{number: treeNum, obj: tree, description: "page labels"},
+		{number: rootRef.ObjectNumber, generation: rootRef.GenerationNumber, obj: catalog, description: "catalog"},
+	}
+	update, err := writeUpdate(data, objects, trailer, nextNum)
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		fmt.Printf("Wrote object %d: %s\n", obj.number, obj.description)
+	}
+
+	return ioutil.WriteFile(outputPath, append(data, update...), 0644)
+}
+
+// Returns the label of the page in the range.
+func formatLabel(r labelRange, page int) string {
+	n := r.start + page - r.page
+	switch r.style {
+	case "D":
+		return r.prefix + strconv.Itoa(n)
+	case "r":
+		return r.prefix + strings.ToLower(romanNumeral(n))
+	case "R":
+		return r.prefix + romanNumeral(n)
+	case "a":
+		return r.prefix + strings.ToLower(letterNumeral(n))
+	case "A":
+		return r.prefix + letterNumeral(n)
+	}
+	return r.prefix
+}
+
+// Returns the upper case roman numeral of n.
+func romanNumeral(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var s strings.Builder
+	for i, v := range values {
+		for n >= v {
+			s.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return s.String()
+}
+
+// Returns the upper case letter numeral of n: A to Z, then AA to ZZ, AAA to ZZZ and so on.
+func letterNumeral(n int) string {
+	if n < 1 {
+		return ""
+	}
+	return strings.Repeat(string(rune('A'+(n-1)%26)), (n-1)/26+1)
+}
+
+// Returns the document catalog.
+func getCatalog(pdfReader *pdf.PdfReader, trailer *pdfcore.PdfObjectDictionary) (*pdfcore.PdfObjectDictionary,
+	error) {
+	root := trailer.Get("Root")
+	if ref, ok := root.(*pdfcore.PdfObjectReference); ok {
+		obj, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, err
+		}
+		root = obj
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(root).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("Missing catalog")
+	}
+	return catalog, nil
+}
+
+// Returns the string as PDF text string: PDFDocEncoding (ASCII) if possible, otherwise UTF-16BE with a byte order
+// mark.
+func encodePdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 128 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\xfe\xff")
+	binary.Write(&buf, binary.BigEndian, utf16.Encode([]rune(s)))
+	return buf.String()
+}
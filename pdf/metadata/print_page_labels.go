@@ -0,0 +1,324 @@
+/*
+ * Print the page labels of a PDF file: the label ranges and the label of each page, as viewers show them.
+ *
+ * The labels are read from the PageLabels number tree of the catalog (see page_labels.go to set them), following the
+ * intermediate nodes (Kids) down to the leaves with the page indexes and label dictionaries (Nums).  The label of a
+ * page is the prefix (P) and the number in the style (S) of the range it is in, counting from the start (St) of the
+ * range.  For a document without page labels, the pages are numbered 1, 2, 3 as viewers do.
+ *
+ * Run as: go run print_page_labels.go [-page N] input.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run print_page_labels.go [-page N] input.pdf\n"
+
+// labelRange is a range of page labels, from its first page to the next range.
+type labelRange struct {
+	page   int // First page, from 1.
+	style  string
+	prefix string
+	start  int
+}
+
+func main() {
+	pageNum := 0
+	flag.IntVar(&pageNum, "page", 0, "Only print the label of this page")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	err := printPageLabels(inputPath, pageNum)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func printPageLabels(inputPath string, pageNum int) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if pageNum < 0 || pageNum > numPages {
+		return fmt.Errorf("Page %d out of range (document has %d pages)", pageNum, numPages)
+	}
+
+	ranges, err := readPageLabels(pdfReader)
+	if err != nil {
+		return err
+	}
+	hasLabels := len(ranges) > 0
+	if !hasLabels {
+		fmt.Printf("No page labels, the pages are numbered 1-%d\n", numPages)
+	} else if ranges[0].page != 1 {
+		// The tree must start at the first page, viewers number the pages before the first range.
+		fmt.Printf("Warning: the page labels do not start at the first page, numbering the pages before page %d\n",
+			ranges[0].page)
+	}
+	if len(ranges) == 0 || ranges[0].page != 1 {
+		ranges = append([]labelRange{{page: 1, style: "D", start: 1}}, ranges...)
+	}
+
+	if pageNum > 0 {
+		fmt.Printf("Page %d: %s\n", pageNum, pageLabel(ranges, pageNum))
+		return nil
+	}
+
+	if hasLabels {
+		for i, r := range ranges {
+			last := numPages
+			if i+1 < len(ranges) {
+				last = ranges[i+1].page - 1
+			}
+			if r.page > numPages {
+				fmt.Printf("Range from page %d: beyond the last page\n", r.page)
+				continue
+			}
+			fmt.Printf("Range from page %d: %s (pages %d-%d)\n", r.page, describeRange(r), r.page, last)
+		}
+	}
+	for page := 1; page <= numPages; page++ {
+		fmt.Printf("Page %d: %s\n", page, pageLabel(ranges, page))
+	}
+	return nil
+}
+
+// Returns the label ranges of the PageLabels number tree, ordered by page.  Returns no ranges if there is no tree.
+func readPageLabels(pdfReader *pdf.PdfReader) ([]labelRange, error) {
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := resolve(pdfReader, trailer.Get("Root"))
+	if err != nil {
+		return nil, err
+	}
+	catalogDict, ok := catalog.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("Missing catalog")
+	}
+	tree, err := resolve(pdfReader, catalogDict.Get("PageLabels"))
+	if err != nil {
+		return nil, err
+	}
+	treeDict, ok := tree.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, nil
+	}
+
+	ranges := []labelRange{}
+	err = collectNumberTree(pdfReader, treeDict, &ranges, 0)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].page < ranges[j].page })
+	return ranges, nil
+}
+
+// Adds the label ranges of the number tree node and its kids.
+func collectNumberTree(pdfReader *pdf.PdfReader, node *pdfcore.PdfObjectDictionary, ranges *[]labelRange,
+	depth int) error {
+	if depth > 32 {
+		return errors.New("Page labels tree too deep")
+	}
+
+	nums, err := resolve(pdfReader, node.Get("Nums"))
+	if err != nil {
+		return err
+	}
+	if arr, ok := nums.(*pdfcore.PdfObjectArray); ok {
+		for i := 0; i+1 < len(*arr); i += 2 {
+			key, ok := pdfcore.TraceToDirectObject((*arr)[i]).(*pdfcore.PdfObjectInteger)
+			if !ok {
+				continue
+			}
+			value, err := resolve(pdfReader, (*arr)[i+1])
+			if err != nil {
+				return err
+			}
+			label, ok := value.(*pdfcore.PdfObjectDictionary)
+			if !ok {
+				continue
+			}
+			r := labelRange{page: int(*key) + 1, start: 1}
+			if s, ok := pdfcore.TraceToDirectObject(label.Get("S")).(*pdfcore.PdfObjectName); ok {
+				r.style = string(*s)
+			}
+			if p, ok := pdfcore.TraceToDirectObject(label.Get("P")).(*pdfcore.PdfObjectString); ok {
+				r.prefix = decodePdfString(string(*p))
+			}
+			if st, ok := pdfcore.TraceToDirectObject(label.Get("St")).(*pdfcore.PdfObjectInteger); ok && *st >= 1 {
+				r.start = int(*st)
+			}
+			*ranges = append(*ranges, r)
+		}
+	}
+
+	kids, err := resolve(pdfReader, node.Get("Kids"))
+	if err != nil {
+		return err
+	}
+	if arr, ok := kids.(*pdfcore.PdfObjectArray); ok {
+		for _, kid := range *arr {
+			kidObj, err := resolve(pdfReader, kid)
+			if err != nil {
+				return err
+			}
+			if kidDict, ok := kidObj.(*pdfcore.PdfObjectDictionary); ok {
+				err = collectNumberTree(pdfReader, kidDict, ranges, depth+1)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the label of the page: from the last range starting at or before it.
+func pageLabel(ranges []labelRange, page int) string {
+	r := ranges[0]
+	for _, candidate := range ranges {
+		if candidate.page <= page {
+			r = candidate
+		}
+	}
+	return formatLabel(r, page)
+}
+
+// Returns a description of the range: style, prefix and start.
+func describeRange(r labelRange) string {
+	styles := map[string]string{
+		"D": "decimal", "r": "lower roman", "R": "upper roman", "a": "lower letters", "A": "upper letters",
+	}
+	desc, ok := styles[r.style]
+	if !ok {
+		desc = "no numbers"
+	}
+	if r.prefix != "" {
+		desc += fmt.Sprintf(", prefix %q", r.prefix)
+	}
+	if r.start != 1 && ok {
+		desc += fmt.Sprintf(", from %d", r.start)
+	}
+	return desc
+}
+
+// Returns the label of the page in the range.
+func formatLabel(r labelRange, page int) string {
+	n := r.start + page - r.page
+	switch r.style {
+	case "D":
+		return r.prefix + strconv.Itoa(n)
+	case "r":
+		return r.prefix + strings.ToLower(romanNumeral(n))
+	case "R":
+		return r.prefix + romanNumeral(n)
+	case "a":
+		return r.prefix + strings.ToLower(letterNumeral(n))
+	case "A":
+		return r.prefix + letterNumeral(n)
+	}
+	return r.prefix
+}
+
+// Returns the upper case roman numeral of n.
+func romanNumeral(n int) string {
+	values := []int{1000, 900, 500, 400, 100, 90, 50, 40, 10, 9, 5, 4, 1}
+	symbols := []string{"M", "CM", "D", "CD", "C", "XC", "L", "XL", "X", "IX", "V", "IV", "I"}
+	var s strings.Builder
+	for i, v := range values {
+		for n >= v {
+			s.WriteString(symbols[i])
+			n -= v
+		}
+	}
+	return s.String()
+}
+
+// Returns the upper case letter numeral of n: A to Z, then AA to ZZ, AAA to ZZZ and so on.
+func letterNumeral(n int) string {
+	if n < 1 {
+		return ""
+	}
+	return strings.Repeat(string(rune('A'+(n-1)%26)), (n-1)/26+1)
+}
+
+// Returns the direct object, looking up references.
+func resolve(pdfReader *pdf.PdfReader, obj pdfcore.PdfObject) (pdfcore.PdfObject, error) {
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		resolved, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, err
+		}
+		obj = resolved
+	}
+	return pdfcore.TraceToDirectObject(obj), nil
+}
+
+// Decodes a PDF text string: UTF-16BE with a byte order mark, or PDFDocEncoding (read as Latin-1).
+func decodePdfString(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := []uint16{}
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
@@ -0,0 +1,716 @@
+/*
+ * Create a document with named destinations for its sections, and links that jump to them with GoTo actions: a table
+ * of contents on the first page, cross references in the text, and a "Back to top" button at the end of each section.
+ *
+ * A named destination is a name for a place in the document: a page and a view of it.  Links refer to the name
+ * rather than to the page (GoTo action with the name as destination, /D (name)), so that the targets can be changed
+ * without changing the links, and other documents or URLs (file.pdf#name) can refer to them as well.  The views of
+ * the destinations show the different types:
+ * - XYZ: the position left, top at the upper left corner of the window and a zoom factor, 0 (null) keeps the current
+ *   zoom.  Used for most sections, with a zoom of 150% for the small print of the specifications.
+ * - FitH: the page fitted to the window width, with the position top at the top of the window.
+ * - FitR: the rectangle left, bottom, right, top fitted to the window, used to zoom in on the figure.
+ * - Fit: the whole page fitted to the window.
+ *
+ * The destinations are in the Dests name tree of the name dictionary (Names) of the catalog, sorted by name.  The
+ * tree is added in an incremental update after the document has been written, as the model does not give access to
+ * the catalog, with the page objects of the written document.  The update is written by writeUpdate in update.go,
+ * shared with open_action.go.  Link borders are invisible by default, use -border to draw them (e.g. to check the
+ * link areas).
+ *
+ * Run as: go run named_destinations.go update.go [-border] output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run named_destinations.go update.go [-border] output.pdf\n"
+
+// destination is a named destination: a page (from 1) and a view of it.  The rectangle is in PDF coordinates, with
+// the origin in the lower left corner of the page.  XYZ uses the upper left corner and the zoom, FitH the top and
+// FitR the whole rectangle.
+type destination struct {
+	name string
+	page int
+	view string
+	rect pdf.PdfRectangle
+	zoom float64
+}
+
+// section is a section of the document with the view of its destination.
+type section struct {
+	dest     string
+	title    string
+	text     []string
+	fontSize float64
+	view     string
+	zoom     float64
+	seeAlso  string // Destination of a cross reference at the end of the section.
+	newPage  bool
+}
+
+const margin = 72.0
+
+// Padding around the link rectangles.  The paragraph height is measured from the baseline, the padding also covers
+// the descenders.
+const linkPadding = 3.0
+
+var (
+	textColor   = creator.ColorRGBFrom8bit(0, 0, 0)
+	linkColor   = creator.ColorRGBFrom8bit(0, 70, 170)
+	buttonColor = creator.ColorRGBFrom8bit(225, 232, 245)
+)
+
+var sections = []section{
+	{
+		dest:  "introduction",
+		title: "1. Introduction",
+		text: []string{
+			"The greenhouse controller keeps the temperature and humidity of a greenhouse within the set ranges, " +
+				"by switching the heater, the vents and the sprinklers.  It logs the readings of the sensors " +
+				"every minute and raises an alarm when a reading is out of range for more than ten minutes.",
+			"This manual describes the installation, the wiring and the configuration of the controller.  Read " +
+				"the safety instructions in the installation section before connecting the controller to the mains.",
+		},
+		view: "XYZ",
+	},
+	{
+		dest:  "installation",
+		title: "2. Installation",
+		text: []string{
+			"Mount the controller on a wall or post out of direct sunlight and away from the sprinklers, at eye " +
+				"level so that the display can be read.  Use the four screws supplied and keep at least 10 cm of " +
+				"free space below the housing for the cables.",
+			"Safety: the controller switches mains voltage.  Disconnect the power before opening the housing, and " +
+				"have the mains wiring done by a qualified electrician.  Protect the supply with a 10 A circuit " +
+				"breaker and a residual current device.",
+			"Place the temperature and humidity sensor in the middle of the greenhouse, at plant height and shaded " +
+				"from the sun.  The sensor cable can be extended to 30 m with a shielded twisted pair cable.",
+		},
+		view:    "XYZ",
+		seeAlso: "figure-wiring",
+	},
+	{
+		dest:  "wiring",
+		title: "3. Wiring",
+		text: []string{
+			"The sensors are connected to the low voltage terminals on the left, the heater, vents and sprinklers " +
+				"to the relay outputs on the right.  Each relay output switches up to 8 A, use a contactor for " +
+				"larger loads.  The figure shows the connections.",
+		},
+		view:    "FitH",
+		newPage: true,
+	},
+	{
+		dest:  "specifications",
+		title: "4. Specifications",
+		text: []string{
+			"Supply: 100-240 V AC, 50/60 Hz, 5 W.  Relay outputs: 3 x 8 A at 250 V AC, normally open.  Sensor " +
+				"input: digital, 3.3 V, up to 30 m.  Temperature range: -20 to 60 °C, accuracy 0.3 °C.  Humidity " +
+				"range: 0 to 100 % RH, accuracy 2 % RH.  Log: 90 days at one reading per minute.",
+			"Housing: polycarbonate, IP65, 180 x 120 x 60 mm.  Operating conditions: -10 to 50 °C, 0 to 100 % RH, " +
+				"non condensing.  Display: 2.4 inch LCD with backlight.  Alarm output: potential free contact, 1 A " +
+				"at 30 V DC.",
+		},
+		fontSize: 8,
+		view:     "XYZ",
+		zoom:     1.5,
+	},
+	{
+		dest:  "troubleshooting",
+		title: "5. Troubleshooting",
+		text: []string{
+			"The display shows no readings: check the sensor cable and the connection to the sensor terminals.  " +
+				"The readings are shown about 10 seconds after the sensor is connected.",
+			"The heater does not switch on: check that the heater is connected to output 1, and that the minimum " +
+				"temperature is set above the current temperature.  The relay LED shows the output state.",
+			"The alarm goes off every day at noon: the sensor is probably in the sun, move it to a shaded place.",
+		},
+		view:    "FitH",
+		seeAlso: "wiring",
+		newPage: true,
+	},
+	{
+		dest:  "notes",
+		title: "Notes",
+		text: []string{
+			"Space for your notes on the installation, e.g. the set ranges and the cable lengths.",
+		},
+		view:    "Fit",
+		newPage: true,
+	},
+}
+
+// Titles of the destinations for the cross references.
+var destTitles = map[string]string{
+	"figure-wiring": "Figure 1: Wiring diagram",
+	"wiring":        "3. Wiring",
+}
+
+// docLayout lays out the document from top to bottom, starting new pages as needed, and collects the destinations.
+type docLayout struct {
+	c           *creator.Creator
+	pages       []*pdf.PdfPage
+	y           float64
+	dests       []destination
+	drawBorders bool
+	numLinks    int
+}
+
+func main() {
+	drawBorders := false
+	flag.BoolVar(&drawBorders, "border", false, "Draw the link borders")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := createDocument(outputPath, drawBorders)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createDocument(outputPath string, drawBorders bool) error {
+	l := &docLayout{c: creator.New(), drawBorders: drawBorders}
+	err := l.newPage()
+	if err != nil {
+		return err
+	}
+
+	title := creator.NewParagraph("Greenhouse Controller Manual")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(20)
+	title.SetEnableWrap(false)
+	// The top of the document, the target of the "Back to top" buttons: the first page at the page width.
+	l.addDestination("top", "FitH", 0, margin, 0, 0, 0)
+	err = l.drawParagraph(title)
+	if err != nil {
+		return err
+	}
+	l.y += 16
+
+	// The table of contents: a link to each section, and to the figure.
+	err = l.drawHeading("Contents")
+	if err != nil {
+		return err
+	}
+	for _, s := range sections {
+		err = l.drawLink(s.title, s.dest, margin+10)
+		if err != nil {
+			return err
+		}
+		if s.dest == "wiring" {
+			err = l.drawLink(destTitles["figure-wiring"], "figure-wiring", margin+30)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	l.y += 24
+
+	for _, s := range sections {
+		err = l.drawSection(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = l.c.WriteToFile(outputPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Added %d links on %d pages\n", l.numLinks, len(l.pages))
+
+	return addNamedDestinations(outputPath, l.dests)
+}
+
+// Starts a new page.  The page is created here rather than with c.NewPage, to be able to add the link annotations
+// to it.
+func (l *docLayout) newPage() error {
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: l.c.Width(), Ury: l.c.Height()}
+	page.Resources = pdf.NewPdfPageResources()
+	err := l.c.AddPage(page)
+	if err != nil {
+		return err
+	}
+	l.pages = append(l.pages, page)
+	l.y = margin
+	return nil
+}
+
+// Starts a new page if there is not enough space for the height left on the page.
+func (l *docLayout) ensureSpace(height float64) error {
+	if l.y+height <= l.c.Height()-margin {
+		return nil
+	}
+	return l.newPage()
+}
+
+// Draws the paragraph at the current position and moves below it.
+func (l *docLayout) drawParagraph(p *creator.Paragraph) error {
+	err := l.ensureSpace(p.Height())
+	if err != nil {
+		return err
+	}
+	p.SetPos(margin, l.y)
+	err = l.c.Draw(p)
+	if err != nil {
+		return err
+	}
+	l.y += p.Height()
+	return nil
+}
+
+func (l *docLayout) drawHeading(text string) error {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(14)
+	p.SetEnableWrap(false)
+	// Keep the heading with the first lines of the text.
+	err := l.ensureSpace(p.Height() + 60)
+	if err != nil {
+		return err
+	}
+	err = l.drawParagraph(p)
+	if err != nil {
+		return err
+	}
+	l.y += 8
+	return nil
+}
+
+// Draws a line of text at x that links to the destination.
+func (l *docLayout) drawLink(text, dest string, x float64) error {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontHelvetica())
+	p.SetFontSize(12)
+	p.SetEnableWrap(false)
+	p.SetColor(linkColor)
+	err := l.ensureSpace(p.Height())
+	if err != nil {
+		return err
+	}
+	p.SetPos(x, l.y)
+	err = l.c.Draw(p)
+	if err != nil {
+		return err
+	}
+	l.addLink(x-linkPadding, l.y-linkPadding, p.Width()+2*linkPadding, p.Height()+2*linkPadding, dest)
+	l.y += p.Height() + 6
+	return nil
+}
+
+// Draws the section: its heading, which is the target of the destination, the text and a "Back to top" button.
+func (l *docLayout) drawSection(s section) error {
+	if s.newPage {
+		err := l.newPage()
+		if err != nil {
+			return err
+		}
+	}
+	err := l.drawHeading(s.title)
+	if err != nil {
+		return err
+	}
+	// The destination is a little above the heading, which has been moved below it.
+	top := l.y - 8 - 20
+	l.addDestination(s.dest, s.view, s.zoom, margin, top, 0, 0)
+
+	fontSize := s.fontSize
+	if fontSize == 0 {
+		fontSize = 11
+	}
+	for _, text := range s.text {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(fontSize)
+		p.SetLineHeight(1.3)
+		p.SetWidth(l.c.Width() - 2*margin)
+		err = l.drawParagraph(p)
+		if err != nil {
+			return err
+		}
+		l.y += 8
+	}
+
+	if s.dest == "wiring" {
+		err = l.drawFigure()
+		if err != nil {
+			return err
+		}
+	}
+	if s.dest == "notes" {
+		err = l.drawNoteLines()
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.seeAlso != "" {
+		p := creator.NewParagraph("See also:")
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(11)
+		p.SetEnableWrap(false)
+		p.SetColor(textColor)
+		err = l.ensureSpace(p.Height())
+		if err != nil {
+			return err
+		}
+		p.SetPos(margin, l.y)
+		err = l.c.Draw(p)
+		if err != nil {
+			return err
+		}
+		err = l.drawLink(destTitles[s.seeAlso], s.seeAlso, margin+p.Width()+4)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = l.drawButton("Back to top", "top")
+	if err != nil {
+		return err
+	}
+	l.y += 24
+	return nil
+}
+
+// Draws a button at the left margin that links to the destination: a filled rectangle with the text.
+func (l *docLayout) drawButton(text, dest string) error {
+	p := creator.NewParagraph(text)
+	p.SetFont(fonts.NewFontHelveticaBold())
+	p.SetFontSize(10)
+	p.SetEnableWrap(false)
+	p.SetColor(linkColor)
+	width := p.Width() + 16
+	height := p.Height() + 10
+	err := l.ensureSpace(height + 4)
+	if err != nil {
+		return err
+	}
+	l.y += 4
+
+	rect := creator.NewRectangle(margin, l.y, width, height)
+	rect.SetFillColor(buttonColor)
+	rect.SetBorderColor(linkColor)
+	rect.SetBorderWidth(0.5)
+	err = l.c.Draw(rect)
+	if err != nil {
+		return err
+	}
+	// The paragraph height includes the descenders below the baseline, move the text down a little to center it.
+	p.SetPos(margin+8, l.y+6)
+	err = l.c.Draw(p)
+	if err != nil {
+		return err
+	}
+	l.addLink(margin, l.y, width, height, dest)
+	l.y += height
+	return nil
+}
+
+// Draws the wiring diagram: the controller with the sensor on the left and the outputs on the right.  The figure is
+// the target of the FitR destination.
+func (l *docLayout) drawFigure() error {
+	width := 400.0
+	height := 210.0
+	err := l.ensureSpace(height + 30)
+	if err != nil {
+		return err
+	}
+	x := margin + (l.c.Width()-2*margin-width)/2
+	y := l.y + 6
+
+	frame := creator.NewRectangle(x, y, width, height)
+	frame.SetBorderColor(creator.ColorRGBFrom8bit(160, 160, 160))
+	frame.SetBorderWidth(0.5)
+	err = l.c.Draw(frame)
+	if err != nil {
+		return err
+	}
+
+	boxes := []struct {
+		label      string
+		x, y, w, h float64
+		fill       creator.Color
+		isOutput   bool
+	}{
+		{label: "Sensor", x: 20, y: 85, w: 80, h: 40, fill: creator.ColorRGBFrom8bit(220, 240, 220)},
+		{label: "Controller", x: 150, y: 40, w: 100, h: 130, fill: creator.ColorRGBFrom8bit(230, 230, 230)},
+		{label: "Heater", x: 300, y: 25, w: 80, h: 36, fill: creator.ColorRGBFrom8bit(250, 225, 215), isOutput: true},
+		{label: "Vents", x: 300, y: 87, w: 80, h: 36, fill: creator.ColorRGBFrom8bit(250, 225, 215), isOutput: true},
+		{label: "Sprinklers", x: 300, y: 149, w: 80, h: 36, fill: creator.ColorRGBFrom8bit(250, 225, 215),
+			isOutput: true},
+	}
+	for _, b := range boxes {
+		rect := creator.NewRectangle(x+b.x, y+b.y, b.w, b.h)
+		rect.SetFillColor(b.fill)
+		rect.SetBorderColor(textColor)
+		rect.SetBorderWidth(1)
+		err = l.c.Draw(rect)
+		if err != nil {
+			return err
+		}
+		p := creator.NewParagraph(b.label)
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(10)
+		p.SetEnableWrap(false)
+		p.SetPos(x+b.x+(b.w-p.Width())/2, y+b.y+b.h/2-5)
+		err = l.c.Draw(p)
+		if err != nil {
+			return err
+		}
+
+		// The connections to the controller, as thin rectangles.
+		if b.label == "Sensor" {
+			err = l.drawWire(x+b.x+b.w, y+b.y+b.h/2, x+150)
+		} else if b.isOutput {
+			err = l.drawWire(x+250, y+b.y+b.h/2, x+b.x)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	caption := creator.NewParagraph(destTitles["figure-wiring"])
+	caption.SetFont(fonts.NewFontHelveticaOblique())
+	caption.SetFontSize(10)
+	caption.SetEnableWrap(false)
+	caption.SetPos(x+(width-caption.Width())/2, y+height+6)
+	err = l.c.Draw(caption)
+	if err != nil {
+		return err
+	}
+
+	// Zoom in on the figure with its caption, with a small margin.
+	l.addDestination("figure-wiring", "FitR", 0, x-10, y-10, width+20, height+caption.Height()+26)
+	l.y = y + height + caption.Height() + 20
+	return nil
+}
+
+// Draws a horizontal wire from x1 to x2 at y.
+func (l *docLayout) drawWire(x1, y, x2 float64) error {
+	wire := creator.NewRectangle(x1, y-0.75, x2-x1, 1.5)
+	wire.SetFillColor(textColor)
+	return l.c.Draw(wire)
+}
+
+// Draws lines for notes to the bottom of the page.
+func (l *docLayout) drawNoteLines() error {
+	for y := l.y + 24; y < l.c.Height()-margin-40; y += 24 {
+		line := creator.NewRectangle(margin, y, l.c.Width()-2*margin, 0.5)
+		line.SetFillColor(creator.ColorRGBFrom8bit(180, 180, 180))
+		err := l.c.Draw(line)
+		if err != nil {
+			return err
+		}
+	}
+	l.y = l.c.Height() - margin - 30
+	return nil
+}
+
+// Adds a destination on the current page.  The area is in creator coordinates, from the upper left corner of the
+// page.
+func (l *docLayout) addDestination(name, view string, zoom, x, y, width, height float64) {
+	pageHeight := l.c.Height()
+	l.dests = append(l.dests, destination{
+		name: name,
+		page: len(l.pages),
+		view: view,
+		rect: pdf.PdfRectangle{Llx: x, Lly: pageHeight - y - height, Urx: x + width, Ury: pageHeight - y},
+		zoom: zoom,
+	})
+}
+
+// Adds a Link annotation with a GoTo action to the named destination on the current page.  The area is in creator
+// coordinates, from the upper left corner of the page.  The border is drawn in the link color if drawBorders is set,
+// otherwise it is invisible (border width 0).
+func (l *docLayout) addLink(x, y, width, height float64, dest string) {
+	page := l.pages[len(l.pages)-1]
+	pageHeight := page.MediaBox.Ury - page.MediaBox.Lly
+
+	// The destination is the name as a string: a name in the Dests name tree.
+	action := pdfcore.MakeDict()
+	action.Set("S", pdfcore.MakeName("GoTo"))
+	action.Set("D", pdfcore.MakeString(dest))
+
+	annot := pdf.NewPdfAnnotationLink()
+	// The annotation rectangle is in PDF coordinates, with the origin in the lower left corner.
+	annot.Rect = pdfcore.MakeArrayFromFloats([]float64{
+		page.MediaBox.Llx + x, page.MediaBox.Lly + pageHeight - y - height,
+		page.MediaBox.Llx + x + width, page.MediaBox.Lly + pageHeight - y,
+	})
+	annot.A = action
+	// Highlight the link area when clicked.
+	annot.H = pdfcore.MakeName("I")
+	if l.drawBorders {
+		r, g, b := linkColor.ToRGB()
+		annot.Border = pdfcore.MakeArrayFromIntegers([]int{0, 0, 1})
+		annot.C = pdfcore.MakeArrayFromFloats([]float64{r, g, b})
+	} else {
+		annot.Border = pdfcore.MakeArrayFromIntegers([]int{0, 0, 0})
+	}
+
+	page.Annotations = append(page.Annotations, annot.PdfAnnotation)
+	l.numLinks++
+}
+
+// Adds the destinations to the Dests name tree of the catalog, appended to the file as an incremental update.  The
+// destinations refer to the page objects of the written file.
+func addNamedDestinations(path string, dests []destination) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	rootRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return errors.New("Missing Root in trailer")
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	catalogObj, err := pdfReader.GetIndirectObjectByNumber(int(rootRef.ObjectNumber))
+	if err != nil {
+		return err
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(catalogObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Catalog not a dictionary")
+	}
+
+	// The names of a name tree node must be sorted.  A single node (the root) with all names is fine for a few
+	// destinations, large trees are split into kids with the limits of their names.
+	sort.Slice(dests, func(i, j int) bool { return dests[i].name < dests[j].name })
+	treeNames := pdfcore.MakeArray()
+	for _, dest := range dests {
+		pageObj, err := pdfReader.GetPageAsIndirectObject(dest.page)
+		if err != nil {
+			return err
+		}
+		pageInd, ok := pageObj.(*pdfcore.PdfIndirectObject)
+		if !ok {
+			return fmt.Errorf("Page %d not an indirect object", dest.page)
+		}
+		pageRef := &pdfcore.PdfObjectReference{
+			ObjectNumber:     pageInd.ObjectNumber,
+			GenerationNumber: pageInd.GenerationNumber,
+		}
+		treeNames.Append(pdfcore.MakeString(dest.name))
+		treeNames.Append(makeExplicitDestination(pageRef, dest))
+		fmt.Printf("Destination %s: page %d, %s\n", dest.name, dest.page, describeView(dest))
+	}
+
+	treeNum := int64(*size)
+	tree := pdfcore.MakeDict()
+	tree.Set("Names", treeNames)
+
+	// Keep the other name trees of an existing name dictionary.
+	names, ok := pdfcore.TraceToDirectObject(catalog.Get("Names")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		names = pdfcore.MakeDict()
+	}
+	names.Set("Dests", &pdfcore.PdfObjectReference{ObjectNumber: treeNum})
+	catalog.Set("Names", names)
+
+	objects := []updateObject{
+		{number: rootRef.ObjectNumber, obj: catalog},
+		{number: treeNum, obj: tree},
+	}
+	update, err := writeUpdate(data, objects, trailer, treeNum+1)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(update)
+	return err
+}
+
+// Returns the explicit destination for the view of the page: [page /XYZ left top zoom], [page /FitH top],
+// [page /FitR left bottom right top] or [page /Fit].
+func makeExplicitDestination(pageRef *pdfcore.PdfObjectReference, dest destination) *pdfcore.PdfObjectArray {
+	arr := pdfcore.MakeArray(pageRef, pdfcore.MakeName(dest.view))
+	switch dest.view {
+	case "XYZ":
+		// A null zoom keeps the current zoom of the viewer.
+		var zoom pdfcore.PdfObject = pdfcore.MakeNull()
+		if dest.zoom > 0 {
+			zoom = pdfcore.MakeFloat(dest.zoom)
+		}
+		arr.Append(pdfcore.MakeFloat(dest.rect.Llx))
+		arr.Append(pdfcore.MakeFloat(dest.rect.Ury))
+		arr.Append(zoom)
+	case "FitH":
+		arr.Append(pdfcore.MakeFloat(dest.rect.Ury))
+	case "FitR":
+		for _, v := range []float64{dest.rect.Llx, dest.rect.Lly, dest.rect.Urx, dest.rect.Ury} {
+			arr.Append(pdfcore.MakeFloat(v))
+		}
+	}
+	return arr
+}
+
+// Returns a description of the view of the destination.
+func describeView(dest destination) string {
+	switch dest.view {
+	case "XYZ":
+		zoom := "current zoom"
+		if dest.zoom > 0 {
+			zoom = fmt.Sprintf("zoom %g%%", dest.zoom*100)
+		}
+		return fmt.Sprintf("position %.0f, %.0f at %s", dest.rect.Llx, dest.rect.Ury, zoom)
+	case "FitH":
+		return fmt.Sprintf("fit width from %.0f", dest.rect.Ury)
+	case "FitR":
+		return fmt.Sprintf("fit rectangle %.0f, %.0f - %.0f, %.0f", dest.rect.Llx, dest.rect.Lly, dest.rect.Urx,
+			dest.rect.Ury)
+	}
+	return "fit page"
+}
@@ -0,0 +1,412 @@
+/*
+ * Set the initial view of a PDF file: the page and zoom it opens at, the page layout, the page mode (which panel is
+ * open) and the viewer preferences for the window and user interface.
+ *
+ * The settings are entries of the catalog:
+ * - OpenAction: the destination shown when the document is opened, a page with a zoom: fit (whole page), width (fit
+ *   the page width), height (fit the page height) or a percentage such as 125.
+ * - PageLayout: single (one page at a time), continuous (one column, scrolling), two-up (two pages side by side) or
+ *   two-up-continuous (two columns, scrolling).  With -cover the odd pages are on the right in the two page layouts,
+ *   so that the first page is shown alone as a cover, as in a printed book.
+ * - PageMode: none, bookmarks (outline panel open), thumbnails (page panel open), fullscreen, layers (optional content
+ *   panel open) or attachments (attachments panel open).
+ * - ViewerPreferences: -hide-toolbar, -hide-menubar, -hide-ui (scroll bars and navigation controls), -fit-window
+ *   (resize the window to the first page), -center-window and -display-title (show the document title rather than
+ *   the file name in the title bar).
+ *
+ * All of these are requests to the viewer and viewers may ignore them: the OpenAction is honored by most viewers,
+ * unless the user has set the viewer to restore the last view of the file.  The page layout and mode are honored by
+ * the desktop viewers, but browsers and mobile viewers often use their own.  The window preferences only apply to
+ * viewers that open documents in their own window, they have no effect in browser tabs, and full screen mode asks the
+ * user for permission in some viewers.  Therefore a document should be readable in any view.
+ *
+ * The entries are set in an incremental update appended to a copy of the input file, as the model does not give access
+ * to the document catalog.  The update is written by writeUpdate in update.go, shared with named_destinations.go.
+ * Existing viewer preferences that are not given are kept.
+ *
+ * Run as: go run open_action.go update.go [options] input.pdf output.pdf
+ * For example: go run open_action.go -page 3 -zoom width -layout continuous -mode bookmarks input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run open_action.go update.go [options] input.pdf output.pdf\n"
+
+// initialView is the view of the document when it is opened.  Empty values are not set.
+type initialView struct {
+	page   int
+	zoom   string
+	layout string
+	cover  bool
+	mode   string
+	prefs  map[string]bool // Viewer preferences that are set to true.
+}
+
+// Page layouts with their PageLayout names, the second for -cover.
+var pageLayouts = map[string][2]string{
+	"single":            {"SinglePage", "SinglePage"},
+	"continuous":        {"OneColumn", "OneColumn"},
+	"two-up":            {"TwoPageLeft", "TwoPageRight"},
+	"two-up-continuous": {"TwoColumnLeft", "TwoColumnRight"},
+}
+
+// Page modes with their PageMode names.
+var pageModes = map[string]string{
+	"none":        "UseNone",
+	"bookmarks":   "UseOutlines",
+	"thumbnails":  "UseThumbs",
+	"fullscreen":  "FullScreen",
+	"layers":      "UseOC",
+	"attachments": "UseAttachments",
+}
+
+// The PDF versions that introduced the names, older names are in PDF 1.3.
+var nameVersions = map[string]string{
+	"TwoPageLeft":    "1.5",
+	"TwoPageRight":   "1.5",
+	"UseOC":          "1.5",
+	"UseAttachments": "1.6",
+}
+
+// Viewer preference flags with their ViewerPreferences keys.
+var viewerPrefFlags = []struct {
+	flag, key, description string
+}{
+	{"hide-toolbar", "HideToolbar", "Hide the viewer toolbars"},
+	{"hide-menubar", "HideMenubar", "Hide the viewer menu bar"},
+	{"hide-ui", "HideWindowUI", "Hide the scroll bars and navigation controls, only show the pages"},
+	{"fit-window", "FitWindow", "Resize the viewer window to fit the first page"},
+	{"center-window", "CenterWindow", "Center the viewer window on the screen"},
+	{"display-title", "DisplayDocTitle", "Show the document title in the window title bar, not the file name"},
+}
+
+func main() {
+	view := initialView{prefs: map[string]bool{}}
+	flag.IntVar(&view.page, "page", 0, "Open at this page (from 1)")
+	flag.StringVar(&view.zoom, "zoom", "", "Zoom when opened: fit, width, height or a percentage such as 125")
+	flag.StringVar(&view.layout, "layout", "", "Page layout: single, continuous, two-up or two-up-continuous")
+	flag.BoolVar(&view.cover, "cover", false, "Show the first page alone in the two page layouts (odd pages right)")
+	flag.StringVar(&view.mode, "mode", "",
+		"Page mode: none, bookmarks, thumbnails, fullscreen, layers or attachments")
+	prefs := map[string]*bool{}
+	for _, p := range viewerPrefFlags {
+		prefs[p.key] = flag.Bool(p.flag, false, p.description)
+	}
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	for key, set := range prefs {
+		if *set {
+			view.prefs[key] = true
+		}
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := setInitialView(inputPath, outputPath, view)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func setInitialView(inputPath, outputPath string, view initialView) error {
+	if _, ok := pageLayouts[view.layout]; view.layout != "" && !ok {
+		return fmt.Errorf("Invalid page layout %q", view.layout)
+	}
+	if _, ok := pageModes[view.mode]; view.mode != "" && !ok {
+		return fmt.Errorf("Invalid page mode %q", view.mode)
+	}
+	if view.cover && !strings.HasPrefix(view.layout, "two-up") {
+		return errors.New("-cover needs a two page layout (two-up or two-up-continuous)")
+	}
+	if view.page == 0 && view.zoom == "" && view.layout == "" && view.mode == "" && len(view.prefs) == 0 {
+		return errors.New("Nothing to set, give the page, zoom, layout, mode or viewer preferences")
+	}
+
+	data, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		return errors.New("Setting the initial view of encrypted files is not supported")
+	}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if view.page < 0 || view.page > numPages {
+		return fmt.Errorf("Page %d out of range (document has %d pages)", view.page, numPages)
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	rootRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return errors.New("Missing Root in trailer")
+	}
+	catalog, err := getCatalog(pdfReader, trailer)
+	if err != nil {
+		return err
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	prevVersion := pdfVersion(data, catalog)
+	version := prevVersion
+
+	// The open action is an explicit destination, the page is the first page if only the zoom is given.
+	if view.page > 0 || view.zoom != "" {
+		if view.page == 0 {
+			view.page = 1
+		}
+		dest, desc, err := makeOpenDestination(pdfReader, view.page, view.zoom)
+		if err != nil {
+			return err
+		}
+		catalog.Set("OpenAction", dest)
+		fmt.Printf("Open action: page %d, %s\n", view.page, desc)
+	}
+
+	if view.layout != "" {
+		layout := pageLayouts[view.layout][0]
+		if view.cover {
+			layout = pageLayouts[view.layout][1]
+		}
+		catalog.Set("PageLayout", pdfcore.MakeName(layout))
+		version = requireVersion(version, layout)
+		fmt.Printf("Page layout: %s\n", layout)
+	}
+
+	if view.mode != "" {
+		mode := pageModes[view.mode]
+		catalog.Set("PageMode", pdfcore.MakeName(mode))
+		version = requireVersion(version, mode)
+		fmt.Printf("Page mode: %s\n", mode)
+		warnMissingPanel(pdfReader, catalog, view.mode)
+	}
+
+	if len(view.prefs) > 0 || view.mode == "fullscreen" {
+		obj, err := resolve(pdfReader, catalog.Get("ViewerPreferences"))
+		if err != nil {
+			return err
+		}
+		prefs, ok := obj.(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			prefs = pdfcore.MakeDict()
+		}
+		for _, p := range viewerPrefFlags {
+			if view.prefs[p.key] {
+				value := pdfcore.PdfObjectBool(true)
+				prefs.Set(pdfcore.PdfObjectName(p.key), &value)
+				fmt.Printf("Viewer preference: %s\n", p.key)
+			}
+		}
+		// The page mode when leaving full screen mode: the outline panel if the document has one.
+		if view.mode == "fullscreen" && prefs.Get("NonFullScreenPageMode") == nil {
+			mode := "UseNone"
+			if catalog.Get("Outlines") != nil {
+				mode = "UseOutlines"
+			}
+			prefs.Set("NonFullScreenPageMode", pdfcore.MakeName(mode))
+			fmt.Printf("Viewer preference: NonFullScreenPageMode %s\n", mode)
+		}
+		catalog.Set("ViewerPreferences", prefs)
+		if view.prefs["DisplayDocTitle"] {
+			warnMissingTitle(pdfReader, trailer)
+		}
+	}
+
+	if version != prevVersion {
+		catalog.Set("Version", pdfcore.MakeName(version))
+		fmt.Printf("PDF version: %s\n", version)
+	}
+
+	objects := []updateObject{
+		{number: rootRef.ObjectNumber, generation: rootRef.GenerationNumber, obj: catalog, description: "catalog"},
+	}
+	update, err := writeUpdate(data, objects, trailer, int64(*size))
+	if err != nil {
+		return err
+	}
+	for _, obj := range objects {
+		fmt.Printf("Wrote object %d: %s\n", obj.number, obj.description)
+	}
+
+	fmt.Printf("Note: the initial view is a request to the viewer, which may be overridden by the viewer settings\n")
+	return ioutil.WriteFile(outputPath, append(data, update...), 0644)
+}
+
+// Returns the explicit destination for the page and zoom with a description: [page /Fit], [page /FitH top],
+// [page /FitV left] or [page /XYZ left top zoom] at the upper left corner of the page.
+func makeOpenDestination(pdfReader *pdf.PdfReader, pageNum int, zoom string) (*pdfcore.PdfObjectArray, string,
+	error) {
+	pageObj, err := pdfReader.GetPageAsIndirectObject(pageNum)
+	if err != nil {
+		return nil, "", err
+	}
+	pageInd, ok := pageObj.(*pdfcore.PdfIndirectObject)
+	if !ok {
+		return nil, "", fmt.Errorf("Page %d not an indirect object", pageNum)
+	}
+	page, err := pdfReader.GetPage(pageNum)
+	if err != nil {
+		return nil, "", err
+	}
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, "", err
+	}
+	pageRef := &pdfcore.PdfObjectReference{ObjectNumber: pageInd.ObjectNumber,
+		GenerationNumber: pageInd.GenerationNumber}
+
+	dest := pdfcore.MakeArray(pageRef)
+	switch zoom {
+	case "", "fit":
+		dest.Append(pdfcore.MakeName("Fit"))
+		return dest, "fit page", nil
+	case "width":
+		dest.Append(pdfcore.MakeName("FitH"))
+		dest.Append(pdfcore.MakeFloat(mbox.Ury))
+		return dest, "fit width", nil
+	case "height":
+		dest.Append(pdfcore.MakeName("FitV"))
+		dest.Append(pdfcore.MakeFloat(mbox.Llx))
+		return dest, "fit height", nil
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(zoom, "%"), 64)
+	if err != nil || percent <= 0 || percent > 6400 {
+		return nil, "", fmt.Errorf("Invalid zoom %q, expected fit, width, height or a percentage up to 6400", zoom)
+	}
+	dest.Append(pdfcore.MakeName("XYZ"))
+	dest.Append(pdfcore.MakeFloat(mbox.Llx))
+	dest.Append(pdfcore.MakeFloat(mbox.Ury))
+	dest.Append(pdfcore.MakeFloat(percent / 100))
+	return dest, fmt.Sprintf("zoom %g%%", percent), nil
+}
+
+// Prints a warning if the document has nothing to show in the panel of the page mode.
+func warnMissingPanel(pdfReader *pdf.PdfReader, catalog *pdfcore.PdfObjectDictionary, mode string) {
+	switch mode {
+	case "bookmarks":
+		if catalog.Get("Outlines") == nil {
+			fmt.Printf("Warning: the document has no bookmarks, the panel is empty\n")
+		}
+	case "layers":
+		if catalog.Get("OCProperties") == nil {
+			fmt.Printf("Warning: the document has no layers (optional content), the panel is empty\n")
+		}
+	case "attachments":
+		obj, _ := resolve(pdfReader, catalog.Get("Names"))
+		names, ok := obj.(*pdfcore.PdfObjectDictionary)
+		if !ok || names.Get("EmbeddedFiles") == nil {
+			fmt.Printf("Warning: the document has no attached files, the panel is empty\n")
+		}
+	}
+}
+
+// Prints a warning if the document has no title to display: the title bar shows the file name then.
+func warnMissingTitle(pdfReader *pdf.PdfReader, trailer *pdfcore.PdfObjectDictionary) {
+	obj, _ := resolve(pdfReader, trailer.Get("Info"))
+	if info, ok := obj.(*pdfcore.PdfObjectDictionary); ok {
+		if title, ok := pdfcore.TraceToDirectObject(info.Get("Title")).(*pdfcore.PdfObjectString); ok && *title != "" {
+			return
+		}
+	}
+	fmt.Printf("Warning: the document has no title, the file name is displayed\n")
+}
+
+// Returns the PDF version of the file: the version of the header, or of the catalog if later.  The catalog is not
+// used if nil.
+func pdfVersion(data []byte, catalog *pdfcore.PdfObjectDictionary) string {
+	version := "1.3"
+	if bytes.HasPrefix(data, []byte("%PDF-")) && len(data) >= 8 {
+		version = string(data[5:8])
+	}
+	if catalog != nil {
+		if v, ok := pdfcore.TraceToDirectObject(catalog.Get("Version")).(*pdfcore.PdfObjectName); ok &&
+			string(*v) > version {
+			version = string(*v)
+		}
+	}
+	return version
+}
+
+// Returns the version needed for the name: the version, or the version that introduced the name if later.
+func requireVersion(version, name string) string {
+	if v, ok := nameVersions[name]; ok && v > version {
+		return v
+	}
+	return version
+}
+
+// Returns the direct object, looking up references.
+func resolve(pdfReader *pdf.PdfReader, obj pdfcore.PdfObject) (pdfcore.PdfObject, error) {
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		resolved, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, err
+		}
+		obj = resolved
+	}
+	return pdfcore.TraceToDirectObject(obj), nil
+}
+
+// Returns the document catalog.
+func getCatalog(pdfReader *pdf.PdfReader, trailer *pdfcore.PdfObjectDictionary) (*pdfcore.PdfObjectDictionary,
+	error) {
+	root := trailer.Get("Root")
+	if ref, ok := root.(*pdfcore.PdfObjectReference); ok {
+		obj, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, err
+		}
+		root = obj
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(root).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("Missing catalog")
+	}
+	return catalog, nil
+}
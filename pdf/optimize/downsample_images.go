@@ -0,0 +1,381 @@
+/*
+ * Downsample embedded images to a maximum resolution (DPI) based on the size they are displayed at on the pages.
+ *
+ * The content streams of all pages (and of the form XObjects they use) are processed while tracking the current
+ * transformation matrix, to find the size each image is displayed at: images are drawn on the unit square, so the
+ * displayed width and height (in points) are the lengths of the transformed unit vectors.  The effective resolution
+ * is then the pixel width divided by the displayed width in inches (and likewise for the height).
+ *
+ * An image used at multiple placements (e.g. a logo on every page, at different sizes) is resampled to the largest
+ * resolution needed by any of its placements, so it never gets too coarse for one of them.  Images already at or
+ * below the maximum DPI are left as they are.  The resampled images are stored as JPEG with the given quality.
+ *
+ * Supported are 8 bit DeviceRGB and DeviceGray images, other images are skipped.  Inline images are not processed.
+ *
+ * Run as: go run downsample_images.go [-dpi 150] [-quality 75] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	goimage "image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"os"
+
+	unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run downsample_images.go [-dpi 150] [-quality 75] input.pdf output.pdf\n"
+
+// imageUsage records the largest display size of an image over all its placements, in points.
+type imageUsage struct {
+	name          string // Resource name of the first placement, for reporting.
+	page          int    // Page of the first placement.
+	displayWidth  float64
+	displayHeight float64
+}
+
+func main() {
+	maxDPI := 0.0
+	quality := 0
+	flag.Float64Var(&maxDPI, "dpi", 150, "Maximum image resolution in DPI")
+	flag.IntVar(&quality, "quality", 75, "JPEG quality of the resampled images")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// Enable debug-level logging.
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := downsampleImages(inputPath, outputPath, maxDPI, quality)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func downsampleImages(inputPath string, outputPath string, maxDPI float64, quality int) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	// Find the display sizes of all the images.
+	usages := map[*pdfcore.PdfObjectStream]*imageUsage{}
+	order := []*pdfcore.PdfObjectStream{}
+	pages := []*pdf.PdfPage{}
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, page)
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return err
+		}
+
+		onImage := func(name pdfcore.PdfObjectName, stream *pdfcore.PdfObjectStream, w, h float64) {
+			u, has := usages[stream]
+			if !has {
+				u = &imageUsage{name: string(name), page: i + 1}
+				usages[stream] = u
+				order = append(order, stream)
+			}
+			u.displayWidth = math.Max(u.displayWidth, w)
+			u.displayHeight = math.Max(u.displayHeight, h)
+		}
+
+		err = findImagePlacements(contents, page.Resources, [6]float64{1, 0, 0, 1, 0, 0}, onImage, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Resample the images.
+	for _, stream := range order {
+		u := usages[stream]
+		done, err := resampleImage(stream, u, maxDPI, quality)
+		if err != nil {
+			fmt.Printf("Page %d, image %s: skipped (%v)\n", u.page, u.name, err)
+			continue
+		}
+		if !done {
+			fmt.Printf("Page %d, image %s: already at or below %.0f DPI\n", u.page, u.name, maxDPI)
+		}
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for _, page := range pages {
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Processes the content stream, calling onImage for each image XObject drawn with its display width and height in
+// points.  Form XObjects are processed recursively.
+func findImagePlacements(contents string, resources *pdf.PdfPageResources, ctm [6]float64,
+	onImage func(name pdfcore.PdfObjectName, stream *pdfcore.PdfObjectStream, w, h float64), depth int) error {
+	cstreamParser := pdfcontent.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return err
+	}
+
+	stack := [][6]float64{}
+	for _, op := range *operations {
+		switch op.Operand {
+		case "q":
+			stack = append(stack, ctm)
+		case "Q":
+			if len(stack) > 0 {
+				ctm = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(op.Params) != 6 {
+				continue
+			}
+			m := [6]float64{}
+			for i, p := range op.Params {
+				m[i], _ = getNumberAsFloat(p)
+			}
+			ctm = multiply(m, ctm)
+		case "Do":
+			if len(op.Params) != 1 || resources == nil {
+				continue
+			}
+			name, ok := op.Params[0].(*pdfcore.PdfObjectName)
+			if !ok {
+				continue
+			}
+
+			stream, xtype := resources.GetXObjectByName(*name)
+			if xtype == pdf.XObjectTypeImage {
+				// The unit square is mapped to the display area.
+				w := math.Hypot(ctm[0], ctm[1])
+				h := math.Hypot(ctm[2], ctm[3])
+				onImage(*name, stream, w, h)
+			} else if xtype == pdf.XObjectTypeForm && depth < 10 {
+				xform, err := pdf.NewXObjectFormFromStream(stream)
+				if err != nil {
+					return err
+				}
+				formContents, err := xform.GetContentStream()
+				if err != nil {
+					return err
+				}
+				formMatrix := [6]float64{1, 0, 0, 1, 0, 0}
+				if arr, ok := pdfcore.TraceToDirectObject(xform.Matrix).(*pdfcore.PdfObjectArray); ok {
+					vals, err := arr.ToFloat64Array()
+					if err == nil && len(vals) == 6 {
+						copy(formMatrix[:], vals)
+					}
+				}
+				formResources := xform.Resources
+				if formResources == nil {
+					formResources = resources
+				}
+				err = findImagePlacements(string(formContents), formResources, multiply(formMatrix, ctm), onImage,
+					depth+1)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns the matrix product m x n.
+func multiply(m, n [6]float64) [6]float64 {
+	return [6]float64{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// Resamples the image to the resolution needed for its largest placement at maxDPI, if its effective resolution is
+// higher.  The stream is updated in place, so all placements use the resampled image.  Returns false if the image
+// did not need resampling.
+func resampleImage(stream *pdfcore.PdfObjectStream, u *imageUsage, maxDPI float64, quality int) (bool, error) {
+	dict := stream.PdfObjectDictionary
+
+	width, _ := pdfcore.TraceToDirectObject(dict.Get("Width")).(*pdfcore.PdfObjectInteger)
+	height, _ := pdfcore.TraceToDirectObject(dict.Get("Height")).(*pdfcore.PdfObjectInteger)
+	bpc, _ := pdfcore.TraceToDirectObject(dict.Get("BitsPerComponent")).(*pdfcore.PdfObjectInteger)
+	cs, _ := pdfcore.TraceToDirectObject(dict.Get("ColorSpace")).(*pdfcore.PdfObjectName)
+	if width == nil || height == nil || bpc == nil || cs == nil || *bpc != 8 || dict.Get("Decode") != nil {
+		return false, errors.New("Unsupported image format")
+	}
+	w, h := int(*width), int(*height)
+
+	// Effective resolution at the largest placement (72 points per inch).
+	dpiX := float64(w) / (u.displayWidth / 72)
+	dpiY := float64(h) / (u.displayHeight / 72)
+	if dpiX <= maxDPI || dpiY <= maxDPI {
+		// Scaling is done uniformly, so the image is limited by the direction with the lowest resolution.
+		return false, nil
+	}
+	scale := maxDPI / math.Min(dpiX, dpiY)
+
+	components := 0
+	switch *cs {
+	case "DeviceGray":
+		components = 1
+	case "DeviceRGB":
+		components = 3
+	default:
+		return false, fmt.Errorf("Unsupported colorspace %s", *cs)
+	}
+
+	data, err := pdfcore.DecodeStream(stream)
+	if err != nil {
+		return false, err
+	}
+	if len(data) < w*h*components {
+		return false, errors.New("Image data too short")
+	}
+
+	nw := int(math.Ceil(float64(w) * scale))
+	nh := int(math.Ceil(float64(h) * scale))
+	img := boxDownsample(data, w, h, components, nw, nh)
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	if err != nil {
+		return false, err
+	}
+
+	unicommon.Log.Debug("Image %s: %dx%d -> %dx%d", u.name, w, h, nw, nh)
+	fmt.Printf("Page %d, image %s: %dx%d px at %.0f DPI -> %dx%d px, %d -> %d bytes\n", u.page, u.name, w, h,
+		math.Min(dpiX, dpiY), nw, nh, len(stream.Stream), buf.Len())
+
+	stream.Stream = buf.Bytes()
+	dict.Set("Filter", pdfcore.MakeName(pdfcore.StreamEncodingFilterNameDCT))
+	dict.Remove("DecodeParms")
+	dict.Set("Width", pdfcore.MakeInteger(int64(nw)))
+	dict.Set("Height", pdfcore.MakeInteger(int64(nh)))
+	dict.Set("Length", pdfcore.MakeInteger(int64(buf.Len())))
+	return true, nil
+}
+
+// Scales the 8 bit image samples down to nw x nh by averaging the source pixels covered by each target pixel.
+func boxDownsample(data []byte, w, h, components int, nw, nh int) goimage.Image {
+	var gray *goimage.Gray
+	var rgba *goimage.RGBA
+	if components == 1 {
+		gray = goimage.NewGray(goimage.Rect(0, 0, nw, nh))
+	} else {
+		rgba = goimage.NewRGBA(goimage.Rect(0, 0, nw, nh))
+	}
+
+	for y := 0; y < nh; y++ {
+		y0 := y * h / nh
+		y1 := (y + 1) * h / nh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < nw; x++ {
+			x0 := x * w / nw
+			x1 := (x + 1) * w / nw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			sum := [3]int{}
+			n := 0
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					idx := (sy*w + sx) * components
+					for k := 0; k < components; k++ {
+						sum[k] += int(data[idx+k])
+					}
+					n++
+				}
+			}
+
+			if components == 1 {
+				gray.SetGray(x, y, color.Gray{Y: uint8(sum[0] / n)})
+			} else {
+				rgba.SetRGBA(x, y, color.RGBA{R: uint8(sum[0] / n), G: uint8(sum[1] / n), B: uint8(sum[2] / n),
+					A: 255})
+			}
+		}
+	}
+
+	if components == 1 {
+		return gray
+	}
+	return rgba
+}
+
+func getNumberAsFloat(obj pdfcore.PdfObject) (float64, error) {
+	if fObj, ok := obj.(*pdfcore.PdfObjectFloat); ok {
+		return float64(*fObj), nil
+	}
+
+	if iObj, ok := obj.(*pdfcore.PdfObjectInteger); ok {
+		return float64(*iObj), nil
+	}
+
+	return 0, errors.New("Not a number")
+}
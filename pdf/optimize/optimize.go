@@ -0,0 +1,527 @@
+/*
+ * Optimize a PDF to reduce the file size.
+ *
+ * This unidoc version does not include an optimizer, so the optimization passes are implemented here on the objects
+ * loaded by the reader:
+ * - Stream recompression: uncompressed streams are Flate compressed, and Flate compressed streams (without predictor
+ *   parameters) are recompressed at the best compression level, keeping the result only if it is smaller.
+ * - Deduplication: identical streams (e.g. the same image or embedded font file included multiple times) and
+ *   identical font dictionaries are replaced by a single shared object.
+ * - Removal of unused objects: the output is written by adding the pages to a new PdfWriter, which only writes the
+ *   objects reachable from the pages, and the outline (bookmarks), the interactive form and the optional content
+ *   properties (layers), which are carried over.  Objects that are not referenced anymore (old revisions, unused
+ *   resources outside the page tree) are dropped.
+ *
+ * These passes do not change the content of the pages.  The PdfWriter of this unidoc version cannot carry over the
+ * other parts of the document, though, which are removed: the names of the document catalog (named destinations,
+ * embedded files and document JavaScript), the page labels, the structure tree of tagged PDFs, the XMP metadata and
+ * the document information (title, author, ...).  Links and outline items that go to named destinations no longer
+ * work.  The removed parts are listed.
+ *
+ * Lossy optimization, downsampling large images and reencoding them as JPEG, is only done when explicitly requested
+ * with -lossy.  It applies to 8 bit DeviceRGB and DeviceGray images larger than -max-image-dim pixels (width or
+ * height), which are scaled down to fit and stored with the given -quality.
+ *
+ * The file sizes before and after are printed.
+ *
+ * Run as: go run optimize.go [-lossy] [-max-image-dim 1500] [-quality 75] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"errors"
+	"flag"
+	"fmt"
+	goimage "image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"strings"
+
+	unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run optimize.go [-lossy] [-max-image-dim 1500] [-quality 75] input.pdf output.pdf\n"
+
+// Optimization options.
+type optimizeOptions struct {
+	Lossy       bool
+	MaxImageDim int
+	Quality     int
+}
+
+func main() {
+	opt := optimizeOptions{}
+	flag.BoolVar(&opt.Lossy, "lossy", false, "Enable lossy image downsampling")
+	flag.IntVar(&opt.MaxImageDim, "max-image-dim", 1500, "Maximum image width/height in pixels (with -lossy)")
+	flag.IntVar(&opt.Quality, "quality", 75, "JPEG quality of downsampled images (with -lossy)")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// Enable debug-level logging.
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := optimizePdf(inputPath, outputPath, opt)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	inputInfo, err := os.Stat(inputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	outputInfo, err := os.Stat(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	before := inputInfo.Size()
+	after := outputInfo.Size()
+	fmt.Printf("Size before: %d bytes\n", before)
+	fmt.Printf("Size after:  %d bytes (%.1f%%)\n", after, 100*float64(after)/float64(before))
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func optimizePdf(inputPath string, outputPath string, opt optimizeOptions) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	pages := []*pdf.PdfPage{}
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, page)
+	}
+
+	ocProperties, err := pdfReader.GetOCProperties()
+	if err != nil {
+		return err
+	}
+
+	// Collect all the streams reachable from the pages and the form.
+	c := newObjectCollector()
+	for _, page := range pages {
+		c.collect(page.ToPdfObject())
+	}
+	if pdfReader.AcroForm != nil {
+		c.collect(pdfReader.AcroForm.ToPdfObject())
+	}
+	fmt.Printf("Streams: %d\n", len(c.streams))
+
+	// Lossy: downsample large images.
+	if opt.Lossy {
+		downsampled := 0
+		for _, stream := range c.streams {
+			done, err := downsampleImage(stream, opt.MaxImageDim, opt.Quality)
+			if err != nil {
+				unicommon.Log.Debug("Image not downsampled: %v", err)
+				continue
+			}
+			if done {
+				downsampled++
+			}
+		}
+		fmt.Printf("Images downsampled: %d\n", downsampled)
+	}
+
+	// Recompress streams.
+	recompressed := 0
+	for _, stream := range c.streams {
+		done, err := recompressStream(stream)
+		if err != nil {
+			return err
+		}
+		if done {
+			recompressed++
+		}
+	}
+	fmt.Printf("Streams recompressed: %d\n", recompressed)
+
+	// Deduplicate streams, then fonts.  The font dictionaries are compared after the font file streams have been
+	// deduplicated, as their serialization includes the references to those streams.
+	canonical := map[pdfcore.PdfObject]pdfcore.PdfObject{}
+	byKey := map[string]pdfcore.PdfObject{}
+	for _, stream := range c.streams {
+		key := streamKey(stream)
+		if first, has := byKey[key]; has {
+			canonical[stream] = first
+		} else {
+			byKey[key] = stream
+		}
+	}
+	numStreamDups := len(canonical)
+	c.replace(canonical)
+
+	byKey = map[string]pdfcore.PdfObject{}
+	for _, ind := range c.fonts {
+		key := ind.PdfObject.DefaultWriteString()
+		if first, has := byKey[key]; has {
+			canonical[ind] = first
+		} else {
+			byKey[key] = ind
+		}
+	}
+	c.replace(canonical)
+	fmt.Printf("Duplicates removed: %d streams, %d fonts\n", numStreamDups, len(canonical)-numStreamDups)
+
+	// The page level contents are regenerated from the page model when writing.
+	for _, page := range pages {
+		if obj, has := canonical[page.Contents]; has {
+			page.Contents = obj
+		}
+	}
+
+	// Write out only the pages and what they reference, with the outline, the form and the optional content.
+	pdfWriter := pdf.NewPdfWriter()
+	for _, page := range pages {
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	pdfWriter.AddOutlineTree(pdfReader.GetOutlineTree())
+	if pdfReader.AcroForm != nil {
+		err = pdfWriter.SetForms(pdfReader.AcroForm)
+		if err != nil {
+			return err
+		}
+	}
+	err = pdfWriter.SetOCProperties(ocProperties)
+	if err != nil {
+		return err
+	}
+	err = reportRemoved(pdfReader)
+	if err != nil {
+		return err
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Lists the parts of the document that the PdfWriter does not write: the entries of the document catalog other than
+// the version, the pages, the outline, the form and the optional content, and the document information.
+func reportRemoved(pdfReader *pdf.PdfReader) error {
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+
+	removed := []string{}
+	root := trailer.Get("Root")
+	if ref, ok := root.(*pdfcore.PdfObjectReference); ok {
+		root, err = pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return err
+		}
+	}
+	if catalog, ok := pdfcore.TraceToDirectObject(root).(*pdfcore.PdfObjectDictionary); ok {
+		for _, key := range catalog.Keys() {
+			switch key {
+			case "Type", "Version", "Pages", "Outlines", "AcroForm", "OCProperties":
+				continue
+			}
+			removed = append(removed, string(key))
+		}
+	}
+	if trailer.Get("Info") != nil {
+		removed = append(removed, "Info")
+	}
+
+	if len(removed) > 0 {
+		fmt.Printf("Removed, not supported by the writer: %s\n", strings.Join(removed, ", "))
+	}
+	return nil
+}
+
+// objectCollector walks the object graph and collects the streams and the font dictionaries, and the containers
+// (dictionaries and arrays) referring to them.
+type objectCollector struct {
+	visited    map[pdfcore.PdfObject]bool
+	streams    []*pdfcore.PdfObjectStream
+	fonts      []*pdfcore.PdfIndirectObject
+	containers []pdfcore.PdfObject
+}
+
+func newObjectCollector() *objectCollector {
+	return &objectCollector{visited: map[pdfcore.PdfObject]bool{}}
+}
+
+func (c *objectCollector) collect(obj pdfcore.PdfObject) {
+	if obj == nil || c.visited[obj] {
+		return
+	}
+
+	switch t := obj.(type) {
+	case *pdfcore.PdfIndirectObject:
+		c.visited[obj] = true
+		c.containers = append(c.containers, t)
+		if dict, ok := t.PdfObject.(*pdfcore.PdfObjectDictionary); ok {
+			if typ, ok := dict.Get("Type").(*pdfcore.PdfObjectName); ok && *typ == "Font" {
+				c.fonts = append(c.fonts, t)
+			}
+		}
+		c.collect(t.PdfObject)
+	case *pdfcore.PdfObjectStream:
+		c.visited[obj] = true
+		c.streams = append(c.streams, t)
+		c.collect(t.PdfObjectDictionary)
+	case *pdfcore.PdfObjectDictionary:
+		c.visited[obj] = true
+		c.containers = append(c.containers, t)
+		for _, key := range t.Keys() {
+			if key == "Parent" {
+				// Do not walk up the page tree.
+				continue
+			}
+			c.collect(t.Get(key))
+		}
+	case *pdfcore.PdfObjectArray:
+		c.visited[obj] = true
+		c.containers = append(c.containers, t)
+		for _, o := range *t {
+			c.collect(o)
+		}
+	}
+}
+
+// Replaces all references to the objects in the canonical map by references to the canonical objects.
+func (c *objectCollector) replace(canonical map[pdfcore.PdfObject]pdfcore.PdfObject) {
+	for _, container := range c.containers {
+		switch t := container.(type) {
+		case *pdfcore.PdfIndirectObject:
+			if obj, has := canonical[t.PdfObject]; has {
+				t.PdfObject = obj
+			}
+		case *pdfcore.PdfObjectDictionary:
+			for _, key := range t.Keys() {
+				if obj, has := canonical[t.Get(key)]; has {
+					t.Set(key, obj)
+				}
+			}
+		case *pdfcore.PdfObjectArray:
+			for i, o := range *t {
+				if obj, has := canonical[o]; has {
+					(*t)[i] = obj
+				}
+			}
+		}
+	}
+}
+
+// Returns a key identifying the stream by its dictionary and data.
+func streamKey(stream *pdfcore.PdfObjectStream) string {
+	sum := sha1.Sum(stream.Stream)
+	return fmt.Sprintf("%s|%x", stream.PdfObjectDictionary.DefaultWriteString(), sum)
+}
+
+// Compresses the stream with Flate at the best compression level if it is uncompressed or Flate compressed without
+// predictor.  Returns true if the stream was changed.
+func recompressStream(stream *pdfcore.PdfObjectStream) (bool, error) {
+	dict := stream.PdfObjectDictionary
+
+	data := stream.Stream
+	switch filter := pdfcore.TraceToDirectObject(dict.Get("Filter")).(type) {
+	case nil:
+	case *pdfcore.PdfObjectName:
+		if *filter != pdfcore.StreamEncodingFilterNameFlate || dict.Get("DecodeParms") != nil {
+			return false, nil
+		}
+		decoded, err := pdfcore.DecodeStream(stream)
+		if err != nil {
+			return false, err
+		}
+		data = decoded
+	default:
+		// Filter arrays are left as they are.
+		return false, nil
+	}
+
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return false, err
+	}
+	w.Write(data)
+	w.Close()
+
+	if buf.Len() >= len(stream.Stream) {
+		return false, nil
+	}
+
+	stream.Stream = buf.Bytes()
+	dict.Set("Filter", pdfcore.MakeName(pdfcore.StreamEncodingFilterNameFlate))
+	dict.Set("Length", pdfcore.MakeInteger(int64(buf.Len())))
+	return true, nil
+}
+
+// Downsamples an 8 bit DeviceRGB or DeviceGray image stream to fit within maxDim x maxDim pixels and reencodes it as
+// JPEG.  The stream is updated in place so all references to it remain valid.  Returns true if downsampled.
+func downsampleImage(stream *pdfcore.PdfObjectStream, maxDim int, quality int) (bool, error) {
+	dict := stream.PdfObjectDictionary
+	if subtype, ok := pdfcore.TraceToDirectObject(dict.Get("Subtype")).(*pdfcore.PdfObjectName); !ok ||
+		*subtype != "Image" {
+		return false, nil
+	}
+
+	width, _ := pdfcore.TraceToDirectObject(dict.Get("Width")).(*pdfcore.PdfObjectInteger)
+	height, _ := pdfcore.TraceToDirectObject(dict.Get("Height")).(*pdfcore.PdfObjectInteger)
+	bpc, _ := pdfcore.TraceToDirectObject(dict.Get("BitsPerComponent")).(*pdfcore.PdfObjectInteger)
+	cs, _ := pdfcore.TraceToDirectObject(dict.Get("ColorSpace")).(*pdfcore.PdfObjectName)
+	if width == nil || height == nil || bpc == nil || cs == nil || *bpc != 8 || dict.Get("Decode") != nil {
+		return false, errors.New("Unsupported image format")
+	}
+	w, h := int(*width), int(*height)
+	if w <= maxDim && h <= maxDim {
+		return false, nil
+	}
+
+	components := 0
+	switch *cs {
+	case "DeviceGray":
+		components = 1
+	case "DeviceRGB":
+		components = 3
+	default:
+		return false, fmt.Errorf("Unsupported colorspace %s", *cs)
+	}
+
+	data, err := pdfcore.DecodeStream(stream)
+	if err != nil {
+		return false, err
+	}
+	if len(data) < w*h*components {
+		return false, errors.New("Image data too short")
+	}
+
+	// New size, keeping the aspect ratio.
+	scale := float64(maxDim) / float64(w)
+	if h > w {
+		scale = float64(maxDim) / float64(h)
+	}
+	nw := int(float64(w)*scale + 0.5)
+	nh := int(float64(h)*scale + 0.5)
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+
+	img := boxDownsample(data, w, h, components, nw, nh)
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	if err != nil {
+		return false, err
+	}
+
+	stream.Stream = buf.Bytes()
+	dict.Set("Filter", pdfcore.MakeName(pdfcore.StreamEncodingFilterNameDCT))
+	dict.Remove("DecodeParms")
+	dict.Set("Width", pdfcore.MakeInteger(int64(nw)))
+	dict.Set("Height", pdfcore.MakeInteger(int64(nh)))
+	dict.Set("Length", pdfcore.MakeInteger(int64(buf.Len())))
+	return true, nil
+}
+
+// Scales the 8 bit image samples down to nw x nh by averaging the source pixels covered by each target pixel.
+func boxDownsample(data []byte, w, h, components int, nw, nh int) goimage.Image {
+	var gray *goimage.Gray
+	var rgba *goimage.RGBA
+	if components == 1 {
+		gray = goimage.NewGray(goimage.Rect(0, 0, nw, nh))
+	} else {
+		rgba = goimage.NewRGBA(goimage.Rect(0, 0, nw, nh))
+	}
+
+	for y := 0; y < nh; y++ {
+		y0 := y * h / nh
+		y1 := (y + 1) * h / nh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < nw; x++ {
+			x0 := x * w / nw
+			x1 := (x + 1) * w / nw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			sum := [3]int{}
+			n := 0
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					idx := (sy*w + sx) * components
+					for k := 0; k < components; k++ {
+						sum[k] += int(data[idx+k])
+					}
+					n++
+				}
+			}
+
+			if components == 1 {
+				gray.SetGray(x, y, color.Gray{Y: uint8(sum[0] / n)})
+			} else {
+				rgba.SetRGBA(x, y, color.RGBA{R: uint8(sum[0] / n), G: uint8(sum[1] / n), B: uint8(sum[2] / n),
+					A: 255})
+			}
+		}
+	}
+
+	if components == 1 {
+		return gray
+	}
+	return rgba
+}
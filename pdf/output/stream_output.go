@@ -0,0 +1,236 @@
+/*
+ * Write a generated PDF to an io.Writer, such as an HTTP response or a pipe, instead of a file.
+ *
+ * The creator writes to an io.WriteSeeker (c.Write), as c.WriteToFile does with the file.  It does not seek back
+ * though: it only asks for the current offset, to record the offsets of the objects for the cross-reference table.
+ * So any io.Writer can be used by wrapping it in an offsetWriter, which counts the bytes written and answers that
+ * query.  The document is then written out object by object as it is serialized, without buffering the whole file
+ * (the pages are built in memory before, as the creator always does).  The size of the file is not known up front,
+ * so HTTP responses are sent chunked, without Content-Length.
+ *
+ * The creator ignores the errors of its writes, so offsetWriter keeps the first error, skips the writes after it, and
+ * writePdf returns it.  In the HTTP handler, an error before anything was sent is reported as 500 Internal Server
+ * Error.  Once the PDF has started, the status and headers are gone, and the handler aborts the connection so the
+ * client sees a failed download rather than a truncated file that looks complete.
+ *
+ * Without -serve, the PDF is written to standard output, e.g. to pipe it to another program.  The messages go to
+ * standard error then (including those of the library), so that they do not end up in the PDF.
+ *
+ * Run as: go run stream_output.go [-rows 200] > output.pdf
+ *     or: go run stream_output.go [-rows 200] -serve localhost:8080
+ *         and open http://localhost:8080/report.pdf (add ?inline=1 to view it in the browser, ?rows=N to change size)
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run stream_output.go [-rows 200] [-serve address] [> output.pdf]\n"
+
+const maxRows = 100000
+
+// offsetWriter lets the creator write to an io.Writer: it counts the bytes written to report the current offset,
+// which is the only seek the creator makes, and keeps the first write error.
+type offsetWriter struct {
+	w      io.Writer
+	offset int64
+	err    error
+}
+
+func main() {
+	rows := 0
+	addr := ""
+	flag.IntVar(&rows, "rows", 200, "Number of rows of the report")
+	flag.StringVar(&addr, "serve", "", "Serve the report over HTTP at this address (e.g. localhost:8080) instead of "+
+		"writing it to standard output")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if rows < 1 || rows > maxRows {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	if addr != "" {
+		http.HandleFunc("/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+			reportHandler(w, r, rows)
+		})
+		log.Printf("Serving http://%s/report.pdf", addr)
+		log.Fatal(http.ListenAndServe(addr, nil))
+	}
+
+	// The library prints to standard output, so it is pointed to standard error while the PDF is written there.
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	c, err := buildReport(rows)
+	if err == nil {
+		_, err = writePdf(c, out)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "Complete, written %d rows to standard output\n", rows)
+}
+
+// reportHandler serves the report as a PDF download, or for display in the browser with ?inline=1.
+func reportHandler(w http.ResponseWriter, r *http.Request, rows int) {
+	if s := r.URL.Query().Get("rows"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxRows {
+			http.Error(w, "Invalid rows", http.StatusBadRequest)
+			return
+		}
+		rows = n
+	}
+
+	c, err := buildReport(rows)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		http.Error(w, "Failed to create the report", http.StatusInternalServerError)
+		return
+	}
+
+	disposition := "attachment"
+	if r.URL.Query().Get("inline") == "1" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition,
+		map[string]string{"filename": "report.pdf"}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	size, err := writePdf(c, w)
+	if err == nil {
+		log.Printf("Sent report.pdf, %d rows, %d bytes", rows, size)
+		return
+	}
+
+	log.Printf("Error: %v", err)
+	if size == 0 {
+		// Nothing sent yet, the headers can still be changed.
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Failed to write the report", http.StatusInternalServerError)
+		return
+	}
+	// Part of the PDF was sent: abort the response so that it is not taken as complete.
+	panic(http.ErrAbortHandler)
+}
+
+// writePdf writes the PDF of the creator to w.  Returns the number of bytes written, and the first error, of the
+// creator or of writing to w.
+func writePdf(c *creator.Creator, w io.Writer) (int64, error) {
+	ow := &offsetWriter{w: w}
+	err := c.Write(ow)
+	if err != nil {
+		return ow.offset, err
+	}
+	return ow.offset, ow.err
+}
+
+// Write writes p to the underlying writer, unless a previous write failed.
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	if ow.err != nil {
+		return 0, ow.err
+	}
+	n, err := ow.w.Write(p)
+	ow.offset += int64(n)
+	if err != nil {
+		ow.err = err
+	}
+	return n, err
+}
+
+// Seek returns the current offset.  Other seeks are not possible on a stream, and fail the write.
+func (ow *offsetWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekCurrent {
+		return ow.offset, nil
+	}
+	err := errors.New("Seek not supported when streaming")
+	if ow.err == nil {
+		ow.err = err
+	}
+	return ow.offset, err
+}
+
+// buildReport builds a report with a table of the given number of rows, spanning as many pages as needed.
+func buildReport(rows int) (*creator.Creator, error) {
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	heading := creator.NewParagraph("Sales report")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 10)
+	err := c.Draw(heading)
+	if err != nil {
+		return nil, err
+	}
+
+	table := creator.NewTable(4)
+	table.SetColumnWidths(0.15, 0.45, 0.2, 0.2)
+	for _, text := range []string{"No.", "Product", "Quantity", "Amount"} {
+		addCell(table, text, fonts.NewFontHelveticaBold())
+	}
+	total := 0.0
+	for i := 1; i <= rows; i++ {
+		qty := 1 + (i*7)%12
+		amount := float64(qty) * (2.5 + float64((i*13)%40))
+		total += amount
+		addCell(table, fmt.Sprintf("%d", i), fonts.NewFontHelvetica())
+		addCell(table, fmt.Sprintf("Product %04d", 1000+(i*37)%9000), fonts.NewFontHelvetica())
+		addCell(table, fmt.Sprintf("%d", qty), fonts.NewFontHelvetica())
+		addCell(table, fmt.Sprintf("%.2f", amount), fonts.NewFontHelvetica())
+	}
+	addCell(table, "", fonts.NewFontHelveticaBold())
+	addCell(table, "Total", fonts.NewFontHelveticaBold())
+	addCell(table, "", fonts.NewFontHelveticaBold())
+	addCell(table, fmt.Sprintf("%.2f", total), fonts.NewFontHelveticaBold())
+
+	err = c.Draw(table)
+	if err != nil {
+		return nil, err
+	}
+
+	c.DrawFooter(func(footer *creator.Block, args creator.FooterFunctionArgs) {
+		p := creator.NewParagraph(fmt.Sprintf("Page %d of %d", args.PageNum, args.TotalPages))
+		p.SetFont(fonts.NewFontHelvetica())
+		p.SetFontSize(8)
+		p.SetPos(footer.Width()-50-p.Width(), 20)
+		footer.Draw(p)
+	})
+	return c, nil
+}
+
+// addCell adds a cell with the text to the table.
+func addCell(table *creator.Table, text string, font fonts.Font) {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(10)
+	cell := table.NewCell()
+	cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+	cell.SetContent(p)
+}
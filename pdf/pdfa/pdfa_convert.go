@@ -0,0 +1,1202 @@
+/*
+ * Convert a PDF file towards PDF/A-1b (ISO 19005-1, level B) and report what could not be fixed.
+ *
+ * The conversion applies the following transformations:
+ * - Encryption is removed (files that need a password to open are rejected).
+ * - Fonts that are not embedded are replaced by embedded TrueType fonts found in the font directory (-fontdir): a
+ *   font file with the name of the font (e.g. Arial-BoldMT.ttf), or for the standard 14 fonts a substitute such as
+ *   LiberationSans-Regular.ttf (metric compatible with Helvetica) or DejaVuSans.ttf.  The replacement has its own
+ *   widths, so the text can look slightly different unless the substitute is metric compatible.
+ * - Font descriptors without a FontName get the name of the font.
+ * - The print flag is set on annotations that lack it.
+ * - Page transparency groups and page actions are removed.
+ * - An output intent with an ICC profile (built-in sRGB, or from a file with -icc), XMP metadata with the PDF/A
+ *   identification, the matching information dictionary and a file identifier are added.
+ *
+ * Everything that cannot be fixed automatically is reported as a blocker, in particular:
+ * - Fonts that cannot be embedded: no font file found, the font file does not permit embedding (fsType in the OS/2
+ *   table), composite (Type0) fonts, symbolic fonts and fonts with custom encodings, where replacing the font would
+ *   change the glyphs shown.
+ * - Transparency: soft masks, constant alpha and blend modes in graphics states, images with soft masks.
+ * - Device colors that do not match the output intent profile (e.g. DeviceCMYK with an RGB profile).
+ * - Hidden annotations, annotations without appearance, multimedia and file attachment annotations.
+ * - Optional content (layers) and forms that rely on the viewer to generate the field appearances.
+ *
+ * The output is written even if blockers remain, but then the program exits with an error.  The check is not a full
+ * validation: use a validator (e.g. veraPDF) to verify the output.  Note that an unlicensed copy of UniDoc adds a
+ * notice in a non-embedded font to each page, so the output is only compliant with a license.
+ *
+ * The incremental update is written by writeUpdate in update.go, shared with pdfa_create.go.
+ *
+ * Run as: go run pdfa_convert.go update.go [-fontdir dir] [-icc profile.icc] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run pdfa_convert.go update.go [-fontdir dir] [-icc profile.icc] input.pdf output.pdf\n"
+
+// Substitute font files (without the .ttf extension) for the standard 14 fonts, in order of preference.  The
+// Liberation fonts have the same metrics as the standard fonts.
+var substituteFonts = map[string][]string{
+	"Helvetica":              {"LiberationSans-Regular", "Arimo-Regular", "DejaVuSans"},
+	"Helvetica-Bold":         {"LiberationSans-Bold", "Arimo-Bold", "DejaVuSans-Bold"},
+	"Helvetica-Oblique":      {"LiberationSans-Italic", "Arimo-Italic", "DejaVuSans-Oblique"},
+	"Helvetica-BoldOblique":  {"LiberationSans-BoldItalic", "Arimo-BoldItalic", "DejaVuSans-BoldOblique"},
+	"Times-Roman":            {"LiberationSerif-Regular", "Tinos-Regular", "DejaVuSerif"},
+	"Times-Bold":             {"LiberationSerif-Bold", "Tinos-Bold", "DejaVuSerif-Bold"},
+	"Times-Italic":           {"LiberationSerif-Italic", "Tinos-Italic", "DejaVuSerif-Italic"},
+	"Times-BoldItalic":       {"LiberationSerif-BoldItalic", "Tinos-BoldItalic", "DejaVuSerif-BoldItalic"},
+	"Courier":                {"LiberationMono-Regular", "Cousine-Regular", "DejaVuSansMono"},
+	"Courier-Bold":           {"LiberationMono-Bold", "Cousine-Bold", "DejaVuSansMono-Bold"},
+	"Courier-Oblique":        {"LiberationMono-Italic", "Cousine-Italic", "DejaVuSansMono-Oblique"},
+	"Courier-BoldOblique":    {"LiberationMono-BoldItalic", "Cousine-BoldItalic", "DejaVuSansMono-BoldOblique"},
+	"Arial":                  {"LiberationSans-Regular", "Arimo-Regular"},
+	"ArialMT":                {"LiberationSans-Regular", "Arimo-Regular"},
+	"Arial-BoldMT":           {"LiberationSans-Bold", "Arimo-Bold"},
+	"TimesNewRomanPSMT":      {"LiberationSerif-Regular", "Tinos-Regular"},
+	"TimesNewRomanPS-BoldMT": {"LiberationSerif-Bold", "Tinos-Bold"},
+}
+
+// Annotation flags (F).
+const (
+	annotFlagInvisible = 1
+	annotFlagHidden    = 2
+	annotFlagPrint     = 4
+	annotFlagNoView    = 32
+)
+
+// conversionReport lists the transformations applied and the blockers that remain.
+type conversionReport struct {
+	applied  []string
+	blockers []string
+	seen     map[string]bool
+}
+
+// docInfo holds the document metadata, written both in the information dictionary and in the XMP metadata.
+type docInfo struct {
+	title    string
+	author   string
+	subject  string
+	keywords string
+	creator  string
+	producer string
+	created  time.Time
+	modified time.Time
+}
+
+// colorUsage records the device color spaces used in the document.
+type colorUsage struct {
+	rgb  bool
+	cmyk bool
+}
+
+func main() {
+	fontDir := ""
+	iccPath := ""
+	flag.StringVar(&fontDir, "fontdir", ".", "Directory with TrueType fonts to embed")
+	flag.StringVar(&iccPath, "icc", "", "ICC profile of the output intent (default: built-in sRGB profile)")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	// The output condition identifies the profile: the registered name for sRGB, Custom for other profiles.
+	iccData := newSRGBProfile()
+	outputCondition := "sRGB IEC61966-2.1"
+	if len(iccPath) > 0 {
+		var err error
+		iccData, err = ioutil.ReadFile(iccPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		outputCondition = "Custom"
+	}
+
+	report, err := convertToPdfA(inputPath, outputPath, fontDir, iccData, outputCondition)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Transformations applied: %d\n", len(report.applied))
+	for _, s := range report.applied {
+		fmt.Printf("  %s\n", s)
+	}
+	fmt.Printf("Blockers: %d\n", len(report.blockers))
+	for _, s := range report.blockers {
+		fmt.Printf("  %s\n", s)
+	}
+
+	if len(report.blockers) > 0 {
+		fmt.Printf("Error: the output is not PDF/A-1b compliant, see output file: %s\n", outputPath)
+		os.Exit(1)
+	}
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Adds an applied transformation to the report.
+func (r *conversionReport) apply(format string, args ...interface{}) {
+	r.add(&r.applied, fmt.Sprintf(format, args...))
+}
+
+// Adds a blocker to the report.
+func (r *conversionReport) block(format string, args ...interface{}) {
+	r.add(&r.blockers, fmt.Sprintf(format, args...))
+}
+
+// Adds a message to the list, unless already reported.
+func (r *conversionReport) add(list *[]string, s string) {
+	if r.seen == nil {
+		r.seen = map[string]bool{}
+	}
+	if r.seen[s] {
+		return
+	}
+	r.seen[s] = true
+	*list = append(*list, s)
+}
+
+func convertToPdfA(inputPath, outputPath, fontDir string, iccData []byte, outputCondition string) (
+	*conversionReport, error) {
+	if len(iccData) < 128 {
+		return nil, errors.New("Invalid ICC profile")
+	}
+	report := &conversionReport{}
+
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("Need to decrypt with password")
+		}
+		// The output is written without encryption.
+		report.apply("Removed encryption (%s)", pdfReader.GetEncryptionMethod())
+	}
+
+	info, err := getDocInfo(pdfReader)
+	if err != nil {
+		return nil, err
+	}
+
+	ocProperties, err := pdfReader.GetOCProperties()
+	if err != nil {
+		return nil, err
+	}
+	if ocProperties != nil {
+		report.block("Optional content (layers) is not allowed")
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	// PDF/A-1 is based on PDF 1.4.
+	pdfWriter.SetVersion(1, 4)
+
+	colors := &colorUsage{}
+	checkedFonts := map[pdfcore.PdfObject]bool{}
+	// Names of the fonts reported as not embedded.
+	unembedded := map[string]bool{}
+	onFont := func(name pdfcore.PdfObjectName, fontObj pdfcore.PdfObject) {
+		if !checkedFonts[fontObj] {
+			checkedFonts[fontObj] = true
+			if fontName, ok := convertFont(fontObj, fontDir, report); !ok {
+				unembedded[fontName] = true
+			}
+		}
+	}
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+
+		visited := map[*pdfcore.PdfObjectStream]bool{}
+		err = collectFonts(page.Resources, onFont, visited)
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return nil, err
+		}
+		err = checkContent(contents, page.Resources, pageNum, colors, report)
+		if err != nil {
+			return nil, err
+		}
+
+		if isTransparencyGroup(page.Group) {
+			page.Group = nil
+			report.apply("Page %d: removed the transparency group", pageNum)
+		}
+		if page.AA != nil {
+			page.AA = nil
+			report.apply("Page %d: removed the page actions", pageNum)
+		}
+
+		convertAnnotations(page, pageNum, report)
+
+		// The annotation appearances are drawn as part of the page.
+		for _, annot := range page.Annotations {
+			for _, stream := range getAppearanceStreams(annot) {
+				xform, err := pdf.NewXObjectFormFromStream(stream)
+				if err != nil {
+					return nil, err
+				}
+				err = collectFonts(xform.Resources, onFont, visited)
+				if err != nil {
+					return nil, err
+				}
+				content, err := xform.GetContentStream()
+				if err != nil {
+					return nil, err
+				}
+				err = checkContent(string(content), xform.Resources, pageNum, colors, report)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Colors: the output intent covers one device color space.
+	profileSpace := string(iccData[16:20])
+	if colors.rgb && profileSpace != "RGB " {
+		report.block("DeviceRGB colors are used, but the output intent profile is not an RGB profile")
+	}
+	if colors.cmyk && profileSpace != "CMYK" {
+		report.block("DeviceCMYK colors are used, but the output intent profile is not a CMYK profile (use -icc)")
+	}
+
+	pdfWriter.AddOutlineTree(pdfReader.GetOutlineTree())
+
+	if pdfReader.AcroForm != nil {
+		if needAppearances := pdfReader.AcroForm.NeedAppearances; needAppearances != nil && bool(*needAppearances) {
+			report.block("The form fields rely on the viewer to generate their appearances (NeedAppearances)")
+		}
+		err = pdfWriter.SetForms(pdfReader.AcroForm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	err = pdfWriter.Write(fWrite)
+	fWrite.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	notEmbedded, err := addPdfAStructures(outputPath, info, iccData, outputCondition)
+	if err != nil {
+		return nil, err
+	}
+	// Fonts added when writing (such as the font of the unlicensed copy notice), which were not checked above.
+	for _, name := range notEmbedded {
+		if !unembedded[name] {
+			report.block("Font %s added when writing is not embedded", name)
+		}
+	}
+	report.apply("Added the output intent (%s), XMP metadata and file identifier", outputCondition)
+
+	return report, nil
+}
+
+// Gets the document information from the information dictionary.  Dates that are missing or cannot be parsed are set
+// to the current time.
+func getDocInfo(pdfReader *pdf.PdfReader) (docInfo, error) {
+	now := time.Now()
+	info := docInfo{created: now, modified: now}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return info, err
+	}
+	infoDict, ok := pdfcore.TraceToDirectObject(trailer.Get("Info")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		if num, ok := objectNumber(trailer.Get("Info")); ok {
+			obj, err := pdfReader.GetIndirectObjectByNumber(int(num))
+			if err != nil {
+				return info, err
+			}
+			infoDict, ok = pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectDictionary)
+		}
+		if !ok {
+			return info, nil
+		}
+	}
+
+	getString := func(key pdfcore.PdfObjectName) string {
+		s, ok := pdfcore.TraceToDirectObject(infoDict.Get(key)).(*pdfcore.PdfObjectString)
+		if !ok {
+			return ""
+		}
+		return decodePdfString(string(*s))
+	}
+	info.title = getString("Title")
+	info.author = getString("Author")
+	info.subject = getString("Subject")
+	info.keywords = getString("Keywords")
+	info.creator = getString("Creator")
+	if t, ok := parsePdfDate(getString("CreationDate")); ok {
+		info.created = t
+	}
+
+	return info, nil
+}
+
+// Embeds the font if it is not embedded, by replacing it with a TrueType font from the font directory.  The font
+// dictionary is replaced in place, so that all the resources using the font get the replacement.  Returns the font
+// name and false if the font remains not embedded.
+func convertFont(fontObj pdfcore.PdfObject, fontDir string, report *conversionReport) (string, bool) {
+	fontName, embedded, descriptorObj := getFontDescriptor(fontObj)
+	if embedded {
+		descriptor, ok := pdfcore.TraceToDirectObject(descriptorObj).(*pdfcore.PdfObjectDictionary)
+		if ok && descriptor.Get("FontName") == nil {
+			descriptor.Set("FontName", pdfcore.MakeName(fontName))
+			report.apply("Font %s: added the FontName to the font descriptor", fontName)
+		}
+		return fontName, true
+	}
+
+	path, err := replaceFont(fontObj, fontName, fontDir)
+	if err != nil {
+		report.block("Font %s is not embedded: %v", fontName, err)
+		return fontName, false
+	}
+	report.apply("Font %s: embedded %s", fontName, path)
+	return fontName, true
+}
+
+// Replaces the simple font with an embedded TrueType font found in the font directory.  Returns the path of the font
+// file.
+func replaceFont(fontObj pdfcore.PdfObject, fontName, fontDir string) (string, error) {
+	fontDict, ok := pdfcore.TraceToDirectObject(fontObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return "", errors.New("Invalid font")
+	}
+
+	subtype := ""
+	if name, ok := pdfcore.TraceToDirectObject(fontDict.Get("Subtype")).(*pdfcore.PdfObjectName); ok {
+		subtype = string(*name)
+	}
+	if subtype != "Type1" && subtype != "TrueType" && subtype != "MMType1" {
+		return "", fmt.Errorf("%s fonts cannot be replaced", subtype)
+	}
+	if fontName == "Symbol" || fontName == "ZapfDingbats" {
+		return "", errors.New("Symbolic font, no replacement")
+	}
+
+	// The replacement uses WinAnsiEncoding, the character codes must mean the same in the font.
+	encoding := pdfcore.TraceToDirectObject(fontDict.Get("Encoding"))
+	if name, ok := encoding.(*pdfcore.PdfObjectName); ok {
+		if *name != "WinAnsiEncoding" && *name != "StandardEncoding" {
+			return "", fmt.Errorf("Encoding %s cannot be kept", *name)
+		}
+	} else if encoding != nil {
+		return "", errors.New("Custom encoding (Differences) cannot be kept")
+	}
+
+	path := findFontFile(fontDir, fontName)
+	if len(path) == 0 {
+		return "", fmt.Errorf("No font file found in %s", fontDir)
+	}
+
+	ttf, err := fonts.TtfParse(path)
+	if err != nil {
+		return "", err
+	}
+	if !ttf.Embeddable {
+		return "", fmt.Errorf("The font file %s does not permit embedding", path)
+	}
+
+	font, err := pdf.NewPdfFontFromTTFFile(path)
+	if err != nil {
+		return "", err
+	}
+	newDict, ok := pdfcore.TraceToDirectObject(font.ToPdfObject()).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return "", errors.New("Invalid replacement font")
+	}
+	if descriptor, ok := pdfcore.TraceToDirectObject(newDict.Get("FontDescriptor")).(*pdfcore.PdfObjectDictionary); ok {
+		descriptor.Set("FontName", pdfcore.MakeName(ttf.PostScriptName))
+	}
+
+	for _, key := range fontDict.Keys() {
+		fontDict.Remove(key)
+	}
+	for _, key := range newDict.Keys() {
+		fontDict.Set(key, newDict.Get(key))
+	}
+
+	return path, nil
+}
+
+// Returns the path of the font file for the font in the directory: a file named as the font, or a substitute file,
+// or "" if none is found.
+func findFontFile(fontDir, fontName string) string {
+	// Subset fonts have a tag such as ABCDEF+ prefixed to the name.
+	if idx := strings.Index(fontName, "+"); idx == 6 {
+		fontName = fontName[idx+1:]
+	}
+
+	names := []string{fontName, strings.Replace(fontName, ",", "-", -1)}
+	names = append(names, substituteFonts[fontName]...)
+	for _, name := range names {
+		path := filepath.Join(fontDir, name+".ttf")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Checks the page annotations: sets the print flag where missing, and reports the annotations that are not allowed.
+func convertAnnotations(page *pdf.PdfPage, pageNum int, report *conversionReport) {
+	for _, annot := range page.Annotations {
+		subtype := "unknown"
+		if annotDict, ok := pdfcore.TraceToDirectObject(annot.ToPdfObject()).(*pdfcore.PdfObjectDictionary); ok {
+			if name, ok := pdfcore.TraceToDirectObject(annotDict.Get("Subtype")).(*pdfcore.PdfObjectName); ok {
+				subtype = string(*name)
+			}
+			if ca, err := getNumberAsFloat(pdfcore.TraceToDirectObject(annotDict.Get("CA"))); err == nil && ca < 1 {
+				report.block("Page %d: %s annotation with opacity (transparency)", pageNum, subtype)
+			}
+		}
+
+		switch annot.GetContext().(type) {
+		case *pdf.PdfAnnotationFileAttachment, *pdf.PdfAnnotationSound, *pdf.PdfAnnotationMovie,
+			*pdf.PdfAnnotationScreen, *pdf.PdfAnnotationRichMedia, *pdf.PdfAnnotation3D:
+			report.block("Page %d: %s annotations are not allowed", pageNum, subtype)
+			continue
+		case *pdf.PdfAnnotationLink, *pdf.PdfAnnotationPopup:
+		default:
+			if annot.AP == nil {
+				report.block("Page %d: %s annotation without appearance stream", pageNum, subtype)
+			}
+		}
+
+		flags := int64(0)
+		if f, ok := pdfcore.TraceToDirectObject(annot.F).(*pdfcore.PdfObjectInteger); ok {
+			flags = int64(*f)
+		}
+		if flags&(annotFlagInvisible|annotFlagHidden|annotFlagNoView) != 0 {
+			report.block("Page %d: hidden %s annotation", pageNum, subtype)
+			continue
+		}
+		if flags&annotFlagPrint == 0 {
+			annot.F = pdfcore.MakeInteger(flags | annotFlagPrint)
+			report.apply("Page %d: set the print flag of %s annotations", pageNum, subtype)
+		}
+	}
+}
+
+// Returns the appearance streams of the annotation: the normal appearance, or the normal appearances of all its
+// states (e.g. on and off for check boxes).
+func getAppearanceStreams(annot *pdf.PdfAnnotation) []*pdfcore.PdfObjectStream {
+	apDict, ok := pdfcore.TraceToDirectObject(annot.AP).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+
+	normal := pdfcore.TraceToDirectObject(apDict.Get("N"))
+	if stream, ok := normal.(*pdfcore.PdfObjectStream); ok {
+		return []*pdfcore.PdfObjectStream{stream}
+	}
+	streams := []*pdfcore.PdfObjectStream{}
+	if states, ok := normal.(*pdfcore.PdfObjectDictionary); ok {
+		for _, key := range states.Keys() {
+			if stream, ok := pdfcore.TraceToDirectObject(states.Get(key)).(*pdfcore.PdfObjectStream); ok {
+				streams = append(streams, stream)
+			}
+		}
+	}
+	return streams
+}
+
+// Checks the content stream and its resources for transparency and the device colors used, including form XObjects.
+func checkContent(contents string, resources *pdf.PdfPageResources, pageNum int, colors *colorUsage,
+	report *conversionReport) error {
+	cstreamParser := pdfcontent.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return err
+	}
+
+	for _, op := range *operations {
+		switch op.Operand {
+		case "rg", "RG":
+			colors.rgb = true
+		case "k", "K":
+			colors.cmyk = true
+		case "cs", "CS":
+			if len(op.Params) == 1 {
+				if name, ok := op.Params[0].(*pdfcore.PdfObjectName); ok {
+					checkColorSpace(name, colors)
+				}
+			}
+		}
+	}
+
+	if resources == nil {
+		return nil
+	}
+
+	if gsDict, ok := pdfcore.TraceToDirectObject(resources.ExtGState).(*pdfcore.PdfObjectDictionary); ok {
+		for _, name := range gsDict.Keys() {
+			gs, ok := pdfcore.TraceToDirectObject(gsDict.Get(name)).(*pdfcore.PdfObjectDictionary)
+			if !ok {
+				continue
+			}
+			smask := pdfcore.TraceToDirectObject(gs.Get("SMask"))
+			if smaskName, ok := smask.(*pdfcore.PdfObjectName); smask != nil && !(ok && *smaskName == "None") {
+				report.block("Page %d: transparency (soft mask in graphics state %s)", pageNum, name)
+			}
+			for _, key := range []pdfcore.PdfObjectName{"CA", "ca"} {
+				if alpha, err := getNumberAsFloat(pdfcore.TraceToDirectObject(gs.Get(key))); err == nil && alpha < 1 {
+					report.block("Page %d: transparency (opacity in graphics state %s)", pageNum, name)
+				}
+			}
+			if bm, ok := pdfcore.TraceToDirectObject(gs.Get("BM")).(*pdfcore.PdfObjectName); ok && *bm != "Normal" &&
+				*bm != "Compatible" {
+				report.block("Page %d: transparency (blend mode %s in graphics state %s)", pageNum, *bm, name)
+			}
+		}
+	}
+
+	xobjDict, ok := pdfcore.TraceToDirectObject(resources.XObject).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	for _, name := range xobjDict.Keys() {
+		stream, xtype := resources.GetXObjectByName(name)
+		switch xtype {
+		case pdf.XObjectTypeImage:
+			if stream.PdfObjectDictionary.Get("SMask") != nil {
+				report.block("Page %d: transparency (image %s with soft mask)", pageNum, name)
+			}
+			if cs, ok := pdfcore.TraceToDirectObject(stream.PdfObjectDictionary.Get("ColorSpace")).(*pdfcore.PdfObjectName); ok {
+				checkColorSpace(cs, colors)
+			}
+		case pdf.XObjectTypeForm:
+			xform, err := pdf.NewXObjectFormFromStream(stream)
+			if err != nil {
+				return err
+			}
+			if isTransparencyGroup(xform.Group) {
+				report.block("Page %d: transparency (form %s is a transparency group)", pageNum, name)
+			}
+			content, err := xform.GetContentStream()
+			if err != nil {
+				return err
+			}
+			err = checkContent(string(content), xform.Resources, pageNum, colors, report)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Records the use of a device color space.
+func checkColorSpace(name *pdfcore.PdfObjectName, colors *colorUsage) {
+	switch *name {
+	case "DeviceRGB":
+		colors.rgb = true
+	case "DeviceCMYK":
+		colors.cmyk = true
+	}
+}
+
+// Returns true if the group is a transparency group.
+func isTransparencyGroup(group pdfcore.PdfObject) bool {
+	groupDict, ok := pdfcore.TraceToDirectObject(group).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return false
+	}
+	s, ok := pdfcore.TraceToDirectObject(groupDict.Get("S")).(*pdfcore.PdfObjectName)
+	return ok && *s == "Transparency"
+}
+
+func getNumberAsFloat(obj pdfcore.PdfObject) (float64, error) {
+	if fObj, ok := obj.(*pdfcore.PdfObjectFloat); ok {
+		return float64(*fObj), nil
+	}
+
+	if iObj, ok := obj.(*pdfcore.PdfObjectInteger); ok {
+		return float64(*iObj), nil
+	}
+
+	return 0, errors.New("Not a number")
+}
+
+// Calls onFont for each font in the resources, and in the resources of the form XObjects in the resources.
+func collectFonts(resources *pdf.PdfPageResources, onFont func(pdfcore.PdfObjectName, pdfcore.PdfObject),
+	visited map[*pdfcore.PdfObjectStream]bool) error {
+	if resources == nil {
+		return nil
+	}
+
+	if fontDict, ok := pdfcore.TraceToDirectObject(resources.Font).(*pdfcore.PdfObjectDictionary); ok {
+		for _, name := range fontDict.Keys() {
+			fontObj, found := resources.GetFontByName(name)
+			if !found {
+				continue
+			}
+			onFont(name, fontObj)
+		}
+	}
+
+	xobjDict, ok := pdfcore.TraceToDirectObject(resources.XObject).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	for _, name := range xobjDict.Keys() {
+		stream, xtype := resources.GetXObjectByName(name)
+		if xtype != pdf.XObjectTypeForm || visited[stream] {
+			continue
+		}
+		visited[stream] = true
+
+		xform, err := pdf.NewXObjectFormFromStream(stream)
+		if err != nil {
+			return err
+		}
+		err = collectFonts(xform.Resources, onFont, visited)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the name of the font, whether it is embedded, and its font descriptor (of the descendant font for Type0
+// fonts, nil for Type3 fonts which need none).
+func getFontDescriptor(fontObj pdfcore.PdfObject) (string, bool, pdfcore.PdfObject) {
+	fontDict, ok := pdfcore.TraceToDirectObject(fontObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return "(invalid font)", false, nil
+	}
+
+	fontName := "(unnamed)"
+	if name, ok := pdfcore.TraceToDirectObject(fontDict.Get("BaseFont")).(*pdfcore.PdfObjectName); ok {
+		fontName = string(*name)
+	}
+
+	descriptorDict := fontDict
+	subtype, _ := pdfcore.TraceToDirectObject(fontDict.Get("Subtype")).(*pdfcore.PdfObjectName)
+	if subtype != nil && *subtype == "Type3" {
+		// Type3 glyphs are defined by content streams in the font itself.
+		return fontName, true, nil
+	}
+	if subtype != nil && *subtype == "Type0" {
+		arr, ok := pdfcore.TraceToDirectObject(fontDict.Get("DescendantFonts")).(*pdfcore.PdfObjectArray)
+		if !ok || len(*arr) == 0 {
+			return fontName, false, nil
+		}
+		descriptorDict, ok = pdfcore.TraceToDirectObject((*arr)[0]).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			return fontName, false, nil
+		}
+	}
+
+	descriptorObj := descriptorDict.Get("FontDescriptor")
+	descriptor, ok := pdfcore.TraceToDirectObject(descriptorObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		// No font descriptor: one of the standard 14 fonts, never embedded.
+		return fontName, false, nil
+	}
+	for _, key := range []pdfcore.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+		if descriptor.Get(key) != nil {
+			return fontName, true, descriptorObj
+		}
+	}
+	return fontName, false, descriptorObj
+}
+
+// Appends the PDF/A structures to the PDF file as an incremental update: the catalog with the metadata and output
+// intent, and the information dictionary.  Returns the names of the fonts used on the pages that are not embedded.
+func addPdfAStructures(path string, info docInfo, iccData []byte, outputCondition string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	rootNum, ok := objectNumber(trailer.Get("Root"))
+	if !ok {
+		return nil, errors.New("Missing Root in trailer")
+	}
+	infoNum, ok := objectNumber(trailer.Get("Info"))
+	if !ok {
+		return nil, errors.New("Missing Info in trailer")
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return nil, errors.New("Missing Size in trailer")
+	}
+	nextNum := int64(*size)
+
+	catalogObj, err := pdfReader.GetIndirectObjectByNumber(int(rootNum))
+	if err != nil {
+		return nil, err
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(catalogObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("Catalog not a dictionary")
+	}
+
+	// The producer is the library that wrote the file, the creator is kept from the input if it had one.
+	infoObj, err := pdfReader.GetIndirectObjectByNumber(int(infoNum))
+	if err != nil {
+		return nil, err
+	}
+	if infoDict, ok := pdfcore.TraceToDirectObject(infoObj).(*pdfcore.PdfObjectDictionary); ok {
+		if s, ok := pdfcore.TraceToDirectObject(infoDict.Get("Producer")).(*pdfcore.PdfObjectString); ok {
+			info.producer = string(*s)
+		}
+		if s, ok := pdfcore.TraceToDirectObject(infoDict.Get("Creator")).(*pdfcore.PdfObjectString); ok &&
+			len(info.creator) == 0 {
+			info.creator = string(*s)
+		}
+	}
+
+	notEmbedded := []string{}
+	checked := map[pdfcore.PdfObject]bool{}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		visited := map[*pdfcore.PdfObjectStream]bool{}
+		err = collectFonts(page.Resources, func(name pdfcore.PdfObjectName, fontObj pdfcore.PdfObject) {
+			if checked[fontObj] {
+				return
+			}
+			checked[fontObj] = true
+			if fontName, embedded, _ := getFontDescriptor(fontObj); !embedded {
+				notEmbedded = append(notEmbedded, fontName)
+			}
+		}, visited)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	objects := []updateObject{}
+
+	// XMP metadata: not compressed, so that it can be read without PDF tools.
+	metadataNum := nextNum
+	nextNum++
+	metadataDict := pdfcore.MakeDict()
+	metadataDict.Set("Type", pdfcore.MakeName("Metadata"))
+	metadataDict.Set("Subtype", pdfcore.MakeName("XML"))
+	objects = append(objects, updateObject{number: metadataNum,
+		obj: &pdfcore.PdfObjectStream{PdfObjectDictionary: metadataDict, Stream: makeXMP(info)}})
+
+	// Number of color components of the profile.
+	components := int64(3)
+	switch string(iccData[16:20]) {
+	case "GRAY":
+		components = 1
+	case "CMYK":
+		components = 4
+	}
+	iccNum := nextNum
+	nextNum++
+	iccStream, err := pdfcore.MakeStream(iccData, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	iccStream.PdfObjectDictionary.Set("N", pdfcore.MakeInteger(components))
+	objects = append(objects, updateObject{number: iccNum, obj: iccStream})
+
+	intentNum := nextNum
+	nextNum++
+	intent := pdfcore.MakeDict()
+	intent.Set("Type", pdfcore.MakeName("OutputIntent"))
+	intent.Set("S", pdfcore.MakeName("GTS_PDFA1"))
+	intent.Set("OutputConditionIdentifier", pdfcore.MakeString(outputCondition))
+	intent.Set("Info", pdfcore.MakeString(outputCondition))
+	intent.Set("DestOutputProfile", &pdfcore.PdfObjectReference{ObjectNumber: iccNum})
+	objects = append(objects, updateObject{number: intentNum, obj: intent})
+
+	catalog.Set("Metadata", &pdfcore.PdfObjectReference{ObjectNumber: metadataNum})
+	catalog.Set("OutputIntents", pdfcore.MakeArray(&pdfcore.PdfObjectReference{ObjectNumber: intentNum}))
+	objects = append(objects, updateObject{number: rootNum, obj: catalog})
+
+	// The information dictionary must match the XMP metadata.
+	newInfo := pdfcore.MakeDict()
+	for _, entry := range []struct {
+		key   pdfcore.PdfObjectName
+		value string
+	}{
+		{"Title", info.title}, {"Author", info.author}, {"Subject", info.subject}, {"Keywords", info.keywords},
+		{"Creator", info.creator}, {"Producer", info.producer},
+	} {
+		if len(entry.value) > 0 {
+			newInfo.Set(entry.key, pdfcore.MakeString(encodePdfString(entry.value)))
+		}
+	}
+	newInfo.Set("CreationDate", pdfcore.MakeString(formatPdfDate(info.created)))
+	newInfo.Set("ModDate", pdfcore.MakeString(formatPdfDate(info.modified)))
+	objects = append(objects, updateObject{number: infoNum, obj: newInfo})
+
+	// The file identifier is required in PDF/A.  Both parts are the same for a new file.
+	id := fmt.Sprintf("%X", md5.Sum(append([]byte(time.Now().String()), data...)))
+	trailer.Set("ID", pdfcore.MakeArray(pdfcore.MakeString(id), pdfcore.MakeString(id)))
+
+	update, err := writeUpdate(data, objects, trailer, nextNum)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	_, err = f.Write(update)
+	if err != nil {
+		return nil, err
+	}
+
+	return notEmbedded, nil
+}
+
+// Returns the object number of an indirect object or reference.
+func objectNumber(obj pdfcore.PdfObject) (int64, bool) {
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectReference:
+		return t.ObjectNumber, true
+	case *pdfcore.PdfIndirectObject:
+		return t.ObjectNumber, true
+	case *pdfcore.PdfObjectStream:
+		return t.ObjectNumber, true
+	}
+	return 0, false
+}
+
+// Decodes a text string: UTF-16BE with a byte order mark, or PDFDocEncoding (treated as Latin-1).
+func decodePdfString(s string) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := []uint16{}
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// Encodes a text string: as is if ASCII, otherwise as UTF-16BE with a byte order mark.
+func encodePdfString(s string) string {
+	ascii := true
+	for _, r := range s {
+		if r >= 128 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return s
+	}
+	var buf bytes.Buffer
+	buf.WriteString("\xfe\xff")
+	binary.Write(&buf, binary.BigEndian, utf16.Encode([]rune(s)))
+	return buf.String()
+}
+
+// Parses a PDF date string, e.g. D:20180213153000+01'00'.  All parts after the year are optional.
+func parsePdfDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(s, "D:")
+	if len(s) < 4 {
+		return time.Time{}, false
+	}
+
+	// Year, month, day, hour, minute, second.
+	fields := []int{0, 1, 1, 0, 0, 0}
+	widths := []int{4, 2, 2, 2, 2, 2}
+	pos := 0
+	for i, width := range widths {
+		if pos+width > len(s) || s[pos] < '0' || s[pos] > '9' {
+			break
+		}
+		v, err := strconv.Atoi(s[pos : pos+width])
+		if err != nil {
+			return time.Time{}, false
+		}
+		fields[i] = v
+		pos += width
+	}
+
+	loc := time.UTC
+	if pos < len(s) && (s[pos] == '+' || s[pos] == '-') {
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, s[pos+1:])
+		offset := 0
+		if len(digits) >= 2 {
+			hours, _ := strconv.Atoi(digits[:2])
+			offset = hours * 3600
+		}
+		if len(digits) >= 4 {
+			minutes, _ := strconv.Atoi(digits[2:4])
+			offset += minutes * 60
+		}
+		if s[pos] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+
+	return time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, loc), true
+}
+
+// Formats the time as a PDF date string, e.g. D:20180213153000+01'00'.
+func formatPdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+// Returns the XMP metadata packet with the PDF/A identification and the document information.
+func makeXMP(info docInfo) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	buf.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	buf.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\">\n")
+	buf.WriteString("   <pdfaid:part>1</pdfaid:part>\n")
+	buf.WriteString("   <pdfaid:conformance>B</pdfaid:conformance>\n")
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	buf.WriteString("   <dc:format>application/pdf</dc:format>\n")
+	if len(info.title) > 0 {
+		buf.WriteString("   <dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">" + escapeXML(info.title) +
+			"</rdf:li></rdf:Alt></dc:title>\n")
+	}
+	if len(info.author) > 0 {
+		buf.WriteString("   <dc:creator><rdf:Seq><rdf:li>" + escapeXML(info.author) +
+			"</rdf:li></rdf:Seq></dc:creator>\n")
+	}
+	if len(info.subject) > 0 {
+		buf.WriteString("   <dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">" + escapeXML(info.subject) +
+			"</rdf:li></rdf:Alt></dc:description>\n")
+	}
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n")
+	buf.WriteString("   <xmp:CreateDate>" + info.created.Format("2006-01-02T15:04:05-07:00") + "</xmp:CreateDate>\n")
+	buf.WriteString("   <xmp:ModifyDate>" + info.modified.Format("2006-01-02T15:04:05-07:00") + "</xmp:ModifyDate>\n")
+	buf.WriteString("   <xmp:MetadataDate>" + info.modified.Format("2006-01-02T15:04:05-07:00") +
+		"</xmp:MetadataDate>\n")
+	if len(info.creator) > 0 {
+		buf.WriteString("   <xmp:CreatorTool>" + escapeXML(info.creator) + "</xmp:CreatorTool>\n")
+	}
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n")
+	buf.WriteString("   <pdf:Producer>" + escapeXML(info.producer) + "</pdf:Producer>\n")
+	if len(info.keywords) > 0 {
+		buf.WriteString("   <pdf:Keywords>" + escapeXML(info.keywords) + "</pdf:Keywords>\n")
+	}
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString(" </rdf:RDF>\n")
+	buf.WriteString("</x:xmpmeta>\n")
+	// Padding, so that the metadata can be edited in place.
+	for i := 0; i < 20; i++ {
+		buf.WriteString(strings.Repeat(" ", 99) + "\n")
+	}
+	buf.WriteString("<?xpacket end=\"w\"?>")
+	return buf.Bytes()
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// Returns an ICC (version 2) display profile for sRGB: the sRGB primaries adapted to the D50 profile connection
+// space, and the sRGB tone curve as a table.
+func newSRGBProfile() []byte {
+	curve := make([]uint16, 1024)
+	for i := range curve {
+		v := float64(i) / float64(len(curve)-1)
+		if v <= 0.04045 {
+			v = v / 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		curve[i] = uint16(math.Round(v * 65535))
+	}
+	trc := iccCurve(curve)
+
+	tags := []struct {
+		signature string
+		data      []byte
+	}{
+		{"desc", iccDescription("sRGB IEC61966-2.1")},
+		{"cprt", iccText("No copyright, use freely")},
+		{"wtpt", iccXYZ(0.9505, 1, 1.0891)},
+		{"rXYZ", iccXYZ(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", iccXYZ(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", iccXYZ(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	// Header, tag table, then the tag data aligned on 4 bytes.
+	var table, tagData bytes.Buffer
+	dataOffset := 128 + 4 + 12*len(tags)
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	for _, tag := range tags {
+		table.WriteString(tag.signature)
+		binary.Write(&table, binary.BigEndian, uint32(dataOffset+tagData.Len()))
+		binary.Write(&table, binary.BigEndian, uint32(len(tag.data)))
+		tagData.Write(tag.data)
+		for tagData.Len()%4 != 0 {
+			tagData.WriteByte(0)
+		}
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(128+table.Len()+tagData.Len()))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // Version 2.1.
+	copy(header[12:], "mntr")                          // Display device profile.
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	now := time.Now().UTC()
+	for i, v := range []int{now.Year(), int(now.Month()), now.Day(), now.Hour(), now.Minute(), now.Second()} {
+		binary.BigEndian.PutUint16(header[24+2*i:], uint16(v))
+	}
+	copy(header[36:], "acsp")
+	// Illuminant of the profile connection space: D50.
+	copy(header[68:], iccXYZ(0.9642, 1, 0.8249)[8:])
+
+	profile := append(header, table.Bytes()...)
+	return append(profile, tagData.Bytes()...)
+}
+
+// Returns an ICC XYZ tag with the values as s15Fixed16 numbers.
+func iccXYZ(x, y, z float64) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("XYZ \x00\x00\x00\x00")
+	for _, v := range []float64{x, y, z} {
+		binary.Write(&buf, binary.BigEndian, int32(math.Round(v*65536)))
+	}
+	return buf.Bytes()
+}
+
+// Returns an ICC curve tag with the table of values.
+func iccCurve(values []uint16) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("curv\x00\x00\x00\x00")
+	binary.Write(&buf, binary.BigEndian, uint32(len(values)))
+	binary.Write(&buf, binary.BigEndian, values)
+	return buf.Bytes()
+}
+
+// Returns an ICC text tag.
+func iccText(s string) []byte {
+	return []byte("text\x00\x00\x00\x00" + s + "\x00")
+}
+
+// Returns an ICC text description tag, with only the ASCII description (empty Unicode and ScriptCode parts).
+func iccDescription(s string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("desc\x00\x00\x00\x00")
+	binary.Write(&buf, binary.BigEndian, uint32(len(s)+1))
+	buf.WriteString(s + "\x00")
+	buf.Write(make([]byte, 4+4+2+1+67))
+	return buf.Bytes()
+}
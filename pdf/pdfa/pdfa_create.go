@@ -0,0 +1,634 @@
+/*
+ * Create a PDF/A-1b (ISO 19005-1, level B) document for long-term archiving.
+ *
+ * PDF/A-1b requires, among others:
+ * - All fonts embedded, with a FontName in the font descriptor.  The text is set in TrueType fonts loaded from files,
+ *   which are embedded; the standard 14 fonts (Helvetica etc.) are not embedded and cannot be used.
+ * - An output intent with an ICC profile, which defines the meaning of the device colors (DeviceRGB) used.
+ * - XMP metadata with the PDF/A identification (pdfaid:part 1, pdfaid:conformance B), consistent with the document
+ *   information dictionary.
+ * - A file identifier (ID) in the trailer.
+ * - No transparency (no opacity, soft masks or blend modes) and no encryption.
+ *
+ * The document is built with the creator, which writes neither the metadata nor the output intent, and then post-
+ * processed: the catalog (with the Metadata and OutputIntents entries), the information dictionary, the metadata and
+ * ICC profile streams and the fixed font descriptors are appended to the file as an incremental update, with a new
+ * trailer containing the ID.  Finally the fonts used on the pages are checked, and the program fails if any font is
+ * not embedded.
+ *
+ * The ICC profile is an sRGB profile generated by the program, or can be read from a file (e.g. the sRGB profile
+ * from color.org) with -icc.  Note that an unlicensed copy of UniDoc adds a notice in a non-embedded font to each
+ * page, so the output is only compliant with a license.
+ *
+ * The incremental update is written by writeUpdate in update.go, shared with pdfa_convert.go.
+ *
+ * Run as: go run pdfa_create.go update.go [-icc profile.icc] [-font regular.ttf] [-bold bold.ttf] [-title text]
+ *         [-author name] output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run pdfa_create.go update.go [-icc profile.icc] [-font regular.ttf] [-bold bold.ttf] " +
+	"[-title text] [-author name] output.pdf\n"
+
+var text = []string{
+	"PDF/A is the ISO standard for the long-term archiving of electronic documents.  A PDF/A file is self-contained: " +
+		"everything needed to display it in the same way in the future is in the file itself, without relying on " +
+		"fonts installed on the system or on external resources.",
+	"Therefore all fonts are embedded, and the colors are device independent: an output intent with an ICC profile " +
+		"defines what the RGB values of this document mean.  Features that could make the appearance ambiguous, " +
+		"such as transparency, and features that prevent access, such as encryption, are not allowed.",
+	"The document metadata is stored as XMP, which identifies the file as PDF/A-1b.  Conformance level B (basic) " +
+		"guarantees the reliable reproduction of the visual appearance of the document.",
+}
+
+// docInfo holds the document metadata, written both in the information dictionary and in the XMP metadata.
+type docInfo struct {
+	title    string
+	author   string
+	creator  string
+	producer string
+	date     time.Time
+}
+
+func main() {
+	iccPath := ""
+	fontPath := ""
+	boldFontPath := ""
+	title := ""
+	author := ""
+	flag.StringVar(&iccPath, "icc", "", "ICC profile of the output intent (default: built-in sRGB profile)")
+	flag.StringVar(&fontPath, "font", "../report/Roboto-Regular.ttf", "TrueType font for the text")
+	flag.StringVar(&boldFontPath, "bold", "../report/Roboto-Bold.ttf", "TrueType font for the title")
+	flag.StringVar(&title, "title", "Archival document", "Document title")
+	flag.StringVar(&author, "author", "UniDoc", "Document author")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	// The output condition identifies the profile: the registered name for sRGB, Custom for other profiles.
+	iccData := newSRGBProfile()
+	outputCondition := "sRGB IEC61966-2.1"
+	if len(iccPath) > 0 {
+		var err error
+		iccData, err = ioutil.ReadFile(iccPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		outputCondition = "Custom"
+	}
+
+	info := docInfo{title: title, author: author, date: time.Now()}
+
+	err := createPdfA(outputPath, fontPath, boldFontPath, info, iccData, outputCondition)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createPdfA(outputPath, fontPath, boldFontPath string, info docInfo, iccData []byte,
+	outputCondition string) error {
+	// The document colors are DeviceRGB, which requires an RGB output intent.
+	if len(iccData) < 128 || string(iccData[16:20]) != "RGB " {
+		return errors.New("The ICC profile is not an RGB profile")
+	}
+
+	font, err := pdf.NewPdfFontFromTTFFile(fontPath)
+	if err != nil {
+		return err
+	}
+	boldFont, err := pdf.NewPdfFontFromTTFFile(boldFontPath)
+	if err != nil {
+		return err
+	}
+
+	c := creator.New()
+	// PDF/A-1 is based on PDF 1.4.
+	c.SetPdfWriterAccessFunc(func(w *pdf.PdfWriter) error {
+		w.SetVersion(1, 4)
+		return nil
+	})
+	c.NewPage()
+
+	margin := 72.0
+	width := c.Width() - 2*margin
+	y := margin
+
+	heading := creator.NewParagraph(info.title)
+	heading.SetFont(boldFont)
+	heading.SetFontSize(20)
+	heading.SetWidth(width)
+	heading.SetPos(margin, y)
+	err = c.Draw(heading)
+	if err != nil {
+		return err
+	}
+	y += heading.Height() + 6
+
+	byline := creator.NewParagraph(fmt.Sprintf("%s, %s", info.author, info.date.Format("2 January 2006")))
+	byline.SetFont(font)
+	byline.SetFontSize(10)
+	byline.SetColor(creator.ColorRGBFrom8bit(90, 90, 90))
+	byline.SetWidth(width)
+	byline.SetPos(margin, y)
+	err = c.Draw(byline)
+	if err != nil {
+		return err
+	}
+	y += byline.Height() + 18
+
+	for _, s := range text {
+		p := creator.NewParagraph(s)
+		p.SetFont(font)
+		p.SetFontSize(12)
+		p.SetLineHeight(1.3)
+		p.SetWidth(width)
+		p.SetTextAlignment(creator.TextAlignmentJustify)
+		p.SetPos(margin, y)
+		err = c.Draw(p)
+		if err != nil {
+			return err
+		}
+		y += p.Height() + 12
+	}
+
+	err = c.WriteToFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	notEmbedded, err := addPdfAStructures(outputPath, info, iccData, outputCondition)
+	if err != nil {
+		return err
+	}
+	if len(notEmbedded) > 0 {
+		for _, name := range notEmbedded {
+			fmt.Printf("Font not embedded: %s\n", name)
+		}
+		return errors.New("The output is not PDF/A compliant: not all fonts are embedded")
+	}
+
+	return nil
+}
+
+// Appends the PDF/A structures to the PDF file as an incremental update.  Returns the names of the fonts used on the
+// pages that are not embedded.
+func addPdfAStructures(path string, info docInfo, iccData []byte, outputCondition string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	rootNum, ok := objectNumber(trailer.Get("Root"))
+	if !ok {
+		return nil, errors.New("Missing Root in trailer")
+	}
+	infoNum, ok := objectNumber(trailer.Get("Info"))
+	if !ok {
+		return nil, errors.New("Missing Info in trailer")
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return nil, errors.New("Missing Size in trailer")
+	}
+	nextNum := int64(*size)
+
+	catalogObj, err := pdfReader.GetIndirectObjectByNumber(int(rootNum))
+	if err != nil {
+		return nil, err
+	}
+	catalog, ok := pdfcore.TraceToDirectObject(catalogObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("Catalog not a dictionary")
+	}
+
+	// Keep the producer and creator tool of the original information dictionary.
+	infoObj, err := pdfReader.GetIndirectObjectByNumber(int(infoNum))
+	if err != nil {
+		return nil, err
+	}
+	if infoDict, ok := pdfcore.TraceToDirectObject(infoObj).(*pdfcore.PdfObjectDictionary); ok {
+		if s, ok := pdfcore.TraceToDirectObject(infoDict.Get("Producer")).(*pdfcore.PdfObjectString); ok {
+			info.producer = string(*s)
+		}
+		if s, ok := pdfcore.TraceToDirectObject(infoDict.Get("Creator")).(*pdfcore.PdfObjectString); ok {
+			info.creator = string(*s)
+		}
+	}
+
+	objects := []updateObject{}
+
+	// Font descriptors without a FontName (required in PDF/A) get the name of the font.
+	notEmbedded, descriptors, err := checkFonts(pdfReader)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range descriptors {
+		objects = append(objects, obj)
+	}
+
+	// XMP metadata: not compressed, so that it can be read without PDF tools.
+	metadataNum := nextNum
+	nextNum++
+	metadataDict := pdfcore.MakeDict()
+	metadataDict.Set("Type", pdfcore.MakeName("Metadata"))
+	metadataDict.Set("Subtype", pdfcore.MakeName("XML"))
+	objects = append(objects, updateObject{number: metadataNum,
+		obj: &pdfcore.PdfObjectStream{PdfObjectDictionary: metadataDict, Stream: makeXMP(info)}})
+
+	iccNum := nextNum
+	nextNum++
+	iccStream, err := pdfcore.MakeStream(iccData, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+	iccStream.PdfObjectDictionary.Set("N", pdfcore.MakeInteger(3))
+	objects = append(objects, updateObject{number: iccNum, obj: iccStream})
+
+	intentNum := nextNum
+	nextNum++
+	intent := pdfcore.MakeDict()
+	intent.Set("Type", pdfcore.MakeName("OutputIntent"))
+	intent.Set("S", pdfcore.MakeName("GTS_PDFA1"))
+	intent.Set("OutputConditionIdentifier", pdfcore.MakeString(outputCondition))
+	intent.Set("Info", pdfcore.MakeString(outputCondition))
+	intent.Set("DestOutputProfile", &pdfcore.PdfObjectReference{ObjectNumber: iccNum})
+	objects = append(objects, updateObject{number: intentNum, obj: intent})
+
+	catalog.Set("Metadata", &pdfcore.PdfObjectReference{ObjectNumber: metadataNum})
+	catalog.Set("OutputIntents", pdfcore.MakeArray(&pdfcore.PdfObjectReference{ObjectNumber: intentNum}))
+	objects = append(objects, updateObject{number: rootNum, obj: catalog})
+
+	// The information dictionary must match the XMP metadata.
+	date := formatPdfDate(info.date)
+	newInfo := pdfcore.MakeDict()
+	newInfo.Set("Title", pdfcore.MakeString(info.title))
+	newInfo.Set("Author", pdfcore.MakeString(info.author))
+	newInfo.Set("Creator", pdfcore.MakeString(info.creator))
+	newInfo.Set("Producer", pdfcore.MakeString(info.producer))
+	newInfo.Set("CreationDate", pdfcore.MakeString(date))
+	newInfo.Set("ModDate", pdfcore.MakeString(date))
+	objects = append(objects, updateObject{number: infoNum, obj: newInfo})
+
+	// The file identifier is required in PDF/A.  Both parts are the same for a new file.
+	id := fmt.Sprintf("%X", md5.Sum(append([]byte(time.Now().String()), data...)))
+	trailer.Set("ID", pdfcore.MakeArray(pdfcore.MakeString(id), pdfcore.MakeString(id)))
+
+	update, err := writeUpdate(data, objects, trailer, nextNum)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	_, err = f.Write(update)
+	if err != nil {
+		return nil, err
+	}
+
+	return notEmbedded, nil
+}
+
+// Checks the fonts used on the pages.  Returns the names of the fonts that are not embedded, and the font
+// descriptors that need a FontName added.
+func checkFonts(pdfReader *pdf.PdfReader) ([]string, map[int64]updateObject, error) {
+	notEmbedded := []string{}
+	descriptors := map[int64]updateObject{}
+	checked := map[pdfcore.PdfObject]bool{}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		visited := map[*pdfcore.PdfObjectStream]bool{}
+		err = collectFonts(page.Resources, func(name pdfcore.PdfObjectName, fontObj pdfcore.PdfObject) {
+			if checked[fontObj] {
+				return
+			}
+			checked[fontObj] = true
+
+			fontName, embedded, descriptorObj := getFontDescriptor(fontObj)
+			if !embedded {
+				notEmbedded = append(notEmbedded, fontName)
+				return
+			}
+			descriptor, ok := pdfcore.TraceToDirectObject(descriptorObj).(*pdfcore.PdfObjectDictionary)
+			if !ok || descriptor.Get("FontName") != nil {
+				return
+			}
+			num, ok := objectNumber(descriptorObj)
+			if !ok {
+				// A direct descriptor would need the font dictionary to be rewritten.
+				notEmbedded = append(notEmbedded, fontName+" (font descriptor without FontName)")
+				return
+			}
+			descriptor.Set("FontName", pdfcore.MakeName(fontName))
+			descriptors[num] = updateObject{number: num, obj: descriptor}
+		}, visited)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return notEmbedded, descriptors, nil
+}
+
+// Calls onFont for each font in the resources, and in the resources of the form XObjects in the resources.
+func collectFonts(resources *pdf.PdfPageResources, onFont func(pdfcore.PdfObjectName, pdfcore.PdfObject),
+	visited map[*pdfcore.PdfObjectStream]bool) error {
+	if resources == nil {
+		return nil
+	}
+
+	if fontDict, ok := pdfcore.TraceToDirectObject(resources.Font).(*pdfcore.PdfObjectDictionary); ok {
+		for _, name := range fontDict.Keys() {
+			fontObj, found := resources.GetFontByName(name)
+			if !found {
+				continue
+			}
+			onFont(name, fontObj)
+		}
+	}
+
+	xobjDict, ok := pdfcore.TraceToDirectObject(resources.XObject).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	for _, name := range xobjDict.Keys() {
+		stream, xtype := resources.GetXObjectByName(name)
+		if xtype != pdf.XObjectTypeForm || visited[stream] {
+			continue
+		}
+		visited[stream] = true
+
+		xform, err := pdf.NewXObjectFormFromStream(stream)
+		if err != nil {
+			return err
+		}
+		err = collectFonts(xform.Resources, onFont, visited)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the name of the font, whether it is embedded, and its font descriptor (of the descendant font for Type0
+// fonts, nil for Type3 fonts which need none).
+func getFontDescriptor(fontObj pdfcore.PdfObject) (string, bool, pdfcore.PdfObject) {
+	fontDict, ok := pdfcore.TraceToDirectObject(fontObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return "(invalid font)", false, nil
+	}
+
+	fontName := "(unnamed)"
+	if name, ok := pdfcore.TraceToDirectObject(fontDict.Get("BaseFont")).(*pdfcore.PdfObjectName); ok {
+		fontName = string(*name)
+	}
+
+	descriptorDict := fontDict
+	subtype, _ := pdfcore.TraceToDirectObject(fontDict.Get("Subtype")).(*pdfcore.PdfObjectName)
+	if subtype != nil && *subtype == "Type3" {
+		// Type3 glyphs are defined by content streams in the font itself.
+		return fontName, true, nil
+	}
+	if subtype != nil && *subtype == "Type0" {
+		arr, ok := pdfcore.TraceToDirectObject(fontDict.Get("DescendantFonts")).(*pdfcore.PdfObjectArray)
+		if !ok || len(*arr) == 0 {
+			return fontName, false, nil
+		}
+		descriptorDict, ok = pdfcore.TraceToDirectObject((*arr)[0]).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			return fontName, false, nil
+		}
+	}
+
+	descriptorObj := descriptorDict.Get("FontDescriptor")
+	descriptor, ok := pdfcore.TraceToDirectObject(descriptorObj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		// No font descriptor: one of the standard 14 fonts, never embedded.
+		return fontName, false, nil
+	}
+	for _, key := range []pdfcore.PdfObjectName{"FontFile", "FontFile2", "FontFile3"} {
+		if descriptor.Get(key) != nil {
+			return fontName, true, descriptorObj
+		}
+	}
+	return fontName, false, descriptorObj
+}
+
+// Returns the object number of an indirect object or reference.
+func objectNumber(obj pdfcore.PdfObject) (int64, bool) {
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectReference:
+		return t.ObjectNumber, true
+	case *pdfcore.PdfIndirectObject:
+		return t.ObjectNumber, true
+	case *pdfcore.PdfObjectStream:
+		return t.ObjectNumber, true
+	}
+	return 0, false
+}
+
+// Formats the time as a PDF date string, e.g. D:20180213153000+01'00'.
+func formatPdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+// Returns the XMP metadata packet with the PDF/A identification and the document information.
+func makeXMP(info docInfo) []byte {
+	date := info.date.Format("2006-01-02T15:04:05-07:00")
+
+	var buf bytes.Buffer
+	buf.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	buf.WriteString("<x:xmpmeta xmlns:x=\"adobe:ns:meta/\">\n")
+	buf.WriteString(" <rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\" xmlns:pdfaid=\"http://www.aiim.org/pdfa/ns/id/\">\n")
+	buf.WriteString("   <pdfaid:part>1</pdfaid:part>\n")
+	buf.WriteString("   <pdfaid:conformance>B</pdfaid:conformance>\n")
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\" xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	buf.WriteString("   <dc:format>application/pdf</dc:format>\n")
+	buf.WriteString("   <dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">" + escapeXML(info.title) +
+		"</rdf:li></rdf:Alt></dc:title>\n")
+	buf.WriteString("   <dc:creator><rdf:Seq><rdf:li>" + escapeXML(info.author) + "</rdf:li></rdf:Seq></dc:creator>\n")
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\" xmlns:xmp=\"http://ns.adobe.com/xap/1.0/\">\n")
+	buf.WriteString("   <xmp:CreateDate>" + date + "</xmp:CreateDate>\n")
+	buf.WriteString("   <xmp:ModifyDate>" + date + "</xmp:ModifyDate>\n")
+	buf.WriteString("   <xmp:MetadataDate>" + date + "</xmp:MetadataDate>\n")
+	buf.WriteString("   <xmp:CreatorTool>" + escapeXML(info.creator) + "</xmp:CreatorTool>\n")
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString("  <rdf:Description rdf:about=\"\" xmlns:pdf=\"http://ns.adobe.com/pdf/1.3/\">\n")
+	buf.WriteString("   <pdf:Producer>" + escapeXML(info.producer) + "</pdf:Producer>\n")
+	buf.WriteString("  </rdf:Description>\n")
+	buf.WriteString(" </rdf:RDF>\n")
+	buf.WriteString("</x:xmpmeta>\n")
+	// Padding, so that the metadata can be edited in place.
+	for i := 0; i < 20; i++ {
+		buf.WriteString(strings.Repeat(" ", 99) + "\n")
+	}
+	buf.WriteString("<?xpacket end=\"w\"?>")
+	return buf.Bytes()
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// Returns an ICC (version 2) display profile for sRGB: the sRGB primaries adapted to the D50 profile connection
+// space, and the sRGB tone curve as a table.
+func newSRGBProfile() []byte {
+	curve := make([]uint16, 1024)
+	for i := range curve {
+		v := float64(i) / float64(len(curve)-1)
+		if v <= 0.04045 {
+			v = v / 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		curve[i] = uint16(math.Round(v * 65535))
+	}
+	trc := iccCurve(curve)
+
+	tags := []struct {
+		signature string
+		data      []byte
+	}{
+		{"desc", iccDescription("sRGB IEC61966-2.1")},
+		{"cprt", iccText("No copyright, use freely")},
+		{"wtpt", iccXYZ(0.9505, 1, 1.0891)},
+		{"rXYZ", iccXYZ(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", iccXYZ(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", iccXYZ(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	// Header, tag table, then the tag data aligned on 4 bytes.
+	var table, tagData bytes.Buffer
+	dataOffset := 128 + 4 + 12*len(tags)
+	binary.Write(&table, binary.BigEndian, uint32(len(tags)))
+	for _, tag := range tags {
+		table.WriteString(tag.signature)
+		binary.Write(&table, binary.BigEndian, uint32(dataOffset+tagData.Len()))
+		binary.Write(&table, binary.BigEndian, uint32(len(tag.data)))
+		tagData.Write(tag.data)
+		for tagData.Len()%4 != 0 {
+			tagData.WriteByte(0)
+		}
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(128+table.Len()+tagData.Len()))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // Version 2.1.
+	copy(header[12:], "mntr")                          // Display device profile.
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	now := time.Now().UTC()
+	for i, v := range []int{now.Year(), int(now.Month()), now.Day(), now.Hour(), now.Minute(), now.Second()} {
+		binary.BigEndian.PutUint16(header[24+2*i:], uint16(v))
+	}
+	copy(header[36:], "acsp")
+	// Illuminant of the profile connection space: D50.
+	copy(header[68:], iccXYZ(0.9642, 1, 0.8249)[8:])
+
+	profile := append(header, table.Bytes()...)
+	return append(profile, tagData.Bytes()...)
+}
+
+// Returns an ICC XYZ tag with the values as s15Fixed16 numbers.
+func iccXYZ(x, y, z float64) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("XYZ \x00\x00\x00\x00")
+	for _, v := range []float64{x, y, z} {
+		binary.Write(&buf, binary.BigEndian, int32(math.Round(v*65536)))
+	}
+	return buf.Bytes()
+}
+
+// Returns an ICC curve tag with the table of values.
+func iccCurve(values []uint16) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("curv\x00\x00\x00\x00")
+	binary.Write(&buf, binary.BigEndian, uint32(len(values)))
+	binary.Write(&buf, binary.BigEndian, values)
+	return buf.Bytes()
+}
+
+// Returns an ICC text tag.
+func iccText(s string) []byte {
+	return []byte("text\x00\x00\x00\x00" + s + "\x00")
+}
+
+// Returns an ICC text description tag, with only the ASCII description (empty Unicode and ScriptCode parts).
+func iccDescription(s string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("desc\x00\x00\x00\x00")
+	binary.Write(&buf, binary.BigEndian, uint32(len(s)+1))
+	buf.WriteString(s + "\x00")
+	buf.Write(make([]byte, 4+4+2+1+67))
+	return buf.Bytes()
+}
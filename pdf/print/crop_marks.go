@@ -0,0 +1,429 @@
+/*
+ * Prepare a PDF for professional printing: place each page on a larger sheet with a bleed area around its trim box,
+ * crop marks, registration marks and a page information line, and set the TrimBox and BleedBox of the pages.
+ *
+ * The trim box is the size of the finished, cut page.  The printer prints on larger paper and cuts it to the trim
+ * box along the crop marks, which are drawn outside the trim and the bleed, so that none of them is left on the
+ * finished page.  The registration marks (targets at the middle of each side) let the printer check that the color
+ * plates line up.  The marks are in the All separation color, which prints on every plate.  The BleedBox is the
+ * trim box grown by -bleed: the page content is kept up to it, and clipped outside it.
+ *
+ * The trim box of an input page is its TrimBox if it has one.  Otherwise it is its MediaBox, or with -include-bleed,
+ * its MediaBox less the bleed on each side, for pages designed with the bleed included.
+ *
+ * Placing content in the bleed: cutting is not precise to the point, so anything meant to reach the edge of the
+ * finished page (background colors, images, rules running off the page) must not stop at the trim edge, or a thin
+ * white line may show after cutting.  It must extend past the trim edge through the whole bleed.  Conversely, text
+ * and other content that must not be cut off should stay inside a safe margin within the trim (e.g. 5 mm).  To lay
+ * out a page with the creator:
+ *
+ * - Make the page the trim size plus the bleed on each side: c.SetPageSize(creator.PageSize{w + 2*bleed,
+ *   h + 2*bleed}).  The trim box is then the page less the bleed, and the page is processed with -include-bleed.
+ * - Draw full bleed elements from the page edges, e.g. a background rectangle from (0, 0) to the page size, not
+ *   from (bleed, bleed).
+ * - Position the text at least bleed + safe margin from the page edges, e.g. with the page margins.
+ *
+ * A page that does not extend beyond its trim box by the bleed (such as a page with no TrimBox or -include-bleed)
+ * has an empty or partial bleed; a warning is printed for it, as its backgrounds may not reach the edge of the cut
+ * page.  With -sample, a sample design is created as described above and written to the input path, and then
+ * processed.
+ *
+ * Run as: go run crop_marks.go [-bleed 3] [-include-bleed] input.pdf output.pdf
+ *     or: go run crop_marks.go -sample [-bleed 3] design.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run crop_marks.go [-bleed 3] [-include-bleed] input.pdf output.pdf\n" +
+	"   or: go run crop_marks.go -sample [-bleed 3] design.pdf output.pdf\n"
+
+const (
+	mm         = 72 / 25.4 // Points per millimeter.
+	markLength = 6 * mm    // Length of the crop marks.
+	minOffset  = 3 * mm    // Minimum distance between the marks and the trim box.
+	targetSize = 5 * mm    // Diameter of the registration marks.
+	infoHeight = 5 * mm    // Space for the page information line below the marks.
+)
+
+func main() {
+	bleed := 0.0
+	includeBleed := false
+	sample := false
+	flag.Float64Var(&bleed, "bleed", 3, "Bleed around the trim box in mm")
+	flag.BoolVar(&includeBleed, "include-bleed", false, "The pages without TrimBox include the bleed")
+	flag.BoolVar(&sample, "sample", false, "Create a sample design with bleed at the input path, and process it")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if bleed < 0 || bleed > 20 {
+		fmt.Printf("Error: -bleed must be between 0 and 20 mm\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	var err error
+	if sample {
+		err = createSampleDesign(inputPath, bleed*mm)
+		if err == nil {
+			fmt.Printf("Sample design written to %s\n", inputPath)
+		}
+		includeBleed = true
+	}
+	if err == nil {
+		err = addPrinterMarks(inputPath, outputPath, bleed*mm, includeBleed)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func addPrinterMarks(inputPath, outputPath string, bleed float64, includeBleed bool) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	// The marks are outside the bleed, and the information line below them.
+	offset := math.Max(bleed, minOffset)
+	slug := offset + markLength + infoHeight
+	registration := newSeparation("All", [4]float64{1, 1, 1, 1})
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+
+		trim, err := trimBox(page, bleed, includeBleed)
+		if err != nil {
+			return fmt.Errorf("Page %d: %v", i+1, err)
+		}
+		mbox, err := page.GetMediaBox()
+		if err != nil {
+			return err
+		}
+		// Allow for rounding in the box coordinates.
+		short := bleed - 0.5
+		if bleed > 0 && (mbox.Llx > trim.Llx-short || mbox.Lly > trim.Lly-short || mbox.Urx < trim.Urx+short ||
+			mbox.Ury < trim.Ury+short) {
+			fmt.Printf("Warning: Page %d does not extend through the bleed, its backgrounds may not reach the edge "+
+				"of the cut page\n", i+1)
+		}
+
+		xform, _, err := pageToXObjectForm(page)
+		if err != nil {
+			return err
+		}
+
+		trimWidth, trimHeight := trim.Urx-trim.Llx, trim.Ury-trim.Lly
+		sheet := pdf.NewPdfPage()
+		sheet.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: trimWidth + 2*slug, Ury: trimHeight + 2*slug}
+		sheet.TrimBox = &pdf.PdfRectangle{Llx: slug, Lly: slug, Urx: slug + trimWidth, Ury: slug + trimHeight}
+		sheet.BleedBox = &pdf.PdfRectangle{Llx: slug - bleed, Lly: slug - bleed, Urx: slug + trimWidth + bleed,
+			Ury: slug + trimHeight + bleed}
+		// Keep the rotation, which turns the marks with the page.
+		sheet.Rotate = page.Rotate
+		sheet.Resources = pdf.NewPdfPageResources()
+		err = sheet.Resources.SetXObjectFormByName("Page", xform)
+		if err != nil {
+			return err
+		}
+		err = sheet.Resources.SetColorspaceByName("Reg", registration)
+		if err != nil {
+			return err
+		}
+		err = sheet.Resources.SetFontByName("Helv", fonts.NewFontHelvetica().ToPdfObject())
+		if err != nil {
+			return err
+		}
+
+		cc := pdfcontent.NewContentCreator()
+
+		// The page, with its trim box moved onto that of the sheet, clipped to the bleed box.
+		b := sheet.BleedBox
+		cc.Add_q()
+		cc.Add_re(b.Llx, b.Lly, b.Urx-b.Llx, b.Ury-b.Lly)
+		cc.Add_W()
+		cc.Add_n()
+		cc.Add_cm(1, 0, 0, 1, slug-trim.Llx, slug-trim.Lly)
+		cc.Add_Do("Page")
+		cc.Add_Q()
+
+		cc.Add_q()
+		cc.Add_CS("Reg")
+		cc.Add_SCN(1)
+		cc.Add_cs("Reg")
+		cc.Add_scn(1)
+		drawCropMarks(cc, sheet.TrimBox, offset)
+		drawRegistrationMarks(cc, sheet.TrimBox, offset+markLength/2)
+
+		info := fmt.Sprintf("%s  page %d of %d  trim %.1f x %.1f mm  bleed %g mm", filepath.Base(inputPath), i+1,
+			numPages, trimWidth/mm, trimHeight/mm, bleed/mm)
+		cc.Add_BT()
+		cc.Add_Tf("Helv", 6)
+		cc.Add_Td(slug, infoHeight/2-1)
+		cc.Add_Tj(pdfcore.PdfObjectString(info))
+		cc.Add_ET()
+		cc.Add_Q()
+
+		err = sheet.SetContentStreams([]string{cc.String()}, pdfcore.NewFlateEncoder())
+		if err != nil {
+			return err
+		}
+		err = pdfWriter.AddPage(sheet)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// trimBox returns the trim box of the page: its TrimBox, or its MediaBox, less the bleed if it includes it.
+func trimBox(page *pdf.PdfPage, bleed float64, includeBleed bool) (*pdf.PdfRectangle, error) {
+	if page.TrimBox != nil {
+		return page.TrimBox, nil
+	}
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+	if !includeBleed {
+		return mbox, nil
+	}
+	trim := &pdf.PdfRectangle{Llx: mbox.Llx + bleed, Lly: mbox.Lly + bleed, Urx: mbox.Urx - bleed,
+		Ury: mbox.Ury - bleed}
+	if trim.Urx-trim.Llx < 1 || trim.Ury-trim.Lly < 1 {
+		return nil, errors.New("Page smaller than the bleed")
+	}
+	return trim, nil
+}
+
+// drawCropMarks draws the crop marks at the corners of the trim box: lines continuing its edges, from offset to
+// offset + markLength beyond it.
+func drawCropMarks(cc *pdfcontent.ContentCreator, trim *pdf.PdfRectangle, offset float64) {
+	cc.Add_w(0.25)
+	for _, x := range []float64{trim.Llx, trim.Urx} {
+		for _, y := range []float64{trim.Lly, trim.Ury} {
+			// Outward directions from the corner.
+			dx, dy := -1.0, -1.0
+			if x == trim.Urx {
+				dx = 1
+			}
+			if y == trim.Ury {
+				dy = 1
+			}
+			cc.Add_m(x+dx*offset, y)
+			cc.Add_l(x+dx*(offset+markLength), y)
+			cc.Add_m(x, y+dy*offset)
+			cc.Add_l(x, y+dy*(offset+markLength))
+		}
+	}
+	cc.Add_S()
+}
+
+// drawRegistrationMarks draws a registration mark centered at distance beyond the middle of each side of the trim
+// box: a circle with a filled inner circle and a cross.
+func drawRegistrationMarks(cc *pdfcontent.ContentCreator, trim *pdf.PdfRectangle, distance float64) {
+	midX, midY := (trim.Llx+trim.Urx)/2, (trim.Lly+trim.Ury)/2
+	centers := [][2]float64{
+		{midX, trim.Ury + distance},
+		{midX, trim.Lly - distance},
+		{trim.Llx - distance, midY},
+		{trim.Urx + distance, midY},
+	}
+
+	r := targetSize / 2
+	for _, c := range centers {
+		cc.Add_w(0.25)
+		addCircle(cc, c[0], c[1], r*0.7)
+		cc.Add_m(c[0]-r, c[1])
+		cc.Add_l(c[0]+r, c[1])
+		cc.Add_m(c[0], c[1]-r)
+		cc.Add_l(c[0], c[1]+r)
+		cc.Add_S()
+		addCircle(cc, c[0], c[1], r*0.3)
+		cc.Add_f()
+	}
+}
+
+// addCircle adds a circle to the path, as four Bezier curves.
+func addCircle(cc *pdfcontent.ContentCreator, x, y, r float64) {
+	// Distance of the control points from the ends of each quarter.
+	k := r * 0.5523
+	cc.Add_m(x+r, y)
+	cc.Add_c(x+r, y+k, x+k, y+r, x, y+r)
+	cc.Add_c(x-k, y+r, x-r, y+k, x-r, y)
+	cc.Add_c(x-r, y-k, x-k, y-r, x, y-r)
+	cc.Add_c(x+k, y-r, x+r, y-k, x+r, y)
+	cc.Add_h()
+}
+
+// createSampleDesign creates a postcard (A6) laid out with the bleed included: the page is the trim size plus the
+// bleed on each side, the background and the color band run to the page edges, and the text is within a safe margin
+// inside the trim.
+func createSampleDesign(outputPath string, bleed float64) error {
+	const safeMargin = 5 * mm
+	trimWidth, trimHeight := 105*mm, 148*mm
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSize{trimWidth + 2*bleed, trimHeight + 2*bleed})
+	margin := bleed + safeMargin
+	c.SetPageMargins(margin, margin, margin, margin)
+	c.NewPage()
+
+	// Full bleed: from the page edges, not from the trim box.
+	background := creator.NewRectangle(0, 0, c.Width(), c.Height())
+	background.SetFillColor(creator.ColorRGBFrom8bit(250, 240, 215))
+	background.SetBorderWidth(0)
+	err := c.Draw(background)
+	if err != nil {
+		return err
+	}
+	band := creator.NewRectangle(0, 0, c.Width(), bleed+55*mm)
+	band.SetFillColor(creator.ColorRGBFrom8bit(30, 90, 140))
+	band.SetBorderWidth(0)
+	err = c.Draw(band)
+	if err != nil {
+		return err
+	}
+
+	// A rule running off the left and right edges.
+	rule := creator.NewLine(0, bleed+62*mm, c.Width(), bleed+62*mm)
+	rule.SetLineWidth(2)
+	rule.SetColor(creator.ColorRGBFrom8bit(200, 60, 40))
+	err = c.Draw(rule)
+	if err != nil {
+		return err
+	}
+
+	// The text, in the safe area.
+	title := creator.NewParagraph("Greetings from the print shop")
+	title.SetFont(fonts.NewFontHelveticaBold())
+	title.SetFontSize(14)
+	title.SetColor(creator.ColorRGBFrom8bit(255, 255, 255))
+	title.SetWidth(c.Width() - 2*margin)
+	title.SetPos(margin, margin+10*mm)
+	err = c.Draw(title)
+	if err != nil {
+		return err
+	}
+
+	text := creator.NewParagraph("The blue band, the red rule and the background extend into the bleed, past the " +
+		"edges of the trimmed card, so that they reach its edges wherever it is cut. This text stays inside the " +
+		"safe margin, so that it is never cut off.")
+	text.SetFont(fonts.NewFontHelvetica())
+	text.SetFontSize(9)
+	text.SetLineHeight(1.3)
+	text.SetWidth(c.Width() - 2*margin)
+	text.SetPos(margin, bleed+70*mm)
+	err = c.Draw(text)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Converts a page to a Form XObject with the page contents and resources.  The BBox of the form is the page MediaBox,
+// which is also returned.
+func pageToXObjectForm(page *pdf.PdfPage) (*pdf.XObjectForm, *pdf.PdfRectangle, error) {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	xform := pdf.NewXObjectForm()
+	xform.Resources = page.Resources
+	xform.BBox = mbox.ToPdfObject()
+	xform.Filter = pdfcore.NewFlateEncoder()
+	err = xform.SetContentStream([]byte(contents), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return xform, mbox, nil
+}
+
+// Returns a Separation color space for the colorant, with an alternate color in DeviceCMYK: the tint transform
+// scales the CMYK values of the full tint linearly from 0 (no ink) to the tint.
+func newSeparation(name string, full [4]float64) *pdf.PdfColorspaceSpecialSeparation {
+	cs := pdf.NewPdfColorspaceSpecialSeparation()
+	cs.ColorantName = pdfcore.MakeName(name)
+	cs.AlternateSpace = pdf.NewPdfColorspaceDeviceCMYK()
+	cs.TintTransform = &pdf.PdfFunctionType2{
+		Domain: []float64{0, 1},
+		C0:     []float64{0, 0, 0, 0},
+		C1:     full[:],
+		N:      1,
+	}
+	return cs
+}
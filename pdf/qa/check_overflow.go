@@ -0,0 +1,411 @@
+/*
+ * Layout QA: detect content that is clipped by the page edge or that runs into the page margins.
+ *
+ * For each page the content stream is walked while tracking the current transformation matrix (cm, q/Q), the text state
+ * (Tm, Td, Tf, Tc, Tw, Tz, Ts, with text_layout.go) and the current path, to compute the bounding box of everything
+ * that is painted: stroked/filled paths, text and XObjects (images as the unit square, forms recursively).
+ *
+ * A page is reported when its content bounding box:
+ * - extends beyond the MediaBox (the content is clipped when viewed or printed), or
+ * - extends into the expected margins.
+ *
+ * Margin assumptions: the expected margins are the same on all pages and are given in points (72 points per inch), by
+ * default 36 points (half an inch) on each side, which can be changed with -margin.  Content that is meant to be in the
+ * margins (headers, footers, page numbers) will also be reported; increase -tolerance or reduce -margin accordingly
+ * (this includes the watermark that an unlicensed copy of unidoc places in the bottom left corner).  The text extent is
+ * the box of each glyph, from the font widths (or the standard 14 font metrics) and the ascent and descent of the font
+ * descriptor.  Clipping paths are not taken into account, so content that is clipped away by a clipping path is still
+ * counted.
+ *
+ * Run as: go run check_overflow.go text_layout.go [-margin 36] [-tolerance 0.5] input.pdf
+ *
+ * To see it catch a table that runs off the right edge of the page:
+ *         go run check_overflow.go text_layout.go -demo overflow_demo.pdf
+ * which creates overflow_demo.pdf and checks it.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// OverflowIssue describes content exceeding the bounds on a single page.  Excess values are in points and are zero
+// for the sides that are within bounds.
+type OverflowIssue struct {
+	PageNum int
+	// Clipped is true when the content extends beyond the MediaBox, false when it only extends into the margins.
+	Clipped bool
+
+	Left, Right, Top, Bottom float64
+}
+
+func (issue OverflowIssue) String() string {
+	kind := "content in margin"
+	if issue.Clipped {
+		kind = "content clipped by page edge"
+	}
+	s := fmt.Sprintf("Page %d: %s:", issue.PageNum, kind)
+	if issue.Left > 0 {
+		s += fmt.Sprintf(" left by %.1f pt", issue.Left)
+	}
+	if issue.Right > 0 {
+		s += fmt.Sprintf(" right by %.1f pt", issue.Right)
+	}
+	if issue.Top > 0 {
+		s += fmt.Sprintf(" top by %.1f pt", issue.Top)
+	}
+	if issue.Bottom > 0 {
+		s += fmt.Sprintf(" bottom by %.1f pt", issue.Bottom)
+	}
+	return s
+}
+
+// Expected margins (points) and tolerance used by CheckContentOverflow.
+var (
+	expectedMargin = 36.0
+	tolerance      = 0.5
+)
+
+func main() {
+	demoPath := ""
+	flag.Float64Var(&expectedMargin, "margin", 36, "Expected page margin in points")
+	flag.Float64Var(&tolerance, "tolerance", 0.5, "Ignore excess smaller than this (points)")
+	flag.StringVar(&demoPath, "demo", "", "Create a demo PDF with a table running off the page at this path")
+	flag.Parse()
+
+	// Enable debug-level logging.
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := ""
+	if len(demoPath) > 0 {
+		err := createOverflowDemo(demoPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		inputPath = demoPath
+	} else {
+		if flag.NArg() < 1 {
+			fmt.Printf("Usage: go run check_overflow.go text_layout.go [-margin 36] [-tolerance 0.5] input.pdf\n")
+			fmt.Printf("   or: go run check_overflow.go text_layout.go -demo overflow_demo.pdf\n")
+			os.Exit(1)
+		}
+		inputPath = flag.Arg(0)
+	}
+
+	issues, err := CheckContentOverflow(inputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(issues) == 0 {
+		fmt.Printf("No overflow found in %s\n", inputPath)
+		return
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s\n", issue)
+	}
+}
+
+// CheckContentOverflow computes the content bounding box of each page in the PDF at inputPath and returns the pages
+// where the content extends beyond the MediaBox or into the expected margins.
+func CheckContentOverflow(inputPath string) ([]OverflowIssue, error) {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []OverflowIssue{}
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return nil, err
+		}
+
+		mbox, err := page.GetMediaBox()
+		if err != nil {
+			return nil, err
+		}
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return nil, err
+		}
+
+		bbox := newBoundingBox()
+		err = contentBoundingBox(contents, page.Resources, identityMatrix(), bbox, 0)
+		if err != nil {
+			return nil, err
+		}
+		if bbox.empty() {
+			unicommon.Log.Debug("Page %d: no content", pageNum)
+			continue
+		}
+		unicommon.Log.Debug("Page %d: content bbox %+v", pageNum, *bbox)
+
+		// Outside the media box: clipped.
+		clipped := OverflowIssue{PageNum: pageNum, Clipped: true}
+		clipped.Left = excess(mbox.Llx - bbox.llx)
+		clipped.Right = excess(bbox.urx - mbox.Urx)
+		clipped.Bottom = excess(mbox.Lly - bbox.lly)
+		clipped.Top = excess(bbox.ury - mbox.Ury)
+		if clipped.hasExcess() {
+			issues = append(issues, clipped)
+		}
+
+		// Inside the media box, but in the margins.  Only sides that are not already clipped are reported.
+		margin := OverflowIssue{PageNum: pageNum}
+		if clipped.Left == 0 {
+			margin.Left = excess(mbox.Llx + expectedMargin - bbox.llx)
+		}
+		if clipped.Right == 0 {
+			margin.Right = excess(bbox.urx - (mbox.Urx - expectedMargin))
+		}
+		if clipped.Bottom == 0 {
+			margin.Bottom = excess(mbox.Lly + expectedMargin - bbox.lly)
+		}
+		if clipped.Top == 0 {
+			margin.Top = excess(bbox.ury - (mbox.Ury - expectedMargin))
+		}
+		if margin.hasExcess() {
+			issues = append(issues, margin)
+		}
+	}
+
+	return issues, nil
+}
+
+func (issue OverflowIssue) hasExcess() bool {
+	return issue.Left > 0 || issue.Right > 0 || issue.Top > 0 || issue.Bottom > 0
+}
+
+// Returns the amount if above the tolerance, otherwise 0.
+func excess(amount float64) float64 {
+	if amount > tolerance {
+		return amount
+	}
+	return 0
+}
+
+// Creates a PDF with a table that is wider than the page: the right margin of the table is negative so it extends
+// past the right margin and off the right edge of the page.
+func createOverflowDemo(outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+
+	p := creator.NewParagraph("This page has a table which runs off the right edge of the page.")
+	p.SetMargins(0, 0, 0, 10)
+	c.Draw(p)
+
+	table := creator.NewTable(6)
+	table.SetMargins(0, -150, 0, 0)
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 6; col++ {
+			p := creator.NewParagraph(fmt.Sprintf("Row %d, col %d", row+1, col+1))
+			p.SetFont(fonts.NewFontHelvetica())
+			cell := table.NewCell()
+			cell.SetBorder(creator.CellBorderStyleBox, 1)
+			cell.SetContent(p)
+		}
+	}
+	err := c.Draw(table)
+	if err != nil {
+		return err
+	}
+
+	// Second page is fine.
+	c.NewPage()
+	c.Draw(creator.NewParagraph("This page is within the margins."))
+
+	return c.WriteToFile(outputPath)
+}
+
+// boundingBox accumulates points in default user space.
+type boundingBox struct {
+	llx, lly, urx, ury float64
+}
+
+func newBoundingBox() *boundingBox {
+	return &boundingBox{llx: math.Inf(1), lly: math.Inf(1), urx: math.Inf(-1), ury: math.Inf(-1)}
+}
+
+func (b *boundingBox) empty() bool {
+	return b.llx > b.urx
+}
+
+func (b *boundingBox) addPoint(x, y float64) {
+	b.llx = math.Min(b.llx, x)
+	b.lly = math.Min(b.lly, y)
+	b.urx = math.Max(b.urx, x)
+	b.ury = math.Max(b.ury, y)
+}
+
+func (b *boundingBox) addBox(other *boundingBox) {
+	if other.empty() {
+		return
+	}
+	b.addPoint(other.llx, other.lly)
+	b.addPoint(other.urx, other.ury)
+}
+
+// Adds the rectangle (x0,y0)-(x1,y1) transformed by m.
+func (b *boundingBox) addRect(m matrix, x0, y0, x1, y1 float64) {
+	b.addPoint(m.transform(x0, y0))
+	b.addPoint(m.transform(x1, y0))
+	b.addPoint(m.transform(x0, y1))
+	b.addPoint(m.transform(x1, y1))
+}
+
+// Walks the content stream and adds the extent of all painted content to bbox.
+func contentBoundingBox(contents string, resources *pdf.PdfPageResources, ctm matrix, bbox *boundingBox,
+	depth int) error {
+	cstreamParser := pdfcontent.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return err
+	}
+
+	state := newTextState(ctm)
+	stack := []textState{}
+	path := newBoundingBox()
+	cache := fontCache{}
+
+	for _, op := range *operations {
+		params := make([]float64, len(op.Params))
+		for i, p := range op.Params {
+			params[i], _ = getNumberAsFloat(p)
+		}
+
+		switch op.Operand {
+		case "q":
+			stack = append(stack, state)
+		case "Q":
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(params) == 6 {
+				state.ctm = matrix{params[0], params[1], params[2], params[3], params[4], params[5]}.mult(state.ctm)
+			}
+
+		// Path construction.
+		case "m", "l":
+			if len(params) == 2 {
+				path.addPoint(state.ctm.transform(params[0], params[1]))
+			}
+		case "c", "v", "y":
+			// The curve lies within the convex hull of its control points.
+			for i := 0; i+1 < len(params); i += 2 {
+				path.addPoint(state.ctm.transform(params[i], params[i+1]))
+			}
+		case "re":
+			if len(params) == 4 {
+				path.addRect(state.ctm, params[0], params[1], params[0]+params[2], params[1]+params[3])
+			}
+
+		// Path painting.
+		case "S", "s", "f", "F", "f*", "B", "B*", "b", "b*":
+			bbox.addBox(path)
+			path = newBoundingBox()
+		case "n":
+			// End path without painting (typically a clipping path).
+			path = newBoundingBox()
+
+		// XObjects.
+		case "Do":
+			if len(op.Params) != 1 || resources == nil {
+				continue
+			}
+			name, ok := op.Params[0].(*pdfcore.PdfObjectName)
+			if !ok {
+				continue
+			}
+			stream, xtype := resources.GetXObjectByName(*name)
+			switch xtype {
+			case pdf.XObjectTypeImage:
+				// Images are painted on the unit square.
+				bbox.addRect(state.ctm, 0, 0, 1, 1)
+			case pdf.XObjectTypeForm:
+				if depth >= maxFormDepth {
+					unicommon.Log.Debug("Form XObjects nested too deep, skipping")
+					continue
+				}
+				xform, err := pdf.NewXObjectFormFromStream(stream)
+				if err != nil {
+					return err
+				}
+				formContents, err := xform.GetContentStream()
+				if err != nil {
+					return err
+				}
+				formMatrix := identityMatrix()
+				if arr, ok := pdfcore.TraceToDirectObject(xform.Matrix).(*pdfcore.PdfObjectArray); ok {
+					vals, err := arr.ToFloat64Array()
+					if err == nil && len(vals) == 6 {
+						copy(formMatrix[:], vals)
+					}
+				}
+				formResources := xform.Resources
+				if formResources == nil {
+					formResources = resources
+				}
+				err = contentBoundingBox(string(formContents), formResources, formMatrix.mult(state.ctm), bbox,
+					depth+1)
+				if err != nil {
+					return err
+				}
+			}
+		case "BI":
+			// Inline image, also painted on the unit square.
+			bbox.addRect(state.ctm, 0, 0, 1, 1)
+
+		// Text: the box of each glyph, from the descent to the ascent.
+		default:
+			state.apply(op, resources, cache, func(g shownGlyph) {
+				bbox.addRect(g.trm, 0, g.font.descent, g.width, g.font.ascent)
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,379 @@
+/*
+ * Create a receipt for 80 mm thermal printer paper: a single narrow page with the store logo, the line items, the
+ * totals and a QR code linking to the receipt online.
+ *
+ * Thermal paper comes on a roll, so the page is as tall as its content.  The receipt is laid out twice with the same
+ * code: first without drawing, to measure the height, and then on a page of that height.  Item names that do not
+ * fit next to the amount wrap within the name column, and words longer than the column are broken.
+ *
+ * The logo is an image file (-logo), scaled to fit the paper width, or the store name in large type when none is
+ * given.  Thermal printers print black only, so everything is black on white.
+ *
+ * Run as: go run thermal_receipt.go [-width 80] [-logo logo.png] output.pdf
+ */
+/*
+ * NOTE: This example depends on github.com/boombuler/barcode, MIT licensed.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run thermal_receipt.go [-width 80] [-logo logo.png] output.pdf\n"
+
+// Layout of the receipt (points).
+const (
+	sideMargin   = 10.0 // Unprintable margin on the left and right of the paper.
+	topMargin    = 12.0
+	bottomMargin = 36.0 // Space below the content for tearing off the receipt.
+	fontSize     = 8.0
+	columnGap    = 8.0 // Space between the item names and the amounts.
+	qrSize       = 96.0
+)
+
+var black = creator.ColorRGBFrom8bit(0, 0, 0)
+
+// item is a line item.  Prices are in cents, to add them up without rounding errors.
+type item struct {
+	Name      string
+	Quantity  int
+	UnitPrice int64
+}
+
+type receipt struct {
+	Store    string
+	Address  []string
+	Number   string
+	Date     string
+	Cashier  string
+	Items    []item
+	TaxRate  float64 // Percent, included in the prices.
+	Paid     int64   // Amount paid in cash, in cents.
+	URL      string  // Link to the receipt online, encoded in the QR code.
+	Greeting string
+}
+
+var sample = receipt{
+	Store:   "CORNER MARKET",
+	Address: []string{"1200 Harbor Street", "Portland, OR 97201", "Tel. (503) 555-0188"},
+	Number:  "0042-118734",
+	Date:    "2017-06-14 17:42",
+	Cashier: "Dana",
+	Items: []item{
+		{"Sourdough bread", 1, 495},
+		{"Organic whole milk 1 gal", 2, 529},
+		{"Free range eggs, large, dozen", 1, 449},
+		{"Extra virgin olive oil, cold pressed, imported from Italy, 750 ml bottle", 1, 1299},
+		{"Bananas", 6, 25},
+		{"Dark chocolate 85%", 3, 349},
+		{"Sparkling water, lemon-lime, 12 pack", 1, 599},
+		{"Gift card activation #7731-0042-9981-2215", 1, 2500},
+	},
+	TaxRate:  8.5,
+	Paid:     8000,
+	URL:      "https://receipts.example.com/r/0042-118734",
+	Greeting: "Thank you for shopping with us!\nReturns within 30 days with this receipt.",
+}
+
+func main() {
+	width := 0.0
+	logoPath := ""
+	flag.Float64Var(&width, "width", 80, "Paper width in mm")
+	flag.StringVar(&logoPath, "logo", "", "Logo image file (optional)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	if width < 50 || width > 120 {
+		fmt.Printf("Error: -width must be between 50 and 120 mm\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := createReceipt(sample, width*72/25.4, logoPath, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createReceipt(r receipt, pageWidth float64, logoPath string, outputPath string) error {
+	var logo *creator.Image
+	if logoPath != "" {
+		var err error
+		logo, err = creator.NewImageFromFile(logoPath)
+		if err != nil {
+			return err
+		}
+	}
+	qrCode, err := qr.Encode(r.URL, qr.M, qr.Auto)
+	if err != nil {
+		return err
+	}
+
+	// Measure the height of the content, then draw it on a page of that height.
+	measure := &layout{width: pageWidth, draw: func(creator.Drawable) error { return nil }}
+	err = measure.receipt(r, logo, qrCode)
+	if err != nil {
+		return err
+	}
+	pageHeight := measure.y + bottomMargin
+	fmt.Printf("Receipt: %.0f x %.0f mm\n", pageWidth*25.4/72, pageHeight*25.4/72)
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSize{pageWidth, pageHeight})
+	c.NewPage()
+
+	l := &layout{width: pageWidth, draw: c.Draw}
+	err = l.receipt(r, logo, qrCode)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// layout lays out the receipt from the top of the page down, with y the top of the next line.  Its draw function
+// is the creator's Draw, or a function that does nothing to only measure the height.
+type layout struct {
+	width float64
+	y     float64
+	draw  func(creator.Drawable) error
+}
+
+// receipt lays out the whole receipt.
+func (l *layout) receipt(r receipt, logo *creator.Image, qrCode barcode.Barcode) error {
+	l.y = topMargin
+	contentWidth := l.width - 2*sideMargin
+
+	// The logo, or the store name.
+	if logo != nil {
+		if logo.Width() > contentWidth {
+			logo.ScaleToWidth(contentWidth)
+		}
+		logo.SetPos((l.width-logo.Width())/2, l.y)
+		err := l.draw(logo)
+		if err != nil {
+			return err
+		}
+		l.y += logo.Height() + 6
+	} else {
+		err := l.text(r.Store, fonts.NewFontHelveticaBold(), 16, creator.TextAlignmentCenter)
+		if err != nil {
+			return err
+		}
+		l.y += 4
+	}
+	err := l.text(strings.Join(r.Address, "\n"), fonts.NewFontCourier(), fontSize, creator.TextAlignmentCenter)
+	if err != nil {
+		return err
+	}
+	l.y += 6
+
+	for _, row := range [][2]string{
+		{"Receipt", r.Number},
+		{"Date", r.Date},
+		{"Cashier", r.Cashier},
+	} {
+		err := l.row(row[0], row[1], fonts.NewFontCourier())
+		if err != nil {
+			return err
+		}
+	}
+	err = l.rule()
+	if err != nil {
+		return err
+	}
+
+	// The line items, with the quantity and unit price on a line below the name if more than one.
+	total := int64(0)
+	count := 0
+	for _, it := range r.Items {
+		if it.Quantity < 1 {
+			return fmt.Errorf("Invalid quantity %d of %q", it.Quantity, it.Name)
+		}
+		amount := int64(it.Quantity) * it.UnitPrice
+		total += amount
+		count += it.Quantity
+
+		err := l.row(it.Name, formatAmount(amount), fonts.NewFontCourier())
+		if err != nil {
+			return err
+		}
+		if it.Quantity > 1 {
+			err := l.row(fmt.Sprintf("  %d x %s", it.Quantity, formatAmount(it.UnitPrice)), "",
+				fonts.NewFontCourier())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = l.rule()
+	if err != nil {
+		return err
+	}
+
+	// The totals.  The prices include tax, so the tax is the part of the total above the net amount.
+	if r.Paid < total {
+		return errors.New("The amount paid is less than the total")
+	}
+	tax := total - int64(float64(total)/(1+r.TaxRate/100)+0.5)
+	for _, row := range []struct {
+		label, value string
+		bold         bool
+	}{
+		{fmt.Sprintf("%d items", count), "", false},
+		{"TOTAL", formatAmount(total), true},
+		{fmt.Sprintf("incl. tax %.1f%%", r.TaxRate), formatAmount(tax), false},
+		{"Cash", formatAmount(r.Paid), false},
+		{"Change", formatAmount(r.Paid - total), false},
+	} {
+		var font fonts.Font = fonts.NewFontCourier()
+		if row.bold {
+			font = fonts.NewFontCourierBold()
+		}
+		err := l.row(row.label, row.value, font)
+		if err != nil {
+			return err
+		}
+	}
+	err = l.rule()
+	if err != nil {
+		return err
+	}
+
+	// The QR code and the greeting.
+	l.y += 4
+	err = l.qrCode(qrCode, (l.width-qrSize)/2, qrSize)
+	if err != nil {
+		return err
+	}
+	l.y += 4
+	return l.text(r.Greeting, fonts.NewFontCourier(), fontSize, creator.TextAlignmentCenter)
+}
+
+// text lays out wrapped text over the content width.
+func (l *layout) text(text string, font fonts.Font, size float64, alignment creator.TextAlignment) error {
+	p := newParagraph(text, font, size)
+	p.SetWidth(l.width - 2*sideMargin)
+	p.SetTextAlignment(alignment)
+	p.SetPos(sideMargin, l.y)
+	l.y += p.Height()
+	return l.draw(p)
+}
+
+// row lays out a label, wrapped within the space left of the value, and the value right aligned on the first line.
+func (l *layout) row(label, value string, font fonts.Font) error {
+	valueWidth := 0.0
+	if value != "" {
+		v := newParagraph(value, font, fontSize)
+		v.SetEnableWrap(false)
+		valueWidth = v.Width() + columnGap
+		v.SetPos(l.width-sideMargin-v.Width(), l.y)
+		err := l.draw(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	p := newParagraph(label, font, fontSize)
+	p.SetWidth(l.width - 2*sideMargin - valueWidth)
+	p.SetPos(sideMargin, l.y)
+	l.y += p.Height() + 1
+	return l.draw(p)
+}
+
+// rule lays out a dashed line over the content width.
+func (l *layout) rule() error {
+	const dash, gap = 3.0, 2.0
+	y := l.y + 4
+	for x := sideMargin; x < l.width-sideMargin; x += dash + gap {
+		end := x + dash
+		if end > l.width-sideMargin {
+			end = l.width - sideMargin
+		}
+		line := creator.NewLine(x, y, end, y)
+		line.SetLineWidth(0.5)
+		line.SetColor(black)
+		err := l.draw(line)
+		if err != nil {
+			return err
+		}
+	}
+	l.y += 9
+	return nil
+}
+
+// qrCode lays out the QR code at x as vector rectangles, including the 4 module quiet zone, within size x size
+// points.  Horizontal runs of dark modules are merged into one rectangle.
+func (l *layout) qrCode(qrCode barcode.Barcode, x, size float64) error {
+	const quietZone = 4
+	bounds := qrCode.Bounds()
+	module := size / float64(bounds.Dx()+2*quietZone)
+
+	for j := bounds.Min.Y; j < bounds.Max.Y; j++ {
+		for i := bounds.Min.X; i < bounds.Max.X; {
+			if !isDark(qrCode, i, j) {
+				i++
+				continue
+			}
+			start := i
+			for i < bounds.Max.X && isDark(qrCode, i, j) {
+				i++
+			}
+			rect := creator.NewRectangle(x+float64(quietZone+start)*module, l.y+float64(quietZone+j)*module,
+				float64(i-start)*module, module)
+			rect.SetFillColor(black)
+			rect.SetBorderWidth(0)
+			err := l.draw(rect)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	l.y += size
+	return nil
+}
+
+func isDark(bc barcode.Barcode, x, y int) bool {
+	r, _, _, _ := bc.At(x, y).RGBA()
+	return r == 0
+}
+
+// formatAmount formats an amount in cents as dollars, e.g. 1299 as 12.99.
+func formatAmount(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
+
+func newParagraph(text string, font fonts.Font, size float64) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetColor(black)
+	return p
+}
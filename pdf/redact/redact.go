@@ -0,0 +1,510 @@
+/*
+ * Redact text: cover areas of pages with opaque black boxes and remove the text underneath from the content
+ * streams, so that it can no longer be extracted, copied or searched.
+ *
+ * The areas are given as rectangles, page:x,y,width,height separated by semicolons, with the coordinates in the PDF
+ * coordinate system (0,0 in the lower left corner), and/or found by searching for a string.  The search ignores case
+ * and matches across text showing operations, where words may be separated by a gap instead of a space character.
+ *
+ * The glyph positions are computed with text_layout.go from the text state (font size, spacing, text and transformation
+ * matrices) and the font widths.  Removal is conservative: a glyph is removed if its box overlaps a redaction area even
+ * partially, and the black box is enlarged to cover the removed glyphs.  The removed glyphs are replaced by position
+ * adjustments in TJ arrays, so the remaining text keeps its position.
+ *
+ * After writing the output, the text inside the black boxes is extracted again to verify that the redacted text is
+ * gone.
+ *
+ * Limitations:
+ * - Only the text of the page content streams is removed.  Form XObjects and images in redaction areas are covered,
+ *   but not removed (a warning is printed), and the text of form XObjects is not verified.
+ * - The search needs simple fonts, text in composite (Type0) fonts can be redacted by rectangles only.
+ * - Without a license key, unidoc adds a watermark ("Unlicensed UniDoc - Get a license on https://unidoc.io") to
+ *   the bottom of each page of the output, drawn over the black boxes where they overlap.  The verification checks
+ *   the text inside the black boxes only, so the watermark is not taken for redacted text left in the output.
+ *
+ * Run as: go run redact.go text_layout.go [-search text] [-rects "page:x,y,w,h;..."] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run redact.go text_layout.go [-search text] [-rects \"page:x,y,w,h;...\"] " +
+	"input.pdf output.pdf\n"
+
+func main() {
+	search := ""
+	rects := ""
+	flag.StringVar(&search, "search", "", "Redact all occurrences of the text")
+	flag.StringVar(&rects, "rects", "", "Redact the areas (page:x,y,width,height separated by semicolons)")
+	flag.Parse()
+
+	if flag.NArg() < 2 || (len(search) == 0 && len(rects) == 0) {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	areas, err := parseAreas(rects)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = redactPdf(inputPath, outputPath, search, areas)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Parses the redaction areas: page:x,y,width,height separated by semicolons.  Returns the rectangles per page.
+func parseAreas(s string) (map[int][]pdf.PdfRectangle, error) {
+	areas := map[int][]pdf.PdfRectangle{}
+	if len(s) == 0 {
+		return areas, nil
+	}
+	for _, part := range strings.Split(s, ";") {
+		pageAndRect := strings.SplitN(part, ":", 2)
+		values := strings.Split(pageAndRect[len(pageAndRect)-1], ",")
+		if len(pageAndRect) != 2 || len(values) != 4 {
+			return nil, fmt.Errorf("Invalid area %q (use page:x,y,width,height)", part)
+		}
+		pageNum, err := strconv.Atoi(strings.TrimSpace(pageAndRect[0]))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid page number in area %q", part)
+		}
+		v := make([]float64, 4)
+		for i, value := range values {
+			v[i], err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid area %q (use page:x,y,width,height)", part)
+			}
+		}
+		if v[2] <= 0 || v[3] <= 0 {
+			return nil, fmt.Errorf("Invalid area %q: width and height must be positive", part)
+		}
+		areas[pageNum] = append(areas[pageNum], pdf.PdfRectangle{Llx: v[0], Lly: v[1], Urx: v[0] + v[2], Ury: v[1] + v[3]})
+	}
+	return areas, nil
+}
+
+func redactPdf(inputPath, outputPath, search string, areas map[int][]pdf.PdfRectangle) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	for pageNum := range areas {
+		if pageNum < 1 || pageNum > numPages {
+			return fmt.Errorf("Page %d out of range (document has %d pages)", pageNum, numPages)
+		}
+	}
+
+	// The black boxes and the removed text runs, for the verification.
+	boxes := map[int][]pdf.PdfRectangle{}
+	removed := map[int][]string{}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+
+		pageBoxes, runs, err := redactPage(page, pageNum, search, areas[pageNum])
+		if err != nil {
+			return err
+		}
+		boxes[pageNum] = pageBoxes
+		if len(runs) > 0 {
+			removed[pageNum] = runs
+			fmt.Printf("Page %d: removed %q\n", pageNum, runs)
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	err = pdfWriter.Write(fWrite)
+	if err != nil {
+		return err
+	}
+
+	return verifyRedaction(outputPath, search, boxes, removed)
+}
+
+// Redacts the areas and the occurrences of the search text on the page.  Returns the black boxes drawn, which cover
+// the areas and the removed glyphs, and the removed text runs.
+func redactPage(page *pdf.PdfPage, pageNum int, search string, rects []pdf.PdfRectangle) ([]pdf.PdfRectangle,
+	[]string, error) {
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, nil, err
+	}
+	operations, err := pdfcontent.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	layout := newTextLayout()
+	err = layout.processOperations(*operations, page.Resources, identityMatrix(), 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(search) > 0 {
+		rects = append(rects, layout.find(search)...)
+	}
+	if len(rects) == 0 {
+		return nil, nil, nil
+	}
+
+	// Mark the glyphs overlapping the areas, and enlarge the black boxes to cover them.  The glyphs of form XObjects
+	// are covered, but not removed.
+	boxes := append([]pdf.PdfRectangle{}, rects...)
+	removed := make([]bool, len(layout.glyphs))
+	for i, g := range layout.glyphs {
+		for j, rect := range rects {
+			if overlaps(g.box, rect) {
+				removed[i] = g.op >= 0
+				boxes[j] = union(boxes[j], g.box)
+			}
+		}
+	}
+
+	for _, xobj := range layout.xobjects {
+		for _, rect := range rects {
+			if overlaps(xobj.box, rect) {
+				fmt.Printf("Warning: page %d: XObject %s overlaps a redaction area, it is covered but not removed\n",
+					pageNum, xobj.name)
+				break
+			}
+		}
+	}
+
+	newOperations, runs := removeGlyphs(*operations, layout.glyphs, removed)
+
+	// The original contents are wrapped in q/Q so that the boxes are drawn in the default coordinate system.
+	content := "q\n" + string(newOperations.Bytes()) + "Q\nq\n0 g\n"
+	for _, box := range boxes {
+		content += fmt.Sprintf("%.2f %.2f %.2f %.2f re\n", box.Llx, box.Lly, box.Urx-box.Llx, box.Ury-box.Lly)
+	}
+	content += "f\nQ\n"
+
+	err = page.SetContentStreams([]string{content}, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return boxes, runs, nil
+}
+
+// Replaces the text showing operations with removed glyphs by TJ operations where the removed glyphs are replaced by
+// adjustments of the same width.  Returns the new operations and the removed text runs.
+func removeGlyphs(operations pdfcontent.ContentStreamOperations, glyphs []glyph, removed []bool) (
+	pdfcontent.ContentStreamOperations, []string) {
+	// The glyphs shown by each operation, for the operations with removed glyphs.
+	shown := map[int][]int{}
+	hasRemoved := map[int]bool{}
+	for i, g := range glyphs {
+		if g.op >= 0 {
+			shown[g.op] = append(shown[g.op], i)
+			hasRemoved[g.op] = hasRemoved[g.op] || removed[i]
+		}
+	}
+
+	newOperations := pdfcontent.ContentStreamOperations{}
+	runs := []string{}
+	for opIndex, op := range operations {
+		if !hasRemoved[opIndex] {
+			newOperations = append(newOperations, op)
+			continue
+		}
+
+		// The ' and " operators move to the next line (and " sets the spacing) before showing the text.
+		switch op.Operand {
+		case "'":
+			newOperations = append(newOperations, &pdfcontent.ContentStreamOperation{Operand: "T*"})
+		case "\"":
+			newOperations = append(newOperations,
+				&pdfcontent.ContentStreamOperation{Operand: "Tw", Params: op.Params[0:1]},
+				&pdfcontent.ContentStreamOperation{Operand: "Tc", Params: op.Params[1:2]},
+				&pdfcontent.ContentStreamOperation{Operand: "T*"})
+		}
+
+		// The strings and adjustments shown, in order.
+		elements := op.Params[len(op.Params)-1:]
+		if op.Operand == "TJ" {
+			elements = *op.Params[0].(*pdfcore.PdfObjectArray)
+		}
+
+		arr := pdfcore.PdfObjectArray{}
+		str := []byte{}
+		adjust := 0.0
+		run := []rune{}
+		next := 0 // Index in shown[opIndex] of the next glyph.
+		for _, obj := range elements {
+			if strObj, ok := obj.(*pdfcore.PdfObjectString); ok {
+				for n := 0; n < len(*strObj) && next < len(shown[opIndex]); next++ {
+					i := shown[opIndex][next]
+					g := glyphs[i]
+					n += len(g.code)
+					if !removed[i] {
+						if adjust != 0 {
+							arr = append(arr, pdfcore.MakeFloat(adjust))
+							adjust = 0
+						}
+						str = append(str, g.code...)
+						if len(run) > 0 {
+							runs = append(runs, strings.TrimSpace(string(run)))
+							run = []rune{}
+						}
+						continue
+					}
+					if len(str) > 0 {
+						arr = append(arr, pdfcore.MakeString(string(str)))
+						str = []byte{}
+					}
+					adjust -= g.advance
+					run = append(run, g.r)
+				}
+				continue
+			}
+
+			adj, err := getNumberAsFloat(obj)
+			if err != nil {
+				continue
+			}
+			if len(str) > 0 {
+				arr = append(arr, pdfcore.MakeString(string(str)))
+				str = []byte{}
+			}
+			adjust += adj
+			if adj < -100 && len(run) > 0 {
+				// Large adjustments are typically used as word spaces.
+				run = append(run, ' ')
+			}
+		}
+		if len(str) > 0 {
+			arr = append(arr, pdfcore.MakeString(string(str)))
+		}
+		// The trailing adjustment keeps the position of the text that follows.
+		if adjust != 0 {
+			arr = append(arr, pdfcore.MakeFloat(adjust))
+		}
+		if len(run) > 0 {
+			runs = append(runs, strings.TrimSpace(string(run)))
+		}
+
+		newOperations = append(newOperations, &pdfcontent.ContentStreamOperation{Operand: "TJ",
+			Params: []pdfcore.PdfObject{&arr}})
+	}
+	return newOperations, runs
+}
+
+// Extracts the text inside the black boxes of the output again, and checks that the search text and the removed text
+// runs are gone from it.  The text elsewhere on the pages is not checked: the same text may occur outside the
+// redaction areas, and unlicensed copies of unidoc add a watermark to each page.
+func verifyRedaction(outputPath, search string, boxes map[int][]pdf.PdfRectangle, removed map[int][]string) error {
+	f, err := os.Open(outputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	problems := 0
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		if len(boxes[pageNum]) == 0 {
+			continue
+		}
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return err
+		}
+		layout := newTextLayout()
+		err = layout.process(contents, page.Resources, identityMatrix(), 0)
+		if err != nil {
+			return err
+		}
+		text := normalizeText(textInBoxes(layout.glyphs, boxes[pageNum]))
+
+		if len(search) > 0 && strings.Contains(text, normalizeText(search)) {
+			fmt.Printf("Warning: page %d: %q can still be extracted\n", pageNum, search)
+			problems++
+		}
+		for _, run := range removed[pageNum] {
+			run = normalizeText(run)
+			if len(run) == 0 || strings.ContainsRune(run, unicode.ReplacementChar) {
+				continue
+			}
+			if strings.Contains(text, run) {
+				fmt.Printf("Warning: page %d: %q can still be extracted\n", pageNum, run)
+				problems++
+			}
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("Verification failed: %d redacted texts found in the output", problems)
+	}
+	fmt.Printf("Verified: the redacted text is not in the text extracted from the redaction areas of the output\n")
+	return nil
+}
+
+// Returns the text of the glyphs of the page content with their center inside one of the boxes, with spaces for the
+// gaps between words.  The text of form XObjects, which is covered but not removed, is left out.
+func textInBoxes(glyphs []glyph, boxes []pdf.PdfRectangle) string {
+	text := []rune{}
+	var last *glyph
+	for i := range glyphs {
+		g := &glyphs[i]
+		x, y := (g.box.Llx+g.box.Urx)/2, (g.box.Lly+g.box.Ury)/2
+		inside := false
+		for _, box := range boxes {
+			inside = inside || (x > box.Llx && x < box.Urx && y > box.Lly && y < box.Ury)
+		}
+		if !inside || g.op < 0 {
+			continue
+		}
+		if last != nil && (!sameLine(*last, *g) || isGap(*last, *g)) {
+			text = append(text, ' ')
+		}
+		text = append(text, g.r)
+		last = g
+	}
+	return string(text)
+}
+
+// Lowercases the text and replaces runs of white space with a single space.
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// Returns true if the rectangles overlap by more than a negligible amount (glyphs that only touch a redaction area
+// are not removed).
+func overlaps(a, b pdf.PdfRectangle) bool {
+	return math.Min(a.Urx, b.Urx)-math.Max(a.Llx, b.Llx) > 0.01 && math.Min(a.Ury, b.Ury)-math.Max(a.Lly, b.Lly) > 0.01
+}
+
+// Finds the occurrences of the search text (ignoring case) and returns the areas to redact, one per line of each
+// occurrence.  A gap between consecutive glyphs, or a change of line, counts as a space.
+func (layout *textLayout) find(search string) []pdf.PdfRectangle {
+	query := []rune(normalizeText(search))
+
+	text := []rune{}
+	glyphs := []*glyph{} // The glyph of each rune, nil for spaces inserted for gaps.
+	for i := range layout.glyphs {
+		g := &layout.glyphs[i]
+		endsWithSpace := len(text) > 0 && text[len(text)-1] == ' '
+		if i > 0 && !endsWithSpace {
+			prev := layout.glyphs[i-1]
+			if !sameLine(prev, *g) || isGap(prev, *g) {
+				text = append(text, ' ')
+				glyphs = append(glyphs, nil)
+				endsWithSpace = true
+			}
+		}
+
+		r := unicode.ToLower(g.r)
+		if unicode.IsSpace(r) {
+			if endsWithSpace {
+				continue
+			}
+			r = ' '
+		}
+		text = append(text, r)
+		glyphs = append(glyphs, g)
+	}
+
+	rects := []pdf.PdfRectangle{}
+	for i := 0; len(query) > 0 && i+len(query) <= len(text); i++ {
+		if string(text[i:i+len(query)]) != string(query) {
+			continue
+		}
+
+		// One rectangle per line of the match.
+		var lineGlyph *glyph
+		for _, g := range glyphs[i : i+len(query)] {
+			if g == nil {
+				continue
+			}
+			if lineGlyph != nil && sameLine(*lineGlyph, *g) {
+				rects[len(rects)-1] = union(rects[len(rects)-1], g.box)
+			} else {
+				rects = append(rects, g.box)
+			}
+			lineGlyph = g
+		}
+		i += len(query) - 1
+	}
+	return rects
+}
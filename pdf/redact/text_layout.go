@@ -0,0 +1,603 @@
+/*
+ * Glyph positioning of redact.go, which works with the positions of the text on the page, and is run together
+ * with this file:
+ *   go run redact.go text_layout.go ...
+ *
+ * This is a copy of pdf/qa/text_layout.go (see there for how the glyph positions are computed).  Changes are made
+ * there and copied here, and pdf/testing/check_copies.go checks that the copies are the same.
+ */
+
+package main
+
+import (
+	"errors"
+	"math"
+	"unicode"
+
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+// Maximum nesting depth of form XObjects.
+const maxFormDepth = 10
+
+// matrix is a PDF transformation matrix [a b c d e f].
+type matrix [6]float64
+
+func identityMatrix() matrix {
+	return matrix{1, 0, 0, 1, 0, 0}
+}
+
+// mult returns m x n, i.e. the transformation m followed by n.
+func (m matrix) mult(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func (m matrix) transform(x, y float64) (float64, float64) {
+	return x*m[0] + y*m[2] + m[4], x*m[1] + y*m[3] + m[5]
+}
+
+// Returns the bounding box of the rectangle transformed by m.
+func (m matrix) transformRect(llx, lly, urx, ury float64) pdf.PdfRectangle {
+	box := pdf.PdfRectangle{Llx: math.Inf(1), Lly: math.Inf(1), Urx: math.Inf(-1), Ury: math.Inf(-1)}
+	for _, corner := range [][2]float64{{llx, lly}, {urx, lly}, {llx, ury}, {urx, ury}} {
+		x, y := m.transform(corner[0], corner[1])
+		box.Llx = math.Min(box.Llx, x)
+		box.Lly = math.Min(box.Lly, y)
+		box.Urx = math.Max(box.Urx, x)
+		box.Ury = math.Max(box.Ury, y)
+	}
+	return box
+}
+
+func union(a, b pdf.PdfRectangle) pdf.PdfRectangle {
+	return pdf.PdfRectangle{
+		Llx: math.Min(a.Llx, b.Llx),
+		Lly: math.Min(a.Lly, b.Lly),
+		Urx: math.Max(a.Urx, b.Urx),
+		Ury: math.Max(a.Ury, b.Ury),
+	}
+}
+
+// Text related graphics state, with the matrices of the current text object.
+type textState struct {
+	ctm        matrix
+	font       *textFont
+	fontSize   float64
+	charSp     float64
+	wordSp     float64
+	hScale     float64
+	rise       float64
+	leading    float64
+	renderMode int
+
+	tm  matrix // Text matrix.
+	tlm matrix // Text line matrix.
+}
+
+func newTextState(ctm matrix) textState {
+	return textState{ctm: ctm, hScale: 1, tm: identityMatrix(), tlm: identityMatrix()}
+}
+
+// shownGlyph is a glyph shown by a text showing operator.
+type shownGlyph struct {
+	code    int
+	bytes   []byte // The bytes of the code in the string.
+	font    *textFont
+	trm     matrix  // Glyph space (1/1000 em) to the space of the CTM.
+	width   float64 // Glyph space units.
+	advance float64 // Displacement to the next glyph in thousandths of the font size, as in TJ adjustments.
+}
+
+// Applies the text operator op: BT, the text state, text positioning and text showing operators.  The fonts are
+// loaded from the resources with the cache, and the glyphs shown are passed to show.  Returns false if op is not a
+// text operator.
+func (ts *textState) apply(op *pdfcontent.ContentStreamOperation, resources *pdf.PdfPageResources, fonts fontCache,
+	show func(g shownGlyph)) bool {
+	params := make([]float64, len(op.Params))
+	for i, p := range op.Params {
+		params[i], _ = getNumberAsFloat(p)
+	}
+
+	switch op.Operand {
+	case "BT":
+		ts.tm = identityMatrix()
+		ts.tlm = identityMatrix()
+	case "Tf":
+		if len(op.Params) == 2 {
+			if name, ok := op.Params[0].(*pdfcore.PdfObjectName); ok {
+				ts.font = fonts.load(resources, *name)
+			}
+			ts.fontSize = params[1]
+		}
+	case "Tc":
+		if len(params) == 1 {
+			ts.charSp = params[0]
+		}
+	case "Tw":
+		if len(params) == 1 {
+			ts.wordSp = params[0]
+		}
+	case "Tz":
+		if len(params) == 1 {
+			ts.hScale = params[0] / 100
+		}
+	case "Ts":
+		if len(params) == 1 {
+			ts.rise = params[0]
+		}
+	case "TL":
+		if len(params) == 1 {
+			ts.leading = params[0]
+		}
+	case "Tr":
+		if len(params) == 1 {
+			ts.renderMode = int(params[0])
+		}
+	case "Td", "TD":
+		if len(params) == 2 {
+			ts.tlm = matrix{1, 0, 0, 1, params[0], params[1]}.mult(ts.tlm)
+			ts.tm = ts.tlm
+			if op.Operand == "TD" {
+				ts.leading = -params[1]
+			}
+		}
+	case "Tm":
+		if len(params) == 6 {
+			ts.tlm = matrix{params[0], params[1], params[2], params[3], params[4], params[5]}
+			ts.tm = ts.tlm
+		}
+	case "T*":
+		ts.tlm = matrix{1, 0, 0, 1, 0, -ts.leading}.mult(ts.tlm)
+		ts.tm = ts.tlm
+	case "Tj", "'", "\"":
+		if op.Operand != "Tj" {
+			if op.Operand == "\"" && len(params) == 3 {
+				ts.wordSp = params[0]
+				ts.charSp = params[1]
+			}
+			ts.tlm = matrix{1, 0, 0, 1, 0, -ts.leading}.mult(ts.tlm)
+			ts.tm = ts.tlm
+		}
+		if len(op.Params) == 0 {
+			break
+		}
+		if str, ok := op.Params[len(op.Params)-1].(*pdfcore.PdfObjectString); ok {
+			ts.showText(string(*str), show)
+		}
+	case "TJ":
+		if len(op.Params) != 1 {
+			break
+		}
+		arr, ok := op.Params[0].(*pdfcore.PdfObjectArray)
+		if !ok {
+			break
+		}
+		for _, obj := range *arr {
+			if str, ok := obj.(*pdfcore.PdfObjectString); ok {
+				ts.showText(string(*str), show)
+			} else if adj, err := getNumberAsFloat(obj); err == nil {
+				ts.tm = matrix{1, 0, 0, 1, -adj / 1000 * ts.fontSize * ts.hScale, 0}.mult(ts.tm)
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// Passes the glyphs of the string to show, advancing the text matrix past each glyph.
+func (ts *textState) showText(str string, show func(g shownGlyph)) {
+	font := ts.font
+	if font == nil {
+		font = &textFont{defaultWidth: 500, ascent: 750, descent: -250}
+	}
+	fs := ts.fontSize
+
+	for i := 0; i < len(str); i++ {
+		g := shownGlyph{code: int(str[i]), bytes: []byte{str[i]}, font: font}
+		if font.twoByte && i+1 < len(str) {
+			g.code = g.code<<8 | int(str[i+1])
+			g.bytes = append(g.bytes, str[i+1])
+			i++
+		}
+
+		g.width = font.width(g.code)
+		tx := g.width/1000*fs + ts.charSp
+		if !font.twoByte && g.code == 32 {
+			tx += ts.wordSp
+		}
+		if fs != 0 {
+			g.advance = tx * 1000 / fs
+		}
+		g.trm = matrix{fs * ts.hScale / 1000, 0, 0, fs / 1000, 0, ts.rise}.mult(ts.tm).mult(ts.ctm)
+		show(g)
+
+		ts.tm = matrix{1, 0, 0, 1, tx * ts.hScale, 0}.mult(ts.tm)
+	}
+}
+
+// glyph is a shown glyph with its position.
+type glyph struct {
+	r       rune             // Unicode rune, unicode.ReplacementChar if unknown.
+	box     pdf.PdfRectangle // Glyph box in page coordinates.
+	rotated bool             // The baseline is not horizontal, left to right.
+	code    []byte           // Character code, as in the string.
+	advance float64          // Displacement to the next glyph in thousandths of the font size.
+	op      int              // Index of the text showing operation in the page content, -1 in form XObjects.
+}
+
+// xobjectArea is the area covered by a form or image XObject on the page.
+type xobjectArea struct {
+	name string
+	box  pdf.PdfRectangle
+}
+
+// textLayout collects the glyphs shown by the content streams of a page, in content stream order.
+type textLayout struct {
+	glyphs   []glyph
+	xobjects []xobjectArea // The XObjects drawn by the page content (not by forms).
+	fonts    fontCache
+}
+
+func newTextLayout() *textLayout {
+	return &textLayout{fonts: fontCache{}}
+}
+
+// Processes the content stream with the given resources and initial transformation matrix.
+func (layout *textLayout) process(contents string, resources *pdf.PdfPageResources, ctm matrix, depth int) error {
+	operations, err := pdfcontent.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return err
+	}
+	return layout.processOperations(*operations, resources, ctm, depth)
+}
+
+// Processes the parsed operations of a content stream with the given resources and initial transformation matrix.
+func (layout *textLayout) processOperations(operations pdfcontent.ContentStreamOperations,
+	resources *pdf.PdfPageResources, ctm matrix, depth int) error {
+	gs := newTextState(ctm)
+	stack := []textState{}
+
+	for opIndex, op := range operations {
+		switch op.Operand {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			vals := make([]float64, len(op.Params))
+			for i, p := range op.Params {
+				vals[i], _ = getNumberAsFloat(p)
+			}
+			if len(vals) == 6 {
+				gs.ctm = matrix{vals[0], vals[1], vals[2], vals[3], vals[4], vals[5]}.mult(gs.ctm)
+			}
+		case "Do":
+			if len(op.Params) != 1 || resources == nil {
+				continue
+			}
+			name, ok := op.Params[0].(*pdfcore.PdfObjectName)
+			if !ok {
+				continue
+			}
+			stream, xtype := resources.GetXObjectByName(*name)
+			if xtype == pdf.XObjectTypeImage && depth == 0 {
+				layout.xobjects = append(layout.xobjects, xobjectArea{string(*name), gs.ctm.transformRect(0, 0, 1, 1)})
+			}
+			if xtype != pdf.XObjectTypeForm || depth >= maxFormDepth {
+				continue
+			}
+			xform, err := pdf.NewXObjectFormFromStream(stream)
+			if err != nil {
+				return err
+			}
+			formContents, err := xform.GetContentStream()
+			if err != nil {
+				return err
+			}
+			formCtm := gs.ctm
+			if arr, ok := pdfcore.TraceToDirectObject(xform.Matrix).(*pdfcore.PdfObjectArray); ok {
+				vals, err := arr.ToFloat64Array()
+				if err == nil && len(vals) == 6 {
+					formCtm = matrix{vals[0], vals[1], vals[2], vals[3], vals[4], vals[5]}.mult(gs.ctm)
+				}
+			}
+			if bbox, ok := pdfcore.TraceToDirectObject(xform.BBox).(*pdfcore.PdfObjectArray); ok && depth == 0 {
+				if r, err := pdf.NewPdfRectangle(*bbox); err == nil {
+					layout.xobjects = append(layout.xobjects,
+						xobjectArea{string(*name), formCtm.transformRect(r.Llx, r.Lly, r.Urx, r.Ury)})
+				}
+			}
+			formResources := xform.Resources
+			if formResources == nil {
+				formResources = resources
+			}
+			err = layout.process(string(formContents), formResources, formCtm, depth+1)
+			if err != nil {
+				return err
+			}
+		default:
+			index := opIndex
+			if depth > 0 {
+				index = -1
+			}
+			gs.apply(op, resources, layout.fonts, func(g shownGlyph) {
+				layout.addGlyph(g, index)
+			})
+		}
+	}
+
+	return nil
+}
+
+// Adds the shown glyph, with its box from the descent to the ascent.
+func (layout *textLayout) addGlyph(g shownGlyph, opIndex int) {
+	layout.glyphs = append(layout.glyphs, glyph{
+		r:       g.font.rune(g.code),
+		box:     g.trm.transformRect(0, g.font.descent, g.width, g.font.ascent),
+		rotated: g.trm[0] <= 0 || math.Abs(g.trm[1]) > 0.01*g.trm[0],
+		code:    g.bytes,
+		advance: g.advance,
+		op:      opIndex,
+	})
+}
+
+// Returns true if the glyphs are on the same line: their boxes overlap vertically by at least half their height.
+func sameLine(a, b glyph) bool {
+	overlap := math.Min(a.box.Ury, b.box.Ury) - math.Max(a.box.Lly, b.box.Lly)
+	return overlap > 0.5*math.Min(a.box.Ury-a.box.Lly, b.box.Ury-b.box.Lly)
+}
+
+// Returns true if there is a word gap between the glyphs on a line, or if the second glyph is before the first
+// (e.g. text drawn out of order).
+func isGap(a, b glyph) bool {
+	gap := b.box.Llx - a.box.Urx
+	return gap > 0.15*(a.box.Ury-a.box.Lly) || gap < -0.5*(a.box.Ury-a.box.Lly)
+}
+
+// textFont holds the metrics and encoding of a PDF font.
+type textFont struct {
+	dict       *pdfcore.PdfObjectDictionary // Font dictionary, nil if invalid.
+	descriptor *pdfcore.PdfObjectDictionary // Font descriptor, of the descendant font for composite fonts.
+	baseFont   string
+	twoByte    bool
+
+	firstChar    int
+	widths       []float64
+	cidWidths    map[int]float64 // Widths of composite fonts by CID.
+	defaultWidth float64
+	ascent       float64 // Glyph space units (1/1000 em).
+	descent      float64
+
+	std         fonts.Font // Metrics of standard 14 fonts.
+	encoder     textencoding.TextEncoder
+	differences map[int]string // Encoding differences: code to glyph name.
+}
+
+// fontCache holds the fonts loaded by font object.
+type fontCache map[pdfcore.PdfObject]*textFont
+
+// Loads the font with the given resource name.
+func (cache fontCache) load(resources *pdf.PdfPageResources, name pdfcore.PdfObjectName) *textFont {
+	if resources == nil {
+		return nil
+	}
+	obj, found := resources.GetFontByName(name)
+	if !found {
+		return nil
+	}
+	if font, has := cache[obj]; has {
+		return font
+	}
+
+	font := &textFont{defaultWidth: 500, ascent: 750, descent: -250, encoder: textencoding.NewWinAnsiTextEncoder()}
+	cache[obj] = font
+
+	dict, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return font
+	}
+	font.dict = dict
+
+	if bf, ok := pdfcore.TraceToDirectObject(dict.Get("BaseFont")).(*pdfcore.PdfObjectName); ok {
+		font.baseFont = string(*bf)
+	}
+
+	descriptorDict := dict
+	if subtype, ok := dict.Get("Subtype").(*pdfcore.PdfObjectName); ok && *subtype == "Type0" {
+		// Composite font: 2 byte codes with Identity encoding assumed.
+		font.twoByte = true
+		font.defaultWidth = 1000
+		if arr, ok := pdfcore.TraceToDirectObject(dict.Get("DescendantFonts")).(*pdfcore.PdfObjectArray); ok &&
+			len(*arr) > 0 {
+			if desc, ok := pdfcore.TraceToDirectObject((*arr)[0]).(*pdfcore.PdfObjectDictionary); ok {
+				descriptorDict = desc
+				if dw, err := getNumberAsFloat(pdfcore.TraceToDirectObject(desc.Get("DW"))); err == nil {
+					font.defaultWidth = dw
+				}
+				font.cidWidths = loadCIDWidths(desc)
+			}
+		}
+	} else {
+		if fc, err := getNumberAsFloat(pdfcore.TraceToDirectObject(dict.Get("FirstChar"))); err == nil {
+			font.firstChar = int(fc)
+		}
+		if arr, ok := pdfcore.TraceToDirectObject(dict.Get("Widths")).(*pdfcore.PdfObjectArray); ok {
+			for _, obj := range *arr {
+				w, _ := getNumberAsFloat(pdfcore.TraceToDirectObject(obj))
+				font.widths = append(font.widths, w)
+			}
+		}
+		font.std = standardFont(font.baseFont)
+		font.differences = loadDifferences(dict)
+	}
+
+	if descriptor, ok := pdfcore.TraceToDirectObject(descriptorDict.Get("FontDescriptor")).(*pdfcore.PdfObjectDictionary); ok {
+		font.descriptor = descriptor
+		ascent, err := getNumberAsFloat(pdfcore.TraceToDirectObject(descriptor.Get("Ascent")))
+		if err == nil && ascent > 0 {
+			font.ascent = ascent
+		}
+		descent, err := getNumberAsFloat(pdfcore.TraceToDirectObject(descriptor.Get("Descent")))
+		if err == nil && descent < 0 {
+			font.descent = descent
+		}
+	}
+
+	return font
+}
+
+// Loads the W array of a CID font: entries "c [w1 w2 ...]" and "cfirst clast w".
+func loadCIDWidths(desc *pdfcore.PdfObjectDictionary) map[int]float64 {
+	widths := map[int]float64{}
+	arr, ok := pdfcore.TraceToDirectObject(desc.Get("W")).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return widths
+	}
+	for i := 0; i+1 < len(*arr); {
+		first, err := getNumberAsFloat(pdfcore.TraceToDirectObject((*arr)[i]))
+		if err != nil {
+			break
+		}
+		if list, ok := pdfcore.TraceToDirectObject((*arr)[i+1]).(*pdfcore.PdfObjectArray); ok {
+			for j, obj := range *list {
+				w, _ := getNumberAsFloat(pdfcore.TraceToDirectObject(obj))
+				widths[int(first)+j] = w
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(*arr) {
+			break
+		}
+		last, err1 := getNumberAsFloat(pdfcore.TraceToDirectObject((*arr)[i+1]))
+		w, err2 := getNumberAsFloat(pdfcore.TraceToDirectObject((*arr)[i+2]))
+		if err1 != nil || err2 != nil {
+			break
+		}
+		for cid := int(first); cid <= int(last); cid++ {
+			widths[cid] = w
+		}
+		i += 3
+	}
+	return widths
+}
+
+// Returns the unicode rune for a character code, using the encoding differences or WinAnsi encoding.
+func (font *textFont) rune(code int) rune {
+	if font.twoByte || font.encoder == nil {
+		return unicode.ReplacementChar
+	}
+	if glyph, has := font.differences[code]; has {
+		if r, ok := font.encoder.GlyphToRune(glyph); ok {
+			return r
+		}
+		return unicode.ReplacementChar
+	}
+	if r, ok := font.encoder.CharcodeToRune(byte(code)); ok {
+		return r
+	}
+	return unicode.ReplacementChar
+}
+
+// Returns the width of the glyph for the code in glyph space units (1/1000 em).
+func (font *textFont) width(code int) float64 {
+	if font.twoByte {
+		if w, has := font.cidWidths[code]; has {
+			return w
+		}
+		return font.defaultWidth
+	}
+	if idx := code - font.firstChar; idx >= 0 && idx < len(font.widths) {
+		return font.widths[idx]
+	}
+	if font.std != nil && code < 256 {
+		if glyph, found := font.encoder.CharcodeToGlyph(byte(code)); found {
+			if metrics, found := font.std.GetGlyphCharMetrics(glyph); found {
+				return metrics.Wx
+			}
+		}
+	}
+	return font.defaultWidth
+}
+
+// Loads the Differences array of the font encoding dictionary.
+func loadDifferences(dict *pdfcore.PdfObjectDictionary) map[int]string {
+	differences := map[int]string{}
+	encDict, ok := pdfcore.TraceToDirectObject(dict.Get("Encoding")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return differences
+	}
+	arr, ok := pdfcore.TraceToDirectObject(encDict.Get("Differences")).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return differences
+	}
+	code := 0
+	for _, obj := range *arr {
+		switch t := pdfcore.TraceToDirectObject(obj).(type) {
+		case *pdfcore.PdfObjectInteger:
+			code = int(*t)
+		case *pdfcore.PdfObjectName:
+			differences[code] = string(*t)
+			code++
+		}
+	}
+	return differences
+}
+
+// Returns the metrics of a standard 14 font by name, or nil if not a standard font.
+func standardFont(baseFont string) fonts.Font {
+	switch baseFont {
+	case "Helvetica":
+		return fonts.NewFontHelvetica()
+	case "Helvetica-Bold":
+		return fonts.NewFontHelveticaBold()
+	case "Helvetica-Oblique":
+		return fonts.NewFontHelveticaOblique()
+	case "Helvetica-BoldOblique":
+		return fonts.NewFontHelveticaBoldOblique()
+	case "Times-Roman":
+		return fonts.NewFontTimesRoman()
+	case "Times-Bold":
+		return fonts.NewFontTimesBold()
+	case "Times-Italic":
+		return fonts.NewFontTimesItalic()
+	case "Times-BoldItalic":
+		return fonts.NewFontTimesBoldItalic()
+	case "Courier":
+		return fonts.NewFontCourier()
+	case "Courier-Bold":
+		return fonts.NewFontCourierBold()
+	case "Courier-Oblique":
+		return fonts.NewFontCourierOblique()
+	case "Courier-BoldOblique":
+		return fonts.NewFontCourierBoldOblique()
+	}
+	return nil
+}
+
+func getNumberAsFloat(obj pdfcore.PdfObject) (float64, error) {
+	if fObj, ok := obj.(*pdfcore.PdfObjectFloat); ok {
+		return float64(*fObj), nil
+	}
+
+	if iObj, ok := obj.(*pdfcore.PdfObjectInteger); ok {
+		return float64(*iObj), nil
+	}
+
+	return 0, errors.New("Not a number")
+}
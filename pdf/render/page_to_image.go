@@ -0,0 +1,165 @@
+/*
+ * Render PDF pages to PNG images.
+ *
+ * This unidoc version does not have a page renderer, so the pages are rendered with the basic renderer of render.go,
+ * with the glyphs positioned by text_layout.go.  See render.go for what it supports; it is meant for previews and as
+ * a starting point.
+ *
+ * Each page is rendered at the given DPI times the scale factor, upright, i.e. taking the page /Rotate into account.
+ * The output files are named <outdir>/page_<n>.png with n being the page number.
+ *
+ * Run as: go run page_to_image.go render.go text_layout.go [-dpi 72] [-scale 1.0] [-pages 1-3,5] [-outdir .] input.pdf
+ */
+/*
+ * NOTE: This example depends on golang.org/x/image (vector, font/sfnt and the Go fonts), BSD licensed.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	goimage "image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run page_to_image.go render.go text_layout.go [-dpi 72] [-scale 1.0] [-pages 1-3,5] " +
+	"[-outdir .] input.pdf\n"
+
+func main() {
+	dpi := 0.0
+	scale := 0.0
+	pageRange := ""
+	outputDir := ""
+	flag.Float64Var(&dpi, "dpi", 72, "Resolution in DPI")
+	flag.Float64Var(&scale, "scale", 1.0, "Scale factor (applied on top of the DPI)")
+	flag.StringVar(&pageRange, "pages", "", "Pages to render, e.g. 1-3,5 (default: all)")
+	flag.StringVar(&outputDir, "outdir", ".", "Output directory")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// Enable debug-level logging.
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+
+	err := renderPages(inputPath, outputDir, pageRange, dpi*scale)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func renderPages(inputPath string, outputDir string, pageRange string, dpi float64) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	pageNums, err := parsePageRange(pageRange, numPages)
+	if err != nil {
+		return err
+	}
+
+	r := newPageRenderer()
+	for _, pageNum := range pageNums {
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+
+		img, err := r.RenderPage(page, dpi)
+		if err != nil {
+			return err
+		}
+
+		outputPath := filepath.Join(outputDir, fmt.Sprintf("page_%d.png", pageNum))
+		err = savePNG(img, outputPath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Page %d: %dx%d px -> %s\n", pageNum, img.Bounds().Dx(), img.Bounds().Dy(), outputPath)
+	}
+
+	return nil
+}
+
+func savePNG(img goimage.Image, outputPath string) error {
+	fOut, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fOut.Close()
+
+	return png.Encode(fOut, img)
+}
+
+// Parses a page range such as "1-3,5" into a list of page numbers.  An empty range means all pages.
+func parsePageRange(pageRange string, numPages int) ([]int, error) {
+	pageNums := []int{}
+	if len(pageRange) == 0 {
+		for i := 1; i <= numPages; i++ {
+			pageNums = append(pageNums, i)
+		}
+		return pageNums, nil
+	}
+
+	for _, part := range strings.Split(pageRange, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		from, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if len(bounds) == 2 {
+			to, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		if from < 1 || to > numPages || from > to {
+			return nil, fmt.Errorf("Invalid page range %s (%d pages)", part, numPages)
+		}
+		for i := from; i <= to; i++ {
+			pageNums = append(pageNums, i)
+		}
+	}
+	return pageNums, nil
+}
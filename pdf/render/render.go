@@ -0,0 +1,868 @@
+/*
+ * Basic page renderer of page_to_image.go and thumbnail_grid.go, which render PDF pages to images, and are run together
+ * with this file and text_layout.go:
+ *   go run page_to_image.go render.go text_layout.go ...
+ *   go run thumbnail_grid.go render.go text_layout.go ...
+ *
+ * This unidoc version does not have a page renderer, so the examples include this basic one, which processes the page
+ * content streams with the content stream parser and rasterizes the result with golang.org/x/image/vector:
+ * - Paths: filled and stroked (round joins).  The colors are converted to RGB by unidoc according to the current color
+ *   space, set with cs/CS or g/rg/k.  Colors in pattern spaces or in color spaces that cannot be loaded are not
+ *   painted.  Clipping paths are approximated by their bounding box.
+ * - Images: image XObjects (8 bit gray/RGB/CMYK via the model image decoding) and stencil masks.
+ * - Text: glyph outlines are taken from embedded TrueType/OpenType fonts.  Other fonts (standard 14 fonts, Type1 and
+ *   CFF fonts) are substituted with the Go fonts of similar style.  The glyphs are positioned with text_layout.go,
+ *   from the text state and the PDF font widths.
+ * - Form XObjects are rendered recursively.
+ * Not supported are shadings, patterns, transparency, blend modes, inline images and annotations.  For accurate
+ * rendering a full PDF renderer is needed; this renderer is meant for previews and as a starting point.
+ *
+ * go run takes the files of a program from a single directory, so visual_diff.go (pdf/diff) has a copy of this file.
+ * Changes are made here and copied there, and pdf/testing/check_copies.go checks that the copy is the same.
+ */
+/*
+ * NOTE: This file depends on golang.org/x/image (vector, font/sfnt and the Go fonts), BSD licensed.
+ */
+
+package main
+
+import (
+	goimage "image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/gomonobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+
+	unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+func (m matrix) inverse() (matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if math.Abs(det) < 1e-12 {
+		return matrix{}, false
+	}
+	return matrix{
+		m[3] / det,
+		-m[1] / det,
+		-m[2] / det,
+		m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}, true
+}
+
+// Scale factor of the matrix (geometric mean of the axis scales), used for line widths.
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+// Path segment in device coordinates.
+type pathSegment struct {
+	op  byte // 'm' move, 'l' line, 'c' cubic curve, 'h' close.
+	pts [3][2]float64
+}
+
+// Graphics state.
+type graphicsState struct {
+	textState // With the CTM, user space to device space.
+
+	clip        goimage.Rectangle
+	fillSpace   pdf.PdfColorspace // nil if not supported (patterns, unknown spaces).
+	strokeSpace pdf.PdfColorspace
+	fillColor   color.RGBA // Transparent, i.e. not painted, if the color space is not supported.
+	strokeColor color.RGBA
+	lineWidth   float64
+}
+
+// pageRenderer renders pages to images.
+type pageRenderer struct {
+	img      *goimage.RGBA
+	fonts    fontCache
+	outlines map[*textFont]*renderFont
+	buf      sfnt.Buffer
+}
+
+func newPageRenderer() *pageRenderer {
+	return &pageRenderer{fonts: fontCache{}, outlines: map[*textFont]*renderFont{}}
+}
+
+// RenderPage renders the page at the given resolution.  The page is rendered upright, i.e. rotated according to its
+// /Rotate entry.
+func (r *pageRenderer) RenderPage(page *pdf.PdfPage, dpi float64) (*goimage.RGBA, error) {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+
+	rotate := int64(0)
+	if page.Rotate != nil {
+		rotate = (*page.Rotate%360 + 360) % 360
+	}
+
+	// Device matrix: user space to pixels, origin in the upper left corner.
+	s := dpi / 72
+	w, h := mbox.Urx-mbox.Llx, mbox.Ury-mbox.Lly
+	var device matrix
+	switch rotate {
+	case 90:
+		device = matrix{0, s, s, 0, -mbox.Lly * s, -mbox.Llx * s}
+		w, h = h, w
+	case 180:
+		device = matrix{-s, 0, 0, s, mbox.Urx * s, -mbox.Lly * s}
+	case 270:
+		device = matrix{0, -s, -s, 0, mbox.Ury * s, mbox.Urx * s}
+		w, h = h, w
+	default:
+		device = matrix{s, 0, 0, -s, -mbox.Llx * s, mbox.Ury * s}
+	}
+
+	width := int(math.Ceil(w * s))
+	height := int(math.Ceil(h * s))
+	r.img = goimage.NewRGBA(goimage.Rect(0, 0, width, height))
+	draw.Draw(r.img, r.img.Bounds(), goimage.White, goimage.Point{}, draw.Src)
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, err
+	}
+
+	gs := graphicsState{
+		textState:   newTextState(device),
+		clip:        r.img.Bounds(),
+		fillSpace:   pdf.NewPdfColorspaceDeviceGray(),
+		strokeSpace: pdf.NewPdfColorspaceDeviceGray(),
+		fillColor:   color.RGBA{0, 0, 0, 255},
+		strokeColor: color.RGBA{0, 0, 0, 255},
+		lineWidth:   1,
+	}
+	err = r.renderContents(contents, page.Resources, gs, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.img, nil
+}
+
+// Renders a content stream with the given resources and initial graphics state.
+func (r *pageRenderer) renderContents(contents string, resources *pdf.PdfPageResources, gs graphicsState,
+	depth int) error {
+	cstreamParser := pdfcontent.NewContentStreamParser(contents)
+	operations, err := cstreamParser.Parse()
+	if err != nil {
+		return err
+	}
+
+	stack := []graphicsState{}
+	path := []pathSegment{}
+	var cx, cy, sx, sy float64 // Current point and subpath start, in user space.
+	clipPending := false
+
+	for _, op := range *operations {
+		params := make([]float64, len(op.Params))
+		for i, p := range op.Params {
+			params[i], _ = getNumberAsFloat(p)
+		}
+
+		switch op.Operand {
+		// Graphics state.
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(params) == 6 {
+				gs.ctm = matrix{params[0], params[1], params[2], params[3], params[4], params[5]}.mult(gs.ctm)
+			}
+		case "w":
+			if len(params) == 1 {
+				gs.lineWidth = params[0]
+			}
+
+		// Colors.
+		case "g", "rg", "k":
+			if c, ok := toRGBA(params); ok {
+				gs.fillSpace, gs.fillColor = deviceColorspace(len(params)), c
+			}
+		case "G", "RG", "K":
+			if c, ok := toRGBA(params); ok {
+				gs.strokeSpace, gs.strokeColor = deviceColorspace(len(params)), c
+			}
+		case "cs":
+			gs.fillSpace = lookupColorspace(op.Params, resources)
+			gs.fillColor = initialColor(gs.fillSpace)
+		case "CS":
+			gs.strokeSpace = lookupColorspace(op.Params, resources)
+			gs.strokeColor = initialColor(gs.strokeSpace)
+		case "sc", "scn":
+			if c, ok := colorspaceToRGBA(gs.fillSpace, params); ok {
+				gs.fillColor = c
+			}
+		case "SC", "SCN":
+			if c, ok := colorspaceToRGBA(gs.strokeSpace, params); ok {
+				gs.strokeColor = c
+			}
+
+		// Path construction.
+		case "m":
+			if len(params) == 2 {
+				cx, cy, sx, sy = params[0], params[1], params[0], params[1]
+				path = append(path, r.segment(gs.ctm, 'm', cx, cy))
+			}
+		case "l":
+			if len(params) == 2 {
+				cx, cy = params[0], params[1]
+				path = append(path, r.segment(gs.ctm, 'l', cx, cy))
+			}
+		case "c", "v", "y":
+			pts := params
+			if op.Operand == "v" && len(params) == 4 {
+				pts = []float64{cx, cy, params[0], params[1], params[2], params[3]}
+			} else if op.Operand == "y" && len(params) == 4 {
+				pts = []float64{params[0], params[1], params[2], params[3], params[2], params[3]}
+			}
+			if len(pts) == 6 {
+				path = append(path, r.segment(gs.ctm, 'c', pts...))
+				cx, cy = pts[4], pts[5]
+			}
+		case "h":
+			path = append(path, pathSegment{op: 'h'})
+			cx, cy = sx, sy
+		case "re":
+			if len(params) == 4 {
+				x, y, w, h := params[0], params[1], params[2], params[3]
+				path = append(path,
+					r.segment(gs.ctm, 'm', x, y),
+					r.segment(gs.ctm, 'l', x+w, y),
+					r.segment(gs.ctm, 'l', x+w, y+h),
+					r.segment(gs.ctm, 'l', x, y+h),
+					pathSegment{op: 'h'})
+				cx, cy, sx, sy = x, y, x, y
+			}
+
+		// Path painting.
+		case "f", "F", "f*":
+			r.fillPath(path, gs.fillColor, gs.clip)
+		case "S":
+			r.strokePath(path, gs.strokeColor, gs.lineWidth*gs.ctm.scale(), gs.clip)
+		case "s":
+			path = append(path, pathSegment{op: 'h'})
+			r.strokePath(path, gs.strokeColor, gs.lineWidth*gs.ctm.scale(), gs.clip)
+		case "B", "B*":
+			r.fillPath(path, gs.fillColor, gs.clip)
+			r.strokePath(path, gs.strokeColor, gs.lineWidth*gs.ctm.scale(), gs.clip)
+		case "b", "b*":
+			path = append(path, pathSegment{op: 'h'})
+			r.fillPath(path, gs.fillColor, gs.clip)
+			r.strokePath(path, gs.strokeColor, gs.lineWidth*gs.ctm.scale(), gs.clip)
+		case "W", "W*":
+			// Applied when the path is ended by the next painting operator.
+			clipPending = true
+		case "n":
+		}
+		switch op.Operand {
+		case "f", "F", "f*", "S", "s", "B", "B*", "b", "b*", "n":
+			if clipPending {
+				gs.clip = gs.clip.Intersect(pathBounds(path))
+				clipPending = false
+			}
+			path = path[:0]
+		}
+
+		switch op.Operand {
+		// XObjects.
+		case "Do":
+			if len(op.Params) != 1 || resources == nil {
+				continue
+			}
+			name, ok := op.Params[0].(*pdfcore.PdfObjectName)
+			if !ok {
+				continue
+			}
+			stream, xtype := resources.GetXObjectByName(*name)
+			switch xtype {
+			case pdf.XObjectTypeImage:
+				err := r.drawImage(stream, gs)
+				if err != nil {
+					unicommon.Log.Debug("Image %s not rendered: %v", *name, err)
+				}
+			case pdf.XObjectTypeForm:
+				if depth >= maxFormDepth {
+					continue
+				}
+				xform, err := pdf.NewXObjectFormFromStream(stream)
+				if err != nil {
+					return err
+				}
+				formContents, err := xform.GetContentStream()
+				if err != nil {
+					return err
+				}
+				formGs := gs
+				if arr, ok := pdfcore.TraceToDirectObject(xform.Matrix).(*pdfcore.PdfObjectArray); ok {
+					vals, err := arr.ToFloat64Array()
+					if err == nil && len(vals) == 6 {
+						formGs.ctm = matrix{vals[0], vals[1], vals[2], vals[3], vals[4], vals[5]}.mult(gs.ctm)
+					}
+				}
+				formResources := xform.Resources
+				if formResources == nil {
+					formResources = resources
+				}
+				err = r.renderContents(string(formContents), formResources, formGs, depth+1)
+				if err != nil {
+					return err
+				}
+			}
+
+		// Text.
+		default:
+			gs.apply(op, resources, r.fonts, func(g shownGlyph) {
+				// Invisible text: render mode 3, and 7 which only clips.
+				if gs.renderMode != 3 && gs.renderMode != 7 {
+					r.drawGlyph(r.loadOutlines(g.font), g.code, g.trm, gs.fillColor, gs.clip)
+				}
+			})
+		}
+	}
+
+	return nil
+}
+
+// Creates a path segment with the points (x1 y1 x2 y2 ...) transformed to device space.
+func (r *pageRenderer) segment(ctm matrix, op byte, coords ...float64) pathSegment {
+	seg := pathSegment{op: op}
+	for i := 0; i+1 < len(coords) && i < 6; i += 2 {
+		seg.pts[i/2][0], seg.pts[i/2][1] = ctm.transform(coords[i], coords[i+1])
+	}
+	return seg
+}
+
+// Returns the device space bounding box of the path.
+func pathBounds(path []pathSegment) goimage.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, seg := range path {
+		n := 1
+		if seg.op == 'c' {
+			n = 3
+		} else if seg.op == 'h' {
+			n = 0
+		}
+		for i := 0; i < n; i++ {
+			minX = math.Min(minX, seg.pts[i][0])
+			minY = math.Min(minY, seg.pts[i][1])
+			maxX = math.Max(maxX, seg.pts[i][0])
+			maxY = math.Max(maxY, seg.pts[i][1])
+		}
+	}
+	if minX > maxX {
+		return goimage.Rectangle{}
+	}
+	return goimage.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
+
+// Fills the path with the color (nonzero winding rule), limited to the clip rectangle.
+func (r *pageRenderer) fillPath(path []pathSegment, c color.RGBA, clip goimage.Rectangle) {
+	bounds := pathBounds(path).Intersect(clip)
+	if bounds.Empty() {
+		return
+	}
+
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	pt := func(i int, seg pathSegment) (float32, float32) {
+		return float32(seg.pts[i][0] - ox), float32(seg.pts[i][1] - oy)
+	}
+	open := false
+	for _, seg := range path {
+		switch seg.op {
+		case 'm':
+			if open {
+				z.ClosePath()
+			}
+			z.MoveTo(pt(0, seg))
+			open = true
+		case 'l':
+			z.LineTo(pt(0, seg))
+		case 'c':
+			x1, y1 := pt(0, seg)
+			x2, y2 := pt(1, seg)
+			x3, y3 := pt(2, seg)
+			z.CubeTo(x1, y1, x2, y2, x3, y3)
+		case 'h':
+			if open {
+				z.ClosePath()
+				open = false
+			}
+		}
+	}
+	if open {
+		z.ClosePath()
+	}
+
+	z.Draw(r.img, bounds, goimage.NewUniform(c), goimage.Point{})
+}
+
+// Strokes the path with the color and line width (in pixels).  Segments are drawn as quadrilaterals with round
+// joins and caps.
+func (r *pageRenderer) strokePath(path []pathSegment, c color.RGBA, width float64, clip goimage.Rectangle) {
+	if width < 1 {
+		// Thinnest line that can be rendered.
+		width = 1
+	}
+	hw := width / 2
+
+	// Flatten to polylines.
+	polylines := [][][2]float64{}
+	var cur [][2]float64
+	for _, seg := range path {
+		switch seg.op {
+		case 'm':
+			if len(cur) > 0 {
+				polylines = append(polylines, cur)
+			}
+			cur = [][2]float64{seg.pts[0]}
+		case 'l':
+			cur = append(cur, seg.pts[0])
+		case 'c':
+			if len(cur) == 0 {
+				continue
+			}
+			p0 := cur[len(cur)-1]
+			for i := 1; i <= 16; i++ {
+				t := float64(i) / 16
+				mt := 1 - t
+				var p [2]float64
+				for k := 0; k < 2; k++ {
+					p[k] = mt*mt*mt*p0[k] + 3*mt*mt*t*seg.pts[0][k] + 3*mt*t*t*seg.pts[1][k] + t*t*t*seg.pts[2][k]
+				}
+				cur = append(cur, p)
+			}
+		case 'h':
+			if len(cur) > 0 {
+				start := cur[0]
+				cur = append(cur, start)
+				polylines = append(polylines, cur)
+				cur = [][2]float64{start}
+			}
+		}
+	}
+	if len(cur) > 1 {
+		polylines = append(polylines, cur)
+	}
+
+	// Outline path: quadrilaterals for segments and polygons approximating circles at the vertices.  All polygons
+	// are given the same orientation, so overlaps add up rather than cancel out.
+	outline := []pathSegment{}
+	addPolygon := func(pts [][2]float64) {
+		area := 0.0
+		for i := range pts {
+			j := (i + 1) % len(pts)
+			area += pts[i][0]*pts[j][1] - pts[j][0]*pts[i][1]
+		}
+		if area < 0 {
+			for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+				pts[i], pts[j] = pts[j], pts[i]
+			}
+		}
+		for i, p := range pts {
+			op := byte('l')
+			if i == 0 {
+				op = 'm'
+			}
+			outline = append(outline, pathSegment{op: op, pts: [3][2]float64{p}})
+		}
+		outline = append(outline, pathSegment{op: 'h'})
+	}
+
+	for _, pl := range polylines {
+		for i, p := range pl {
+			if hw > 0.75 {
+				circle := [][2]float64{}
+				for k := 0; k < 12; k++ {
+					a := float64(k) * math.Pi / 6
+					circle = append(circle, [2]float64{p[0] + hw*math.Cos(a), p[1] + hw*math.Sin(a)})
+				}
+				addPolygon(circle)
+			}
+			if i == 0 {
+				continue
+			}
+			q := pl[i-1]
+			dx, dy := p[0]-q[0], p[1]-q[1]
+			l := math.Hypot(dx, dy)
+			if l == 0 {
+				continue
+			}
+			nx, ny := -dy/l*hw, dx/l*hw
+			addPolygon([][2]float64{
+				{q[0] + nx, q[1] + ny},
+				{p[0] + nx, p[1] + ny},
+				{p[0] - nx, p[1] - ny},
+				{q[0] - nx, q[1] - ny},
+			})
+		}
+	}
+
+	r.fillPath(outline, c, clip)
+}
+
+// Draws an image XObject on the unit square transformed by the CTM.
+func (r *pageRenderer) drawImage(stream *pdfcore.PdfObjectStream, gs graphicsState) error {
+	ximg, err := pdf.NewXObjectImageFromStream(stream)
+	if err != nil {
+		return err
+	}
+
+	var sample func(x, y int) (color.RGBA, bool)
+	var w, h int
+	if ximg.ImageMask != nil {
+		if isMask, ok := pdfcore.TraceToDirectObject(ximg.ImageMask).(*pdfcore.PdfObjectBool); ok && bool(*isMask) {
+			// Stencil mask: paint the fill color where the sample is 0 (or 1 with Decode [1 0]).
+			data, err := pdfcore.DecodeStream(stream)
+			if err != nil {
+				return err
+			}
+			w, h = int(*ximg.Width), int(*ximg.Height)
+			paint := byte(0)
+			if arr, ok := pdfcore.TraceToDirectObject(ximg.Decode).(*pdfcore.PdfObjectArray); ok {
+				if vals, err := arr.ToFloat64Array(); err == nil && len(vals) == 2 && vals[0] == 1 {
+					paint = 1
+				}
+			}
+			rowBytes := (w + 7) / 8
+			sample = func(x, y int) (color.RGBA, bool) {
+				idx := y*rowBytes + x/8
+				if idx >= len(data) {
+					return color.RGBA{}, false
+				}
+				bit := (data[idx] >> uint(7-x%8)) & 1
+				return gs.fillColor, bit == paint && gs.fillColor.A > 0
+			}
+		}
+	}
+	if sample == nil {
+		img, err := ximg.ToImage()
+		if err != nil {
+			return err
+		}
+		goimg, err := img.ToGoImage()
+		if err != nil {
+			return err
+		}
+		b := goimg.Bounds()
+		w, h = b.Dx(), b.Dy()
+		sample = func(x, y int) (color.RGBA, bool) {
+			c := color.RGBAModel.Convert(goimg.At(b.Min.X+x, b.Min.Y+y)).(color.RGBA)
+			c.A = 255
+			return c, true
+		}
+	}
+	if w == 0 || h == 0 {
+		return nil
+	}
+
+	inv, ok := gs.ctm.inverse()
+	if !ok {
+		return nil
+	}
+
+	// Device bounding box of the unit square.
+	square := []pathSegment{
+		r.segment(gs.ctm, 'm', 0, 0), r.segment(gs.ctm, 'l', 1, 0),
+		r.segment(gs.ctm, 'l', 1, 1), r.segment(gs.ctm, 'l', 0, 1),
+	}
+	bounds := pathBounds(square).Intersect(gs.clip)
+
+	// Map each device pixel back to the image (the top row of the image is at v=1).
+	for py := bounds.Min.Y; py < bounds.Max.Y; py++ {
+		for px := bounds.Min.X; px < bounds.Max.X; px++ {
+			u, v := inv.transform(float64(px)+0.5, float64(py)+0.5)
+			if u < 0 || u >= 1 || v <= 0 || v > 1 {
+				continue
+			}
+			ix := int(u * float64(w))
+			iy := int((1 - v) * float64(h))
+			if c, paint := sample(ix, iy); paint {
+				r.img.SetRGBA(px, py, c)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Draws the glyph outline for the character code.
+func (r *pageRenderer) drawGlyph(font *renderFont, code int, trm matrix, c color.RGBA, clip goimage.Rectangle) {
+	if font == nil {
+		return
+	}
+	gid, ok := font.glyphIndex(code, &r.buf)
+	if !ok {
+		return
+	}
+
+	// Load the glyph with 1000 units per em, i.e. in glyph space units.
+	segments, err := font.sfnt.LoadGlyph(&r.buf, gid, fixed.I(1000), nil)
+	if err != nil {
+		return
+	}
+
+	path := []pathSegment{}
+	for _, seg := range segments {
+		coords := []float64{}
+		for _, p := range seg.Args {
+			// The sfnt Y axis points down.
+			coords = append(coords, float64(p.X)/64, -float64(p.Y)/64)
+		}
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			path = append(path, r.segment(trm, 'm', coords[0], coords[1]))
+		case sfnt.SegmentOpLineTo:
+			path = append(path, r.segment(trm, 'l', coords[0], coords[1]))
+		case sfnt.SegmentOpQuadTo:
+			// Convert the quadratic curve to a cubic one.
+			last := path[len(path)-1].pts[0]
+			if path[len(path)-1].op == 'c' {
+				last = path[len(path)-1].pts[2]
+			}
+			x0, y0 := inverseTransform(trm, last)
+			cx1, cy1 := x0+2.0/3*(coords[0]-x0), y0+2.0/3*(coords[1]-y0)
+			cx2, cy2 := coords[2]+2.0/3*(coords[0]-coords[2]), coords[3]+2.0/3*(coords[1]-coords[3])
+			path = append(path, r.segment(trm, 'c', cx1, cy1, cx2, cy2, coords[2], coords[3]))
+		case sfnt.SegmentOpCubeTo:
+			path = append(path, r.segment(trm, 'c', coords...))
+		}
+	}
+
+	r.fillPath(path, c, clip)
+}
+
+// Maps a device space point back with the inverse of m.
+func inverseTransform(m matrix, p [2]float64) (float64, float64) {
+	inv, ok := m.inverse()
+	if !ok {
+		return 0, 0
+	}
+	return inv.transform(p[0], p[1])
+}
+
+// renderFont holds the glyph outlines of a PDF font.
+type renderFont struct {
+	*textFont
+	sfnt     *sfnt.Font
+	embedded bool
+}
+
+// Loads the glyph outlines of the font: the embedded font program, or a substitute Go font.  Returns nil for text
+// without a valid font.
+func (r *pageRenderer) loadOutlines(font *textFont) *renderFont {
+	if font.dict == nil {
+		return nil
+	}
+	if outlines, has := r.outlines[font]; has {
+		return outlines
+	}
+
+	outlines := &renderFont{textFont: font}
+	r.outlines[font] = outlines
+
+	// Embedded font program.
+	if font.descriptor != nil {
+		for _, key := range []pdfcore.PdfObjectName{"FontFile2", "FontFile3"} {
+			stream, ok := pdfcore.TraceToDirectObject(font.descriptor.Get(key)).(*pdfcore.PdfObjectStream)
+			if !ok {
+				continue
+			}
+			data, err := pdfcore.DecodeStream(stream)
+			if err != nil {
+				continue
+			}
+			f, err := sfnt.Parse(data)
+			if err != nil {
+				// E.g. bare CFF or Type1 font programs are not supported by sfnt.
+				unicommon.Log.Debug("Font %s: %v", font.baseFont, err)
+				continue
+			}
+			outlines.sfnt = f
+			outlines.embedded = true
+		}
+	}
+
+	if outlines.sfnt == nil {
+		outlines.sfnt = substituteFont(font.baseFont)
+	}
+
+	return outlines
+}
+
+// Returns the glyph index for the character code.
+func (font *renderFont) glyphIndex(code int, buf *sfnt.Buffer) (sfnt.GlyphIndex, bool) {
+	if font.twoByte {
+		if font.embedded {
+			// Identity CIDToGIDMap: the CID is the glyph index.
+			return sfnt.GlyphIndex(code), true
+		}
+		gid, err := font.sfnt.GlyphIndex(buf, rune(code))
+		return gid, err == nil && gid != 0
+	}
+
+	if r := font.rune(code); r != unicode.ReplacementChar {
+		if gid, err := font.sfnt.GlyphIndex(buf, r); err == nil && gid != 0 {
+			return gid, true
+		}
+	}
+	if font.embedded {
+		// Symbolic TrueType fonts map the codes directly, or in the 0xF000 range.
+		for _, r := range []rune{rune(code), rune(0xF000 + code)} {
+			if gid, err := font.sfnt.GlyphIndex(buf, r); err == nil && gid != 0 {
+				return gid, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Returns a Go font with a style similar to the named font, to substitute fonts that are not embedded.
+func substituteFont(baseFont string) *sfnt.Font {
+	name := strings.ToLower(baseFont)
+	bold := strings.Contains(name, "bold")
+	italic := strings.Contains(name, "italic") || strings.Contains(name, "oblique")
+
+	data := goregular.TTF
+	switch {
+	case strings.Contains(name, "courier") || strings.Contains(name, "mono"):
+		data = gomono.TTF
+		if bold {
+			data = gomonobold.TTF
+		}
+	case bold && italic:
+		data = gobolditalic.TTF
+	case bold:
+		data = gobold.TTF
+	case italic:
+		data = goitalic.TTF
+	}
+
+	f, err := sfnt.Parse(data)
+	if err != nil {
+		// The Go fonts are known to be valid.
+		panic(err)
+	}
+	return f
+}
+
+// Returns the color space set by the operand of cs or CS, a device space or a space of the resources, or nil if it
+// is a pattern space or cannot be loaded.
+func lookupColorspace(operands []pdfcore.PdfObject, resources *pdf.PdfPageResources) pdf.PdfColorspace {
+	if len(operands) != 1 {
+		return nil
+	}
+	name, ok := operands[0].(*pdfcore.PdfObjectName)
+	if !ok {
+		return nil
+	}
+
+	var space pdf.PdfColorspace
+	switch *name {
+	case "DeviceGray", "DeviceRGB", "DeviceCMYK":
+		space, _ = pdf.NewPdfColorspaceFromPdfObject(name)
+	default:
+		if resources != nil {
+			space, _ = resources.GetColorspaceByName(*name)
+		}
+	}
+	if _, isPattern := space.(*pdf.PdfColorspaceSpecialPattern); isPattern {
+		return nil
+	}
+	return space
+}
+
+// Returns the initial color of the color space: black, or the full tint of separation and DeviceN spaces.
+// Transparent if the space is not supported.
+func initialColor(space pdf.PdfColorspace) color.RGBA {
+	if space == nil {
+		return color.RGBA{}
+	}
+	vals := make([]float64, space.GetNumComponents())
+	switch space.(type) {
+	case *pdf.PdfColorspaceDeviceCMYK:
+		vals[3] = 1
+	case *pdf.PdfColorspaceSpecialSeparation, *pdf.PdfColorspaceDeviceN:
+		for i := range vals {
+			vals[i] = 1
+		}
+	}
+	if c, ok := colorspaceToRGBA(space, vals); ok {
+		return c
+	}
+	return color.RGBA{0, 0, 0, 255}
+}
+
+// Converts color components in the color space to RGBA.  Fails if the space is not supported or the components do
+// not match it.
+func colorspaceToRGBA(space pdf.PdfColorspace, vals []float64) (color.RGBA, bool) {
+	if space == nil || len(vals) != space.GetNumComponents() {
+		return color.RGBA{}, false
+	}
+	col, err := space.ColorFromFloats(vals)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	rgb, err := space.ColorToRGB(col)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	c, ok := rgb.(*pdf.PdfColorDeviceRGB)
+	if !ok {
+		return color.RGBA{}, false
+	}
+	return toRGBA([]float64{c.R(), c.G(), c.B()})
+}
+
+// Returns the device color space with the number of components.
+func deviceColorspace(n int) pdf.PdfColorspace {
+	switch n {
+	case 1:
+		return pdf.NewPdfColorspaceDeviceGray()
+	case 3:
+		return pdf.NewPdfColorspaceDeviceRGB()
+	}
+	return pdf.NewPdfColorspaceDeviceCMYK()
+}
+
+// Converts color components (gray, RGB or CMYK depending on the number) to RGBA.
+func toRGBA(vals []float64) (color.RGBA, bool) {
+	to8 := func(v float64) uint8 {
+		return uint8(math.Max(0, math.Min(1, v))*255 + 0.5)
+	}
+	switch len(vals) {
+	case 1:
+		g := to8(vals[0])
+		return color.RGBA{g, g, g, 255}, true
+	case 3:
+		return color.RGBA{to8(vals[0]), to8(vals[1]), to8(vals[2]), 255}, true
+	case 4:
+		k := vals[3]
+		return color.RGBA{to8((1 - vals[0]) * (1 - k)), to8((1 - vals[1]) * (1 - k)), to8((1 - vals[2]) * (1 - k)),
+			255}, true
+	}
+	return color.RGBA{}, false
+}
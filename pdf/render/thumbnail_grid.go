@@ -0,0 +1,226 @@
+/*
+ * Create a contact sheet of a PDF: thumbnails of all pages laid out in a grid (4x5 per sheet by default) with the
+ * page numbers as captions.
+ *
+ * The thumbnail size is computed from the grid dimensions and the sheet margins, and each page is scaled uniformly
+ * to fit its cell.  Rotated pages are shown upright.  When the page count is not a multiple of the grid size, the
+ * remaining cells on the last sheet are left blank.
+ *
+ * The pages are rendered to images of the thumbnail size with the basic renderer of render.go (as in
+ * page_to_image.go), with the glyphs positioned by text_layout.go, so its limitations apply.  The resolution is
+ * given relative to the thumbnail size on the sheet: the default 150 DPI is sharp enough for viewing and printing
+ * the sheets, and keeps the output small as each thumbnail is a separate image.
+ *
+ * Run as: go run thumbnail_grid.go render.go text_layout.go [-cols 4] [-rows 5] [-margin 36] [-dpi 150]
+ *                                                           input.pdf output.pdf
+ */
+/*
+ * NOTE: This example depends on golang.org/x/image (vector, font/sfnt and the Go fonts), BSD licensed.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run thumbnail_grid.go render.go text_layout.go [-cols 4] [-rows 5] [-margin 36] " +
+	"[-dpi 150] input.pdf output.pdf\n"
+
+const (
+	titleHeight   = 24.0 // Space for the sheet title at the top.
+	captionHeight = 14.0 // Space for the page number below each thumbnail.
+	cellPadding   = 6.0  // Padding around the thumbnails within the cells.
+)
+
+func main() {
+	cols := 0
+	rows := 0
+	margin := 0.0
+	dpi := 0.0
+	flag.IntVar(&cols, "cols", 4, "Number of columns per sheet")
+	flag.IntVar(&rows, "rows", 5, "Number of rows per sheet")
+	flag.Float64Var(&margin, "margin", 36, "Sheet margins (points)")
+	flag.Float64Var(&dpi, "dpi", 150, "Resolution of the thumbnail images")
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+	if cols < 1 || rows < 1 {
+		fmt.Printf("Error: the grid needs at least one row and column\n")
+		os.Exit(1)
+	}
+	if dpi < 10 || dpi > 600 {
+		fmt.Printf("Error: -dpi must be between 10 and 600\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := createThumbnailGrid(inputPath, outputPath, cols, rows, margin, dpi)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createThumbnailGrid(inputPath, outputPath string, cols, rows int, margin, dpi float64) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	c := creator.New()
+
+	// Cell size from the grid dimensions and the margins.
+	cellWidth := (c.Width() - 2*margin) / float64(cols)
+	cellHeight := (c.Height() - 2*margin - titleHeight) / float64(rows)
+	thumbWidth := cellWidth - 2*cellPadding
+	thumbHeight := cellHeight - 2*cellPadding - captionHeight
+	if thumbWidth <= 0 || thumbHeight <= 0 {
+		return errors.New("Grid too dense for the sheet size and margins")
+	}
+
+	r := newPageRenderer()
+	perSheet := cols * rows
+	numSheets := (numPages + perSheet - 1) / perSheet
+
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		cell := i % perSheet
+		if cell == 0 {
+			c.NewPage()
+
+			title := creator.NewParagraph(fmt.Sprintf("%s - sheet %d of %d", filepath.Base(inputPath),
+				i/perSheet+1, numSheets))
+			title.SetFont(fonts.NewFontHelveticaBold())
+			title.SetFontSize(11)
+			title.SetPos(margin, margin)
+			err = c.Draw(title)
+			if err != nil {
+				return err
+			}
+		}
+
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+
+		// Upper left corner of the cell.
+		x := margin + float64(cell%cols)*cellWidth
+		y := margin + titleHeight + float64(cell/cols)*cellHeight
+
+		err = drawThumbnail(c, r, page, x+cellPadding, y+cellPadding, thumbWidth, thumbHeight, dpi)
+		if err != nil {
+			return err
+		}
+
+		caption := creator.NewParagraph(fmt.Sprintf("%d", pageNum))
+		caption.SetFont(fonts.NewFontHelvetica())
+		caption.SetFontSize(8)
+		caption.SetWidth(cellWidth)
+		caption.SetTextAlignment(creator.TextAlignmentCenter)
+		caption.SetPos(x, y+cellHeight-cellPadding-captionHeight+4)
+		err = c.Draw(caption)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Renders the page at the given resolution of the thumbnail, scaled to fit in the area with upper left corner (x, y),
+// and draws it framed.  The thumbnail is centered horizontally and aligned to the bottom of the area, so it is right
+// above the caption.
+func drawThumbnail(c *creator.Creator, r *pageRenderer, page *pdf.PdfPage, x, y, width, height, dpi float64) error {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return err
+	}
+
+	rotate := int64(0)
+	if page.Rotate != nil {
+		rotate = (*page.Rotate%360 + 360) % 360
+	}
+
+	// Size of the page as displayed, i.e. after rotation.  The renderer renders the pages upright.
+	displayWidth, displayHeight := mbox.Urx-mbox.Llx, mbox.Ury-mbox.Lly
+	if rotate == 90 || rotate == 270 {
+		displayWidth, displayHeight = displayHeight, displayWidth
+	}
+
+	scale := math.Min(width/displayWidth, height/displayHeight)
+	displayWidth *= scale
+	displayHeight *= scale
+
+	img, err := r.RenderPage(page, dpi*scale)
+	if err != nil {
+		return err
+	}
+	thumbnail, err := creator.NewImageFromGoImage(img)
+	if err != nil {
+		return err
+	}
+
+	// Position of the upper left corner of the thumbnail.
+	tx := x + (width-displayWidth)/2
+	ty := y + height - displayHeight
+
+	thumbnail.SetWidth(displayWidth)
+	thumbnail.SetHeight(displayHeight)
+	thumbnail.SetPos(tx, ty)
+	err = c.Draw(thumbnail)
+	if err != nil {
+		return err
+	}
+
+	frame := creator.NewRectangle(tx, ty, displayWidth, displayHeight)
+	frame.SetBorderColor(creator.ColorRGBFrom8bit(160, 160, 160))
+	frame.SetBorderWidth(0.5)
+	return c.Draw(frame)
+}
@@ -0,0 +1,645 @@
+/*
+ * Repair a PDF file with a damaged cross-reference table and write a repaired copy.
+ *
+ * The file is first opened normally.  If that works and all the pages can be loaded, the pages are written to the
+ * output file as is.  Only when the normal parsing fails, the cross-reference is rebuilt by scanning the file for the
+ * "<num> <gen> obj" markers of the objects:
+ *  - Each object is taken from its marker up to its endobj.  Objects without an end, e.g. at the end of a truncated
+ *    file, are lost.  When an object number occurs several times (incremental updates), the last one is used.
+ *  - The objects compressed in object streams are extracted and stored as regular objects.
+ *  - The document catalog is taken from the last Root entry found in a trailer, or else from the last object with
+ *    /Type /Catalog.
+ *  - If the page tree cannot be loaded, e.g. when its root Pages object was lost, a new Pages object is made with all
+ *    the recovered /Type /Page objects, in the order of the file.  Attributes the pages inherited from the page tree,
+ *    such as the resources or the media box, are lost.
+ * The recovered objects are written to the output with a new cross-reference table and trailer.  Then the output is
+ * checked: objects that cannot be parsed, references to objects that were not recovered and pages that cannot be
+ * loaded are reported as lost.  The output is written and the losses are listed even if the check fails.
+ *
+ * Encrypted files cannot be repaired by this example.
+ *
+ * Run as: go run repair_xref.go input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run repair_xref.go input.pdf output.pdf\n"
+
+var (
+	reObjectMarker = regexp.MustCompile(`(\d+)[ \t\r\n\f\x00]+(\d+)[ \t\r\n\f\x00]+obj\b`)
+	reHeader       = regexp.MustCompile(`^%PDF-1\.\d`)
+	reRoot         = regexp.MustCompile(`/Root[ \t\r\n\f\x00]*(\d+)[ \t\r\n\f\x00]+(\d+)[ \t\r\n\f\x00]+R`)
+	reInfo         = regexp.MustCompile(`/Info[ \t\r\n\f\x00]*(\d+)[ \t\r\n\f\x00]+(\d+)[ \t\r\n\f\x00]+R`)
+	reEncrypt      = regexp.MustCompile(`/Encrypt[ \t\r\n\f\x00]*(\d+[ \t\r\n\f\x00]+\d+[ \t\r\n\f\x00]+R|<<)`)
+)
+
+// recoveredObject is an object found by scanning the file.
+type recoveredObject struct {
+	number     int64
+	generation int64
+	data       []byte // From the marker to endobj.
+	offset     int64  // Offset in the input file.
+	objectStm  int64  // Number of the object stream containing the object, 0 for regular objects.
+}
+
+// repairReport collects what was recovered and what was lost.
+type repairReport struct {
+	lost []string
+}
+
+func (r *repairReport) addLost(format string, a ...interface{}) {
+	r.lost = append(r.lost, fmt.Sprintf(format, a...))
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := os.Args[1]
+	outputPath := os.Args[2]
+
+	data, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = copyPdf(data, outputPath)
+	if err == nil {
+		fmt.Printf("The file can be read normally, no repair needed\n")
+		fmt.Printf("Complete, see output file: %s\n", outputPath)
+		return
+	}
+	fmt.Printf("Normal parsing failed: %v\n", err)
+	fmt.Printf("Rebuilding the cross-reference table\n")
+
+	report := &repairReport{}
+	err = repairPdf(data, outputPath, report)
+
+	// What was lost is also listed when the repair fails, as far as it got.
+	if len(report.lost) > 0 {
+		fmt.Printf("Lost in the repair:\n")
+		for _, lost := range report.lost {
+			fmt.Printf("  %s\n", lost)
+		}
+	} else if err == nil {
+		fmt.Printf("Nothing was lost in the repair\n")
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Opens the file normally and writes all the pages to the output file.
+func copyPdf(data []byte, outputPath string) error {
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Unable to decrypt pdf with empty pass")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Rebuilds the cross-reference table from the objects found in the file, writes the output file and checks it.
+func repairPdf(data []byte, outputPath string, report *repairReport) error {
+	if reEncrypt.Match(data) {
+		return errors.New("Repairing encrypted files is not supported")
+	}
+
+	objects, end := scanObjects(data, report)
+	if len(objects) == 0 {
+		return errors.New("No objects found")
+	}
+	if end < int64(len(data)) {
+		rest := bytes.TrimSpace(data[end:])
+		// A damaged final xref section and trailer are expected, only other content is lost.
+		if len(rest) > 0 && !bytes.HasPrefix(rest, []byte("xref")) && !bytes.HasPrefix(rest, []byte("startxref")) &&
+			!bytes.HasPrefix(rest, []byte("trailer")) {
+			report.addLost("%d bytes at the end of the file after the last complete object", len(data)-int(end))
+		}
+	}
+
+	// Extract the objects from the object streams, which requires the file to be parsed.  Regular objects have
+	// precedence, as the object streams are not ordered with them.
+	rebuilt, err := rebuildFile(data, objects, 0, 0)
+	if err != nil {
+		return err
+	}
+	numCompressed, err := extractCompressedObjects(rebuilt, objects, report)
+	if err != nil {
+		return err
+	}
+
+	rootNum, err := findRoot(data, objects)
+	if err != nil {
+		return err
+	}
+	infoNum := int64(0)
+	for _, match := range reInfo.FindAllSubmatch(data, -1) {
+		num, _ := strconv.ParseInt(string(match[1]), 10, 64)
+		if _, has := objects[num]; has {
+			infoNum = num
+		}
+	}
+
+	err = repairPageTree(data, objects, rootNum, report)
+	if err != nil {
+		return err
+	}
+
+	rebuilt, err = rebuildFile(data, objects, rootNum, infoNum)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Recovered %d objects (%d from object streams), catalog is object %d\n", len(objects),
+		numCompressed, rootNum)
+
+	// The recovered objects are written even if the check fails, they may still be of use.
+	err = ioutil.WriteFile(outputPath, rebuilt, 0644)
+	if err != nil {
+		return err
+	}
+	return checkRebuilt(rebuilt, objects, report)
+}
+
+// Scans the file for the object markers and returns the complete objects by object number, and the offset after the
+// last complete object.
+func scanObjects(data []byte, report *repairReport) (map[int64]*recoveredObject, int64) {
+	objects := map[int64]*recoveredObject{}
+	end := int64(0)
+
+	pos := 0
+	for pos < len(data) {
+		loc := reObjectMarker.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		markerEnd := pos + loc[1]
+		// The marker must not be part of a longer token, e.g. the end of "1 12 0 obj".
+		if start > 0 && !isWhiteSpace(data[start-1]) && !isDelimiter(data[start-1]) {
+			pos = markerEnd
+			continue
+		}
+		number, _ := strconv.ParseInt(string(data[pos+loc[2]:pos+loc[3]]), 10, 64)
+		generation, _ := strconv.ParseInt(string(data[pos+loc[4]:pos+loc[5]]), 10, 64)
+
+		objEnd, err := findObjectEnd(data, markerEnd)
+		if err != nil {
+			report.addLost("Object %d at offset %d: %v", number, start, err)
+			pos = markerEnd
+			continue
+		}
+
+		objects[number] = &recoveredObject{
+			number:     number,
+			generation: generation,
+			data:       data[start:objEnd],
+			offset:     int64(start),
+		}
+		end = int64(objEnd)
+		pos = objEnd
+	}
+
+	return objects, end
+}
+
+// Returns the offset after the endobj of the object whose marker ends at the given offset.  The data of streams is
+// skipped, as it can contain anything.
+func findObjectEnd(data []byte, markerEnd int) (int, error) {
+	endObj := bytes.Index(data[markerEnd:], []byte("endobj"))
+	streamPos := bytes.Index(data[markerEnd:], []byte("stream"))
+	nextMarker := reObjectMarker.FindIndex(data[markerEnd:])
+
+	searchFrom := markerEnd
+	if streamPos >= 0 && (endObj < 0 || streamPos < endObj) && (nextMarker == nil || streamPos < nextMarker[0]) {
+		endStream := bytes.Index(data[markerEnd+streamPos:], []byte("endstream"))
+		if endStream < 0 {
+			return 0, errors.New("stream truncated")
+		}
+		searchFrom = markerEnd + streamPos + endStream + len("endstream")
+		endObj = bytes.Index(data[searchFrom:], []byte("endobj"))
+		nextMarker = reObjectMarker.FindIndex(data[searchFrom:])
+	}
+
+	if endObj < 0 {
+		return 0, errors.New("object truncated")
+	}
+	if nextMarker != nil && nextMarker[0] < endObj {
+		return 0, errors.New("endobj missing")
+	}
+	return searchFrom + endObj + len("endobj"), nil
+}
+
+// Returns the object number of the document catalog.
+func findRoot(data []byte, objects map[int64]*recoveredObject) (int64, error) {
+	matches := reRoot.FindAllSubmatch(data, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		num, _ := strconv.ParseInt(string(matches[i][1]), 10, 64)
+		if _, has := objects[num]; has {
+			return num, nil
+		}
+	}
+
+	// No trailer left, look for the catalog dictionary.
+	rebuilt, err := rebuildFile(data, objects, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+	parser, err := pdfcore.NewParser(bytes.NewReader(rebuilt))
+	if err != nil {
+		return 0, err
+	}
+	rootNum := int64(0)
+	rootOffset := int64(-1)
+	for num, object := range objects {
+		obj, err := parser.LookupByNumber(int(num))
+		if err != nil {
+			continue
+		}
+		dict, ok := pdfcore.TraceToDirectObject(obj).(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		if name, ok := dict.Get("Type").(*pdfcore.PdfObjectName); ok && *name == "Catalog" &&
+			object.offset > rootOffset {
+			rootNum = num
+			rootOffset = object.offset
+		}
+	}
+	if rootNum == 0 {
+		return 0, errors.New("No document catalog found")
+	}
+	return rootNum, nil
+}
+
+// Rebuilds the page tree if it cannot be loaded, e.g. when its root Pages object was lost: a new Pages object gets all
+// the recovered /Type /Page objects as kids, in the order of the file, and replaces the Pages entry of the catalog.
+func repairPageTree(data []byte, objects map[int64]*recoveredObject, rootNum int64, report *repairReport) error {
+	rebuilt, err := rebuildFile(data, objects, rootNum, 0)
+	if err != nil {
+		return err
+	}
+	_, loadErr := pdf.NewPdfReader(bytes.NewReader(rebuilt))
+	if loadErr == nil {
+		return nil
+	}
+
+	parser, err := pdfcore.NewParser(bytes.NewReader(rebuilt))
+	if err != nil {
+		return err
+	}
+	nums := []int64{}
+	maxNum := int64(0)
+	for num := range objects {
+		nums = append(nums, num)
+		if num > maxNum {
+			maxNum = num
+		}
+	}
+	sort.Slice(nums, func(i, j int) bool {
+		if objects[nums[i]].offset != objects[nums[j]].offset {
+			return objects[nums[i]].offset < objects[nums[j]].offset
+		}
+		return nums[i] < nums[j]
+	})
+
+	pagesNum := maxNum + 1
+	pagesRef := &pdfcore.PdfObjectReference{ObjectNumber: pagesNum}
+	kids := pdfcore.PdfObjectArray{}
+	for _, num := range nums {
+		obj, err := parser.LookupByNumber(int(num))
+		if err != nil {
+			continue
+		}
+		ind, ok := obj.(*pdfcore.PdfIndirectObject)
+		if !ok {
+			continue
+		}
+		dict, ok := ind.PdfObject.(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		if name, ok := dict.Get("Type").(*pdfcore.PdfObjectName); !ok || *name != "Page" {
+			continue
+		}
+		dict.Set("Parent", pagesRef)
+		objects[num].data = objectData(num, objects[num].generation, dict)
+		kids = append(kids, &pdfcore.PdfObjectReference{ObjectNumber: num, GenerationNumber: objects[num].generation})
+	}
+	if len(kids) == 0 {
+		report.addLost("Page tree: %v, and no pages were recovered", loadErr)
+		return nil
+	}
+
+	obj, err := parser.LookupByNumber(int(rootNum))
+	if err != nil {
+		return err
+	}
+	ind, ok := obj.(*pdfcore.PdfIndirectObject)
+	if !ok {
+		return errors.New("Catalog is not a dictionary")
+	}
+	catalog, ok := ind.PdfObject.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Catalog is not a dictionary")
+	}
+	catalog.Set("Pages", pagesRef)
+	objects[rootNum].data = objectData(rootNum, objects[rootNum].generation, catalog)
+
+	pages := pdfcore.MakeDict()
+	pages.Set("Type", pdfcore.MakeName("Pages"))
+	pages.Set("Kids", &kids)
+	pages.Set("Count", pdfcore.MakeInteger(int64(len(kids))))
+	objects[pagesNum] = &recoveredObject{
+		number: pagesNum,
+		data:   objectData(pagesNum, 0, pages),
+		offset: int64(len(data)),
+	}
+
+	report.addLost("Page tree: %v, rebuilt as object %d with the %d recovered pages, without the inherited attributes",
+		loadErr, pagesNum, len(kids))
+	return nil
+}
+
+// Returns the data of the object with the given number and generation and dictionary, from the marker to endobj.
+func objectData(num, generation int64, dict *pdfcore.PdfObjectDictionary) []byte {
+	return []byte(fmt.Sprintf("%d %d obj\n%s\nendobj", num, generation, dict.DefaultWriteString()))
+}
+
+// Extracts the objects compressed in the object streams of the rebuilt file into objects.  Returns the number of
+// objects extracted.
+func extractCompressedObjects(rebuilt []byte, objects map[int64]*recoveredObject, report *repairReport) (int,
+	error) {
+	parser, err := pdfcore.NewParser(bytes.NewReader(rebuilt))
+	if err != nil {
+		return 0, err
+	}
+
+	streamNums := []int64{}
+	for num := range objects {
+		streamNums = append(streamNums, num)
+	}
+	sort.Slice(streamNums, func(i, j int) bool { return streamNums[i] < streamNums[j] })
+
+	count := 0
+	for _, streamNum := range streamNums {
+		if !bytes.Contains(objects[streamNum].data, []byte("/ObjStm")) {
+			continue
+		}
+		obj, err := parser.LookupByNumber(int(streamNum))
+		if err != nil {
+			continue
+		}
+		stream, ok := obj.(*pdfcore.PdfObjectStream)
+		if !ok {
+			continue
+		}
+		if name, ok := stream.PdfObjectDictionary.Get("Type").(*pdfcore.PdfObjectName); !ok || *name != "ObjStm" {
+			continue
+		}
+
+		decoded, err := pdfcore.DecodeStream(stream)
+		if err != nil {
+			report.addLost("Object stream %d cannot be decoded: %v", streamNum, err)
+			continue
+		}
+		n, ok1 := pdfcore.TraceToDirectObject(stream.PdfObjectDictionary.Get("N")).(*pdfcore.PdfObjectInteger)
+		first, ok2 := pdfcore.TraceToDirectObject(stream.PdfObjectDictionary.Get("First")).(*pdfcore.PdfObjectInteger)
+		if !ok1 || !ok2 || int(*first) > len(decoded) {
+			report.addLost("Object stream %d is invalid", streamNum)
+			continue
+		}
+
+		// The header has pairs of object number and offset relative to First.
+		header := bytes.Fields(decoded[:*first])
+		if len(header) < 2*int(*n) {
+			report.addLost("Object stream %d is invalid", streamNum)
+			continue
+		}
+		for i := 0; i < int(*n); i++ {
+			num, err1 := strconv.ParseInt(string(header[2*i]), 10, 64)
+			start, err2 := strconv.Atoi(string(header[2*i+1]))
+			end := len(decoded) - int(*first)
+			if i+1 < int(*n) {
+				end, _ = strconv.Atoi(string(header[2*i+3]))
+			}
+			if err1 != nil || err2 != nil || start > end || int(*first)+end > len(decoded) {
+				report.addLost("Object stream %d is invalid", streamNum)
+				break
+			}
+			if _, has := objects[num]; has {
+				continue
+			}
+
+			var buf bytes.Buffer
+			buf.WriteString(fmt.Sprintf("%d 0 obj\n", num))
+			buf.Write(bytes.TrimSpace(decoded[int(*first)+start : int(*first)+end]))
+			buf.WriteString("\nendobj")
+			objects[num] = &recoveredObject{
+				number:    num,
+				data:      buf.Bytes(),
+				offset:    objects[streamNum].offset,
+				objectStm: streamNum,
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Writes the objects with a new cross-reference table and a trailer with the Root and Info (if not 0) entries.
+func rebuildFile(data []byte, objects map[int64]*recoveredObject, rootNum, infoNum int64) ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := reHeader.Find(data)
+	if header == nil {
+		header = []byte("%PDF-1.7")
+	}
+	buf.Write(header)
+	buf.WriteString("\n%\xe2\xe3\xcf\xd3\n")
+
+	maxNum := int64(0)
+	offsets := map[int64]int{}
+	nums := []int64{}
+	for num := range objects {
+		nums = append(nums, num)
+		if num > maxNum {
+			maxNum = num
+		}
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+	for _, num := range nums {
+		offsets[num] = buf.Len()
+		buf.Write(objects[num].data)
+		buf.WriteString("\n")
+	}
+
+	xrefOffset := buf.Len()
+	buf.WriteString(fmt.Sprintf("xref\n0 %d\n", maxNum+1))
+	buf.WriteString("0000000000 65535 f\r\n")
+	for num := int64(1); num <= maxNum; num++ {
+		if object, has := objects[num]; has {
+			buf.WriteString(fmt.Sprintf("%010d %05d n\r\n", offsets[num], object.generation))
+		} else {
+			buf.WriteString("0000000000 00000 f\r\n")
+		}
+	}
+
+	trailer := pdfcore.MakeDict()
+	trailer.Set("Size", pdfcore.MakeInteger(maxNum+1))
+	if rootNum > 0 {
+		trailer.Set("Root", &pdfcore.PdfObjectReference{ObjectNumber: rootNum,
+			GenerationNumber: objects[rootNum].generation})
+	}
+	if infoNum > 0 {
+		trailer.Set("Info", &pdfcore.PdfObjectReference{ObjectNumber: infoNum,
+			GenerationNumber: objects[infoNum].generation})
+	}
+	buf.WriteString(fmt.Sprintf("trailer\n%s\nstartxref\n%d\n%%%%EOF\n", trailer.DefaultWriteString(), xrefOffset))
+
+	return buf.Bytes(), nil
+}
+
+// Checks the rebuilt file: all objects can be parsed, all referenced objects exist and all pages can be loaded.
+func checkRebuilt(rebuilt []byte, objects map[int64]*recoveredObject, report *repairReport) error {
+	parser, err := pdfcore.NewParser(bytes.NewReader(rebuilt))
+	if err != nil {
+		return err
+	}
+
+	nums := []int64{}
+	for num := range objects {
+		nums = append(nums, num)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	missing := map[int64]bool{}
+	for _, num := range nums {
+		obj, err := parser.LookupByNumber(int(num))
+		if err != nil {
+			report.addLost("Object %d cannot be parsed: %v", num, err)
+			continue
+		}
+		refs := []*pdfcore.PdfObjectReference{}
+		switch t := obj.(type) {
+		case *pdfcore.PdfIndirectObject:
+			refs = collectReferences(t.PdfObject, refs)
+		case *pdfcore.PdfObjectStream:
+			refs = collectReferences(t.PdfObjectDictionary, refs)
+		}
+		for _, ref := range refs {
+			if _, has := objects[ref.ObjectNumber]; !has && !missing[ref.ObjectNumber] {
+				missing[ref.ObjectNumber] = true
+				report.addLost("Object %d is referenced by object %d but was not recovered", ref.ObjectNumber, num)
+			}
+		}
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(rebuilt))
+	if err != nil {
+		return err
+	}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	loaded := 0
+	for i := 0; i < numPages; i++ {
+		_, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			report.addLost("Page %d cannot be loaded: %v", i+1, err)
+			continue
+		}
+		loaded++
+	}
+	fmt.Printf("%d of %d pages can be loaded\n", loaded, numPages)
+
+	return nil
+}
+
+// Appends the references in the dictionaries and arrays of the object to refs.
+func collectReferences(obj pdfcore.PdfObject, refs []*pdfcore.PdfObjectReference) []*pdfcore.PdfObjectReference {
+	switch t := obj.(type) {
+	case *pdfcore.PdfObjectReference:
+		refs = append(refs, t)
+	case *pdfcore.PdfObjectDictionary:
+		for _, key := range t.Keys() {
+			refs = collectReferences(t.Get(key), refs)
+		}
+	case *pdfcore.PdfObjectArray:
+		for _, v := range *t {
+			refs = collectReferences(v, refs)
+		}
+	}
+	return refs
+}
+
+func isWhiteSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '\f' || b == 0
+}
+
+func isDelimiter(b byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), b) >= 0
+}
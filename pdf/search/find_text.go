@@ -0,0 +1,214 @@
+/*
+ * Search a PDF for text and report the page number and bounding box of each match.
+ *
+ * The extractor of this UniDoc version returns the plain text only, so the glyph positions are computed from the
+ * content streams with text_layout.go: the text state (font size, spacing, text and transformation matrices) and the
+ * font widths give the box of each glyph.  Text in form XObjects is included.
+ *
+ * The search matches phrases across text showing operations: a space in the query matches a space character as well
+ * as a gap between words or a line change.  With -i, case is ignored.  With -join-hyphens, words hyphenated at the
+ * end of a line are joined, i.e. "exam-" at the end of a line followed by "ple" matches "example".
+ *
+ * The bounding boxes are in page coordinates (the PDF coordinate system, 0,0 in the lower left corner of the
+ * MediaBox as specified).  For matches spanning several lines, the box of each line is listed too.
+ *
+ * Limitation: the characters are decoded with the simple font encodings (WinAnsi and Differences), text in composite
+ * (Type0) fonts is not matched.
+ *
+ * Run as: go run find_text.go text_layout.go [-i] [-join-hyphens] input.pdf <query>
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run find_text.go text_layout.go [-i] [-join-hyphens] input.pdf <query>\n"
+
+func main() {
+	ignoreCase := false
+	joinHyphens := false
+	flag.BoolVar(&ignoreCase, "i", false, "Ignore case")
+	flag.BoolVar(&joinHyphens, "join-hyphens", false, "Join words hyphenated at line ends")
+	flag.Parse()
+
+	if flag.NArg() < 2 || len(strings.TrimSpace(flag.Arg(1))) == 0 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	query := flag.Arg(1)
+
+	err := findText(inputPath, query, ignoreCase, joinHyphens)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func findText(inputPath, query string, ignoreCase, joinHyphens bool) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return err
+		}
+
+		layout := newTextLayout()
+		err = layout.process(contents, page.Resources, identityMatrix(), 0)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range layout.find(query, ignoreCase, joinHyphens) {
+			total++
+			fmt.Printf("Page %d: %q at [%.2f %.2f %.2f %.2f]\n", pageNum, m.text, m.bbox.Llx, m.bbox.Lly,
+				m.bbox.Urx, m.bbox.Ury)
+			if len(m.lines) > 1 {
+				for _, line := range m.lines {
+					fmt.Printf("    line at [%.2f %.2f %.2f %.2f]\n", line.Llx, line.Lly, line.Urx, line.Ury)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("%d matches for %q\n", total, query)
+	return nil
+}
+
+// match is an occurrence of the query.
+type match struct {
+	text  string             // The matched text as on the page.
+	bbox  pdf.PdfRectangle   // Bounding box of the match.
+	lines []pdf.PdfRectangle // Bounding box of each line of the match.
+}
+
+// Finds the occurrences of the query.  Returns the matches in content stream order.
+func (layout *textLayout) find(query string, ignoreCase, joinHyphens bool) []match {
+	fold := func(r rune) rune {
+		if ignoreCase {
+			return unicode.ToLower(r)
+		}
+		return r
+	}
+
+	// Runes to search, with the index of the glyph of each rune (-1 for spaces inserted for gaps).  White space is
+	// collapsed to single spaces, in the query as well.
+	q := []rune{}
+	for _, r := range strings.Join(strings.Fields(query), " ") {
+		q = append(q, fold(r))
+	}
+	text := []rune{}
+	indices := []int{}
+	for i, g := range layout.glyphs {
+		endsWithSpace := len(text) > 0 && text[len(text)-1] == ' '
+		if i > 0 {
+			prev := layout.glyphs[i-1]
+			newLine := !sameLine(prev, g)
+			if newLine && joinHyphens && len(text) > 0 && isHyphen(text[len(text)-1]) {
+				// Drop the hyphen and join the word parts.
+				text = text[:len(text)-1]
+				indices = indices[:len(indices)-1]
+			} else if (newLine || isGap(prev, g)) && !endsWithSpace {
+				text = append(text, ' ')
+				indices = append(indices, -1)
+				endsWithSpace = true
+			}
+		}
+
+		r := fold(g.r)
+		if unicode.IsSpace(r) {
+			if endsWithSpace {
+				continue
+			}
+			r = ' '
+		}
+		text = append(text, r)
+		indices = append(indices, i)
+	}
+
+	matches := []match{}
+	for i := 0; len(q) > 0 && i+len(q) <= len(text); i++ {
+		if string(text[i:i+len(q)]) != string(q) {
+			continue
+		}
+
+		m := match{}
+		var last *glyph
+		for _, idx := range indices[i : i+len(q)] {
+			if idx < 0 {
+				m.text += " "
+				continue
+			}
+			g := &layout.glyphs[idx]
+			m.text += string(g.r)
+			if last == nil {
+				m.bbox = g.box
+			} else {
+				m.bbox = union(m.bbox, g.box)
+			}
+			if last != nil && sameLine(*last, *g) {
+				m.lines[len(m.lines)-1] = union(m.lines[len(m.lines)-1], g.box)
+			} else {
+				m.lines = append(m.lines, g.box)
+			}
+			last = g
+		}
+		matches = append(matches, m)
+		i += len(q) - 1
+	}
+	return matches
+}
+
+func isHyphen(r rune) bool {
+	return r == '-' || r == '\u00ad' || r == '\u2010'
+}
@@ -0,0 +1,391 @@
+/*
+ * Highlight all occurrences of search terms in a PDF with highlight annotations, one color per term.
+ *
+ * Terms are given as term=#rrggbb, or just the term to use a color from a default palette.  Each match gets its own
+ * highlight annotation, with one quadrilateral per line for matches that span lines, and an appearance stream so it
+ * looks the same in all viewers.  The matches are found as in find_text.go, with the glyph positions from
+ * text_layout.go (see there for how they are computed, and find_text.go for the -i and -join-hyphens options).
+ *
+ * Overlapping matches:
+ * - The occurrences of a term do not overlap, the search continues after each match.
+ * - When several terms match exactly the same text, it is highlighted once, in the color of the first term.  Matches
+ *   that overlap partially are all highlighted; the highlights are drawn with the multiply blend mode, so the text
+ *   remains readable where they overlap.
+ *
+ * Terms can occur hundreds of times: the annotations share a single graphics state object, and the output is read
+ * back after writing to check that it is valid and contains all the highlights.
+ *
+ * Run as: go run highlight_matches.go text_layout.go [-i] [-join-hyphens] input.pdf output.pdf term[=#rrggbb] ...
+ * For example: go run highlight_matches.go text_layout.go -i input.pdf output.pdf PDF=#ffeb3b "content stream=#90caf9"
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run highlight_matches.go text_layout.go [-i] [-join-hyphens] input.pdf output.pdf " +
+	"term[=#rrggbb] ...\n"
+
+// Annotation flag (F): print the annotation.
+const annotFlagPrint = 4
+
+// Colors for terms without a color.
+var palette = []string{"#ffeb3b", "#a5d6a7", "#90caf9", "#f48fb1", "#ffcc80", "#ce93d8"}
+
+// searchTerm is a term to highlight with its color.
+type searchTerm struct {
+	text  string
+	color creator.Color
+}
+
+func main() {
+	ignoreCase := false
+	joinHyphens := false
+	flag.BoolVar(&ignoreCase, "i", false, "Ignore case")
+	flag.BoolVar(&joinHyphens, "join-hyphens", false, "Join words hyphenated at line ends")
+	flag.Parse()
+
+	if flag.NArg() < 3 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	terms := []searchTerm{}
+	for i, arg := range flag.Args()[2:] {
+		text, colorHex := arg, palette[i%len(palette)]
+		if pos := strings.LastIndex(arg, "=#"); pos >= 0 {
+			text, colorHex = arg[:pos], arg[pos+1:]
+		}
+		if len(strings.TrimSpace(text)) == 0 || len(colorHex) != 7 {
+			fmt.Printf("Error: invalid term %q (use term or term=#rrggbb)\n", arg)
+			os.Exit(1)
+		}
+		terms = append(terms, searchTerm{text: text, color: creator.ColorRGBFromHex(colorHex)})
+	}
+
+	err := highlightMatches(inputPath, outputPath, terms, ignoreCase, joinHyphens)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func highlightMatches(inputPath, outputPath string, terms []searchTerm, ignoreCase, joinHyphens bool) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	// Graphics state of the highlight appearances, shared by all annotations.
+	gs := pdfcore.MakeDict()
+	gs.Set("Type", pdfcore.MakeName("ExtGState"))
+	gs.Set("BM", pdfcore.MakeName("Multiply"))
+	gsObj := pdfcore.MakeIndirectObject(gs)
+
+	date := formatPdfDate(time.Now())
+	counts := make([]int, len(terms))
+	duplicates := 0
+	total := 0
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		pageNum := i + 1
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+
+		contents, err := page.GetAllContentStreams()
+		if err != nil {
+			return err
+		}
+
+		layout := newTextLayout()
+		err = layout.process(contents, page.Resources, identityMatrix(), 0)
+		if err != nil {
+			return err
+		}
+
+		// Glyph ranges already highlighted on the page.
+		highlighted := map[[2]int]bool{}
+		for t, term := range terms {
+			for _, m := range layout.find(term.text, ignoreCase, joinHyphens) {
+				key := [2]int{m.first, m.last}
+				if highlighted[key] {
+					duplicates++
+					continue
+				}
+				highlighted[key] = true
+
+				name := fmt.Sprintf("highlight-%d-%d", pageNum, len(highlighted))
+				annot, err := newHighlightAnnotation(m, term.color, name, date, gsObj)
+				if err != nil {
+					return err
+				}
+				page.Annotations = append(page.Annotations, annot)
+				counts[t]++
+				total++
+			}
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	for t, term := range terms {
+		fmt.Printf("%q: %d matches\n", term.text, counts[t])
+	}
+	if duplicates > 0 {
+		fmt.Printf("%d matches already highlighted for a previous term\n", duplicates)
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	err = pdfWriter.Write(fWrite)
+	if err != nil {
+		return err
+	}
+
+	return checkOutput(outputPath, total)
+}
+
+// Creates a highlight annotation for the match, with a quadrilateral per line.
+func newHighlightAnnotation(m match, color creator.Color, name, date string, gsObj pdfcore.PdfObject) (
+	*pdf.PdfAnnotation, error) {
+	r, g, b := color.ToRGB()
+
+	highlight := pdf.NewPdfAnnotationHighlight()
+	highlight.Contents = pdfcore.MakeString(m.text)
+	highlight.NM = pdfcore.MakeString(name)
+	highlight.M = pdfcore.MakeString(date)
+	highlight.CreationDate = pdfcore.MakeString(date)
+	highlight.C = pdfcore.MakeArrayFromFloats([]float64{r, g, b})
+	highlight.F = pdfcore.MakeInteger(annotFlagPrint)
+	highlight.Rect = m.bbox.ToPdfObject()
+
+	// The points of each quadrilateral are in the order upper left, upper right, lower left, lower right, which is
+	// the order used by the common viewers.
+	quadPoints := []float64{}
+	content := fmt.Sprintf("q\n/GS0 gs\n%.4f %.4f %.4f rg\n", r, g, b)
+	for _, line := range m.lines {
+		quadPoints = append(quadPoints,
+			line.Llx, line.Ury, line.Urx, line.Ury,
+			line.Llx, line.Lly, line.Urx, line.Lly)
+		content += fmt.Sprintf("%.4f %.4f %.4f %.4f re\n", line.Llx, line.Lly, line.Urx-line.Llx,
+			line.Ury-line.Lly)
+	}
+	content += "f\nQ\n"
+	highlight.QuadPoints = pdfcore.MakeArrayFromFloats(quadPoints)
+
+	// Appearance in page coordinates: the BBox is the annotation Rect.
+	form := pdf.NewXObjectForm()
+	form.Resources = pdf.NewPdfPageResources()
+	err := form.Resources.AddExtGState("GS0", gsObj)
+	if err != nil {
+		return nil, err
+	}
+	form.BBox = m.bbox.ToPdfObject()
+	err = form.SetContentStream([]byte(content), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	apDict := pdfcore.MakeDict()
+	apDict.Set("N", form.ToPdfObject())
+	highlight.AP = apDict
+
+	return highlight.PdfAnnotation, nil
+}
+
+// Reads the output back and checks that it contains the expected number of highlight annotations.
+func checkOutput(outputPath string, expected int) error {
+	f, err := os.Open(outputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return fmt.Errorf("Output cannot be read: %v", err)
+	}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return fmt.Errorf("Output page %d cannot be read: %v", i+1, err)
+		}
+		for _, annot := range page.Annotations {
+			if _, isHighlight := annot.GetContext().(*pdf.PdfAnnotationHighlight); isHighlight {
+				count++
+			}
+		}
+	}
+
+	if count < expected {
+		return fmt.Errorf("Output has %d highlight annotations, expected at least %d", count, expected)
+	}
+	fmt.Printf("Output checked: %d highlight annotations\n", count)
+	return nil
+}
+
+// Formats the time as a PDF date string, e.g. D:20180213153000+01'00'.
+func formatPdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
+
+// match is an occurrence of the query.
+type match struct {
+	text        string             // The matched text as on the page.
+	bbox        pdf.PdfRectangle   // Bounding box of the match.
+	lines       []pdf.PdfRectangle // Bounding box of each line of the match.
+	first, last int                // Indices of the first and last glyph of the match.
+}
+
+// Finds the occurrences of the query.  Returns the matches in content stream order.
+func (layout *textLayout) find(query string, ignoreCase, joinHyphens bool) []match {
+	fold := func(r rune) rune {
+		if ignoreCase {
+			return unicode.ToLower(r)
+		}
+		return r
+	}
+
+	// Runes to search, with the index of the glyph of each rune (-1 for spaces inserted for gaps).  White space is
+	// collapsed to single spaces, in the query as well.
+	q := []rune{}
+	for _, r := range strings.Join(strings.Fields(query), " ") {
+		q = append(q, fold(r))
+	}
+	text := []rune{}
+	indices := []int{}
+	for i, g := range layout.glyphs {
+		endsWithSpace := len(text) > 0 && text[len(text)-1] == ' '
+		if i > 0 {
+			prev := layout.glyphs[i-1]
+			newLine := !sameLine(prev, g)
+			if newLine && joinHyphens && len(text) > 0 && isHyphen(text[len(text)-1]) {
+				// Drop the hyphen and join the word parts.
+				text = text[:len(text)-1]
+				indices = indices[:len(indices)-1]
+			} else if (newLine || isGap(prev, g)) && !endsWithSpace {
+				text = append(text, ' ')
+				indices = append(indices, -1)
+				endsWithSpace = true
+			}
+		}
+
+		r := fold(g.r)
+		if unicode.IsSpace(r) {
+			if endsWithSpace {
+				continue
+			}
+			r = ' '
+		}
+		text = append(text, r)
+		indices = append(indices, i)
+	}
+
+	matches := []match{}
+	for i := 0; len(q) > 0 && i+len(q) <= len(text); i++ {
+		if string(text[i:i+len(q)]) != string(q) {
+			continue
+		}
+
+		m := match{first: -1}
+		var last *glyph
+		for _, idx := range indices[i : i+len(q)] {
+			if idx < 0 {
+				m.text += " "
+				continue
+			}
+			if m.first < 0 {
+				m.first = idx
+			}
+			m.last = idx
+			g := &layout.glyphs[idx]
+			m.text += string(g.r)
+			if last == nil {
+				m.bbox = g.box
+			} else {
+				m.bbox = union(m.bbox, g.box)
+			}
+			if last != nil && sameLine(*last, *g) {
+				m.lines[len(m.lines)-1] = union(m.lines[len(m.lines)-1], g.box)
+			} else {
+				m.lines = append(m.lines, g.box)
+			}
+			last = g
+		}
+		matches = append(matches, m)
+		i += len(q) - 1
+	}
+	return matches
+}
+
+func isHyphen(r rune) bool {
+	return r == '-' || r == '\u00ad' || r == '\u2010'
+}
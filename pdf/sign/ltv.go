@@ -0,0 +1,1058 @@
+/*
+ * Sign a PDF file with a long-term validation (LTV) enabled signature: the certificate chain and the revocation
+ * information (OCSP responses and CRLs) are embedded in the file, so that the signature can be validated long after
+ * the certificates expire, when the OCSP responders and CRLs are no longer available.
+ *
+ * Validating a signature needs the chain of certificates up to a trusted root, and for each certificate (except the
+ * root) evidence that it was not revoked at the signing time: an OCSP response, or a CRL of its issuer.  Here they are
+ * fetched before signing:
+ * - the chain: the certificates of -cert, completed with the issuers from the authority information access (AIA) URLs
+ *   of the certificates,
+ * - an OCSP response from the OCSP URL of each certificate, or else the CRL from its CRL distribution points.  The
+ *   response or CRL must be signed by the issuer (or a responder certified by it), and the certificate must not be
+ *   revoked.
+ * They are embedded twice:
+ * - in the signature, as a signed attribute (adbe-revocationInfoArchival) with the OCSP responses and CRLs, and the
+ *   chain in the CMS certificates,
+ * - in the document security store (DSS) of the catalog, added by a second incremental update after the signature:
+ *   Certs, OCSPs and CRLs arrays of streams, and a validation related information (VRI) dictionary for the signature,
+ *   keyed by the SHA-1 hash of its value.  This is the PAdES (ETSI) form, which can also be extended later, e.g.
+ *   with the revocation information of a timestamp.  The DSS update does not invalidate the signature.
+ *
+ * A certificate without an OCSP or CRL URL, an issuer that cannot be found, or a failed request leaves the LTV
+ * incomplete: this is reported as a warning, the file is still signed, and the missing parts are listed at the end.
+ * A revoked certificate is an error.
+ *
+ * The signature is a detached CMS (PKCS#7) signature (SubFilter adbe.pkcs7.detached), added as an incremental update
+ * in the first unsigned signature field (e.g. one prepared by prepare_signature_field.go), a field given with -field,
+ * or a new invisible signature field.  The signing certificate and key are PEM files (-cert, which can include the
+ * chain, and -key, RSA or ECDSA).  Without them, a self-signed test certificate is generated, which needs no
+ * revocation information but is not trusted.  The signing code and the update writer are in signing.go, shared
+ * with timestamp.go.
+ *
+ * Run as: go run ltv.go signing.go [-cert cert.pem -key key.pem] [-hash sha256] [-field name] [-reason text]
+ *                                  input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run ltv.go signing.go [-cert cert.pem -key key.pem] [-hash sha256] [-field name] " +
+	"[-reason text] input.pdf output.pdf\n"
+
+var (
+	oidSHA1        = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+	oidOCSPBasic   = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidOCSPNoCheck = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 5}
+)
+
+// The signature algorithms of OCSP responses by OID.
+var signatureAlgorithms = map[string]x509.SignatureAlgorithm{
+	"1.2.840.113549.1.1.5":  x509.SHA1WithRSA,
+	"1.2.840.113549.1.1.11": x509.SHA256WithRSA,
+	"1.2.840.113549.1.1.12": x509.SHA384WithRSA,
+	"1.2.840.113549.1.1.13": x509.SHA512WithRSA,
+	"1.2.840.10045.4.1":     x509.ECDSAWithSHA1,
+	"1.2.840.10045.4.3.2":   x509.ECDSAWithSHA256,
+	"1.2.840.10045.4.3.3":   x509.ECDSAWithSHA384,
+	"1.2.840.10045.4.3.4":   x509.ECDSAWithSHA512,
+}
+
+// ASN.1 structures of OCSP (RFC 6960) and the Adobe revocation information attribute.
+
+type certID struct {
+	HashAlgorithm  algorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+type tbsRequest struct {
+	RequestList []singleRequest
+}
+
+type singleRequest struct {
+	CertID certID
+}
+
+type ocspResponse struct {
+	Status        asn1.Enumerated
+	ResponseBytes responseBytes `asn1:"explicit,optional,tag:0"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicOCSPResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm algorithmIdentifier
+	Signature          asn1.BitString
+	Certs              []asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type responseData struct {
+	Version     int `asn1:"explicit,optional,default:0,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []singleResponse
+	Extensions  asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type singleResponse struct {
+	CertID     certID
+	CertStatus asn1.RawValue // [0] good, [1] revoked, [2] unknown.
+	ThisUpdate time.Time     `asn1:"generalized"`
+	NextUpdate time.Time     `asn1:"generalized,explicit,optional,tag:0"`
+	Extensions asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type revocationInfoArchival struct {
+	CRLs  []asn1.RawValue `asn1:"explicit,optional,tag:0"`
+	OCSPs []asn1.RawValue `asn1:"explicit,optional,tag:1"`
+}
+
+// validationData is the certificate chain and revocation information of a signature.
+type validationData struct {
+	chain      []*x509.Certificate // The signing certificate first.
+	responders []*x509.Certificate // Certificates of OCSP responders.
+	ocsps      [][]byte            // OCSP responses (DER).
+	crls       [][]byte            // CRLs (DER).
+	missing    []string            // Why the LTV is incomplete.
+}
+
+// errRevoked is the error for a revoked certificate.
+type errRevoked struct {
+	cert *x509.Certificate
+	at   time.Time
+}
+
+func (e errRevoked) Error() string {
+	return fmt.Sprintf("The certificate %s was revoked on %s", e.cert.Subject.CommonName, e.at.Format(time.RFC3339))
+}
+
+func main() {
+	hashName := ""
+	certPath := ""
+	keyPath := ""
+	fieldName := ""
+	reason := ""
+	timeout := time.Duration(0)
+	flag.StringVar(&hashName, "hash", "sha256", "Hash algorithm: sha256, sha384 or sha512")
+	flag.StringVar(&certPath, "cert", "", "Signing certificate (PEM, optionally followed by the chain)")
+	flag.StringVar(&keyPath, "key", "", "Private key of the certificate (PEM)")
+	flag.StringVar(&fieldName, "field", "", "Signature field to sign (default: the first unsigned one, or a new one)")
+	flag.StringVar(&reason, "reason", "", "Reason for signing")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "Timeout of the OCSP, CRL and certificate requests")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	hash, ok := hashAlgorithms[hashName]
+	if !ok {
+		fmt.Printf("Error: unsupported hash algorithm %q\n", hashName)
+		os.Exit(1)
+	}
+	if (certPath == "") != (keyPath == "") {
+		fmt.Printf("Error: -cert and -key must be given together\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	var s *signer
+	var err error
+	if certPath != "" {
+		s, err = loadSigner(certPath, keyPath)
+	} else {
+		fmt.Printf("No certificate given, signing with a self-signed test certificate\n")
+		s, err = newTestSigner()
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	vd, err := signLTV(inputPath, outputPath, s, hash, client, fieldName, reason)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(vd.missing) > 0 {
+		fmt.Printf("Warning: LTV cannot be completed, the signature can only be validated while the following " +
+			"are available online:\n")
+		for _, m := range vd.missing {
+			fmt.Printf("- %s\n", m)
+		}
+	} else {
+		fmt.Printf("The signature is LTV enabled\n")
+	}
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func signLTV(inputPath, outputPath string, s *signer, hash crypto.Hash, client *http.Client, fieldName,
+	reason string) (*validationData, error) {
+	data, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		return nil, errors.New("Signing encrypted files is not supported")
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return nil, errors.New("Missing Size in trailer")
+	}
+	nextNum := int64(*size)
+	catalogRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return nil, errors.New("Missing Root in trailer")
+	}
+	catalogObj, catalog := resolve(pdfReader, catalogRef)
+	catalogDict, ok := catalog.(*pdfcore.PdfObjectDictionary)
+	if !ok || catalogObj == nil {
+		return nil, errors.New("Missing catalog")
+	}
+
+	err = checkDocMDP(pdfReader, catalogDict)
+	if err != nil {
+		return nil, err
+	}
+
+	// The chain and the revocation information, before signing: they are embedded in the signature.
+	vd, err := fetchValidationData(client, s.certs)
+	if err != nil {
+		return nil, err
+	}
+	s.certs = vd.chain
+	archival, err := vd.revocationArchival()
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature dictionary, written with placeholders for the byte range and the signature value.
+	now := time.Now()
+	sigNum := nextNum
+	nextNum++
+	reserve := len(archival) + 8192
+	for _, cert := range s.certs {
+		reserve += len(cert.Raw)
+	}
+	sigDict := "<< /Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached " +
+		"/ByteRange [" + strings.Repeat(" ", byteRangeWidth) + "] " +
+		"/Contents <" + strings.Repeat("0", 2*reserve) + "> " +
+		"/M " + pdfcore.MakeString(formatPdfDate(now)).DefaultWriteString() + " " +
+		"/Name " + pdfcore.MakeString(s.certs[0].Subject.CommonName).DefaultWriteString()
+	if reason != "" {
+		sigDict += " /Reason " + pdfcore.MakeString(reason).DefaultWriteString()
+	}
+	sigDict += " >>"
+	objects := []updateObject{{number: sigNum, obj: rawObject(sigDict), description: "signature"}}
+	sigRef := &pdfcore.PdfObjectReference{ObjectNumber: sigNum}
+
+	// The field: an unsigned signature field, or a new invisible one.
+	formObj, form := resolve(pdfReader, catalogDict.Get("AcroForm"))
+	formDict, _ := form.(*pdfcore.PdfObjectDictionary)
+	var field *sigField
+	if formDict != nil {
+		field, err = findSignatureField(pdfReader, formDict, fieldName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if field == nil && fieldName != "" {
+		return nil, fmt.Errorf("No unsigned signature field %s", fieldName)
+	}
+
+	if field == nil {
+		if formDict == nil {
+			formDict = pdfcore.MakeDict()
+			catalogDict.Set("AcroForm", formDict)
+			formObj = nil
+		}
+		newObjects, err := addInvisibleField(pdfReader, formDict, sigRef, nextNum)
+		if err != nil {
+			return nil, err
+		}
+		nextNum++
+		objects = append(objects, newObjects...)
+	} else {
+		fmt.Printf("Signing the field %s\n", field.name)
+		field.dict.Set("V", sigRef)
+		objects = append(objects, updateObject{number: field.obj.ObjectNumber, generation: field.obj.GenerationNumber,
+			obj: field.dict, description: "signature field"})
+
+		// The appearance of the visible widgets: the signer and the date.
+		for _, w := range field.widgets {
+			appearance, err := makeSignedAppearance(w.dict, s.certs[0].Subject.CommonName, now)
+			if err != nil || appearance == nil {
+				continue
+			}
+			ap := pdfcore.MakeDict()
+			ap.Set("N", &pdfcore.PdfObjectReference{ObjectNumber: nextNum})
+			w.dict.Set("AP", ap)
+			objects = append(objects,
+				updateObject{number: nextNum, obj: appearance, description: "signature appearance"},
+				updateObject{number: w.obj.ObjectNumber, generation: w.obj.GenerationNumber, obj: w.dict,
+					description: "signature widget"})
+			nextNum++
+		}
+	}
+
+	formDict.Set("SigFlags", pdfcore.MakeInteger(sigFlags))
+	if formObj != nil {
+		objects = append(objects, updateObject{number: formObj.ObjectNumber, generation: formObj.GenerationNumber,
+			obj: formDict, description: "form"})
+	} else {
+		objects = append(objects, updateObject{number: catalogObj.ObjectNumber,
+			generation: catalogObj.GenerationNumber, obj: catalogDict, description: "catalog"})
+	}
+
+	update, err := writeUpdate(data, objects, trailer, nextNum)
+	if err != nil {
+		return nil, err
+	}
+	output := append(append([]byte{}, data...), update...)
+	for _, obj := range objects {
+		fmt.Printf("Updated object %d: %s\n", obj.number, obj.description)
+	}
+
+	// The byte range: the whole file except the signature value.
+	sigOffset := bytes.Index(output[len(data):], []byte(fmt.Sprintf("%d 0 obj\n", sigNum))) + len(data)
+	brStart := bytes.Index(output[sigOffset:], []byte("/ByteRange [")) + sigOffset + len("/ByteRange [")
+	contentsStart := bytes.Index(output[sigOffset:], []byte("/Contents <")) + sigOffset + len("/Contents ")
+	contentsEnd := contentsStart + 2*reserve + 2
+	byteRange := []int{0, contentsStart, contentsEnd, len(output) - contentsEnd}
+	br := fmt.Sprintf("0 %d %d %d", byteRange[1], byteRange[2], byteRange[3])
+	copy(output[brStart:], br+strings.Repeat(" ", byteRangeWidth-len(br)))
+
+	// The signature of the signed bytes, with the revocation information in the signed attributes.
+	h := hash.New()
+	h.Write(output[:contentsStart])
+	h.Write(output[contentsEnd:])
+	si, err := s.sign(h.Sum(nil), hash, now, archival)
+	if err != nil {
+		return nil, err
+	}
+	cms, err := s.makeCMS(si, hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(cms) > reserve {
+		return nil, fmt.Errorf("The signature (%d bytes) does not fit in the reserved space (%d bytes)", len(cms),
+			reserve)
+	}
+	copy(output[contentsStart+1:], strings.ToUpper(hex.EncodeToString(cms)))
+
+	err = verifySignedFile(output)
+	if err != nil {
+		return nil, fmt.Errorf("Verification of the signed file failed: %v", err)
+	}
+
+	// The DSS, in a second update.  The signature value is the whole Contents string, with the padding.
+	sigValue := make([]byte, reserve)
+	copy(sigValue, cms)
+	dssUpdate, err := writeDSS(output, sigValue, vd, now)
+	if err != nil {
+		return nil, err
+	}
+	output = append(output, dssUpdate...)
+
+	err = verifyDSS(output, sigValue)
+	if err != nil {
+		return nil, fmt.Errorf("Verification of the DSS failed: %v", err)
+	}
+
+	err = ioutil.WriteFile(outputPath, output, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return vd, nil
+}
+
+// Returns the chain of the certificates, and the revocation information of each certificate except the root.
+// Missing information is reported, a revoked certificate is an error.
+func fetchValidationData(client *http.Client, certs []*x509.Certificate) (*validationData, error) {
+	vd := &validationData{}
+
+	// The chain, from the signing certificate up to a self-signed root.
+	cert := certs[0]
+	for len(vd.chain) < 10 {
+		vd.chain = append(vd.chain, cert)
+		if isSelfSigned(cert) {
+			break
+		}
+		issuer, err := findIssuer(client, cert, certs)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			vd.missing = append(vd.missing, fmt.Sprintf("the issuer of %s", cert.Subject.CommonName))
+			break
+		}
+		cert = issuer
+	}
+	for _, cert := range vd.chain {
+		fmt.Printf("Chain: %s (issued by %s, expires %s)\n", cert.Subject.CommonName, cert.Issuer.CommonName,
+			cert.NotAfter.Format("2006-01-02"))
+	}
+
+	// The revocation information of each certificate, by its issuer.  OCSP responders are checked too, unless
+	// their certificate has the no check extension.
+	type pending struct{ cert, issuer *x509.Certificate }
+	var todo []pending
+	for i := 0; i+1 < len(vd.chain); i++ {
+		todo = append(todo, pending{vd.chain[i], vd.chain[i+1]})
+	}
+	for len(todo) > 0 {
+		cert, issuer := todo[0].cert, todo[0].issuer
+		todo = todo[1:]
+
+		responder, err := vd.fetchRevocation(client, cert, issuer)
+		if _, ok := err.(errRevoked); ok {
+			return nil, err
+		}
+		if err != nil {
+			fmt.Printf("Warning: %v, LTV cannot be completed\n", err)
+			vd.missing = append(vd.missing, "the revocation status of "+cert.Subject.CommonName)
+			continue
+		}
+		if responder != nil && !hasExtension(responder, oidOCSPNoCheck) && len(vd.responders) < 10 {
+			todo = append(todo, pending{responder, issuer})
+		}
+	}
+	return vd, nil
+}
+
+// Returns whether the certificate is self-signed, a root.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// Returns whether the certificate has the extension.
+func hasExtension(cert *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the issuer of the certificate: one of the given certificates, or the certificate from its authority
+// information access URLs.
+func findIssuer(client *http.Client, cert *x509.Certificate, certs []*x509.Certificate) (*x509.Certificate,
+	error) {
+	for _, c := range certs {
+		if bytes.Equal(cert.RawIssuer, c.RawSubject) && cert.CheckSignatureFrom(c) == nil {
+			return c, nil
+		}
+	}
+	if len(cert.IssuingCertificateURL) == 0 {
+		return nil, fmt.Errorf("The issuer of %s is not in the chain and the certificate has no issuer URL",
+			cert.Subject.CommonName)
+	}
+	var lastErr error
+	for _, url := range cert.IssuingCertificateURL {
+		data, err := httpGet(client, url)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		issuer, err := parseCertificate(data)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %v", url, err)
+			continue
+		}
+		if err := cert.CheckSignatureFrom(issuer); err != nil {
+			lastErr = fmt.Errorf("%s: not the issuer of %s", url, cert.Subject.CommonName)
+			continue
+		}
+		fmt.Printf("Fetched the issuer of %s from %s\n", cert.Subject.CommonName, url)
+		return issuer, nil
+	}
+	return nil, fmt.Errorf("Cannot fetch the issuer of %s: %v", cert.Subject.CommonName, lastErr)
+}
+
+// Parses a certificate in DER or PEM form.
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	return x509.ParseCertificate(data)
+}
+
+// Fetches the revocation information of the certificate: an OCSP response, or else a CRL.  Returns the certificate
+// of a delegated OCSP responder, if any.
+func (vd *validationData) fetchRevocation(client *http.Client, cert, issuer *x509.Certificate) (*x509.Certificate,
+	error) {
+	name := cert.Subject.CommonName
+	if len(cert.OCSPServer) == 0 && len(cert.CRLDistributionPoints) == 0 {
+		return nil, fmt.Errorf("The certificate %s has no OCSP or CRL URL", name)
+	}
+
+	var lastErr error
+	for _, url := range cert.OCSPServer {
+		resp, responder, err := fetchOCSP(client, url, cert, issuer)
+		if _, ok := err.(errRevoked); ok {
+			return nil, err
+		}
+		if err != nil {
+			fmt.Printf("Warning: OCSP request for %s failed: %v\n", name, err)
+			lastErr = err
+			continue
+		}
+		fmt.Printf("OCSP response for %s from %s: good\n", name, url)
+		vd.ocsps = append(vd.ocsps, resp)
+		if responder != nil {
+			vd.responders = append(vd.responders, responder)
+			return responder, nil
+		}
+		return nil, nil
+	}
+
+	for _, url := range cert.CRLDistributionPoints {
+		crl, err := fetchCRL(client, url, cert, issuer)
+		if _, ok := err.(errRevoked); ok {
+			return nil, err
+		}
+		if err != nil {
+			fmt.Printf("Warning: CRL request for %s failed: %v\n", name, err)
+			lastErr = err
+			continue
+		}
+		fmt.Printf("CRL for %s from %s: not revoked\n", name, url)
+		vd.crls = append(vd.crls, crl)
+		return nil, nil
+	}
+	return nil, fmt.Errorf("No revocation information for %s: %v", name, lastErr)
+}
+
+// Requests the status of the certificate from an OCSP responder.  Returns the response (DER), which is signed by
+// the issuer or a responder certified by it, and the certificate of a delegated responder.
+func fetchOCSP(client *http.Client, url string, cert, issuer *x509.Certificate) ([]byte, *x509.Certificate,
+	error) {
+	id, err := makeCertID(cert, issuer)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := asn1.Marshal(ocspRequest{TBSRequest: tbsRequest{RequestList: []singleRequest{{CertID: id}}}})
+	if err != nil {
+		return nil, nil, err
+	}
+	httpResp, err := client.Post(url, "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer httpResp.Body.Close()
+	data, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("HTTP status %s", httpResp.Status)
+	}
+
+	var resp ocspResponse
+	_, err = asn1.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid OCSP response: %v", err)
+	}
+	// Status 0: successful, 1: malformed request, 2: internal error, 3: try later, 5: signature required,
+	// 6: unauthorized.
+	if resp.Status != 0 {
+		return nil, nil, fmt.Errorf("OCSP responder status %d", resp.Status)
+	}
+	if !resp.ResponseBytes.ResponseType.Equal(oidOCSPBasic) {
+		return nil, nil, errors.New("Unsupported OCSP response type")
+	}
+	var basic basicOCSPResponse
+	_, err = asn1.Unmarshal(resp.ResponseBytes.Response, &basic)
+	if err != nil {
+		return nil, nil, err
+	}
+	var rd responseData
+	_, err = asn1.Unmarshal(basic.TBSResponseData.FullBytes, &rd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The signer: the issuer, or a responder certified by the issuer for OCSP signing.
+	alg, ok := signatureAlgorithms[basic.SignatureAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, nil, fmt.Errorf("Unsupported signature algorithm %s", basic.SignatureAlgorithm.Algorithm)
+	}
+	tbs := basic.TBSResponseData.FullBytes
+	var responder *x509.Certificate
+	if issuer.CheckSignature(alg, tbs, basic.Signature.RightAlign()) != nil {
+		for _, raw := range basic.Certs {
+			c, err := x509.ParseCertificate(raw.FullBytes)
+			if err != nil || c.CheckSignature(alg, tbs, basic.Signature.RightAlign()) != nil {
+				continue
+			}
+			ocspSigning := false
+			for _, usage := range c.ExtKeyUsage {
+				ocspSigning = ocspSigning || usage == x509.ExtKeyUsageOCSPSigning
+			}
+			if c.CheckSignatureFrom(issuer) == nil && ocspSigning {
+				responder = c
+			}
+		}
+		if responder == nil {
+			return nil, nil, errors.New("The OCSP response is not signed by the issuer or an authorized responder")
+		}
+	}
+
+	now := time.Now()
+	for _, single := range rd.Responses {
+		if single.CertID.SerialNumber.Cmp(cert.SerialNumber) != 0 ||
+			!bytes.Equal(single.CertID.IssuerKeyHash, id.IssuerKeyHash) {
+			continue
+		}
+		if !single.NextUpdate.IsZero() && now.After(single.NextUpdate) {
+			return nil, nil, fmt.Errorf("The OCSP response is outdated (next update %s)",
+				single.NextUpdate.Format(time.RFC3339))
+		}
+		switch single.CertStatus.Tag {
+		case 0:
+			return data, responder, nil
+		case 1:
+			// RevokedInfo: the revocation time first.
+			var revocationTime time.Time
+			asn1.UnmarshalWithParams(single.CertStatus.Bytes, &revocationTime, "generalized")
+			return nil, nil, errRevoked{cert: cert, at: revocationTime}
+		default:
+			return nil, nil, errors.New("The certificate is unknown to the OCSP responder")
+		}
+	}
+	return nil, nil, errors.New("No status of the certificate in the OCSP response")
+}
+
+// Returns the OCSP identifier of the certificate: SHA-1 hashes of the issuer name and key, and the serial number.
+func makeCertID(cert, issuer *x509.Certificate) (certID, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	_, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki)
+	if err != nil {
+		return certID{}, err
+	}
+	nameHash := sha1.Sum(issuer.RawSubject)
+	keyHash := sha1.Sum(spki.PublicKey.RightAlign())
+	return certID{
+		HashAlgorithm:  algorithmIdentifier{Algorithm: oidSHA1, Parameters: asn1.RawValue{Tag: asn1.TagNull}},
+		IssuerNameHash: nameHash[:],
+		IssuerKeyHash:  keyHash[:],
+		SerialNumber:   cert.SerialNumber,
+	}, nil
+}
+
+// Fetches the CRL and checks that it is signed by the issuer, up to date, and does not revoke the certificate.
+// Returns the CRL (DER).
+func fetchCRL(client *http.Client, url string, cert, issuer *x509.Certificate) ([]byte, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("Unsupported CRL URL %s", url)
+	}
+	data, err := httpGet(client, url)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid CRL: %v", err)
+	}
+	err = crl.CheckSignatureFrom(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("The CRL is not signed by the issuer: %v", err)
+	}
+	if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+		return nil, fmt.Errorf("The CRL is outdated (next update %s)", crl.NextUpdate.Format(time.RFC3339))
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return nil, errRevoked{cert: cert, at: entry.RevocationTime}
+		}
+	}
+	return data, nil
+}
+
+// Returns the body of a GET request.
+func httpGet(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP status %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Returns the value of the adbe-revocationInfoArchival attribute (DER) with the OCSP responses and CRLs, or nil if
+// there are none.
+func (vd *validationData) revocationArchival() ([]byte, error) {
+	if len(vd.ocsps) == 0 && len(vd.crls) == 0 {
+		return nil, nil
+	}
+	var ria revocationInfoArchival
+	for _, crl := range vd.crls {
+		ria.CRLs = append(ria.CRLs, asn1.RawValue{FullBytes: crl})
+	}
+	for _, resp := range vd.ocsps {
+		ria.OCSPs = append(ria.OCSPs, asn1.RawValue{FullBytes: resp})
+	}
+	return asn1.Marshal(ria)
+}
+
+// Returns the incremental update with the DSS of the signed file: the certificates, OCSP responses and CRLs as
+// streams, and the VRI of the signature.  An existing DSS is extended.
+func writeDSS(output, sigValue []byte, vd *validationData, t time.Time) ([]byte, error) {
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(output))
+	if err != nil {
+		return nil, err
+	}
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return nil, err
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return nil, errors.New("Missing Size in trailer")
+	}
+	nextNum := int64(*size)
+	catalogObj, catalog := resolve(pdfReader, trailer.Get("Root"))
+	catalogDict, ok := catalog.(*pdfcore.PdfObjectDictionary)
+	if !ok || catalogObj == nil {
+		return nil, errors.New("Missing catalog")
+	}
+
+	// The DSS: an existing one is extended with the new items, and the items already in it are reused.
+	dssObj, dss := resolve(pdfReader, catalogDict.Get("DSS"))
+	dssDict, ok := dss.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		dssDict = pdfcore.MakeDict()
+		dssObj = nil
+	}
+
+	var objects []updateObject
+	addStreams := func(key pdfcore.PdfObjectName, items [][]byte, description string) (*pdfcore.PdfObjectArray,
+		error) {
+		all := pdfcore.MakeArray()
+		existing := map[[sha1.Size]byte]*pdfcore.PdfObjectReference{}
+		_, existingObj := resolve(pdfReader, dssDict.Get(key))
+		if existingArr, ok := existingObj.(*pdfcore.PdfObjectArray); ok {
+			for _, item := range *existingArr {
+				_, obj := resolve(pdfReader, item)
+				stream, ok := obj.(*pdfcore.PdfObjectStream)
+				if !ok {
+					continue
+				}
+				all.Append(item)
+				if decoded, err := pdfcore.DecodeStream(stream); err == nil {
+					existing[sha1.Sum(decoded)] = &pdfcore.PdfObjectReference{ObjectNumber: stream.ObjectNumber,
+						GenerationNumber: stream.GenerationNumber}
+				}
+			}
+		}
+
+		refs := pdfcore.MakeArray()
+		for _, item := range items {
+			if ref, ok := existing[sha1.Sum(item)]; ok {
+				refs.Append(ref)
+				continue
+			}
+			stream, err := pdfcore.MakeStream(item, pdfcore.NewFlateEncoder())
+			if err != nil {
+				return nil, err
+			}
+			ref := &pdfcore.PdfObjectReference{ObjectNumber: nextNum}
+			objects = append(objects, updateObject{number: nextNum, obj: stream, description: description})
+			refs.Append(ref)
+			all.Append(ref)
+			existing[sha1.Sum(item)] = ref
+			nextNum++
+		}
+		if len(*all) > 0 {
+			dssDict.Set(key, all)
+		}
+		return refs, nil
+	}
+	var certs [][]byte
+	for _, cert := range append(append([]*x509.Certificate{}, vd.chain...), vd.responders...) {
+		certs = append(certs, cert.Raw)
+	}
+	certRefs, err := addStreams("Certs", certs, "DSS certificate")
+	if err != nil {
+		return nil, err
+	}
+	ocspRefs, err := addStreams("OCSPs", vd.ocsps, "DSS OCSP response")
+	if err != nil {
+		return nil, err
+	}
+	crlRefs, err := addStreams("CRLs", vd.crls, "DSS CRL")
+	if err != nil {
+		return nil, err
+	}
+
+	// The VRI of the signature, keyed by the SHA-1 hash of the signature value (uppercase hex).
+	vri := pdfcore.MakeDict()
+	vri.Set("Cert", certRefs)
+	if len(*ocspRefs) > 0 {
+		vri.Set("OCSP", ocspRefs)
+	}
+	if len(*crlRefs) > 0 {
+		vri.Set("CRL", crlRefs)
+	}
+	vri.Set("TU", pdfcore.MakeString(formatPdfDate(t)))
+	_, vris := resolve(pdfReader, dssDict.Get("VRI"))
+	vriDict, ok := vris.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		vriDict = pdfcore.MakeDict()
+		dssDict.Set("VRI", vriDict)
+	}
+	key := sha1.Sum(sigValue)
+	vriDict.Set(pdfcore.PdfObjectName(strings.ToUpper(hex.EncodeToString(key[:]))), vri)
+
+	if dssObj != nil {
+		objects = append(objects, updateObject{number: dssObj.ObjectNumber, generation: dssObj.GenerationNumber,
+			obj: dssDict, description: "DSS"})
+	} else {
+		objects = append(objects, updateObject{number: nextNum, obj: dssDict, description: "DSS"})
+		catalogDict.Set("DSS", &pdfcore.PdfObjectReference{ObjectNumber: nextNum})
+		nextNum++
+	}
+
+	// The extension of PDF 1.7 with the DSS (ETSI, extension level 5).
+	_, ext := resolve(pdfReader, catalogDict.Get("Extensions"))
+	extDict, ok := ext.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		extDict = pdfcore.MakeDict()
+	}
+	esic := pdfcore.MakeDict()
+	esic.Set("BaseVersion", pdfcore.MakeName("1.7"))
+	esic.Set("ExtensionLevel", pdfcore.MakeInteger(5))
+	extDict.Set("ESIC", esic)
+	catalogDict.Set("Extensions", extDict)
+	if v, ok := pdfcore.TraceToDirectObject(catalogDict.Get("Version")).(*pdfcore.PdfObjectName); !ok || *v < "1.7" {
+		catalogDict.Set("Version", pdfcore.MakeName("1.7"))
+	}
+	objects = append(objects, updateObject{number: catalogObj.ObjectNumber, generation: catalogObj.GenerationNumber,
+		obj: catalogDict, description: "catalog"})
+
+	update, err := writeUpdate(output, objects, trailer, nextNum)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objects {
+		fmt.Printf("Updated object %d: %s\n", obj.number, obj.description)
+	}
+	return update, nil
+}
+
+// Verifies that the DSS of the file has the VRI of the signature, with the certificates and revocation information.
+func verifyDSS(output, sigValue []byte) error {
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(output))
+	if err != nil {
+		return err
+	}
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	_, catalog := resolve(pdfReader, trailer.Get("Root"))
+	catalogDict, ok := catalog.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Missing catalog")
+	}
+	_, dss := resolve(pdfReader, catalogDict.Get("DSS"))
+	dssDict, ok := dss.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Missing DSS")
+	}
+	_, vris := resolve(pdfReader, dssDict.Get("VRI"))
+	vriDict, ok := vris.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Missing VRI")
+	}
+	key := sha1.Sum(sigValue)
+	_, vri := resolve(pdfReader, vriDict.Get(pdfcore.PdfObjectName(strings.ToUpper(hex.EncodeToString(key[:])))))
+	vriEntry, ok := vri.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Missing VRI of the signature")
+	}
+
+	counts := []string{}
+	for _, key := range []pdfcore.PdfObjectName{"Cert", "OCSP", "CRL"} {
+		_, obj := resolve(pdfReader, vriEntry.Get(key))
+		arr, _ := obj.(*pdfcore.PdfObjectArray)
+		n := 0
+		if arr != nil {
+			for _, item := range *arr {
+				_, stream := resolve(pdfReader, item)
+				if _, ok := stream.(*pdfcore.PdfObjectStream); !ok {
+					return fmt.Errorf("Invalid %s entry in the VRI", key)
+				}
+				n++
+			}
+		}
+		counts = append(counts, fmt.Sprintf("%d %s", n, key))
+	}
+	fmt.Printf("Verified: DSS with the VRI of the signature (%s)\n", strings.Join(counts, ", "))
+	return nil
+}
+
+// Verifies the last signature of the signed file: the byte range covers the whole file except the signature value,
+// and the CMS signature is valid for the signed bytes.
+func verifySignedFile(output []byte) error {
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(output))
+	if err != nil {
+		return err
+	}
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	_, catalogObj := resolve(pdfReader, trailer.Get("Root"))
+	catalog, ok := catalogObj.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Missing catalog")
+	}
+
+	// The signature covering the whole file.
+	var sigDict *pdfcore.PdfObjectDictionary
+	var byteRange []int64
+	var visit func(obj pdfcore.PdfObject, depth int)
+	visit = func(obj pdfcore.PdfObject, depth int) {
+		_, direct := resolve(pdfReader, obj)
+		field, ok := direct.(*pdfcore.PdfObjectDictionary)
+		if !ok || depth > 32 {
+			return
+		}
+		if _, v := resolve(pdfReader, field.Get("V")); v != nil {
+			if d, ok := v.(*pdfcore.PdfObjectDictionary); ok {
+				if arr, ok := pdfcore.TraceToDirectObject(d.Get("ByteRange")).(*pdfcore.PdfObjectArray); ok {
+					if values, err := arr.ToIntegerArray(); err == nil && len(values) == 4 &&
+						values[2]+values[3] == len(output) {
+						sigDict = d
+						byteRange = []int64{int64(values[0]), int64(values[1]), int64(values[2]),
+							int64(values[3])}
+					}
+				}
+			}
+		}
+		_, kidsObj := resolve(pdfReader, field.Get("Kids"))
+		if kids, ok := kidsObj.(*pdfcore.PdfObjectArray); ok {
+			for _, kid := range *kids {
+				visit(kid, depth+1)
+			}
+		}
+	}
+	_, formObj := resolve(pdfReader, catalog.Get("AcroForm"))
+	if form, ok := formObj.(*pdfcore.PdfObjectDictionary); ok {
+		_, fieldsObj := resolve(pdfReader, form.Get("Fields"))
+		if fields, ok := fieldsObj.(*pdfcore.PdfObjectArray); ok {
+			for _, obj := range *fields {
+				visit(obj, 0)
+			}
+		}
+	}
+	if sigDict == nil {
+		return errors.New("No signature covering the whole file")
+	}
+	if byteRange[0] != 0 || byteRange[1] >= byteRange[2] {
+		return fmt.Errorf("Invalid byte range %v", byteRange)
+	}
+	contents, ok := pdfcore.TraceToDirectObject(sigDict.Get("Contents")).(*pdfcore.PdfObjectString)
+	if !ok {
+		return errors.New("Missing signature value")
+	}
+
+	sd, certs, err := parseSignedData([]byte(*contents))
+	if err != nil {
+		return err
+	}
+	var signed []byte
+	signed = append(signed, output[:byteRange[1]]...)
+	signed = append(signed, output[byteRange[2]:]...)
+	cert, err := verifySignerInfo(sd, certs, signed, oidData)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Verified: signature by %s covering the whole file (%d bytes signed)\n", cert.Subject.CommonName,
+		len(signed))
+
+	// The chain in the signature, up to its root.
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	for _, c := range certs {
+		if isSelfSigned(c) {
+			roots.AddCert(c)
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+	if err != nil {
+		fmt.Printf("Note: the certificate chain in the signature is incomplete: %v\n", err)
+	}
+	return nil
+}
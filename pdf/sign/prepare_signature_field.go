@@ -0,0 +1,328 @@
+/*
+ * Add empty signature fields to a PDF file, with a visible "Sign here" placeholder, so that the document is ready to
+ * be signed later with another tool.
+ *
+ * A signature field (FT Sig) without a value (V) is an unsigned signature field: viewers and signing tools list it
+ * and offer to sign it, and signing fills in the value with the signature dictionary.  The field has a widget
+ * annotation, the area on the page where the signature is shown, with an appearance stream: a dashed box with the
+ * "Sign here" label and a signature line.  The appearance is only the placeholder: the signing tool replaces it with
+ * the appearance of the signature.  The name of the signer is drawn on the page below the box, so it remains after
+ * signing.
+ *
+ * There is a field for each signer (-signers), for signing by several parties one after the other.  Each field has
+ * a distinct name, made from the name of the signer (e.g. Signature_Buyer), and the signer in its tooltip (TU).  The
+ * names are made unique if needed, including against the fields of an existing form in the input, which is kept.
+ * Creating all the fields before the first signature matters: each signature is then an incremental update that only
+ * fills in its own field, and a first signer can certify the document while still allowing the others to sign.
+ *
+ * The placeholders are laid out two per row at the bottom of a page (-page, the last page by default), over the
+ * existing content, or on a new page appended for the signatures (-newpage) when there is no room.
+ *
+ * Run as: go run prepare_signature_field.go [-signers "Buyer,Seller"] [-page 0] [-newpage] input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run prepare_signature_field.go [-signers \"Buyer,Seller\"] [-page 0] [-newpage] " +
+	"input.pdf output.pdf\n"
+
+// Annotation flags.
+const annotFlagPrint = 4
+
+// Layout of the placeholders, in points.
+const (
+	margin        = 72.0
+	boxWidth      = 200.0
+	boxHeight     = 56.0
+	captionHeight = 14.0
+	rowSpacing    = 24.0
+	labelSize     = 9.0
+)
+
+var captionColor = creator.ColorRGBFrom8bit(60, 60, 60)
+
+// Characters not used in field names: the period separates the parts of a full name.
+var nameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func main() {
+	signers := ""
+	pageNum := 0
+	newPage := false
+	flag.StringVar(&signers, "signers", "Signer", "Comma separated names of the signers, one field for each")
+	flag.IntVar(&pageNum, "page", 0, "Page for the signature fields (0: the last page)")
+	flag.BoolVar(&newPage, "newpage", false, "Add the signature fields on a new page at the end")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var names []string
+	for _, s := range strings.Split(signers, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			names = append(names, s)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Printf("Error: no signers\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := prepareSignatureFields(inputPath, outputPath, names, pageNum, newPage)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func prepareSignatureFields(inputPath, outputPath string, signers []string, pageNum int, newPage bool) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if pageNum == 0 {
+		pageNum = numPages
+	}
+	if pageNum < 1 || pageNum > numPages {
+		return fmt.Errorf("Page %d out of range (1-%d)", pageNum, numPages)
+	}
+
+	// Keep the fields of an existing form, the new names must differ from theirs.
+	form := pdfReader.AcroForm
+	if form == nil {
+		form = pdf.NewPdfAcroForm()
+	}
+	if form.Fields == nil {
+		form.Fields = &[]*pdf.PdfField{}
+	}
+	used := map[string]bool{}
+	for _, field := range *form.Fields {
+		if t, ok := pdfcore.TraceToDirectObject(field.T).(*pdfcore.PdfObjectString); ok {
+			used[string(*t)] = true
+		}
+	}
+
+	// The creator draws on the last page added, so the fields are added right after their page.
+	c := creator.New()
+	var lastPage *pdf.PdfPage
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+		err = c.AddPage(page)
+		if err != nil {
+			return err
+		}
+		if i+1 == pageNum && !newPage {
+			err = addSignatureFields(c, page, form, signers, used, false)
+			if err != nil {
+				return err
+			}
+		}
+		lastPage = page
+	}
+
+	if newPage {
+		// A page of the same size as the last page.
+		mbox, err := lastPage.GetMediaBox()
+		if err != nil {
+			return err
+		}
+		page := pdf.NewPdfPage()
+		page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: mbox.Urx - mbox.Llx, Ury: mbox.Ury - mbox.Lly}
+		page.Resources = pdf.NewPdfPageResources()
+		err = c.AddPage(page)
+		if err != nil {
+			return err
+		}
+
+		heading := creator.NewParagraph("Signatures")
+		heading.SetFont(fonts.NewFontHelveticaBold())
+		heading.SetFontSize(16)
+		heading.SetPos(margin, margin)
+		err = c.Draw(heading)
+		if err != nil {
+			return err
+		}
+
+		err = addSignatureFields(c, page, form, signers, used, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.SetForms(form)
+	if err != nil {
+		return err
+	}
+	return c.WriteToFile(outputPath)
+}
+
+// Adds a signature field for each signer to the page and the form, with a placeholder widget and a caption, two per
+// row.  The rows are at the top of the page (below a heading) if atTop, otherwise at the bottom.
+func addSignatureFields(c *creator.Creator, page *pdf.PdfPage, form *pdf.PdfAcroForm, signers []string,
+	used map[string]bool, atTop bool) error {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return err
+	}
+	pageWidth := mbox.Urx - mbox.Llx
+	pageHeight := mbox.Ury - mbox.Lly
+
+	cols := 2
+	if pageWidth < 2*margin+2*boxWidth+rowSpacing {
+		cols = 1
+	}
+	rows := (len(signers) + cols - 1) / cols
+	rowHeight := boxHeight + captionHeight + rowSpacing
+	// Top of the first row, from the top of the page.
+	top := margin + 40
+	if !atTop {
+		top = pageHeight - margin - float64(rows)*rowHeight + rowSpacing
+	}
+	if top < margin || top+float64(rows)*rowHeight-rowSpacing > pageHeight-margin {
+		return fmt.Errorf("No room for %d signature fields on the page, use -newpage", len(signers))
+	}
+
+	appearance, err := makePlaceholderAppearance()
+	if err != nil {
+		return err
+	}
+
+	for i, signer := range signers {
+		x := margin
+		if i%cols == 1 {
+			x = pageWidth - margin - boxWidth
+		}
+		y := top + float64(i/cols)*rowHeight
+
+		name := uniqueFieldName(signer, used)
+		field := pdf.NewPdfField()
+		field.FT = pdfcore.MakeName("Sig")
+		field.T = pdfcore.MakeString(name)
+		field.TU = pdfcore.MakeString("Signature of " + signer)
+
+		ap := pdfcore.MakeDict()
+		ap.Set("N", appearance)
+
+		widget := pdf.NewPdfAnnotationWidget()
+		// The annotation rectangle is in PDF coordinates, with the origin in the lower left corner.
+		widget.Rect = pdfcore.MakeArrayFromFloats([]float64{mbox.Llx + x, mbox.Ury - y - boxHeight,
+			mbox.Llx + x + boxWidth, mbox.Ury - y})
+		widget.F = pdfcore.MakeInteger(annotFlagPrint)
+		widget.P = page.GetPageAsIndirectObject()
+		widget.Parent = field.GetContainingPdfObject()
+		widget.AP = ap
+		field.KidsA = append(field.KidsA, widget.PdfAnnotation)
+		page.Annotations = append(page.Annotations, widget.PdfAnnotation)
+		*form.Fields = append(*form.Fields, field)
+
+		caption := creator.NewParagraph(signer)
+		caption.SetFont(fonts.NewFontHelvetica())
+		caption.SetFontSize(labelSize)
+		caption.SetColor(captionColor)
+		caption.SetEnableWrap(false)
+		caption.SetPos(x, y+boxHeight+3)
+		err := c.Draw(caption)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Signature field %s for %s on the page\n", name, signer)
+	}
+	return nil
+}
+
+// Returns a field name for the signer, Signature_ followed by the signer name, that is not used yet.  The name is
+// added to the used names.
+func uniqueFieldName(signer string, used map[string]bool) string {
+	base := "Signature"
+	if s := strings.Trim(nameChars.ReplaceAllString(signer, "_"), "_"); s != "" {
+		base += "_" + s
+	}
+	name := base
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	used[name] = true
+	return name
+}
+
+// Returns the placeholder appearance of the signature widgets: a light dashed box with the "Sign here" label and a
+// signature line.  All the widgets share it.
+func makePlaceholderAppearance() (*pdfcore.PdfObjectStream, error) {
+	resources := pdf.NewPdfPageResources()
+	err := resources.SetFontByName("Helv", fonts.NewFontHelvetica().ToPdfObject())
+	if err != nil {
+		return nil, err
+	}
+
+	content := fmt.Sprintf("q 1 0.98 0.86 rg 0 0 %.2f %.2f re f Q\n", boxWidth, boxHeight) +
+		fmt.Sprintf("q 0.85 0.55 0.1 RG 1 w [4 3] 0 d 0.5 0.5 %.2f %.2f re S Q\n", boxWidth-1, boxHeight-1) +
+		fmt.Sprintf("q 0.4 G 0.5 w 24 14 m %.2f 14 l S Q\n", boxWidth-10) +
+		"BT 0.4 g /Helv 12 Tf 10 16 Td (X) Tj ET\n" +
+		fmt.Sprintf("BT 0.75 0.45 0.05 rg /Helv %g Tf 10 %.2f Td (Sign here) Tj ET\n", labelSize,
+			boxHeight-labelSize-6)
+
+	xform := pdf.NewXObjectForm()
+	xform.BBox = pdfcore.MakeArrayFromFloats([]float64{0, 0, boxWidth, boxHeight})
+	xform.Resources = resources
+	err = xform.SetContentStream([]byte(content), nil)
+	if err != nil {
+		return nil, err
+	}
+	return xform.ToPdfObject().(*pdfcore.PdfObjectStream), nil
+}
@@ -0,0 +1,878 @@
+/*
+ * Signing code shared by the signature examples timestamp.go and ltv.go, which are run together with this file:
+ *   go run timestamp.go signing.go ...
+ *   go run ltv.go signing.go ...
+ *
+ * It has the lookup of the signature field (or the invisible field added when there is none), the signing key and
+ * certificate, from PEM files or a generated test certificate, and the CMS (PKCS#7) signature: the ASN.1 structures,
+ * signing and verification.  The incremental update writer (updateObject, writeUpdate and previousXrefOffset) is a
+ * copy of pdf/incremental/update.go: changes to it are made there and copied here, and pdf/testing/check_copies.go
+ * checks that the copies are the same.
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+// Width of the ByteRange placeholder.
+const byteRangeWidth = 40
+
+// Annotation flags: print, and locked for the invisible signature widget.
+const (
+	annotFlagPrint  = 4
+	annotFlagLocked = 128
+)
+
+// Signature flags of the form (SigFlags): the document has signatures, and must only be changed by incremental
+// updates.
+const sigFlags = 3
+
+var (
+	oidData                   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidContentType            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSigningTime            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidRevocationInfoArchival = asn1.ObjectIdentifier{1, 2, 840, 113583, 1, 1, 8}
+	oidRSAEncryption          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidRSASSAPSS              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 10}
+	oidECDSAWithSHA256        = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSAWithSHA384        = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSAWithSHA512        = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+)
+
+// The hash algorithms by name, and the digest algorithms of CMS by OID.  SHA-1 is accepted when verifying (e.g. in
+// timestamp tokens), but not for signing.
+var hashAlgorithms = map[string]crypto.Hash{
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+var digestAlgorithms = map[string]crypto.Hash{
+	"1.3.14.3.2.26":          crypto.SHA1,
+	"2.16.840.1.101.3.4.2.1": crypto.SHA256,
+	"2.16.840.1.101.3.4.2.2": crypto.SHA384,
+	"2.16.840.1.101.3.4.2.3": crypto.SHA512,
+}
+
+// ASN.1 structures of CMS (RFC 5652).
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional,tag:0"` // [0] EXPLICIT content.
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []algorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional,tag:0"` // [0] EXPLICIT content.
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue // IssuerAndSerialNumber, or [0] SubjectKeyIdentifier.
+	DigestAlgorithm    algorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm algorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue // SET of values.
+}
+
+// signer is the signing key with its certificate chain.
+type signer struct {
+	key   crypto.Signer
+	certs []*x509.Certificate // The signing certificate first.
+}
+
+// sigField is an unsigned signature field with its widgets.
+type sigField struct {
+	name    string
+	obj     *pdfcore.PdfIndirectObject
+	dict    *pdfcore.PdfObjectDictionary
+	widgets []sigWidget
+}
+
+type sigWidget struct {
+	obj  *pdfcore.PdfIndirectObject
+	dict *pdfcore.PdfObjectDictionary
+}
+
+// updateObject is an object written in the incremental update.
+type updateObject struct {
+	number      int64
+	generation  int64
+	obj         pdfcore.PdfObject
+	description string
+}
+
+// rawObject is an object written as is, for the signature dictionary with placeholders.
+type rawObject string
+
+func (r rawObject) String() string             { return string(r) }
+func (r rawObject) DefaultWriteString() string { return string(r) }
+
+// Returns the unsigned signature field with the name, or the first one if name is "".  Returns nil if there is none.
+func findSignatureField(pdfReader *pdf.PdfReader, form *pdfcore.PdfObjectDictionary, name string) (*sigField,
+	error) {
+	_, fieldsObj := resolve(pdfReader, form.Get("Fields"))
+	fields, ok := fieldsObj.(*pdfcore.PdfObjectArray)
+	if !ok {
+		return nil, nil
+	}
+
+	var found *sigField
+	var walk func(objs pdfcore.PdfObjectArray, parentName, parentType string, depth int)
+	walk = func(objs pdfcore.PdfObjectArray, parentName, parentType string, depth int) {
+		for _, obj := range objs {
+			ind, direct := resolve(pdfReader, obj)
+			dict, ok := direct.(*pdfcore.PdfObjectDictionary)
+			if !ok || found != nil || depth > 32 {
+				continue
+			}
+			t, ok := pdfcore.TraceToDirectObject(dict.Get("T")).(*pdfcore.PdfObjectString)
+			if !ok {
+				// A widget.
+				continue
+			}
+			fullName := string(*t)
+			if parentName != "" {
+				fullName = parentName + "." + fullName
+			}
+			fieldType := parentType
+			if ft, ok := pdfcore.TraceToDirectObject(dict.Get("FT")).(*pdfcore.PdfObjectName); ok {
+				fieldType = string(*ft)
+			}
+
+			var widgets []sigWidget
+			_, kidsObj := resolve(pdfReader, dict.Get("Kids"))
+			kids, _ := kidsObj.(*pdfcore.PdfObjectArray)
+			if kids != nil {
+				for _, kid := range *kids {
+					kidInd, kidDirect := resolve(pdfReader, kid)
+					kidDict, ok := kidDirect.(*pdfcore.PdfObjectDictionary)
+					if ok && kidDict.Get("T") == nil && kidInd != nil {
+						widgets = append(widgets, sigWidget{obj: kidInd, dict: kidDict})
+					}
+				}
+				walk(*kids, fullName, fieldType, depth+1)
+			} else if ind != nil {
+				widgets = append(widgets, sigWidget{obj: ind, dict: dict})
+			}
+
+			if fieldType != "Sig" || dict.Get("V") != nil || (name != "" && name != fullName) || ind == nil {
+				continue
+			}
+			if found == nil {
+				found = &sigField{name: fullName, obj: ind, dict: dict, widgets: widgets}
+			}
+		}
+	}
+	walk(*fields, "", "", 0)
+	return found, nil
+}
+
+// Adds an invisible signature field, with its widget on the first page, to the form.  Returns the objects to update.
+func addInvisibleField(pdfReader *pdf.PdfReader, form *pdfcore.PdfObjectDictionary, sigRef *pdfcore.PdfObjectReference,
+	fieldNum int64) ([]updateObject, error) {
+	pageObj, err := pdfReader.GetPageAsIndirectObject(1)
+	if err != nil {
+		return nil, err
+	}
+	pageInd, ok := pageObj.(*pdfcore.PdfIndirectObject)
+	if !ok {
+		return nil, errors.New("Page not an indirect object")
+	}
+	pageDict, ok := pageInd.PdfObject.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil, errors.New("Page not a dictionary")
+	}
+
+	// A unique name.
+	used := map[string]bool{}
+	_, fieldsObj := resolve(pdfReader, form.Get("Fields"))
+	fields, _ := fieldsObj.(*pdfcore.PdfObjectArray)
+	if fields != nil {
+		for _, obj := range *fields {
+			_, direct := resolve(pdfReader, obj)
+			if dict, ok := direct.(*pdfcore.PdfObjectDictionary); ok {
+				if t, ok := pdfcore.TraceToDirectObject(dict.Get("T")).(*pdfcore.PdfObjectString); ok {
+					used[string(*t)] = true
+				}
+			}
+		}
+	}
+	name := "Signature1"
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("Signature%d", i)
+	}
+	fmt.Printf("Signing a new invisible field %s\n", name)
+
+	// The field and its widget in one dictionary, with an empty rectangle.
+	field := pdfcore.MakeDict()
+	field.Set("FT", pdfcore.MakeName("Sig"))
+	field.Set("T", pdfcore.MakeString(name))
+	field.Set("V", sigRef)
+	field.Set("Type", pdfcore.MakeName("Annot"))
+	field.Set("Subtype", pdfcore.MakeName("Widget"))
+	field.Set("Rect", pdfcore.MakeArrayFromIntegers([]int{0, 0, 0, 0}))
+	field.Set("F", pdfcore.MakeInteger(annotFlagPrint|annotFlagLocked))
+	field.Set("P", pageInd)
+	fieldRef := &pdfcore.PdfObjectReference{ObjectNumber: fieldNum}
+	objects := []updateObject{{number: fieldNum, obj: field, description: "signature field"}}
+
+	// The form fields: in the form dictionary, or a separate array object.
+	if ind, ok := form.Get("Fields").(*pdfcore.PdfIndirectObject); ok && fields != nil {
+		*fields = append(*fields, fieldRef)
+		objects = append(objects, updateObject{number: ind.ObjectNumber, generation: ind.GenerationNumber,
+			obj: fields, description: "form fields"})
+	} else if fields != nil && form.Get("Fields") == pdfcore.PdfObject(fields) {
+		*fields = append(*fields, fieldRef)
+	} else if fields == nil {
+		form.Set("Fields", pdfcore.MakeArray(fieldRef))
+	} else {
+		return nil, errors.New("Unsupported Fields array")
+	}
+
+	// The page annotations: in the page dictionary, or a separate array object.
+	annotsInd, annotsObj := resolve(pdfReader, pageDict.Get("Annots"))
+	switch annots := annotsObj.(type) {
+	case nil:
+		pageDict.Set("Annots", pdfcore.MakeArray(fieldRef))
+		objects = append(objects, updateObject{number: pageInd.ObjectNumber, generation: pageInd.GenerationNumber,
+			obj: pageDict, description: "page"})
+	case *pdfcore.PdfObjectArray:
+		*annots = append(*annots, fieldRef)
+		if annotsInd != nil {
+			objects = append(objects, updateObject{number: annotsInd.ObjectNumber,
+				generation: annotsInd.GenerationNumber, obj: annots, description: "annotations array"})
+		} else {
+			objects = append(objects, updateObject{number: pageInd.ObjectNumber,
+				generation: pageInd.GenerationNumber, obj: pageDict, description: "page"})
+		}
+	default:
+		return nil, fmt.Errorf("Invalid Annots (%T)", annots)
+	}
+	return objects, nil
+}
+
+// Returns the appearance of a signed widget, with the signer and the date, or nil for an invisible widget.
+func makeSignedAppearance(widget *pdfcore.PdfObjectDictionary, signerName string, t time.Time) (
+	*pdfcore.PdfObjectStream, error) {
+	rectArr, ok := pdfcore.TraceToDirectObject(widget.Get("Rect")).(*pdfcore.PdfObjectArray)
+	if !ok {
+		return nil, nil
+	}
+	rect, err := rectArr.ToFloat64Array()
+	if err != nil || len(rect) != 4 {
+		return nil, err
+	}
+	width := rect[2] - rect[0]
+	height := rect[3] - rect[1]
+	if width < 0 {
+		width = -width
+	}
+	if height < 0 {
+		height = -height
+	}
+	if width < 1 || height < 1 {
+		return nil, nil
+	}
+
+	size := height / 4
+	if size > 10 {
+		size = 10
+	}
+	content := fmt.Sprintf("q 0.94 0.96 1 rg 0 0 %.2f %.2f re f Q\n", width, height) +
+		fmt.Sprintf("BT 0 g /Helv %.2f Tf 6 %.2f Td %s Tj 0 %.2f Td %s Tj ET\n", size, height-size-6,
+			pdfcore.MakeString("Digitally signed by "+signerName).DefaultWriteString(), -1.4*size,
+			pdfcore.MakeString("Date: "+t.Format("2006-01-02 15:04:05 -07:00")).DefaultWriteString())
+
+	font := pdfcore.MakeDict()
+	font.Set("Type", pdfcore.MakeName("Font"))
+	font.Set("Subtype", pdfcore.MakeName("Type1"))
+	font.Set("BaseFont", pdfcore.MakeName("Helvetica"))
+	font.Set("Encoding", pdfcore.MakeName("WinAnsiEncoding"))
+	fonts := pdfcore.MakeDict()
+	fonts.Set("Helv", font)
+	resources := pdfcore.MakeDict()
+	resources.Set("Font", fonts)
+
+	stream, err := pdfcore.MakeStream([]byte(content), nil)
+	if err != nil {
+		return nil, err
+	}
+	stream.Set("Type", pdfcore.MakeName("XObject"))
+	stream.Set("Subtype", pdfcore.MakeName("Form"))
+	stream.Set("BBox", pdfcore.MakeArrayFromFloats([]float64{0, 0, width, height}))
+	stream.Set("Resources", resources)
+	return stream, nil
+}
+
+// Loads the signing certificate chain and private key from PEM files.
+func loadSigner(certPath, keyPath string) (*signer, error) {
+	certData, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &signer{}
+	for {
+		var block *pem.Block
+		block, certData = pem.Decode(certData)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		s.certs = append(s.certs, cert)
+	}
+	if len(s.certs) == 0 {
+		return nil, fmt.Errorf("No certificate in %s", certPath)
+	}
+
+	keyData, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyData)
+	if block == nil {
+		return nil, fmt.Errorf("No PEM data in %s", keyPath)
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s.key = k
+	case *ecdsa.PrivateKey:
+		s.key = k
+	default:
+		return nil, fmt.Errorf("Unsupported key type %T (RSA or ECDSA required)", key)
+	}
+	return s, nil
+}
+
+// Returns a signer with a new RSA key and a self-signed certificate, for testing.
+func newTestSigner() (*signer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Example Signer", Organization: []string{"Example"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageContentCommitment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &signer{key: key, certs: []*x509.Certificate{cert}}, nil
+}
+
+// Returns the signer info with the signature of the digest of the signed bytes.  The signed attributes are the
+// content type, the signing time, the digest and the revocation information (if not nil), and the signature is of
+// their DER encoding.
+func (s *signer) sign(digest []byte, hash crypto.Hash, t time.Time, revocation []byte) (*signerInfo, error) {
+	type attr struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}
+	list := []attr{{oidContentType, oidData}, {oidSigningTime, t.UTC()}, {oidMessageDigest, digest}}
+	if revocation != nil {
+		list = append(list, attr{oidRevocationInfoArchival, asn1.RawValue{FullBytes: revocation}})
+	}
+	var attrs [][]byte
+	for _, a := range list {
+		value, err := asn1.Marshal(a.value)
+		if err != nil {
+			return nil, err
+		}
+		attr, err := asn1.Marshal(attribute{Type: a.oid, Values: asn1.RawValue{Class: asn1.ClassUniversal,
+			Tag: asn1.TagSet, IsCompound: true, Bytes: value}})
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+	}
+	// DER: the elements of a SET OF are sorted by their encoding.
+	sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+	signedAttrs := bytes.Join(attrs, nil)
+
+	setDER, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true,
+		Bytes: signedAttrs})
+	if err != nil {
+		return nil, err
+	}
+	h := hash.New()
+	h.Write(setDER)
+	signature, err := s.key.Sign(rand.Reader, h.Sum(nil), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	sid, err := asn1.Marshal(issuerAndSerial{Issuer: asn1.RawValue{FullBytes: s.certs[0].RawIssuer},
+		Serial: s.certs[0].SerialNumber})
+	if err != nil {
+		return nil, err
+	}
+	sigAlg, err := signatureAlgorithmIdentifier(s.key, hash)
+	if err != nil {
+		return nil, err
+	}
+	return &signerInfo{
+		Version:            1,
+		SID:                asn1.RawValue{FullBytes: sid},
+		DigestAlgorithm:    digestAlgorithmIdentifier(hash),
+		SignedAttrs:        asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedAttrs},
+		SignatureAlgorithm: sigAlg,
+		Signature:          signature,
+	}, nil
+}
+
+// Returns the CMS signed data (DER) with the signer info and the certificates, without the signed content.
+func (s *signer) makeCMS(si *signerInfo, hash crypto.Hash) ([]byte, error) {
+	var certs []byte
+	for _, cert := range s.certs {
+		certs = append(certs, cert.Raw...)
+	}
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []algorithmIdentifier{digestAlgorithmIdentifier(hash)},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos:      []signerInfo{*si},
+	}
+	content, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{ContentType: oidSignedData, Content: asn1.RawValue{
+		Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content}})
+}
+
+// Returns the algorithm identifier of the hash algorithm.
+func digestAlgorithmIdentifier(hash crypto.Hash) algorithmIdentifier {
+	for oid, h := range digestAlgorithms {
+		if h == hash {
+			return algorithmIdentifier{Algorithm: parseOID(oid)}
+		}
+	}
+	return algorithmIdentifier{}
+}
+
+// Returns the signature algorithm identifier for the key: RSA (PKCS#1 v1.5) or ECDSA with the hash.
+func signatureAlgorithmIdentifier(key crypto.Signer, hash crypto.Hash) (algorithmIdentifier, error) {
+	switch key.(type) {
+	case *rsa.PrivateKey:
+		return algorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.RawValue{Tag: asn1.TagNull}}, nil
+	case *ecdsa.PrivateKey:
+		switch hash {
+		case crypto.SHA256:
+			return algorithmIdentifier{Algorithm: oidECDSAWithSHA256}, nil
+		case crypto.SHA384:
+			return algorithmIdentifier{Algorithm: oidECDSAWithSHA384}, nil
+		case crypto.SHA512:
+			return algorithmIdentifier{Algorithm: oidECDSAWithSHA512}, nil
+		}
+	}
+	return algorithmIdentifier{}, errors.New("Unsupported key or hash algorithm")
+}
+
+// Parses a dotted OID.
+func parseOID(s string) asn1.ObjectIdentifier {
+	var oid asn1.ObjectIdentifier
+	for _, part := range strings.Split(s, ".") {
+		n, _ := strconv.Atoi(part)
+		oid = append(oid, n)
+	}
+	return oid
+}
+
+// Parses a CMS content info with signed data.  Returns the signed data and its certificates.
+func parseSignedData(der []byte) (*signedData, []*x509.Certificate, error) {
+	var ci contentInfo
+	_, err := asn1.Unmarshal(der, &ci)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, nil, errors.New("Not CMS signed data")
+	}
+	var sd signedData
+	_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, nil, fmt.Errorf("%d signers, expected 1", len(sd.SignerInfos))
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &sd, certs, nil
+}
+
+// Verifies the signer info of signed data for the content: the signed attributes have the content type and the
+// digest of the content, and are signed by the certificate of the signer.  Returns the certificate.
+func verifySignerInfo(sd *signedData, certs []*x509.Certificate, content []byte,
+	contentType asn1.ObjectIdentifier) (*x509.Certificate, error) {
+	si := sd.SignerInfos[0]
+
+	// The certificate of the signer: by issuer and serial number, or subject key identifier.
+	var cert *x509.Certificate
+	var ias issuerAndSerial
+	if _, err := asn1.Unmarshal(si.SID.FullBytes, &ias); err == nil {
+		for _, c := range certs {
+			if bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.Serial) == 0 {
+				cert = c
+			}
+		}
+	} else if si.SID.Class == asn1.ClassContextSpecific && si.SID.Tag == 0 {
+		for _, c := range certs {
+			if bytes.Equal(c.SubjectKeyId, si.SID.Bytes) {
+				cert = c
+			}
+		}
+	}
+	if cert == nil {
+		return nil, errors.New("The signer certificate is missing")
+	}
+
+	hash, ok := digestAlgorithms[si.DigestAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported digest algorithm %s", si.DigestAlgorithm.Algorithm)
+	}
+	h := hash.New()
+	h.Write(content)
+	digest := h.Sum(nil)
+
+	var foundDigest, foundType bool
+	rest := si.SignedAttrs.Bytes
+	for len(rest) > 0 {
+		var attr attribute
+		var err error
+		rest, err = asn1.Unmarshal(rest, &attr)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case attr.Type.Equal(oidMessageDigest):
+			var value []byte
+			_, err := asn1.Unmarshal(attr.Values.Bytes, &value)
+			if err != nil || !bytes.Equal(value, digest) {
+				return nil, errors.New("The digest of the content does not match")
+			}
+			foundDigest = true
+		case attr.Type.Equal(oidContentType):
+			var value asn1.ObjectIdentifier
+			_, err := asn1.Unmarshal(attr.Values.Bytes, &value)
+			if err != nil || !value.Equal(contentType) {
+				return nil, errors.New("The content type does not match")
+			}
+			foundType = true
+		}
+	}
+	if !foundDigest || !foundType {
+		return nil, errors.New("Missing signed attributes")
+	}
+
+	setDER, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true,
+		Bytes: si.SignedAttrs.Bytes})
+	if err != nil {
+		return nil, err
+	}
+	alg, err := x509SignatureAlgorithm(cert, hash, si.SignatureAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	err = cert.CheckSignature(alg, setDER, si.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// Returns the x509 signature algorithm for the key of the certificate and the hash.
+func x509SignatureAlgorithm(cert *x509.Certificate, hash crypto.Hash, sigAlg asn1.ObjectIdentifier) (
+	x509.SignatureAlgorithm, error) {
+	switch cert.PublicKeyAlgorithm {
+	case x509.RSA:
+		pss := sigAlg.Equal(oidRSASSAPSS)
+		switch hash {
+		case crypto.SHA1:
+			return x509.SHA1WithRSA, nil
+		case crypto.SHA256:
+			if pss {
+				return x509.SHA256WithRSAPSS, nil
+			}
+			return x509.SHA256WithRSA, nil
+		case crypto.SHA384:
+			if pss {
+				return x509.SHA384WithRSAPSS, nil
+			}
+			return x509.SHA384WithRSA, nil
+		case crypto.SHA512:
+			if pss {
+				return x509.SHA512WithRSAPSS, nil
+			}
+			return x509.SHA512WithRSA, nil
+		}
+	case x509.ECDSA:
+		switch hash {
+		case crypto.SHA1:
+			return x509.ECDSAWithSHA1, nil
+		case crypto.SHA256:
+			return x509.ECDSAWithSHA256, nil
+		case crypto.SHA384:
+			return x509.ECDSAWithSHA384, nil
+		case crypto.SHA512:
+			return x509.ECDSAWithSHA512, nil
+		}
+	}
+	return x509.UnknownSignatureAlgorithm, errors.New("Unsupported signature algorithm")
+}
+
+// Returns the indirect object (nil for a direct object) and the direct object of an object, resolving references.
+func resolve(pdfReader *pdf.PdfReader, obj pdfcore.PdfObject) (*pdfcore.PdfIndirectObject, pdfcore.PdfObject) {
+	if ref, ok := obj.(*pdfcore.PdfObjectReference); ok {
+		resolved, err := pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil, nil
+		}
+		obj = resolved
+	}
+	if ind, ok := obj.(*pdfcore.PdfIndirectObject); ok {
+		return ind, ind.PdfObject
+	}
+	return nil, obj
+}
+
+// Checks that the certification signature, if any, allows signing.  The permissions (P) are 1: no changes,
+// 2: form filling and signing, 3: also annotations.
+func checkDocMDP(pdfReader *pdf.PdfReader, catalog *pdfcore.PdfObjectDictionary) error {
+	_, permsObj := resolve(pdfReader, catalog.Get("Perms"))
+	perms, ok := permsObj.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	_, sigObj := resolve(pdfReader, perms.Get("DocMDP"))
+	sigDict, ok := sigObj.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return nil
+	}
+	_, refsObj := resolve(pdfReader, sigDict.Get("Reference"))
+	refs, ok := refsObj.(*pdfcore.PdfObjectArray)
+	if !ok {
+		return nil
+	}
+
+	for _, refObj := range *refs {
+		_, refDirect := resolve(pdfReader, refObj)
+		ref, ok := refDirect.(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		_, paramsObj := resolve(pdfReader, ref.Get("TransformParams"))
+		params, ok := paramsObj.(*pdfcore.PdfObjectDictionary)
+		if !ok {
+			continue
+		}
+		_, pObj := resolve(pdfReader, params.Get("P"))
+		if p, ok := pObj.(*pdfcore.PdfObjectInteger); ok && *p == 1 {
+			return errors.New("The document is certified with no changes allowed, signing would invalidate the " +
+				"certification")
+		}
+	}
+	return nil
+}
+
+// Returns the incremental update for the file data: the objects, a cross-reference section in the same form as the
+// previous one, and the trailer.  The Root, Info and ID entries of the new trailer are taken from trailer.  size is
+// the number of objects including the new ones.
+func writeUpdate(data []byte, objects []updateObject, trailer *pdfcore.PdfObjectDictionary, size int64) ([]byte,
+	error) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].number < objects[j].number })
+	prevOffset := previousXrefOffset(data)
+	if prevOffset < 0 || prevOffset >= len(data) {
+		return nil, errors.New("Invalid startxref")
+	}
+	xrefStream := !bytes.HasPrefix(data[prevOffset:], []byte("xref"))
+
+	var buf bytes.Buffer
+	// The update starts on a new line.
+	if len(data) > 0 && data[len(data)-1] != '\n' && data[len(data)-1] != '\r' {
+		buf.WriteString("\n")
+	}
+
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = len(data) + buf.Len()
+		// The string of a stream object is a reference to it: the dictionary and data are written instead.
+		if stream, ok := obj.obj.(*pdfcore.PdfObjectStream); ok {
+			stream.PdfObjectDictionary.Set("Length", pdfcore.MakeInteger(int64(len(stream.Stream))))
+			buf.WriteString(fmt.Sprintf("%d %d obj\n%s\nstream\n", obj.number, obj.generation,
+				stream.PdfObjectDictionary.DefaultWriteString()))
+			buf.Write(stream.Stream)
+			buf.WriteString("\nendstream\nendobj\n")
+			continue
+		}
+		buf.WriteString(fmt.Sprintf("%d %d obj\n%s\nendobj\n", obj.number, obj.generation,
+			obj.obj.DefaultWriteString()))
+	}
+
+	// The new trailer: the entries of the previous trailer that identify the document.
+	newTrailer := pdfcore.MakeDict()
+	for _, key := range []pdfcore.PdfObjectName{"Root", "Info", "ID"} {
+		if v := trailer.Get(key); v != nil {
+			newTrailer.Set(key, v)
+		}
+	}
+	newTrailer.Set("Prev", pdfcore.MakeInteger(int64(prevOffset)))
+
+	xrefOffset := len(data) + buf.Len()
+	if !xrefStream {
+		// One subsection per object, as the object numbers are not contiguous.
+		buf.WriteString("xref\n")
+		for i, obj := range objects {
+			buf.WriteString(fmt.Sprintf("%d 1\n%010d %05d n\r\n", obj.number, offsets[i], obj.generation))
+		}
+		newTrailer.Set("Size", pdfcore.MakeInteger(size))
+		buf.WriteString("trailer\n")
+		buf.WriteString(newTrailer.DefaultWriteString())
+		buf.WriteString("\n")
+	} else {
+		// The cross-reference stream is an object itself, with an entry for itself.  Entries are 1 byte type (1:
+		// in use), 4 bytes offset and 2 bytes generation.
+		xrefNum := size
+		objects = append(objects, updateObject{number: xrefNum})
+		offsets = append(offsets, xrefOffset)
+
+		var entries bytes.Buffer
+		index := []int64{}
+		for i, obj := range objects {
+			entries.WriteByte(1)
+			binary.Write(&entries, binary.BigEndian, uint32(offsets[i]))
+			binary.Write(&entries, binary.BigEndian, uint16(obj.generation))
+			index = append(index, obj.number, 1)
+		}
+
+		stream, err := pdfcore.MakeStream(entries.Bytes(), pdfcore.NewFlateEncoder())
+		if err != nil {
+			return nil, err
+		}
+		xrefDict := stream.PdfObjectDictionary
+		xrefDict.Set("Type", pdfcore.MakeName("XRef"))
+		xrefDict.Set("Size", pdfcore.MakeInteger(size+1))
+		xrefDict.Set("Index", pdfcore.MakeArrayFromIntegers64(index))
+		xrefDict.Set("W", pdfcore.MakeArrayFromIntegers([]int{1, 4, 2}))
+		xrefDict.Merge(newTrailer)
+		buf.WriteString(fmt.Sprintf("%d 0 obj\n%s\nstream\n", xrefNum, xrefDict.DefaultWriteString()))
+		buf.Write(stream.Stream)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+	buf.WriteString(fmt.Sprintf("startxref\n%d\n%%%%EOF\n", xrefOffset))
+
+	return buf.Bytes(), nil
+}
+
+// Returns the offset of the last cross-reference section of the file data, from the startxref at the end, or -1 if
+// not found.
+func previousXrefOffset(data []byte) int {
+	idx := bytes.LastIndex(data, []byte("startxref"))
+	if idx < 0 {
+		return -1
+	}
+	fields := strings.Fields(string(data[idx+len("startxref"):]))
+	if len(fields) == 0 {
+		return -1
+	}
+	offset, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return -1
+	}
+	return offset
+}
+
+// Formats the time as a PDF date string, e.g. D:20180213153000+01'00'.
+func formatPdfDate(t time.Time) string {
+	_, offset := t.Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return fmt.Sprintf("D:%s%s%02d'%02d'", t.Format("20060102150405"), sign, offset/3600, offset%3600/60)
+}
@@ -0,0 +1,617 @@
+/*
+ * Sign a PDF file with a trusted timestamp from an RFC 3161 time stamping authority (TSA) embedded in the signature.
+ *
+ * The signature is a detached CMS (PKCS#7) signature (SubFilter adbe.pkcs7.detached) of the file bytes, except for the
+ * signature value itself: the ByteRange of the signature dictionary gives the signed parts, before and after the
+ * Contents entry with the CMS data.  The signature is added as an incremental update, so that existing signatures
+ * stay valid, in the first unsigned signature field (e.g. one prepared by prepare_signature_field.go), a field given
+ * with -field, or a new invisible signature field.  A visible field gets an appearance with the signer and the date.
+ *
+ * The signing time in a signature is the computer clock of the signer, which proves nothing.  A timestamp token from
+ * a TSA proves that the signature existed at the time in the token: the TSA signs a hash of the signature value
+ * (the message imprint) with the time.  The token is embedded in the signature as an unsigned attribute (a signature
+ * timestamp), so it is requested after signing, when the signature value is known:
+ * 1. the file with a placeholder for the signature is written, and the ByteRange is set,
+ * 2. the signed bytes are hashed and signed (CMS signed attributes with the hash),
+ * 3. the hash of the signature value is sent to the TSA (-tsa) in a time stamp request, with a random nonce,
+ * 4. the token in the response is verified: the message imprint and the nonce are those of the request, and the token
+ *    is signed by a certificate for time stamping,
+ * 5. the CMS data with the token is written in the placeholder.
+ * The space for the signature value is reserved before the token is known, so it allows for a large token.  The
+ * hash algorithm (-hash) is used for the document, the signature and the message imprint.
+ *
+ * Network errors, HTTP errors and TSA rejections are reported, and no output is written, unless -optional is set: then
+ * the file is signed without a timestamp.  After signing, the output is read back and the signature and the
+ * timestamp are verified.  Whether the TSA certificate is trusted is reported with the system root certificates.
+ *
+ * The signing certificate and key are PEM files (-cert, which can include the chain, and -key, RSA or ECDSA).  Without
+ * them, a self-signed test certificate is generated, which viewers show as not trusted.  The signing code and the
+ * update writer are in signing.go, shared with ltv.go.
+ *
+ * Run as: go run timestamp.go signing.go [-tsa http://timestamp.digicert.com] [-hash sha256]
+ *                                        [-cert cert.pem -key key.pem] [-field name] [-reason text] [-optional]
+ *                                        input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run timestamp.go signing.go [-tsa http://timestamp.digicert.com] [-hash sha256] " +
+	"[-cert cert.pem -key key.pem] [-field name] [-reason text] [-optional] input.pdf output.pdf\n"
+
+// Space reserved for the timestamp token in the signature value, in bytes.
+const tokenReserve = 16384
+
+var (
+	oidTimeStampToken = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 14}
+	oidTSTInfo        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+)
+
+// ASN.1 structures of time stamping (RFC 3161).
+
+type messageImprint struct {
+	HashAlgorithm algorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional,utf8"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time     `asn1:"generalized"`
+	Accuracy       accuracy      `asn1:"optional"`
+	Ordering       bool          `asn1:"optional,default:false"`
+	Nonce          *big.Int      `asn1:"optional"`
+	TSA            asn1.RawValue `asn1:"optional,tag:0"`
+	Extensions     asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// timestampInfo is the content of a verified timestamp token.
+type timestampInfo struct {
+	genTime time.Time
+	serial  *big.Int
+	policy  asn1.ObjectIdentifier
+	cert    *x509.Certificate
+	certs   []*x509.Certificate
+}
+
+func main() {
+	tsaURL := ""
+	hashName := ""
+	certPath := ""
+	keyPath := ""
+	fieldName := ""
+	reason := ""
+	optional := false
+	timeout := time.Duration(0)
+	flag.StringVar(&tsaURL, "tsa", "http://timestamp.digicert.com", "URL of the RFC 3161 time stamping authority")
+	flag.StringVar(&hashName, "hash", "sha256", "Hash algorithm: sha256, sha384 or sha512")
+	flag.StringVar(&certPath, "cert", "", "Signing certificate (PEM, optionally followed by the chain)")
+	flag.StringVar(&keyPath, "key", "", "Private key of the certificate (PEM)")
+	flag.StringVar(&fieldName, "field", "", "Signature field to sign (default: the first unsigned one, or a new one)")
+	flag.StringVar(&reason, "reason", "", "Reason for signing")
+	flag.BoolVar(&optional, "optional", false, "Sign without a timestamp if the TSA fails")
+	flag.DurationVar(&timeout, "timeout", 30*time.Second, "Timeout of the TSA request")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	hash, ok := hashAlgorithms[hashName]
+	if !ok {
+		fmt.Printf("Error: unsupported hash algorithm %q\n", hashName)
+		os.Exit(1)
+	}
+	if (certPath == "") != (keyPath == "") {
+		fmt.Printf("Error: -cert and -key must be given together\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	var s *signer
+	var err error
+	if certPath != "" {
+		s, err = loadSigner(certPath, keyPath)
+	} else {
+		fmt.Printf("No certificate given, signing with a self-signed test certificate\n")
+		s, err = newTestSigner()
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	tsa := &tsaClient{url: tsaURL, client: &http.Client{Timeout: timeout}}
+	err = signWithTimestamp(inputPath, outputPath, s, hash, tsa, fieldName, reason, optional)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func signWithTimestamp(inputPath, outputPath string, s *signer, hash crypto.Hash, tsa *tsaClient, fieldName,
+	reason string, optional bool) error {
+	data, err := ioutil.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		return errors.New("Signing encrypted files is not supported")
+	}
+
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	size, ok := pdfcore.TraceToDirectObject(trailer.Get("Size")).(*pdfcore.PdfObjectInteger)
+	if !ok {
+		return errors.New("Missing Size in trailer")
+	}
+	nextNum := int64(*size)
+	catalogRef, ok := trailer.Get("Root").(*pdfcore.PdfObjectReference)
+	if !ok {
+		return errors.New("Missing Root in trailer")
+	}
+	catalogObj, catalog := resolve(pdfReader, catalogRef)
+	catalogDict, ok := catalog.(*pdfcore.PdfObjectDictionary)
+	if !ok || catalogObj == nil {
+		return errors.New("Missing catalog")
+	}
+
+	err = checkDocMDP(pdfReader, catalogDict)
+	if err != nil {
+		return err
+	}
+
+	// The signature dictionary, written with placeholders for the byte range and the signature value.
+	now := time.Now()
+	sigNum := nextNum
+	nextNum++
+	var certsLen int
+	for _, cert := range s.certs {
+		certsLen += len(cert.Raw)
+	}
+	reserve := certsLen + 4096 + tokenReserve
+	sigDict := "<< /Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached " +
+		"/ByteRange [" + strings.Repeat(" ", byteRangeWidth) + "] " +
+		"/Contents <" + strings.Repeat("0", 2*reserve) + "> " +
+		"/M " + pdfcore.MakeString(formatPdfDate(now)).DefaultWriteString() + " " +
+		"/Name " + pdfcore.MakeString(s.certs[0].Subject.CommonName).DefaultWriteString()
+	if reason != "" {
+		sigDict += " /Reason " + pdfcore.MakeString(reason).DefaultWriteString()
+	}
+	sigDict += " >>"
+	objects := []updateObject{{number: sigNum, obj: rawObject(sigDict), description: "signature"}}
+	sigRef := &pdfcore.PdfObjectReference{ObjectNumber: sigNum}
+
+	// The field: an unsigned signature field, or a new invisible one.
+	formObj, form := resolve(pdfReader, catalogDict.Get("AcroForm"))
+	formDict, _ := form.(*pdfcore.PdfObjectDictionary)
+	var field *sigField
+	if formDict != nil {
+		field, err = findSignatureField(pdfReader, formDict, fieldName)
+		if err != nil {
+			return err
+		}
+	}
+	if field == nil && fieldName != "" {
+		return fmt.Errorf("No unsigned signature field %s", fieldName)
+	}
+
+	if field == nil {
+		if formDict == nil {
+			formDict = pdfcore.MakeDict()
+			catalogDict.Set("AcroForm", formDict)
+			formObj = nil
+		}
+		newObjects, err := addInvisibleField(pdfReader, formDict, sigRef, nextNum)
+		if err != nil {
+			return err
+		}
+		nextNum++
+		objects = append(objects, newObjects...)
+	} else {
+		fmt.Printf("Signing the field %s\n", field.name)
+		field.dict.Set("V", sigRef)
+		objects = append(objects, updateObject{number: field.obj.ObjectNumber, generation: field.obj.GenerationNumber,
+			obj: field.dict, description: "signature field"})
+
+		// The appearance of the visible widgets: the signer and the date.
+		for _, w := range field.widgets {
+			appearance, err := makeSignedAppearance(w.dict, s.certs[0].Subject.CommonName, now)
+			if err != nil || appearance == nil {
+				continue
+			}
+			ap := pdfcore.MakeDict()
+			ap.Set("N", &pdfcore.PdfObjectReference{ObjectNumber: nextNum})
+			w.dict.Set("AP", ap)
+			objects = append(objects,
+				updateObject{number: nextNum, obj: appearance, description: "signature appearance"},
+				updateObject{number: w.obj.ObjectNumber, generation: w.obj.GenerationNumber, obj: w.dict,
+					description: "signature widget"})
+			nextNum++
+		}
+	}
+
+	formDict.Set("SigFlags", pdfcore.MakeInteger(sigFlags))
+	if formObj != nil {
+		objects = append(objects, updateObject{number: formObj.ObjectNumber, generation: formObj.GenerationNumber,
+			obj: formDict, description: "form"})
+	} else {
+		objects = append(objects, updateObject{number: catalogObj.ObjectNumber,
+			generation: catalogObj.GenerationNumber, obj: catalogDict, description: "catalog"})
+	}
+
+	update, err := writeUpdate(data, objects, trailer, nextNum)
+	if err != nil {
+		return err
+	}
+	output := append(append([]byte{}, data...), update...)
+	for _, obj := range objects {
+		fmt.Printf("Updated object %d: %s\n", obj.number, obj.description)
+	}
+
+	// 1. The byte range: the whole file except the signature value.
+	sigOffset := bytes.Index(output[len(data):], []byte(fmt.Sprintf("%d 0 obj\n", sigNum))) + len(data)
+	brStart := bytes.Index(output[sigOffset:], []byte("/ByteRange [")) + sigOffset + len("/ByteRange [")
+	contentsStart := bytes.Index(output[sigOffset:], []byte("/Contents <")) + sigOffset + len("/Contents ")
+	contentsEnd := contentsStart + 2*reserve + 2
+	byteRange := []int{0, contentsStart, contentsEnd, len(output) - contentsEnd}
+	br := fmt.Sprintf("0 %d %d %d", byteRange[1], byteRange[2], byteRange[3])
+	copy(output[brStart:], br+strings.Repeat(" ", byteRangeWidth-len(br)))
+
+	// 2. The signature of the signed bytes.
+	h := hash.New()
+	h.Write(output[:contentsStart])
+	h.Write(output[contentsEnd:])
+	digest := h.Sum(nil)
+	si, err := s.sign(digest, hash, now, nil)
+	if err != nil {
+		return err
+	}
+
+	// 3., 4. The timestamp of the signature value.
+	token, info, err := tsa.timestamp(si.Signature, hash)
+	if err != nil {
+		if !optional {
+			return fmt.Errorf("%v (use -optional to sign without a timestamp)", err)
+		}
+		fmt.Printf("Warning: %v, signing without a timestamp\n", err)
+	} else {
+		fmt.Printf("Timestamp: %s from %s (serial %s)\n", info.genTime.Format(time.RFC3339),
+			info.cert.Subject.CommonName, info.serial)
+		attr, err := asn1.Marshal(attribute{Type: oidTimeStampToken, Values: asn1.RawValue{Class: asn1.ClassUniversal,
+			Tag: asn1.TagSet, IsCompound: true, Bytes: token}})
+		if err != nil {
+			return err
+		}
+		si.UnsignedAttrs = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: attr}
+	}
+
+	// 5. The CMS data in the placeholder.
+	cms, err := s.makeCMS(si, hash)
+	if err != nil {
+		return err
+	}
+	if len(cms) > reserve {
+		return fmt.Errorf("The signature (%d bytes) does not fit in the reserved space (%d bytes)", len(cms), reserve)
+	}
+	copy(output[contentsStart+1:], strings.ToUpper(hex.EncodeToString(cms)))
+
+	err = verifySignedFile(output)
+	if err != nil {
+		return fmt.Errorf("Verification of the signed file failed: %v", err)
+	}
+
+	return ioutil.WriteFile(outputPath, output, 0644)
+}
+
+// tsaClient requests timestamps from a TSA over HTTP.
+type tsaClient struct {
+	url    string
+	client *http.Client
+}
+
+// Requests a timestamp token for the data from the TSA and verifies it.  Returns the token (a CMS content info) and
+// its content.
+func (tsa *tsaClient) timestamp(data []byte, hash crypto.Hash) ([]byte, *timestampInfo, error) {
+	h := hash.New()
+	h.Write(data)
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, nil, err
+	}
+	hashAlg := digestAlgorithmIdentifier(hash)
+	hashAlg.Parameters = asn1.RawValue{Tag: asn1.TagNull}
+	req, err := asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: messageImprint{HashAlgorithm: hashAlg, HashedMessage: h.Sum(nil)},
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	httpReq, err := http.NewRequest("POST", tsa.url, bytes.NewReader(req))
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid TSA URL: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/timestamp-query")
+	resp, err := tsa.client.Do(httpReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("TSA request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("TSA response incomplete: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("TSA returned HTTP status %s", resp.Status)
+	}
+
+	var tsResp timeStampResp
+	_, err = asn1.Unmarshal(body, &tsResp)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid TSA response (%s): %v", resp.Header.Get("Content-Type"), err)
+	}
+	// Status 0: granted, 1: granted with modifications.
+	if tsResp.Status.Status > 1 {
+		msg := fmt.Sprintf("TSA rejected the request: status %d", tsResp.Status.Status)
+		if len(tsResp.Status.StatusString) > 0 {
+			msg += ", " + strings.Join(tsResp.Status.StatusString, "; ")
+		}
+		for bit := 0; bit < tsResp.Status.FailInfo.BitLength; bit++ {
+			if tsResp.Status.FailInfo.At(bit) != 0 {
+				msg += fmt.Sprintf(", failure %d", bit)
+			}
+		}
+		return nil, nil, errors.New(msg)
+	}
+	token := tsResp.TimeStampToken.FullBytes
+	if len(token) == 0 {
+		return nil, nil, errors.New("No timestamp token in the TSA response")
+	}
+
+	info, err := verifyTimestampToken(token, data, nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid timestamp token: %v", err)
+	}
+	return token, info, nil
+}
+
+// Verifies a timestamp token for the data: the message imprint is the hash of the data, the nonce is the one of the
+// request (if not nil), and the token is signed by its TSA certificate, which is for time stamping.
+func verifyTimestampToken(token, data []byte, nonce *big.Int) (*timestampInfo, error) {
+	sd, certs, err := parseSignedData(token)
+	if err != nil {
+		return nil, err
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, errors.New("Not a timestamp token")
+	}
+	var content []byte
+	_, err = asn1.Unmarshal(sd.EncapContentInfo.EContent.Bytes, &content)
+	if err != nil {
+		return nil, err
+	}
+	var tst tstInfo
+	_, err = asn1.Unmarshal(content, &tst)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, ok := digestAlgorithms[tst.MessageImprint.HashAlgorithm.Algorithm.String()]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported message imprint algorithm %s", tst.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	h := hash.New()
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), tst.MessageImprint.HashedMessage) {
+		return nil, errors.New("The message imprint does not match")
+	}
+	if nonce != nil && (tst.Nonce == nil || tst.Nonce.Cmp(nonce) != 0) {
+		return nil, errors.New("The nonce does not match")
+	}
+
+	cert, err := verifySignerInfo(sd, certs, content, oidTSTInfo)
+	if err != nil {
+		return nil, err
+	}
+	timeStamping := false
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageTimeStamping {
+			timeStamping = true
+		}
+	}
+	if !timeStamping {
+		return nil, errors.New("The TSA certificate is not for time stamping")
+	}
+
+	return &timestampInfo{genTime: tst.GenTime, serial: tst.SerialNumber, policy: tst.Policy, cert: cert,
+		certs: certs}, nil
+}
+
+// Verifies the last signature of the signed file: the byte range covers the whole file except the signature value,
+// the CMS signature is valid for the signed bytes, and the timestamp token, if any, is valid for the signature.
+func verifySignedFile(output []byte) error {
+	pdfReader, err := pdf.NewPdfReader(bytes.NewReader(output))
+	if err != nil {
+		return err
+	}
+	trailer, err := pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	_, catalogObj := resolve(pdfReader, trailer.Get("Root"))
+	catalog, ok := catalogObj.(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Missing catalog")
+	}
+
+	// The signature covering the whole file.
+	var sigDict *pdfcore.PdfObjectDictionary
+	var byteRange []int64
+	var visit func(obj pdfcore.PdfObject, depth int)
+	visit = func(obj pdfcore.PdfObject, depth int) {
+		_, direct := resolve(pdfReader, obj)
+		field, ok := direct.(*pdfcore.PdfObjectDictionary)
+		if !ok || depth > 32 {
+			return
+		}
+		if _, v := resolve(pdfReader, field.Get("V")); v != nil {
+			if d, ok := v.(*pdfcore.PdfObjectDictionary); ok {
+				if arr, ok := pdfcore.TraceToDirectObject(d.Get("ByteRange")).(*pdfcore.PdfObjectArray); ok {
+					if values, err := arr.ToIntegerArray(); err == nil && len(values) == 4 &&
+						values[2]+values[3] == len(output) {
+						sigDict = d
+						byteRange = []int64{int64(values[0]), int64(values[1]), int64(values[2]),
+							int64(values[3])}
+					}
+				}
+			}
+		}
+		_, kidsObj := resolve(pdfReader, field.Get("Kids"))
+		if kids, ok := kidsObj.(*pdfcore.PdfObjectArray); ok {
+			for _, kid := range *kids {
+				visit(kid, depth+1)
+			}
+		}
+	}
+	_, formObj := resolve(pdfReader, catalog.Get("AcroForm"))
+	if form, ok := formObj.(*pdfcore.PdfObjectDictionary); ok {
+		_, fieldsObj := resolve(pdfReader, form.Get("Fields"))
+		if fields, ok := fieldsObj.(*pdfcore.PdfObjectArray); ok {
+			for _, obj := range *fields {
+				visit(obj, 0)
+			}
+		}
+	}
+	if sigDict == nil {
+		return errors.New("No signature covering the whole file")
+	}
+	if byteRange[0] != 0 || byteRange[1] >= byteRange[2] {
+		return fmt.Errorf("Invalid byte range %v", byteRange)
+	}
+	contents, ok := pdfcore.TraceToDirectObject(sigDict.Get("Contents")).(*pdfcore.PdfObjectString)
+	if !ok {
+		return errors.New("Missing signature value")
+	}
+
+	sd, certs, err := parseSignedData([]byte(*contents))
+	if err != nil {
+		return err
+	}
+	var signed []byte
+	signed = append(signed, output[:byteRange[1]]...)
+	signed = append(signed, output[byteRange[2]:]...)
+	cert, err := verifySignerInfo(sd, certs, signed, oidData)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Verified: signature by %s covering the whole file (%d bytes signed)\n", cert.Subject.CommonName,
+		len(signed))
+
+	// The timestamp token in the unsigned attributes.
+	si := sd.SignerInfos[0]
+	rest := si.UnsignedAttrs.Bytes
+	for len(rest) > 0 {
+		var attr attribute
+		rest, err = asn1.Unmarshal(rest, &attr)
+		if err != nil {
+			return err
+		}
+		if !attr.Type.Equal(oidTimeStampToken) {
+			continue
+		}
+		info, err := verifyTimestampToken(attr.Values.Bytes, si.Signature, nil)
+		if err != nil {
+			return fmt.Errorf("Timestamp: %v", err)
+		}
+		fmt.Printf("Verified: timestamp %s of the signature value, TSA certificate %s\n",
+			info.genTime.Format(time.RFC3339), info.cert.Subject.CommonName)
+
+		// Trust depends on the root certificates of the system, it is reported but not required.
+		intermediates := x509.NewCertPool()
+		for _, c := range info.certs {
+			intermediates.AddCert(c)
+		}
+		_, err = info.cert.Verify(x509.VerifyOptions{Intermediates: intermediates, CurrentTime: info.genTime,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}})
+		if err != nil {
+			fmt.Printf("Note: the TSA certificate is not trusted on this system: %v\n", err)
+		} else {
+			fmt.Printf("The TSA certificate is trusted on this system\n")
+		}
+	}
+	return nil
+}
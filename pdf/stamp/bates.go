@@ -0,0 +1,228 @@
+/*
+ * Stamp Bates numbers on every page of a set of PDF documents.
+ *
+ * Each page gets a sequential identifier such as ABC-000001 in a corner of the page, overlaid on the existing content
+ * with the creator.  The sequence continues from one input file to the next, so a document set processed in one run
+ * is numbered consecutively.  Each input file is written to its own output file in the output directory (with a
+ * _bates suffix), and the Bates range of each file is printed.  Input files that would have the same output file
+ * (same name in different directories) are rejected before anything is written.
+ *
+ * The prefix, starting number and zero-padding width are configurable, as is the corner and the distance from the
+ * page edges (margin, in points).
+ *
+ * Large documents: the files are processed one at a time, each with its own creator, so only a single document is
+ * held in memory at any time.  The font is loaded once and reused for all pages.
+ *
+ * Run as: go run bates.go [-prefix ABC-] [-start 1] [-width 6] [-corner bottom-right] [-margin 20] [-outdir .]
+ *                         input1.pdf [input2.pdf ...]
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run bates.go [-prefix ABC-] [-start 1] [-width 6] [-corner bottom-right] [-margin 20] " +
+	"[-outdir .] input1.pdf [input2.pdf ...]\n"
+
+// BatesStamper stamps sequential Bates numbers on pages.
+type BatesStamper struct {
+	Prefix   string
+	Next     int // Number to use for the next page.
+	Width    int // Zero padding width of the number.
+	Corner   string
+	Margin   float64
+	FontSize float64
+
+	font fonts.Font
+}
+
+func main() {
+	stamper := &BatesStamper{FontSize: 10, font: fonts.NewFontHelveticaBold()}
+	outputDir := ""
+
+	flag.StringVar(&stamper.Prefix, "prefix", "ABC-", "Prefix of the Bates number")
+	flag.IntVar(&stamper.Next, "start", 1, "Starting number")
+	flag.IntVar(&stamper.Width, "width", 6, "Zero padding width of the number")
+	flag.StringVar(&stamper.Corner, "corner", "bottom-right",
+		"Corner of the stamp: top-left, top-right, bottom-left or bottom-right")
+	flag.Float64Var(&stamper.Margin, "margin", 20, "Distance of the stamp from the page edges (points)")
+	flag.StringVar(&outputDir, "outdir", ".", "Output directory")
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	switch stamper.Corner {
+	case "top-left", "top-right", "bottom-left", "bottom-right":
+	default:
+		fmt.Printf("Error: invalid corner %q\n", stamper.Corner)
+		os.Exit(1)
+	}
+
+	outputPaths, err := outputPathsFor(flag.Args(), outputDir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	for i, inputPath := range flag.Args() {
+		outputPath := outputPaths[i]
+
+		first := stamper.Next
+		err = stamper.StampFile(inputPath, outputPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s: %s - %s -> %s\n", inputPath, stamper.format(first), stamper.format(stamper.Next-1),
+			outputPath)
+	}
+
+	fmt.Printf("Complete, next Bates number: %s\n", stamper.format(stamper.Next))
+}
+
+// outputPathsFor returns the output path of each input file.  Inputs from different directories can have the same
+// name, and their outputs would overwrite each other, leaving printed Bates ranges that do not match the files, and an
+// output can be another input, e.g. a_bates.pdf for a.pdf: this is detected before any file is written.  Paths are
+// compared as absolute paths ignoring case, for case-insensitive file systems.
+func outputPathsFor(inputPaths []string, outputDir string) ([]string, error) {
+	key := func(path string) string {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = filepath.Clean(path)
+		}
+		return strings.ToLower(abs)
+	}
+	inputs := map[string]string{}
+	for _, inputPath := range inputPaths {
+		inputs[key(inputPath)] = inputPath
+	}
+
+	outputPaths := []string{}
+	inputOf := map[string]string{}
+	for _, inputPath := range inputPaths {
+		base := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+		outputPath := filepath.Join(outputDir, base+"_bates.pdf")
+		k := key(outputPath)
+		if other, ok := inputOf[k]; ok {
+			return nil, fmt.Errorf("%s and %s would both be written to %s, rename one of them", other, inputPath,
+				outputPath)
+		}
+		if other, ok := inputs[k]; ok {
+			return nil, fmt.Errorf("The output of %s would overwrite the input %s, use another -outdir", inputPath,
+				other)
+		}
+		inputOf[k] = inputPath
+		outputPaths = append(outputPaths, outputPath)
+	}
+	return outputPaths, nil
+}
+
+// Formats Bates number n, e.g. ABC-000001.
+func (s *BatesStamper) format(n int) string {
+	return fmt.Sprintf("%s%0*d", s.Prefix, s.Width, n)
+}
+
+// StampFile stamps each page of the PDF at inputPath with the next Bates number and writes to outputPath.
+func (s *BatesStamper) StampFile(inputPath string, outputPath string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	c := creator.New()
+
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+
+		err = c.AddPage(page)
+		if err != nil {
+			return err
+		}
+
+		mbox, err := page.GetMediaBox()
+		if err != nil {
+			return err
+		}
+
+		err = s.stampPage(c, mbox.Urx-mbox.Llx, mbox.Ury-mbox.Lly)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Draws the next Bates number on the current page of the creator.
+func (s *BatesStamper) stampPage(c *creator.Creator, pageWidth, pageHeight float64) error {
+	p := creator.NewParagraph(s.format(s.Next))
+	p.SetFont(s.font)
+	p.SetFontSize(s.FontSize)
+	p.SetEnableWrap(false)
+
+	// Creator coordinates have the origin in the upper left corner.
+	x := s.Margin
+	if strings.HasSuffix(s.Corner, "right") {
+		x = pageWidth - s.Margin - p.Width()
+	}
+	y := s.Margin
+	if strings.HasPrefix(s.Corner, "bottom") {
+		y = pageHeight - s.Margin - p.Height()
+	}
+	p.SetPos(x, y)
+
+	err := c.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	s.Next++
+	return nil
+}
@@ -0,0 +1,226 @@
+/*
+ * Use a single page PDF (e.g. a letterhead) as a background template on every page of another PDF.
+ *
+ * The template page is imported as a Form XObject: its content stream and resources are copied into the form and its
+ * MediaBox is used as the form BBox.  On each target page the form is drawn by a content stream prefix, i.e. before
+ * the page's own content, so the template appears underneath it.  The existing page content is not modified, so its
+ * text remains selectable/searchable.  The form XObject is shared by all the pages and only stored once in the output.
+ *
+ * When the template and target page sizes differ, the template is placed according to the mode:
+ * - fit:     scale uniformly to fit inside the page and center it (default),
+ * - stretch: scale non-uniformly to cover the page exactly,
+ * - center:  keep the original size and center it on the page.
+ *
+ * Run as: go run overlay_template.go [-mode fit|stretch|center] template.pdf input.pdf output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run overlay_template.go [-mode fit|stretch|center] template.pdf input.pdf output.pdf\n"
+
+func main() {
+	mode := ""
+	flag.StringVar(&mode, "mode", "fit", "Template placement when the page sizes differ: fit, stretch or center")
+	flag.Parse()
+
+	if flag.NArg() < 3 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+	if mode != "fit" && mode != "stretch" && mode != "center" {
+		fmt.Printf("Error: invalid mode %q\n", mode)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	templatePath := flag.Arg(0)
+	inputPath := flag.Arg(1)
+	outputPath := flag.Arg(2)
+
+	err := overlayTemplate(templatePath, inputPath, outputPath, mode)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func overlayTemplate(templatePath, inputPath, outputPath, mode string) error {
+	// Load the template.  The file needs to remain open until the output has been written, as the template objects
+	// are loaded lazily from it.
+	ft, err := os.Open(templatePath)
+	if err != nil {
+		return err
+	}
+	defer ft.Close()
+
+	templateReader, err := openPdfReader(ft)
+	if err != nil {
+		return err
+	}
+
+	templatePage, err := templateReader.GetPage(1)
+	if err != nil {
+		return err
+	}
+
+	xform, tbox, err := pageToXObjectForm(templatePage)
+	if err != nil {
+		return err
+	}
+
+	// Load the target document.
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := openPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+
+		err = addTemplateToPage(page, xform, tbox, mode)
+		if err != nil {
+			return err
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+func openPdfReader(f *os.File) (*pdf.PdfReader, error) {
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("Need to decrypt with password")
+		}
+	}
+
+	return pdfReader, nil
+}
+
+// Converts a page to a Form XObject with the page contents and resources.  The BBox of the form is the page MediaBox,
+// which is also returned.
+func pageToXObjectForm(page *pdf.PdfPage) (*pdf.XObjectForm, *pdf.PdfRectangle, error) {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	xform := pdf.NewXObjectForm()
+	xform.Resources = page.Resources
+	xform.BBox = mbox.ToPdfObject()
+	xform.Filter = pdfcore.NewFlateEncoder()
+	err = xform.SetContentStream([]byte(contents), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return xform, mbox, nil
+}
+
+// Draws the template form underneath the existing content of the page.
+func addTemplateToPage(page *pdf.PdfPage, xform *pdf.XObjectForm, tbox *pdf.PdfRectangle, mode string) error {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return err
+	}
+
+	if page.Resources == nil {
+		page.Resources = pdf.NewPdfPageResources()
+	}
+
+	// Find an unused resource name.
+	name := pdfcore.PdfObjectName("Template")
+	for i := 1; page.Resources.HasXObjectByName(name); i++ {
+		name = pdfcore.PdfObjectName(fmt.Sprintf("Template%d", i))
+	}
+	err = page.Resources.SetXObjectFormByName(name, xform)
+	if err != nil {
+		return err
+	}
+
+	// Transformation from the template space to the page space.
+	tw, th := tbox.Urx-tbox.Llx, tbox.Ury-tbox.Lly
+	pw, ph := mbox.Urx-mbox.Llx, mbox.Ury-mbox.Lly
+	sx, sy := 1.0, 1.0
+	switch mode {
+	case "fit":
+		sx = math.Min(pw/tw, ph/th)
+		sy = sx
+	case "stretch":
+		sx = pw / tw
+		sy = ph / th
+	}
+	tx := mbox.Llx + (pw-tw*sx)/2 - tbox.Llx*sx
+	ty := mbox.Lly + (ph-th*sy)/2 - tbox.Lly*sy
+
+	prefix := fmt.Sprintf("q\n%.4f 0 0 %.4f %.4f %.4f cm\n/%s Do\nQ\n", sx, sy, tx, ty, name)
+
+	// Prepend the template to the existing content streams.
+	contentStreams, err := page.GetContentStreams()
+	if err != nil {
+		return err
+	}
+	contentStreams = append([]string{prefix}, contentStreams...)
+
+	return page.SetContentStreams(contentStreams, pdfcore.NewFlateEncoder())
+}
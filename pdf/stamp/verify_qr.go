@@ -0,0 +1,421 @@
+/*
+ * Stamp a QR code linking to a verification URL with a hash of the document content, and check the hash of a
+ * stamped document.
+ *
+ * The hash is computed before the stamp is written, as the URL in the QR code depends on it.  The stamp is part of
+ * the output document, so a hash of the output file would differ from the hash in the URL, and writing the file
+ * changes its bytes anyway (object numbers, compression, file identifier).  The hash is therefore a SHA-256 of the
+ * original content only, in a form that does not depend on how the file is written:
+ * - for each page in order, the page number, the MediaBox and the decoded content streams,
+ * - leaving out the content streams of verification stamps.
+ * The stamp is drawn by a content stream of its own, appended to the page: "q <matrix> cm /VerifyQR Do Q" with the
+ * QR code as a form XObject.  The original content streams are left as they are, so that the hash of the stamped
+ * document, without the stamp stream, is the hash of the original document.
+ *
+ * The hash covers the text and graphics of the pages, not the resources they use (fonts, images) or the other parts
+ * of the document (annotations, metadata): it shows that the content was not changed by mistake, but it is not a
+ * protection against forgery, which requires a digital signature.  The verification service would look the hash up
+ * in the list of documents it issued.  Note that an unlicensed copy of UniDoc adds a notice to each page when writing,
+ * after the stamp: this is content that the hash does not cover, so the verification of such files fails.
+ *
+ * The QR code is stamped on a page (-page) in a corner (-corner), with a Link annotation over it so that the URL can
+ * also be opened by clicking.  With -verify, the hash of a stamped document is computed again and compared with the
+ * hash at the end of the URL of the link.
+ *
+ * Run as: go run verify_qr.go [-url https://example.com/verify/] [-page 1] [-corner bottom-right] [-size 64]
+ *                             [-margin 20] input.pdf output.pdf
+ *     or: go run verify_qr.go -verify stamped.pdf
+ */
+/*
+ * NOTE: This example depends on github.com/boombuler/barcode, MIT licensed.
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run verify_qr.go [-url https://example.com/verify/] [-page 1] [-corner bottom-right] " +
+	"[-size 64] [-margin 20] input.pdf output.pdf\n" +
+	"   or: go run verify_qr.go -verify stamped.pdf\n"
+
+// Quiet zone around the QR code, in modules.
+const quietZone = 4
+
+// The content stream of a verification stamp, which is left out of the hash.
+var stampStream = regexp.MustCompile(`^q\n[-0-9. ]+ cm\n/VerifyQR\d* Do\nQ\n$`)
+
+// The hash at the end of a verification URL.
+var urlHash = regexp.MustCompile(`[0-9a-f]{64}$`)
+
+// stampOptions is the placement of the stamp.
+type stampOptions struct {
+	Page   int
+	Corner string
+	Size   float64
+	Margin float64
+}
+
+func main() {
+	baseURL := ""
+	verifyPath := ""
+	opts := stampOptions{}
+	flag.StringVar(&baseURL, "url", "https://example.com/verify/", "Verification URL, followed by the hash")
+	flag.IntVar(&opts.Page, "page", 1, "Page to stamp")
+	flag.StringVar(&opts.Corner, "corner", "bottom-right",
+		"Corner of the stamp: top-left, top-right, bottom-left or bottom-right")
+	flag.Float64Var(&opts.Size, "size", 64, "Size of the QR code (points)")
+	flag.Float64Var(&opts.Margin, "margin", 20, "Distance of the stamp from the page edges (points)")
+	flag.StringVar(&verifyPath, "verify", "", "Stamped file to verify")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	if verifyPath != "" {
+		ok, err := verifyFile(verifyPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(2)
+		}
+		return
+	}
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	switch opts.Corner {
+	case "top-left", "top-right", "bottom-left", "bottom-right":
+	default:
+		fmt.Printf("Error: invalid corner %q\n", opts.Corner)
+		os.Exit(1)
+	}
+	if opts.Size < 20 {
+		fmt.Printf("Error: -size must be at least 20 points\n")
+		os.Exit(1)
+	}
+
+	inputPath := flag.Arg(0)
+	outputPath := flag.Arg(1)
+
+	err := stampFile(inputPath, outputPath, baseURL, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// Stamps the QR code with the verification URL for the content of the input file.
+func stampFile(inputPath, outputPath, baseURL string, opts stampOptions) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := openPdfReader(f)
+	if err != nil {
+		return err
+	}
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+	if opts.Page < 1 || opts.Page > numPages {
+		return fmt.Errorf("Page %d out of range (1-%d)", opts.Page, numPages)
+	}
+
+	// The hash first, from the original content.
+	hash, err := contentHash(pdfReader)
+	if err != nil {
+		return err
+	}
+	url := baseURL + hash
+	fmt.Printf("Content hash: %s\n", hash)
+	fmt.Printf("Verification URL: %s\n", url)
+
+	qrCode, err := qr.Encode(url, qr.M, qr.Auto)
+	if err != nil {
+		return err
+	}
+	xform, err := makeQRCodeForm(qrCode)
+	if err != nil {
+		return err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+
+		if i+1 == opts.Page {
+			err = addStamp(page, xform, url, opts)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Computes the hash of the content of a stamped file again and compares it with the hash in the verification URL.
+// Returns whether they match.
+func verifyFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	pdfReader, err := openPdfReader(f)
+	if err != nil {
+		return false, err
+	}
+
+	url, err := findVerificationURL(pdfReader)
+	if err != nil {
+		return false, err
+	}
+	hash, err := contentHash(pdfReader)
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Printf("Verification URL: %s\n", url)
+	fmt.Printf("Content hash:     %s\n", hash)
+	if !strings.HasSuffix(url, hash) {
+		fmt.Printf("MISMATCH: the content was changed after stamping\n")
+		return false, nil
+	}
+	fmt.Printf("OK: the content matches the stamp\n")
+	return true, nil
+}
+
+// Returns the SHA-256 hash (hex) of the page contents of the document, without verification stamps.
+func contentHash(pdfReader *pdf.PdfReader) (string, error) {
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return "", err
+		}
+		mbox, err := page.GetMediaBox()
+		if err != nil {
+			return "", err
+		}
+		streams, err := page.GetContentStreams()
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "page %d\n%.4f %.4f %.4f %.4f\n", i+1, mbox.Llx, mbox.Lly, mbox.Urx, mbox.Ury)
+		for _, s := range streams {
+			if stampStream.MatchString(s) {
+				continue
+			}
+			h.Write([]byte(s))
+			h.Write([]byte("\n"))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns the URL of the first link of the document that ends with a hash.
+func findVerificationURL(pdfReader *pdf.PdfReader) (string, error) {
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return "", err
+		}
+		for _, annot := range page.Annotations {
+			link, ok := annot.GetContext().(*pdf.PdfAnnotationLink)
+			if !ok {
+				continue
+			}
+			action, ok := pdfcore.TraceToDirectObject(link.A).(*pdfcore.PdfObjectDictionary)
+			if !ok {
+				continue
+			}
+			uri, ok := pdfcore.TraceToDirectObject(action.Get("URI")).(*pdfcore.PdfObjectString)
+			if ok && urlHash.MatchString(string(*uri)) {
+				return string(*uri), nil
+			}
+		}
+	}
+	return "", errors.New("No verification link found")
+}
+
+// Makes a form XObject with the QR code, including the quiet zone, as vector rectangles: one unit per module, with
+// horizontal runs of dark modules merged into one rectangle.
+func makeQRCodeForm(qrCode barcode.Barcode) (*pdf.XObjectForm, error) {
+	bounds := qrCode.Bounds()
+	n := bounds.Dx() + 2*quietZone
+
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_g(1).
+		Add_re(0, 0, float64(n), float64(n)).
+		Add_f().
+		Add_g(0)
+	for j := bounds.Min.Y; j < bounds.Max.Y; j++ {
+		// Module rows are from the top, PDF coordinates from the bottom.
+		y := float64(n - quietZone - (j - bounds.Min.Y) - 1)
+		for i := bounds.Min.X; i < bounds.Max.X; {
+			if !isDark(qrCode, i, j) {
+				i++
+				continue
+			}
+			start := i
+			for i < bounds.Max.X && isDark(qrCode, i, j) {
+				i++
+			}
+			cc.Add_re(float64(quietZone+start-bounds.Min.X), y, float64(i-start), 1)
+		}
+	}
+	cc.Add_f()
+
+	xform := pdf.NewXObjectForm()
+	xform.BBox = pdfcore.MakeArrayFromFloats([]float64{0, 0, float64(n), float64(n)})
+	xform.Resources = pdf.NewPdfPageResources()
+	xform.Filter = pdfcore.NewFlateEncoder()
+	err := xform.SetContentStream(cc.Bytes(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return xform, nil
+}
+
+func isDark(bc barcode.Barcode, x, y int) bool {
+	r, _, _, _ := bc.At(x, y).RGBA()
+	return r == 0
+}
+
+// Draws the QR code form in the corner of the page, in a content stream of its own after the existing ones, and adds
+// a link to the URL over it.
+func addStamp(page *pdf.PdfPage, xform *pdf.XObjectForm, url string, opts stampOptions) error {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return err
+	}
+
+	if page.Resources == nil {
+		page.Resources = pdf.NewPdfPageResources()
+	}
+	name := pdfcore.PdfObjectName("VerifyQR")
+	for i := 1; page.Resources.HasXObjectByName(name); i++ {
+		name = pdfcore.PdfObjectName(fmt.Sprintf("VerifyQR%d", i))
+	}
+	err = page.Resources.SetXObjectFormByName(name, xform)
+	if err != nil {
+		return err
+	}
+
+	// The lower left corner of the stamp, in PDF coordinates.
+	x := mbox.Llx + opts.Margin
+	if strings.HasSuffix(opts.Corner, "right") {
+		x = mbox.Urx - opts.Margin - opts.Size
+	}
+	y := mbox.Lly + opts.Margin
+	if strings.HasPrefix(opts.Corner, "top") {
+		y = mbox.Ury - opts.Margin - opts.Size
+	}
+
+	bbox, err := xform.BBox.(*pdfcore.PdfObjectArray).ToFloat64Array()
+	if err != nil {
+		return err
+	}
+	scale := opts.Size / bbox[2]
+	stamp := fmt.Sprintf("q\n%.4f 0 0 %.4f %.4f %.4f cm\n/%s Do\nQ\n", scale, scale, x, y, name)
+
+	contentStreams, err := page.GetContentStreams()
+	if err != nil {
+		return err
+	}
+	contentStreams = append(contentStreams, stamp)
+	err = page.SetContentStreams(contentStreams, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return err
+	}
+
+	action := pdfcore.MakeDict()
+	action.Set("S", pdfcore.MakeName("URI"))
+	action.Set("URI", pdfcore.MakeString(url))
+
+	annot := pdf.NewPdfAnnotationLink()
+	annot.Rect = pdfcore.MakeArrayFromFloats([]float64{x, y, x + opts.Size, y + opts.Size})
+	annot.A = action
+	annot.Border = pdfcore.MakeArrayFromIntegers([]int{0, 0, 0})
+	page.Annotations = append(page.Annotations, annot.PdfAnnotation)
+	return nil
+}
+
+func openPdfReader(f *os.File) (*pdf.PdfReader, error) {
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("Need to decrypt with password")
+		}
+	}
+
+	return pdfReader, nil
+}
@@ -0,0 +1,366 @@
+/*
+ * Draw a table with merged cells, i.e. cells spanning multiple columns and/or rows.
+ *
+ * The creator Table of this unidoc version keeps the row and column span of its cells private and does not offer a
+ * setter for them, so this example does the span layout itself in a small SpanTable type:
+ * - Cells are added in row-major order with a row and column span.  An occupancy grid keeps track of the slots covered
+ *   by earlier spanning cells, so subsequent cells flow into the next free slot (skipping over slots covered by a
+ *   row span from a previous row).
+ * - Row heights are calculated from the cell contents.  When the content of a spanning cell is taller than the rows
+ *   it spans, the last spanned row grows by the difference.
+ * - Each cell is drawn as a single rectangle covering all its spanned rows and columns, so borders are drawn around
+ *   the merged area and not across it.
+ *
+ * The example builds a pricing matrix with a merged title row, a merged "Price per user" header spanning three columns,
+ * merged region label cells spanning several rows, and a merged "Contact sales" cell spanning the price columns.
+ *
+ * Run as: go run colspan_rowspan.go output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// SpanCell is a table cell which can span multiple rows and columns.
+type SpanCell struct {
+	row, col         int
+	rowspan, colspan int
+
+	content         *creator.Paragraph
+	backgroundColor creator.Color
+}
+
+// SetBackgroundColor sets the fill color of the (merged) cell area.
+func (cell *SpanCell) SetBackgroundColor(col creator.Color) {
+	cell.backgroundColor = col
+}
+
+// SpanTable is a table layout with support for row and column spans.
+type SpanTable struct {
+	colWidths []float64
+	cells     []*SpanCell
+
+	// Occupancy grid: occupied[row][col] is true if covered by a cell.
+	occupied [][]bool
+	curRow   int
+	curCol   int
+
+	MinRowHeight float64
+	Padding      float64
+	BorderWidth  float64
+	BorderColor  creator.Color
+}
+
+// NewSpanTable returns a new table with the specified column widths (in points).
+func NewSpanTable(colWidths ...float64) *SpanTable {
+	table := &SpanTable{}
+	table.colWidths = colWidths
+	table.MinRowHeight = 18
+	table.Padding = 4
+	table.BorderWidth = 1
+	table.BorderColor = creator.ColorRGBFrom8bit(0, 0, 0)
+	return table
+}
+
+// AddCell adds a cell with the given content spanning rowspan rows and colspan columns.  The cell is placed in the
+// next free slot, skipping over slots covered by previously added cells.
+func (table *SpanTable) AddCell(content *creator.Paragraph, rowspan, colspan int) (*SpanCell, error) {
+	numCols := len(table.colWidths)
+	if rowspan < 1 || colspan < 1 || colspan > numCols {
+		return nil, errors.New("Invalid span")
+	}
+
+	// Find the next free slot.
+	for table.isOccupied(table.curRow, table.curCol) {
+		table.advance()
+	}
+	if table.curCol+colspan > numCols {
+		return nil, fmt.Errorf("Cell spanning %d columns does not fit in row %d at column %d", colspan,
+			table.curRow+1, table.curCol+1)
+	}
+	for j := 0; j < colspan; j++ {
+		if table.isOccupied(table.curRow, table.curCol+j) {
+			return nil, fmt.Errorf("Cell at row %d, column %d overlaps a spanning cell", table.curRow+1,
+				table.curCol+j+1)
+		}
+	}
+
+	cell := &SpanCell{
+		row:     table.curRow,
+		col:     table.curCol,
+		rowspan: rowspan,
+		colspan: colspan,
+		content: content,
+	}
+	table.cells = append(table.cells, cell)
+
+	for i := 0; i < rowspan; i++ {
+		for j := 0; j < colspan; j++ {
+			table.setOccupied(cell.row+i, cell.col+j)
+		}
+	}
+
+	return cell, nil
+}
+
+func (table *SpanTable) isOccupied(row, col int) bool {
+	if row >= len(table.occupied) {
+		return false
+	}
+	return table.occupied[row][col]
+}
+
+func (table *SpanTable) setOccupied(row, col int) {
+	for row >= len(table.occupied) {
+		table.occupied = append(table.occupied, make([]bool, len(table.colWidths)))
+	}
+	table.occupied[row][col] = true
+}
+
+func (table *SpanTable) advance() {
+	table.curCol++
+	if table.curCol >= len(table.colWidths) {
+		table.curCol = 0
+		table.curRow++
+	}
+}
+
+// Total width of the columns spanned by the cell.
+func (table *SpanTable) cellWidth(cell *SpanCell) float64 {
+	w := 0.0
+	for j := 0; j < cell.colspan; j++ {
+		w += table.colWidths[cell.col+j]
+	}
+	return w
+}
+
+// Calculates the row heights from the cell contents.  Cells are processed in order of increasing row span so the
+// single row cells determine the base heights, and the spanning cells only add height where still needed.
+func (table *SpanTable) rowHeights() []float64 {
+	heights := make([]float64, len(table.occupied))
+	for i := range heights {
+		heights[i] = table.MinRowHeight
+	}
+
+	maxSpan := 1
+	for _, cell := range table.cells {
+		if cell.rowspan > maxSpan {
+			maxSpan = cell.rowspan
+		}
+	}
+
+	for span := 1; span <= maxSpan; span++ {
+		for _, cell := range table.cells {
+			if cell.rowspan != span {
+				continue
+			}
+			cell.content.SetWidth(table.cellWidth(cell) - 2*table.Padding)
+			need := cell.content.Height() + 2*table.Padding
+
+			have := 0.0
+			for i := 0; i < cell.rowspan; i++ {
+				have += heights[cell.row+i]
+			}
+			if need > have {
+				heights[cell.row+cell.rowspan-1] += need - have
+			}
+		}
+	}
+
+	return heights
+}
+
+// Draw draws the table at the current position of the creator and moves the position to below the table.  The table
+// is moved to a new page if it does not fit on the current one.
+func (table *SpanTable) Draw(c *creator.Creator) error {
+	heights := table.rowHeights()
+
+	// Cumulative offsets of rows and columns from the top left corner.
+	rowOffsets := make([]float64, len(heights)+1)
+	for i, h := range heights {
+		rowOffsets[i+1] = rowOffsets[i] + h
+	}
+	colOffsets := make([]float64, len(table.colWidths)+1)
+	for j, w := range table.colWidths {
+		colOffsets[j+1] = colOffsets[j] + w
+	}
+	totalHeight := rowOffsets[len(heights)]
+
+	ctx := c.Context()
+	if totalHeight > ctx.Height {
+		c.NewPage()
+		ctx = c.Context()
+	}
+	x0, y0 := ctx.X, ctx.Y
+
+	for _, cell := range table.cells {
+		x := x0 + colOffsets[cell.col]
+		y := y0 + rowOffsets[cell.row]
+		w := colOffsets[cell.col+cell.colspan] - colOffsets[cell.col]
+		h := rowOffsets[cell.row+cell.rowspan] - rowOffsets[cell.row]
+
+		// Background and border of the whole merged area.
+		rect := creator.NewRectangle(x, y, w, h)
+		if cell.backgroundColor != nil {
+			rect.SetFillColor(cell.backgroundColor)
+		}
+		rect.SetBorderColor(table.BorderColor)
+		rect.SetBorderWidth(table.BorderWidth)
+		err := c.Draw(rect)
+		if err != nil {
+			return err
+		}
+
+		// Content, centered vertically in the merged area.
+		p := cell.content
+		p.SetWidth(w - 2*table.Padding)
+		p.SetPos(x+table.Padding, y+(h-p.Height())/2)
+		err = c.Draw(p)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.MoveTo(x0, y0+totalHeight)
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run colspan_rowspan.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := drawPricingMatrix(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func drawPricingMatrix(outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	heading := creator.NewParagraph("Pricing matrix with merged cells")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 15)
+	c.Draw(heading)
+
+	table := NewSpanTable(100, 95, 100, 100, 100)
+
+	headerColor := creator.ColorRGBFrom8bit(52, 73, 94)
+	subHeaderColor := creator.ColorRGBFrom8bit(214, 224, 235)
+	labelColor := creator.ColorRGBFrom8bit(240, 240, 240)
+
+	// Adds a cell with text in the specified style.
+	addCell := func(text string, rowspan, colspan int, bold bool, align creator.TextAlignment,
+		bgColor creator.Color) error {
+		p := creator.NewParagraph(text)
+		p.SetFontSize(10)
+		if bold {
+			p.SetFont(fonts.NewFontHelveticaBold())
+		} else {
+			p.SetFont(fonts.NewFontHelvetica())
+		}
+		if bgColor == headerColor {
+			p.SetColor(creator.ColorWhite)
+		}
+		p.SetTextAlignment(align)
+
+		cell, err := table.AddCell(p, rowspan, colspan)
+		if err != nil {
+			return err
+		}
+		if bgColor != nil {
+			cell.SetBackgroundColor(bgColor)
+		}
+		return nil
+	}
+
+	center := creator.TextAlignmentCenter
+	left := creator.TextAlignmentLeft
+	right := creator.TextAlignmentRight
+
+	cells := []struct {
+		text             string
+		rowspan, colspan int
+		bold             bool
+		align            creator.TextAlignment
+		bgColor          creator.Color
+	}{
+		// Title row spanning all columns.
+		{"Subscription prices 2026 (USD)", 1, 5, true, center, headerColor},
+
+		// Two header rows: Region and Plan span both, Price per user spans the three price columns.
+		{"Region", 2, 1, true, center, subHeaderColor},
+		{"Plan", 2, 1, true, center, subHeaderColor},
+		{"Price per user", 1, 3, true, center, subHeaderColor},
+		{"Monthly", 1, 1, true, center, subHeaderColor},
+		{"Annual", 1, 1, true, center, subHeaderColor},
+		{"3 years", 1, 1, true, center, subHeaderColor},
+
+		// Region labels span the rows of their plans.
+		{"North America", 3, 1, true, left, labelColor},
+		{"Basic", 1, 1, false, left, nil},
+		{"12.00", 1, 1, false, right, nil},
+		{"120.00", 1, 1, false, right, nil},
+		{"330.00", 1, 1, false, right, nil},
+		{"Professional", 1, 1, false, left, nil},
+		{"25.00", 1, 1, false, right, nil},
+		{"250.00", 1, 1, false, right, nil},
+		{"690.00", 1, 1, false, right, nil},
+		{"Enterprise", 1, 1, false, left, nil},
+		{"Contact sales for volume pricing and custom terms", 1, 3, false, center, nil},
+
+		{"Europe (prices include VAT where applicable)", 4, 1, true, left, labelColor},
+		{"Basic", 1, 1, false, left, nil},
+		{"11.00", 1, 1, false, right, nil},
+		{"110.00", 1, 1, false, right, nil},
+		{"300.00", 1, 1, false, right, nil},
+		{"Professional", 1, 1, false, left, nil},
+		{"23.00", 1, 1, false, right, nil},
+		{"230.00", 1, 1, false, right, nil},
+		{"630.00", 1, 1, false, right, nil},
+		// Student plan: only offered monthly and annually, the 3 year cell spans both rows below.
+		{"Student", 1, 1, false, left, nil},
+		{"5.00", 1, 1, false, right, nil},
+		{"50.00", 1, 1, false, right, nil},
+		{"Not available", 2, 1, false, center, labelColor},
+		{"Enterprise", 1, 1, false, left, nil},
+		{"Contact sales", 1, 2, false, center, nil},
+
+		{"All prices are per user and exclude local taxes unless noted otherwise.", 1, 5, false, left, nil},
+	}
+	for _, cell := range cells {
+		err := addCell(cell.text, cell.rowspan, cell.colspan, cell.bold, cell.align, cell.bgColor)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := table.Draw(c)
+	if err != nil {
+		return err
+	}
+
+	note := creator.NewParagraph("Cells following a merged cell flow into the next free slot of the grid.")
+	note.SetFontSize(9)
+	note.SetMargins(0, 0, 10, 0)
+	c.Draw(note)
+
+	return c.WriteToFile(outputPath)
+}
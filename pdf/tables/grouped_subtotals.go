@@ -0,0 +1,302 @@
+/*
+ * Render a summary table of records grouped by a key, with a subtotal row after each group and a grand total row at
+ * the end.
+ *
+ * The records are sales by region.  Each group starts with a group header row, followed by its detail rows,
+ * indented, and a bold subtotal row.  The groups are listed in a given order, followed by any other keys in the order
+ * they appear in the records.  A region without sales still gets its group, with a "No records" row and a zero
+ * subtotal, so that it is visible in the summary rather than silently missing.
+ *
+ * Page breaks: as in zebra.go, the rows are split into one table per page, each starting with the column header row.
+ * When a group continues on the next page, its group header is repeated, marked "(continued)".  A group header is
+ * never the last row on a page: it is moved to the next page together with the first row of the group, and
+ * likewise the last row of a group is kept with the subtotal row.
+ *
+ * Run as: go run grouped_subtotals.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// Table styling.
+const (
+	rowHeight    = 18.0
+	headerHeight = 22.0
+	detailIndent = 20.0 // Indent of the first column of the detail rows.
+)
+
+var (
+	headerColor   = creator.ColorRGBFrom8bit(44, 62, 80)
+	groupColor    = creator.ColorRGBFrom8bit(214, 226, 240)
+	subtotalColor = creator.ColorRGBFrom8bit(240, 240, 240)
+	totalRowColor = creator.ColorRGBFrom8bit(255, 236, 153)
+	borderColor   = creator.ColorRGBFrom8bit(180, 180, 180)
+	columnWidths  = []float64{0.4, 0.2, 0.15, 0.25}
+	rightAligned  = []bool{false, false, true, true}
+)
+
+// rowStyle is the style of a table row.
+type rowStyle int
+
+const (
+	styleDetail rowStyle = iota
+	styleGroup
+	styleSubtotal
+	styleTotal
+)
+
+// sale is a record of the table.
+type sale struct {
+	Region  string
+	Product string
+	Quarter string
+	Units   int
+	Revenue float64
+}
+
+// group is the records with the same key.
+type group struct {
+	Key     string
+	Records []sale
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run grouped_subtotals.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := renderGroupedTable(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func renderGroupedTable(outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	heading := creator.NewParagraph("Sales by region")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 10)
+	c.Draw(heading)
+
+	// Sample data: the Central region has no sales, and Online is not one of the listed regions.
+	regions := []string{"North", "East", "South", "Central", "West"}
+	products := []string{"Desk lamp", "Office chair", "Standing desk", "Monitor arm", "Bookshelf", "Filing cabinet",
+		"Whiteboard", "Desk organizer"}
+	quarters := []string{"Q1", "Q2", "Q3", "Q4"}
+	records := []sale{}
+	for i := 0; i < 110; i++ {
+		region := regions[(i*3)%len(regions)]
+		if region == "Central" {
+			region = "Online"
+		}
+		units := 5 + (i*17)%60
+		records = append(records, sale{
+			Region:  region,
+			Product: products[(i*5)%len(products)],
+			Quarter: quarters[(i/7)%len(quarters)],
+			Units:   units,
+			Revenue: float64(units) * (19.5 + float64((i*11)%30)),
+		})
+	}
+
+	groups := groupByRegion(records, regions)
+	for _, g := range groups {
+		fmt.Printf("%s: %d records\n", g.Key, len(g.Records))
+	}
+
+	err := drawGroupedTable(c, groups)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// groupByRegion groups the records by region, with the groups of keys in order first, including those without
+// records, followed by the other regions in the order they first appear.
+func groupByRegion(records []sale, keys []string) []group {
+	groups := []group{}
+	index := map[string]int{}
+	for _, key := range keys {
+		index[key] = len(groups)
+		groups = append(groups, group{Key: key})
+	}
+	for _, r := range records {
+		i, ok := index[r.Region]
+		if !ok {
+			i = len(groups)
+			index[r.Region] = i
+			groups = append(groups, group{Key: r.Region})
+		}
+		groups[i].Records = append(groups[i].Records, r)
+	}
+	return groups
+}
+
+// groupedTable breaks the rows across pages, with a table per page that starts with the column header row.
+type groupedTable struct {
+	c      *creator.Creator
+	header []string
+	table  *creator.Table
+	rows   int     // Rows on the current page, not counting the header row.
+	avail  float64 // Space left on the current page.
+}
+
+// Draws the groups with their subtotals and the grand total.
+func drawGroupedTable(c *creator.Creator, groups []group) error {
+	t := &groupedTable{c: c, header: []string{"Product", "Quarter", "Units", "Revenue"}}
+	t.newTable()
+
+	totalUnits, totalRevenue := 0, 0.0
+	for _, g := range groups {
+		// Keep the group header with the first row, and an empty group together.
+		need := 2
+		if len(g.Records) == 0 {
+			need = 3
+		}
+		err := t.ensureSpace(need, "")
+		if err != nil {
+			return err
+		}
+		t.addRow([]string{g.Key, "", "", ""}, styleGroup)
+
+		units, revenue := 0, 0.0
+		if len(g.Records) == 0 {
+			t.addRow([]string{"No records", "", "", ""}, styleDetail)
+		}
+		for i, r := range g.Records {
+			// Keep the last row with the subtotal.
+			need := 1
+			if i == len(g.Records)-1 {
+				need = 2
+			}
+			err := t.ensureSpace(need, g.Key)
+			if err != nil {
+				return err
+			}
+			t.addRow([]string{r.Product, r.Quarter, fmt.Sprintf("%d", r.Units), fmt.Sprintf("%.2f", r.Revenue)},
+				styleDetail)
+			units += r.Units
+			revenue += r.Revenue
+		}
+
+		err = t.ensureSpace(1, g.Key)
+		if err != nil {
+			return err
+		}
+		t.addRow([]string{"Subtotal " + g.Key, "", fmt.Sprintf("%d", units), fmt.Sprintf("%.2f", revenue)},
+			styleSubtotal)
+		totalUnits += units
+		totalRevenue += revenue
+	}
+
+	err := t.ensureSpace(1, "")
+	if err != nil {
+		return err
+	}
+	t.addRow([]string{"Grand total", "", fmt.Sprintf("%d", totalUnits), fmt.Sprintf("%.2f", totalRevenue)},
+		styleTotal)
+	return t.c.Draw(t.table)
+}
+
+// Starts the table of a page with the column header row.
+func (t *groupedTable) newTable() {
+	t.table = creator.NewTable(len(t.header))
+	t.table.SetColumnWidths(columnWidths...)
+	t.rows = 0
+	t.avail = t.c.Context().Height - headerHeight
+
+	for col, text := range t.header {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(10)
+		p.SetColor(creator.ColorWhite)
+
+		cell := t.table.NewCell()
+		cell.SetBackgroundColor(headerColor)
+		cell.SetBorder(creator.CellBorderStyleBox, 1)
+		cell.SetBorderColor(headerColor)
+		cell.SetVerticalAlignment(creator.CellVerticalAlignmentMiddle)
+		if rightAligned[col] {
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+		}
+		cell.SetContent(p)
+	}
+	t.table.SetRowHeight(t.table.CurRow(), headerHeight)
+}
+
+// Moves to the next page unless there is space for n more rows.  If the rows continue a group, its header is
+// repeated on the next page.
+func (t *groupedTable) ensureSpace(n int, continuedGroup string) error {
+	if t.avail >= float64(n)*rowHeight {
+		return nil
+	}
+	if t.rows > 0 {
+		err := t.c.Draw(t.table)
+		if err != nil {
+			return err
+		}
+	}
+	t.c.NewPage()
+	t.newTable()
+	if continuedGroup != "" {
+		t.addRow([]string{continuedGroup + " (continued)", "", "", ""}, styleGroup)
+	}
+	return nil
+}
+
+// Adds a row of cells in the given style.
+func (t *groupedTable) addRow(values []string, style rowStyle) {
+	bgColor := creator.ColorWhite
+	switch style {
+	case styleGroup:
+		bgColor = groupColor
+	case styleSubtotal:
+		bgColor = subtotalColor
+	case styleTotal:
+		bgColor = totalRowColor
+	}
+
+	for col, text := range values {
+		p := creator.NewParagraph(text)
+		if style == styleDetail {
+			p.SetFont(fonts.NewFontHelvetica())
+		} else {
+			p.SetFont(fonts.NewFontHelveticaBold())
+		}
+		p.SetFontSize(10)
+
+		cell := t.table.NewCell()
+		cell.SetBackgroundColor(bgColor)
+		cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+		cell.SetBorderColor(borderColor)
+		cell.SetVerticalAlignment(creator.CellVerticalAlignmentMiddle)
+		if rightAligned[col] {
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+		}
+		if col == 0 && style == styleDetail {
+			cell.SetIndent(detailIndent)
+		}
+		cell.SetContent(p)
+	}
+	t.table.SetRowHeight(t.table.CurRow(), rowHeight)
+	t.rows++
+	t.avail -= rowHeight
+}
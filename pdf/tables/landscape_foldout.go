@@ -0,0 +1,274 @@
+/*
+ * Place a table that is too wide for a portrait page on a page of its own, turned by 90 degrees, in an otherwise
+ * portrait document.  The page itself stays portrait, like the page of a printed book: the table is read by turning
+ * the sheet clockwise, with the top of the table at the left edge of the page.
+ *
+ * The table is drawn into a creator block of landscape proportions, as large as the content area of the portrait
+ * page turned on its side, and the block is rotated by 90 degrees onto the page.  The creator rotates blocks
+ * counterclockwise about their upper left corner, so the block is positioned with that corner at the bottom left of
+ * the content area: after the rotation its width runs up the page and its height across it.
+ *
+ * The header and footer are drawn with the DrawHeader and DrawFooter callbacks on every page and are not rotated, so
+ * they stay in the portrait reading orientation and line up with the other pages when the document is printed and
+ * bound.  The header of the rotated page names the table and tells the reader to turn the page.
+ *
+ * The whole table must fit on the rotated page; an error is returned if it has too many rows.
+ *
+ * Run as: go run landscape_foldout.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const pageMargin = 50.0
+
+var (
+	headerColor = creator.ColorRGBFrom8bit(44, 62, 80)
+	mutedColor  = creator.ColorRGBFrom8bit(120, 120, 120)
+	boxColor    = creator.ColorRGBFrom8bit(214, 226, 240)
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run landscape_foldout.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := createReport(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createReport(outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(pageMargin, pageMargin, pageMargin, pageMargin)
+
+	// Page numbers of the rotated pages and the captions of their tables, for the header.
+	rotated := map[int]string{}
+
+	c.DrawHeader(func(block *creator.Block, args creator.HeaderFunctionArgs) {
+		title := newParagraph("Annual Sales Report 2025", fonts.NewFontHelveticaBold(), 9, headerColor)
+		title.SetPos(pageMargin, 25)
+		_ = block.Draw(title)
+
+		if caption, ok := rotated[args.PageNum]; ok {
+			info := newParagraph(caption+" (turn the page to read)", fonts.NewFontHelvetica(), 9, mutedColor)
+			info.SetWidth(c.Width() - 2*pageMargin)
+			info.SetTextAlignment(creator.TextAlignmentRight)
+			info.SetPos(pageMargin, 25)
+			_ = block.Draw(info)
+		}
+	})
+	c.DrawFooter(func(block *creator.Block, args creator.FooterFunctionArgs) {
+		p := newParagraph(fmt.Sprintf("Page %d of %d", args.PageNum, args.TotalPages), fonts.NewFontHelvetica(), 9,
+			mutedColor)
+		p.SetWidth(c.Width())
+		p.SetTextAlignment(creator.TextAlignmentCenter)
+		p.SetPos(0, block.Height()-25)
+		_ = block.Draw(p)
+	})
+
+	c.NewPage()
+	err := drawHeading(c, "Sales overview")
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 4; i++ {
+		err := drawText(c, strings.Repeat(fmt.Sprintf("Paragraph %d of the overview.  The monthly figures of all "+
+			"regions are given in Table 1 on the next page, which is turned on its side to fit the width of the "+
+			"table. ", i+1), 2))
+		if err != nil {
+			return err
+		}
+	}
+
+	// The rotated table on a page of its own.
+	caption := "Table 1: Monthly sales by region (thousand units)"
+	table, err := newSalesTable()
+	if err != nil {
+		return err
+	}
+	c.NewPage()
+	err = drawRotated(c, caption, table)
+	if err != nil {
+		return err
+	}
+	rotated[c.Context().Page] = "Table 1"
+
+	c.NewPage()
+	err = drawHeading(c, "Outlook")
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 3; i++ {
+		err := drawText(c, strings.Repeat(fmt.Sprintf("Paragraph %d of the outlook, back on an upright portrait "+
+			"page. ", i+1), 4))
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 1; i <= c.Context().Page; i++ {
+		if caption, ok := rotated[i]; ok {
+			fmt.Printf("Page %d: %s, rotated 90 degrees\n", i, caption)
+		} else {
+			fmt.Printf("Page %d: portrait text\n", i)
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawRotated draws the caption and the table on the current page, turned 90 degrees counterclockwise to fill the
+// content area of the page.
+func drawRotated(c *creator.Creator, caption string, table *creator.Table) error {
+	// The block is the content area turned on its side: its width is the height of the content area.
+	width := c.Height() - 2*pageMargin
+	height := c.Width() - 2*pageMargin
+	block := creator.NewBlock(width, height)
+
+	p := newParagraph(caption, fonts.NewFontHelveticaBold(), 12, headerColor)
+	p.SetPos(0, 0)
+	err := block.Draw(p)
+	if err != nil {
+		return err
+	}
+
+	table.SetMargins(0, 0, 24, 0)
+	if table.Height()+24 > height {
+		return fmt.Errorf("Table too long for the rotated page: %.0f points, %.0f available", table.Height(),
+			height-24)
+	}
+	err = block.Draw(table)
+	if err != nil {
+		return err
+	}
+
+	// The block is rotated about its upper left corner, which goes to the bottom left of the content area.
+	block.SetAngle(90)
+	block.SetPos(pageMargin, c.Height()-pageMargin)
+	return c.Draw(block)
+}
+
+// newSalesTable returns a table with a column for each month, too wide for a portrait page.
+func newSalesTable() (*creator.Table, error) {
+	months := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+	regions := []string{"North America", "Central America", "South America", "Western Europe", "Northern Europe",
+		"Southern Europe", "Eastern Europe", "North Africa", "West Africa", "East Africa", "Southern Africa",
+		"Middle East", "Central Asia", "South Asia", "East Asia", "Southeast Asia", "Australia", "New Zealand",
+		"Pacific Islands", "Caribbean"}
+
+	table := creator.NewTable(2 + len(months))
+	widths := []float64{0.16}
+	for range months {
+		widths = append(widths, 0.76/float64(len(months)))
+	}
+	widths = append(widths, 0.08)
+	err := table.SetColumnWidths(widths...)
+	if err != nil {
+		return nil, err
+	}
+
+	addCell := func(text string, header, right bool) error {
+		var font fonts.Font = fonts.NewFontHelvetica()
+		if header {
+			font = fonts.NewFontHelveticaBold()
+		}
+		cell := table.NewCell()
+		cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+		if header {
+			cell.SetBackgroundColor(boxColor)
+		}
+		if right {
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+		}
+		return cell.SetContent(newParagraph(text, font, 9, creator.ColorBlack))
+	}
+
+	header := append(append([]string{"Region"}, months...), "Total")
+	for i, text := range header {
+		err := addCell(text, true, i > 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	table.SetRowHeight(table.CurRow(), 20)
+
+	monthTotals := make([]int, len(months))
+	for i, region := range regions {
+		err := addCell(region, false, false)
+		if err != nil {
+			return nil, err
+		}
+		total := 0
+		for m := range months {
+			value := 40 + (i*37+m*53)%160
+			total += value
+			monthTotals[m] += value
+			err := addCell(fmt.Sprintf("%d", value), false, true)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = addCell(fmt.Sprintf("%d", total), true, true)
+		if err != nil {
+			return nil, err
+		}
+		table.SetRowHeight(table.CurRow(), 18)
+	}
+
+	err = addCell("Total", true, false)
+	if err != nil {
+		return nil, err
+	}
+	total := 0
+	for _, value := range monthTotals {
+		total += value
+		err := addCell(fmt.Sprintf("%d", value), true, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = addCell(fmt.Sprintf("%d", total), true, true)
+	if err != nil {
+		return nil, err
+	}
+	table.SetRowHeight(table.CurRow(), 20)
+
+	return table, nil
+}
+
+func drawHeading(c *creator.Creator, text string) error {
+	p := newParagraph(text, fonts.NewFontHelveticaBold(), 18, headerColor)
+	p.SetMargins(0, 0, 0, 12)
+	return c.Draw(p)
+}
+
+func drawText(c *creator.Creator, text string) error {
+	p := newParagraph(text, fonts.NewFontHelvetica(), 11, creator.ColorBlack)
+	p.SetLineHeight(1.3)
+	p.SetMargins(0, 0, 0, 10)
+	return c.Draw(p)
+}
+
+func newParagraph(text string, font fonts.Font, size float64, color creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(size)
+	p.SetColor(color)
+	return p
+}
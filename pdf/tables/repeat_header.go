@@ -0,0 +1,217 @@
+/*
+ * Break a long table (hundreds of rows) across many pages, repeating the header row(s) at the top of every page.
+ *
+ * Rows are added either as header rows or as body rows.  The creator table of this unidoc version has no notion of
+ * header rows and simply continues on the next page when a row does not fit, so the RepeatHeaderTable below does the
+ * page breaking itself:
+ * - The height of each row is measured the same way the creator table sizes its rows (wrapped paragraph height plus
+ *   padding), so rows with long, wrapping content are accounted for.
+ * - The height of the header rows is subtracted from the space available on each page before the body rows are
+ *   placed, i.e. the header height is excluded from the available content height.
+ * - Each page gets its own creator table, starting with the header rows followed by the body rows that fit.
+ *
+ * The final page may hold only a couple of rows; it still gets the full header.  The page layout is printed out when
+ * running the example.
+ *
+ * Run as: go run repeat_header.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// Cell layout parameters, matching the creator table defaults.
+const (
+	cellIndent       = 5.0  // Default left indent of table cells.
+	defaultRowHeight = 10.0 // Default minimum row height of the creator table.
+	fontSize         = 9.0
+)
+
+// RepeatHeaderTable is a table which repeats its header rows on every page it spans.
+type RepeatHeaderTable struct {
+	colWidths  []float64 // Fractions of the available width, adding up to 1.
+	headerRows [][]string
+	bodyRows   [][]string
+}
+
+// NewRepeatHeaderTable returns a new table with the specified fractional column widths.
+func NewRepeatHeaderTable(colWidths ...float64) *RepeatHeaderTable {
+	return &RepeatHeaderTable{colWidths: colWidths}
+}
+
+// AddHeaderRow adds a header row, which is drawn at the top of every page.
+func (t *RepeatHeaderTable) AddHeaderRow(values ...string) {
+	t.headerRows = append(t.headerRows, values)
+}
+
+// AddRow adds a body row.
+func (t *RepeatHeaderTable) AddRow(values ...string) {
+	t.bodyRows = append(t.bodyRows, values)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run repeat_header.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := renderLongTable(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func renderLongTable(outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	heading := creator.NewParagraph("Event log")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 10)
+	c.Draw(heading)
+
+	table := NewRepeatHeaderTable(0.08, 0.2, 0.17, 0.55)
+	table.AddHeaderRow("#", "Timestamp", "Source", "Message")
+
+	// Some messages are long and wrap over multiple lines, giving rows of different heights.  The number of rows is
+	// chosen so that the last page only has a couple of rows.
+	words := strings.Fields("the quick brown fox jumps over the lazy dog while the service restarts and reconnects " +
+		"to the database after a timeout")
+	sources := []string{"api-gateway", "scheduler", "billing", "auth-service", "storage"}
+	for i := 0; i < 390; i++ {
+		msg := strings.Join(words[:1+(i*7)%len(words)], " ")
+		if i%11 == 0 {
+			msg += ". " + strings.Join(words, " ") + "."
+		}
+		table.AddRow(
+			fmt.Sprintf("%d", i+1),
+			fmt.Sprintf("2026-03-%02d %02d:%02d:%02d", 1+i/96, (i/4)%24, (i*15)%60, (i*37)%60),
+			sources[i%len(sources)],
+			msg,
+		)
+	}
+
+	pages, err := table.Draw(c)
+	if err != nil {
+		return err
+	}
+
+	for i, n := range pages {
+		fmt.Printf("Page %d: %d body rows\n", i+1, n)
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Draw draws the table starting at the current position, breaking it across pages as needed and repeating the header
+// rows at the top of each page.  Returns the number of body rows placed on each page.
+func (t *RepeatHeaderTable) Draw(c *creator.Creator) ([]int, error) {
+	tableWidth := c.Context().Width
+
+	headerHeight := 0.0
+	for _, values := range t.headerRows {
+		headerHeight += t.rowHeight(values, tableWidth, true)
+	}
+
+	pages := []int{}
+	rowIdx := 0
+	freshPage := false // Nothing drawn on the page yet.
+	for rowIdx < len(t.bodyRows) {
+		// Space available for body rows: the header rows take up part of the page.
+		avail := c.Context().Height - headerHeight
+
+		table := t.newPageTable()
+		count := 0
+		for rowIdx < len(t.bodyRows) {
+			h := t.rowHeight(t.bodyRows[rowIdx], tableWidth, false)
+			// A row taller than a whole page is placed anyway, otherwise continue on the next page.
+			if h > avail && (count > 0 || !freshPage) {
+				break
+			}
+			t.addRow(table, t.bodyRows[rowIdx], false)
+			avail -= h
+			rowIdx++
+			count++
+		}
+
+		if count > 0 {
+			err := c.Draw(table)
+			if err != nil {
+				return nil, err
+			}
+			pages = append(pages, count)
+		}
+		if rowIdx < len(t.bodyRows) {
+			c.NewPage()
+			freshPage = true
+		}
+	}
+
+	return pages, nil
+}
+
+// Creates a creator table for a single page, containing the header rows.
+func (t *RepeatHeaderTable) newPageTable() *creator.Table {
+	table := creator.NewTable(len(t.colWidths))
+	table.SetColumnWidths(t.colWidths...)
+	for _, values := range t.headerRows {
+		t.addRow(table, values, true)
+	}
+	return table
+}
+
+func (t *RepeatHeaderTable) addRow(table *creator.Table, values []string, header bool) {
+	for _, text := range values {
+		cell := table.NewCell()
+		cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+		if header {
+			cell.SetBackgroundColor(creator.ColorRGBFrom8bit(220, 220, 220))
+		}
+		cell.SetContent(t.newParagraph(text, header))
+	}
+}
+
+func (t *RepeatHeaderTable) newParagraph(text string, header bool) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	if header {
+		p.SetFont(fonts.NewFontHelveticaBold())
+	} else {
+		p.SetFont(fonts.NewFontHelvetica())
+	}
+	p.SetFontSize(fontSize)
+	p.SetLineHeight(1.1)
+	// Paragraphs in table cells are not wrapped unless enabled explicitly.
+	p.SetEnableWrap(true)
+	p.SetMargins(0, 0, 0, 2)
+	return p
+}
+
+// Measures the height of a row the way the creator table sizes rows: the tallest wrapped paragraph plus its bottom
+// margin (counted twice by the table) and half a line of top padding.
+func (t *RepeatHeaderTable) rowHeight(values []string, tableWidth float64, header bool) float64 {
+	h := defaultRowHeight
+	for col, text := range values {
+		p := t.newParagraph(text, header)
+		p.SetWidth(t.colWidths[col]*tableWidth - cellIndent)
+		_, _, _, bottom := p.GetMargins()
+		ph := p.Height() + 2*bottom + 0.5*fontSize*1.1
+		if ph > h {
+			h = ph
+		}
+	}
+	return h
+}
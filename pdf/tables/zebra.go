@@ -0,0 +1,196 @@
+/*
+ * Render a data table with alternating row colors (zebra striping).
+ *
+ * The background color of each data row is chosen from the row index while the cells are populated: even rows get one
+ * color and odd rows another.  The header row has its own distinct style and is repeated at the top of every page, and
+ * the final "total" row is drawn with a highlight color.
+ *
+ * Striping across page breaks: the creator table does not repeat header rows by itself, so the rows are split into
+ * one table per page, each starting with a copy of the header.  The stripe color is based on the index of the row
+ * within the data (not within the table on the page and not counting the header rows), so the alternation continues
+ * seamlessly from one page to the next.
+ *
+ * Run as: go run zebra.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+// Table styling.
+const (
+	rowHeight    = 18.0
+	headerHeight = 22.0
+)
+
+var (
+	headerColor   = creator.ColorRGBFrom8bit(44, 62, 80)
+	evenRowColor  = creator.ColorRGBFrom8bit(255, 255, 255)
+	oddRowColor   = creator.ColorRGBFrom8bit(232, 240, 248)
+	totalRowColor = creator.ColorRGBFrom8bit(255, 236, 153)
+	borderColor   = creator.ColorRGBFrom8bit(180, 180, 180)
+	columnWidths  = []float64{0.15, 0.45, 0.1, 0.15, 0.15}
+	rightAligned  = []bool{false, false, true, true, true}
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run zebra.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	outputPath := os.Args[1]
+
+	err := renderZebraTable(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func renderZebraTable(outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	heading := creator.NewParagraph("Order lines")
+	heading.SetFont(fonts.NewFontHelveticaBold())
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 10)
+	c.Draw(heading)
+
+	// Sample data, enough to span several pages.
+	header := []string{"Item no.", "Description", "Qty", "Unit price", "Amount"}
+	rows := [][]string{}
+	sum := 0.0
+	for i := 0; i < 95; i++ {
+		qty := 1 + (i*7)%12
+		price := 2.5 + float64((i*13)%40)
+		amount := float64(qty) * price
+		sum += amount
+		rows = append(rows, []string{
+			fmt.Sprintf("A-%04d", 1000+i*3),
+			fmt.Sprintf("Product line item number %d", i+1),
+			fmt.Sprintf("%d", qty),
+			fmt.Sprintf("%.2f", price),
+			fmt.Sprintf("%.2f", amount),
+		})
+	}
+	total := []string{"", "Total", "", "", fmt.Sprintf("%.2f", sum)}
+
+	err := drawStripedTable(c, header, rows, total)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// Draws the rows as striped tables, one per page, each with the header row on top, followed by the highlighted
+// total row.
+func drawStripedTable(c *creator.Creator, header []string, rows [][]string, total []string) error {
+	rowIdx := 0
+	for {
+		// Number of data rows that fit on this page below the header.
+		avail := c.Context().Height - headerHeight
+		fit := int(avail / rowHeight)
+		if fit < 1 {
+			c.NewPage()
+			continue
+		}
+
+		remaining := len(rows) - rowIdx
+		if remaining <= fit-1 {
+			// All remaining rows and the total row fit.
+			table := newStripedTable(header)
+			for ; rowIdx < len(rows); rowIdx++ {
+				addRow(table, rows[rowIdx], stripeColor(rowIdx), false)
+			}
+			addRow(table, total, totalRowColor, true)
+			return c.Draw(table)
+		}
+
+		// Fill the page.  If all the rows fit but not the total row, keep at least one data row for the next page,
+		// so the total row does not end up on a page of its own.
+		n := fit
+		if remaining <= fit {
+			n = remaining - 1
+		}
+		table := newStripedTable(header)
+		for i := 0; i < n; i++ {
+			addRow(table, rows[rowIdx], stripeColor(rowIdx), false)
+			rowIdx++
+		}
+		err := c.Draw(table)
+		if err != nil {
+			return err
+		}
+		c.NewPage()
+	}
+}
+
+// Returns the background color for data row rowIdx (0-based index within all data rows).
+func stripeColor(rowIdx int) creator.Color {
+	if rowIdx%2 == 0 {
+		return evenRowColor
+	}
+	return oddRowColor
+}
+
+// Creates a new table with the header row.
+func newStripedTable(header []string) *creator.Table {
+	table := creator.NewTable(len(header))
+	table.SetColumnWidths(columnWidths...)
+
+	for col, text := range header {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(10)
+		p.SetColor(creator.ColorWhite)
+
+		cell := table.NewCell()
+		cell.SetBackgroundColor(headerColor)
+		cell.SetBorder(creator.CellBorderStyleBox, 1)
+		cell.SetBorderColor(headerColor)
+		cell.SetVerticalAlignment(creator.CellVerticalAlignmentMiddle)
+		if rightAligned[col] {
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+		}
+		cell.SetContent(p)
+	}
+	table.SetRowHeight(table.CurRow(), headerHeight)
+
+	return table
+}
+
+// Adds a row of cells with the given background color.
+func addRow(table *creator.Table, values []string, bgColor creator.Color, bold bool) {
+	for col, text := range values {
+		p := creator.NewParagraph(text)
+		if bold {
+			p.SetFont(fonts.NewFontHelveticaBold())
+		} else {
+			p.SetFont(fonts.NewFontHelvetica())
+		}
+		p.SetFontSize(10)
+
+		cell := table.NewCell()
+		cell.SetBackgroundColor(bgColor)
+		cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+		cell.SetBorderColor(borderColor)
+		cell.SetVerticalAlignment(creator.CellVerticalAlignmentMiddle)
+		if rightAligned[col] {
+			cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+		}
+		cell.SetContent(p)
+	}
+	table.SetRowHeight(table.CurRow(), rowHeight)
+}
@@ -0,0 +1,210 @@
+/*
+ * Check that the copies of the files shared by examples in several directories are the same as the originals.
+ *
+ * go run takes the files of a program from a single directory, so the examples that share code with examples in
+ * other directories have copies of the shared file, such as the glyph positioning pdf/qa/text_layout.go, the page
+ * renderer pdf/render/render.go and the incremental update writer pdf/incremental/update.go.  The copies name the
+ * original in their header comment ("This is a copy of pdf/..."), and this checks that each top-level declaration of
+ * the original is in the copy, with the same source and doc comment.  A file that is a copy as a whole ("This is a
+ * copy of") must not have other declarations; a file with a copy of some of its code, such as pdf/sign/signing.go,
+ * may.  The header comments and the imports are not compared.
+ *
+ * The differing declarations are listed, and the exit status is 1 if a copy differs from its original.  Run it after
+ * changing a shared file, when copying the change.
+ *
+ * Run as: go run check_copies.go [-root ../..]
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const usage = "Usage: go run check_copies.go [-root ../..]\n"
+
+// The original named in the header comment of a copy, with the line breaks of the comment removed.
+var copyRegexp = regexp.MustCompile(`(This is a|is a) copy of (pdf/[\w/.-]+\.go)`)
+
+func main() {
+	root := ""
+	flag.StringVar(&root, "root", "../..", "Root directory of the repository")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	differences, err := checkCopies(root)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if differences > 0 {
+		fmt.Printf("%d differences: copy the changes from the originals\n", differences)
+		os.Exit(1)
+	}
+	fmt.Printf("All copies are the same as their originals\n")
+}
+
+// Checks the copies in the pdf directory of the repository, and returns the number of differences.
+func checkCopies(root string) (int, error) {
+	paths := []string{}
+	err := filepath.Walk(filepath.Join(root, "pdf"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(path, ".go") {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	differences := 0
+	for _, path := range paths {
+		file, err := parseFile(path)
+		if err != nil {
+			return 0, err
+		}
+		m := copyRegexp.FindStringSubmatch(file.header)
+		if m == nil {
+			continue
+		}
+		whole := m[1] == "This is a"
+		original, err := parseFile(filepath.Join(root, filepath.FromSlash(m[2])))
+		if err != nil {
+			return 0, err
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		diffs := compareDecls(original.decls, file.decls, whole)
+		for _, diff := range diffs {
+			fmt.Printf("%s: %s (original %s)\n", filepath.ToSlash(rel), diff, m[2])
+		}
+		if len(diffs) == 0 {
+			fmt.Printf("%s: same as %s\n", filepath.ToSlash(rel), m[2])
+		}
+		differences += len(diffs)
+	}
+	return differences, nil
+}
+
+// goFile is a parsed Go file: the header comment, with the line breaks and comment markers removed, and the source of
+// the top-level declarations (other than imports) with their doc comments, by name.
+type goFile struct {
+	header string
+	decls  map[string]string
+}
+
+func parseFile(path string) (*goFile, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &goFile{decls: map[string]string{}}
+	if len(f.Comments) > 0 && f.Comments[0].Pos() < f.Package {
+		words := []string{}
+		for _, line := range strings.Split(f.Comments[0].Text(), "\n") {
+			line = strings.TrimPrefix(strings.TrimSpace(line), "*")
+			words = append(words, strings.Fields(line)...)
+		}
+		file.header = strings.Join(words, " ")
+	}
+
+	source := func(node ast.Node, doc *ast.CommentGroup) string {
+		start := node.Pos()
+		if doc != nil {
+			start = doc.Pos()
+		}
+		return string(src[fset.Position(start).Offset:fset.Position(node.End()).Offset])
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = receiverName(d.Recv.List[0].Type) + "." + name
+			}
+			file.decls[name] = source(d, d.Doc)
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			names := []string{}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						names = append(names, n.Name)
+					}
+				}
+			}
+			file.decls[strings.Join(names, ",")] = source(d, d.Doc)
+		}
+	}
+	return file, nil
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
+
+// Returns the differences of the declarations of the copy from those of the original, sorted by name.  If whole is
+// set, the copy must not have other declarations.
+func compareDecls(original, copied map[string]string, whole bool) []string {
+	names := []string{}
+	for name := range original {
+		names = append(names, name)
+	}
+	if whole {
+		for name := range copied {
+			if _, ok := original[name]; !ok {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	diffs := []string{}
+	for _, name := range names {
+		orig, inOriginal := original[name]
+		cp, inCopy := copied[name]
+		switch {
+		case !inCopy:
+			diffs = append(diffs, fmt.Sprintf("%s is missing", name))
+		case !inOriginal:
+			diffs = append(diffs, fmt.Sprintf("%s is not in the original", name))
+		case orig != cp:
+			diffs = append(diffs, fmt.Sprintf("%s differs", name))
+		}
+	}
+	return diffs
+}
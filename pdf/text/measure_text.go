@@ -0,0 +1,401 @@
+/*
+ * Measure the width and height of text for a font and font size from the font metrics, and use the measurements to
+ * lay out text without a table: labels right aligned to a column next to their values, and values right aligned to
+ * the right margin with dot leaders from their labels.
+ *
+ * TextMeasurer measures text the same way the creator's paragraphs do: the width is the sum of the glyph widths
+ * (in thousandths of the font size) and the height of a line is the font size times the line height.  The ascent and
+ * descent, how far the glyphs extend above and below the baseline, are not part of the font metrics in this unidoc
+ * version, so they are given separately: read from the TrueType file for TrueType fonts (-font), and taken from the
+ * Adobe font metrics (AFM) files for the standard fonts.
+ *
+ * Fallbacks for fonts without full metrics:
+ * - Characters that the encoding or the font has no metrics for, for example "→" in the standard fonts, which only
+ *   cover the Latin character set, are measured as half the font size wide.  Width returns the number of such
+ *   characters, so that the caller can tell an exact width from an estimate.  (The creator's paragraphs cannot draw
+ *   these characters, so the estimate is only useful to reserve space.)
+ * - Without ascent and descent, 0.75 and 0.25 of the font size are used, which is close for most Latin fonts.
+ *
+ * The page shows boxes of the measured sizes around sample text, and the measured widths are printed and compared
+ * to the width of a creator paragraph with the same text.
+ *
+ * Run as: go run measure_text.go [-font font.ttf] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+const usage = "Usage: go run measure_text.go [-font font.ttf] output.pdf\n"
+
+const pageMargin = 60.0
+
+// Ascent and descent of the standard fonts in thousandths of the font size, from their AFM files.
+var standardFontMetrics = map[string][2]float64{
+	"Helvetica":      {718, -207},
+	"Helvetica-Bold": {718, -207},
+	"Times-Roman":    {683, -217},
+	"Courier":        {629, -157},
+}
+
+var (
+	boxColor      = creator.ColorRGBFrom8bit(41, 128, 185)
+	baselineColor = creator.ColorRGBFrom8bit(231, 76, 60)
+	mutedColor    = creator.ColorRGBFrom8bit(120, 120, 120)
+)
+
+// TextMeasurer measures text set in a font at a font size.
+type TextMeasurer struct {
+	font     fonts.Font
+	encoder  textencoding.TextEncoder
+	fontSize float64
+	ascent   float64 // Thousandths of the font size above the baseline.
+	descent  float64 // Thousandths of the font size below the baseline, negative.
+}
+
+// NewTextMeasurer returns a measurer for the font at the font size, with the WinAnsi encoding that the creator uses
+// and the default ascent and descent.
+func NewTextMeasurer(font fonts.Font, fontSize float64) *TextMeasurer {
+	return &TextMeasurer{
+		font:     font,
+		encoder:  textencoding.NewWinAnsiTextEncoder(),
+		fontSize: fontSize,
+		ascent:   750,
+		descent:  -250,
+	}
+}
+
+// SetAscentDescent sets the ascent and descent of the font, in thousandths of the font size.
+func (m *TextMeasurer) SetAscentDescent(ascent, descent float64) {
+	m.ascent = ascent
+	m.descent = descent
+}
+
+// Width returns the width of the text in points and the number of characters without metrics, which are counted as
+// half the font size wide.
+func (m *TextMeasurer) Width(text string) (float64, int) {
+	width := 0.0
+	missing := 0
+	for _, r := range text {
+		glyph, found := m.encoder.RuneToGlyph(r)
+		if found {
+			var metrics fonts.CharMetrics
+			metrics, found = m.font.GetGlyphCharMetrics(glyph)
+			if found {
+				width += m.fontSize * metrics.Wx / 1000.0
+				continue
+			}
+		}
+		width += m.fontSize / 2
+		missing++
+	}
+	return width, missing
+}
+
+// Height returns the height of lines of text with the line height (relative to the font size), as the creator's
+// paragraphs lay them out.  The baseline of each line is at the bottom of its line height.
+func (m *TextMeasurer) Height(lines int, lineHeight float64) float64 {
+	return float64(lines) * lineHeight * m.fontSize
+}
+
+// Ascent returns how far the glyphs extend above the baseline, in points.
+func (m *TextMeasurer) Ascent() float64 {
+	return m.ascent * m.fontSize / 1000.0
+}
+
+// Descent returns how far the glyphs extend below the baseline, in points (positive).
+func (m *TextMeasurer) Descent() float64 {
+	return -m.descent * m.fontSize / 1000.0
+}
+
+// sampleFont is a font to measure, with its name.
+type sampleFont struct {
+	name string
+	font fonts.Font
+	// Ascent and descent in thousandths of the font size, or zero if not known.
+	ascent, descent float64
+}
+
+func main() {
+	fontPath := ""
+	flag.StringVar(&fontPath, "font", "", "TrueType font file to measure in addition to the standard fonts")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	samples := []sampleFont{}
+	for _, f := range []struct {
+		name string
+		font fonts.Font
+	}{
+		{"Helvetica", fonts.NewFontHelvetica()},
+		{"Helvetica-Bold", fonts.NewFontHelveticaBold()},
+		{"Times-Roman", fonts.NewFontTimesRoman()},
+		{"Courier", fonts.NewFontCourier()},
+	} {
+		metrics := standardFontMetrics[f.name]
+		samples = append(samples, sampleFont{f.name, f.font, metrics[0], metrics[1]})
+	}
+	if fontPath != "" {
+		sample, err := loadTrueTypeFont(fontPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		samples = append(samples, sample)
+	}
+
+	err := createPage(samples, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// loadTrueTypeFont loads the font with the ascent and descent from the font file.
+func loadTrueTypeFont(path string) (sampleFont, error) {
+	font, err := pdf.NewPdfFontFromTTFFile(path)
+	if err != nil {
+		return sampleFont{}, err
+	}
+	ttf, err := fonts.TtfParse(path)
+	if err != nil {
+		return sampleFont{}, err
+	}
+	sample := sampleFont{name: ttf.PostScriptName, font: font}
+	if ttf.UnitsPerEm > 0 {
+		k := 1000.0 / float64(ttf.UnitsPerEm)
+		sample.ascent = k * float64(ttf.TypoAscender)
+		sample.descent = k * float64(ttf.TypoDescender)
+	}
+	return sample, nil
+}
+
+// newMeasurer returns a measurer for the sample font, with its ascent and descent if known.
+func newMeasurer(sample sampleFont, fontSize float64) *TextMeasurer {
+	m := NewTextMeasurer(sample.font, fontSize)
+	if sample.ascent != 0 {
+		m.SetAscentDescent(sample.ascent, sample.descent)
+	}
+	return m
+}
+
+func createPage(samples []sampleFont, outputPath string) error {
+	c := creator.New()
+	c.NewPage()
+
+	y := pageMargin
+	err := drawText(c, "Measuring text", fonts.NewFontHelveticaBold(), 20, pageMargin, y)
+	if err != nil {
+		return err
+	}
+	y += 40
+
+	// Boxes of the measured width and line height, with the baseline, the ascent and the descent.
+	y, err = drawHeading(c, "Measured text", y)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%-22s %5s %9s %9s %9s\n", "Font", "Size", "Measured", "Creator", "Missing")
+	for i, sample := range samples {
+		fontSize := 18.0 + float64(i%3)*6
+		height, err := drawMeasuredSample(c, sample, fontSize, "Typography, measured.", pageMargin, y)
+		if err != nil {
+			return err
+		}
+		y += height + 14
+	}
+
+	// A string with characters that are not in the standard fonts: the width is an estimate.
+	m := NewTextMeasurer(fonts.NewFontHelvetica(), 12)
+	text := "Next → page"
+	width, missing := m.Width(text)
+	fmt.Printf("%q in Helvetica 12: %.2f points, estimated for %d character(s) without metrics\n", text, width,
+		missing)
+	y += 6
+
+	// Labels right aligned to a column, each followed by its value.
+	y, err = drawHeading(c, "Right aligned labels", y)
+	if err != nil {
+		return err
+	}
+	fields := [][2]string{
+		{"Name:", "Jordan Avery"},
+		{"Email address:", "jordan.avery@example.com"},
+		{"Phone:", "+1 555 0142 873"},
+		{"Shipping address:", "1200 Harbor Street, Portland, OR 97201"},
+	}
+	labelFont := fonts.NewFontHelveticaBold()
+	labels := NewTextMeasurer(labelFont, 11)
+	labelColumn := 0.0
+	for _, f := range fields {
+		w, _ := labels.Width(f[0])
+		if w > labelColumn {
+			labelColumn = w
+		}
+	}
+	for _, f := range fields {
+		w, _ := labels.Width(f[0])
+		err := drawText(c, f[0], labelFont, 11, pageMargin+labelColumn-w, y)
+		if err != nil {
+			return err
+		}
+		err = drawText(c, f[1], fonts.NewFontHelvetica(), 11, pageMargin+labelColumn+8, y)
+		if err != nil {
+			return err
+		}
+		y += labels.Height(1, 1.6)
+	}
+	y += 14
+
+	// Values right aligned to the margin, with dot leaders from the labels.
+	y, err = drawHeading(c, "Right aligned values with leaders", y)
+	if err != nil {
+		return err
+	}
+	lines := [][2]string{
+		{"Subtotal", "1,204.50"},
+		{"Shipping", "12.00"},
+		{"Discount", "-120.45"},
+		{"Tax 8.5%", "92.15"},
+		{"Total due", "1,188.20"},
+	}
+	text12 := NewTextMeasurer(fonts.NewFontHelvetica(), 12)
+	right := c.Width() - pageMargin
+	left := right - 260
+	dotWidth, _ := text12.Width(".")
+	for _, line := range lines {
+		labelWidth, _ := text12.Width(line[0])
+		valueWidth, _ := text12.Width(line[1])
+		valueX := right - valueWidth
+
+		// As many dots as fit between the label and the value, with a space on either side.
+		space, _ := text12.Width(" ")
+		dots := int((valueX - space - (left + labelWidth + space)) / dotWidth)
+		leader := ""
+		if dots > 0 {
+			leader = strings.Repeat(".", dots)
+		}
+
+		err := drawText(c, line[0]+" "+leader, fonts.NewFontHelvetica(), 12, left, y)
+		if err != nil {
+			return err
+		}
+		err = drawText(c, line[1], fonts.NewFontHelvetica(), 12, valueX, y)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%-10s label %6.2f, value %6.2f at x %.2f, %d dots\n", line[0], labelWidth, valueWidth, valueX,
+			dots)
+		y += text12.Height(1, 1.5)
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawMeasuredSample draws the text with a box of the measured width and line height, the baseline and the ascent
+// and descent, and a label with the measurements.  Returns the height of the sample.
+func drawMeasuredSample(c *creator.Creator, sample sampleFont, fontSize float64, text string, x, y float64) (float64,
+	error) {
+	m := newMeasurer(sample, fontSize)
+	width, missing := m.Width(text)
+	lineHeight := m.Height(1, 1)
+	baseline := y + lineHeight
+
+	// The creator's measurement of the same text, for comparison.
+	p := creator.NewParagraph(text)
+	p.SetFont(sample.font)
+	p.SetFontSize(fontSize)
+	p.SetEnableWrap(false)
+	fmt.Printf("%-22s %5.1f %9.2f %9.2f %9d\n", sample.name, fontSize, width, p.Width(), missing)
+
+	// The ascent and descent, shaded, and the line box.
+	extent := creator.NewRectangle(x, baseline-m.Ascent(), width, m.Ascent()+m.Descent())
+	extent.SetFillColor(creator.ColorRGBFrom8bit(230, 240, 250))
+	extent.SetBorderWidth(0)
+	err := c.Draw(extent)
+	if err != nil {
+		return 0, err
+	}
+	box := creator.NewRectangle(x, y, width, lineHeight)
+	box.SetBorderColor(boxColor)
+	box.SetBorderWidth(0.5)
+	err = c.Draw(box)
+	if err != nil {
+		return 0, err
+	}
+	line := creator.NewLine(x-6, baseline, x+width+6, baseline)
+	line.SetLineWidth(0.5)
+	line.SetColor(baselineColor)
+	err = c.Draw(line)
+	if err != nil {
+		return 0, err
+	}
+
+	p.SetPos(x, y)
+	err = c.Draw(p)
+	if err != nil {
+		return 0, err
+	}
+
+	label := fmt.Sprintf("%s %.0f pt\nwidth %.2f, line height %.2f\nascent %.2f, descent %.2f", sample.name,
+		fontSize, width, lineHeight, m.Ascent(), m.Descent())
+	labelParagraph := creator.NewParagraph(label)
+	labelParagraph.SetFont(fonts.NewFontHelvetica())
+	labelParagraph.SetFontSize(8)
+	labelParagraph.SetColor(mutedColor)
+	labelParagraph.SetPos(c.Width()-pageMargin-150, y)
+	err = c.Draw(labelParagraph)
+	if err != nil {
+		return 0, err
+	}
+
+	return maxFloat(lineHeight+m.Descent(), labelParagraph.Height()), nil
+}
+
+// Draws a section heading at y and returns the y position below it.
+func drawHeading(c *creator.Creator, title string, y float64) (float64, error) {
+	err := drawText(c, title, fonts.NewFontHelveticaBold(), 13, pageMargin, y)
+	return y + 24, err
+}
+
+func drawText(c *creator.Creator, text string, font fonts.Font, fontSize float64, x, y float64) error {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetEnableWrap(false)
+	p.SetPos(x, y)
+	return c.Draw(p)
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,442 @@
+/*
+ * Draw paragraphs that mix regular, bold, italic and colored text inline.
+ *
+ * The creator's paragraphs use one font, size and color, so the example defines RichParagraph, a creator Drawable
+ * built from a slice of styled text runs.  The text wraps at spaces regardless of the run boundaries: a line can
+ * break in the middle of a run, and a word made of several runs (e.g. a bold word followed by a regular comma) is
+ * kept together.  Each space is measured in the font of the run it belongs to, so the spacing stays correct between
+ * runs of different fonts and sizes.  A newline in a run starts a new line.
+ *
+ * Run as: go run rich_text.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+// TextStyle is the font, size and color of a text run.
+type TextStyle struct {
+	Font     fonts.Font
+	FontSize float64
+	Color    creator.Color
+}
+
+// TextRun is a piece of text in a single style.
+type TextRun struct {
+	Text  string
+	Style TextStyle
+}
+
+// richPiece is the part of a word in a single run.
+type richPiece struct {
+	text  string
+	style TextStyle
+	width float64
+}
+
+// richWord is a word, possibly spanning several runs, with the space preceding it.
+type richWord struct {
+	pieces     []richPiece
+	space      TextStyle // Style of the preceding space.
+	spaceWidth float64   // Width of the preceding space, 0 at the start of the paragraph or after a newline.
+	newline    bool      // The word starts a new line.
+}
+
+// richLine is a laid out line of words.
+type richLine struct {
+	words    []richWord
+	width    float64
+	fontSize float64 // Largest font size in the line.
+}
+
+// RichParagraph is a paragraph of styled text runs, wrapped to a width and drawn at an absolute position from the
+// upper left corner of the page.  Implements the creator Drawable interface.
+type RichParagraph struct {
+	runs       []TextRun
+	encoder    textencoding.TextEncoder
+	lineHeight float64
+	alignment  creator.TextAlignment
+	width      float64
+	x, y       float64
+}
+
+// NewRichParagraph returns a paragraph of the given runs, left aligned with a line height of 1.2.
+func NewRichParagraph(runs []TextRun) *RichParagraph {
+	return &RichParagraph{
+		runs:       runs,
+		encoder:    textencoding.NewWinAnsiTextEncoder(),
+		lineHeight: 1.2,
+		alignment:  creator.TextAlignmentLeft,
+		width:      500,
+	}
+}
+
+// SetLineHeight sets the line height relative to the largest font size in each line.
+func (rp *RichParagraph) SetLineHeight(lineHeight float64) {
+	rp.lineHeight = lineHeight
+}
+
+// SetTextAlignment sets the alignment: creator.TextAlignmentLeft, TextAlignmentCenter or TextAlignmentRight.
+func (rp *RichParagraph) SetTextAlignment(alignment creator.TextAlignment) {
+	rp.alignment = alignment
+}
+
+// SetWidth sets the width the text wraps at.
+func (rp *RichParagraph) SetWidth(width float64) {
+	rp.width = width
+}
+
+// SetPos sets the position of the upper left corner of the paragraph.
+func (rp *RichParagraph) SetPos(x, y float64) {
+	rp.x = x
+	rp.y = y
+}
+
+// Height returns the height of the paragraph after wrapping.
+func (rp *RichParagraph) Height() (float64, error) {
+	lines, err := rp.layout()
+	if err != nil {
+		return 0, err
+	}
+	height := 0.0
+	for _, line := range lines {
+		height += rp.lineHeight * line.fontSize
+	}
+	return height, nil
+}
+
+// textWidth returns the width of text in the given style.
+func (rp *RichParagraph) textWidth(text string, style TextStyle) (float64, error) {
+	width := 0.0
+	for _, r := range text {
+		glyph, found := rp.encoder.RuneToGlyph(r)
+		if !found {
+			return 0, fmt.Errorf("Character %q is not supported by the text encoding", r)
+		}
+		metrics, found := style.Font.GetGlyphCharMetrics(glyph)
+		if !found {
+			return 0, fmt.Errorf("Character %q (%s) is not in the font", r, glyph)
+		}
+		width += style.FontSize * metrics.Wx / 1000.0
+	}
+	return width, nil
+}
+
+// splitWords splits the runs into words at spaces and newlines.  The text between two spaces forms a word even
+// when it spans several runs.
+func (rp *RichParagraph) splitWords() ([]richWord, error) {
+	words := []richWord{}
+	current := richWord{}
+	endWord := func() {
+		if len(current.pieces) > 0 {
+			words = append(words, current)
+			current = richWord{}
+		}
+	}
+
+	for _, run := range rp.runs {
+		start := 0
+		text := run.Text
+		for i := 0; i <= len(text); i++ {
+			if i < len(text) && text[i] != ' ' && text[i] != '\n' {
+				continue
+			}
+			if i > start {
+				w, err := rp.textWidth(text[start:i], run.Style)
+				if err != nil {
+					return nil, err
+				}
+				current.pieces = append(current.pieces, richPiece{text: text[start:i], style: run.Style, width: w})
+			}
+			if i == len(text) {
+				break
+			}
+
+			// A space or newline ends the current word and applies to the next one.
+			endWord()
+			if text[i] == '\n' {
+				current.newline = true
+			} else {
+				w, err := rp.textWidth(" ", run.Style)
+				if err != nil {
+					return nil, err
+				}
+				current.space = run.Style
+				current.spaceWidth = w
+			}
+			start = i + 1
+		}
+	}
+	endWord()
+
+	return words, nil
+}
+
+// layout wraps the words into lines.
+func (rp *RichParagraph) layout() ([]richLine, error) {
+	words, err := rp.splitWords()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []richLine{}
+	line := richLine{}
+	for _, word := range words {
+		wordWidth := 0.0
+		for _, piece := range word.pieces {
+			wordWidth += piece.width
+		}
+		if len(line.words) > 0 && (word.newline || line.width+word.spaceWidth+wordWidth > rp.width) {
+			lines = append(lines, line)
+			line = richLine{}
+		}
+		if len(line.words) > 0 {
+			line.width += word.spaceWidth
+		}
+		line.words = append(line.words, word)
+		line.width += wordWidth
+		for _, piece := range word.pieces {
+			if piece.style.FontSize > line.fontSize {
+				line.fontSize = piece.style.FontSize
+			}
+		}
+	}
+	if len(line.words) > 0 {
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// GeneratePageBlocks draws the paragraph on a block representing the page.  Implements the Drawable interface.
+func (rp *RichParagraph) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	lines, err := rp.layout()
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	// Add each font to the resources once.  The standard fonts are distinct types, so the type identifies the font.
+	resources := pdf.NewPdfPageResources()
+	fontNames := map[string]pdfcore.PdfObjectName{}
+	fontName := func(font fonts.Font) (pdfcore.PdfObjectName, error) {
+		key := fmt.Sprintf("%T", font)
+		name, has := fontNames[key]
+		if !has {
+			name = pdfcore.PdfObjectName(fmt.Sprintf("F%d", len(fontNames)+1))
+			err := resources.SetFontByName(name, font.ToPdfObject())
+			if err != nil {
+				return "", err
+			}
+			fontNames[key] = name
+		}
+		return name, nil
+	}
+	show := func(cc *pdfcontent.ContentCreator, text string, style TextStyle) error {
+		name, err := fontName(style.Font)
+		if err != nil {
+			return err
+		}
+		color := style.Color
+		if color == nil {
+			color = creator.ColorRGBFrom8bit(0, 0, 0)
+		}
+		cc.Add_rg(color.ToRGB())
+		cc.Add_Tf(name, style.FontSize)
+		cc.Add_Tj(pdfcore.PdfObjectString(rp.encoder.Encode(text)))
+		return nil
+	}
+
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q()
+
+	y := rp.y
+	for _, line := range lines {
+		y += rp.lineHeight * line.fontSize
+
+		x := rp.x
+		switch rp.alignment {
+		case creator.TextAlignmentCenter:
+			x += (rp.width - line.width) / 2
+		case creator.TextAlignmentRight:
+			x += rp.width - line.width
+		}
+
+		cc.Add_BT()
+		cc.Add_Td(x, ctx.PageHeight-y)
+		for i, word := range line.words {
+			if i > 0 && word.spaceWidth > 0 {
+				if err := show(cc, " ", word.space); err != nil {
+					return nil, ctx, err
+				}
+			}
+			for _, piece := range word.pieces {
+				if err := show(cc, piece.text, piece.style); err != nil {
+					return nil, ctx, err
+				}
+			}
+		}
+		cc.Add_ET()
+	}
+	cc.Add_Q()
+
+	// Blocks with custom contents are created from a page with the contents.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = resources
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run rich_text.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	err := createDocument(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createDocument(outputPath string) error {
+	black := creator.ColorRGBFrom8bit(0, 0, 0)
+	red := creator.ColorRGBFrom8bit(192, 57, 43)
+	blue := creator.ColorRGBFrom8bit(41, 98, 185)
+	green := creator.ColorRGBFrom8bit(39, 140, 76)
+
+	regular := TextStyle{fonts.NewFontTimesRoman(), 12, black}
+	bold := TextStyle{fonts.NewFontTimesBold(), 12, black}
+	italic := TextStyle{fonts.NewFontTimesItalic(), 12, black}
+	boldItalic := TextStyle{fonts.NewFontTimesBoldItalic(), 12, black}
+	code := TextStyle{fonts.NewFontCourier(), 11, blue}
+	warning := TextStyle{fonts.NewFontHelveticaBold(), 12, red}
+	large := TextStyle{fonts.NewFontTimesBold(), 18, green}
+
+	paragraphs := []struct {
+		title     string
+		width     float64
+		alignment creator.TextAlignment
+		runs      []TextRun
+	}{
+		{
+			"Mixed styles", 450, creator.TextAlignmentLeft, []TextRun{
+				{"This paragraph mixes ", regular},
+				{"bold", bold},
+				{", ", regular},
+				{"italic", italic},
+				{" and ", regular},
+				{"bold italic", boldItalic},
+				{" text with ", regular},
+				{"colored", TextStyle{regular.Font, 12, red}},
+				{" runs and ", regular},
+				{"monospaced code()", code},
+				{" in a single paragraph.  Words are wrapped at spaces, also in the middle of a run, and a word that " +
+					"is made of several runs such as ", regular},
+				{"bold", bold},
+				{"-", regular},
+				{"italic", italic},
+				{" stays together.", regular},
+			},
+		},
+		{
+			"Wrapping within a styled run", 220, creator.TextAlignmentLeft, []TextRun{
+				{"In a narrow column, ", regular},
+				{"this long bold run wraps over several lines in the middle of the run", bold},
+				{" and continues in ", regular},
+				{"a regular run.", italic},
+			},
+		},
+		{
+			"Different sizes and fonts", 450, creator.TextAlignmentLeft, []TextRun{
+				{"Runs can also differ in size: ", regular},
+				{"Large green heading text", large},
+				{" followed by normal text.  The line height is taken from the largest font in each line, and the " +
+					"spaces are as wide as in the font of their run: ", regular},
+				{"Warning:", warning},
+				{" check the ", regular},
+				{"fonts", code},
+				{" before printing.", regular},
+			},
+		},
+		{
+			"Centered, with line breaks", 450, creator.TextAlignmentCenter, []TextRun{
+				{"Certificate of Completion\n", large},
+				{"awarded to ", italic},
+				{"Jane Doe", bold},
+				{"\nfor the course ", italic},
+				{"Introduction to PDF", boldItalic},
+			},
+		},
+		{
+			"Right aligned", 450, creator.TextAlignmentRight, []TextRun{
+				{"Total: ", bold},
+				{"$1,234.56", TextStyle{bold.Font, 12, green}},
+				{"\nincluding ", italic},
+				{"VAT", bold},
+			},
+		},
+	}
+
+	c := creator.New()
+	c.NewPage()
+
+	const left = 72.0
+	y := 72.0
+	for _, para := range paragraphs {
+		title := creator.NewParagraph(para.title)
+		title.SetFont(fonts.NewFontHelveticaBold())
+		title.SetFontSize(11)
+		title.SetColor(creator.ColorRGBFrom8bit(100, 100, 100))
+		title.SetPos(left, y)
+		_ = c.Draw(title)
+		y += 18
+
+		rp := NewRichParagraph(para.runs)
+		rp.SetWidth(para.width)
+		rp.SetTextAlignment(para.alignment)
+		rp.SetPos(left, y)
+		height, err := rp.Height()
+		if err != nil {
+			return err
+		}
+
+		// Outline the paragraph width to show the alignment.
+		rect := creator.NewRectangle(left, y, para.width, height+4)
+		rect.SetBorderColor(creator.ColorRGBFrom8bit(220, 220, 220))
+		rect.SetBorderWidth(0.5)
+		_ = c.Draw(rect)
+
+		err = c.Draw(rp)
+		if err != nil {
+			return err
+		}
+		y += height + 24
+	}
+
+	return c.WriteToFile(outputPath)
+}
@@ -0,0 +1,285 @@
+/*
+ * Draw text rotated at arbitrary angles: a vertical axis label and slanted tick labels on a bar chart, a ring of
+ * labels rotated around a common center, and a banner at a configurable angle.
+ *
+ * The text is drawn into a creator block which is rotated with the block's SetAngle.  The creator rotates blocks
+ * about their upper left corner, so the example computes the block position that puts a chosen anchor point of the
+ * text (e.g. its center, or the middle of its right edge) at the desired place on the page, and rotation then
+ * happens around that point.  The bounding box of the rotated block is computed from its four corners and used to
+ * move the text back onto the page when it would extend beyond the margins.
+ *
+ * Angles are in degrees, counterclockwise.
+ *
+ * Run as: go run rotated_text.go [-angle 45] output.pdf
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run rotated_text.go [-angle 45] output.pdf\n"
+
+const pageMargin = 36.0
+
+// RotatedText is a single line of text in a block rotated about an anchor point.  The anchor is given relative to
+// the block: (0, 0) is the upper left corner, (1, 1) the lower right corner and (0.5, 0.5) the center.
+type RotatedText struct {
+	block            *creator.Block
+	angle            float64
+	anchorX, anchorY float64
+}
+
+// NewRotatedText returns the text in a block sized to fit it.  The block extends below the baseline to include the
+// descenders.
+func NewRotatedText(text string, font fonts.Font, fontSize float64, col creator.Color) (*RotatedText, error) {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetColor(col)
+	p.SetEnableWrap(false)
+	p.SetPos(0, 0)
+
+	block := creator.NewBlock(p.Width(), p.Height()+0.25*fontSize)
+	err := block.Draw(p)
+	if err != nil {
+		return nil, err
+	}
+	return &RotatedText{block: block}, nil
+}
+
+// SetAngle sets the rotation angle in degrees, counterclockwise.
+func (rt *RotatedText) SetAngle(angle float64) {
+	rt.angle = angle
+}
+
+// SetAnchor sets the point of the text that is positioned and rotated about, relative to the block size.
+func (rt *RotatedText) SetAnchor(ax, ay float64) {
+	rt.anchorX = ax
+	rt.anchorY = ay
+}
+
+// rotate returns the offset (dx, dy) from the upper left corner of the block, in page coordinates with y pointing
+// down, of the block point (bx, by) after the rotation.
+func (rt *RotatedText) rotate(bx, by float64) (float64, float64) {
+	// The creator rotates counterclockwise in PDF coordinates, where y points up.
+	rad := rt.angle * math.Pi / 180
+	sin, cos := math.Sin(rad), math.Cos(rad)
+	x, y := bx, -by
+	return x*cos - y*sin, -(x*sin + y*cos)
+}
+
+// corner returns the position of the upper left corner of the block such that the anchor is at (x, y).
+func (rt *RotatedText) corner(x, y float64) (float64, float64) {
+	dx, dy := rt.rotate(rt.anchorX*rt.block.Width(), rt.anchorY*rt.block.Height())
+	return x - dx, y - dy
+}
+
+// BoundingBox returns the bounding box (left, top, right, bottom) of the rotated text with its anchor at (x, y).
+func (rt *RotatedText) BoundingBox(x, y float64) (float64, float64, float64, float64) {
+	cx, cy := rt.corner(x, y)
+	w, h := rt.block.Width(), rt.block.Height()
+
+	left, top := math.Inf(1), math.Inf(1)
+	right, bottom := math.Inf(-1), math.Inf(-1)
+	for _, pt := range [][2]float64{{0, 0}, {w, 0}, {0, h}, {w, h}} {
+		dx, dy := rt.rotate(pt[0], pt[1])
+		left = math.Min(left, cx+dx)
+		right = math.Max(right, cx+dx)
+		top = math.Min(top, cy+dy)
+		bottom = math.Max(bottom, cy+dy)
+	}
+	return left, top, right, bottom
+}
+
+// KeepOnPage returns the anchor position closest to (x, y) for which the rotated text lies within the page
+// margins.  Text larger than the area between the margins is aligned with the left or top margin.
+func (rt *RotatedText) KeepOnPage(x, y, pageWidth, pageHeight float64) (float64, float64) {
+	left, top, right, bottom := rt.BoundingBox(x, y)
+	if right > pageWidth-pageMargin {
+		x -= right - (pageWidth - pageMargin)
+		left -= right - (pageWidth - pageMargin)
+	}
+	if left < pageMargin {
+		x += pageMargin - left
+	}
+	if bottom > pageHeight-pageMargin {
+		y -= bottom - (pageHeight - pageMargin)
+		top -= bottom - (pageHeight - pageMargin)
+	}
+	if top < pageMargin {
+		y += pageMargin - top
+	}
+	return x, y
+}
+
+// Draw draws the text rotated about its anchor, with the anchor at (x, y).
+func (rt *RotatedText) Draw(c *creator.Creator, x, y float64) error {
+	cx, cy := rt.corner(x, y)
+	rt.block.SetAngle(rt.angle)
+	rt.block.SetPos(cx, cy)
+	return c.Draw(rt.block)
+}
+
+func main() {
+	angle := 0.0
+	flag.Float64Var(&angle, "angle", 45, "Rotation angle of the banner (degrees, counterclockwise)")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	err := createDocument(angle, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createDocument(bannerAngle float64, outputPath string) error {
+	c := creator.New()
+	c.NewPage()
+
+	err := drawChart(c, 90, 60, 430, 250)
+	if err != nil {
+		return err
+	}
+	err = drawLabelRing(c, 170, 520, 90)
+	if err != nil {
+		return err
+	}
+	err = drawBanner(c, bannerAngle, 430, 520)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawChart draws a bar chart with the box at (x, y) with a vertical axis label and tick labels at 45 degrees.
+func drawChart(c *creator.Creator, x, y, width, height float64) error {
+	black := creator.ColorRGBFrom8bit(0, 0, 0)
+	blue := creator.ColorRGBFrom8bit(41, 128, 185)
+
+	months := []string{"January", "February", "March", "April", "May", "June", "July", "August"}
+	values := []float64{42, 55, 61, 48, 70, 82, 77, 90}
+
+	axisX := creator.NewLine(x, y+height, x+width, y+height)
+	axisY := creator.NewLine(x, y, x, y+height)
+	for _, axis := range []*creator.Line{axisX, axisY} {
+		axis.SetColor(black)
+		axis.SetLineWidth(1)
+		_ = c.Draw(axis)
+	}
+
+	slot := width / float64(len(values))
+	for i, value := range values {
+		barHeight := value / 100 * height
+		bar := creator.NewRectangle(x+float64(i)*slot+slot*0.2, y+height-barHeight, slot*0.6, barHeight)
+		bar.SetFillColor(blue)
+		bar.SetBorderWidth(0)
+		_ = c.Draw(bar)
+
+		// Tick labels at 45 degrees, anchored at the middle of their right edge just below the tick.
+		label, err := NewRotatedText(months[i], fonts.NewFontHelvetica(), 9, black)
+		if err != nil {
+			return err
+		}
+		label.SetAngle(45)
+		label.SetAnchor(1, 0.5)
+		err = label.Draw(c, x+float64(i)*slot+slot/2, y+height+6)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The vertical axis label, rotated 90 degrees about its center, centered along the axis.
+	axisLabel, err := NewRotatedText("Sales (thousand units)", fonts.NewFontHelveticaBold(), 11, black)
+	if err != nil {
+		return err
+	}
+	axisLabel.SetAngle(90)
+	axisLabel.SetAnchor(0.5, 0.5)
+	return axisLabel.Draw(c, x-20, y+height/2)
+}
+
+// drawLabelRing draws labels at every 30 degrees around the center (cx, cy), each rotated about the middle of its
+// left edge which is placed on a small circle around the center.
+func drawLabelRing(c *creator.Creator, cx, cy, radius float64) error {
+	gray := creator.ColorRGBFrom8bit(180, 180, 180)
+	circle := creator.NewEllipse(cx, cy, 2*radius, 2*radius)
+	circle.SetBorderColor(gray)
+	circle.SetBorderWidth(0.5)
+	_ = c.Draw(circle)
+
+	for angle := 0.0; angle < 360; angle += 30 {
+		rad := angle * math.Pi / 180
+		// Page coordinates have y pointing down, so counterclockwise angles go towards smaller y.
+		x := cx + 20*math.Cos(rad)
+		y := cy - 20*math.Sin(rad)
+
+		label, err := NewRotatedText(fmt.Sprintf("%.0f degrees", angle), fonts.NewFontHelvetica(), 10,
+			creator.ColorRGBFrom8bit(192, 57, 43))
+		if err != nil {
+			return err
+		}
+		label.SetAngle(angle)
+		label.SetAnchor(0, 0.5)
+		err = label.Draw(c, x, y)
+		if err != nil {
+			return err
+		}
+	}
+
+	dot := creator.NewEllipse(cx, cy, 4, 4)
+	dot.SetFillColor(creator.ColorRGBFrom8bit(0, 0, 0))
+	_ = c.Draw(dot)
+	return nil
+}
+
+// drawBanner draws a large banner rotated about its center at (x, y), moved as needed to stay on the page, with its
+// bounding box outlined.
+func drawBanner(c *creator.Creator, angle, x, y float64) error {
+	banner, err := NewRotatedText("APPROVED FOR RELEASE", fonts.NewFontHelveticaBold(), 28,
+		creator.ColorRGBFrom8bit(39, 140, 76))
+	if err != nil {
+		return err
+	}
+	banner.SetAngle(angle)
+	banner.SetAnchor(0.5, 0.5)
+
+	ctx := c.Context()
+	newX, newY := banner.KeepOnPage(x, y, ctx.PageWidth, ctx.PageHeight)
+	if newX != x || newY != y {
+		fmt.Printf("Banner moved from (%.1f, %.1f) to (%.1f, %.1f) to stay on the page\n", x, y, newX, newY)
+	}
+
+	left, top, right, bottom := banner.BoundingBox(newX, newY)
+	box := creator.NewRectangle(left, top, right-left, bottom-top)
+	box.SetBorderColor(creator.ColorRGBFrom8bit(200, 200, 200))
+	box.SetBorderWidth(0.5)
+	_ = c.Draw(box)
+
+	return banner.Draw(c, newX, newY)
+}
@@ -0,0 +1,343 @@
+/*
+ * Draw text with adjusted character spacing (tracking) and word spacing, using the text state operators Tc and Tw.
+ *
+ * The same sentence is drawn at several character and word spacing values for comparison, followed by justified
+ * paragraphs.
+ *
+ * Tc adds space after every character (including spaces, and negative values tighten the text), Tw adds space after
+ * every space character (byte 32) only.  Both are in unscaled text space units, i.e. points at a horizontal scaling
+ * of 100%.  The creator's paragraphs can't be used for this: they draw spaces as TJ offsets rather than space
+ * characters, so Tw would have no effect, and they measure lines without Tc, so tracked text would overflow the
+ * wrap width.  The example therefore defines SpacedText, a creator Drawable that includes the spacing when wrapping
+ * and draws the spaces as characters.
+ *
+ * Justified text interacts with word spacing: justification works by adjusting the space between words, so for each
+ * justified line the word spacing is computed to fill the line width, and the requested word spacing acts as the
+ * minimum which is used for breaking the lines (and for the last line, which is not justified).  Character spacing
+ * is applied first and included in the line width, so justified text with tracking still fills exactly the width.
+ *
+ * Run as: go run spacing.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+const sentence = "The quick brown fox jumps over the lazy dog."
+
+// SpacedText is a paragraph with character and word spacing, wrapped to a width and drawn at an absolute position
+// from the upper left corner of the page.  Implements the creator Drawable interface.
+type SpacedText struct {
+	text        string
+	font        fonts.Font
+	encoder     textencoding.TextEncoder
+	fontSize    float64
+	lineHeight  float64
+	charSpacing float64
+	wordSpacing float64
+	justify     bool
+	width       float64
+	x, y        float64
+}
+
+// spacedLine is a wrapped line: its words and the word spacing to draw it with.
+type spacedLine struct {
+	words       []string
+	wordSpacing float64
+}
+
+// NewSpacedText returns a left aligned paragraph in Helvetica 12pt without extra spacing.
+func NewSpacedText(text string) *SpacedText {
+	return &SpacedText{
+		text:       text,
+		font:       fonts.NewFontHelvetica(),
+		encoder:    textencoding.NewWinAnsiTextEncoder(),
+		fontSize:   12,
+		lineHeight: 1.3,
+		width:      450,
+	}
+}
+
+// SetFont sets the font and font size.
+func (st *SpacedText) SetFont(font fonts.Font, fontSize float64) {
+	st.font = font
+	st.fontSize = fontSize
+}
+
+// SetCharSpacing sets the extra space after each character (Tc), in points.
+func (st *SpacedText) SetCharSpacing(spacing float64) {
+	st.charSpacing = spacing
+}
+
+// SetWordSpacing sets the extra space after each space character (Tw), in points.  For justified text this is the
+// minimum word spacing.
+func (st *SpacedText) SetWordSpacing(spacing float64) {
+	st.wordSpacing = spacing
+}
+
+// SetJustify enables justified text: all lines but the last are stretched to the full width by increasing the
+// word spacing.
+func (st *SpacedText) SetJustify(justify bool) {
+	st.justify = justify
+}
+
+// SetWidth sets the width the text wraps at.
+func (st *SpacedText) SetWidth(width float64) {
+	st.width = width
+}
+
+// SetPos sets the position of the upper left corner of the paragraph.
+func (st *SpacedText) SetPos(x, y float64) {
+	st.x = x
+	st.y = y
+}
+
+// Height returns the height of the paragraph after wrapping.
+func (st *SpacedText) Height() (float64, error) {
+	lines, err := st.layout()
+	if err != nil {
+		return 0, err
+	}
+	return float64(len(lines)) * st.lineHeight * st.fontSize, nil
+}
+
+// glyphWidths returns the total width of the glyphs of text, without any spacing.
+func (st *SpacedText) glyphWidths(text string) (float64, error) {
+	width := 0.0
+	for _, r := range text {
+		glyph, found := st.encoder.RuneToGlyph(r)
+		if !found {
+			return 0, fmt.Errorf("Character %q is not supported by the text encoding", r)
+		}
+		metrics, found := st.font.GetGlyphCharMetrics(glyph)
+		if !found {
+			return 0, fmt.Errorf("Character %q (%s) is not in the font", r, glyph)
+		}
+		width += st.fontSize * metrics.Wx / 1000.0
+	}
+	return width, nil
+}
+
+// lineWidth returns the width of the words set on one line with the given word spacing.  The character spacing is
+// added after every character but the last, which has no visible effect at the end of the line.
+func (st *SpacedText) lineWidth(words []string, wordSpacing float64) (float64, error) {
+	text := strings.Join(words, " ")
+	width, err := st.glyphWidths(text)
+	if err != nil {
+		return 0, err
+	}
+	chars := len([]rune(text))
+	spaces := len(words) - 1
+	return width + float64(chars-1)*st.charSpacing + float64(spaces)*wordSpacing, nil
+}
+
+// layout wraps the words into lines, including the character and word spacing in the line widths, and computes the
+// word spacing of each line.
+func (st *SpacedText) layout() ([]spacedLine, error) {
+	lines := []spacedLine{}
+	current := []string{}
+	for _, word := range strings.Fields(st.text) {
+		candidate := append(append([]string{}, current...), word)
+		w, err := st.lineWidth(candidate, st.wordSpacing)
+		if err != nil {
+			return nil, err
+		}
+		if w > st.width && len(current) > 0 {
+			lines = append(lines, spacedLine{words: current, wordSpacing: st.wordSpacing})
+			current = []string{word}
+			continue
+		}
+		current = candidate
+	}
+	if len(current) > 0 {
+		lines = append(lines, spacedLine{words: current, wordSpacing: st.wordSpacing})
+	}
+
+	if st.justify {
+		// Distribute the remaining width over the spaces of each line but the last.
+		for i := 0; i < len(lines)-1; i++ {
+			spaces := len(lines[i].words) - 1
+			if spaces == 0 {
+				continue
+			}
+			w, err := st.lineWidth(lines[i].words, st.wordSpacing)
+			if err != nil {
+				return nil, err
+			}
+			lines[i].wordSpacing = st.wordSpacing + (st.width-w)/float64(spaces)
+		}
+	}
+
+	return lines, nil
+}
+
+// GeneratePageBlocks draws the text on a block representing the page.  Implements the Drawable interface.
+func (st *SpacedText) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	lines, err := st.layout()
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	resources := pdf.NewPdfPageResources()
+	err = resources.SetFontByName("F1", st.font.ToPdfObject())
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q()
+	cc.Add_BT()
+	cc.Add_Tf("F1", st.fontSize)
+	cc.Add_TL(st.lineHeight * st.fontSize)
+	cc.Add_Tc(st.charSpacing)
+	cc.Add_Td(st.x, ctx.PageHeight-st.y-st.lineHeight*st.fontSize)
+	for i, line := range lines {
+		if i > 0 {
+			cc.Add_Tstar()
+		}
+		// Tw only applies to the single byte space character, which it is with the WinAnsi encoding.
+		cc.Add_Tw(line.wordSpacing)
+		cc.Add_Tj(pdfcore.PdfObjectString(st.encoder.Encode(strings.Join(line.words, " "))))
+	}
+	cc.Add_ET()
+	cc.Add_Q()
+
+	// Blocks with custom contents are created from a page with the contents.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = resources
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run spacing.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	err := createDocument(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createDocument(outputPath string) error {
+	c := creator.New()
+	c.NewPage()
+
+	const left, labelWidth = 60.0, 110.0
+	y := 60.0
+	gray := creator.ColorRGBFrom8bit(110, 110, 110)
+
+	heading := func(text string) {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(12)
+		p.SetPos(left, y)
+		_ = c.Draw(p)
+		y += 20
+	}
+	label := func(text string, x, y float64) {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontCourier())
+		p.SetFontSize(9)
+		p.SetColor(gray)
+		p.SetPos(x, y+3)
+		_ = c.Draw(p)
+	}
+	draw := func(st *SpacedText) (float64, error) {
+		height, err := st.Height()
+		if err != nil {
+			return 0, err
+		}
+		return height, c.Draw(st)
+	}
+
+	// The same sentence at several spacing values.
+	samples := []struct {
+		heading string
+		values  [][2]float64 // Character spacing, word spacing.
+	}{
+		{"Character spacing (Tc)", [][2]float64{{-0.5, 0}, {0, 0}, {1, 0}, {2, 0}, {4, 0}}},
+		{"Word spacing (Tw)", [][2]float64{{0, 0}, {0, 3}, {0, 6}, {0, 12}}},
+		{"Combined", [][2]float64{{1, 4}, {-0.3, 8}}},
+	}
+	for _, sample := range samples {
+		heading(sample.heading)
+		for _, v := range sample.values {
+			label(fmt.Sprintf("Tc %-4g Tw %g", v[0], v[1]), left, y)
+			st := NewSpacedText(sentence)
+			st.SetCharSpacing(v[0])
+			st.SetWordSpacing(v[1])
+			st.SetWidth(600 - left - labelWidth)
+			st.SetPos(left+labelWidth, y)
+			h, err := draw(st)
+			if err != nil {
+				return err
+			}
+			y += h + 2
+		}
+		y += 10
+	}
+
+	// Justified paragraphs: the word spacing of each line is computed to fill the width.
+	heading("Justified text")
+	text := "Justified text is stretched to the full width of the column by adding space between the words, so " +
+		"the word spacing of each line depends on how much room is left.  The requested word spacing is the " +
+		"minimum: it decides where the lines break, and the last line keeps it.  Character spacing is applied to " +
+		"every character first and is included when measuring the lines."
+	const colWidth, gap = 230.0, 20.0
+	top := y
+	for i, v := range [][2]float64{{0, 0}, {1, 2}} {
+		x := left + float64(i)*(colWidth+gap)
+		label(fmt.Sprintf("justified, Tc %g Tw %g (min)", v[0], v[1]), x, top)
+
+		st := NewSpacedText(text)
+		st.SetFont(fonts.NewFontTimesRoman(), 11)
+		st.SetCharSpacing(v[0])
+		st.SetWordSpacing(v[1])
+		st.SetJustify(true)
+		st.SetWidth(colWidth)
+		st.SetPos(x, top+14)
+		h, err := draw(st)
+		if err != nil {
+			return err
+		}
+
+		// Mark the column edges to show that the justified lines end exactly at the width.
+		for _, edge := range []float64{x, x + colWidth} {
+			line := creator.NewLine(edge, top+14, edge, top+14+h)
+			line.SetColor(creator.ColorRGBFrom8bit(220, 80, 80))
+			line.SetLineWidth(0.5)
+			_ = c.Draw(line)
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
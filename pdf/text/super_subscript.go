@@ -0,0 +1,494 @@
+/*
+ * Render text with superscripts and subscripts, e.g. formulas such as E = mc² and H₂O, and footnote markers.
+ *
+ * The creator's paragraphs use a single font and size, so the example defines StyledText, a creator Drawable that
+ * lays out a paragraph of styled runs.  Superscripts and subscripts are set in a smaller font size and shifted from
+ * the baseline with the text rise (Ts) operator, and the text wraps at spaces like a normal paragraph.  Lines with
+ * superscripts or subscripts that extend beyond the normal line height are moved apart so they do not overlap the
+ * neighbouring lines.
+ *
+ * The text is given with a simple markup: ^{...} for a superscript, _{...} for a subscript (the braces can be
+ * omitted for a single character), *...* for italics and a backslash to escape any of these characters.
+ * Example: "*E* = *mc*^2 and H_2O, 6.022 × 10^{23} mol^{-1}".
+ *
+ * Run as: go run super_subscript.go output.pdf ["text with markup"]
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+// Vertical position of a run relative to the baseline.
+const (
+	PositionNormal = iota
+	PositionSuperscript
+	PositionSubscript
+)
+
+// Size and baseline offset of superscripts and subscripts relative to the font size.
+const (
+	scriptScale     = 0.65
+	superscriptRise = 0.38
+	subscriptRise   = -0.18
+)
+
+// Approximate ascender and descender heights of the standard fonts relative to the font size, used to find how far
+// superscripts and subscripts extend above and below a line.
+const (
+	fontAscent  = 0.72
+	fontDescent = 0.21
+)
+
+// TextRun is a piece of text with a single style.
+type TextRun struct {
+	Text     string
+	Position int // PositionNormal, PositionSuperscript or PositionSubscript.
+	Italic   bool
+}
+
+// textPiece is a laid out piece of a word in a single style.
+type textPiece struct {
+	run   TextRun
+	width float64
+}
+
+// textLine is a laid out line: its words (each a list of pieces) and its extent above and below the baseline.
+type textLine struct {
+	words   [][]textPiece
+	ascent  float64
+	descent float64
+}
+
+// StyledText is a paragraph of text runs with superscripts, subscripts and italics, wrapped to a width and drawn
+// at an absolute position from the upper left corner of the page.  Implements the creator Drawable interface.
+type StyledText struct {
+	runs       []TextRun
+	font       fonts.Font
+	italicFont fonts.Font
+	encoder    textencoding.TextEncoder
+	fontSize   float64
+	lineHeight float64
+	color      creator.Color
+	width      float64
+	x, y       float64
+}
+
+// NewStyledText returns a paragraph of the given runs, in Times 12pt with a line height of 1.2.
+func NewStyledText(runs []TextRun) *StyledText {
+	return &StyledText{
+		runs:       runs,
+		font:       fonts.NewFontTimesRoman(),
+		italicFont: fonts.NewFontTimesItalic(),
+		encoder:    textencoding.NewWinAnsiTextEncoder(),
+		fontSize:   12,
+		lineHeight: 1.2,
+		color:      creator.ColorRGBFrom8bit(0, 0, 0),
+		width:      500,
+	}
+}
+
+// SetFonts sets the regular and italic fonts.
+func (st *StyledText) SetFonts(font, italicFont fonts.Font) {
+	st.font = font
+	st.italicFont = italicFont
+}
+
+// SetFontSize sets the font size of the normal text.
+func (st *StyledText) SetFontSize(fontSize float64) {
+	st.fontSize = fontSize
+}
+
+// SetLineHeight sets the line height relative to the font size.
+func (st *StyledText) SetLineHeight(lineHeight float64) {
+	st.lineHeight = lineHeight
+}
+
+// SetColor sets the text color.
+func (st *StyledText) SetColor(col creator.Color) {
+	st.color = col
+}
+
+// SetWidth sets the width the text wraps at.
+func (st *StyledText) SetWidth(width float64) {
+	st.width = width
+}
+
+// SetPos sets the position of the upper left corner of the paragraph.
+func (st *StyledText) SetPos(x, y float64) {
+	st.x = x
+	st.y = y
+}
+
+// Height returns the height of the paragraph after wrapping.
+func (st *StyledText) Height() (float64, error) {
+	lines, err := st.layout()
+	if err != nil {
+		return 0, err
+	}
+	height := 0.0
+	for _, line := range lines {
+		height += line.ascent + line.descent
+	}
+	return height, nil
+}
+
+// runStyle returns the font, font size and text rise of a run.
+func (st *StyledText) runStyle(run TextRun) (fonts.Font, float64, float64) {
+	font := st.font
+	if run.Italic {
+		font = st.italicFont
+	}
+	switch run.Position {
+	case PositionSuperscript:
+		return font, scriptScale * st.fontSize, superscriptRise * st.fontSize
+	case PositionSubscript:
+		return font, scriptScale * st.fontSize, subscriptRise * st.fontSize
+	}
+	return font, st.fontSize, 0
+}
+
+// textWidth returns the width of text in the given font and size.
+func (st *StyledText) textWidth(text string, font fonts.Font, fontSize float64) (float64, error) {
+	width := 0.0
+	for _, r := range text {
+		glyph, found := st.encoder.RuneToGlyph(r)
+		if !found {
+			return 0, fmt.Errorf("Character %q is not supported by the text encoding", r)
+		}
+		metrics, found := font.GetGlyphCharMetrics(glyph)
+		if !found {
+			return 0, fmt.Errorf("Character %q (%s) is not in the font", r, glyph)
+		}
+		width += fontSize * metrics.Wx / 1000.0
+	}
+	return width, nil
+}
+
+// layout splits the runs into words at spaces and wraps the words into lines.  Each line gets the space it needs
+// above and below the baseline: at least the normal share of the line height, more if a superscript or subscript
+// extends further.
+func (st *StyledText) layout() ([]textLine, error) {
+	// Split into words; a word can consist of pieces of different styles, e.g. "mc" and superscript "2".
+	words := [][]textPiece{}
+	word := []textPiece{}
+	addPiece := func(run TextRun, text string) error {
+		if text == "" {
+			return nil
+		}
+		font, size, _ := st.runStyle(run)
+		w, err := st.textWidth(text, font, size)
+		if err != nil {
+			return err
+		}
+		run.Text = text
+		word = append(word, textPiece{run: run, width: w})
+		return nil
+	}
+	for _, run := range st.runs {
+		parts := strings.Split(run.Text, " ")
+		for i, part := range parts {
+			if i > 0 && len(word) > 0 {
+				words = append(words, word)
+				word = []textPiece{}
+			}
+			if err := addPiece(run, part); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+
+	spaceWidth, err := st.textWidth(" ", st.font, st.fontSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// The normal line height is divided above and below the baseline in proportion to the font's ascent and descent.
+	lineAscent := st.lineHeight * st.fontSize * fontAscent / (fontAscent + fontDescent)
+	lineDescent := st.lineHeight*st.fontSize - lineAscent
+
+	lines := []textLine{}
+	line := textLine{ascent: lineAscent, descent: lineDescent}
+	lineWidth := 0.0
+	for _, w := range words {
+		wordWidth := 0.0
+		for _, piece := range w {
+			wordWidth += piece.width
+		}
+		if len(line.words) > 0 && lineWidth+spaceWidth+wordWidth > st.width {
+			lines = append(lines, line)
+			line = textLine{ascent: lineAscent, descent: lineDescent}
+			lineWidth = 0
+		}
+		if len(line.words) > 0 {
+			lineWidth += spaceWidth
+		}
+		line.words = append(line.words, w)
+		lineWidth += wordWidth
+
+		for _, piece := range w {
+			_, size, rise := st.runStyle(piece.run)
+			line.ascent = math.Max(line.ascent, rise+size*fontAscent)
+			line.descent = math.Max(line.descent, size*fontDescent-rise)
+		}
+	}
+	if len(line.words) > 0 {
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// GeneratePageBlocks draws the text on a block representing the page.  Implements the Drawable interface.
+func (st *StyledText) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	lines, err := st.layout()
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	resources := pdf.NewPdfPageResources()
+	fontNames := map[bool]pdfcore.PdfObjectName{false: "F1", true: "F2"}
+	err = resources.SetFontByName(fontNames[false], st.font.ToPdfObject())
+	if err != nil {
+		return nil, ctx, err
+	}
+	err = resources.SetFontByName(fontNames[true], st.italicFont.ToPdfObject())
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q()
+	cc.Add_rg(st.color.ToRGB())
+
+	y := st.y
+	for _, line := range lines {
+		y += line.ascent
+		cc.Add_BT()
+		cc.Add_Td(st.x, ctx.PageHeight-y)
+		for i, word := range line.words {
+			if i > 0 {
+				cc.Add_Tf(fontNames[false], st.fontSize).Add_Ts(0)
+				cc.Add_Tj(pdfcore.PdfObjectString(st.encoder.Encode(" ")))
+			}
+			for _, piece := range word {
+				_, size, rise := st.runStyle(piece.run)
+				cc.Add_Tf(fontNames[piece.run.Italic], size).Add_Ts(rise)
+				cc.Add_Tj(pdfcore.PdfObjectString(st.encoder.Encode(piece.run.Text)))
+			}
+		}
+		cc.Add_ET()
+		y += line.descent
+	}
+	cc.Add_Q()
+
+	// Blocks with custom contents are created from a page with the contents.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = resources
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+// ParseStyledText parses the markup: ^{...} superscript, _{...} subscript (braces optional for a single character),
+// *...* italics and backslash escapes.
+func ParseStyledText(markup string) ([]TextRun, error) {
+	runs := []TextRun{}
+	current := TextRun{}
+	text := []rune{}
+	flush := func() {
+		if len(text) > 0 {
+			current.Text = string(text)
+			runs = append(runs, current)
+			text = text[:0]
+		}
+	}
+
+	in := []rune(markup)
+	for i := 0; i < len(in); i++ {
+		r := in[i]
+		switch r {
+		case '\\':
+			if i+1 < len(in) {
+				i++
+				text = append(text, in[i])
+			}
+		case '*':
+			flush()
+			current.Italic = !current.Italic
+		case '^', '_':
+			if current.Position != PositionNormal {
+				return nil, errors.New("Nested superscripts and subscripts are not supported")
+			}
+			if i+1 >= len(in) {
+				return nil, fmt.Errorf("Missing text after %c", r)
+			}
+			flush()
+			position := PositionSuperscript
+			if r == '_' {
+				position = PositionSubscript
+			}
+
+			var script []rune
+			if in[i+1] == '{' {
+				end := i + 2
+				for end < len(in) && in[end] != '}' {
+					end++
+				}
+				if end >= len(in) {
+					return nil, fmt.Errorf("Missing } after %c{", r)
+				}
+				script = in[i+2 : end]
+				i = end
+			} else {
+				script = in[i+1 : i+2]
+				i++
+			}
+			runs = append(runs, TextRun{Text: string(script), Position: position, Italic: current.Italic})
+		default:
+			text = append(text, r)
+		}
+	}
+	flush()
+
+	return runs, nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run super_subscript.go output.pdf [\"text with markup\"]\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+	userText := ""
+	if len(os.Args) > 2 {
+		userText = os.Args[2]
+	}
+
+	err := createDocument(userText, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createDocument(userText string, outputPath string) error {
+	c := creator.New()
+	c.NewPage()
+
+	const left, width = 72.0, 450.0
+	y := 72.0
+
+	heading := func(text string) {
+		p := creator.NewParagraph(text)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(13)
+		p.SetPos(left, y)
+		_ = c.Draw(p)
+		y += 22
+	}
+	styled := func(markup string, fontSize, lineHeight float64) error {
+		runs, err := ParseStyledText(markup)
+		if err != nil {
+			return err
+		}
+		st := NewStyledText(runs)
+		st.SetFontSize(fontSize)
+		st.SetLineHeight(lineHeight)
+		st.SetWidth(width)
+		st.SetPos(left, y)
+		height, err := st.Height()
+		if err != nil {
+			return err
+		}
+		err = c.Draw(st)
+		if err != nil {
+			return err
+		}
+		y += height + 10
+		return nil
+	}
+
+	samples := []struct {
+		heading string
+		lines   []string
+	}{
+		{"Formulas", []string{
+			"*E* = *mc*^2",
+			"*a*^2 + *b*^2 = *c*^2",
+			"sin^2 *x* + cos^2 *x* = 1",
+			"*x*_{1,2} = (-*b* ± (*b*^2 - 4*ac*)^{1/2}) / 2*a*",
+		}},
+		{"Chemistry", []string{
+			"H_2O, CO_2, C_6H_{12}O_6",
+			"2 H_2 + O_2 = 2 H_2O",
+			"SO_4^{2-} and NH_4^+",
+		}},
+		{"Units and numbers", []string{
+			"Avogadro constant: *N*_A = 6.022 × 10^{23} mol^{-1}",
+			"Acceleration: 9.81 m/s^2, area: 25 cm^2, density: 1.2 kg m^{-3}",
+			"The 1^{st}, 2^{nd} and 3^{rd} place.",
+		}},
+	}
+	for _, sample := range samples {
+		heading(sample.heading)
+		for _, line := range sample.lines {
+			err := styled(line, 14, 1.2)
+			if err != nil {
+				return fmt.Errorf("%q: %v", line, err)
+			}
+		}
+		y += 8
+	}
+
+	// A wrapped paragraph with tight line spacing: the lines with superscripts and subscripts are moved apart where
+	// needed, the other lines keep the line height.
+	heading("Wrapped text with tight line spacing")
+	paragraph := "The kinetic energy of a body is *E*_k = ½*mv*^2, where *m* is the mass and *v* the velocity. " +
+		"The ideal gas law *pV* = *nRT* relates pressure, volume and temperature, with *R* = 8.314 J mol^{-1} " +
+		"K^{-1}. Water (H_2O) boils at 100 °C at standard pressure, 1.013 × 10^5 Pa. The footnote markers^{1} " +
+		"refer to the notes^{2} below, while plain lines of text keep the normal line spacing of the paragraph " +
+		"without any extra gaps between them."
+	err := styled(paragraph, 11, 1.05)
+	if err != nil {
+		return err
+	}
+
+	if userText != "" {
+		y += 8
+		heading("Your text")
+		err = styled(userText, 14, 1.2)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.WriteToFile(outputPath)
+}
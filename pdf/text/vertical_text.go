@@ -0,0 +1,350 @@
+/*
+ * Lay out Japanese text in vertical writing mode: characters are stacked from top to bottom and the columns proceed
+ * from right to left.
+ *
+ * The CJK text uses a Type0 (composite) font with the predefined CMap UniJIS-UCS2-V, which selects the vertical
+ * writing mode: each character advances downwards by its vertical metrics (DW2 in the CIDFont: the vertical origin
+ * at 880 units and an advance of -1000 units, i.e. one em).  The -V CMap also maps punctuation and other characters
+ * that are rotated or repositioned in vertical text, such as 、。「」（）and ー, to their vertical glyph forms, so
+ * they are not rotated explicitly.
+ *
+ * Latin text embedded in the vertical text is handled in the two common ways:
+ * - Short numbers and "!?" of up to two characters are set upright across the column (tate-chu-yoko), centered in
+ *   the character cell and condensed if needed.
+ * - Longer words are rotated 90 degrees clockwise and set sideways along the column, in Helvetica.
+ * Columns are broken before a sideways word that does not fit, and the punctuation 、。 is allowed to hang below the
+ * end of a column rather than start a new one.
+ *
+ * The CJK font (Kozuka Mincho Pr6N, Adobe-Japan1) is not embedded; viewers use their own Japanese font, e.g. the
+ * Adobe Reader font packs, so the text can look different depending on the installed fonts.
+ *
+ * Run as: go run vertical_text.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf16"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcontent "github.com/unidoc/unidoc/pdf/contentstream"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+	"github.com/unidoc/unidoc/pdf/model/textencoding"
+)
+
+// Ways to set a segment of vertical text.
+const (
+	SegmentUpright  = iota // CJK characters, stacked top to bottom.
+	SegmentTateChu         // Short horizontal text across the column (tate-chu-yoko).
+	SegmentSideways        // Latin text rotated 90 degrees clockwise.
+)
+
+// Fraction of the font size between the columns' center lines.
+const columnSpacing = 1.7
+
+var sampleText = []string{
+	"　縦書きは、日本語や中国語などの伝統的な書字方向です。文字は上から下へ並び、行は右から左へ進みます。",
+	"　句読点「、」や「。」、括弧（かっこ）、長音記号の「ー」は、縦書き用の字形に置き換えられます。",
+	"　英数字が混ざる場合、PDFやUniDocのような単語は九十度回転して組み、2017年6月14日の「6」や「14」のような" +
+		"短い数字は縦中横で組みます。感嘆符も!?のように一文字分に収めます。",
+}
+
+// segment is a piece of text set in one way.
+type segment struct {
+	kind  int
+	text  string
+	width float64 // Extent along the column, in points.
+}
+
+// VerticalText lays out paragraphs in vertical columns within a box, from the right edge of the box to the left.
+// Implements the creator Drawable interface.
+type VerticalText struct {
+	paragraphs          []string
+	fontSize            float64
+	x, y, width, height float64
+	latinFont           fonts.Font
+	latinEncoder        textencoding.TextEncoder
+}
+
+// NewVerticalText returns vertical text in the box at (x, y) of the given size, from the upper left corner of the
+// page.
+func NewVerticalText(paragraphs []string, x, y, width, height float64) *VerticalText {
+	return &VerticalText{
+		paragraphs:   paragraphs,
+		fontSize:     14,
+		x:            x,
+		y:            y,
+		width:        width,
+		height:       height,
+		latinFont:    fonts.NewFontHelvetica(),
+		latinEncoder: textencoding.NewWinAnsiTextEncoder(),
+	}
+}
+
+// makeVerticalFont returns a Type0 font with the vertical writing mode CMap UniJIS-UCS2-V and its CIDFont with the
+// vertical metrics.
+func makeVerticalFont() *pdfcore.PdfIndirectObject {
+	const fontName = "KozMinPr6N-Regular"
+
+	descriptor := pdfcore.MakeDict()
+	descriptor.Set("Type", pdfcore.MakeName("FontDescriptor"))
+	descriptor.Set("FontName", pdfcore.MakeName(fontName))
+	descriptor.Set("Flags", pdfcore.MakeInteger(6)) // Serif, symbolic.
+	descriptor.Set("FontBBox", pdfcore.MakeArray(pdfcore.MakeInteger(-437), pdfcore.MakeInteger(-340),
+		pdfcore.MakeInteger(1147), pdfcore.MakeInteger(1317)))
+	descriptor.Set("ItalicAngle", pdfcore.MakeInteger(0))
+	descriptor.Set("Ascent", pdfcore.MakeInteger(1317))
+	descriptor.Set("Descent", pdfcore.MakeInteger(-349))
+	descriptor.Set("CapHeight", pdfcore.MakeInteger(742))
+	descriptor.Set("StemV", pdfcore.MakeInteger(80))
+
+	sysInfo := pdfcore.MakeDict()
+	sysInfo.Set("Registry", pdfcore.MakeString("Adobe"))
+	sysInfo.Set("Ordering", pdfcore.MakeString("Japan1"))
+	sysInfo.Set("Supplement", pdfcore.MakeInteger(6))
+
+	cidFont := pdfcore.MakeDict()
+	cidFont.Set("Type", pdfcore.MakeName("Font"))
+	cidFont.Set("Subtype", pdfcore.MakeName("CIDFontType0"))
+	cidFont.Set("BaseFont", pdfcore.MakeName(fontName))
+	cidFont.Set("CIDSystemInfo", sysInfo)
+	cidFont.Set("FontDescriptor", &pdfcore.PdfIndirectObject{PdfObject: descriptor})
+	// Horizontal and vertical default metrics: 1000 units wide, vertical origin at 880 and a vertical advance of
+	// 1000 units downwards.
+	cidFont.Set("DW", pdfcore.MakeInteger(1000))
+	cidFont.Set("DW2", pdfcore.MakeArray(pdfcore.MakeInteger(880), pdfcore.MakeInteger(-1000)))
+
+	font := pdfcore.MakeDict()
+	font.Set("Type", pdfcore.MakeName("Font"))
+	font.Set("Subtype", pdfcore.MakeName("Type0"))
+	font.Set("BaseFont", pdfcore.MakeName(fontName+"-UniJIS-UCS2-V"))
+	font.Set("Encoding", pdfcore.MakeName("UniJIS-UCS2-V"))
+	font.Set("DescendantFonts", pdfcore.MakeArray(&pdfcore.PdfIndirectObject{PdfObject: cidFont}))
+
+	return &pdfcore.PdfIndirectObject{PdfObject: font}
+}
+
+// encodeUCS2 encodes text as big-endian UCS-2 codes for the UniJIS-UCS2 CMaps.
+func encodeUCS2(text string) string {
+	codes := utf16.Encode([]rune(text))
+	b := make([]byte, 0, 2*len(codes))
+	for _, code := range codes {
+		b = append(b, byte(code>>8), byte(code))
+	}
+	return string(b)
+}
+
+// isLatin returns true for characters set as Latin text: ASCII and Latin-1 letters, digits and punctuation.  Full
+// width forms and CJK characters are set upright.
+func isLatin(r rune) bool {
+	return r < 0x2000
+}
+
+// latinWidth returns the width of text in the Latin font.
+func (vt *VerticalText) latinWidth(text string, fontSize float64) (float64, error) {
+	width := 0.0
+	for _, r := range text {
+		glyph, found := vt.latinEncoder.RuneToGlyph(r)
+		if !found {
+			return 0, fmt.Errorf("Character %q is not supported by the text encoding", r)
+		}
+		metrics, found := vt.latinFont.GetGlyphCharMetrics(glyph)
+		if !found {
+			return 0, fmt.Errorf("Character %q (%s) is not in the font", r, glyph)
+		}
+		width += fontSize * metrics.Wx / 1000.0
+	}
+	return width, nil
+}
+
+// segments splits a paragraph into segments: single upright CJK characters, and runs of Latin characters which are
+// set across the column if they are short digits or !?, and sideways otherwise.
+func (vt *VerticalText) segments(paragraph string) ([]segment, error) {
+	segments := []segment{}
+	runes := []rune(paragraph)
+	for i := 0; i < len(runes); {
+		if !isLatin(runes[i]) {
+			segments = append(segments, segment{kind: SegmentUpright, text: string(runes[i]), width: vt.fontSize})
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(runes) && isLatin(runes[i]) {
+			i++
+		}
+		text := string(runes[start:i])
+
+		if i-start <= 2 && isTateChuYoko(text) {
+			segments = append(segments, segment{kind: SegmentTateChu, text: text, width: vt.fontSize})
+			continue
+		}
+		w, err := vt.latinWidth(text, vt.fontSize)
+		if err != nil {
+			return nil, err
+		}
+		// Some space around the sideways text.
+		segments = append(segments, segment{kind: SegmentSideways, text: text, width: w + 0.2*vt.fontSize})
+	}
+	return segments, nil
+}
+
+// isTateChuYoko returns true for text that is set horizontally across the column: digits and exclamation and
+// question marks.
+func isTateChuYoko(text string) bool {
+	for _, r := range text {
+		if !(r >= '0' && r <= '9') && r != '!' && r != '?' {
+			return false
+		}
+	}
+	return true
+}
+
+// canHang returns true for punctuation that may hang below the end of a column.
+func canHang(s segment) bool {
+	return s.kind == SegmentUpright && (s.text == "、" || s.text == "。")
+}
+
+// GeneratePageBlocks draws the text on a block representing the page.  Implements the Drawable interface.
+func (vt *VerticalText) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	resources := pdf.NewPdfPageResources()
+	err := resources.SetFontByName("FV", makeVerticalFont())
+	if err != nil {
+		return nil, ctx, err
+	}
+	err = resources.SetFontByName("FL", vt.latinFont.ToPdfObject())
+	if err != nil {
+		return nil, ctx, err
+	}
+
+	// Coordinates in PDF space: the first column center line at the right of the box, the top of the box.
+	size := vt.fontSize
+	colX := vt.x + vt.width - size/2
+	top := ctx.PageHeight - vt.y
+	bottom := top - vt.height
+	pos := top
+
+	cc := pdfcontent.NewContentCreator()
+	cc.Add_q()
+	cc.Add_BT()
+
+	newColumn := func() error {
+		colX -= columnSpacing * size
+		pos = top
+		if colX-size/2 < vt.x {
+			return fmt.Errorf("The text does not fit in the box")
+		}
+		return nil
+	}
+
+	for p, paragraph := range vt.paragraphs {
+		if p > 0 {
+			if err := newColumn(); err != nil {
+				return nil, ctx, err
+			}
+		}
+		segments, err := vt.segments(paragraph)
+		if err != nil {
+			return nil, ctx, err
+		}
+
+		for _, s := range segments {
+			if pos-s.width < bottom && pos < top && !canHang(s) {
+				if err := newColumn(); err != nil {
+					return nil, ctx, err
+				}
+			}
+
+			switch s.kind {
+			case SegmentUpright:
+				// In vertical mode the text position is the glyph's vertical origin at the top center of the
+				// character cell.
+				cc.Add_Tf("FV", size)
+				cc.Add_Tz(100)
+				cc.Add_Tm(1, 0, 0, 1, colX, pos)
+				cc.Add_Tj(pdfcore.PdfObjectString(encodeUCS2(s.text)))
+			case SegmentTateChu:
+				// Horizontal text centered in the character cell, condensed to fit the column width.
+				w, err := vt.latinWidth(s.text, size)
+				if err != nil {
+					return nil, ctx, err
+				}
+				scale := 100.0
+				if w > 0.9*size {
+					scale = 100 * 0.9 * size / w
+					w = 0.9 * size
+				}
+				cc.Add_Tf("FL", size)
+				cc.Add_Tz(scale)
+				cc.Add_Tm(1, 0, 0, 1, colX-w/2, pos-0.5*size-0.35*size)
+				cc.Add_Tj(pdfcore.PdfObjectString(vt.latinEncoder.Encode(s.text)))
+			case SegmentSideways:
+				// Rotated 90 degrees clockwise: the text runs down the column with the tops of the glyphs to the
+				// right.  The baseline is offset to center the glyphs on the column.
+				cc.Add_Tf("FL", size)
+				cc.Add_Tz(100)
+				cc.Add_Tm(0, -1, 1, 0, colX-0.25*size, pos-0.1*size)
+				cc.Add_Tj(pdfcore.PdfObjectString(vt.latinEncoder.Encode(s.text)))
+			}
+			pos -= s.width
+		}
+	}
+
+	cc.Add_ET()
+	cc.Add_Q()
+
+	// Blocks with custom contents are created from a page with the contents.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: ctx.PageWidth, Ury: ctx.PageHeight}
+	page.Resources = resources
+	page.AddContentStreamByString(cc.String())
+
+	block, err := creator.NewBlockFromPage(page)
+	if err != nil {
+		return nil, ctx, err
+	}
+	return []*creator.Block{block}, ctx, nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Printf("Usage: go run vertical_text.go output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	c := creator.New()
+	c.SetPageSize(creator.PageSizeA5)
+	c.NewPage()
+
+	ctx := c.Context()
+	const margin = 50.0
+	box := creator.NewRectangle(margin, margin, ctx.PageWidth-2*margin, ctx.PageHeight-2*margin)
+	box.SetBorderColor(creator.ColorRGBFrom8bit(200, 200, 200))
+	box.SetBorderWidth(0.5)
+	_ = c.Draw(box)
+
+	vt := NewVerticalText(sampleText, margin+10, margin+10, ctx.PageWidth-2*margin-20, ctx.PageHeight-2*margin-20)
+	err := c.Draw(vt)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	err = c.WriteToFile(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
@@ -0,0 +1,468 @@
+/*
+ * Create a boarding pass on a custom 8 x 3.25 inch page: a main panel with the flight details and a QR code, and a
+ * tear-off stub separated by a dashed tear line.
+ *
+ * The page is created with an explicit MediaBox rather than one of the creator's standard page sizes.  Long field
+ * values such as the passenger name wrap within their field.
+ *
+ * The QR code encodes the flight details in the mandatory fields of the IATA bar coded boarding pass (BCBP) format.
+ * The payload is decoded again and compared to the input before writing, so fields that do not fit their fixed
+ * width are reported instead of producing a code that scans differently.  The QR modules are drawn as vector
+ * rectangles with the required quiet zone, so the code stays sharp at any print size.
+ *
+ * Run as: go run boarding_pass.go [-name "DOE/JANE MARIE"] [-from JFK] [-to LHR] [-flight "BA 0178"]
+ *                                 [-date 2017-06-14] [-seat 23A] output.pdf
+ */
+/*
+ * NOTE: This example depends on github.com/boombuler/barcode, MIT licensed.
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run boarding_pass.go [-name ...] [-from ...] [-to ...] [-flight ...] [-date ...] " +
+	"[-seat ...] output.pdf\n"
+
+// Page layout (points).
+const (
+	pageWidth    = 8 * 72
+	pageHeight   = 3.25 * 72
+	headerHeight = 34.0
+	tearX        = 420.0 // Position of the tear line; the stub is to the right.
+	panelPadding = 14.0
+	qrSize       = 112.0
+)
+
+var (
+	colorBrand = creator.ColorRGBFrom8bit(0, 70, 140)
+	colorLabel = creator.ColorRGBFrom8bit(110, 110, 110)
+	colorText  = creator.ColorRGBFrom8bit(20, 20, 20)
+	colorWhite = creator.ColorRGBFrom8bit(255, 255, 255)
+	colorPaper = creator.ColorRGBFrom8bit(240, 245, 250)
+)
+
+// boardingPass holds the ticket fields.
+type boardingPass struct {
+	airline   string
+	name      string // SURNAME/GIVEN NAMES
+	pnr       string // Booking reference.
+	from, to  string // IATA airport codes.
+	fromCity  string
+	toCity    string
+	carrier   string // IATA airline designator.
+	flight    string // Flight number.
+	date      time.Time
+	boarding  string
+	gate      string
+	class     string // Compartment code, e.g. Y for economy.
+	seat      string
+	sequence  int // Check-in sequence number.
+	cabinName string
+}
+
+func main() {
+	bp := boardingPass{
+		airline:   "UNIDOC AIRWAYS",
+		pnr:       "ABC123",
+		fromCity:  "New York",
+		toCity:    "London Heathrow",
+		boarding:  "18:45",
+		gate:      "B22",
+		class:     "Y",
+		sequence:  42,
+		cabinName: "Economy",
+	}
+	flightStr := ""
+	dateStr := ""
+	flag.StringVar(&bp.name, "name", "MONTGOMERY-RICHARDSON/ALEXANDRA ELIZABETH", "Passenger (SURNAME/GIVEN NAMES)")
+	flag.StringVar(&bp.from, "from", "JFK", "Departure airport code")
+	flag.StringVar(&bp.to, "to", "LHR", "Arrival airport code")
+	flag.StringVar(&flightStr, "flight", "UD 0178", "Carrier and flight number")
+	flag.StringVar(&dateStr, "date", "2017-06-14", "Flight date (YYYY-MM-DD)")
+	flag.StringVar(&bp.seat, "seat", "23A", "Seat")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := flag.Arg(0)
+
+	parts := strings.Fields(flightStr)
+	if len(parts) != 2 {
+		fmt.Printf("Error: Flight must be given as carrier and number, e.g. \"UD 0178\"\n")
+		os.Exit(1)
+	}
+	bp.carrier, bp.flight = strings.ToUpper(parts[0]), strings.ToUpper(parts[1])
+	bp.name = strings.ToUpper(bp.name)
+	bp.from = strings.ToUpper(bp.from)
+	bp.to = strings.ToUpper(bp.to)
+	bp.seat = strings.ToUpper(bp.seat)
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	bp.date = date
+
+	payload, err := encodeBCBP(bp)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("QR payload: %q\n", payload)
+
+	err = createBoardingPass(bp, payload, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// encodeBCBP returns the mandatory fields of an IATA BCBP (Resolution 792) single leg boarding pass.  The name is
+// truncated to 20 characters as the standard prescribes; any other field that does not fit is an error.  The
+// payload is decoded again and compared with the input to make sure it round-trips.
+func encodeBCBP(bp boardingPass) (string, error) {
+	for _, r := range bp.name {
+		if r > 126 {
+			return "", errors.New("The BCBP name field is ASCII only, use a transliterated name")
+		}
+	}
+	if len(bp.from) != 3 || len(bp.to) != 3 {
+		return "", fmt.Errorf("Airport codes must have 3 letters: %q, %q", bp.from, bp.to)
+	}
+	if len(bp.carrier) < 2 || len(bp.carrier) > 3 {
+		return "", fmt.Errorf("Invalid carrier designator %q", bp.carrier)
+	}
+
+	name := bp.name
+	if len(name) > 20 {
+		name = name[:20]
+	}
+
+	flightNum, suffix := bp.flight, ""
+	if n := len(flightNum); n > 0 && (flightNum[n-1] < '0' || flightNum[n-1] > '9') {
+		flightNum, suffix = flightNum[:n-1], flightNum[n-1:]
+	}
+	num, err := strconv.Atoi(flightNum)
+	if err != nil || num < 1 || num > 9999 {
+		return "", fmt.Errorf("Invalid flight number %q", bp.flight)
+	}
+
+	if len(bp.seat) < 2 {
+		return "", fmt.Errorf("Invalid seat %q", bp.seat)
+	}
+	seatRow, seatLetter := bp.seat[:len(bp.seat)-1], bp.seat[len(bp.seat)-1:]
+	row, err := strconv.Atoi(seatRow)
+	if err != nil || row < 1 || row > 999 {
+		return "", fmt.Errorf("Invalid seat %q", bp.seat)
+	}
+
+	var b strings.Builder
+	b.WriteString("M1")                         // Format code and number of legs.
+	fmt.Fprintf(&b, "%-20s", name)              // Passenger name.
+	b.WriteString("E")                          // Electronic ticket indicator.
+	fmt.Fprintf(&b, "%-7s", bp.pnr)             // Booking reference.
+	fmt.Fprintf(&b, "%-3s%-3s", bp.from, bp.to) // From and to airports.
+	fmt.Fprintf(&b, "%-3s", bp.carrier)         // Operating carrier.
+	fmt.Fprintf(&b, "%04d%-1s", num, suffix)    // Flight number.
+	fmt.Fprintf(&b, "%03d", bp.date.YearDay())  // Date of flight (Julian date).
+	fmt.Fprintf(&b, "%-1s", bp.class)           // Compartment code.
+	fmt.Fprintf(&b, "%03d%s", row, seatLetter)  // Seat number.
+	fmt.Fprintf(&b, "%04d ", bp.sequence)       // Check-in sequence number.
+	b.WriteString("1")                          // Passenger status.
+	b.WriteString("00")                         // Size of the conditional items (none).
+	payload := b.String()
+
+	// Decode the payload and check that every field reads back as given.
+	decoded, err := decodeBCBP(payload)
+	if err != nil {
+		return "", err
+	}
+	expected := map[string]string{
+		"name":     name,
+		"pnr":      bp.pnr,
+		"from":     bp.from,
+		"to":       bp.to,
+		"carrier":  bp.carrier,
+		"flight":   fmt.Sprintf("%04d%s", num, suffix),
+		"julian":   fmt.Sprintf("%03d", bp.date.YearDay()),
+		"class":    bp.class,
+		"seat":     fmt.Sprintf("%03d%s", row, seatLetter),
+		"sequence": fmt.Sprintf("%04d", bp.sequence),
+	}
+	for field, value := range expected {
+		if decoded[field] != strings.TrimSpace(value) {
+			return "", fmt.Errorf("BCBP field %s does not round-trip: %q encoded as %q", field, value,
+				decoded[field])
+		}
+	}
+
+	return payload, nil
+}
+
+// decodeBCBP splits the mandatory fields of a single leg BCBP payload.
+func decodeBCBP(payload string) (map[string]string, error) {
+	if len(payload) != 60 || !strings.HasPrefix(payload, "M1") {
+		return nil, fmt.Errorf("Invalid BCBP payload length %d (expecting 60)", len(payload))
+	}
+
+	fields := map[string]string{}
+	for _, f := range []struct {
+		name       string
+		start, end int
+	}{
+		{"name", 2, 22},
+		{"pnr", 23, 30},
+		{"from", 30, 33},
+		{"to", 33, 36},
+		{"carrier", 36, 39},
+		{"flight", 39, 44},
+		{"julian", 44, 47},
+		{"class", 47, 48},
+		{"seat", 48, 52},
+		{"sequence", 52, 57},
+	} {
+		fields[f.name] = strings.TrimSpace(payload[f.start:f.end])
+	}
+	return fields, nil
+}
+
+func createBoardingPass(bp boardingPass, payload string, outputPath string) error {
+	qrCode, err := qr.Encode(payload, qr.M, qr.Auto)
+	if err != nil {
+		return err
+	}
+	if qrCode.Content() != payload {
+		return errors.New("QR code content differs from the payload")
+	}
+
+	c := creator.New()
+
+	// A page with a custom MediaBox.  The creator's SetPageSize does the same for new pages; here the page is set
+	// up explicitly.
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: pageWidth, Ury: pageHeight}
+	err = c.AddPage(page)
+	if err != nil {
+		return err
+	}
+
+	background := creator.NewRectangle(0, 0, pageWidth, pageHeight)
+	background.SetFillColor(colorPaper)
+	background.SetBorderWidth(0)
+	_ = c.Draw(background)
+
+	header := creator.NewRectangle(0, 0, pageWidth, headerHeight)
+	header.SetFillColor(colorBrand)
+	header.SetBorderWidth(0)
+	_ = c.Draw(header)
+	drawText(c, bp.airline, fonts.NewFontHelveticaBold(), 14, colorWhite, panelPadding, 10)
+	drawText(c, "BOARDING PASS", fonts.NewFontHelvetica(), 11, colorWhite, tearX-panelPadding-qrSize, 12)
+	drawText(c, "BOARDING PASS", fonts.NewFontHelvetica(), 9, colorWhite, tearX+panelPadding, 13)
+
+	drawMainPanel(c, bp)
+	drawStub(c, bp)
+	drawTearLine(c)
+	drawQRCode(c, qrCode, tearX-panelPadding-qrSize, headerHeight+panelPadding, qrSize)
+
+	return c.WriteToFile(outputPath)
+}
+
+// drawMainPanel draws the fields of the main panel, left of the QR code, row by row.
+func drawMainPanel(c *creator.Creator, bp boardingPass) {
+	x := panelPadding
+	width := tearX - 3*panelPadding - qrSize
+	y := headerHeight + panelPadding
+
+	y += drawField(c, "PASSENGER", displayName(bp.name), x, y, width, 11)
+	y += 6
+
+	// Route with the airport codes large and the city names below.
+	colWidth := width / 2
+	h1 := drawField(c, bp.fromCity, bp.from, x, y, colWidth-8, 24)
+	h2 := drawField(c, bp.toCity, bp.to, x+colWidth, y, colWidth-8, 24)
+	y += max(h1, h2) + 6
+
+	colWidth = width / 4
+	row := [][2]string{
+		{"FLIGHT", bp.carrier + " " + bp.flight},
+		{"DATE", strings.ToUpper(bp.date.Format("02 Jan"))},
+		{"BOARDING", bp.boarding},
+		{"GATE", bp.gate},
+	}
+	y += drawRow(c, row, x, y, colWidth) + 6
+
+	row = [][2]string{
+		{"SEAT", bp.seat},
+		{"CLASS", bp.cabinName},
+		{"SEQ", fmt.Sprintf("%03d", bp.sequence)},
+		{"BOOKING", bp.pnr},
+	}
+	drawRow(c, row, x, y, colWidth)
+}
+
+// drawStub draws the tear-off stub right of the tear line.
+func drawStub(c *creator.Creator, bp boardingPass) {
+	x := tearX + panelPadding
+	width := pageWidth - tearX - 2*panelPadding
+	y := headerHeight + panelPadding
+
+	y += drawField(c, "PASSENGER", displayName(bp.name), x, y, width, 8)
+	y += 4
+	y += drawField(c, "FROM / TO", bp.from+" - "+bp.to, x, y, width, 14) + 4
+
+	colWidth := width / 2
+	y += drawRow(c, [][2]string{
+		{"FLIGHT", bp.carrier + " " + bp.flight},
+		{"DATE", strings.ToUpper(bp.date.Format("02 Jan"))},
+	}, x, y, colWidth) + 4
+	drawRow(c, [][2]string{
+		{"SEAT", bp.seat},
+		{"GATE", bp.gate},
+	}, x, y, colWidth)
+}
+
+// displayName adds spaces around the slash between surname and given names, so that long names wrap there rather
+// than within a word.
+func displayName(name string) string {
+	return strings.Replace(name, "/", " / ", 1)
+}
+
+// drawRow draws the (label, value) fields side by side in columns of width colWidth and returns the height of the
+// tallest field.
+func drawRow(c *creator.Creator, fields [][2]string, x, y, colWidth float64) float64 {
+	height := 0.0
+	for i, f := range fields {
+		h := drawField(c, f[0], f[1], x+float64(i)*colWidth, y, colWidth-6, 11)
+		height = max(height, h)
+	}
+	return height
+}
+
+// drawField draws a small gray label with the value below it, wrapped to width, and returns the total height.
+func drawField(c *creator.Creator, label, value string, x, y, width, fontSize float64) float64 {
+	l := newParagraph(strings.ToUpper(label), fonts.NewFontHelvetica(), 6.5, colorLabel)
+	l.SetWidth(width)
+	l.SetPos(x, y)
+	_ = c.Draw(l)
+
+	v := newParagraph(value, fonts.NewFontHelveticaBold(), fontSize, colorText)
+	v.SetWidth(width)
+	v.SetPos(x, y+l.Height())
+	_ = c.Draw(v)
+
+	return l.Height() + v.Height()
+}
+
+func drawText(c *creator.Creator, text string, font fonts.Font, fontSize float64, col creator.Color, x, y float64) {
+	p := newParagraph(text, font, fontSize, col)
+	p.SetEnableWrap(false)
+	p.SetPos(x, y)
+	_ = c.Draw(p)
+}
+
+func newParagraph(text string, font fonts.Font, fontSize float64, col creator.Color) *creator.Paragraph {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(fontSize)
+	p.SetColor(col)
+	p.SetLineHeight(1.15)
+	return p
+}
+
+// drawTearLine draws a dashed vertical line between the main panel and the stub, with semicircular notches at the
+// top and bottom edges.  The creator's lines are solid, so the dashes are drawn as separate short lines.
+func drawTearLine(c *creator.Creator) {
+	const dash, gap = 4.0, 3.0
+	for y := headerHeight; y < pageHeight; y += dash + gap {
+		end := y + dash
+		if end > pageHeight {
+			end = pageHeight
+		}
+		line := creator.NewLine(tearX, y, tearX, end)
+		line.SetColor(colorLabel)
+		line.SetLineWidth(0.75)
+		_ = c.Draw(line)
+	}
+
+	for _, y := range []float64{0, pageHeight} {
+		notch := creator.NewEllipse(tearX, y, 14, 14)
+		notch.SetFillColor(colorWhite)
+		notch.SetBorderColor(colorWhite)
+		notch.SetBorderWidth(0)
+		_ = c.Draw(notch)
+	}
+}
+
+// drawQRCode draws the QR code with its top left corner at x, y as vector rectangles, including the 4 module quiet
+// zone, within size x size points.  Horizontal runs of dark modules are merged into one rectangle.
+func drawQRCode(c *creator.Creator, qrCode barcode.Barcode, x, y, size float64) {
+	const quietZone = 4
+	bounds := qrCode.Bounds()
+	module := size / float64(bounds.Dx()+2*quietZone)
+
+	white := creator.NewRectangle(x, y, size, size)
+	white.SetFillColor(colorWhite)
+	white.SetBorderWidth(0)
+	_ = c.Draw(white)
+
+	black := creator.ColorRGBFrom8bit(0, 0, 0)
+	for j := bounds.Min.Y; j < bounds.Max.Y; j++ {
+		for i := bounds.Min.X; i < bounds.Max.X; {
+			if !isDark(qrCode, i, j) {
+				i++
+				continue
+			}
+			start := i
+			for i < bounds.Max.X && isDark(qrCode, i, j) {
+				i++
+			}
+			rect := creator.NewRectangle(x+float64(quietZone+start)*module, y+float64(quietZone+j)*module,
+				float64(i-start)*module, module)
+			rect.SetFillColor(black)
+			rect.SetBorderWidth(0)
+			_ = c.Draw(rect)
+		}
+	}
+}
+
+func isDark(bc barcode.Barcode, x, y int) bool {
+	r, _, _, _ := bc.At(x, y).RGBA()
+	return r == 0
+}
+
+func max(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
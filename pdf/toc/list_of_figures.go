@@ -0,0 +1,415 @@
+/*
+ * Create a document with a list of figures and a list of tables after the table of contents, with the page numbers
+ * of the captioned figures and tables.
+ *
+ * The lists work like the table of contents of the creator, which collects the chapters with their page numbers as
+ * they are drawn: figures and tables are wrapped in captioned drawables that register their caption with the page
+ * number in a list when they are drawn.  The numbers in the captions (Figure 1, Table 1) are assigned in drawing
+ * order as well.  The lists are added to the contents chapter, which the creator generates after the document has
+ * been laid out and places in front.
+ *
+ * A figure is kept together with its caption: if both do not fit on the rest of the page, they are moved to the next
+ * page, and the page is recorded where they are actually drawn, not where the drawing started.  Table captions are
+ * above the table and are kept with the first rows, a table may continue on the next pages and is listed with the
+ * page it starts on.
+ *
+ * The creator shifts the chapter page numbers by the number of pages it inserts in front (the contents), the figure
+ * and table pages are shifted by the same amount.
+ *
+ * Run as: go run list_of_figures.go output.pdf
+ */
+
+package main
+
+import (
+	"fmt"
+	goimage "image"
+	"image/color"
+	"os"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run list_of_figures.go output.pdf\n"
+
+// Page margins, on all sides.
+const margin = 60.0
+
+// captionList collects the captions of one kind, figures or tables, with their page numbers as they are drawn.
+type captionList struct {
+	label   string // Caption label, e.g. Figure.
+	above   bool   // Caption above the content, as for tables.
+	entries []captionEntry
+}
+
+// captionEntry is a caption with the page number where it was drawn.
+type captionEntry struct {
+	number int
+	title  string
+	page   int
+	moved  bool // Moved to the next page to keep the content with the caption.
+}
+
+// captioned is a figure or table with its caption, implementing the creator's Drawable interface.  The caption is
+// registered in its list when drawn.
+type captioned struct {
+	list    *captionList
+	content creator.Drawable
+	title   string
+	// Height of the content that is kept together with the caption: the whole figure, or the first rows of a table.
+	keepHeight float64
+}
+
+// Text of the body paragraphs.
+const loremText = "The measurements were taken over a period of twelve months at three sites, with sensors " +
+	"sampling every minute.  The values were averaged per month and checked against the manual readings of the " +
+	"site staff.  Outliers caused by sensor faults were removed before the averages were computed, they are listed " +
+	"in the appendix of the full report."
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	outputPath := os.Args[1]
+
+	err := createDocument(outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func createDocument(outputPath string) error {
+	c := creator.New()
+	c.SetPageMargins(margin, margin, margin, margin)
+	figures := &captionList{label: "Figure"}
+	tables := &captionList{label: "Table", above: true}
+
+	// 1. Introduction: text and a figure.
+	err := drawChapter(c, "Introduction", 2)
+	if err != nil {
+		return err
+	}
+	err = drawFigure(c, figures, "Monthly averages at site A", []float64{3, 5, 8, 12, 16, 19, 21, 20, 17, 12, 7, 4},
+		220)
+	if err != nil {
+		return err
+	}
+	err = drawText(c, 1)
+	if err != nil {
+		return err
+	}
+
+	// 2. Results: a table, text, and a tall figure that does not fit on the rest of the page.
+	c.NewPage()
+	err = drawChapter(c, "Results", 1)
+	if err != nil {
+		return err
+	}
+	err = c.Draw(newTable(tables, "Averages per site", 12))
+	if err != nil {
+		return err
+	}
+	err = drawText(c, 2)
+	if err != nil {
+		return err
+	}
+	err = drawFigure(c, figures, "Monthly averages at site B", []float64{1, 2, 6, 10, 14, 18, 20, 19, 15, 10, 5, 2},
+		300)
+	if err != nil {
+		return err
+	}
+	err = drawFigure(c, figures, "Monthly averages at site C", []float64{6, 7, 10, 13, 17, 21, 24, 24, 20, 15, 10, 7},
+		180)
+	if err != nil {
+		return err
+	}
+
+	// 3. Discussion: a long table that continues on the next page.
+	c.NewPage()
+	err = drawChapter(c, "Discussion", 1)
+	if err != nil {
+		return err
+	}
+	err = c.Draw(newTable(tables, "Daily readings over six weeks", 42))
+	if err != nil {
+		return err
+	}
+	err = drawText(c, 1)
+	if err != nil {
+		return err
+	}
+
+	// The contents: the chapters from the creator's table of contents, and the figures and tables from the lists.
+	// The creator calls the function twice, to estimate the number of pages of the contents, and then to draw them
+	// with the chapter page numbers shifted by the pages inserted in front.  The shift is the difference of the first
+	// chapter between the calls.
+	firstPage := -1
+	offset := 0
+	c.CreateTableOfContents(func(toc *creator.TableOfContents) (*creator.Chapter, error) {
+		entries := toc.Entries()
+		if len(entries) > 0 {
+			if firstPage < 0 {
+				firstPage = entries[0].PageNumber
+			} else {
+				offset = entries[0].PageNumber - firstPage
+			}
+		}
+
+		ch := c.NewChapter("Contents")
+		ch.GetHeading().SetFont(fonts.NewFontHelveticaBold())
+		ch.GetHeading().SetFontSize(20)
+		ch.GetHeading().SetMargins(0, 0, 0, 20)
+
+		table := creator.NewTable(2)
+		table.SetColumnWidths(0.9, 0.1)
+		for _, entry := range entries {
+			title := fmt.Sprintf("%d. %s", entry.Chapter, entry.Title)
+			if entry.Subchapter > 0 {
+				title = fmt.Sprintf("        %d.%d. %s", entry.Chapter, entry.Subchapter, entry.Title)
+			}
+			addListRow(table, title, entry.PageNumber)
+		}
+		err := ch.Add(table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, list := range []*captionList{figures, tables} {
+			heading := creator.NewParagraph("List of " + list.label + "s")
+			heading.SetFont(fonts.NewFontHelveticaBold())
+			heading.SetFontSize(14)
+			heading.SetMargins(0, 0, 24, 10)
+			err = ch.Add(heading)
+			if err != nil {
+				return nil, err
+			}
+
+			table := creator.NewTable(2)
+			table.SetColumnWidths(0.9, 0.1)
+			for _, entry := range list.entries {
+				addListRow(table, fmt.Sprintf("%s %d: %s", list.label, entry.number, entry.title), entry.page+offset)
+			}
+			err = ch.Add(table)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return ch, nil
+	})
+
+	c.DrawFooter(func(block *creator.Block, args creator.FooterFunctionArgs) {
+		p := creator.NewParagraph(fmt.Sprintf("Page %d of %d", args.PageNum, args.TotalPages))
+		p.SetFontSize(8)
+		p.SetPos(block.Width()/2-20, 20)
+		block.Draw(p)
+	})
+
+	err = c.WriteToFile(outputPath)
+	if err != nil {
+		return err
+	}
+
+	for _, list := range []*captionList{figures, tables} {
+		for _, entry := range list.entries {
+			moved := ""
+			if entry.moved {
+				moved = " (moved to the next page to keep it with its caption)"
+			}
+			fmt.Printf("%s %d: %s, page %d%s\n", list.label, entry.number, entry.title, entry.page+offset, moved)
+		}
+	}
+	return nil
+}
+
+// Adds a row with the title and the page number to the table of a list.
+func addListRow(table *creator.Table, title string, page int) {
+	p := creator.NewParagraph(title)
+	p.SetFontSize(11)
+	cell := table.NewCell()
+	cell.SetContent(p)
+
+	p = creator.NewParagraph(fmt.Sprintf("%d", page))
+	p.SetFontSize(11)
+	cell = table.NewCell()
+	cell.SetHorizontalAlignment(creator.CellHorizontalAlignmentRight)
+	cell.SetContent(p)
+}
+
+// Draws a chapter with its heading and paragraphs of text.  The chapter registers itself in the table of contents.
+func drawChapter(c *creator.Creator, title string, paragraphs int) error {
+	ch := c.NewChapter(title)
+	ch.GetHeading().SetFont(fonts.NewFontHelveticaBold())
+	ch.GetHeading().SetFontSize(18)
+	ch.GetHeading().SetMargins(0, 0, 0, 12)
+	for i := 0; i < paragraphs; i++ {
+		err := ch.Add(newTextParagraph())
+		if err != nil {
+			return err
+		}
+	}
+	return c.Draw(ch)
+}
+
+// Draws paragraphs of text.
+func drawText(c *creator.Creator, paragraphs int) error {
+	for i := 0; i < paragraphs; i++ {
+		err := c.Draw(newTextParagraph())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newTextParagraph() *creator.Paragraph {
+	p := creator.NewParagraph(loremText)
+	p.SetFontSize(11)
+	p.SetLineHeight(1.3)
+	p.SetMargins(0, 0, 0, 10)
+	return p
+}
+
+// Draws a bar chart of the values as a captioned figure, scaled to the height or the page width if narrower.
+func drawFigure(c *creator.Creator, figures *captionList, title string, values []float64, height float64) error {
+	img, err := creator.NewImageFromGoImage(makeBarChart(values))
+	if err != nil {
+		return err
+	}
+	img.ScaleToHeight(height)
+	if img.Width() > c.Width()-2*margin {
+		img.ScaleToWidth(c.Width() - 2*margin)
+	}
+	img.SetMargins(0, 0, 6, 0)
+	return c.Draw(&captioned{list: figures, content: img, title: title, keepHeight: img.Height() + 6})
+}
+
+// Returns a table of the readings as a captioned table.
+func newTable(tables *captionList, title string, rows int) *captioned {
+	table := creator.NewTable(4)
+	table.SetMargins(0, 0, 0, 12)
+	headerColor := creator.ColorRGBFrom8bit(220, 228, 240)
+	for _, header := range []string{"Row", "Site A", "Site B", "Site C"} {
+		p := creator.NewParagraph(header)
+		p.SetFont(fonts.NewFontHelveticaBold())
+		p.SetFontSize(10)
+		cell := table.NewCell()
+		cell.SetBackgroundColor(headerColor)
+		cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+		cell.SetContent(p)
+	}
+	for i := 1; i <= rows; i++ {
+		for col, value := range []float64{float64(i), 10 + float64(i%7), 8 + float64(i%5), 12 + float64(i%3)} {
+			text := fmt.Sprintf("%.1f", value)
+			if col == 0 {
+				text = fmt.Sprintf("%d", i)
+			}
+			p := creator.NewParagraph(text)
+			p.SetFontSize(10)
+			cell := table.NewCell()
+			cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+			cell.SetContent(p)
+		}
+	}
+
+	// Keep the caption with the header and the first rows.
+	keepHeight := table.Height()
+	if keepHeight > 60 {
+		keepHeight = 60
+	}
+	return &captioned{list: tables, content: table, title: title, keepHeight: keepHeight}
+}
+
+// Returns an image of a bar chart of the values.
+func makeBarChart(values []float64) goimage.Image {
+	barWidth, gap, scale := 30, 10, 12.0
+	maxValue := 0.0
+	for _, v := range values {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+	width := len(values)*(barWidth+gap) + gap
+	height := int(maxValue*scale) + 2*gap
+	img := goimage.NewRGBA(goimage.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{245, 245, 245, 255})
+		}
+	}
+	for i, v := range values {
+		x0 := gap + i*(barWidth+gap)
+		for y := height - gap - int(v*scale); y < height-gap; y++ {
+			for x := x0; x < x0+barWidth; x++ {
+				img.Set(x, y, color.RGBA{70, 120, 180, 255})
+			}
+		}
+	}
+	return img
+}
+
+// GeneratePageBlocks draws the content with its caption, moving both to a new page if they do not fit on the rest of
+// the page, and registers the caption with the page number it is drawn on.  Implements the Drawable interface.
+func (item *captioned) GeneratePageBlocks(ctx creator.DrawContext) ([]*creator.Block, creator.DrawContext, error) {
+	entry := captionEntry{number: len(item.list.entries) + 1, title: item.title}
+
+	caption := creator.NewParagraph(fmt.Sprintf("%s %d: %s", item.list.label, entry.number, item.title))
+	caption.SetFont(fonts.NewFontHelveticaOblique())
+	caption.SetFontSize(10)
+	caption.SetWidth(ctx.Width)
+	if item.list.above {
+		caption.SetMargins(0, 0, 10, 6)
+	} else {
+		caption.SetMargins(0, 0, 6, 16)
+	}
+	_, _, top, bottom := caption.GetMargins()
+	captionHeight := top + caption.Height() + bottom
+
+	// Move to a new page if the content and the caption do not fit, unless already at the top of a page (then they
+	// would not fit on any page).
+	blocks := []*creator.Block{creator.NewBlock(ctx.PageWidth, ctx.PageHeight)}
+	if item.keepHeight+captionHeight > ctx.Height && ctx.Y > margin {
+		blocks = append(blocks, creator.NewBlock(ctx.PageWidth, ctx.PageHeight))
+		ctx.Page++
+		ctx.Y = margin
+		ctx.Height = ctx.PageHeight - 2*margin
+		entry.moved = true
+	}
+	entry.page = ctx.Page
+
+	parts := []creator.Drawable{item.content, caption}
+	if item.list.above {
+		parts = []creator.Drawable{caption, item.content}
+	}
+	for _, part := range parts {
+		page := ctx.Page
+		newBlocks, newCtx, err := part.GeneratePageBlocks(ctx)
+		if err != nil {
+			return nil, ctx, err
+		}
+		if len(newBlocks) == 0 {
+			continue
+		}
+		// The first block is on the current page, merge it with the last block.  Not all drawables count the pages
+		// in the context (tables do not), so the pages are counted from the blocks.
+		err = blocks[len(blocks)-1].Draw(newBlocks[0])
+		if err != nil {
+			return nil, ctx, err
+		}
+		blocks = append(blocks, newBlocks[1:]...)
+		ctx = newCtx
+		ctx.Page = page + len(newBlocks) - 1
+	}
+
+	item.list.entries = append(item.list.entries, entry)
+	return blocks, ctx, nil
+}
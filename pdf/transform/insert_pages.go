@@ -0,0 +1,330 @@
+/*
+ * Insert blank pages or a cover document into a PDF at given positions.
+ *
+ * The positions are given as "insert after page N", with several positions separated by commas, e.g. 0,4,12 inserts
+ * before the first page, after page 4 and after page 12.  Use the page count (or "end") to append at the end.
+ *
+ * Inserted pages get the size of the neighboring page, i.e. the page after which they are inserted (the first page
+ * when inserting at the beginning):
+ * - Blank pages copy the MediaBox, CropBox and Rotate of the neighbor page.
+ * - Cover pages are scaled to fit the neighbor page as displayed (taking /Rotate into account) and centered.  The cover
+ *   is imported once as a Form XObject per cover page and shared by all insertions.
+ *
+ * Run as: go run insert_pages.go [-cover cover.pdf] [-count 1] input.pdf <after_pages> output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+const usage = "Usage: go run insert_pages.go [-cover cover.pdf] [-count 1] input.pdf <after_pages> output.pdf\n"
+
+func main() {
+	coverPath := ""
+	count := 0
+	flag.StringVar(&coverPath, "cover", "", "Insert the pages of this PDF instead of blank pages")
+	flag.IntVar(&count, "count", 1, "Number of blank pages per insertion point")
+	flag.Parse()
+
+	if flag.NArg() < 3 {
+		fmt.Print(usage)
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := flag.Arg(0)
+	positions := flag.Arg(1)
+	outputPath := flag.Arg(2)
+
+	err := insertPages(inputPath, positions, coverPath, count, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+// coverPage is a cover page imported as a Form XObject.
+type coverPage struct {
+	xform *pdf.XObjectForm
+	bbox  *pdf.PdfRectangle
+}
+
+func insertPages(inputPath, positions, coverPath string, count int, outputPath string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := openPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	afterPages, err := parsePositions(positions, numPages)
+	if err != nil {
+		return err
+	}
+
+	// Load the cover pages.  The file needs to remain open until the output has been written.
+	covers := []coverPage{}
+	if len(coverPath) > 0 {
+		fc, err := os.Open(coverPath)
+		if err != nil {
+			return err
+		}
+		defer fc.Close()
+
+		coverReader, err := openPdfReader(fc)
+		if err != nil {
+			return err
+		}
+		numCoverPages, err := coverReader.GetNumPages()
+		if err != nil {
+			return err
+		}
+		for i := 0; i < numCoverPages; i++ {
+			page, err := coverReader.GetPage(i + 1)
+			if err != nil {
+				return err
+			}
+			xform, bbox, err := pageToXObjectForm(page)
+			if err != nil {
+				return err
+			}
+			covers = append(covers, coverPage{xform, bbox})
+		}
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	inserted := 0
+
+	// Inserts the blank pages or the cover for each insertion point after page pageNum (0: before the first page).
+	insertAfter := func(pageNum int) error {
+		neighborNum := pageNum
+		if neighborNum == 0 {
+			neighborNum = 1
+		}
+		neighbor, err := pdfReader.GetPage(neighborNum)
+		if err != nil {
+			return err
+		}
+
+		for _, after := range afterPages {
+			if after != pageNum {
+				continue
+			}
+
+			newPages := []*pdf.PdfPage{}
+			if len(covers) > 0 {
+				for _, cover := range covers {
+					page, err := newCoverPage(cover, neighbor)
+					if err != nil {
+						return err
+					}
+					newPages = append(newPages, page)
+				}
+			} else {
+				for i := 0; i < count; i++ {
+					page, err := newBlankPage(neighbor)
+					if err != nil {
+						return err
+					}
+					newPages = append(newPages, page)
+				}
+			}
+
+			for _, page := range newPages {
+				err = pdfWriter.AddPage(page)
+				if err != nil {
+					return err
+				}
+				inserted++
+			}
+		}
+		return nil
+	}
+
+	err = insertAfter(0)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < numPages; i++ {
+		page, err := pdfReader.GetPage(i + 1)
+		if err != nil {
+			return err
+		}
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+
+		err = insertAfter(i + 1)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Inserted %d pages (%d -> %d pages)\n", inserted, numPages, numPages+inserted)
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+func openPdfReader(f *os.File) (*pdf.PdfReader, error) {
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return nil, err
+		}
+		if !auth {
+			return nil, errors.New("Need to decrypt with password")
+		}
+	}
+
+	return pdfReader, nil
+}
+
+// Parses the comma separated list of "after page" positions.  0 is before the first page, the page count or "end"
+// after the last page.
+func parsePositions(positions string, numPages int) ([]int, error) {
+	afterPages := []int{}
+	for _, part := range strings.Split(positions, ",") {
+		part = strings.TrimSpace(part)
+		if part == "end" {
+			afterPages = append(afterPages, numPages)
+			continue
+		}
+		after, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid position %q", part)
+		}
+		if after < 0 || after > numPages {
+			return nil, fmt.Errorf("Position %d out of range (0-%d)", after, numPages)
+		}
+		afterPages = append(afterPages, after)
+	}
+	sort.Ints(afterPages)
+	return afterPages, nil
+}
+
+// Creates a blank page with the same page boxes and rotation as the neighbor page.
+func newBlankPage(neighbor *pdf.PdfPage) (*pdf.PdfPage, error) {
+	mbox, err := neighbor.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+
+	page := pdf.NewPdfPage()
+	page.MediaBox = copyRectangle(mbox)
+	if neighbor.CropBox != nil {
+		page.CropBox = copyRectangle(neighbor.CropBox)
+	}
+	if neighbor.Rotate != nil {
+		rotate := *neighbor.Rotate
+		page.Rotate = &rotate
+	}
+	page.Resources = pdf.NewPdfPageResources()
+
+	return page, nil
+}
+
+// Creates a page of the size of the neighbor page as displayed, with the cover page scaled to fit and centered.
+func newCoverPage(cover coverPage, neighbor *pdf.PdfPage) (*pdf.PdfPage, error) {
+	mbox, err := neighbor.GetMediaBox()
+	if err != nil {
+		return nil, err
+	}
+
+	pw, ph := mbox.Urx-mbox.Llx, mbox.Ury-mbox.Lly
+	if neighbor.Rotate != nil && (*neighbor.Rotate/90)%2 != 0 {
+		pw, ph = ph, pw
+	}
+
+	page := pdf.NewPdfPage()
+	page.MediaBox = &pdf.PdfRectangle{Llx: 0, Lly: 0, Urx: pw, Ury: ph}
+	page.Resources = pdf.NewPdfPageResources()
+	err = page.Resources.SetXObjectFormByName("Cover", cover.xform)
+	if err != nil {
+		return nil, err
+	}
+
+	cw, ch := cover.bbox.Urx-cover.bbox.Llx, cover.bbox.Ury-cover.bbox.Lly
+	scale := math.Min(pw/cw, ph/ch)
+	tx := (pw-cw*scale)/2 - cover.bbox.Llx*scale
+	ty := (ph-ch*scale)/2 - cover.bbox.Lly*scale
+
+	content := fmt.Sprintf("q\n%.4f 0 0 %.4f %.4f %.4f cm\n/Cover Do\nQ\n", scale, scale, tx, ty)
+	err = page.SetContentStreams([]string{content}, pdfcore.NewFlateEncoder())
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Converts a page to a Form XObject with the page contents and resources.  The BBox of the form is the page MediaBox,
+// which is also returned.
+func pageToXObjectForm(page *pdf.PdfPage) (*pdf.XObjectForm, *pdf.PdfRectangle, error) {
+	mbox, err := page.GetMediaBox()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	xform := pdf.NewXObjectForm()
+	xform.Resources = page.Resources
+	xform.BBox = mbox.ToPdfObject()
+	xform.Filter = pdfcore.NewFlateEncoder()
+	err = xform.SetContentStream([]byte(contents), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return xform, mbox, nil
+}
+
+func copyRectangle(r *pdf.PdfRectangle) *pdf.PdfRectangle {
+	rect := *r
+	return &rect
+}
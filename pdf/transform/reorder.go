@@ -0,0 +1,362 @@
+/*
+ * Reorder and delete pages: outputs the pages of a PDF in the given order, e.g. 3,1,2,5.  Pages that are not listed
+ * are omitted, so the example also serves to delete pages.  Ranges such as 4-7 can be used as a shorthand.
+ *
+ * The page numbers are validated against the page count, and listing a page more than once is reported as an error.
+ *
+ * Link annotations on the output pages are checked:
+ * - Links to pages that are omitted would be broken, they are removed and a warning is printed.
+ * - Links to named destinations are changed to explicit destinations (the target page and position), as the named
+ *   destinations of the document catalog are not carried over to the output.
+ * The document outline (bookmarks) is not carried over either.
+ *
+ * Run as: go run reorder.go input.pdf <page_order> output.pdf
+ * For example: go run reorder.go input.pdf 3,1,2,5 output.pdf
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	pdfcore "github.com/unidoc/unidoc/pdf/core"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+)
+
+func main() {
+	if len(os.Args) < 4 {
+		fmt.Printf("Usage: go run reorder.go input.pdf <page_order> output.pdf\n")
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	inputPath := os.Args[1]
+	pageOrder := os.Args[2]
+	outputPath := os.Args[3]
+
+	err := reorderPdf(inputPath, pageOrder, outputPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Complete, see output file: %s\n", outputPath)
+}
+
+func reorderPdf(inputPath string, pageOrder string, outputPath string) error {
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pdfReader, err := pdf.NewPdfReader(f)
+	if err != nil {
+		return err
+	}
+
+	isEncrypted, err := pdfReader.IsEncrypted()
+	if err != nil {
+		return err
+	}
+	if isEncrypted {
+		auth, err := pdfReader.Decrypt([]byte(""))
+		if err != nil {
+			return err
+		}
+		if !auth {
+			return errors.New("Need to decrypt with password")
+		}
+	}
+
+	numPages, err := pdfReader.GetNumPages()
+	if err != nil {
+		return err
+	}
+
+	pageNums, err := parsePageOrder(pageOrder, numPages)
+	if err != nil {
+		return err
+	}
+
+	fixer, err := newLinkFixer(pdfReader, numPages, pageNums)
+	if err != nil {
+		return err
+	}
+
+	pdfWriter := pdf.NewPdfWriter()
+	for _, pageNum := range pageNums {
+		page, err := pdfReader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+
+		fixer.fixLinks(page, pageNum)
+
+		err = pdfWriter.AddPage(page)
+		if err != nil {
+			return err
+		}
+	}
+
+	omitted := []string{}
+	for i := 1; i <= numPages; i++ {
+		if _, kept := fixer.newPageNums[i]; !kept {
+			omitted = append(omitted, strconv.Itoa(i))
+		}
+	}
+	if len(omitted) > 0 {
+		fmt.Printf("Omitted pages: %s\n", strings.Join(omitted, ","))
+	}
+
+	fWrite, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer fWrite.Close()
+
+	return pdfWriter.Write(fWrite)
+}
+
+// Parses the page order, e.g. "3,1,2,5" or "4-7,1", and validates the page numbers.
+func parsePageOrder(pageOrder string, numPages int) ([]int, error) {
+	pageNums := []int{}
+	positions := map[int]int{}
+	for _, part := range strings.Split(pageOrder, ",") {
+		part = strings.TrimSpace(part)
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid page number %q", part)
+		}
+		to := from
+		if len(bounds) == 2 {
+			to, err = strconv.Atoi(bounds[1])
+			if err != nil || to < from {
+				return nil, fmt.Errorf("Invalid page range %q", part)
+			}
+		}
+
+		for pageNum := from; pageNum <= to; pageNum++ {
+			if pageNum < 1 || pageNum > numPages {
+				return nil, fmt.Errorf("Page %d out of range (document has %d pages)", pageNum, numPages)
+			}
+			if pos, has := positions[pageNum]; has {
+				return nil, fmt.Errorf("Page %d is listed more than once (positions %d and %d)", pageNum, pos,
+					len(pageNums)+1)
+			}
+			pageNums = append(pageNums, pageNum)
+			positions[pageNum] = len(pageNums)
+		}
+	}
+	return pageNums, nil
+}
+
+// linkFixer checks the link destinations on the output pages.
+type linkFixer struct {
+	pdfReader   *pdf.PdfReader
+	pageObjects map[pdfcore.PdfObject]int // Page objects to page numbers in the input.
+	newPageNums map[int]int               // Input page numbers to page numbers in the output.
+	namedDests  map[string]pdfcore.PdfObject
+}
+
+func newLinkFixer(pdfReader *pdf.PdfReader, numPages int, pageNums []int) (*linkFixer, error) {
+	fixer := &linkFixer{
+		pdfReader:   pdfReader,
+		pageObjects: map[pdfcore.PdfObject]int{},
+		newPageNums: map[int]int{},
+		namedDests:  map[string]pdfcore.PdfObject{},
+	}
+
+	for i := 1; i <= numPages; i++ {
+		obj, err := pdfReader.GetPageAsIndirectObject(i)
+		if err != nil {
+			return nil, err
+		}
+		fixer.pageObjects[obj] = i
+	}
+	for i, pageNum := range pageNums {
+		fixer.newPageNums[pageNum] = i + 1
+	}
+
+	err := fixer.loadNamedDestinations()
+	if err != nil {
+		return nil, err
+	}
+
+	return fixer, nil
+}
+
+// Loads the named destinations from the document catalog: the /Dests dictionary (PDF 1.1) and the /Dests name tree
+// in the /Names dictionary.
+func (fixer *linkFixer) loadNamedDestinations() error {
+	trailer, err := fixer.pdfReader.GetTrailer()
+	if err != nil {
+		return err
+	}
+	catalog, ok := fixer.resolve(trailer.Get("Root")).(*pdfcore.PdfObjectDictionary)
+	if !ok {
+		return errors.New("Invalid catalog")
+	}
+
+	if dests, ok := fixer.resolve(catalog.Get("Dests")).(*pdfcore.PdfObjectDictionary); ok {
+		for _, name := range dests.Keys() {
+			fixer.namedDests[string(name)] = dests.Get(name)
+		}
+	}
+
+	if names, ok := fixer.resolve(catalog.Get("Names")).(*pdfcore.PdfObjectDictionary); ok {
+		fixer.loadNameTree(names.Get("Dests"), 0)
+	}
+
+	return nil
+}
+
+// Loads the destinations from a name tree node and its kids.
+func (fixer *linkFixer) loadNameTree(obj pdfcore.PdfObject, depth int) {
+	node, ok := fixer.resolve(obj).(*pdfcore.PdfObjectDictionary)
+	if !ok || depth > 32 {
+		return
+	}
+
+	if names, ok := fixer.resolve(node.Get("Names")).(*pdfcore.PdfObjectArray); ok {
+		for i := 0; i+1 < len(*names); i += 2 {
+			if key, ok := fixer.resolve((*names)[i]).(*pdfcore.PdfObjectString); ok {
+				fixer.namedDests[string(*key)] = (*names)[i+1]
+			}
+		}
+	}
+
+	if kids, ok := fixer.resolve(node.Get("Kids")).(*pdfcore.PdfObjectArray); ok {
+		for _, kid := range *kids {
+			fixer.loadNameTree(kid, depth+1)
+		}
+	}
+}
+
+// Resolves references to the direct object.  Objects that are only reachable from the catalog are not loaded by the
+// reader, so references are looked up by their object number.
+func (fixer *linkFixer) resolve(obj pdfcore.PdfObject) pdfcore.PdfObject {
+	if ref, isRef := obj.(*pdfcore.PdfObjectReference); isRef {
+		var err error
+		obj, err = fixer.pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+		if err != nil {
+			return nil
+		}
+	}
+	return pdfcore.TraceToDirectObject(obj)
+}
+
+// Returns the explicit destination for the destination object and the name for named destinations.  Returns nil if
+// the destination cannot be resolved.
+func (fixer *linkFixer) explicitDestination(dest pdfcore.PdfObject) (*pdfcore.PdfObjectArray, string) {
+	name := ""
+	switch t := fixer.resolve(dest).(type) {
+	case *pdfcore.PdfObjectArray:
+		return t, ""
+	case *pdfcore.PdfObjectName:
+		name = string(*t)
+	case *pdfcore.PdfObjectString:
+		name = string(*t)
+	default:
+		return nil, ""
+	}
+
+	named, has := fixer.namedDests[name]
+	if !has {
+		return nil, name
+	}
+	target := fixer.resolve(named)
+	if dict, isDict := target.(*pdfcore.PdfObjectDictionary); isDict {
+		target = fixer.resolve(dict.Get("D"))
+	}
+	arr, ok := target.(*pdfcore.PdfObjectArray)
+	if !ok || len(*arr) == 0 {
+		return nil, name
+	}
+
+	// Copy the destination with the page reference resolved to the page object, as the writer does not accept
+	// references.
+	explicit := pdfcore.PdfObjectArray{}
+	for i, obj := range *arr {
+		if ref, isRef := obj.(*pdfcore.PdfObjectReference); isRef && i == 0 {
+			pageObj, err := fixer.pdfReader.GetIndirectObjectByNumber(int(ref.ObjectNumber))
+			if err != nil {
+				return nil, name
+			}
+			obj = pageObj
+		} else {
+			obj = fixer.resolve(obj)
+		}
+		explicit = append(explicit, obj)
+	}
+	return &explicit, name
+}
+
+// Checks the link annotations on the page: removes links to omitted pages and replaces named destinations with
+// explicit destinations.
+func (fixer *linkFixer) fixLinks(page *pdf.PdfPage, pageNum int) {
+	annotations := []*pdf.PdfAnnotation{}
+	for _, annot := range page.Annotations {
+		link, ok := annot.GetContext().(*pdf.PdfAnnotationLink)
+		if !ok {
+			annotations = append(annotations, annot)
+			continue
+		}
+
+		// The destination is either in /Dest or in a GoTo action.
+		dest := link.Dest
+		var action *pdfcore.PdfObjectDictionary
+		if dest == nil {
+			action, ok = pdfcore.TraceToDirectObject(link.A).(*pdfcore.PdfObjectDictionary)
+			if !ok {
+				annotations = append(annotations, annot)
+				continue
+			}
+			if s, ok := action.Get("S").(*pdfcore.PdfObjectName); !ok || *s != "GoTo" {
+				// Not an internal link (e.g. URI).
+				annotations = append(annotations, annot)
+				continue
+			}
+			dest = action.Get("D")
+		}
+
+		explicit, name := fixer.explicitDestination(dest)
+		if explicit == nil {
+			fmt.Printf("Warning: page %d: link to unknown destination %q left unchanged\n", pageNum, name)
+			annotations = append(annotations, annot)
+			continue
+		}
+
+		targetNum, isPage := fixer.pageObjects[(*explicit)[0]]
+		if !isPage {
+			// E.g. a page number (remote destinations).
+			annotations = append(annotations, annot)
+			continue
+		}
+		newTargetNum, kept := fixer.newPageNums[targetNum]
+		if !kept {
+			fmt.Printf("Warning: page %d: removed link to omitted page %d\n", pageNum, targetNum)
+			continue
+		}
+
+		if len(name) > 0 {
+			if action != nil {
+				action.Set("D", explicit)
+			} else {
+				link.Dest = explicit
+			}
+			fmt.Printf("Page %d: link to named destination %q changed to page %d of the output\n", pageNum, name,
+				newTargetNum)
+		}
+		annotations = append(annotations, annot)
+	}
+	page.Annotations = annotations
+}
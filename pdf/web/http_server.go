@@ -0,0 +1,362 @@
+/*
+ * Serve reports generated on demand over HTTP: GET /report.pdf builds a PDF from the query parameters and streams it
+ * to the client.
+ *
+ * Parameters: title (the report title), rows (the number of rows, 1 - maxRows), and inline=1 to show the PDF in the
+ * browser instead of downloading it.  E.g. http://localhost:8080/report.pdf?title=Q3+sales&rows=500&inline=1
+ *
+ * The report is built as in pdf/output/stream_output.go, with a creator per request, and written to the response as
+ * it is serialized through an offsetWriter (see there), so the file is not buffered: the memory used by a request is
+ * that of its pages, which is bounded by maxRows.
+ *
+ * Fonts: the TrueType fonts are loaded (parsed and compressed) once at startup, not per request.  A loaded font
+ * becomes the same PDF objects in every document it is used in, and the writer numbers these objects while writing,
+ * so a font can only be used by one document at a time.  Therefore -workers sets of fonts are loaded into a pool,
+ * and each request takes a set for building and writing its report, and puts it back after.  The pool also limits
+ * the number of reports generated at the same time, and so the memory used: further requests wait for a set, up to
+ * queueTimeout, and then get 503 Service Unavailable.
+ *
+ * Caching: the same parameters always give the same report, so the response has an ETag computed from the
+ * parameters (and reportVersion, to change when the layout changes), and Cache-Control allows private caches to keep
+ * it for cacheMaxAge.  A request with a matching If-None-Match gets 304 Not Modified without generating the report.
+ *
+ * Run as: go run http_server.go [-addr localhost:8080] [-workers 4] [-font regular.ttf] [-bold bold.ttf]
+ */
+
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	//unicommon "github.com/unidoc/unidoc/common"
+	"github.com/unidoc/unidoc/pdf/creator"
+	pdf "github.com/unidoc/unidoc/pdf/model"
+	"github.com/unidoc/unidoc/pdf/model/fonts"
+)
+
+const usage = "Usage: go run http_server.go [-addr localhost:8080] [-workers 4] [-font regular.ttf] [-bold bold.ttf]\n"
+
+const (
+	maxRows       = 20000
+	maxTitle      = 200
+	reportVersion = "1" // Part of the ETag: change it when the report layout changes.
+	cacheMaxAge   = 10 * time.Minute
+	queueTimeout  = 30 * time.Second
+)
+
+// fontSet is the fonts used by a report.
+type fontSet struct {
+	regular fonts.Font
+	bold    fonts.Font
+}
+
+// reportServer serves the reports, with a pool of font sets shared by the requests.
+type reportServer struct {
+	fontPool chan *fontSet
+}
+
+// reportParams is the parameters of a report.
+type reportParams struct {
+	title string
+	rows  int
+}
+
+// offsetWriter lets the creator write to an io.Writer: it counts the bytes written to report the current offset,
+// which is the only seek the creator makes, and keeps the first write error.
+type offsetWriter struct {
+	w      io.Writer
+	offset int64
+	err    error
+}
+
+func main() {
+	addr := ""
+	workers := 0
+	fontPath := ""
+	boldFontPath := ""
+	flag.StringVar(&addr, "addr", "localhost:8080", "Address to listen on")
+	flag.IntVar(&workers, "workers", 4, "Number of reports generated at the same time")
+	flag.StringVar(&fontPath, "font", "../report/Roboto-Regular.ttf", "TrueType font for the text")
+	flag.StringVar(&boldFontPath, "bold", "../report/Roboto-Bold.ttf", "TrueType font for the headings")
+	flag.Usage = func() {
+		fmt.Print(usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if workers < 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// When debugging, log to console:
+	//unicommon.SetLogger(unicommon.NewConsoleLogger(unicommon.LogLevelDebug))
+
+	server := &reportServer{fontPool: make(chan *fontSet, workers)}
+	for i := 0; i < workers; i++ {
+		fs, err := loadFonts(fontPath, boldFontPath)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		server.fontPool <- fs
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/report.pdf", server)
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       time.Minute,
+		// No WriteTimeout, which would cut off large reports sent to slow clients.
+	}
+
+	log.Printf("Serving http://%s/report.pdf", addr)
+	err := httpServer.ListenAndServe()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadFonts loads a set of fonts from the TrueType font files.
+func loadFonts(fontPath, boldFontPath string) (*fontSet, error) {
+	regular, err := pdf.NewPdfFontFromTTFFile(fontPath)
+	if err != nil {
+		return nil, fmt.Errorf("Font %s: %v", fontPath, err)
+	}
+	bold, err := pdf.NewPdfFontFromTTFFile(boldFontPath)
+	if err != nil {
+		return nil, fmt.Errorf("Font %s: %v", boldFontPath, err)
+	}
+	return &fontSet{regular: regular, bold: bold}, nil
+}
+
+// ServeHTTP serves a report built from the query parameters.
+func (s *reportServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	params, err := parseParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The response depends on the parameters only, so it can be cached and revalidated by its ETag.  The
+	// disposition is part of it, as it is a header of the response.
+	disposition := "attachment"
+	if r.URL.Query().Get("inline") == "1" {
+		disposition = "inline"
+	}
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%s", reportVersion, params.title, params.rows,
+		disposition)))
+	etag := fmt.Sprintf(`"%x"`, hash[:16])
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(cacheMaxAge.Seconds())))
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition,
+		map[string]string{"filename": "report.pdf"}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	// Wait for a font set, which is also a slot to generate a report.
+	var fs *fontSet
+	timer := time.NewTimer(queueTimeout)
+	defer timer.Stop()
+	select {
+	case fs = <-s.fontPool:
+	case <-timer.C:
+		s.fail(w, http.StatusServiceUnavailable, "Too many requests, try again later")
+		return
+	case <-r.Context().Done():
+		// The client is gone.
+		return
+	}
+	defer func() { s.fontPool <- fs }()
+
+	start := time.Now()
+	c, err := buildReport(params, fs)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		s.fail(w, http.StatusInternalServerError, "Failed to create the report")
+		return
+	}
+
+	size, err := writePdf(c, w)
+	if err == nil {
+		log.Printf("%s: %d rows, %d bytes in %v", r.URL.RequestURI(), params.rows, size,
+			time.Since(start).Round(time.Millisecond))
+		return
+	}
+
+	log.Printf("%s: Error: %v", r.URL.RequestURI(), err)
+	if size == 0 {
+		s.fail(w, http.StatusInternalServerError, "Failed to write the report")
+		return
+	}
+	// Part of the PDF was sent: abort the response so that it is not taken as complete.  The font set is put back by
+	// the deferred function.
+	panic(http.ErrAbortHandler)
+}
+
+// fail sends an error response, replacing the headers of the PDF, which must not have been sent yet.
+func (s *reportServer) fail(w http.ResponseWriter, code int, message string) {
+	for _, key := range []string{"Content-Disposition", "ETag", "Cache-Control"} {
+		w.Header().Del(key)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if code == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "10")
+	}
+	http.Error(w, message, code)
+}
+
+// parseParams returns the parameters of the report from the query of the request.
+func parseParams(r *http.Request) (reportParams, error) {
+	query := r.URL.Query()
+	params := reportParams{title: "Sales report", rows: 100}
+
+	if s := query.Get("title"); s != "" {
+		if len(s) > maxTitle {
+			return params, errors.New("Title too long")
+		}
+		params.title = s
+	}
+	if s := query.Get("rows"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxRows {
+			return params, fmt.Errorf("Invalid rows, must be 1 - %d", maxRows)
+		}
+		params.rows = n
+	}
+	return params, nil
+}
+
+// etagMatches returns true if the If-None-Match header value matches the ETag.
+func etagMatches(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+		if value == etag || value == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// writePdf writes the PDF of the creator to w.  Returns the number of bytes written, and the first error, of the
+// creator or of writing to w.
+func writePdf(c *creator.Creator, w io.Writer) (int64, error) {
+	ow := &offsetWriter{w: w}
+	err := c.Write(ow)
+	if err != nil {
+		return ow.offset, err
+	}
+	return ow.offset, ow.err
+}
+
+// Write writes p to the underlying writer, unless a previous write failed.
+func (ow *offsetWriter) Write(p []byte) (int, error) {
+	if ow.err != nil {
+		return 0, ow.err
+	}
+	n, err := ow.w.Write(p)
+	ow.offset += int64(n)
+	if err != nil {
+		ow.err = err
+	}
+	return n, err
+}
+
+// Seek returns the current offset.  Other seeks are not possible on a stream, and fail the write.
+func (ow *offsetWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekCurrent {
+		return ow.offset, nil
+	}
+	err := errors.New("Seek not supported when streaming")
+	if ow.err == nil {
+		ow.err = err
+	}
+	return ow.offset, err
+}
+
+// buildReport builds a report with a table of the given number of rows, spanning as many pages as needed.
+func buildReport(params reportParams, fs *fontSet) (*creator.Creator, error) {
+	c := creator.New()
+	c.SetPageMargins(50, 50, 50, 50)
+	c.NewPage()
+
+	heading := creator.NewParagraph(params.title)
+	heading.SetFont(fs.bold)
+	heading.SetFontSize(16)
+	heading.SetMargins(0, 0, 0, 10)
+	err := c.Draw(heading)
+	if err != nil {
+		return nil, err
+	}
+
+	table := creator.NewTable(4)
+	table.SetColumnWidths(0.15, 0.45, 0.2, 0.2)
+	for _, text := range []string{"No.", "Product", "Quantity", "Amount"} {
+		addCell(table, text, fs.bold)
+	}
+	total := 0.0
+	for i := 1; i <= params.rows; i++ {
+		qty := 1 + (i*7)%12
+		amount := float64(qty) * (2.5 + float64((i*13)%40))
+		total += amount
+		addCell(table, fmt.Sprintf("%d", i), fs.regular)
+		addCell(table, fmt.Sprintf("Product %04d", 1000+(i*37)%9000), fs.regular)
+		addCell(table, fmt.Sprintf("%d", qty), fs.regular)
+		addCell(table, fmt.Sprintf("%.2f", amount), fs.regular)
+	}
+	addCell(table, "", fs.bold)
+	addCell(table, "Total", fs.bold)
+	addCell(table, "", fs.bold)
+	addCell(table, fmt.Sprintf("%.2f", total), fs.bold)
+
+	err = c.Draw(table)
+	if err != nil {
+		return nil, err
+	}
+
+	c.DrawFooter(func(footer *creator.Block, args creator.FooterFunctionArgs) {
+		p := creator.NewParagraph(fmt.Sprintf("Page %d of %d", args.PageNum, args.TotalPages))
+		p.SetFont(fs.regular)
+		p.SetFontSize(8)
+		p.SetPos(footer.Width()-50-p.Width(), 20)
+		footer.Draw(p)
+	})
+	return c, nil
+}
+
+// addCell adds a cell with the text to the table.
+func addCell(table *creator.Table, text string, font fonts.Font) {
+	p := creator.NewParagraph(text)
+	p.SetFont(font)
+	p.SetFontSize(10)
+	cell := table.NewCell()
+	cell.SetBorder(creator.CellBorderStyleBox, 0.5)
+	cell.SetContent(p)
+}